@@ -1,4 +1,5 @@
 // All-in-one dev server: runs fleet + area + zone + edge (one per robot) in one process with a shared in-memory bus.
+// With TOPOLOGY it runs many areas and zones, with one simulator shard per zone.
 package main
 
 import (
@@ -15,10 +16,18 @@ import (
 	"github.com/robotfleetos/robotfleetos/internal/area"
 	"github.com/robotfleetos/robotfleetos/internal/edge"
 	"github.com/robotfleetos/robotfleetos/internal/fleet"
+	"github.com/robotfleetos/robotfleetos/internal/topology"
 	"github.com/robotfleetos/robotfleetos/internal/zone"
 	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/cmdsign"
+	"github.com/robotfleetos/robotfleetos/pkg/facility"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
 	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+	"github.com/robotfleetos/robotfleetos/pkg/robotmodel"
 	"github.com/robotfleetos/robotfleetos/pkg/state"
+	"github.com/robotfleetos/robotfleetos/pkg/telemetry"
 )
 
 func main() {
@@ -32,9 +41,15 @@ func main() {
 		cancel()
 	}()
 
+	if d := os.Getenv("SIM_DURATION"); d != "" {
+		runShift(ctx, d)
+		return
+	}
+
 	// Single shared bus for fleet and area (in-memory for dev).
 	bus := messaging.NewMemoryBus()
-	_ = state.NewMemoryStore()
+	store := state.NewMemoryStore() // zones publish their command signing keys here for the edges
+	stats := &telemetry.Layers{}
 
 	// ---- Fleet ----
 	fleetCfg, err := fleet.LoadConfig("")
@@ -42,19 +57,89 @@ func main() {
 		fleetCfg = &fleet.Config{}
 		fleetCfg.Fleet.APIListen = ":8080"
 	}
-	workOrderPub := messaging.NewWorkOrderPublisher(bus)
-	scheduler := fleet.NewScheduler(workOrderPub)
-	globalState := fleet.NewGlobalState()
-	go func() { _ = globalState.Run(ctx, bus) }()
-	fleetServer := &fleet.Server{Scheduler: scheduler, State: globalState}
-	httpSrv := &http.Server{Addr: fleetCfg.Fleet.APIListen, Handler: fleetServer.Handler()}
-	go func() {
-		log.Printf("fleet: API on http://localhost%s", fleetCfg.Fleet.APIListen)
-		if err := httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("fleet: %v", err)
+	// Fleet API plus the simulator control API (/sim/), mounted once the edge is set up. Auth, if configured,
+	// covers both: writes to /sim/ need admin. Both record their writes in the audit log.
+	authn, err := auth.New(fleetCfg.Auth, fleet.AuthRules)
+	if err != nil {
+		log.Fatalf("fleet: %v", err)
+	}
+	auditLog, err := audit.Open("fleet", fleetCfg.Audit)
+	if err != nil {
+		log.Fatalf("fleet: %v", err)
+	}
+	defer auditLog.Close()
+	idem, err := idempotency.New(fleetCfg.Idempotency)
+	if err != nil {
+		log.Fatalf("fleet: %v", err)
+	}
+	apiMux := http.NewServeMux()
+	httpSrv := &http.Server{Addr: fleetCfg.Fleet.APIListen, Handler: authn.Wrap(apiMux)}
+	facilityMap := fleet.NewFacilityMap(nil)
+	if fleetCfg.Fleet.FacilityMap != "" {
+		m, err := facility.Load(fleetCfg.Fleet.FacilityMap)
+		if err != nil {
+			log.Fatalf("fleet: %v", err)
 		}
-	}()
+		facilityMap.Set(m)
+		log.Printf("fleet: facility map %s (%d nodes, %d edges)", fleetCfg.Fleet.FacilityMap, len(m.Nodes), len(m.Edges))
+	}
+	var models *robotmodel.Registry
+	if fleetCfg.Fleet.ModelRegistry != "" {
+		if models, err = robotmodel.Load(fleetCfg.Fleet.ModelRegistry); err != nil {
+			log.Fatalf("fleet: %v", err)
+		}
+		log.Printf("fleet: model registry %s (%d models)", fleetCfg.Fleet.ModelRegistry, len(models.Models))
+	}
+	stats.Measure("fleet", func() int {
+		workOrderPub := messaging.NewWorkOrderPublisher(bus)
+		scheduler := fleet.NewScheduler(workOrderPub)
+		globalState := fleet.NewGlobalState()
+		telemetry.Go(ctx, "fleet", func(ctx context.Context) { _ = globalState.Run(ctx, bus) })
+		safetyLog := fleet.NewSafetyLog(0)
+		telemetry.Go(ctx, "fleet", func(ctx context.Context) { _ = safetyLog.Run(ctx, bus) })
+		securityLog := fleet.NewSecurityLog(0)
+		telemetry.Go(ctx, "fleet", func(ctx context.Context) { _ = securityLog.Run(ctx, bus) })
+		fleetServer := &fleet.Server{Scheduler: scheduler, State: globalState, Safety: safetyLog, Security: securityLog, Map: facilityMap, Models: models, Audit: auditLog, Idempotency: idem}
+		apiMux.Handle("/", fleetServer.Handler())
+		apiMux.Handle("/debug/layers", stats)
+		telemetry.Go(ctx, "fleet", func(ctx context.Context) {
+			log.Printf("fleet: API on http://localhost%s", fleetCfg.Fleet.APIListen)
+			if err := httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("fleet: %v", err)
+			}
+		})
+		return 1
+	})
+
+	zoneCfg, errZone := zone.LoadConfig("")
+	if errZone != nil || zoneCfg == nil {
+		zoneCfg = &zone.Config{}
+		zoneCfg.Zone.ZoneID = "zone-1"
+		zoneCfg.Zone.AreaID = "area-1"
+		zoneCfg.Zone.Robots = []string{"robot-1"}
+	}
+	// TOPOLOGY: a topology file or an areas x zones-per-area x robots-per-zone spec (e.g. "10x50x20").
+	if spec := os.Getenv("TOPOLOGY"); spec != "" {
+		topo, err := topology.Parse(spec)
+		if err != nil {
+			log.Fatalf("topology: %v", err)
+		}
+		startTopology(ctx, bus, apiMux, auditLog, stats, topo, zoneCfg.Zone.Firmware.Policy(), facilityMap, models)
+	} else {
+		startSingleZone(ctx, bus, store, apiMux, auditLog, stats, zoneCfg, facilityMap, models)
+	}
+	stats.LogSummary()
 
+	<-ctx.Done()
+	log.Println("shutting down...")
+	_ = httpSrv.Shutdown(context.Background())
+	log.Println("done")
+}
+
+// startSingleZone runs the area from AREA_CONFIG, the zone from ZONE_CONFIG (or SIMULATE_ROBOTS robots in
+// it) and the edge: one gateway per robot, or one simulator for many robots. If the zone signs its robot
+// commands, it publishes its key to store and the edge requires valid signatures.
+func startSingleZone(ctx context.Context, bus *messaging.MemoryBus, store state.Store, apiMux *http.ServeMux, auditLog *audit.Log, stats *telemetry.Layers, zoneCfg *zone.Config, maps *fleet.FacilityMap, models *robotmodel.Registry) {
 	// ---- Area (same bus) ----
 	areaCfg, errArea := area.LoadConfig("")
 	if errArea != nil || areaCfg == nil {
@@ -62,33 +147,33 @@ func main() {
 		areaCfg.Area.AreaID = "area-1"
 		areaCfg.Area.Zones = []string{"zone-1"}
 	}
-	zonePub := messaging.NewZoneTaskPublisher(bus)
-	areaPub := messaging.NewAreaSummaryPublisher(bus)
-	zones := make([]api.ZoneID, len(areaCfg.Area.Zones))
-	for i, z := range areaCfg.Area.Zones {
-		zones[i] = api.ZoneID(z)
-	}
-	areaCtrl := area.NewController(
-		api.AreaID(areaCfg.Area.AreaID),
-		zones,
-		zonePub,
-		areaPub,
-		bus,
-		10*time.Second,
-	)
-	go func() {
-		log.Printf("area: %s (zones: %v)", areaCfg.Area.AreaID, areaCfg.Area.Zones)
-		_ = areaCtrl.Run(ctx)
-	}()
+	stats.Measure("area", func() int {
+		zonePub := messaging.NewZoneTaskPublisher(bus)
+		areaPub := messaging.NewAreaSummaryPublisher(bus)
+		zones := make([]api.ZoneID, len(areaCfg.Area.Zones))
+		for i, z := range areaCfg.Area.Zones {
+			zones[i] = api.ZoneID(z)
+		}
+		report, _ := areaCfg.Area.ReportPeriod()
+		areaCtrl := area.NewController(
+			api.AreaID(areaCfg.Area.AreaID),
+			zones,
+			zonePub,
+			areaPub,
+			bus,
+			report,
+		)
+		if graphs, err := areaCfg.Area.TaskGraphs.Policy(); err == nil {
+			areaCtrl.SetTaskGraphPolicy(graphs)
+		}
+		telemetry.Go(ctx, "area", func(ctx context.Context) {
+			log.Printf("area: %s (zones: %v)", areaCfg.Area.AreaID, areaCfg.Area.Zones)
+			_ = areaCtrl.Run(ctx)
+		})
+		return 1
+	})
 
 	// ---- Zone (same bus) ----
-	zoneCfg, errZone := zone.LoadConfig("")
-	if errZone != nil || zoneCfg == nil {
-		zoneCfg = &zone.Config{}
-		zoneCfg.Zone.ZoneID = "zone-1"
-		zoneCfg.Zone.AreaID = "area-1"
-		zoneCfg.Zone.Robots = []string{"robot-1"}
-	}
 	zoneRobots := make([]api.RobotID, len(zoneCfg.Zone.Robots))
 	for i, r := range zoneCfg.Zone.Robots {
 		zoneRobots[i] = api.RobotID(r)
@@ -106,58 +191,142 @@ func main() {
 			log.Printf("simulation: using %d simulated robots (SIMULATE_ROBOTS=%s)", n, nStr)
 		}
 	}
-	cmdPub := messaging.NewRobotCommandPublisher(bus)
-	zoneSummaryPub := messaging.NewZoneSummaryPublisher(bus)
-	zoneCtrl := zone.NewController(
-		api.ZoneID(zoneCfg.Zone.ZoneID),
-		zoneRobots,
-		cmdPub,
-		zoneSummaryPub,
-		bus,
-		5*time.Second,
-	)
-	go func() {
-		log.Printf("zone: %s (robots: %d)", zoneCfg.Zone.ZoneID, len(zoneRobots))
-		_ = zoneCtrl.Run(ctx)
-	}()
-
-	// ---- Edge: one gateway per robot, or one Simulator for many robots ----
-	const useSimulatorThreshold = 25
-	if len(zoneRobots) >= useSimulatorThreshold {
-		statusPub := messaging.NewRobotStatusPublisher(bus)
-		sim := edge.NewSimulator(
+	signer, err := zoneCfg.Zone.CommandSigning.Signer(api.ZoneID(zoneCfg.Zone.ZoneID))
+	if err != nil {
+		log.Fatalf("zone: %v", err)
+	}
+	var verifier *cmdsign.Verifier
+	if signer != nil {
+		if err := cmdsign.PublishKey(ctx, store, signer.Key()); err != nil {
+			log.Fatalf("zone: publish command signing key: %v", err)
+		}
+		verifier = cmdsign.NewVerifier(cmdsign.NewKeyring(), cmdsign.Policy{Require: true})
+		if err := verifier.Keys().UseStore(ctx, store); err != nil {
+			log.Fatalf("edge: %v", err)
+		}
+		verifier.SetSecurityEventPublisher(messaging.NewSecurityEventPublisher(bus))
+		log.Printf("zone: signing robot commands with key %s; edge requires signatures", signer.Key().ID)
+	}
+	stats.Measure("zone", func() int {
+		var cmdPub messaging.RobotCommandPublisher = messaging.NewRobotCommandPublisher(bus)
+		if signer != nil {
+			cmdPub = signer.Publisher(cmdPub)
+		}
+		zoneSummaryPub := messaging.NewZoneSummaryPublisher(bus)
+		report, _ := zoneCfg.Zone.ReportPeriod()
+		zoneCtrl := zone.NewController(
 			api.ZoneID(zoneCfg.Zone.ZoneID),
 			zoneRobots,
-			statusPub,
+			cmdPub,
+			zoneSummaryPub,
 			bus,
-			5*time.Second,
-			2*time.Second,
+			report,
 		)
-		go func() {
-			log.Printf("edge: simulator for %d robots (zone %s)", len(zoneRobots), zoneCfg.Zone.ZoneID)
-			_ = sim.Run(ctx)
-		}()
-	} else {
+		zoneCtrl.SetFirmwarePolicy(zoneCfg.Zone.Firmware.Policy())
+		zoneCtrl.SetStepResultPublisher(messaging.NewTaskResultPublisher(bus))
+		if traffic, err := zoneCfg.Zone.Traffic.Policy(); err == nil {
+			zoneCtrl.SetTrafficPolicy(traffic)
+		}
+		if charging, err := zoneCfg.Zone.Charging.Policy(); err == nil {
+			zoneCtrl.SetChargingPolicy(charging)
+		}
+		zoneCtrl.SetModelRegistry(models)
+		followMap(zoneCtrl, maps)
+		telemetry.Go(ctx, "zone", func(ctx context.Context) {
+			log.Printf("zone: %s (robots: %d)", zoneCfg.Zone.ZoneID, len(zoneRobots))
+			_ = zoneCtrl.Run(ctx)
+		})
+		return 1
+	})
+
+	// ---- Edge: one gateway per robot, or one Simulator for many robots ----
+	const useSimulatorThreshold = 25
+	safetyPub := messaging.NewSafetyEventPublisher(bus)
+	resultPub := messaging.NewTaskResultPublisher(bus)
+	if len(zoneRobots) >= useSimulatorThreshold {
+		stats.Measure("edge", func() int {
+			statusPub := messaging.NewRobotStatusPublisher(bus)
+			sim := edge.NewSimulator(
+				api.ZoneID(zoneCfg.Zone.ZoneID),
+				zoneRobots,
+				statusPub,
+				bus,
+				5*time.Second,
+				2*time.Second,
+			)
+			sim.SetSafetyEventPublisher(safetyPub)
+			sim.SetTaskResultPublisher(resultPub)
+			sim.SetCommandVerifier(verifier)
+			sim.SetSimConfig(loadSimConfig(maps.Get()))
+			apiMux.Handle("/sim/", sim.ControlHandler(auditLog))
+			telemetry.Go(ctx, "edge", func(ctx context.Context) {
+				log.Printf("edge: simulator for %d robots (zone %s)", len(zoneRobots), zoneCfg.Zone.ZoneID)
+				_ = sim.Run(ctx)
+			})
+			return 1
+		})
+		return
+	}
+	apiMux.HandleFunc("/sim/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "fault injection needs the simulator (SIMULATE_ROBOTS >= 25 or TOPOLOGY)", http.StatusServiceUnavailable)
+	})
+	stats.Measure("edge", func() int {
 		for _, robotID := range zoneCfg.Zone.Robots {
 			statusPub := messaging.NewRobotStatusPublisher(bus)
+			driver, err := edge.NewDriver("stub", edge.DriverConfig{RobotID: api.RobotID(robotID)})
+			if err != nil {
+				log.Fatalf("edge: %v", err)
+			}
 			gw := edge.NewGateway(
 				api.RobotID(robotID),
 				api.ZoneID(zoneCfg.Zone.ZoneID),
-				"stub",
+				driver,
 				statusPub,
 				bus,
 				2*time.Second,
-				2*time.Second,
 			)
-			go func(id string) {
+			gw.SetSafetyEventPublisher(safetyPub)
+			gw.SetTaskResultPublisher(resultPub)
+			gw.SetCommandVerifier(verifier)
+			id := robotID
+			telemetry.Go(ctx, "edge", func(ctx context.Context) {
 				log.Printf("edge: %s (zone %s)", id, zoneCfg.Zone.ZoneID)
 				_ = gw.Run(ctx)
-			}(robotID)
+			})
 		}
-	}
+		return len(zoneCfg.Zone.Robots)
+	})
+}
 
-	<-ctx.Done()
-	log.Println("shutting down...")
-	_ = httpSrv.Shutdown(context.Background())
-	log.Println("done")
+// followMap keeps a zone controller on the fleet's current facility map, including maps uploaded with PUT /map.
+func followMap(ctrl *zone.Controller, maps *fleet.FacilityMap) {
+	ctrl.SetFacilityMap(maps.Get())
+	maps.OnUpdate(ctrl.SetFacilityMap)
+}
+
+// loadSimConfig reads SIM_CONFIG (physical model: speeds, battery, chargers); SIM_SEED overrides its seed
+// for reproducible runs. Locations bound on the facility map m (may be nil) fill in coordinates the config
+// does not list.
+func loadSimConfig(m *facility.Map) *edge.SimConfig {
+	simCfg := edge.DefaultSimConfig()
+	if path := os.Getenv("SIM_CONFIG"); path != "" {
+		var err error
+		if simCfg, err = edge.LoadSimConfig(path); err != nil {
+			log.Fatalf("edge: %v", err)
+		}
+	}
+	if seed, err := strconv.ParseInt(os.Getenv("SIM_SEED"), 10, 64); err == nil {
+		simCfg.Seed = seed
+	}
+	if m != nil {
+		if simCfg.Locations == nil {
+			simCfg.Locations = make(map[string]api.Point)
+		}
+		for _, n := range m.Nodes {
+			if _, ok := simCfg.Locations[n.LocationID]; n.LocationID != "" && !ok {
+				simCfg.Locations[n.LocationID] = n.Point()
+			}
+		}
+	}
+	return simCfg
 }
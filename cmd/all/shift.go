@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/internal/edge"
+	"github.com/robotfleetos/robotfleetos/internal/sim"
+	"github.com/robotfleetos/robotfleetos/internal/zone"
+	"github.com/robotfleetos/robotfleetos/pkg/facility"
+)
+
+// runShift runs a discrete-event shift on a virtual clock and prints the report as JSON.
+// SIM_DURATION sets the virtual length. Other settings come from SIMULATE_ROBOTS, SIM_ORDER_RATE (orders/hour),
+// SIM_SEED, SIM_CONFIG, FACILITY_MAP (orders between the map's locations, routed by the zone) and
+// SIM_CHARGING=robot (robots charge on their own instead of being sent by the zone). Layer logs are
+// discarded unless SIM_VERBOSE is set.
+func runShift(ctx context.Context, duration string) {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		log.Fatalf("simulation: SIM_DURATION: %v", err)
+	}
+	opts := sim.Options{Duration: d}
+	if n, err := strconv.Atoi(os.Getenv("SIMULATE_ROBOTS")); err == nil {
+		opts.Robots = n
+	}
+	if rate, err := strconv.ParseFloat(os.Getenv("SIM_ORDER_RATE"), 64); err == nil {
+		opts.OrderRatePerHour = rate
+	}
+	if seed, err := strconv.ParseInt(os.Getenv("SIM_SEED"), 10, 64); err == nil {
+		opts.Seed = seed
+	}
+	if path := os.Getenv("SIM_CONFIG"); path != "" {
+		if opts.Sim, err = edge.LoadSimConfig(path); err != nil {
+			log.Fatalf("simulation: %v", err)
+		}
+		if opts.Seed != 0 {
+			opts.Sim.Seed = opts.Seed
+		}
+	}
+
+	if path := os.Getenv("FACILITY_MAP"); path != "" {
+		if opts.Map, err = facility.Load(path); err != nil {
+			log.Fatalf("simulation: %v", err)
+		}
+	}
+
+	if os.Getenv("SIM_CHARGING") == "robot" {
+		opts.Charging = &zone.ChargingPolicy{Disabled: true}
+	}
+
+	out := log.Writer()
+	if os.Getenv("SIM_VERBOSE") == "" {
+		log.SetOutput(io.Discard)
+	}
+	report, err := sim.Run(ctx, opts)
+	log.SetOutput(out)
+	if err != nil {
+		log.Fatalf("simulation: %v", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(report)
+}
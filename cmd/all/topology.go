@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/internal/area"
+	"github.com/robotfleetos/robotfleetos/internal/edge"
+	"github.com/robotfleetos/robotfleetos/internal/fleet"
+	"github.com/robotfleetos/robotfleetos/internal/topology"
+	"github.com/robotfleetos/robotfleetos/internal/zone"
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+	"github.com/robotfleetos/robotfleetos/pkg/robotmodel"
+	"github.com/robotfleetos/robotfleetos/pkg/telemetry"
+)
+
+// startTopology runs one area controller per area, one zone controller per zone and one simulator shard
+// per zone, all on the shared bus. Robots keep their own seeded random source, so sharding does not change
+// how a robot behaves.
+func startTopology(ctx context.Context, bus *messaging.MemoryBus, apiMux *http.ServeMux, auditLog *audit.Log, stats *telemetry.Layers, topo *topology.Topology, firmware api.FirmwarePolicy, maps *fleet.FacilityMap, models *robotmodel.Registry) {
+	areas, zones, robots := topo.Counts()
+	log.Printf("topology: %d areas, %d zones, %d robots", areas, zones, robots)
+
+	stats.Measure("area", func() int {
+		zonePub := messaging.NewZoneTaskPublisher(bus)
+		areaPub := messaging.NewAreaSummaryPublisher(bus)
+		for _, a := range topo.Areas {
+			ctrl := area.NewController(api.AreaID(a.AreaID), a.ZoneIDs(), zonePub, areaPub, bus, 10*time.Second)
+			telemetry.Go(ctx, "area", func(ctx context.Context) { _ = ctrl.Run(ctx) })
+		}
+		return areas
+	})
+
+	stats.Measure("zone", func() int {
+		cmdPub := messaging.NewRobotCommandPublisher(bus)
+		summaryPub := messaging.NewZoneSummaryPublisher(bus)
+		resultPub := messaging.NewTaskResultPublisher(bus)
+		for _, a := range topo.Areas {
+			for _, z := range a.Zones {
+				ctrl := zone.NewController(api.ZoneID(z.ZoneID), z.RobotIDs(), cmdPub, summaryPub, bus, 5*time.Second)
+				ctrl.SetFirmwarePolicy(firmware)
+				ctrl.SetStepResultPublisher(resultPub)
+				ctrl.SetModelRegistry(models)
+				followMap(ctrl, maps)
+				telemetry.Go(ctx, "zone", func(ctx context.Context) { _ = ctrl.Run(ctx) })
+			}
+		}
+		return zones
+	})
+
+	var shards []*edge.Simulator
+	stats.Measure("edge", func() int {
+		simCfg := loadSimConfig(maps.Get())
+		statusPub := messaging.NewRobotStatusPublisher(bus)
+		safetyPub := messaging.NewSafetyEventPublisher(bus)
+		resultPub := messaging.NewTaskResultPublisher(bus)
+		for _, a := range topo.Areas {
+			for _, z := range a.Zones {
+				sim := edge.NewSimulator(api.ZoneID(z.ZoneID), z.RobotIDs(), statusPub, bus, 5*time.Second, 2*time.Second)
+				sim.SetSafetyEventPublisher(safetyPub)
+				sim.SetTaskResultPublisher(resultPub)
+				// Each shard gets its own copy so runtime fault settings are not shared across locks.
+				cfg := *simCfg
+				sim.SetSimConfig(&cfg)
+				shards = append(shards, sim)
+				telemetry.Go(ctx, "edge", func(ctx context.Context) { _ = sim.Run(ctx) })
+			}
+		}
+		return len(shards)
+	})
+	apiMux.Handle("/sim/", edge.ShardedControlHandler(shards, auditLog))
+}
@@ -8,10 +8,10 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/robotfleetos/robotfleetos/internal/area"
 	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
 	"github.com/robotfleetos/robotfleetos/pkg/messaging"
 )
 
@@ -26,7 +26,8 @@ func main() {
 		cancel()
 	}()
 
-	cfg, err := area.LoadConfig("")
+	path := os.Getenv("AREA_CONFIG")
+	cfg, err := area.LoadConfig(path)
 	if err != nil {
 		log.Fatalf("area: load config: %v", err)
 	}
@@ -35,7 +36,7 @@ func main() {
 	if busURL == "" {
 		busURL = cfg.Messaging.Broker
 	}
-	bus, err := messaging.NewBusFromURL(busURL)
+	bus, err := messaging.NewBusFromURL(busURL, cfg.Messaging.ConnOptions)
 	if err != nil {
 		log.Fatalf("area: connect to message bus: %v", err)
 	}
@@ -46,6 +47,10 @@ func main() {
 	for _, z := range cfg.Area.Zones {
 		zones = append(zones, api.ZoneID(z))
 	}
+	report, err := cfg.Area.ReportPeriod()
+	if err != nil {
+		log.Fatalf("area: %v", err)
+	}
 
 	ctrl := area.NewController(
 		api.AreaID(cfg.Area.AreaID),
@@ -53,10 +58,27 @@ func main() {
 		zonePub,
 		areaPub,
 		bus,
-		10*time.Second,
+		report,
 	)
+	graphs, err := cfg.Area.TaskGraphs.Policy()
+	if err != nil {
+		log.Fatalf("area: %v", err)
+	}
+	ctrl.SetTaskGraphPolicy(graphs)
 
 	log.Printf("area: starting %s (zones: %v)", cfg.Area.AreaID, cfg.Area.Zones)
+	if path != "" {
+		// The report interval and task graph settings apply on SIGHUP (or CONFIG_WATCH); a file that fails
+		// validation is logged and the running config kept.
+		go config.Watch(ctx, path, config.PollInterval(), func() {
+			next, err := area.LoadConfig(path)
+			if err != nil {
+				log.Printf("area: reload: %v; keeping the running config", err)
+				return
+			}
+			cfg = ctrl.Reload(ctx, cfg, next)
+		})
+	}
 	if err := ctrl.Run(ctx); err != nil && err != context.Canceled {
 		log.Printf("area: %v", err)
 		os.Exit(1)
@@ -0,0 +1,132 @@
+// Load benchmark: drives work orders through fleet → area → zone → edge (simulated robots) on the in-memory
+// bus or a NATS server and prints a JSON report: dispatch latency percentiles, bus throughput, CPU and heap
+// per layer and summary propagation lag. With -baseline it compares against an earlier report and exits 1
+// when a key metric regressed by more than -max-regression.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/robotfleetos/robotfleetos/internal/bench"
+	"github.com/robotfleetos/robotfleetos/internal/topology"
+)
+
+func main() {
+	var opts bench.Options
+	topo := flag.String("topology", "2x10x20", "areas x zones-per-area x robots-per-zone, or a topology file")
+	flag.StringVar(&opts.BusURL, "bus", "memory", `"memory" or a NATS URL such as nats://localhost:4222`)
+	flag.Float64Var(&opts.Rate, "rate", 1000, "work orders per second")
+	flag.DurationVar(&opts.Duration, "duration", 0, "load phase (default 10s)")
+	flag.DurationVar(&opts.Drain, "drain", 0, "wait for in-flight orders after the load phase (default 5s)")
+	flag.IntVar(&opts.Workers, "workers", 0, "concurrent submitters (default 4)")
+	flag.DurationVar(&opts.StatusInterval, "status-interval", 0, "robot status interval (default 5s)")
+	flag.DurationVar(&opts.ZoneReport, "zone-report", 0, "zone summary interval (default 1s)")
+	flag.DurationVar(&opts.AreaReport, "area-report", 0, "area summary interval (default 2s)")
+	flag.DurationVar(&opts.TaskDuration, "task-duration", 0, "simulated task length (default 2s)")
+	out := flag.String("o", "", "write the report to this file instead of stdout")
+	baseline := flag.String("baseline", "", "compare with this earlier report")
+	maxRegression := flag.Float64("max-regression", 0.2, "allowed relative regression against -baseline")
+	verbose := flag.Bool("v", false, "keep controller logs")
+	flag.Parse()
+	opts.BusConn.ApplyEnv()
+
+	t, err := topology.Parse(*topo)
+	if err != nil {
+		log.Fatalf("bench: %v", err)
+	}
+	opts.Topology = t
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sig
+		cancel()
+	}()
+
+	logOut := log.Writer()
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+	report, err := bench.Run(ctx, opts)
+	log.SetOutput(logOut)
+	if err != nil {
+		log.Fatalf("bench: %v", err)
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.Create(*out); err != nil {
+			log.Fatalf("bench: %v", err)
+		}
+		defer w.Close()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatalf("bench: %v", err)
+	}
+
+	if *baseline != "" {
+		regressions, err := compare(*baseline, report, *maxRegression)
+		if err != nil {
+			log.Fatalf("bench: %v", err)
+		}
+		for _, r := range regressions {
+			fmt.Fprintln(os.Stderr, "regression:", r)
+		}
+		if len(regressions) > 0 {
+			w.Close()
+			os.Exit(1)
+		}
+	}
+}
+
+// compare checks the metrics worth gating on: lower is better except for the achieved order rate.
+func compare(path string, cur *bench.Report, maxRegression float64) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var base bench.Report
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if base.Version != cur.Version {
+		return nil, fmt.Errorf("%s: report version %d, want %d", path, base.Version, cur.Version)
+	}
+	if base.Bus != cur.Bus || base.TargetRate != cur.TargetRate || base.Areas != cur.Areas || base.Zones != cur.Zones || base.Robots != cur.Robots {
+		return nil, fmt.Errorf("%s: baseline ran with a different bus, rate or topology", path)
+	}
+	var out []string
+	check := func(name string, was, now float64, higherIsBetter bool) {
+		if was <= 0 {
+			return
+		}
+		change := (now - was) / was
+		if higherIsBetter {
+			change = -change
+		}
+		if change > maxRegression {
+			out = append(out, fmt.Sprintf("%s %.3f -> %.3f (%+.0f%%)", name, was, now, 100*(now-was)/was))
+		}
+	}
+	check("orders_per_sec", base.AchievedRate, cur.AchievedRate, true)
+	check("dispatch_latency_ms.p50", base.DispatchLatency.P50, cur.DispatchLatency.P50, false)
+	check("dispatch_latency_ms.p99", base.DispatchLatency.P99, cur.DispatchLatency.P99, false)
+	check("cpu_usec_per_order", base.CPUPerOrderUsec, cur.CPUPerOrderUsec, false)
+	check("alloc_bytes_per_order", base.AllocBytesPerOrd, cur.AllocBytesPerOrd, false)
+	if cur.OrdersLost > base.OrdersLost {
+		out = append(out, fmt.Sprintf("orders_lost %d -> %d", base.OrdersLost, cur.OrdersLost))
+	}
+	return out, nil
+}
@@ -8,6 +8,11 @@ import (
 	"syscall"
 
 	"github.com/robotfleetos/robotfleetos/internal/cmms"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/client"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
 func main() {
@@ -15,10 +20,38 @@ func main() {
 	if err != nil {
 		log.Fatalf("cmms: load config: %v", err)
 	}
-	store := cmms.NewStore()
-	fleet := cmms.NewFleetClient(cfg.Fleet.APIURL)
+	db, err := storage.Open(cfg.Storage)
+	if err != nil {
+		log.Fatalf("cmms: %v", err)
+	}
+	if db != nil {
+		defer db.Close()
+		log.Printf("cmms: storing data in %s", cfg.Storage.Path)
+	}
+	store, err := cmms.NewStore(db)
+	if err != nil {
+		log.Fatalf("cmms: %v", err)
+	}
+	fleet := client.NewFleet(cfg.Fleet.APIURL)
+	fleet.Token = cfg.Fleet.Token
 	svc := cmms.NewService(store, fleet)
-	server := &cmms.Server{Service: svc}
+	authn, err := auth.New(cfg.Auth, cmms.AuthRules)
+	if err != nil {
+		log.Fatalf("cmms: %v", err)
+	}
+	if authn != nil {
+		log.Printf("cmms: API authentication on")
+	}
+	auditLog, err := audit.Open("cmms", cfg.Audit)
+	if err != nil {
+		log.Fatalf("cmms: %v", err)
+	}
+	defer auditLog.Close()
+	idem, err := idempotency.New(cfg.Idempotency)
+	if err != nil {
+		log.Fatalf("cmms: %v", err)
+	}
+	server := &cmms.Server{Service: svc, Auth: authn, Audit: auditLog, Idempotency: idem}
 	httpSrv := &http.Server{Addr: cfg.CMMS.Listen, Handler: server.Handler()}
 	go func() {
 		log.Printf("cmms: API on http://localhost%s", cfg.CMMS.Listen)
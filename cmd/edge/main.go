@@ -1,4 +1,4 @@
-// Edge layer: one process per robot or small cell.
+// Edge layer: one process per robot, or a cell gateway serving many robots (edge.robots / edge.robots_csv).
 // Subscribes to robot commands, executes them (stub or real protocol), publishes robot status to the zone.
 package main
 
@@ -12,6 +12,7 @@ import (
 
 	"github.com/robotfleetos/robotfleetos/internal/edge"
 	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
 	"github.com/robotfleetos/robotfleetos/pkg/messaging"
 )
 
@@ -26,32 +27,86 @@ func main() {
 		cancel()
 	}()
 
-	cfg, err := edge.LoadConfig("")
+	path := os.Getenv("EDGE_CONFIG")
+	cfg, err := edge.LoadConfig(path)
 	if err != nil {
 		log.Fatalf("edge: load config: %v", err)
 	}
+	statusInterval, err := cfg.Edge.StatusPeriod()
+	if err != nil {
+		log.Fatalf("edge: %v", err)
+	}
+	// watch reloads the config on SIGHUP (or CONFIG_WATCH) and applies the status interval to r; a file
+	// that fails validation is logged and the running config kept.
+	watch := func(r interface{ SetStatusInterval(time.Duration) }) {
+		if path == "" {
+			return
+		}
+		go config.Watch(ctx, path, config.PollInterval(), func() {
+			next, err := edge.LoadConfig(path)
+			if err != nil {
+				log.Printf("edge: reload: %v; keeping the running config", err)
+				return
+			}
+			cfg = edge.Reload(r, cfg, next)
+		})
+	}
 
 	busURL := os.Getenv("MESSAGING_URL")
 	if busURL == "" {
 		busURL = cfg.Messaging.Broker
 	}
-	bus, err := messaging.NewBusFromURL(busURL)
+	bus, err := messaging.NewBusFromURL(busURL, cfg.Messaging.ConnOptions)
 	if err != nil {
 		log.Fatalf("edge: connect to message bus: %v", err)
 	}
 	statusPub := messaging.NewRobotStatusPublisher(bus)
+	safetyPub := messaging.NewSafetyEventPublisher(bus)
+	resultPub := messaging.NewTaskResultPublisher(bus)
+	verifier, err := cfg.Edge.CommandVerification.Verifier()
+	if err != nil {
+		log.Fatalf("edge: %v", err)
+	}
+	if verifier != nil {
+		verifier.SetSecurityEventPublisher(messaging.NewSecurityEventPublisher(bus))
+	}
+
+	if cfg.Edge.CellMode() {
+		cell, err := edge.NewCell(cfg.Edge, statusPub, bus, statusInterval)
+		if err != nil {
+			log.Fatalf("edge: %v", err)
+		}
+		cell.SetSafetyEventPublisher(safetyPub)
+		cell.SetTaskResultPublisher(resultPub)
+		cell.SetCommandVerifier(verifier)
+		log.Printf("edge: starting cell gateway with %d robots (protocol %s)", cell.Len(), cfg.Edge.Protocol)
+		watch(cell)
+		if err := cell.Run(ctx); err != nil && err != context.Canceled {
+			log.Printf("edge: %v", err)
+			os.Exit(1)
+		}
+		log.Println("edge: shutdown")
+		return
+	}
 
+	driver, err := edge.NewDriver(cfg.Edge.Protocol, cfg.Edge.DriverConfig())
+	if err != nil {
+		log.Fatalf("edge: %v", err)
+	}
 	gw := edge.NewGateway(
 		api.RobotID(cfg.Edge.RobotID),
 		api.ZoneID(cfg.Edge.ZoneID),
-		cfg.Edge.Protocol,
+		driver,
 		statusPub,
 		bus,
-		2*time.Second,
-		2*time.Second,
+		statusInterval,
 	)
+	gw.SetSafetyEventPublisher(safetyPub)
+	gw.SetTaskResultPublisher(resultPub)
+	gw.SetCommandVerifier(verifier)
 
 	log.Printf("edge: starting %s (zone %s, protocol %s)", cfg.Edge.RobotID, cfg.Edge.ZoneID, cfg.Edge.Protocol)
+	watch(gw)
 	if err := gw.Run(ctx); err != nil && err != context.Canceled {
 		log.Printf("edge: %v", err)
 		os.Exit(1)
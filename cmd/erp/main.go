@@ -8,6 +8,11 @@ import (
 	"syscall"
 
 	"github.com/robotfleetos/robotfleetos/internal/erp"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/client"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
 func main() {
@@ -15,10 +20,38 @@ func main() {
 	if err != nil {
 		log.Fatalf("erp: load config: %v", err)
 	}
-	store := erp.NewStore()
-	mes := erp.NewMESClient(cfg.MES.APIURL)
+	db, err := storage.Open(cfg.Storage)
+	if err != nil {
+		log.Fatalf("erp: %v", err)
+	}
+	if db != nil {
+		defer db.Close()
+		log.Printf("erp: storing data in %s", cfg.Storage.Path)
+	}
+	store, err := erp.NewStore(db)
+	if err != nil {
+		log.Fatalf("erp: %v", err)
+	}
+	mes := client.NewMES(cfg.MES.APIURL)
+	mes.Token = cfg.MES.Token
 	svc := erp.NewService(store, mes, cfg.MES.DefaultAreaID)
-	server := &erp.Server{Service: svc}
+	authn, err := auth.New(cfg.Auth, erp.AuthRules)
+	if err != nil {
+		log.Fatalf("erp: %v", err)
+	}
+	if authn != nil {
+		log.Printf("erp: API authentication on")
+	}
+	auditLog, err := audit.Open("erp", cfg.Audit)
+	if err != nil {
+		log.Fatalf("erp: %v", err)
+	}
+	defer auditLog.Close()
+	idem, err := idempotency.New(cfg.Idempotency)
+	if err != nil {
+		log.Fatalf("erp: %v", err)
+	}
+	server := &erp.Server{Service: svc, Auth: authn, Audit: auditLog, Idempotency: idem}
 	httpSrv := &http.Server{Addr: cfg.ERP.Listen, Handler: server.Handler()}
 	go func() {
 		log.Printf("erp: API on http://localhost%s", cfg.ERP.Listen)
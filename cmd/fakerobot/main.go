@@ -0,0 +1,197 @@
+// Fake robot: a local server speaking an edge driver protocol, for testing the edge without hardware.
+// -protocol tcp-json (default): start cmd/edge with robot_protocol: tcp-json and robot_address pointing at it.
+// -protocol opcua: an in-process OPC UA server laid out as configs/opcua/stub-model.yaml; start cmd/edge with
+// robot_protocol: opcua, robot_address: opc.tcp://localhost:4840 and driver_options.node_map set to that file.
+// -protocol mqtt: an embedded MQTT broker simulating -robots robots per configs/mqtt/stub-model.yaml; start cmd/edge
+// as a cell gateway with robot_protocol: mqtt, robot_address: tcp://localhost:1883 and driver_options.mapping.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/internal/edge"
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// robot is the simulated robot state shared by all connections.
+type robot struct {
+	mu       sync.Mutex
+	x, y     float64
+	battery  float64
+	charging bool
+	modelID  string
+	firmware string
+	stopped  bool
+}
+
+func main() {
+	protocol := flag.String("protocol", "tcp-json", "robot protocol: tcp-json, opcua or mqtt")
+	listen := flag.String("listen", "", "listen address (default :9000 for tcp-json, localhost:4840 for opcua, :1883 for mqtt)")
+	robotIDs := flag.String("robots", "robot-1", "mqtt only: robot IDs to simulate, comma-separated, or a count N for robot-1..robot-N")
+	taskDuration := flag.Duration("task", 3*time.Second, "how long each command takes")
+	drainPerTask := flag.Float64("drain", 2, "battery percent used per command")
+	modelID := flag.String("model", "stub-model", "model_id reported in telemetry")
+	flag.Parse()
+
+	r := &robot{battery: 100, modelID: *modelID, firmware: "1.0.0"}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+
+	switch *protocol {
+	case "tcp-json":
+		if *listen == "" {
+			*listen = ":9000"
+		}
+		serveTCP(r, *listen, sig, *taskDuration, *drainPerTask)
+	case "opcua":
+		if *listen == "" {
+			*listen = "localhost:4840"
+		}
+		serveOPCUA(r, *listen, sig, *taskDuration, *drainPerTask)
+	case "mqtt":
+		if *listen == "" {
+			*listen = ":1883"
+		}
+		serveMQTT(parseRobotIDs(*robotIDs), *modelID, *listen, sig, *taskDuration, *drainPerTask)
+	default:
+		log.Fatalf("fakerobot: unsupported protocol %q (tcp-json, opcua, mqtt)", *protocol)
+	}
+}
+
+func serveTCP(r *robot, listen string, sig <-chan os.Signal, taskDuration time.Duration, drainPerTask float64) {
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		log.Fatalf("fakerobot: %v", err)
+	}
+	log.Printf("fakerobot: listening on %s (model %s)", ln.Addr(), r.modelID)
+	go func() {
+		<-sig
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			log.Println("fakerobot: shutdown")
+			return
+		}
+		log.Printf("fakerobot: edge connected from %s", conn.RemoteAddr())
+		go serve(conn, r, taskDuration, drainPerTask)
+	}
+}
+
+func serve(conn net.Conn, r *robot, taskDuration time.Duration, drain float64) {
+	defer conn.Close()
+	var writeMu sync.Mutex
+	send := func(msg edge.TCPMessage) {
+		data, _ := json.Marshal(msg)
+		writeMu.Lock()
+		_, _ = conn.Write(append(data, '\n'))
+		writeMu.Unlock()
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				send(edge.TCPMessage{Type: edge.TCPMessageTelemetry, Telemetry: r.telemetry()})
+			}
+		}
+	}()
+
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		var msg edge.TCPMessage
+		if err := json.Unmarshal(sc.Bytes(), &msg); err != nil {
+			log.Printf("fakerobot: invalid message: %v", err)
+			continue
+		}
+		switch msg.Type {
+		case edge.TCPMessageCommand:
+			go func(msg edge.TCPMessage) {
+				log.Printf("fakerobot: %s %s", msg.Command, msg.ID)
+				ok, reason := r.runCommand(taskDuration, drain)
+				send(edge.TCPMessage{Type: edge.TCPMessageResult, ID: msg.ID, OK: ok, Error: reason})
+			}(msg)
+		case edge.TCPMessageEStop:
+			r.mu.Lock()
+			r.stopped = true
+			r.mu.Unlock()
+			log.Println("fakerobot: E-STOP")
+			send(edge.TCPMessage{Type: edge.TCPMessageResult, ID: msg.ID, OK: true})
+		case edge.TCPMessageFirmware:
+			go func(msg edge.TCPMessage) {
+				var p api.FirmwareUpdatePayload
+				if err := json.Unmarshal(msg.Payload, &p); err != nil {
+					send(edge.TCPMessage{Type: edge.TCPMessageResult, ID: msg.ID, Error: err.Error()})
+					return
+				}
+				for _, st := range []string{api.FirmwareStatusDownloading, api.FirmwareStatusApplying} {
+					send(edge.TCPMessage{Type: edge.TCPMessageFirmwareStatus, ID: msg.ID, Status: st})
+					time.Sleep(2 * time.Second)
+				}
+				r.mu.Lock()
+				r.firmware = p.Version
+				r.mu.Unlock()
+				log.Printf("fakerobot: firmware -> %s", p.Version)
+				send(edge.TCPMessage{Type: edge.TCPMessageResult, ID: msg.ID, OK: true})
+			}(msg)
+		}
+	}
+	log.Printf("fakerobot: edge %s disconnected", conn.RemoteAddr())
+}
+
+// runCommand moves the robot a step and drains the battery; fails while e-stopped or out of battery.
+func (r *robot) runCommand(d time.Duration, drain float64) (bool, string) {
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return false, "robot is e-stopped"
+	}
+	if r.battery < drain {
+		r.mu.Unlock()
+		return false, "battery depleted"
+	}
+	r.charging = false
+	r.mu.Unlock()
+	time.Sleep(d)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.x += 1
+	r.y += 0.5
+	r.battery -= drain
+	return true, ""
+}
+
+func (r *robot) telemetry() *edge.Telemetry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	battery := r.battery
+	t := &edge.Telemetry{
+		Position:        fmt.Sprintf("%.1f,%.1f", r.x, r.y),
+		Battery:         &battery,
+		Charging:        r.charging,
+		ModelID:         r.modelID,
+		FirmwareVersion: r.firmware,
+	}
+	if r.stopped {
+		t.Fault = "estop"
+	}
+	return t
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+// Topic layout of configs/mqtt/stub-model.yaml.
+const mqttTopicPrefix = "fleet/robots/"
+
+// serveMQTT runs an embedded MQTT broker and simulates every robot in robotIDs on it, speaking the
+// configs/mqtt/stub-model.yaml mapping. Point a cell gateway (robot_protocol: mqtt) at the same broker.
+func serveMQTT(robotIDs []string, modelID, listen string, sig <-chan os.Signal, taskDuration time.Duration, drain float64) {
+	srv := mqtt.New(&mqtt.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+	})
+	_ = srv.AddHook(new(auth.AllowHook), nil)
+	if err := srv.AddListener(listeners.NewTCP(listeners.Config{ID: "tcp", Address: listen})); err != nil {
+		log.Fatalf("fakerobot: mqtt listener: %v", err)
+	}
+
+	robots := make(map[string]*robot, len(robotIDs))
+	for _, id := range robotIDs {
+		robots[id] = &robot{battery: 100, modelID: modelID, firmware: "1.0.0"}
+	}
+	publish := func(id, suffix string, v interface{}) {
+		data, _ := json.Marshal(v)
+		if err := srv.Publish(mqttTopicPrefix+id+"/"+suffix, data, false, 1); err != nil {
+			log.Printf("fakerobot: publish: %v", err)
+		}
+	}
+	publishState := func(id string, r *robot) {
+		t := r.telemetry()
+		r.mu.Lock()
+		x, y := r.x, r.y
+		r.mu.Unlock()
+		state := map[string]interface{}{
+			"pose":     map[string]float64{"x": x, "y": y},
+			"battery":  map[string]interface{}{"soc": t.Battery, "charging": t.Charging},
+			"firmware": t.FirmwareVersion,
+			"error":    nil,
+		}
+		if t.Fault != "" {
+			state["error"] = t.Fault
+		}
+		publish(id, "state", state)
+	}
+
+	// handler routes "fleet/robots/<id>/<kind>" to the robot with that ID.
+	handler := func(kind string, fn func(id string, r *robot, body map[string]interface{})) mqtt.InlineSubFn {
+		return func(_ *mqtt.Client, _ packets.Subscription, pk packets.Packet) {
+			id := strings.TrimSuffix(strings.TrimPrefix(pk.TopicName, mqttTopicPrefix), "/"+kind)
+			r := robots[id]
+			if r == nil {
+				return
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(pk.Payload, &body); err != nil {
+				log.Printf("fakerobot: %s: invalid %s: %v", id, kind, err)
+				return
+			}
+			fn(id, r, body)
+		}
+	}
+	subs := map[string]mqtt.InlineSubFn{
+		"cmd": handler("cmd", func(id string, r *robot, body map[string]interface{}) {
+			go func() {
+				jobID := fmt.Sprint(body["job_id"])
+				log.Printf("fakerobot: %s %v %s", id, body["action"], jobID)
+				res := map[string]interface{}{"job_id": jobID, "status": "done"}
+				if ok, reason := r.runCommand(taskDuration, drain); !ok {
+					res["status"], res["message"] = "failed", reason
+				}
+				publishState(id, r)
+				publish(id, "result", res)
+			}()
+		}),
+		"estop": handler("estop", func(id string, r *robot, _ map[string]interface{}) {
+			r.mu.Lock()
+			r.stopped = true
+			r.mu.Unlock()
+			log.Printf("fakerobot: %s E-STOP", id)
+			publishState(id, r)
+		}),
+		"firmware": handler("firmware", func(id string, r *robot, body map[string]interface{}) {
+			go func() {
+				for _, st := range []string{"DOWNLOADING", "INSTALLING"} {
+					publish(id, "firmware/status", map[string]string{"state": st})
+					time.Sleep(2 * time.Second)
+				}
+				version := fmt.Sprint(body["version"])
+				r.mu.Lock()
+				r.firmware = version
+				r.mu.Unlock()
+				log.Printf("fakerobot: %s firmware -> %s", id, version)
+				publishState(id, r)
+				publish(id, "firmware/status", map[string]string{"state": "OK"})
+			}()
+		}),
+	}
+	i := 1
+	for kind, fn := range subs {
+		if err := srv.Subscribe(mqttTopicPrefix+"+/"+kind, i, fn); err != nil {
+			log.Fatalf("fakerobot: subscribe %s: %v", kind, err)
+		}
+		i++
+	}
+
+	go func() {
+		if err := srv.Serve(); err != nil {
+			log.Fatalf("fakerobot: mqtt broker: %v", err)
+		}
+	}()
+	defer srv.Close()
+	log.Printf("fakerobot: MQTT broker on %s simulating %d robots (model %s)", listen, len(robots), modelID)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sig:
+			log.Println("fakerobot: shutdown")
+			return
+		case <-ticker.C:
+			for id, r := range robots {
+				publishState(id, r)
+			}
+		}
+	}
+}
+
+// parseRobotIDs accepts "robot-1,robot-7" or a count N meaning robot-1..robot-N.
+func parseRobotIDs(s string) []string {
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		ids := make([]string, n)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("robot-%d", i+1)
+		}
+		return ids
+	}
+	var ids []string
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gopcua/opcua/server"
+	"github.com/gopcua/opcua/ua"
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// serveOPCUA exposes the robot as an OPC UA server with one map namespace (ns=1), matching
+// configs/opcua/stub-model.yaml. Commands start when the edge writes true to Command.Start,
+// firmware when it writes true to Firmware.Start; Robot.EStop latches the e-stop.
+func serveOPCUA(r *robot, listen string, sig <-chan os.Signal, taskDuration time.Duration, drain float64) {
+	host, portStr, err := net.SplitHostPort(listen)
+	if err != nil {
+		log.Fatalf("fakerobot: -listen: %v", err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		log.Fatalf("fakerobot: -listen port: %v", err)
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	srv := server.New(
+		server.EnableSecurity("None", ua.MessageSecurityModeNone),
+		server.EnableAuthMode(ua.UserTokenTypeAnonymous),
+		server.EndPoint(host, port),
+	)
+	ns := server.NewMapNamespace(srv, "RobotFleetOS.FakeRobot")
+	for k, v := range map[string]any{
+		"Robot.Battery": r.battery, "Robot.PositionX": 0.0, "Robot.PositionY": 0.0,
+		"Robot.Charging": false, "Robot.Fault": "", "Robot.Busy": false, "Robot.EStop": false,
+		"Robot.FirmwareVersion": r.firmware, "Robot.FirmwareStatus": api.FirmwareStatusIdle,
+		"Command.ID": "", "Command.Type": "", "Command.Payload": "", "Command.Start": false,
+		"Firmware.Version": "", "Firmware.URL": "", "Firmware.Checksum": "", "Firmware.Start": false,
+	} {
+		ns.Data[k] = v
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := srv.Start(ctx); err != nil {
+		log.Fatalf("fakerobot: opcua server: %v", err)
+	}
+	defer srv.Close()
+	log.Printf("fakerobot: OPC UA server on %v (namespace %d, model %s)", srv.URLs(), ns.ID(), r.modelID)
+
+	publish := func() {
+		t := r.telemetry()
+		r.mu.Lock()
+		x, y := r.x, r.y
+		r.mu.Unlock()
+		ns.SetValue("Robot.Battery", *t.Battery)
+		ns.SetValue("Robot.PositionX", x)
+		ns.SetValue("Robot.PositionY", y)
+		ns.SetValue("Robot.Charging", t.Charging)
+		ns.SetValue("Robot.Fault", t.Fault)
+		ns.SetValue("Robot.FirmwareVersion", t.FirmwareVersion)
+	}
+	publish()
+
+	// Writes arrive on ExternalNotification but are dropped when nobody is receiving, so the start
+	// flags are also polled.
+	poll := time.NewTicker(200 * time.Millisecond)
+	defer poll.Stop()
+	telemetry := time.NewTicker(time.Second)
+	defer telemetry.Stop()
+	for {
+		select {
+		case <-sig:
+			log.Println("fakerobot: shutdown")
+			return
+		case <-telemetry.C:
+			publish()
+			continue
+		case <-ns.ExternalNotification:
+		case <-poll.C:
+		}
+
+		if b, _ := ns.GetValue("Robot.EStop").(bool); b {
+			r.mu.Lock()
+			if !r.stopped {
+				log.Println("fakerobot: E-STOP")
+			}
+			r.stopped = true
+			r.mu.Unlock()
+		}
+		if b, _ := ns.GetValue("Command.Start").(bool); b {
+			ns.SetValue("Command.Start", false)
+			ns.SetValue("Robot.Busy", true)
+			go func(id, typ string) {
+				log.Printf("fakerobot: %s %s", typ, id)
+				if ok, reason := r.runCommand(taskDuration, drain); !ok {
+					log.Printf("fakerobot: %s failed: %s", id, reason)
+				}
+				publish()
+				ns.SetValue("Robot.Busy", false)
+			}(str(ns.GetValue("Command.ID")), str(ns.GetValue("Command.Type")))
+		}
+		if b, _ := ns.GetValue("Firmware.Start").(bool); b {
+			ns.SetValue("Firmware.Start", false)
+			go func(version string) {
+				for _, st := range []string{api.FirmwareStatusDownloading, api.FirmwareStatusApplying} {
+					ns.SetValue("Robot.FirmwareStatus", st)
+					time.Sleep(2 * time.Second)
+				}
+				r.mu.Lock()
+				r.firmware = version
+				r.mu.Unlock()
+				log.Printf("fakerobot: firmware -> %s", version)
+				publish()
+				ns.SetValue("Robot.FirmwareStatus", api.FirmwareStatusSuccess)
+			}(str(ns.GetValue("Firmware.Version")))
+		}
+	}
+}
+
+func str(v any) string {
+	s, _ := v.(string)
+	return s
+}
@@ -11,7 +11,12 @@ import (
 	"syscall"
 
 	"github.com/robotfleetos/robotfleetos/internal/fleet"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/facility"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
 	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+	"github.com/robotfleetos/robotfleetos/pkg/robotmodel"
 	"github.com/robotfleetos/robotfleetos/pkg/state"
 )
 
@@ -39,7 +44,7 @@ func main() {
 	}
 
 	busURL := getBrokerURL(cfg)
-	bus, busErr := messaging.NewBusFromURL(busURL)
+	bus, busErr := messaging.NewBusFromURL(busURL, cfg.Messaging.ConnOptions)
 	if busErr != nil {
 		log.Fatalf("fleet: connect to message bus: %v", busErr)
 	}
@@ -54,7 +59,49 @@ func main() {
 		_ = globalState.Run(ctx, bus)
 	}()
 
-	server := &fleet.Server{Scheduler: scheduler, State: globalState}
+	// Edge safety events (e-stops, protective stops, rejected commands) for GET /safety/events.
+	safetyLog := fleet.NewSafetyLog(0)
+	go func() {
+		_ = safetyLog.Run(ctx, bus)
+	}()
+
+	// Robot commands edges rejected as unsigned, forged, stale or replayed, for GET /security/events.
+	securityLog := fleet.NewSecurityLog(0)
+	go func() {
+		_ = securityLog.Run(ctx, bus)
+	}()
+
+	var facilityMap *facility.Map
+	if cfg.Fleet.FacilityMap != "" {
+		if facilityMap, err = facility.Load(cfg.Fleet.FacilityMap); err != nil {
+			log.Fatalf("fleet: %v", err)
+		}
+	}
+
+	var models *robotmodel.Registry
+	if cfg.Fleet.ModelRegistry != "" {
+		if models, err = robotmodel.Load(cfg.Fleet.ModelRegistry); err != nil {
+			log.Fatalf("fleet: %v", err)
+		}
+	}
+
+	authn, err := auth.New(cfg.Auth, fleet.AuthRules)
+	if err != nil {
+		log.Fatalf("fleet: %v", err)
+	}
+	if authn != nil {
+		log.Printf("fleet: API authentication on")
+	}
+	auditLog, err := audit.Open("fleet", cfg.Audit)
+	if err != nil {
+		log.Fatalf("fleet: %v", err)
+	}
+	defer auditLog.Close()
+	idem, err := idempotency.New(cfg.Idempotency)
+	if err != nil {
+		log.Fatalf("fleet: %v", err)
+	}
+	server := &fleet.Server{Scheduler: scheduler, State: globalState, Safety: safetyLog, Security: securityLog, Map: fleet.NewFacilityMap(facilityMap), Models: models, Auth: authn, Audit: auditLog, Idempotency: idem}
 
 	srv := &http.Server{Addr: cfg.Fleet.APIListen, Handler: server.Handler()}
 	go func() {
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+)
+
+// Config is the fleetctl config file: one context per factory or environment.
+type Config struct {
+	CurrentContext string              `yaml:"current_context"`
+	Contexts       map[string]*Context `yaml:"contexts"`
+}
+
+// Context holds the API base URLs of one factory and the credential to call them with.
+type Context struct {
+	Fleet        string `yaml:"fleet,omitempty"`
+	MES          string `yaml:"mes,omitempty"`
+	ERP          string `yaml:"erp,omitempty"`
+	WMS          string `yaml:"wms,omitempty"`
+	CMMS         string `yaml:"cmms,omitempty"`
+	QMS          string `yaml:"qms,omitempty"`
+	PLM          string `yaml:"plm,omitempty"`
+	Traceability string `yaml:"traceability,omitempty"`
+	Token        string `yaml:"token,omitempty"` // API key or JWT, sent as a bearer token
+}
+
+// services are the APIs a context points at, in display order.
+var services = []string{"fleet", "mes", "erp", "wms", "cmms", "qms", "plm", "traceability"}
+
+// localContext is used when no context is configured: every service on localhost at its default port.
+var localContext = Context{
+	Fleet:        "http://localhost:8080",
+	MES:          "http://localhost:8081",
+	WMS:          "http://localhost:8082",
+	Traceability: "http://localhost:8083",
+	QMS:          "http://localhost:8084",
+	CMMS:         "http://localhost:8085",
+	PLM:          "http://localhost:8086",
+	ERP:          "http://localhost:8087",
+}
+
+// url returns a pointer to the base URL of service, or nil if there is no such service.
+func (c *Context) url(service string) *string {
+	switch service {
+	case "fleet":
+		return &c.Fleet
+	case "mes":
+		return &c.MES
+	case "erp":
+		return &c.ERP
+	case "wms":
+		return &c.WMS
+	case "cmms":
+		return &c.CMMS
+	case "qms":
+		return &c.QMS
+	case "plm":
+		return &c.PLM
+	case "traceability":
+		return &c.Traceability
+	}
+	return nil
+}
+
+// URL returns the base URL of service.
+func (c *Context) URL(service string) string {
+	if u := c.url(service); u != nil {
+		return *u
+	}
+	return ""
+}
+
+// configPath returns the config file path: env FLEETCTL_CONFIG, else fleetctl.yaml in the user config dir.
+func configPath() (string, error) {
+	if p := os.Getenv("FLEETCTL_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "robotfleetos", "fleetctl.yaml"), nil
+}
+
+// loadConfig reads the config file; a missing file is an empty config.
+func loadConfig() (*Config, string, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, "", err
+	}
+	cfg := &Config{}
+	if err := config.Load(path, cfg); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, "", err
+	}
+	if cfg.Contexts == nil {
+		cfg.Contexts = make(map[string]*Context)
+	}
+	return cfg, path, nil
+}
+
+// save writes the config to path, readable only by the user since contexts hold tokens.
+func (c *Config) save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// current returns the context to use: name if set, else the current context, else localContext.
+func (c *Config) current(name string) (string, *Context, error) {
+	if name == "" {
+		name = c.CurrentContext
+	}
+	if name == "" {
+		ctx := localContext
+		return "local", &ctx, nil
+	}
+	ctx, ok := c.Contexts[name]
+	if !ok {
+		return "", nil, fmt.Errorf("no context %q (see fleetctl context list)", name)
+	}
+	return name, ctx, nil
+}
+
+func (c *Config) names() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,718 @@
+// Operator CLI for the fleet and enterprise APIs (fleet, MES, ERP, WMS, CMMS, QMS, PLM, traceability):
+// lists and describes work orders, robots, zones, firmware campaigns, production orders, holds and the
+// rest, submits and cancels them, follows their progress and triggers firmware campaigns. A context holds
+// one factory's API URLs and token, e.g.
+//
+//	fleetctl context set plant-a -fleet http://fleet.plant-a:8080 -mes http://mes.plant-a:8081 -token $KEY
+//	fleetctl context use plant-a
+//	fleetctl get orders -filter status=in_progress
+//	fleetctl submit order -sku WIDGET-001 -quantity 50 -area area-1
+//	fleetctl release order ord-7
+//	fleetctl get order ord-7 -w
+//	fleetctl firmware trigger -seed-busy 10 -o json
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	sdk "github.com/robotfleetos/robotfleetos/pkg/client"
+)
+
+const usage = `usage: fleetctl [flags] COMMAND [args]
+
+Commands:
+  get RESOURCE [ID]          list RESOURCE, or show one; -w follows changes
+  describe RESOURCE ID       show one in full
+  submit work-order|order    submit a fleet work order or MES production order from flags
+  create RESOURCE -f FILE    create from a JSON file ("-" for stdin)
+  ACTION RESOURCE ID         run an action, e.g. cancel order ord-7, release hold hold-2
+  firmware trigger           start a firmware campaign
+  context list|current|use NAME|set NAME|delete NAME
+  help resources             list resources with their service, filters and actions
+
+Flags for every command:
+  -context NAME              context to use instead of the current one (env FLEETCTL_CONTEXT)
+  -o table|json|yaml         output format (default table)
+  -token TOKEN               API key or JWT instead of the context's (env FLEETCTL_TOKEN)
+
+Contexts are kept in $FLEETCTL_CONFIG or <user config dir>/robotfleetos/fleetctl.yaml. Without one,
+every service is expected on localhost at its default port.
+`
+
+// globals are the flags every command takes, and where it writes.
+type globals struct {
+	context string
+	output  string
+	token   string
+
+	stdout, stderr io.Writer
+}
+
+func (g *globals) register(fs *flag.FlagSet) {
+	fs.SetOutput(g.stderr)
+	fs.StringVar(&g.context, "context", g.context, "context to use")
+	fs.StringVar(&g.output, "o", g.output, "output format: table, json or yaml")
+	fs.StringVar(&g.token, "token", g.token, "API key or JWT")
+}
+
+// parse parses args with fs, allowing flags after positional arguments, and returns the positional ones.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return nil, err
+			}
+			return nil, flagError{err}
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return pos, nil
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
+
+// flagError is a bad flag, already reported with the usage by the flag package.
+type flagError struct{ err error }
+
+func (e flagError) Error() string { return e.err.Error() }
+
+// multiFlag is a repeatable string flag.
+type multiFlag []string
+
+func (m *multiFlag) String() string     { return strings.Join(*m, ",") }
+func (m *multiFlag) Set(s string) error { *m = append(*m, s); return nil }
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run runs the command line args and returns the exit status: 0 on success, 1 if the command failed and
+// 2 for a bad command line.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	g := &globals{context: os.Getenv("FLEETCTL_CONTEXT"), output: "table", token: os.Getenv("FLEETCTL_TOKEN"), stdout: stdout, stderr: stderr}
+	fs := flag.NewFlagSet("fleetctl", flag.ContinueOnError)
+	g.register(fs)
+	fs.Usage = func() { fmt.Fprint(fs.Output(), usage) }
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	cmd, args := fs.Arg(0), fs.Args()[1:]
+
+	var err error
+	switch cmd {
+	case "get":
+		err = runGet(ctx, g, args)
+	case "describe":
+		err = runDescribe(ctx, g, args)
+	case "submit":
+		err = runSubmit(ctx, g, args)
+	case "create":
+		err = runCreate(ctx, g, args)
+	case "firmware":
+		err = runFirmware(ctx, g, args)
+	case "context":
+		err = runContext(g, args)
+	case "help":
+		if len(args) > 0 && args[0] == "resources" {
+			printResources(g.stdout)
+		} else {
+			fmt.Fprint(g.stdout, usage)
+		}
+	default:
+		if !isAction(cmd) {
+			fmt.Fprintf(g.stderr, "fleetctl: unknown command %q\n\n%s", cmd, usage)
+			return 2
+		}
+		err = runAction(ctx, g, cmd, args)
+	}
+	var fe flagError
+	switch {
+	case err == nil, err == flag.ErrHelp:
+		return 0
+	case errors.As(err, &fe):
+		return 2
+	}
+	fmt.Fprintf(g.stderr, "fleetctl: %v\n", err)
+	return 1
+}
+
+// client calls the APIs of one context.
+type client struct {
+	name  string // context name, for errors
+	ctx   *Context
+	token string
+	apis  map[string]*sdk.Client
+}
+
+func newClient(g *globals) (*client, error) {
+	cfg, _, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	name, ctx, err := cfg.current(g.context)
+	if err != nil {
+		return nil, err
+	}
+	token := ctx.Token
+	if g.token != "" {
+		token = g.token
+	}
+	return &client{name: name, ctx: ctx, token: token, apis: make(map[string]*sdk.Client)}, nil
+}
+
+// api returns the SDK client of service.
+func (c *client) api(service string) (*sdk.Client, error) {
+	if a, ok := c.apis[service]; ok {
+		return a, nil
+	}
+	base := c.ctx.URL(service)
+	if base == "" {
+		return nil, fmt.Errorf("context %s has no %s URL (fleetctl context set %s -%s URL)", c.name, service, c.name, service)
+	}
+	a := sdk.New(service, base)
+	a.HTTPClient.Timeout = 30 * time.Second
+	a.Token = c.token
+	c.apis[service] = a
+	return a, nil
+}
+
+// call sends body (nil, raw JSON bytes or a value to encode) to service at path and decodes the JSON
+// response into out, if not nil, keeping numbers as json.Number. Responses other than 2xx are errors
+// carrying the service's message.
+func (c *client) call(ctx context.Context, method, service, path string, body, out interface{}) error {
+	a, err := c.api(service)
+	if err != nil {
+		return err
+	}
+	if b, ok := body.([]byte); ok {
+		body = json.RawMessage(b)
+	}
+	var data json.RawMessage
+	if err := a.Do(ctx, method, path, body, &data); err != nil {
+		return err
+	}
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(out); err != nil {
+		return fmt.Errorf("%s %s: bad response: %w", service, path, err)
+	}
+	return nil
+}
+
+// list returns the items of res, filtered by the name=value query filters.
+func (c *client) list(ctx context.Context, res *resource, filters []string) ([]map[string]interface{}, error) {
+	q := url.Values{}
+	if res.query != "" {
+		q, _ = url.ParseQuery(res.query)
+	}
+	for _, f := range filters {
+		k, v, ok := strings.Cut(f, "=")
+		if !ok || !contains(res.filters, k) {
+			return nil, fmt.Errorf("%s: bad filter %q (want name=value, name one of: %s)", res.name, f, strings.Join(res.filters, ", "))
+		}
+		q.Set(k, v)
+	}
+	path := res.path
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var resp map[string]json.RawMessage
+	if err := c.call(ctx, http.MethodGet, res.service, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	var items []map[string]interface{}
+	if raw, ok := resp[res.list]; ok {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&items); err != nil {
+			return nil, fmt.Errorf("%s: bad response: %w", res.name, err)
+		}
+	}
+	if res.match != nil {
+		kept := items[:0]
+		for _, item := range items {
+			if res.match(item) {
+				kept = append(kept, item)
+			}
+		}
+		items = kept
+	}
+	return items, nil
+}
+
+// get returns one item of res by ID.
+func (c *client) get(ctx context.Context, res *resource, id string) (map[string]interface{}, error) {
+	if res.byID {
+		var item map[string]interface{}
+		err := c.call(ctx, http.MethodGet, res.service, res.path+"/"+url.PathEscape(id), nil, &item)
+		return item, err
+	}
+	items, err := c.list(ctx, res, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if res.id(item) == id {
+			return item, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %s not found", res.name, id)
+}
+
+func runGet(ctx context.Context, g *globals, args []string) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	g.register(fs)
+	var filters multiFlag
+	fs.Var(&filters, "filter", "list filter name=value, repeatable (see fleetctl help resources)")
+	watch := fs.Bool("w", false, "keep polling and print changes; with an ID, until it is finished")
+	interval := fs.Duration("interval", 2*time.Second, "poll interval for -w")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) < 1 || len(pos) > 2 {
+		return fmt.Errorf("usage: fleetctl get RESOURCE [ID] [-filter name=value] [-w]")
+	}
+	res, err := lookup(pos[0])
+	if err != nil {
+		return err
+	}
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+	if len(pos) == 2 {
+		return follow(ctx, g, c, res, pos[1], *watch, *interval)
+	}
+	var last []map[string]interface{}
+	for first := true; ; first = false {
+		items, err := c.list(ctx, res, filters)
+		if err != nil {
+			return err
+		}
+		if first || !reflect.DeepEqual(items, last) {
+			if !first {
+				fmt.Fprintln(g.stdout)
+			}
+			if err := printItems(g.stdout, g.output, res.columns, items); err != nil {
+				return err
+			}
+			last = items
+		}
+		if !*watch || !sleep(ctx, *interval) {
+			return nil
+		}
+	}
+}
+
+// finished are the statuses after which an order, task or work order no longer changes.
+var finished = []string{"completed", "cancelled", "closed", "implemented", "submitted"}
+
+// isFinished reports whether item has a finished status or, for items without one such as holds, has
+// been released.
+func isFinished(item map[string]interface{}) bool {
+	if status, ok := item["status"].(string); ok {
+		return contains(finished, status)
+	}
+	return item["released_at"] != nil
+}
+
+// follow prints one item, and with watch keeps printing it as it changes until it is finished (or, for a
+// hold, released).
+func follow(ctx context.Context, g *globals, c *client, res *resource, id string, watch bool, interval time.Duration) error {
+	// Rows are flushed one at a time as they change, so columns get a fixed minimum width to stay aligned.
+	tw := tabwriter.NewWriter(g.stdout, 16, 0, 2, ' ', 0)
+	var last map[string]interface{}
+	for first := true; ; first = false {
+		item, err := c.get(ctx, res, id)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(item, last) {
+			if g.output == "table" {
+				if first {
+					fmt.Fprintln(tw, header(res.columns))
+				}
+				fmt.Fprintln(tw, row(res.columns, item))
+				err = tw.Flush()
+			} else {
+				err = printValue(g.stdout, g.output, item)
+			}
+			if err != nil {
+				return err
+			}
+			last = item
+		}
+		if !watch || isFinished(item) || !sleep(ctx, interval) {
+			return nil
+		}
+	}
+}
+
+func runDescribe(ctx context.Context, g *globals, args []string) error {
+	fs := flag.NewFlagSet("describe", flag.ContinueOnError)
+	g.register(fs)
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 2 {
+		return fmt.Errorf("usage: fleetctl describe RESOURCE ID")
+	}
+	res, err := lookup(pos[0])
+	if err != nil {
+		return err
+	}
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+	item, err := c.get(ctx, res, pos[1])
+	if err != nil {
+		return err
+	}
+	return printValue(g.stdout, g.output, item)
+}
+
+func runSubmit(ctx context.Context, g *globals, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: fleetctl submit work-order|order [flags]")
+	}
+	what, args := args[0], args[1:]
+	fs := flag.NewFlagSet("submit "+what, flag.ContinueOnError)
+	g.register(fs)
+	area := fs.String("area", "area-1", "area ID")
+	priority := fs.Int("priority", 1, "priority")
+	var body interface{}
+	var service, path string
+	switch what {
+	case "work-order", "wo":
+		payload := fs.String("payload", "", "payload (JSON or text) the area hands to its zones")
+		deadline := fs.String("deadline", "", "deadline, RFC3339")
+		if _, err := parse(fs, args); err != nil {
+			return err
+		}
+		req := map[string]interface{}{"area_id": *area, "priority": *priority, "payload": *payload}
+		if *deadline != "" {
+			req["deadline"] = *deadline
+		}
+		body, service, path = req, "fleet", "/work_orders"
+	case "order":
+		sku := fs.String("sku", "", "SKU to produce")
+		quantity := fs.Int("quantity", 0, "quantity")
+		zone := fs.String("zone", "", "zone ID (optional)")
+		erpRef := fs.String("erp-ref", "", "ERP order reference (optional)")
+		if _, err := parse(fs, args); err != nil {
+			return err
+		}
+		if *sku == "" || *quantity <= 0 {
+			return fmt.Errorf("submit order: -sku and a positive -quantity are required")
+		}
+		body = map[string]interface{}{"sku": *sku, "quantity": *quantity, "area_id": *area, "zone_id": *zone,
+			"priority": *priority, "erp_order_ref": *erpRef}
+		service, path = "mes", "/orders"
+	default:
+		return fmt.Errorf("submit: want work-order or order, got %q (use create for others)", what)
+	}
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+	var out map[string]interface{}
+	if err := c.call(ctx, http.MethodPost, service, path, body, &out); err != nil {
+		return err
+	}
+	return printValue(g.stdout, g.output, out)
+}
+
+func runCreate(ctx context.Context, g *globals, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	g.register(fs)
+	file := fs.String("f", "", "JSON file with the object, - for stdin")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 || *file == "" {
+		return fmt.Errorf("usage: fleetctl create RESOURCE -f FILE")
+	}
+	res, err := lookup(pos[0])
+	if err != nil {
+		return err
+	}
+	if !res.create {
+		return fmt.Errorf("%s cannot be created", res.name)
+	}
+	body, err := readBody(*file)
+	if err != nil {
+		return err
+	}
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+	var out map[string]interface{}
+	if err := c.call(ctx, http.MethodPost, res.service, res.path, body, &out); err != nil {
+		return err
+	}
+	return printValue(g.stdout, g.output, out)
+}
+
+func isAction(verb string) bool {
+	return contains(actionVerbs(), verb)
+}
+
+func runAction(ctx context.Context, g *globals, action string, args []string) error {
+	fs := flag.NewFlagSet(action, flag.ContinueOnError)
+	g.register(fs)
+	file := fs.String("f", "", "JSON file with the request body, - for stdin (e.g. for scrap)")
+	pos, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 2 {
+		return fmt.Errorf("usage: fleetctl %s RESOURCE ID", action)
+	}
+	res, err := lookup(pos[0])
+	if err != nil {
+		return err
+	}
+	if !res.hasAction(action) {
+		return fmt.Errorf("%s has no action %s (it has: %s)", res.name, action, strings.Join(res.actions, ", "))
+	}
+	var body interface{}
+	if *file != "" {
+		if body, err = readBody(*file); err != nil {
+			return err
+		}
+	}
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+	var out map[string]interface{}
+	if err := c.call(ctx, http.MethodPost, res.service, res.path+"/"+url.PathEscape(pos[1])+"/"+action, body, &out); err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	if g.output == "table" && out["id"] != nil {
+		return printItems(g.stdout, g.output, res.columns, []map[string]interface{}{out})
+	}
+	return printValue(g.stdout, g.output, out)
+}
+
+func runFirmware(ctx context.Context, g *globals, args []string) error {
+	if len(args) == 0 || args[0] != "trigger" {
+		return fmt.Errorf("usage: fleetctl firmware trigger [-via fleet|mes|cmms] [-seed-busy N] [-deadline RFC3339] [-policy FILE]; list campaigns with fleetctl get campaigns")
+	}
+	fs := flag.NewFlagSet("firmware trigger", flag.ContinueOnError)
+	g.register(fs)
+	via := fs.String("via", "fleet", "API that starts the campaign: fleet, mes or cmms")
+	seedBusy := fs.Int("seed-busy", 0, "submit this many work orders first, so busy robots defer the update")
+	deadline := fs.String("deadline", "", "RFC3339 time after which the policy no longer holds the update back (fleet only)")
+	policy := fs.String("policy", "", "JSON file with the rollout policy: maintenance windows, min battery, holdback (fleet only)")
+	if _, err := parse(fs, args[1:]); err != nil {
+		return err
+	}
+	body := map[string]interface{}{"seed_busy": *seedBusy}
+	path := "/firmware/trigger"
+	switch *via {
+	case "fleet":
+		path = "/firmware/simulate"
+		if *deadline != "" {
+			body["deadline"] = *deadline
+		}
+		if *policy != "" {
+			data, err := readBody(*policy)
+			if err != nil {
+				return err
+			}
+			body["policy"] = json.RawMessage(data)
+		}
+	case "mes", "cmms":
+		if *deadline != "" || *policy != "" {
+			return fmt.Errorf("firmware trigger: -deadline and -policy need -via fleet")
+		}
+	default:
+		return fmt.Errorf("firmware trigger: -via must be fleet, mes or cmms")
+	}
+	c, err := newClient(g)
+	if err != nil {
+		return err
+	}
+	var out map[string]interface{}
+	if err := c.call(ctx, http.MethodPost, *via, path, body, &out); err != nil {
+		return err
+	}
+	return printValue(g.stdout, g.output, out)
+}
+
+func runContext(g *globals, args []string) error {
+	cfg, path, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	switch args[0] {
+	case "list":
+		if len(cfg.Contexts) == 0 {
+			fmt.Fprintln(g.stdout, "no contexts; using local (every service on localhost). Add one with fleetctl context set NAME -fleet URL ...")
+			return nil
+		}
+		items := make([]map[string]interface{}, 0, len(cfg.Contexts))
+		for _, name := range cfg.names() {
+			cur := ""
+			if name == cfg.CurrentContext {
+				cur = "*"
+			}
+			items = append(items, map[string]interface{}{"current": cur, "name": name, "fleet": cfg.Contexts[name].Fleet})
+		}
+		return printItems(g.stdout, g.output, cols("CURRENT", "current", "NAME", "name", "FLEET", "fleet"), items)
+	case "current":
+		name, ctx, err := cfg.current(g.context)
+		if err != nil {
+			return err
+		}
+		shown := *ctx
+		if shown.Token != "" {
+			shown.Token = "(set)"
+		}
+		fmt.Fprintf(g.stdout, "context: %s\n", name)
+		return printValue(g.stdout, "yaml", shown)
+	case "use":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: fleetctl context use NAME")
+		}
+		if _, ok := cfg.Contexts[args[1]]; !ok {
+			return fmt.Errorf("no context %q", args[1])
+		}
+		cfg.CurrentContext = args[1]
+		return cfg.save(path)
+	case "set":
+		fs := flag.NewFlagSet("context set", flag.ContinueOnError)
+		fs.SetOutput(g.stderr)
+		urls := make(map[string]*string, len(services))
+		for _, s := range services {
+			urls[s] = fs.String(s, "", s+" API base URL")
+		}
+		token := fs.String("token", "", "API key or JWT")
+		pos, err := parse(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(pos) != 1 {
+			return fmt.Errorf("usage: fleetctl context set NAME [-fleet URL] [-mes URL] ... [-token TOKEN]")
+		}
+		ctx := cfg.Contexts[pos[0]]
+		if ctx == nil {
+			ctx = &Context{}
+			cfg.Contexts[pos[0]] = ctx
+		}
+		for s, u := range urls {
+			if *u != "" {
+				*ctx.url(s) = *u
+			}
+		}
+		if *token != "" {
+			ctx.Token = *token
+		}
+		if cfg.CurrentContext == "" {
+			cfg.CurrentContext = pos[0]
+		}
+		return cfg.save(path)
+	case "delete":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: fleetctl context delete NAME")
+		}
+		delete(cfg.Contexts, args[1])
+		if cfg.CurrentContext == args[1] {
+			cfg.CurrentContext = ""
+		}
+		return cfg.save(path)
+	}
+	return fmt.Errorf("usage: fleetctl context list|current|use NAME|set NAME|delete NAME")
+}
+
+func printResources(w io.Writer) {
+	items := make([]map[string]interface{}, len(resources))
+	for i, r := range resources {
+		items[i] = map[string]interface{}{
+			"name": r.name, "service": r.service,
+			"filters": strings.Join(r.filters, ","), "actions": strings.Join(r.actions, ","),
+		}
+	}
+	printItems(w, "table", cols("RESOURCE", "name", "SERVICE", "service", "FILTERS", "filters", "ACTIONS", "actions"), items)
+}
+
+// readBody reads a JSON request body from file, or stdin for "-".
+func readBody(file string) ([]byte, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("%s: not valid JSON", file)
+	}
+	return data, nil
+}
+
+// sleep waits d, returning false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fleetctl runs a command line and returns its output and exit status.
+func fleetctl(t *testing.T, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code = run(context.Background(), args, &out, &errOut)
+	return out.String(), errOut.String(), code
+}
+
+// setup points fleetctl at a new config file and clears the environment it reads.
+func setup(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fleetctl.yaml")
+	t.Setenv("FLEETCTL_CONFIG", path)
+	t.Setenv("FLEETCTL_CONTEXT", "")
+	t.Setenv("FLEETCTL_TOKEN", "")
+	return path
+}
+
+// api is a fake MES and CMMS recording the requests it gets.
+type api struct {
+	mu       sync.Mutex
+	requests []string // "METHOD path?query"
+	auth     string
+	bodies   []map[string]interface{}
+}
+
+func (a *api) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	a.requests = append(a.requests, r.Method+" "+r.URL.RequestURI())
+	a.auth = r.Header.Get("Authorization")
+	if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+		var body map[string]interface{}
+		json.Unmarshal(data, &body)
+		a.bodies = append(a.bodies, body)
+	}
+	a.mu.Unlock()
+
+	order := `{"id":"ord-1","sku":"WIDGET-001","quantity":50,"quantity_completed":10,"area_id":"area-1","status":"in_progress","tags":["a"]}`
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method + " " + r.URL.Path {
+	case "GET /orders":
+		io.WriteString(w, `{"orders":[`+order+`,{"id":"ord-2","sku":"GEAR","quantity":5,"status":"draft"}]}`)
+	case "GET /orders/ord-1":
+		io.WriteString(w, order)
+	case "POST /orders":
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"id":"ord-3","status":"draft"}`)
+	case "POST /orders/ord-1/release":
+		io.WriteString(w, strings.Replace(order, "in_progress", "released", 1))
+	case "POST /orders/ord-1/cancel":
+		http.Error(w, "order ord-1 is in progress", http.StatusConflict)
+	case "GET /equipment":
+		io.WriteString(w, `{"equipment":[{"id":"robot-1","name":"AMR 1","type":"robot","status":"operational"}]}`)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (a *api) last() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.requests) == 0 {
+		return ""
+	}
+	return a.requests[len(a.requests)-1]
+}
+
+func TestUsage(t *testing.T) {
+	setup(t)
+	for _, tc := range []struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		{nil, 2, "usage: fleetctl"},
+		{[]string{"-h"}, 0, "usage: fleetctl"},
+		{[]string{"-bogus"}, 2, "flag provided but not defined"},
+		{[]string{"frobnicate"}, 2, `unknown command "frobnicate"`},
+		{[]string{"get"}, 1, "usage: fleetctl get"},
+		{[]string{"get", "widgets"}, 1, `unknown resource "widgets"`},
+		{[]string{"get", "orders", "-bogus"}, 2, "flag provided but not defined: -bogus"},
+		{[]string{"get", "orders", "-filter", "colour=red"}, 1, `bad filter "colour=red"`},
+		{[]string{"get", "orders", "-filter", "status"}, 1, "want name=value"},
+		{[]string{"describe", "order"}, 1, "usage: fleetctl describe"},
+		{[]string{"start", "order", "ord-1"}, 1, "orders has no action start"},
+		{[]string{"release", "order"}, 1, "usage: fleetctl release RESOURCE ID"},
+		{[]string{"submit"}, 1, "usage: fleetctl submit"},
+		{[]string{"submit", "robot"}, 1, "want work-order or order"},
+		{[]string{"submit", "order", "-sku", "WIDGET-001"}, 1, "positive -quantity"},
+		{[]string{"submit", "order", "-quantity", "ten"}, 2, "invalid value"},
+		{[]string{"create", "areas", "-f", "area.json"}, 1, "areas cannot be created"},
+		{[]string{"create", "orders", "-f", filepath.Join(t.TempDir(), "missing.json")}, 1, "no such file"},
+		{[]string{"firmware"}, 1, "usage: fleetctl firmware trigger"},
+		{[]string{"firmware", "trigger", "-via", "erp"}, 1, "-via must be fleet, mes or cmms"},
+		{[]string{"firmware", "trigger", "-via", "mes", "-deadline", "2025-01-06T06:00:00Z"}, 1, "need -via fleet"},
+		{[]string{"-context", "plant-b", "get", "orders"}, 1, `no context "plant-b"`},
+		{[]string{"context", "use", "plant-b"}, 1, `no context "plant-b"`},
+		{[]string{"context", "frobnicate"}, 1, "usage: fleetctl context"},
+	} {
+		_, stderr, code := fleetctl(t, tc.args...)
+		if code != tc.code || !strings.Contains(stderr, tc.stderr) {
+			t.Errorf("fleetctl %s: exit %d, stderr %q; want %d and %q", strings.Join(tc.args, " "), code, stderr, tc.code, tc.stderr)
+		}
+	}
+
+	stdout, _, code := fleetctl(t, "help", "resources")
+	if code != 0 || !strings.Contains(stdout, "RESOURCE") || !strings.Contains(stdout, "release,pause,complete,cancel,scrap") {
+		t.Errorf("help resources: exit %d\n%s", code, stdout)
+	}
+}
+
+func TestContext(t *testing.T) {
+	path := setup(t)
+	if stdout, _, code := fleetctl(t, "context", "list"); code != 0 || !strings.Contains(stdout, "no contexts") {
+		t.Errorf("context list without config: exit %d, %q", code, stdout)
+	}
+	if stdout, _, _ := fleetctl(t, "context", "current"); !strings.Contains(stdout, "context: local") || !strings.Contains(stdout, "http://localhost:8081") {
+		t.Errorf("default context:\n%s", stdout)
+	}
+	for _, args := range [][]string{
+		{"context", "set", "plant-a", "-fleet", "http://fleet.a:8080", "-token", "secret"},
+		{"context", "set", "plant-b", "-mes", "http://mes.b:8081"},
+		{"context", "set", "plant-a", "-mes", "http://mes.a:8081"},
+	} {
+		if _, stderr, code := fleetctl(t, args...); code != 0 {
+			t.Fatalf("%v: exit %d: %s", args, code, stderr)
+		}
+	}
+
+	// The first context set becomes current; tokens are not shown and the file is private.
+	stdout, _, _ := fleetctl(t, "context", "current")
+	if !strings.Contains(stdout, "context: plant-a") || !strings.Contains(stdout, "mes: http://mes.a:8081") ||
+		!strings.Contains(stdout, "fleet: http://fleet.a:8080") || strings.Contains(stdout, "secret") {
+		t.Errorf("context current:\n%s", stdout)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("config file %v, %v", fi.Mode(), err)
+	}
+	fleetctl(t, "context", "use", "plant-b")
+	stdout, _, _ = fleetctl(t, "-o", "json", "context", "list")
+	var list []map[string]string
+	if err := json.Unmarshal([]byte(stdout), &list); err != nil || len(list) != 2 || list[0]["name"] != "plant-a" || list[1]["current"] != "*" {
+		t.Errorf("context list: %v\n%s", err, stdout)
+	}
+	fleetctl(t, "context", "delete", "plant-b")
+	if stdout, _, _ := fleetctl(t, "context", "current"); !strings.Contains(stdout, "context: local") {
+		t.Errorf("after deleting the current context:\n%s", stdout)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	setup(t)
+	a := &api{}
+	srv := httptest.NewServer(a)
+	defer srv.Close()
+	if _, stderr, code := fleetctl(t, "context", "set", "test", "-mes", srv.URL, "-cmms", srv.URL, "-token", "secret"); code != 0 {
+		t.Fatal(stderr)
+	}
+
+	for _, tc := range []struct {
+		args    []string
+		code    int
+		request string
+		stdout  []string // lines or fragments the output must contain
+		stderr  string
+	}{
+		{[]string{"get", "orders"}, 0, "GET /orders", []string{"ID      SKU          QTY   DONE   SCRAP   AREA     STATUS", "ord-1   WIDGET-001   50    10", "ord-2   GEAR         5"}, ""},
+		{[]string{"get", "orders", "-filter", "status=in_progress"}, 0, "GET /orders?status=in_progress", nil, ""},
+		{[]string{"get", "robots", "-filter", "status=operational"}, 0, "GET /equipment?status=operational&type=robot", []string{"robot-1   AMR 1"}, ""},
+		{[]string{"get", "order", "ord-1", "-o", "yaml"}, 0, "GET /orders/ord-1", []string{"quantity: 50\n", "tags:\n    - a\n"}, ""},
+		{[]string{"describe", "order", "ord-9"}, 1, "GET /orders/ord-9", nil, "not found"},
+		{[]string{"get", "orders", "-o", "xml"}, 1, "GET /orders", nil, `unknown output format "xml"`},
+		{[]string{"submit", "order", "-sku", "WIDGET-001", "-quantity", "5", "-o", "json"}, 0, "POST /orders", []string{`"id": "ord-3"`}, ""},
+		{[]string{"release", "order", "ord-1"}, 0, "POST /orders/ord-1/release", []string{"ord-1   WIDGET-001   50    10", "released"}, ""},
+		{[]string{"cancel", "order", "ord-1"}, 1, "POST /orders/ord-1/cancel", nil, "order ord-1 is in progress"},
+	} {
+		stdout, stderr, code := fleetctl(t, tc.args...)
+		name := strings.Join(tc.args, " ")
+		if code != tc.code || !strings.Contains(stderr, tc.stderr) {
+			t.Errorf("fleetctl %s: exit %d, stderr %q; want %d and %q", name, code, stderr, tc.code, tc.stderr)
+		}
+		if got := a.last(); got != tc.request {
+			t.Errorf("fleetctl %s: request %q, want %q", name, got, tc.request)
+		}
+		for _, s := range tc.stdout {
+			if !strings.Contains(stdout, s) {
+				t.Errorf("fleetctl %s: output lacks %q:\n%s", name, s, stdout)
+			}
+		}
+	}
+
+	if a.auth != "Bearer secret" {
+		t.Errorf("Authorization %q", a.auth)
+	}
+	if body := a.bodies[0]; body["sku"] != "WIDGET-001" || body["quantity"] != 5.0 || body["area_id"] != "area-1" {
+		t.Errorf("submitted order %v", body)
+	}
+	stdout, _, _ := fleetctl(t, "get", "orders", "-o", "json", "-token", "other")
+	var orders []map[string]interface{}
+	if err := json.Unmarshal([]byte(stdout), &orders); err != nil || len(orders) != 2 || orders[0]["quantity"] != 50.0 {
+		t.Errorf("get orders -o json: %v\n%s", err, stdout)
+	}
+	if a.auth != "Bearer other" {
+		t.Errorf("-token: Authorization %q", a.auth)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// printItems prints items in format: a table with columns, or the items as JSON or YAML.
+func printItems(w io.Writer, format string, columns []column, items []map[string]interface{}) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(tw, header(columns))
+		for _, item := range items {
+			fmt.Fprintln(tw, row(columns, item))
+		}
+		return tw.Flush()
+	case "json", "yaml":
+		if items == nil {
+			items = []map[string]interface{}{}
+		}
+		return printValue(w, format, items)
+	}
+	return fmt.Errorf("unknown output format %q (want table, json or yaml)", format)
+}
+
+// printValue prints one object. A table is not a good fit for a single object with nested fields, so
+// "table" prints it as YAML.
+func printValue(w io.Writer, format string, v interface{}) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "yaml", "table":
+		data, err := yaml.Marshal(plain(v))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	return fmt.Errorf("unknown output format %q (want table, json or yaml)", format)
+}
+
+// header returns the tab-separated table headers.
+func header(columns []column) string {
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = c.header
+	}
+	return strings.Join(headers, "\t")
+}
+
+// row returns the tab-separated table cells of item.
+func row(columns []column, item map[string]interface{}) string {
+	cells := make([]string, len(columns))
+	for i, c := range columns {
+		cells[i] = cell(item[c.field])
+	}
+	return strings.Join(cells, "\t")
+}
+
+func cell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			if t.IsZero() {
+				return ""
+			}
+			return t.Local().Format("2006-01-02 15:04:05")
+		}
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprint(v)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// plain turns the json.Numbers of decoded JSON into ints and floats so YAML prints them as numbers.
+func plain(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = plain(e)
+		}
+		return out
+	case []map[string]interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = plain(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = plain(e)
+		}
+		return out
+	}
+	return v
+}
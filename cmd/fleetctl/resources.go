@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// resource is one kind of object fleetctl lists and acts on, served by one API.
+type resource struct {
+	name    string   // plural, as typed: "orders"
+	aliases []string // e.g. the singular
+	service string   // API serving it: a context URL key (see Context.URL)
+	path    string   // collection path, e.g. "/orders"
+	query   string   // fixed list query, e.g. "type=robot"
+	list    string   // JSON field of the list response holding the items
+	idField string   // item field with its ID; "id" if empty
+	byID    bool     // GET path/ID returns one item; otherwise describe searches the list
+	create  bool     // POST path creates one
+	filters []string // list query parameters, set with -filter name=value
+	actions []string // POST path/ID/action
+	columns []column
+	match   func(item map[string]interface{}) bool // optional: keep only these items of the list
+}
+
+// column is one table column: a header and the item field shown under it.
+type column struct {
+	header string
+	field  string
+}
+
+func cols(pairs ...string) []column {
+	out := make([]column, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		out = append(out, column{pairs[i], pairs[i+1]})
+	}
+	return out
+}
+
+var resources = []*resource{
+	{
+		name: "work-orders", aliases: []string{"work-order", "wo"}, service: "fleet",
+		path: "/work_orders", list: "work_orders", create: true,
+		columns: cols("ID", "id", "AREA", "area_id", "PRIORITY", "priority", "PAYLOAD", "payload_summary", "CREATED", "created_at"),
+	},
+	{
+		name: "campaigns", aliases: []string{"campaign"}, service: "fleet",
+		path: "/work_orders", list: "work_orders",
+		columns: cols("WORK ORDER", "id", "AREA", "area_id", "FIRMWARE", "payload_summary", "CREATED", "created_at"),
+		match: func(item map[string]interface{}) bool {
+			s, _ := item["payload_summary"].(string)
+			return strings.HasPrefix(s, "firmware")
+		},
+	},
+	{
+		name: "areas", aliases: []string{"area"}, service: "fleet",
+		path: "/state/areas", list: "areas", idField: "area_id",
+		columns: cols("AREA", "area_id", "ZONES", "zone_count", "ROBOTS", "robot_count", "CHARGERS", "chargers", "UPDATED", "updated_at"),
+	},
+	{
+		name: "robots", aliases: []string{"robot"}, service: "cmms",
+		path: "/equipment", query: "type=robot", list: "equipment", byID: true, filters: []string{"status"},
+		columns: cols("ROBOT", "id", "NAME", "name", "AREA", "area_id", "ZONE", "zone_id", "STATUS", "status"),
+	},
+	{
+		name: "zones", aliases: []string{"zone"}, service: "cmms",
+		path: "/equipment", query: "type=zone", list: "equipment", byID: true, filters: []string{"status"},
+		columns: cols("ZONE", "id", "NAME", "name", "AREA", "area_id", "STATUS", "status"),
+	},
+	{
+		name: "equipment", service: "cmms",
+		path: "/equipment", list: "equipment", byID: true, filters: []string{"status", "type"}, create: true,
+		columns: cols("ID", "id", "NAME", "name", "TYPE", "type", "AREA", "area_id", "ZONE", "zone_id", "STATUS", "status"),
+	},
+	{
+		name: "mwos", aliases: []string{"mwo"}, service: "cmms",
+		path: "/mwo", list: "work_orders", byID: true, filters: []string{"status", "equipment_id"}, create: true,
+		actions: []string{"start", "complete", "cancel", "submit_to_fleet"},
+		columns: cols("ID", "id", "EQUIPMENT", "equipment_id", "TYPE", "type", "PRIORITY", "priority", "STATUS", "status", "FLEET WO", "fleet_work_order_id"),
+	},
+	{
+		name: "orders", aliases: []string{"order"}, service: "mes",
+		path: "/orders", list: "orders", byID: true, filters: []string{"status"}, create: true,
+		actions: []string{"release", "pause", "complete", "cancel", "scrap"}, // scrap takes -f with {"quantity", "reason"}
+		columns: cols("ID", "id", "SKU", "sku", "QTY", "quantity", "DONE", "quantity_completed", "SCRAP", "quantity_scrapped",
+			"AREA", "area_id", "STATUS", "status", "FLEET WO", "fleet_work_order_id"),
+	},
+	{
+		name: "erp-orders", aliases: []string{"erp-order"}, service: "erp",
+		path: "/orders", list: "orders", byID: true, filters: []string{"status"}, create: true,
+		actions: []string{"submit_to_mes", "cancel"},
+		columns: cols("ID", "id", "REF", "order_ref", "SKU", "sku", "QTY", "quantity", "DUE", "due_date", "STATUS", "status", "MES ORDER", "mes_order_id"),
+	},
+	{
+		name: "tasks", aliases: []string{"task"}, service: "wms",
+		path: "/tasks", list: "tasks", byID: true, filters: []string{"status", "type"}, create: true,
+		actions: []string{"release", "complete", "cancel"},
+		columns: cols("ID", "id", "TYPE", "type", "SKU", "sku", "QTY", "quantity", "FROM", "from_location_id", "TO", "to_location_id",
+			"STATUS", "status", "FLEET WO", "fleet_work_order_id"),
+	},
+	{
+		name: "locations", aliases: []string{"location"}, service: "wms",
+		path: "/locations", list: "locations", create: true,
+		columns: cols("ID", "id", "TYPE", "type", "NAME", "name", "ZONE", "zone_id"),
+	},
+	{
+		name: "inventory", service: "wms",
+		path: "/inventory", list: "inventory", idField: "sku", filters: []string{"location_id", "sku"},
+		columns: cols("LOCATION", "location_id", "SKU", "sku", "LOT", "lot", "QTY", "quantity", "UPDATED", "updated_at"),
+	},
+	{
+		name: "holds", aliases: []string{"hold"}, service: "qms",
+		path: "/holds", list: "holds", byID: true, filters: []string{"active"}, create: true,
+		actions: []string{"release"},
+		columns: cols("ID", "id", "SERIAL", "serial", "LOT", "lot", "REASON", "reason", "HELD", "held_at", "RELEASED", "released_at"),
+	},
+	{
+		name: "ncrs", aliases: []string{"ncr"}, service: "qms",
+		path: "/ncr", list: "ncrs", byID: true, filters: []string{"status"}, create: true,
+		actions: []string{"close"},
+		columns: cols("ID", "id", "SKU", "sku", "SERIAL", "serial", "LOT", "lot", "STATUS", "status", "DESCRIPTION", "description"),
+	},
+	{
+		name: "inspections", aliases: []string{"inspection"}, service: "qms",
+		path: "/inspections", list: "inspections", filters: []string{"serial", "lot"}, create: true,
+		columns: cols("ID", "id", "SKU", "sku", "SERIAL", "serial", "LOT", "lot", "RESULT", "result", "CREATED", "created_at"),
+	},
+	{
+		name: "products", aliases: []string{"product"}, service: "plm",
+		path: "/products", list: "products", byID: true, filters: []string{"status", "sku"}, create: true,
+		columns: cols("ID", "id", "SKU", "sku", "NAME", "name", "REVISION", "revision", "STATUS", "status"),
+	},
+	{
+		name: "ecos", aliases: []string{"eco"}, service: "plm",
+		path: "/ecos", list: "ecos", byID: true, filters: []string{"status", "product_id"}, create: true,
+		columns: cols("ID", "id", "TITLE", "title", "PRODUCT", "product_id", "STATUS", "status", "UPDATED", "updated_at"),
+	},
+	{
+		name: "genealogy", service: "traceability",
+		path: "/genealogy", list: "records", filters: []string{"serial", "lot"},
+		columns: cols("ID", "id", "EVENT", "event_type", "SKU", "sku", "SERIAL", "serial", "PARENT", "parent_serial", "LOT", "lot",
+			"MES ORDER", "mes_order_id", "CREATED", "created_at"),
+	},
+	{
+		name: "recall", service: "traceability",
+		path: "/recall", list: "records", filters: []string{"lot", "sku", "from", "to"},
+		columns: cols("ID", "id", "EVENT", "event_type", "SKU", "sku", "SERIAL", "serial", "LOT", "lot", "QTY", "quantity",
+			"MES ORDER", "mes_order_id", "CREATED", "created_at"),
+	},
+}
+
+// lookup returns the resource called name or one of its aliases.
+func lookup(name string) (*resource, error) {
+	for _, r := range resources {
+		if r.name == name {
+			return r, nil
+		}
+		for _, a := range r.aliases {
+			if a == name {
+				return r, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown resource %q (see fleetctl help resources)", name)
+}
+
+// actionVerbs returns every action some resource has, sorted; each is also a fleetctl command.
+func actionVerbs() []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, r := range resources {
+		for _, a := range r.actions {
+			if !seen[a] {
+				seen[a] = true
+				out = append(out, a)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (r *resource) hasAction(action string) bool {
+	for _, a := range r.actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *resource) id(item map[string]interface{}) string {
+	f := r.idField
+	if f == "" {
+		f = "id"
+	}
+	s, _ := item[f].(string)
+	return s
+}
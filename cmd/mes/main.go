@@ -9,6 +9,11 @@ import (
 	"syscall"
 
 	"github.com/robotfleetos/robotfleetos/internal/mes"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/client"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
 func main() {
@@ -17,10 +22,38 @@ func main() {
 		log.Fatalf("mes: load config: %v", err)
 	}
 
-	store := mes.NewStore()
-	fleetClient := mes.NewFleetClient(cfg.Fleet.APIURL)
+	db, err := storage.Open(cfg.Storage)
+	if err != nil {
+		log.Fatalf("mes: %v", err)
+	}
+	if db != nil {
+		defer db.Close()
+		log.Printf("mes: storing data in %s", cfg.Storage.Path)
+	}
+	store, err := mes.NewStore(db)
+	if err != nil {
+		log.Fatalf("mes: %v", err)
+	}
+	fleetClient := client.NewFleet(cfg.Fleet.APIURL)
+	fleetClient.Token = cfg.Fleet.Token
 	svc := mes.NewService(store, fleetClient)
-	server := &mes.Server{Service: svc}
+	authn, err := auth.New(cfg.Auth, mes.AuthRules)
+	if err != nil {
+		log.Fatalf("mes: %v", err)
+	}
+	if authn != nil {
+		log.Printf("mes: API authentication on")
+	}
+	auditLog, err := audit.Open("mes", cfg.Audit)
+	if err != nil {
+		log.Fatalf("mes: %v", err)
+	}
+	defer auditLog.Close()
+	idem, err := idempotency.New(cfg.Idempotency)
+	if err != nil {
+		log.Fatalf("mes: %v", err)
+	}
+	server := &mes.Server{Service: svc, Auth: authn, Audit: auditLog, Idempotency: idem}
 
 	httpSrv := &http.Server{
 		Addr:    cfg.MES.Listen,
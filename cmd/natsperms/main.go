@@ -0,0 +1,68 @@
+// NATS permissions: prints the subjects a layer may publish and subscribe to (see messaging.Permissions),
+// for a nats-server authorization block (-format conf), nsc user flags (-format nsc) or JSON.
+// Without -areas, -zones and -robots it prints templates filled from the NATS user's tags, e.g.
+//
+//	nsc add user zone-1 --tag zone:zone-1 --tag robot:robot-1 --tag robot:robot-2 $(natsperms -layer zone -format nsc)
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+func main() {
+	layer := flag.String("layer", "", "fleet, area, zone or edge")
+	areas := flag.String("areas", "", "areas the connection owns, comma-separated")
+	zones := flag.String("zones", "", "zones the connection owns, comma-separated")
+	robots := flag.String("robots", "", "robots the connection owns, comma-separated")
+	format := flag.String("format", "conf", "conf, nsc or json")
+	flag.Parse()
+
+	scope := messaging.Scope{Areas: list(*areas), Zones: list(*zones), Robots: list(*robots)}
+	if scope.Areas == nil && scope.Zones == nil && scope.Robots == nil {
+		scope = messaging.TemplateScope
+	}
+	p, err := messaging.Permissions(*layer, scope)
+	if err != nil {
+		log.Fatalf("natsperms: %v", err)
+	}
+	switch *format {
+	case "conf":
+		fmt.Printf("permissions: {\n  publish: { allow: %s }\n  subscribe: { allow: %s }\n}\n", quoted(p.Publish), quoted(p.Subscribe))
+	case "nsc":
+		fmt.Printf("--allow-pub %s --allow-sub %s\n", strings.Join(p.Publish, ","), strings.Join(p.Subscribe, ","))
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(p)
+	default:
+		log.Fatalf("natsperms: unknown format %q", *format)
+	}
+}
+
+func list(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func quoted(subjects []string) string {
+	q := make([]string, len(subjects))
+	for i, s := range subjects {
+		q[i] = fmt.Sprintf("%q", s)
+	}
+	return "[" + strings.Join(q, ", ") + "]"
+}
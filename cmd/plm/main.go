@@ -8,6 +8,9 @@ import (
 	"syscall"
 
 	"github.com/robotfleetos/robotfleetos/internal/plm"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
 func main() {
@@ -15,9 +18,32 @@ func main() {
 	if err != nil {
 		log.Fatalf("plm: load config: %v", err)
 	}
-	store := plm.NewStore()
+	db, err := storage.Open(cfg.Storage)
+	if err != nil {
+		log.Fatalf("plm: %v", err)
+	}
+	if db != nil {
+		defer db.Close()
+		log.Printf("plm: storing data in %s", cfg.Storage.Path)
+	}
+	store, err := plm.NewStore(db)
+	if err != nil {
+		log.Fatalf("plm: %v", err)
+	}
 	svc := plm.NewService(store)
-	server := &plm.Server{Service: svc}
+	authn, err := auth.New(cfg.Auth, plm.AuthRules)
+	if err != nil {
+		log.Fatalf("plm: %v", err)
+	}
+	if authn != nil {
+		log.Printf("plm: API authentication on")
+	}
+	auditLog, err := audit.Open("plm", cfg.Audit)
+	if err != nil {
+		log.Fatalf("plm: %v", err)
+	}
+	defer auditLog.Close()
+	server := &plm.Server{Service: svc, Auth: authn, Audit: auditLog}
 	httpSrv := &http.Server{Addr: cfg.PLM.Listen, Handler: server.Handler()}
 	go func() {
 		log.Printf("plm: API on http://localhost%s", cfg.PLM.Listen)
@@ -9,6 +9,9 @@ import (
 	"syscall"
 
 	"github.com/robotfleetos/robotfleetos/internal/qms"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
 func main() {
@@ -17,9 +20,32 @@ func main() {
 		log.Fatalf("qms: load config: %v", err)
 	}
 
-	store := qms.NewStore()
+	db, err := storage.Open(cfg.Storage)
+	if err != nil {
+		log.Fatalf("qms: %v", err)
+	}
+	if db != nil {
+		defer db.Close()
+		log.Printf("qms: storing data in %s", cfg.Storage.Path)
+	}
+	store, err := qms.NewStore(db)
+	if err != nil {
+		log.Fatalf("qms: %v", err)
+	}
 	svc := qms.NewService(store)
-	server := &qms.Server{Service: svc}
+	authn, err := auth.New(cfg.Auth, qms.AuthRules)
+	if err != nil {
+		log.Fatalf("qms: %v", err)
+	}
+	if authn != nil {
+		log.Printf("qms: API authentication on")
+	}
+	auditLog, err := audit.Open("qms", cfg.Audit)
+	if err != nil {
+		log.Fatalf("qms: %v", err)
+	}
+	defer auditLog.Close()
+	server := &qms.Server{Service: svc, Auth: authn, Audit: auditLog}
 
 	httpSrv := &http.Server{
 		Addr:    cfg.QMS.Listen,
@@ -9,6 +9,9 @@ import (
 	"syscall"
 
 	"github.com/robotfleetos/robotfleetos/internal/traceability"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
 func main() {
@@ -17,9 +20,32 @@ func main() {
 		log.Fatalf("traceability: load config: %v", err)
 	}
 
-	store := traceability.NewStore()
+	db, err := storage.Open(cfg.Storage)
+	if err != nil {
+		log.Fatalf("traceability: %v", err)
+	}
+	if db != nil {
+		defer db.Close()
+		log.Printf("traceability: storing data in %s", cfg.Storage.Path)
+	}
+	store, err := traceability.NewStore(db)
+	if err != nil {
+		log.Fatalf("traceability: %v", err)
+	}
 	svc := traceability.NewService(store)
-	server := &traceability.Server{Service: svc}
+	authn, err := auth.New(cfg.Auth, traceability.AuthRules)
+	if err != nil {
+		log.Fatalf("traceability: %v", err)
+	}
+	if authn != nil {
+		log.Printf("traceability: API authentication on")
+	}
+	auditLog, err := audit.Open("traceability", cfg.Audit)
+	if err != nil {
+		log.Fatalf("traceability: %v", err)
+	}
+	defer auditLog.Close()
+	server := &traceability.Server{Service: svc, Auth: authn, Audit: auditLog}
 
 	httpSrv := &http.Server{
 		Addr:    cfg.Traceability.Listen,
@@ -0,0 +1,81 @@
+// Config validation: loads every layer and service config under each DIR the way its process would
+// (unknown keys, bad durations, missing IDs, ...) and checks the area, zone and edge configs of each
+// deployment against each other. Prints the problems and exits non-zero if there are any, e.g.
+//
+//	validate-config deploy configs
+//
+// With -schema it prints the JSON Schema of one config instead, for editors:
+//
+//	validate-config -schema zone > zone.schema.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/robotfleetos/robotfleetos/internal/configcheck"
+)
+
+func main() {
+	schema := flag.String("schema", "", "print the JSON Schema of a config ("+strings.Join(configcheck.Kinds(), ", ")+") and exit")
+	verbose := flag.Bool("v", false, "also list the files that are valid or skipped")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: validate-config [-v] DIR|FILE...\n       validate-config -schema CONFIG\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *schema != "" {
+		s, err := configcheck.Schema(*schema)
+		if err != nil {
+			log.Fatalf("validate-config: %v", err)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(s); err != nil {
+			log.Fatalf("validate-config: %v", err)
+		}
+		return
+	}
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	files, failed := 0, 0
+	for _, dir := range flag.Args() {
+		r, err := configcheck.Dir(dir)
+		if err != nil {
+			log.Fatalf("validate-config: %v", err)
+		}
+		for _, f := range r.Files {
+			switch {
+			case f.Err != nil:
+				fmt.Printf("FAIL %s (%s)\n", f.Path, strings.Join(f.Configs, ", "))
+				for _, line := range strings.Split(f.Err.Error(), "\n") {
+					fmt.Printf("\t%s\n", line)
+				}
+			case len(f.Configs) == 0:
+				if *verbose {
+					fmt.Printf("skip %s (not a config file)\n", f.Path)
+				}
+				continue
+			case *verbose:
+				fmt.Printf("ok   %s (%s)\n", f.Path, strings.Join(f.Configs, ", "))
+			}
+			files++
+		}
+		for _, err := range r.Errors {
+			fmt.Printf("FAIL %v\n", err)
+		}
+		failed += r.Failed()
+	}
+	if failed > 0 {
+		fmt.Printf("%d config files, %d problems\n", files, failed)
+		os.Exit(1)
+	}
+	fmt.Printf("%d config files ok\n", files)
+}
@@ -9,6 +9,12 @@ import (
 	"syscall"
 
 	"github.com/robotfleetos/robotfleetos/internal/wms"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/client"
+	"github.com/robotfleetos/robotfleetos/pkg/facility"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
 func main() {
@@ -17,10 +23,45 @@ func main() {
 		log.Fatalf("wms: load config: %v", err)
 	}
 
-	store := wms.NewStore()
-	fleetClient := wms.NewFleetClient(cfg.Fleet.APIURL)
+	db, err := storage.Open(cfg.Storage)
+	if err != nil {
+		log.Fatalf("wms: %v", err)
+	}
+	if db != nil {
+		defer db.Close()
+		log.Printf("wms: storing data in %s", cfg.Storage.Path)
+	}
+	store, err := wms.NewStore(db)
+	if err != nil {
+		log.Fatalf("wms: %v", err)
+	}
+	fleetClient := client.NewFleet(cfg.Fleet.APIURL)
+	fleetClient.Token = cfg.Fleet.Token
 	svc := wms.NewService(store, fleetClient, cfg.WMS.WarehouseAreaID)
-	server := &wms.Server{Service: svc}
+	if cfg.WMS.FacilityMap != "" {
+		m, err := facility.Load(cfg.WMS.FacilityMap)
+		if err != nil {
+			log.Fatalf("wms: %v", err)
+		}
+		svc.SetFacilityMap(m)
+	}
+	authn, err := auth.New(cfg.Auth, wms.AuthRules)
+	if err != nil {
+		log.Fatalf("wms: %v", err)
+	}
+	if authn != nil {
+		log.Printf("wms: API authentication on")
+	}
+	auditLog, err := audit.Open("wms", cfg.Audit)
+	if err != nil {
+		log.Fatalf("wms: %v", err)
+	}
+	defer auditLog.Close()
+	idem, err := idempotency.New(cfg.Idempotency)
+	if err != nil {
+		log.Fatalf("wms: %v", err)
+	}
+	server := &wms.Server{Service: svc, Auth: authn, Audit: auditLog, Idempotency: idem}
 
 	httpSrv := &http.Server{
 		Addr:    cfg.WMS.Listen,
@@ -8,11 +8,13 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/robotfleetos/robotfleetos/internal/zone"
 	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+	"github.com/robotfleetos/robotfleetos/pkg/facility"
 	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+	"github.com/robotfleetos/robotfleetos/pkg/robotmodel"
 )
 
 func main() {
@@ -26,7 +28,8 @@ func main() {
 		cancel()
 	}()
 
-	cfg, err := zone.LoadConfig("")
+	path := os.Getenv("ZONE_CONFIG")
+	cfg, err := zone.LoadConfig(path)
 	if err != nil {
 		log.Fatalf("zone: load config: %v", err)
 	}
@@ -35,17 +38,29 @@ func main() {
 	if busURL == "" {
 		busURL = cfg.Messaging.Broker
 	}
-	bus, err := messaging.NewBusFromURL(busURL)
+	bus, err := messaging.NewBusFromURL(busURL, cfg.Messaging.ConnOptions)
 	if err != nil {
 		log.Fatalf("zone: connect to message bus: %v", err)
 	}
-	cmdPub := messaging.NewRobotCommandPublisher(bus)
+	var cmdPub messaging.RobotCommandPublisher = messaging.NewRobotCommandPublisher(bus)
+	signer, err := cfg.Zone.CommandSigning.Signer(api.ZoneID(cfg.Zone.ZoneID))
+	if err != nil {
+		log.Fatalf("zone: %v", err)
+	}
+	if signer != nil {
+		cmdPub = signer.Publisher(cmdPub)
+		log.Printf("zone: signing robot commands with key %s", signer.Key().ID)
+	}
 	summaryPub := messaging.NewZoneSummaryPublisher(bus)
 
 	robots := make([]api.RobotID, 0, len(cfg.Zone.Robots))
 	for _, r := range cfg.Zone.Robots {
 		robots = append(robots, api.RobotID(r))
 	}
+	report, err := cfg.Zone.ReportPeriod()
+	if err != nil {
+		log.Fatalf("zone: %v", err)
+	}
 
 	ctrl := zone.NewController(
 		api.ZoneID(cfg.Zone.ZoneID),
@@ -53,10 +68,49 @@ func main() {
 		cmdPub,
 		summaryPub,
 		bus,
-		5*time.Second,
+		report,
 	)
+	ctrl.SetFirmwarePolicy(cfg.Zone.Firmware.Policy())
+	ctrl.SetStepResultPublisher(messaging.NewTaskResultPublisher(bus))
+	traffic, err := cfg.Zone.Traffic.Policy()
+	if err != nil {
+		log.Fatalf("zone: %v", err)
+	}
+	ctrl.SetTrafficPolicy(traffic)
+	charging, err := cfg.Zone.Charging.Policy()
+	if err != nil {
+		log.Fatalf("zone: %v", err)
+	}
+	ctrl.SetChargingPolicy(charging)
+	if cfg.Zone.ModelRegistry != "" {
+		models, err := robotmodel.Load(cfg.Zone.ModelRegistry)
+		if err != nil {
+			log.Fatalf("zone: %v", err)
+		}
+		ctrl.SetModelRegistry(models)
+	}
+	if cfg.Zone.FacilityMap != "" {
+		m, err := facility.Load(cfg.Zone.FacilityMap)
+		if err != nil {
+			log.Fatalf("zone: %v", err)
+		}
+		ctrl.SetFacilityMap(m)
+	}
 
 	log.Printf("zone: starting %s (robots: %v)", cfg.Zone.ZoneID, cfg.Zone.Robots)
+	if path != "" {
+		// Robots, report interval and the firmware, traffic and charging settings apply on SIGHUP (or
+		// CONFIG_WATCH); a file that fails validation is logged and the running config kept.
+		go config.Watch(ctx, path, config.PollInterval(), func() {
+			next, err := zone.LoadConfig(path)
+			if err != nil {
+				log.Printf("zone: reload: %v; keeping the running config", err)
+				return
+			}
+			cfg = ctrl.Reload(ctx, cfg, next)
+		})
+	}
+
 	if err := ctrl.Run(ctx); err != nil && err != context.Canceled {
 		log.Printf("zone: %v", err)
 		os.Exit(1)
@@ -1,9 +1,12 @@
 package area
 
 import (
+	"fmt"
 	"os"
+	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
 )
 
 // Config holds area layer configuration.
@@ -13,13 +16,55 @@ type Config struct {
 }
 
 type AreaConfig struct {
-	AreaID string   `yaml:"area_id"`
-	Zones  []string `yaml:"zones"` // zone IDs this area owns (for dispatching work)
+	AreaID         string          `yaml:"area_id"`
+	Zones          []string        `yaml:"zones"`           // zone IDs this area owns (for dispatching work)
+	ReportInterval string          `yaml:"report_interval"` // how often the area summary goes to the fleet, e.g. "10s" (default)
+	TaskGraphs     TaskGraphConfig `yaml:"task_graphs"`
+}
+
+// ReportPeriod returns the report interval, 10s if unset.
+func (a AreaConfig) ReportPeriod() (time.Duration, error) {
+	return config.Duration("area.report_interval", a.ReportInterval, 10*time.Second)
+}
+
+// TaskGraphConfig tunes multi-step work orders; zero fields take the defaults of DefaultTaskGraphPolicy.
+type TaskGraphConfig struct {
+	StepTimeout string   `yaml:"step_timeout"` // a step without a result this long has failed, e.g. "15m"
+	RetryDelay  string   `yaml:"retry_delay"`  // wait before retrying a failed step, e.g. "30s"
+	Decompose   []string `yaml:"decompose"`    // payload types split into go-to, pick, go-to, place, e.g. ["putaway"]
+	Retries     int      `yaml:"retries"`      // extra attempts per step of a decomposed order
+}
+
+// Policy converts the task graph settings, filling in defaults.
+func (t TaskGraphConfig) Policy() (TaskGraphPolicy, error) {
+	p := DefaultTaskGraphPolicy()
+	if t.StepTimeout != "" {
+		d, err := time.ParseDuration(t.StepTimeout)
+		if err != nil || d <= 0 {
+			return p, fmt.Errorf("task_graphs.step_timeout: invalid duration %q", t.StepTimeout)
+		}
+		p.StepTimeout = d
+	}
+	if t.RetryDelay != "" {
+		d, err := time.ParseDuration(t.RetryDelay)
+		if err != nil || d < 0 {
+			return p, fmt.Errorf("task_graphs.retry_delay: invalid duration %q", t.RetryDelay)
+		}
+		p.RetryDelay = d
+	}
+	if t.Retries < 0 {
+		return p, fmt.Errorf("task_graphs.retries must not be negative: %d", t.Retries)
+	}
+	p.Decompose = t.Decompose
+	p.Retries = t.Retries
+	return p, nil
 }
 
 type MessagingConfig struct {
 	Broker      string `yaml:"broker"`
 	TopicPrefix string `yaml:"topic_prefix"`
+
+	messaging.ConnOptions `yaml:",inline"` // NATS TLS and credentials
 }
 
 // LoadConfig reads config from path. If path is empty, uses env AREA_CONFIG or defaults.
@@ -40,11 +85,7 @@ func LoadConfig(path string) (*Config, error) {
 		}
 	}
 	if path != "" {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return nil, err
-		}
-		if err := yaml.Unmarshal(data, cfg); err != nil {
+		if err := config.Load(path, cfg); err != nil {
 			return nil, err
 		}
 	}
@@ -54,5 +95,34 @@ func LoadConfig(path string) (*Config, error) {
 	if len(cfg.Area.Zones) == 0 {
 		cfg.Area.Zones = []string{"zone-1"}
 	}
+	cfg.Messaging.ApplyEnv()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
+
+// Validate checks the area's IDs, the report interval and the task graph settings.
+func (c *Config) Validate() error {
+	a := c.Area
+	if a.AreaID == "" {
+		return fmt.Errorf("area.area_id is required")
+	}
+	seen := make(map[string]bool, len(a.Zones))
+	for i, z := range a.Zones {
+		if z == "" {
+			return fmt.Errorf("area.zones[%d]: empty zone ID", i)
+		}
+		if seen[z] {
+			return fmt.Errorf("area.zones: %s listed twice", z)
+		}
+		seen[z] = true
+	}
+	if _, err := a.ReportPeriod(); err != nil {
+		return err
+	}
+	if _, err := a.TaskGraphs.Policy(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -3,15 +3,22 @@ package area
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/clock"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
 	"github.com/robotfleetos/robotfleetos/pkg/messaging"
 )
 
+// dedupeTTL is how long a work order ID is remembered to drop redeliveries of it.
+const dedupeTTL = 10 * time.Minute
+
 // Controller runs the area layer: consumes work orders, publishes zone tasks, aggregates zone summaries, reports to fleet.
 type Controller struct {
 	areaID   api.AreaID
@@ -21,10 +28,20 @@ type Controller struct {
 	areaPub  *messaging.AreaSummaryPublisher // publishes to fleet
 	bus      messaging.Subscriber
 	reportInterval time.Duration
+	clock    clock.Clock
 
 	mu          sync.RWMutex
+	report      func() // publishes the summary; set while running, for SetReportInterval
+	stopReport  func()
 	zoneSummary map[api.ZoneID]*api.ZoneSummary
 	taskSeq     atomic.Uint64
+	idSeq       atomic.Uint64
+	seen        *idempotency.Dedupe // work orders handled lately, so a redelivered one is dropped
+
+	graphPolicy TaskGraphPolicy
+	graphs      map[api.WorkOrderID]*graphRun // multi-step work orders in progress
+	stepTasks   map[api.TaskID]*stepRun       // running step attempts by zone task
+	graphStats  api.TaskGraphStats
 }
 
 // NewController creates an area controller that consumes work orders from the bus and publishes zone tasks and area summaries.
@@ -41,31 +58,64 @@ func NewController(areaID api.AreaID, zones []api.ZoneID, zonePub messaging.Zone
 		bus:           bus,
 		reportInterval: reportInterval,
 		zoneSummary:   zoneMap,
+		clock:         clock.Real,
+		graphPolicy:   DefaultTaskGraphPolicy(),
+		graphs:        make(map[api.WorkOrderID]*graphRun),
+		stepTasks:     make(map[api.TaskID]*stepRun),
+		seen:          idempotency.NewDedupe(dedupeTTL),
 	}
 }
 
+// SetClock sets the time source (default clock.Real). Call before Start or Run.
+func (c *Controller) SetClock(clk clock.Clock) {
+	c.clock = clk
+}
+
 // Run subscribes to work orders and zone summaries, and periodically publishes area summary. Blocks until ctx is done.
 func (c *Controller) Run(ctx context.Context) error {
+	if err := c.Start(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return nil
+}
+
+// Start subscribes and schedules the periodic area summary and step timeouts on the controller's clock,
+// then returns. They stop when ctx is done.
+func (c *Controller) Start(ctx context.Context) error {
 	// Subscribe to work orders: filter by our area_id, dispatch to zones.
-	if err := c.bus.Subscribe(ctx, messaging.TopicWorkOrders, c.handleWorkOrder); err != nil {
+	if err := messaging.SubscribeKeys(ctx, c.bus, messaging.TopicWorkOrders, []string{string(c.areaID)}, c.handleWorkOrder); err != nil {
 		return err
 	}
 	// Subscribe to zone summaries: aggregate and update local state.
-	if err := c.bus.Subscribe(ctx, messaging.TopicZoneSummary, c.handleZoneSummary); err != nil {
+	zoneKeys := make([]string, len(c.zones))
+	for i, z := range c.zones {
+		zoneKeys[i] = string(z)
+	}
+	if err := messaging.SubscribeKeys(ctx, c.bus, messaging.TopicZoneSummary, zoneKeys, c.handleZoneSummary); err != nil {
+		return err
+	}
+	// Subscribe to step results of multi-step work orders.
+	if err := messaging.SubscribeKeys(ctx, c.bus, messaging.TopicStepResults, zoneKeys, c.handleStepResult); err != nil {
 		return err
 	}
 
-	// Periodically publish area summary to fleet.
-	ticker := time.NewTicker(c.reportInterval)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		case <-ticker.C:
-			c.publishAreaSummary(ctx)
-		}
+	// Periodically publish area summary to fleet and time out steps without a result.
+	report := func() {
+		c.expireSteps()
+		c.publishAreaSummary(ctx)
 	}
+	c.mu.Lock()
+	c.report = report
+	c.stopReport = clock.Every(c.clock, c.reportInterval, report)
+	c.mu.Unlock()
+	context.AfterFunc(ctx, func() {
+		c.mu.Lock()
+		c.report = nil
+		c.stopReport()
+		c.mu.Unlock()
+	})
+	return nil
 }
 
 func (c *Controller) handleWorkOrder(key string, value []byte) error {
@@ -76,18 +126,77 @@ func (c *Controller) handleWorkOrder(key string, value []byte) error {
 	if order.AreaID != c.areaID {
 		return nil
 	}
+	if !c.seen.First(string(order.ID), c.clock.Now()) {
+		log.Printf("area %s: dropping redelivered work order %s", c.areaID, order.ID)
+		return nil
+	}
+	if err := c.dispatchWorkOrder(order); err != nil {
+		c.seen.Forget(string(order.ID)) // let a redelivery try again
+		return err
+	}
+	return nil
+}
+
+// dispatchWorkOrder sends order to its zones: a safety broadcast to every targeted zone, a task graph step
+// by step, anything else to one capable zone.
+func (c *Controller) dispatchWorkOrder(order api.WorkOrder) error {
 	// Dispatch to one zone (round-robin or first). For simplicity: first zone.
 	if len(c.zones) == 0 {
 		log.Printf("area %s: no zones configured, dropping work order %s", c.areaID, order.ID)
 		return nil
 	}
-	zoneID := c.zones[int(c.taskSeq.Add(1))%len(c.zones)]
+	if targets, ok := c.safetyTargets(order.Payload); ok {
+		// Safety broadcasts go to every targeted zone, not round-robin.
+		for _, zoneID := range targets {
+			task := &api.ZoneTask{
+				ID:        api.TaskID(c.genTaskID(zoneID) + "-" + string(order.ID)),
+				ZoneID:    zoneID,
+				OrderID:   order.ID,
+				Payload:   order.Payload,
+				CreatedAt: c.clock.Now().UTC(),
+			}
+			if err := c.zonePub.PublishZoneTask(context.Background(), task); err != nil {
+				log.Printf("area %s: publish zone task: %v", c.areaID, err)
+				return err
+			}
+		}
+		log.Printf("area %s: safety work order %s -> zones %v", c.areaID, order.ID, targets)
+		return nil
+	}
+	if len(order.Steps) > 0 {
+		if err := api.ValidateSteps(order.Steps); err != nil {
+			log.Printf("area %s: rejecting work order %s: %v", c.areaID, order.ID, err)
+			return nil
+		}
+	}
+	c.mu.Lock()
+	steps := order.Steps
+	if len(steps) == 0 {
+		steps = c.transportSteps(order.Payload)
+	}
+	if len(steps) > 0 {
+		if c.graphs[order.ID] != nil {
+			c.mu.Unlock()
+			return nil // already running
+		}
+		tasks := c.startGraphLocked(order, steps)
+		c.mu.Unlock()
+		return c.publishSteps(tasks)
+	}
+	c.mu.Unlock()
+	zones := c.capableZones(order.Requirements)
+	if len(zones) == 0 {
+		log.Printf("area %s: rejecting work order %s: no zone has a robot that meets its requirements", c.areaID, order.ID)
+		return nil
+	}
+	zoneID := zones[int(c.taskSeq.Add(1))%len(zones)]
 	task := &api.ZoneTask{
-		ID:        api.TaskID(genTaskID(zoneID)),
-		ZoneID:    zoneID,
-		OrderID:   order.ID,
-		Payload:   order.Payload,
-		CreatedAt: time.Now().UTC(),
+		ID:           api.TaskID(c.genTaskID(zoneID)),
+		ZoneID:       zoneID,
+		OrderID:      order.ID,
+		Payload:      order.Payload,
+		CreatedAt:    c.clock.Now().UTC(),
+		Requirements: order.Requirements,
 	}
 	if err := c.zonePub.PublishZoneTask(context.Background(), task); err != nil {
 		log.Printf("area %s: publish zone task: %v", c.areaID, err)
@@ -97,6 +206,58 @@ func (c *Controller) handleWorkOrder(key string, value []byte) error {
 	return nil
 }
 
+// capableZones returns the zones that may take work with req: those whose last summary lists a robot model
+// meeting it or, while none does, those that have not reported their models. Without requirements every
+// zone may.
+func (c *Controller) capableZones(req *api.Requirements) []api.ZoneID {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.capableZonesLocked(req)
+}
+
+// capableZonesLocked is capableZones for callers holding c.mu.
+func (c *Controller) capableZonesLocked(req *api.Requirements) []api.ZoneID {
+	if req.IsZero() {
+		return c.zones
+	}
+	var capable, unknown []api.ZoneID
+	for _, z := range c.zones {
+		sum := c.zoneSummary[z]
+		switch {
+		case sum == nil || len(sum.Models) == 0:
+			unknown = append(unknown, z)
+		case api.CanServe(sum.Models, req):
+			capable = append(capable, z)
+		}
+	}
+	if len(capable) == 0 {
+		return unknown
+	}
+	return capable
+}
+
+// safetyTargets reports whether payload is a stop_all / reset_all broadcast and returns its zones:
+// the payload's zone_id if set (and owned by this area), otherwise every zone in the area.
+func (c *Controller) safetyTargets(payload []byte) ([]api.ZoneID, bool) {
+	var p struct {
+		Type   string     `json:"type"`
+		ZoneID api.ZoneID `json:"zone_id"`
+	}
+	if len(payload) == 0 || json.Unmarshal(payload, &p) != nil {
+		return nil, false
+	}
+	if p.Type != api.ZoneTaskTypeStopAll && p.Type != api.ZoneTaskTypeResetAll {
+		return nil, false
+	}
+	if p.ZoneID == "" {
+		return c.zones, true
+	}
+	if !c.ownsZone(p.ZoneID) {
+		return nil, true
+	}
+	return []api.ZoneID{p.ZoneID}, true
+}
+
 func (c *Controller) handleZoneSummary(key string, value []byte) error {
 	var sum api.ZoneSummary
 	if err := json.Unmarshal(value, &sum); err != nil {
@@ -125,25 +286,33 @@ func (c *Controller) publishAreaSummary(ctx context.Context) {
 	c.mu.RLock()
 	zoneCount := 0
 	robotCount := 0
+	sum := &api.AreaSummary{AreaID: c.areaID}
+	var chargerTime float64
 	for _, s := range c.zoneSummary {
 		if s != nil {
 			zoneCount++
 			robotCount += s.RobotCount
+			sum.Chargers += s.Chargers
+			sum.ChargersInUse += s.ChargersInUse
+			sum.ChargeQueue += s.ChargeQueue
+			chargerTime += s.ChargerUtilization * float64(s.Chargers)
+			sum.Models = api.MergeModelCounts(sum.Models, s.Models)
 		}
 	}
+	sum.TaskGraphs = c.graphStatsLocked()
 	c.mu.RUnlock()
 
-	sum := &api.AreaSummary{
-		AreaID:     c.areaID,
-		ZoneCount:  zoneCount,
-		RobotCount: robotCount,
-		UpdatedAt:  time.Now().UTC(),
+	sum.ZoneCount = zoneCount
+	sum.RobotCount = robotCount
+	sum.UpdatedAt = c.clock.Now().UTC()
+	if sum.Chargers > 0 {
+		sum.ChargerUtilization = math.Round(chargerTime/float64(sum.Chargers)*1000) / 1000
 	}
 	if err := c.areaPub.PublishAreaSummary(ctx, sum); err != nil {
 		log.Printf("area %s: publish area summary: %v", c.areaID, err)
 	}
 }
 
-func genTaskID(zoneID api.ZoneID) string {
-	return string(zoneID) + "-" + time.Now().Format("20060102150405")
+func (c *Controller) genTaskID(zoneID api.ZoneID) string {
+	return fmt.Sprintf("%s-%s-%d", zoneID, c.clock.Now().Format("20060102150405"), c.idSeq.Add(1))
 }
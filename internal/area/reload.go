@@ -0,0 +1,69 @@
+package area
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/clock"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+)
+
+// reloadable are the settings Reload applies to a running controller; other changes need a restart.
+var reloadable = []string{"area.report_interval", "area.task_graphs"}
+
+// Reload applies the reloadable settings of next, a validated config read again from the same file, and
+// returns the config now in effect: cur with those settings taken from next. Other changed settings are
+// logged as needing a restart.
+func (c *Controller) Reload(ctx context.Context, cur, next *Config) *Config {
+	eff := *cur
+	var applied, restart []string
+	for _, p := range config.Changed(cur, next) {
+		if config.Under(p, reloadable...) {
+			applied = append(applied, p)
+		} else {
+			restart = append(restart, p)
+		}
+	}
+	a := next.Area
+	if cur.Area.ReportInterval != a.ReportInterval {
+		d, _ := a.ReportPeriod()
+		c.SetReportInterval(d)
+		eff.Area.ReportInterval = a.ReportInterval
+	}
+	if !reflect.DeepEqual(cur.Area.TaskGraphs, a.TaskGraphs) {
+		p, _ := a.TaskGraphs.Policy()
+		c.SetTaskGraphPolicy(p)
+		eff.Area.TaskGraphs = a.TaskGraphs
+	}
+	if len(applied) > 0 {
+		log.Printf("area %s: config reloaded: %s", c.areaID, strings.Join(applied, ", "))
+	}
+	if len(restart) > 0 {
+		log.Printf("area %s: config: %s changed; restart to apply", c.areaID, strings.Join(restart, ", "))
+	}
+	return &eff
+}
+
+// SetReportInterval sets how often the area summary is published and step timeouts are checked. Safe to
+// call while running.
+func (c *Controller) SetReportInterval(d time.Duration) {
+	c.mu.Lock()
+	c.reportInterval = d
+	report, stop := c.report, c.stopReport
+	c.mu.Unlock()
+	if report == nil {
+		return
+	}
+	stop()
+	next := clock.Every(c.clock, d, report)
+	c.mu.Lock()
+	if c.report == nil {
+		next() // stopped meanwhile
+	} else {
+		c.stopReport = next
+	}
+	c.mu.Unlock()
+}
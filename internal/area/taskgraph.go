@@ -0,0 +1,413 @@
+package area
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// TaskGraphPolicy controls how the area runs multi-step work orders.
+type TaskGraphPolicy struct {
+	StepTimeout time.Duration // a running step without a result this long has failed
+	RetryDelay  time.Duration // wait before a failed step's next attempt, e.g. for its robot to recover
+	Decompose   []string      // payload types of transport orders split into go-to, pick, go-to, place
+	Retries     int           // extra attempts per step of a decomposed order
+}
+
+// DefaultTaskGraphPolicy fails steps after 15 minutes, retries after 30 seconds and runs transport orders
+// as single tasks.
+func DefaultTaskGraphPolicy() TaskGraphPolicy {
+	return TaskGraphPolicy{StepTimeout: 15 * time.Minute, RetryDelay: 30 * time.Second}
+}
+
+// SetTaskGraphPolicy replaces the task graph policy. Safe to call while running; steps already waiting for
+// a retry or timeout keep the old delay.
+func (c *Controller) SetTaskGraphPolicy(p TaskGraphPolicy) {
+	c.mu.Lock()
+	c.graphPolicy = p
+	c.mu.Unlock()
+}
+
+// Step states.
+const (
+	stepPending   = "pending"
+	stepRunning   = "running"
+	stepRetrying  = "retrying" // waiting for the next attempt
+	stepSucceeded = "succeeded"
+	stepFailed    = "failed"
+)
+
+// graphRun is a multi-step work order in progress.
+type graphRun struct {
+	order  api.WorkOrder
+	steps  []*stepRun // in the order's order
+	byID   map[string]*stepRun
+	done   []*stepRun // succeeded steps, in completion order
+	failed string     // why the order failed; set once a step is out of attempts
+	undo   []*stepRun // compensating steps still to run, next first
+	undone bool       // undo has been filled in
+}
+
+// stepRun is one step of a graphRun, or one compensating step.
+type stepRun struct {
+	graph    *graphRun
+	step     api.TaskStep
+	state    string
+	attempts int
+	taskID   api.TaskID // of the current attempt
+	zoneID   api.ZoneID
+	robotID  api.RobotID // robot that did (or must do) the step
+	started  time.Time
+	undoing  bool // compensating step
+}
+
+// startGraphLocked starts a multi-step work order and returns the zone tasks of its first steps. Caller
+// holds c.mu and publishes the tasks after unlocking.
+func (c *Controller) startGraphLocked(order api.WorkOrder, steps []api.TaskStep) []*api.ZoneTask {
+	g := &graphRun{order: order, byID: make(map[string]*stepRun, len(steps))}
+	for _, s := range steps {
+		sr := &stepRun{graph: g, step: s, state: stepPending}
+		g.steps = append(g.steps, sr)
+		g.byID[s.ID] = sr
+	}
+	c.graphs[order.ID] = g
+	log.Printf("area %s: work order %s -> task graph of %d steps", c.areaID, order.ID, len(steps))
+	return c.advanceGraphLocked(g)
+}
+
+// advanceGraphLocked starts every pending step whose predecessors have succeeded. Once a step has failed
+// for good no new steps start; when the running ones have ended, the succeeded steps are compensated one at
+// a time in reverse order. Caller holds c.mu.
+func (c *Controller) advanceGraphLocked(g *graphRun) []*api.ZoneTask {
+	var tasks []*api.ZoneTask
+	if g.failed == "" {
+		complete := true
+		for _, sr := range g.steps {
+			if sr.state != stepSucceeded {
+				complete = false
+			}
+			if sr.state != stepPending || !g.ready(sr) {
+				continue
+			}
+			task, err := c.dispatchStepLocked(sr)
+			if err != "" {
+				c.failStepLocked(sr, err)
+				break
+			}
+			tasks = append(tasks, task)
+		}
+		if complete {
+			c.finishGraphLocked(g)
+			return nil
+		}
+		if g.failed == "" {
+			return tasks
+		}
+	}
+	for _, sr := range g.steps {
+		if sr.state == stepRunning || sr.state == stepRetrying {
+			return tasks // compensate once nothing else runs
+		}
+	}
+	if !g.undone {
+		g.undo, g.undone = g.compensations(), true
+	}
+	for len(g.undo) > 0 {
+		sr := g.undo[0]
+		g.undo = g.undo[1:]
+		task, err := c.dispatchStepLocked(sr)
+		if err != "" {
+			log.Printf("area %s: work order %s: compensating step %s: %s", c.areaID, g.order.ID, sr.step.ID, err)
+			continue
+		}
+		c.graphStats.Compensations++
+		return append(tasks, task) // the next one starts when this one ends
+	}
+	c.finishGraphLocked(g)
+	return tasks
+}
+
+// ready reports whether every step sr runs after has succeeded.
+func (g *graphRun) ready(sr *stepRun) bool {
+	for _, dep := range sr.step.After {
+		if g.byID[dep].state != stepSucceeded {
+			return false
+		}
+	}
+	return true
+}
+
+// dispatchStepLocked makes the zone task of a step's next attempt: to the step's zone, the zone of its
+// SameRobot step, or the next capable zone. It returns why not if there is none. Caller holds c.mu.
+func (c *Controller) dispatchStepLocked(sr *stepRun) (*api.ZoneTask, string) {
+	g := sr.graph
+	req := sr.step.Requirements
+	if req == nil {
+		req = g.order.Requirements
+	}
+	zoneID, robotID := sr.step.ZoneID, sr.robotID
+	if same := g.byID[sr.step.SameRobot]; same != nil && !sr.undoing {
+		robotID = same.robotID
+		if zoneID == "" {
+			zoneID = same.zoneID
+		}
+	}
+	if sr.undoing {
+		zoneID = sr.zoneID
+	}
+	switch {
+	case zoneID != "" && !c.ownsZone(zoneID):
+		return nil, fmt.Sprintf("zone %s is not in area %s", zoneID, c.areaID)
+	case zoneID == "":
+		zones := c.capableZonesLocked(req)
+		if len(zones) == 0 {
+			return nil, "no zone has a robot that meets its requirements"
+		}
+		zoneID = zones[int(c.taskSeq.Add(1))%len(zones)]
+	}
+	now := c.clock.Now()
+	sr.attempts++
+	sr.state, sr.started = stepRunning, now
+	sr.taskID = api.TaskID(c.genTaskID(zoneID) + "-" + sr.step.ID)
+	sr.zoneID = zoneID
+	c.stepTasks[sr.taskID] = sr
+	return &api.ZoneTask{
+		ID:           sr.taskID,
+		ZoneID:       zoneID,
+		OrderID:      g.order.ID,
+		Payload:      sr.step.Payload,
+		CreatedAt:    now.UTC(),
+		Requirements: req,
+		StepID:       sr.step.ID,
+		Type:         sr.step.Type,
+		RobotID:      robotID,
+	}, ""
+}
+
+// handleStepResult applies a step result forwarded by a zone and starts whatever it unblocks.
+func (c *Controller) handleStepResult(key string, value []byte) error {
+	var res api.TaskResult
+	if err := json.Unmarshal(value, &res); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	sr := c.stepTasks[res.TaskID]
+	if sr == nil {
+		c.mu.Unlock()
+		return nil
+	}
+	tasks := c.stepEndedLocked(sr, &res)
+	c.mu.Unlock()
+	return c.publishSteps(tasks)
+}
+
+// stepEndedLocked records how a step attempt ended: a success gates the steps after it; a failure is
+// retried while the step has attempts left and otherwise fails the order. Caller holds c.mu.
+func (c *Controller) stepEndedLocked(sr *stepRun, res *api.TaskResult) []*api.ZoneTask {
+	delete(c.stepTasks, sr.taskID)
+	g := sr.graph
+	switch {
+	case res.Status == api.TaskResultSucceeded:
+		sr.state = stepSucceeded
+		if res.RobotID != "" {
+			sr.robotID = res.RobotID
+		}
+		if !sr.undoing {
+			g.done = append(g.done, sr)
+		}
+	case sr.attempts <= sr.step.Retries && (g.failed == "" || sr.undoing):
+		log.Printf("area %s: work order %s: step %s failed (%s), retrying in %v", c.areaID, g.order.ID, sr.step.ID, res.Error, c.graphPolicy.RetryDelay)
+		c.graphStats.Retries++
+		c.retryLater(sr)
+		return nil
+	case sr.undoing:
+		sr.state = stepFailed
+		log.Printf("area %s: work order %s: compensating step %s failed: %s", c.areaID, g.order.ID, sr.step.ID, res.Error)
+	default:
+		c.failStepLocked(sr, res.Error)
+	}
+	return c.advanceGraphLocked(g)
+}
+
+// retryLater starts the step's next attempt after the retry delay, unless its order has failed meanwhile.
+// Caller holds c.mu.
+func (c *Controller) retryLater(sr *stepRun) {
+	sr.state = stepRetrying
+	c.clock.AfterFunc(c.graphPolicy.RetryDelay, func() {
+		c.mu.Lock()
+		g := sr.graph
+		var tasks []*api.ZoneTask
+		switch {
+		case g.failed != "" && !sr.undoing:
+			sr.state = stepFailed
+			tasks = c.advanceGraphLocked(g)
+		default:
+			task, err := c.dispatchStepLocked(sr)
+			if err == "" {
+				tasks = append(tasks, task)
+				break
+			}
+			c.failStepLocked(sr, err)
+			tasks = c.advanceGraphLocked(g)
+		}
+		c.mu.Unlock()
+		if err := c.publishSteps(tasks); err != nil {
+			log.Printf("area %s: %v", c.areaID, err)
+		}
+	})
+}
+
+// failStepLocked fails the order of a step that is out of attempts. Caller holds c.mu.
+func (c *Controller) failStepLocked(sr *stepRun, reason string) {
+	sr.state = stepFailed
+	g := sr.graph
+	if g.failed != "" {
+		return
+	}
+	g.failed = fmt.Sprintf("step %s: %s", sr.step.ID, reason)
+	log.Printf("area %s: work order %s failed: %s", c.areaID, g.order.ID, g.failed)
+}
+
+// compensations returns the compensating steps of the succeeded steps, latest first, each on the robot
+// and in the zone of the step it undoes.
+func (g *graphRun) compensations() []*stepRun {
+	var undo []*stepRun
+	for i := len(g.done) - 1; i >= 0; i-- {
+		done := g.done[i]
+		if done.step.Compensate == nil {
+			continue
+		}
+		step := *done.step.Compensate
+		if step.ID == "" {
+			step.ID = done.step.ID + "-compensate"
+		}
+		undo = append(undo, &stepRun{
+			graph:   g,
+			step:    step,
+			state:   stepPending,
+			zoneID:  done.zoneID,
+			robotID: done.robotID,
+			undoing: true,
+		})
+	}
+	return undo
+}
+
+// finishGraphLocked ends a multi-step work order. Caller holds c.mu.
+func (c *Controller) finishGraphLocked(g *graphRun) {
+	delete(c.graphs, g.order.ID)
+	if g.failed != "" {
+		c.graphStats.Failed++
+		log.Printf("area %s: work order %s: compensation done", c.areaID, g.order.ID)
+		return
+	}
+	c.graphStats.Succeeded++
+	log.Printf("area %s: work order %s done (%d steps)", c.areaID, g.order.ID, len(g.steps))
+}
+
+// expireSteps fails running steps that have gone without a result for longer than the step timeout, e.g.
+// because their robot went silent or their zone is down.
+func (c *Controller) expireSteps() {
+	now := c.clock.Now()
+	c.mu.Lock()
+	var tasks []*api.ZoneTask
+	for _, sr := range c.stepTasks {
+		if now.Sub(sr.started) < c.graphPolicy.StepTimeout || c.stepTasks[sr.taskID] != sr {
+			continue
+		}
+		tasks = append(tasks, c.stepEndedLocked(sr, &api.TaskResult{
+			TaskID: sr.taskID,
+			Status: api.TaskResultFailed,
+			Error:  "timed out",
+			At:     now.UTC(),
+		})...)
+	}
+	c.mu.Unlock()
+	if err := c.publishSteps(tasks); err != nil {
+		log.Printf("area %s: %v", c.areaID, err)
+	}
+}
+
+func (c *Controller) publishSteps(tasks []*api.ZoneTask) error {
+	for _, task := range tasks {
+		if err := c.zonePub.PublishZoneTask(context.Background(), task); err != nil {
+			return fmt.Errorf("publish zone task: %w", err)
+		}
+		log.Printf("area %s: work order %s step %s -> zone %s task %s", c.areaID, task.OrderID, task.StepID, task.ZoneID, task.ID)
+	}
+	return nil
+}
+
+// graphStatsLocked returns the task graph counters for the area summary, or nil while the area has run no
+// multi-step order. Caller holds c.mu.
+func (c *Controller) graphStatsLocked() *api.TaskGraphStats {
+	stats := c.graphStats
+	stats.Running = len(c.graphs)
+	if stats == (api.TaskGraphStats{}) {
+		return nil
+	}
+	return &stats
+}
+
+// transportSteps splits a transport payload whose type is in the policy's Decompose list into go-to pickup,
+// pick, go-to drop and place, all on one robot. A pick is undone by placing the load back at the pickup.
+// It returns nil for other payloads and payloads without both locations. Caller holds c.mu.
+func (c *Controller) transportSteps(payload []byte) []api.TaskStep {
+	if len(c.graphPolicy.Decompose) == 0 || len(payload) == 0 {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	var loc api.TaskLocations
+	if json.Unmarshal(payload, &fields) != nil || json.Unmarshal(payload, &loc) != nil {
+		return nil
+	}
+	var typ string
+	_ = json.Unmarshal(fields["type"], &typ)
+	decompose := false
+	for _, t := range c.graphPolicy.Decompose {
+		decompose = decompose || t == typ
+	}
+	hasFrom := loc.From != nil || loc.FromNodeID != "" || loc.FromLocationID != ""
+	hasTo := loc.To != nil || loc.ToNodeID != "" || loc.ToLocationID != ""
+	if !decompose || !hasFrom || !hasTo {
+		return nil
+	}
+	for _, k := range []string{"from", "to", "from_node_id", "to_node_id", "from_location_id", "to_location_id"} {
+		delete(fields, k)
+	}
+	goTo := func(p *api.Point, nodeID, locationID string) json.RawMessage {
+		data, _ := json.Marshal(api.TaskLocations{To: p, ToNodeID: nodeID, ToLocationID: locationID})
+		return data
+	}
+	// PICK and PLACE carry the order's other fields (SKU, quantity, ...) and the location they handle; at
+	// adds where to drive first.
+	handle := func(locationID string, at json.RawMessage) json.RawMessage {
+		out := make(map[string]json.RawMessage, len(fields)+4)
+		for k, v := range fields {
+			out[k] = v
+		}
+		var extra map[string]json.RawMessage
+		_ = json.Unmarshal(at, &extra)
+		for k, v := range extra {
+			out[k] = v
+		}
+		if locationID != "" {
+			out["location_id"], _ = json.Marshal(locationID)
+		}
+		data, _ := json.Marshal(out)
+		return data
+	}
+	retries := c.graphPolicy.Retries
+	back := goTo(loc.From, loc.FromNodeID, loc.FromLocationID)
+	return []api.TaskStep{
+		{ID: "to-pickup", Type: api.RobotCommandTypeGoTo, Payload: goTo(loc.From, loc.FromNodeID, loc.FromLocationID), Retries: retries},
+		{ID: "pick", Type: api.RobotCommandTypePick, Payload: handle(loc.FromLocationID, nil), After: []string{"to-pickup"}, SameRobot: "to-pickup", Retries: retries,
+			Compensate: &api.TaskStep{Type: api.RobotCommandTypePlace, Payload: handle(loc.FromLocationID, back), Retries: retries}},
+		{ID: "to-drop", Type: api.RobotCommandTypeGoTo, Payload: goTo(loc.To, loc.ToNodeID, loc.ToLocationID), After: []string{"pick"}, SameRobot: "to-pickup", Retries: retries},
+		{ID: "place", Type: api.RobotCommandTypePlace, Payload: handle(loc.ToLocationID, nil), After: []string{"to-drop"}, SameRobot: "to-pickup", Retries: retries},
+	}
+}
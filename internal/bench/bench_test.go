@@ -0,0 +1,91 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/internal/topology"
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+// go test ./internal/bench -run '^$' -bench . -benchmem
+// The NATS variants run when BENCH_NATS_URL is set, e.g. BENCH_NATS_URL=nats://localhost:4222.
+
+func BenchmarkDispatchMemoryBus(b *testing.B) {
+	benchmarkDispatch(b, "memory")
+}
+
+func BenchmarkDispatchNATS(b *testing.B) {
+	url := os.Getenv("BENCH_NATS_URL")
+	if url == "" {
+		b.Skip("BENCH_NATS_URL not set")
+	}
+	benchmarkDispatch(b, url)
+}
+
+// benchmarkDispatch submits b.N work orders and waits until each has reached the edge as a robot command.
+func benchmarkDispatch(b *testing.B, busURL string) {
+	for _, spec := range [][3]int{{1, 1, 10}, {10, 50, 10}} {
+		b.Run(fmt.Sprintf("%dx%dx%d", spec[0], spec[1], spec[2]), func(b *testing.B) {
+			ctx := context.Background()
+			s, err := NewStack(ctx, StackOptions{
+				BusURL:         busURL,
+				Topology:       topology.Generate(spec[0], spec[1], spec[2]),
+				StatusInterval: time.Hour, // keep status traffic out of the dispatch path
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer s.Close()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := s.Submit(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if !s.Wait(int64(b.N), 30*time.Second) {
+				b.Fatalf("dispatched %d of %d orders", s.Dispatched(), b.N)
+			}
+			b.StopTimer()
+			dispatch, _ := s.Latencies()
+			p := percentiles(dispatch)
+			b.ReportMetric(p.P50, "p50-ms")
+			b.ReportMetric(p.P99, "p99-ms")
+		})
+	}
+}
+
+// BenchmarkStatusFanIn publishes robot status into a 5000-zone topology: the cost of routing one status to
+// its zone controller among thousands of subscribers.
+func BenchmarkStatusFanIn(b *testing.B) {
+	ctx := context.Background()
+	topo := topology.Generate(50, 100, 10)
+	s, err := NewStack(ctx, StackOptions{Topology: topo, StatusInterval: time.Hour, ZoneReport: time.Hour, AreaReport: time.Hour})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer s.Close()
+	pub := messaging.NewRobotStatusPublisher(s.buses["edge"])
+	_, _, robots := topo.Counts()
+	status := &api.RobotStatus{State: "IDLE", Battery: 80}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		status.RobotID = api.RobotID(fmt.Sprintf("robot-%d", i%robots+1))
+		if err := pub.PublishRobotStatus(ctx, status); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard) // controllers log every dispatch
+	os.Exit(m.Run())
+}
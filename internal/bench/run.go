@@ -0,0 +1,277 @@
+package bench
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/telemetry"
+)
+
+// ReportVersion is bumped when Report fields change meaning, so stored reports are compared like for like.
+const ReportVersion = 1
+
+// Options describe one load run. Zero fields take defaults.
+type Options struct {
+	StackOptions
+	Rate     float64       // work orders per second (default 1000)
+	Duration time.Duration // load phase (default 10s)
+	Drain    time.Duration // wait for in-flight orders after the load phase (default 5s)
+	Workers  int           // concurrent submitters (default 4)
+}
+
+func (o *Options) setDefaults() {
+	if o.Rate <= 0 {
+		o.Rate = 1000
+	}
+	if o.Duration <= 0 {
+		o.Duration = 10 * time.Second
+	}
+	if o.Drain <= 0 {
+		o.Drain = 5 * time.Second
+	}
+	if o.Workers <= 0 {
+		o.Workers = 4
+	}
+}
+
+// Report is the machine-readable outcome of a run. Durations are in milliseconds, CPU in seconds.
+type Report struct {
+	Version    int       `json:"version"`
+	StartedAt  time.Time `json:"started_at"`
+	GoVersion  string    `json:"go_version"`
+	GOMAXPROCS int       `json:"gomaxprocs"`
+	Bus        string    `json:"bus"`
+
+	Areas          int     `json:"areas"`
+	Zones          int     `json:"zones"`
+	Robots         int     `json:"robots"`
+	TargetRate     float64 `json:"target_orders_per_sec"`
+	DurationSec    float64 `json:"duration_sec"`
+	StatusInterval float64 `json:"status_interval_sec"`
+	ZoneReport     float64 `json:"zone_report_sec"`
+	AreaReport     float64 `json:"area_report_sec"`
+
+	OrdersSubmitted  int64       `json:"orders_submitted"`
+	OrdersDispatched int64       `json:"orders_dispatched"`
+	OrdersLost       int64       `json:"orders_lost"` // not dispatched by the end of the drain
+	SubmitErrors     int64       `json:"submit_errors"`
+	AchievedRate     float64     `json:"orders_per_sec"`
+	DispatchLatency  Percentiles `json:"dispatch_latency_ms"` // fleet submit → robot command at the edge
+	SummaryLag       Percentiles `json:"summary_lag_ms"`      // zone summary → fleet sees the area summary including it
+
+	BusMessages   int64                 `json:"bus_messages"` // published, all topics
+	BusMsgsPerSec float64               `json:"bus_msgs_per_sec"`
+	BusMBPerSec   float64               `json:"bus_mb_per_sec"`
+	Topics        map[string]TopicStats `json:"topics"`
+
+	CPUSeconds      float64            `json:"cpu_sec"` // sampled by the CPU profiler during the load phase
+	CPUCores        float64            `json:"cpu_cores"`
+	CPUByLayer      map[string]float64 `json:"cpu_sec_by_layer"` // "other": runtime, GC, unlabelled
+	CPUPerOrderUsec float64            `json:"cpu_usec_per_order"`
+
+	HeapByLayer      map[string]uint64 `json:"heap_bytes_by_layer"` // retained by each layer's setup
+	HeapPeakBytes    uint64            `json:"heap_peak_bytes"`
+	HeapEndBytes     uint64            `json:"heap_end_bytes"`
+	AllocBytesPerOrd float64           `json:"alloc_bytes_per_order"`
+	GCCycles         uint32            `json:"gc_cycles"`
+	Goroutines       int               `json:"goroutines"` // at the end of the load phase
+
+	WallSec float64 `json:"wall_sec"`
+}
+
+// Percentiles summarize a latency distribution in milliseconds.
+type Percentiles struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	P999  float64 `json:"p999"`
+	Max   float64 `json:"max"`
+}
+
+// TopicStats counts published messages on one topic.
+type TopicStats struct {
+	Messages int64 `json:"messages"`
+	Bytes    int64 `json:"bytes"`
+}
+
+// Run starts a stack, submits orders at opts.Rate for opts.Duration, waits for stragglers and reports.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	opts.setDefaults()
+	wallStart := time.Now()
+	s, err := NewStack(ctx, opts.StackOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	opts.StackOptions = s.opts
+	areas, zones, robots := opts.Topology.Counts()
+	r := &Report{
+		Version:        ReportVersion,
+		StartedAt:      wallStart.UTC(),
+		GoVersion:      runtime.Version(),
+		GOMAXPROCS:     runtime.GOMAXPROCS(0),
+		Bus:            opts.BusURL,
+		Areas:          areas,
+		Zones:          zones,
+		Robots:         robots,
+		TargetRate:     opts.Rate,
+		DurationSec:    opts.Duration.Seconds(),
+		StatusInterval: opts.StatusInterval.Seconds(),
+		ZoneReport:     opts.ZoneReport.Seconds(),
+		AreaReport:     opts.AreaReport.Seconds(),
+		HeapByLayer:    make(map[string]uint64),
+	}
+	for _, u := range s.Layers.Report().Layers {
+		r.HeapByLayer[u.Layer] = u.HeapBytes
+	}
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+	stopCPU, err := telemetry.StartCPUProfile()
+	if err != nil {
+		return nil, err
+	}
+	heapDone := make(chan struct{})
+	heapPeak := sampleHeap(heapDone)
+	loadStart := time.Now()
+	r.SubmitErrors = generate(ctx, s, opts)
+	loadTime := time.Since(loadStart)
+	cpu, err := stopCPU()
+	close(heapDone)
+	if err != nil {
+		return nil, err
+	}
+	r.Goroutines = runtime.NumGoroutine()
+	busStats := s.BusStats()
+
+	s.Wait(s.Submitted(), opts.Drain)
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	r.OrdersSubmitted = s.Submitted()
+	r.OrdersDispatched = s.Dispatched()
+	r.OrdersLost = r.OrdersSubmitted - r.OrdersDispatched
+	r.AchievedRate = round(float64(r.OrdersSubmitted)/loadTime.Seconds(), 1)
+	dispatch, lags := s.Latencies()
+	r.DispatchLatency = percentiles(dispatch)
+	r.SummaryLag = percentiles(lags)
+
+	r.Topics = busStats
+	var bytes int64
+	for _, t := range busStats {
+		r.BusMessages += t.Messages
+		bytes += t.Bytes
+	}
+	r.BusMsgsPerSec = round(float64(r.BusMessages)/loadTime.Seconds(), 1)
+	r.BusMBPerSec = round(float64(bytes)/loadTime.Seconds()/1e6, 3)
+
+	r.CPUByLayer = make(map[string]float64)
+	var total time.Duration
+	for layer, d := range cpu {
+		if layer == "" {
+			layer = "other"
+		}
+		r.CPUByLayer[layer] = round(d.Seconds(), 3)
+		total += d
+	}
+	r.CPUSeconds = round(total.Seconds(), 3)
+	r.CPUCores = round(total.Seconds()/loadTime.Seconds(), 2)
+	if r.OrdersSubmitted > 0 {
+		r.CPUPerOrderUsec = round(float64(total.Microseconds())/float64(r.OrdersSubmitted), 1)
+		r.AllocBytesPerOrd = round(float64(after.TotalAlloc-before.TotalAlloc)/float64(r.OrdersSubmitted), 0)
+	}
+	r.HeapPeakBytes = max(<-heapPeak, after.HeapAlloc)
+	r.HeapEndBytes = after.HeapAlloc
+	r.GCCycles = after.NumGC - before.NumGC
+	r.WallSec = round(time.Since(wallStart).Seconds(), 2)
+	return r, nil
+}
+
+// generate submits orders on a fixed schedule from opts.Workers goroutines: worker w sends every
+// opts.Workers-th order, so together they hold the target rate. It returns the number of failed submits.
+func generate(ctx context.Context, s *Stack, opts Options) int64 {
+	total := int64(opts.Rate * opts.Duration.Seconds())
+	start := time.Now()
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errors int64
+	)
+	for w := 0; w < opts.Workers; w++ {
+		wg.Add(1)
+		telemetry.Go(ctx, "fleet", func(ctx context.Context) {
+			defer wg.Done()
+			for i := int64(w); i < total; i += int64(opts.Workers) {
+				due := start.Add(time.Duration(float64(i) / opts.Rate * float64(time.Second)))
+				if d := time.Until(due); d > 0 {
+					time.Sleep(d)
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				if err := s.Submit(ctx); err != nil {
+					mu.Lock()
+					errors++
+					mu.Unlock()
+				}
+			}
+		})
+	}
+	wg.Wait()
+	return errors
+}
+
+// sampleHeap polls HeapAlloc until done is closed and then sends the peak.
+func sampleHeap(done <-chan struct{}) <-chan uint64 {
+	out := make(chan uint64, 1)
+	go func() {
+		var peak uint64
+		t := time.NewTicker(100 * time.Millisecond)
+		defer t.Stop()
+		var m runtime.MemStats
+		for {
+			runtime.ReadMemStats(&m)
+			peak = max(peak, m.HeapAlloc)
+			select {
+			case <-done:
+				out <- peak
+				return
+			case <-t.C:
+			}
+		}
+	}()
+	return out
+}
+
+func percentiles(d []time.Duration) Percentiles {
+	if len(d) == 0 {
+		return Percentiles{}
+	}
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+	ms := func(x time.Duration) float64 { return round(float64(x)/float64(time.Millisecond), 3) }
+	at := func(q float64) float64 { return ms(d[int(math.Ceil(q*float64(len(d))))-1]) }
+	var sum time.Duration
+	for _, x := range d {
+		sum += x
+	}
+	return Percentiles{
+		Count: len(d),
+		Mean:  ms(sum / time.Duration(len(d))),
+		P50:   at(0.50),
+		P90:   at(0.90),
+		P99:   at(0.99),
+		P999:  at(0.999),
+		Max:   ms(d[len(d)-1]),
+	}
+}
+
+func round(f float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(f*p) / p
+}
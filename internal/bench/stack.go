@@ -0,0 +1,365 @@
+// Package bench drives work orders through fleet → area → zone → edge on a MemoryBus or a NATS server and
+// measures dispatch latency, bus throughput, CPU and memory per layer and summary propagation lag.
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/internal/area"
+	"github.com/robotfleetos/robotfleetos/internal/edge"
+	"github.com/robotfleetos/robotfleetos/internal/fleet"
+	"github.com/robotfleetos/robotfleetos/internal/topology"
+	"github.com/robotfleetos/robotfleetos/internal/zone"
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/clock"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+	"github.com/robotfleetos/robotfleetos/pkg/telemetry"
+)
+
+// Layers in the order they are set up; each has its own (labelled) view of the bus.
+var layerNames = []string{"fleet", "area", "zone", "edge"}
+
+// StackOptions configure the control hierarchy under test. Zero fields take defaults.
+type StackOptions struct {
+	BusURL         string                // "" or "memory": one MemoryBus; otherwise a NATS URL, one connection per layer
+	BusConn        messaging.ConnOptions // NATS TLS and credentials, shared by every layer's connection
+	Topology       *topology.Topology    // default 2x10x20
+	StatusInterval time.Duration         // robot status (default 5s)
+	ZoneReport     time.Duration         // zone summary interval (default 1s)
+	AreaReport     time.Duration         // area summary interval (default 2s)
+	TaskDuration   time.Duration         // simulated task length (default 2s)
+}
+
+func (o *StackOptions) setDefaults() {
+	if o.BusURL == "" {
+		o.BusURL = "memory"
+	}
+	if o.Topology == nil {
+		o.Topology = topology.Generate(2, 10, 20)
+	}
+	if o.StatusInterval <= 0 {
+		o.StatusInterval = 5 * time.Second
+	}
+	if o.ZoneReport <= 0 {
+		o.ZoneReport = time.Second
+	}
+	if o.AreaReport <= 0 {
+		o.AreaReport = 2 * time.Second
+	}
+	if o.TaskDuration <= 0 {
+		o.TaskDuration = 2 * time.Second
+	}
+}
+
+// Stack is a running hierarchy with probes on the bus: submitted orders are timed until their robot
+// command reaches the edge side, and zone summaries until the fleet sees an area summary that includes them.
+type Stack struct {
+	opts      StackOptions
+	ctx       context.Context
+	cancel    context.CancelFunc
+	buses     map[string]*countingBus
+	closers   []func()
+	scheduler *fleet.Scheduler
+	areaIDs   []api.AreaID
+	Layers    telemetry.Layers
+
+	seq        atomic.Int64
+	dispatched atomic.Int64
+	mu         sync.Mutex
+	sent       map[int64]time.Time
+	latencies  []time.Duration
+	zoneArea   map[api.ZoneID]api.AreaID
+	pending    map[api.AreaID][]time.Time // zone summaries not yet reflected at the fleet
+	lags       []time.Duration
+	notify     chan struct{}
+}
+
+// NewStack starts fleet, area, zone and edge for the topology and subscribes the probes.
+func NewStack(ctx context.Context, opts StackOptions) (*Stack, error) {
+	opts.setDefaults()
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Stack{
+		opts:     opts,
+		ctx:      ctx,
+		cancel:   cancel,
+		buses:    make(map[string]*countingBus),
+		sent:     make(map[int64]time.Time),
+		zoneArea: make(map[api.ZoneID]api.AreaID),
+		pending:  make(map[api.AreaID][]time.Time),
+		notify:   make(chan struct{}, 1),
+	}
+	if err := s.connect(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	if err := s.start(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// connect gives each layer (and the probes) a bus. On MemoryBus they share one; on NATS each has its own
+// connection, as separate processes would.
+func (s *Stack) connect() error {
+	var shared *messaging.MemoryBus
+	if s.opts.BusURL == "memory" {
+		shared = messaging.NewMemoryBus()
+	}
+	for _, layer := range append(append([]string(nil), layerNames...), "probe") {
+		var bus messaging.Bus = shared
+		if shared == nil {
+			nb, err := messaging.NewNATSBus(s.opts.BusURL, s.opts.BusConn)
+			if err != nil {
+				return fmt.Errorf("bench: %s: %w", layer, err)
+			}
+			s.closers = append(s.closers, nb.Close)
+			bus = nb
+		}
+		s.buses[layer] = &countingBus{bus: telemetry.LabelBus(bus, layer)}
+	}
+	return nil
+}
+
+func (s *Stack) start() error {
+	topo := s.opts.Topology
+	clk := func(layer string) clock.Clock { return telemetry.LabelClock(clock.Real, layer) }
+	var err error
+	s.Layers.Measure("fleet", func() int {
+		bus := s.buses["fleet"]
+		s.scheduler = fleet.NewScheduler(messaging.NewWorkOrderPublisher(bus))
+		err = fleet.NewGlobalState().Run(s.ctx, bus)
+		return 1
+	})
+	if err != nil {
+		return err
+	}
+	s.Layers.Measure("area", func() int {
+		bus := s.buses["area"]
+		zonePub, areaPub := messaging.NewZoneTaskPublisher(bus), messaging.NewAreaSummaryPublisher(bus)
+		for _, a := range topo.Areas {
+			s.areaIDs = append(s.areaIDs, api.AreaID(a.AreaID))
+			for _, z := range a.Zones {
+				s.zoneArea[api.ZoneID(z.ZoneID)] = api.AreaID(a.AreaID)
+			}
+			ctrl := area.NewController(api.AreaID(a.AreaID), a.ZoneIDs(), zonePub, areaPub, bus, s.opts.AreaReport)
+			ctrl.SetClock(clk("area"))
+			if err == nil {
+				err = ctrl.Start(s.ctx)
+			}
+		}
+		return len(topo.Areas)
+	})
+	if err != nil {
+		return err
+	}
+	s.Layers.Measure("zone", func() int {
+		bus := s.buses["zone"]
+		cmdPub, sumPub := messaging.NewRobotCommandPublisher(bus), messaging.NewZoneSummaryPublisher(bus)
+		n := 0
+		for _, a := range topo.Areas {
+			for _, z := range a.Zones {
+				ctrl := zone.NewController(api.ZoneID(z.ZoneID), z.RobotIDs(), cmdPub, sumPub, bus, s.opts.ZoneReport)
+				ctrl.SetClock(clk("zone"))
+				if err == nil {
+					err = ctrl.Start(s.ctx)
+				}
+				n++
+			}
+		}
+		return n
+	})
+	if err != nil {
+		return err
+	}
+	s.Layers.Measure("edge", func() int {
+		bus := s.buses["edge"]
+		statusPub := messaging.NewRobotStatusPublisher(bus)
+		n := 0
+		for _, a := range topo.Areas {
+			for _, z := range a.Zones {
+				sim := edge.NewSimulator(api.ZoneID(z.ZoneID), z.RobotIDs(), statusPub, bus, s.opts.StatusInterval, s.opts.TaskDuration)
+				sim.SetClock(clk("edge"))
+				if err == nil {
+					err = sim.Start(s.ctx)
+				}
+				n++
+			}
+		}
+		return n
+	})
+	if err != nil {
+		return err
+	}
+	// Probes: robot commands as the edge receives them, zone summaries as the area receives them, area
+	// summaries as the fleet receives them.
+	if err := s.buses["probe"].Subscribe(s.ctx, messaging.TopicRobotCommands, s.handleCommand); err != nil {
+		return err
+	}
+	if err := s.buses["probe"].Subscribe(s.ctx, messaging.TopicZoneSummary, s.handleZoneSummary); err != nil {
+		return err
+	}
+	return s.buses["probe"].Subscribe(s.ctx, messaging.TopicAreaSummary, s.handleAreaSummary)
+}
+
+// benchPayload rides through area and zone unchanged in WorkOrder, ZoneTask and RobotCommand payloads.
+type benchPayload struct {
+	BenchSeq int64 `json:"bench_seq"`
+}
+
+// Submit sends one work order through the fleet scheduler, spreading orders over the areas.
+func (s *Stack) Submit(ctx context.Context) error {
+	seq := s.seq.Add(1)
+	payload, _ := json.Marshal(benchPayload{BenchSeq: seq})
+	s.mu.Lock()
+	s.sent[seq] = time.Now()
+	s.mu.Unlock()
+	return s.scheduler.SubmitWorkOrder(ctx, &api.WorkOrder{
+		AreaID:   s.areaIDs[int(seq)%len(s.areaIDs)],
+		Priority: 1,
+		Payload:  payload,
+	})
+}
+
+func (s *Stack) handleCommand(key string, value []byte) error {
+	now := time.Now()
+	var cmd api.RobotCommand
+	var p benchPayload
+	if json.Unmarshal(value, &cmd) != nil || json.Unmarshal(cmd.Payload, &p) != nil || p.BenchSeq == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	if t, ok := s.sent[p.BenchSeq]; ok {
+		delete(s.sent, p.BenchSeq)
+		s.latencies = append(s.latencies, now.Sub(t))
+	}
+	s.mu.Unlock()
+	s.dispatched.Add(1)
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (s *Stack) handleZoneSummary(key string, value []byte) error {
+	now := time.Now()
+	var sum api.ZoneSummary
+	if err := json.Unmarshal(value, &sum); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if a, ok := s.zoneArea[sum.ZoneID]; ok {
+		s.pending[a] = append(s.pending[a], now)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// handleAreaSummary resolves the zone summaries the area had received before it published this summary.
+func (s *Stack) handleAreaSummary(key string, value []byte) error {
+	now := time.Now()
+	var sum api.AreaSummary
+	if err := json.Unmarshal(value, &sum); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	pending := s.pending[sum.AreaID]
+	i := sort.Search(len(pending), func(i int) bool { return pending[i].After(sum.UpdatedAt) })
+	for _, t := range pending[:i] {
+		s.lags = append(s.lags, now.Sub(t))
+	}
+	s.pending[sum.AreaID] = pending[i:]
+	s.mu.Unlock()
+	return nil
+}
+
+// Dispatched returns how many submitted orders have reached the edge as robot commands.
+func (s *Stack) Dispatched() int64 { return s.dispatched.Load() }
+
+// Submitted returns how many orders have been submitted.
+func (s *Stack) Submitted() int64 { return s.seq.Load() }
+
+// Wait blocks until n orders have been dispatched or timeout passes, and reports whether they were.
+func (s *Stack) Wait(n int64, timeout time.Duration) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for s.dispatched.Load() < n {
+		select {
+		case <-s.notify:
+		case <-deadline.C:
+			return s.dispatched.Load() >= n
+		}
+	}
+	return true
+}
+
+// Latencies returns the dispatch latencies and summary lags recorded so far and resets them.
+func (s *Stack) Latencies() (dispatch, summaryLag []time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dispatch, summaryLag = s.latencies, s.lags
+	s.latencies, s.lags = nil, nil
+	return dispatch, summaryLag
+}
+
+// Close stops every layer and closes NATS connections.
+func (s *Stack) Close() {
+	s.cancel()
+	for _, c := range s.closers {
+		c()
+	}
+}
+
+// countingBus counts published messages and bytes per topic.
+type countingBus struct {
+	bus    messaging.Bus
+	mu     sync.Mutex
+	topics map[string]*TopicStats
+}
+
+func (b *countingBus) Publish(ctx context.Context, topic, key string, value []byte) error {
+	b.mu.Lock()
+	if b.topics == nil {
+		b.topics = make(map[string]*TopicStats)
+	}
+	t := b.topics[topic]
+	if t == nil {
+		t = &TopicStats{}
+		b.topics[topic] = t
+	}
+	t.Messages++
+	t.Bytes += int64(len(value))
+	b.mu.Unlock()
+	return b.bus.Publish(ctx, topic, key, value)
+}
+
+func (b *countingBus) Subscribe(ctx context.Context, topic string, handler func(key string, value []byte) error) error {
+	return b.bus.Subscribe(ctx, topic, handler)
+}
+
+func (b *countingBus) SubscribeKey(ctx context.Context, topic, key string, handler func(key string, value []byte) error) error {
+	return messaging.SubscribeKeys(ctx, b.bus, topic, []string{key}, handler)
+}
+
+// BusStats returns messages and bytes published per topic, over all layers.
+func (s *Stack) BusStats() map[string]TopicStats {
+	out := make(map[string]TopicStats)
+	for _, b := range s.buses {
+		b.mu.Lock()
+		for topic, t := range b.topics {
+			o := out[topic]
+			o.Messages += t.Messages
+			o.Bytes += t.Bytes
+			out[topic] = o
+		}
+		b.mu.Unlock()
+	}
+	return out
+}
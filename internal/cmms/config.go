@@ -3,13 +3,21 @@ package cmms
 import (
 	"os"
 
-	"gopkg.in/yaml.v3"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
 // Config holds CMMS server configuration.
 type Config struct {
 	CMMS  CMMSConfig  `yaml:"cmms"`
 	Fleet FleetConfig `yaml:"fleet"`
+	Auth  auth.Config `yaml:"auth"` // API keys and JWT for this service's API; none configured leaves it open
+	Audit audit.Config `yaml:"audit"` // tamper-evident log of API actions; env AUDIT_LOG overrides the path
+	Idempotency idempotency.Config `yaml:"idempotency"` // replays retried POSTs with the same Idempotency-Key; env IDEMPOTENCY_TTL overrides the TTL
+	Storage storage.Config `yaml:"storage"` // memory (default) or sqlite; env STORE_BACKEND and STORE_PATH override
 }
 
 // CMMSConfig is the server config.
@@ -20,6 +28,7 @@ type CMMSConfig struct {
 // FleetConfig is the Fleet API client config.
 type FleetConfig struct {
 	APIURL string `yaml:"api_url"`
+	Token  string `yaml:"token"` // API key or JWT sent to the API; env FLEET_API_TOKEN overrides
 }
 
 // LoadConfig reads config from path. Uses env and defaults if path empty.
@@ -34,11 +43,7 @@ func LoadConfig(path string) (*Config, error) {
 		}
 	}
 	if path != "" {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return nil, err
-		}
-		if err := yaml.Unmarshal(data, cfg); err != nil {
+		if err := config.Load(path, cfg); err != nil {
 			return nil, err
 		}
 	}
@@ -54,5 +59,12 @@ func LoadConfig(path string) (*Config, error) {
 	if cfg.Fleet.APIURL == "" {
 		cfg.Fleet.APIURL = "http://localhost:8080"
 	}
+	if t := os.Getenv("FLEET_API_TOKEN"); t != "" {
+		cfg.Fleet.Token = t
+	}
+	cfg.Auth.ApplyEnv()
+	cfg.Audit.ApplyEnv()
+	cfg.Idempotency.ApplyEnv()
+	cfg.Storage.ApplyEnv()
 	return cfg, nil
 }
@@ -0,0 +1,51 @@
+package cmms
+
+import (
+	"net/http"
+
+	"github.com/robotfleetos/robotfleetos/pkg/openapi"
+)
+
+// OpenAPI is the CMMS API's OpenAPI document, served at GET /openapi.json.
+var OpenAPI = &openapi.API{
+	Title:       "RobotFleetOS CMMS API",
+	Description: "Equipment and maintenance work orders; work orders can be submitted to the fleet.",
+	Rules:       AuthRules,
+	Idempotent:  true,
+	Ops: openapi.Join(
+		openapi.Dashboard("/", "/ui"),
+		[]openapi.Op{
+			{Method: http.MethodPost, Path: "/equipment", Summary: "Register equipment",
+				Body: Equipment{}, Status: http.StatusCreated, Response: Equipment{}, Errors: []int{http.StatusBadRequest},
+				Example: Equipment{ID: "robot-1", Name: "AMR 1", Type: EquipmentTypeRobot, AreaID: "area-1", Status: EquipmentOperational}},
+			{Method: http.MethodGet, Path: "/equipment", Summary: "List equipment",
+				Query: []string{"status", "type"}, Response: openapi.Object{"equipment": []Equipment{}}},
+			{Method: http.MethodGet, Path: "/equipment/{id}", Summary: "Get equipment",
+				Response: Equipment{}, Errors: []int{http.StatusNotFound}},
+			{Method: http.MethodPut, Path: "/equipment/{id}", Summary: "Update equipment",
+				Body: Equipment{}, Response: Equipment{}, Errors: []int{http.StatusBadRequest},
+				Example: Equipment{Name: "AMR 1", Type: EquipmentTypeRobot, AreaID: "area-1", Status: EquipmentUnderMaintenance}},
+			{Method: http.MethodPost, Path: "/mwo", Summary: "Create a maintenance work order",
+				Body: MaintenanceWorkOrder{}, Status: http.StatusCreated, Response: MaintenanceWorkOrder{},
+				Errors:  []int{http.StatusBadRequest},
+				Example: MaintenanceWorkOrder{EquipmentID: "robot-1", Type: MWOCorrective, Priority: 3, Description: "replace wheel"}},
+			{Method: http.MethodGet, Path: "/mwo", Summary: "List maintenance work orders",
+				Query: []string{"status", "equipment_id"}, Response: openapi.Object{"work_orders": []MaintenanceWorkOrder{}}},
+			{Method: http.MethodGet, Path: "/mwo/{id}", Summary: "Get a maintenance work order",
+				Response: MaintenanceWorkOrder{}, Errors: []int{http.StatusNotFound}},
+			{Method: http.MethodPost, Path: "/mwo/{id}/start", Summary: "Start a maintenance work order",
+				Response: MaintenanceWorkOrder{}, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPost, Path: "/mwo/{id}/submit_to_fleet", Summary: "Submit a maintenance work order to the fleet",
+				Query: []string{"priority"}, Response: openapi.Object{"fleet_work_order_id": ""},
+				Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPost, Path: "/mwo/{id}/complete", Summary: "Complete a maintenance work order",
+				Response: MaintenanceWorkOrder{}, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPost, Path: "/mwo/{id}/cancel", Summary: "Cancel a maintenance work order",
+				Response: MaintenanceWorkOrder{}, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPost, Path: "/firmware/trigger", Summary: "Start a firmware update campaign on the fleet",
+				Body: openapi.Object{"seed_busy": 0}, Response: openapi.Object{"message": ""},
+				Errors: []int{http.StatusBadRequest}},
+		},
+		openapi.Common("cmms"),
+	),
+}
@@ -6,6 +6,10 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
 )
 
 //go:embed static/index.html
@@ -13,19 +17,36 @@ var dashboardHTML []byte
 
 // Server is the CMMS HTTP API server.
 type Server struct {
-	Service *Service
+	Service     *Service
+	Auth        *auth.Authenticator // optional: API key/JWT authentication and role checks; nil leaves the API open
+	Audit       *audit.Log          // optional: records every change made through the API; GET /audit
+	Idempotency *idempotency.Store  // optional: replays the response to a retried POST with the same Idempotency-Key
 }
 
+// AuthRules are the CMMS API's route permissions; see auth.Rule.
+var AuthRules = auth.Join(
+	audit.AuthRules,
+	auth.Public("/", "/ui", "/health", "/openapi.json"),
+	auth.Writes("/firmware/trigger", auth.RoleMaintenance),
+	auth.Writes("/equipment", auth.RoleMaintenance),
+	auth.Writes("/equipment/", auth.RoleMaintenance),
+	auth.Writes("/mwo", auth.RoleMaintenance),
+	auth.Writes("/mwo/", auth.RoleMaintenance),
+)
+
 // Handler returns the HTTP handler.
 func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", s.handleHealth)
+	mux.Handle("/openapi.json", OpenAPI)
+	mux.Handle("/audit", s.Audit)
+	mux.Handle("/audit/", s.Audit)
 	mux.HandleFunc("/firmware/trigger", s.handleFirmwareTrigger)
 	mux.HandleFunc("/equipment", s.handleEquipment)
 	mux.HandleFunc("/equipment/", s.handleEquipmentByID)
 	mux.HandleFunc("/mwo", s.handleMWO)
 	mux.HandleFunc("/mwo/", s.handleMWOByID)
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		p := r.URL.Path
 		if p == "/" || p == "/ui" || p == "/ui/" {
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -35,6 +56,7 @@ func (s *Server) Handler() http.Handler {
 		}
 		mux.ServeHTTP(w, r)
 	})
+	return s.Audit.Wrap(s.Auth.Wrap(s.Idempotency.Wrap(h)))
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -60,6 +82,7 @@ func (s *Server) handleFirmwareTrigger(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	s.Audit.Record(r, "firmware.trigger", "firmware_campaign", "", nil, map[string]interface{}{"seed_busy": body.SeedBusy, "message": msg})
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": msg})
 }
@@ -84,6 +107,7 @@ func (s *Server) handleEquipment(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.Audit.Record(r, "equipment.create", "equipment", created.ID, nil, created)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(created)
@@ -115,11 +139,13 @@ func (s *Server) handleEquipmentByID(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		e.ID = id
+		before := s.Service.GetEquipment(r.Context(), id)
 		updated, err := s.Service.UpdateEquipment(r.Context(), &e)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.Audit.Record(r, "equipment.update", "equipment", id, before, updated)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(updated)
 		return
@@ -147,6 +173,7 @@ func (s *Server) handleMWO(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.Audit.Record(r, "mwo.create", "mwo", created.ID, nil, created)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(created)
@@ -180,6 +207,7 @@ func (s *Server) handleMWOByID(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodPost && action != "" {
 		var result *MaintenanceWorkOrder
 		var err error
+		before := s.Service.GetMWO(r.Context(), id)
 		switch action {
 		case "start":
 			result, err = s.Service.StartMWO(r.Context(), id)
@@ -199,6 +227,7 @@ func (s *Server) handleMWOByID(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, errF.Error(), http.StatusBadRequest)
 				return
 			}
+			s.Audit.Record(r, "mwo.submit_to_fleet", "mwo", id, before, s.Service.GetMWO(r.Context(), id))
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]string{"fleet_work_order_id": fid})
 			return
@@ -210,6 +239,7 @@ func (s *Server) handleMWOByID(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.Audit.Record(r, "mwo."+action, "mwo", id, before, result)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(result)
 		return
@@ -2,19 +2,20 @@ package cmms
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/client"
 )
 
 // Service implements CMMS business logic.
 type Service struct {
-	Store        *Store
-	FleetClient  *FleetClient
+	Store        Store
+	FleetClient  *client.Fleet
 }
 
 // NewService returns a CMMS service.
-func NewService(store *Store, fleet *FleetClient) *Service {
+func NewService(store Store, fleet *client.Fleet) *Service {
 	return &Service{Store: store, FleetClient: fleet}
 }
 
@@ -26,7 +27,7 @@ func (s *Service) CreateEquipment(ctx context.Context, e *Equipment) (*Equipment
 	if e.Type == "" {
 		e.Type = EquipmentTypeOther
 	}
-	return s.Store.CreateEquipment(e), nil
+	return s.Store.CreateEquipment(e)
 }
 
 // GetEquipment returns equipment by ID.
@@ -39,7 +40,9 @@ func (s *Service) UpdateEquipment(ctx context.Context, e *Equipment) (*Equipment
 	if s.Store.GetEquipment(e.ID) == nil {
 		return nil, fmt.Errorf("equipment not found: %s", e.ID)
 	}
-	s.Store.UpdateEquipment(e)
+	if err := s.Store.UpdateEquipment(e); err != nil {
+		return nil, err
+	}
 	return s.Store.GetEquipment(e.ID), nil
 }
 
@@ -62,7 +65,7 @@ func (s *Service) CreateMWO(ctx context.Context, m *MaintenanceWorkOrder) (*Main
 	if m.Priority == 0 {
 		m.Priority = 3
 	}
-	return s.Store.CreateMWO(m), nil
+	return s.Store.CreateMWO(m)
 }
 
 // GetMWO returns an MWO by ID.
@@ -80,10 +83,14 @@ func (s *Service) StartMWO(ctx context.Context, id string) (*MaintenanceWorkOrde
 		return nil, fmt.Errorf("mwo not open: %s", m.Status)
 	}
 	m.Status = MWOStatusInProgress
-	s.Store.UpdateMWO(m)
+	if err := s.Store.UpdateMWO(m); err != nil {
+		return nil, err
+	}
 	if e := s.Store.GetEquipment(m.EquipmentID); e != nil {
 		e.Status = EquipmentUnderMaintenance
-		s.Store.UpdateEquipment(e)
+		if err := s.Store.UpdateEquipment(e); err != nil {
+			return nil, err
+		}
 	}
 	return s.Store.GetMWO(id), nil
 }
@@ -100,10 +107,14 @@ func (s *Service) CompleteMWO(ctx context.Context, id string) (*MaintenanceWorkO
 	now := time.Now().UTC()
 	m.Status = MWOStatusCompleted
 	m.CompletedAt = &now
-	s.Store.UpdateMWO(m)
+	if err := s.Store.UpdateMWO(m); err != nil {
+		return nil, err
+	}
 	if e := s.Store.GetEquipment(m.EquipmentID); e != nil {
 		e.Status = EquipmentOperational
-		s.Store.UpdateEquipment(e)
+		if err := s.Store.UpdateEquipment(e); err != nil {
+			return nil, err
+		}
 	}
 	return s.Store.GetMWO(id), nil
 }
@@ -118,10 +129,14 @@ func (s *Service) CancelMWO(ctx context.Context, id string) (*MaintenanceWorkOrd
 		return nil, fmt.Errorf("mwo already %s", m.Status)
 	}
 	m.Status = MWOStatusCancelled
-	s.Store.UpdateMWO(m)
+	if err := s.Store.UpdateMWO(m); err != nil {
+		return nil, err
+	}
 	if e := s.Store.GetEquipment(m.EquipmentID); e != nil && e.Status == EquipmentUnderMaintenance {
 		e.Status = EquipmentOperational
-		s.Store.UpdateEquipment(e)
+		if err := s.Store.UpdateEquipment(e); err != nil {
+			return nil, err
+		}
 	}
 	return s.Store.GetMWO(id), nil
 }
@@ -159,20 +174,24 @@ func (s *Service) SubmitMWOToFleet(ctx context.Context, mwoID string, priority i
 	if m.Type == MWOFirmwareUpgrade && m.TargetFirmwareVersion != "" {
 		payload["target_firmware_version"] = m.TargetFirmwareVersion
 	}
-	payloadBytes, _ := json.Marshal(payload)
-	var payloadMap map[string]interface{}
-	_ = json.Unmarshal(payloadBytes, &payloadMap)
-
-	fid, err := s.FleetClient.SubmitMaintenanceWorkOrder(ctx, areaID, priority, payloadMap)
+	ctx = client.WithIdempotencyKey(ctx, "cmms-mwo-"+mwoID)
+	wo, err := s.FleetClient.SubmitPayload(ctx, areaID, priority, payload)
 	if err != nil {
 		return "", err
 	}
+	fid := wo.ID
 	m.FleetWorkOrderID = fid
-	s.Store.UpdateMWO(m)
+	if err := s.Store.UpdateMWO(m); err != nil {
+		return fid, fmt.Errorf("submitted as fleet work order %s but not recorded: %w", fid, err)
+	}
 	return fid, nil
 }
 
 // TriggerFirmwareCampaign triggers a firmware campaign on Fleet (broadcast to zone; busy robots defer).
 func (s *Service) TriggerFirmwareCampaign(ctx context.Context, seedBusy int) (message string, err error) {
-	return s.FleetClient.TriggerFirmwareSimulate(ctx, seedBusy)
+	campaign, err := s.FleetClient.FirmwareSimulate(ctx, client.FirmwareRequest{SeedBusy: seedBusy})
+	if err != nil {
+		return "", err
+	}
+	return campaign.Message, nil
 }
@@ -0,0 +1,186 @@
+package cmms
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
+)
+
+// sqlMigrations is the CMMS schema, one entry per version; only ever append (see storage.Migrate).
+var sqlMigrations = []string{`
+CREATE TABLE cmms_equipment (
+	id     TEXT PRIMARY KEY,
+	status TEXT NOT NULL,
+	type   TEXT NOT NULL,
+	data   TEXT NOT NULL
+);
+CREATE TABLE cmms_mwos (
+	id           TEXT PRIMARY KEY,
+	status       TEXT NOT NULL,
+	equipment_id TEXT NOT NULL,
+	data         TEXT NOT NULL
+);
+CREATE INDEX cmms_mwos_equipment ON cmms_mwos (equipment_id);`,
+}
+
+// SQLStore keeps equipment and MWOs in a database, each as JSON with the fields it is filtered on in
+// columns.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore migrates db to the current CMMS schema and returns a store on it.
+func NewSQLStore(db *sql.DB) (*SQLStore, error) {
+	if err := storage.Migrate(db, "cmms", sqlMigrations); err != nil {
+		return nil, err
+	}
+	return &SQLStore{db: db}, nil
+}
+
+const insertEquipment = `INSERT INTO cmms_equipment (id, status, type, data) VALUES (?, ?, ?, ?)`
+
+// CreateEquipment adds equipment. ID is set if empty.
+func (s *SQLStore) CreateEquipment(e *Equipment) (*Equipment, error) {
+	err := storage.InTx(s.db, func(tx *sql.Tx) error {
+		if e.ID == "" {
+			n, err := storage.NextSeq(tx, "cmms_equipment")
+			if err != nil {
+				return err
+			}
+			e.ID = seqID("eq", n)
+		}
+		initEquipment(e)
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		return storage.Insert(tx, insertEquipment, e.ID, string(e.Status), string(e.Type), string(data))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store equipment: %w", err)
+	}
+	cp := *e
+	return &cp, nil
+}
+
+// GetEquipment returns equipment by ID.
+func (s *SQLStore) GetEquipment(id string) *Equipment {
+	var e Equipment
+	ok, err := storage.GetJSON(s.db, &e, `SELECT data FROM cmms_equipment WHERE id = ?`, id)
+	if err != nil {
+		log.Printf("cmms: store: get equipment %s: %v", id, err)
+	}
+	if !ok || err != nil {
+		return nil
+	}
+	return &e
+}
+
+// UpdateEquipment updates equipment.
+func (s *SQLStore) UpdateEquipment(e *Equipment) error {
+	e.UpdatedAt = time.Now().UTC()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	err = storage.ExecOne(s.db, `UPDATE cmms_equipment SET status = ?, type = ?, data = ? WHERE id = ?`,
+		string(e.Status), string(e.Type), string(data), e.ID)
+	if err != nil {
+		return fmt.Errorf("equipment %s: %w", e.ID, err)
+	}
+	return nil
+}
+
+// ListEquipment returns all equipment in creation order, optionally filtered by status or type.
+func (s *SQLStore) ListEquipment(status EquipmentStatus, eqType EquipmentType) []Equipment {
+	var out []Equipment
+	err := storage.EachJSON(s.db, func(data []byte) error {
+		var e Equipment
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		out = append(out, e)
+		return nil
+	}, `SELECT data FROM cmms_equipment WHERE (? = '' OR status = ?) AND (? = '' OR type = ?) ORDER BY rowid`,
+		string(status), string(status), string(eqType), string(eqType))
+	if err != nil {
+		log.Printf("cmms: store: list equipment: %v", err)
+	}
+	return out
+}
+
+const insertMWO = `INSERT INTO cmms_mwos (id, status, equipment_id, data) VALUES (?, ?, ?, ?)`
+
+// CreateMWO adds a maintenance work order.
+func (s *SQLStore) CreateMWO(m *MaintenanceWorkOrder) (*MaintenanceWorkOrder, error) {
+	err := storage.InTx(s.db, func(tx *sql.Tx) error {
+		if m.ID == "" {
+			n, err := storage.NextSeq(tx, "cmms_mwos")
+			if err != nil {
+				return err
+			}
+			m.ID = seqID("mwo", n)
+		}
+		initMWO(m)
+		data, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		return storage.Insert(tx, insertMWO, m.ID, string(m.Status), m.EquipmentID, string(data))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store mwo: %w", err)
+	}
+	cp := *m
+	return &cp, nil
+}
+
+// GetMWO returns an MWO by ID.
+func (s *SQLStore) GetMWO(id string) *MaintenanceWorkOrder {
+	var m MaintenanceWorkOrder
+	ok, err := storage.GetJSON(s.db, &m, `SELECT data FROM cmms_mwos WHERE id = ?`, id)
+	if err != nil {
+		log.Printf("cmms: store: get mwo %s: %v", id, err)
+	}
+	if !ok || err != nil {
+		return nil
+	}
+	return &m
+}
+
+// UpdateMWO updates an MWO.
+func (s *SQLStore) UpdateMWO(m *MaintenanceWorkOrder) error {
+	m.UpdatedAt = time.Now().UTC()
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	err = storage.ExecOne(s.db, `UPDATE cmms_mwos SET status = ?, equipment_id = ?, data = ? WHERE id = ?`,
+		string(m.Status), m.EquipmentID, string(data), m.ID)
+	if err != nil {
+		return fmt.Errorf("mwo %s: %w", m.ID, err)
+	}
+	return nil
+}
+
+// ListMWOs returns MWOs in creation order, optionally filtered by status or equipment_id.
+func (s *SQLStore) ListMWOs(status MWOStatus, equipmentID string) []MaintenanceWorkOrder {
+	var out []MaintenanceWorkOrder
+	err := storage.EachJSON(s.db, func(data []byte) error {
+		var m MaintenanceWorkOrder
+		if err := json.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		out = append(out, m)
+		return nil
+	}, `SELECT data FROM cmms_mwos WHERE (? = '' OR status = ?) AND (? = '' OR equipment_id = ?) ORDER BY rowid`,
+		string(status), string(status), equipmentID, equipmentID)
+	if err != nil {
+		log.Printf("cmms: store: list mwos: %v", err)
+	}
+	return out
+}
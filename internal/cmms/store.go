@@ -1,14 +1,74 @@
 package cmms
 
 import (
+	"database/sql"
 	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
-// Store is an in-memory store for equipment and maintenance work orders.
-type Store struct {
+// Store holds equipment and maintenance work orders: MemoryStore in memory, SQLStore in a database (see
+// pkg/storage). Reads log a database failure and report nothing found; writes return it.
+type Store interface {
+	// CreateEquipment adds equipment. ID is set if empty; storage.ErrExists if it is taken.
+	CreateEquipment(e *Equipment) (*Equipment, error)
+	// GetEquipment returns equipment by ID.
+	GetEquipment(id string) *Equipment
+	// UpdateEquipment updates equipment; storage.ErrNotFound if there is none.
+	UpdateEquipment(e *Equipment) error
+	// ListEquipment returns all equipment, optionally filtered by status or type.
+	ListEquipment(status EquipmentStatus, eqType EquipmentType) []Equipment
+	// CreateMWO adds a maintenance work order; storage.ErrExists if its ID is taken.
+	CreateMWO(m *MaintenanceWorkOrder) (*MaintenanceWorkOrder, error)
+	// GetMWO returns an MWO by ID.
+	GetMWO(id string) *MaintenanceWorkOrder
+	// UpdateMWO updates an MWO; storage.ErrNotFound if there is none.
+	UpdateMWO(m *MaintenanceWorkOrder) error
+	// ListMWOs returns MWOs, optionally filtered by status or equipment_id.
+	ListMWOs(status MWOStatus, equipmentID string) []MaintenanceWorkOrder
+}
+
+// NewStore returns an SQLStore on db, or a MemoryStore if db is nil (the memory backend).
+func NewStore(db *sql.DB) (Store, error) {
+	if db == nil {
+		return NewMemoryStore(), nil
+	}
+	s, err := NewSQLStore(db)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// initEquipment sets the defaults of new equipment.
+func initEquipment(e *Equipment) {
+	now := time.Now().UTC()
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = now
+	}
+	e.UpdatedAt = now
+	if e.Status == "" {
+		e.Status = EquipmentOperational
+	}
+}
+
+// initMWO sets the defaults of a new MWO.
+func initMWO(m *MaintenanceWorkOrder) {
+	now := time.Now().UTC()
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = now
+	}
+	m.UpdatedAt = now
+	if m.Status == "" {
+		m.Status = MWOStatusOpen
+	}
+}
+
+// MemoryStore is an in-memory store for equipment and maintenance work orders.
+type MemoryStore struct {
 	mu       sync.RWMutex
 	equipSeq atomic.Uint64
 	equip    map[string]*Equipment
@@ -16,9 +76,9 @@ type Store struct {
 	mwo      map[string]*MaintenanceWorkOrder
 }
 
-// NewStore returns a new CMMS store.
-func NewStore() *Store {
-	return &Store{
+// NewMemoryStore returns a new in-memory CMMS store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
 		equip: make(map[string]*Equipment),
 		mwo:   make(map[string]*MaintenanceWorkOrder),
 	}
@@ -29,27 +89,23 @@ func seqID(prefix string, n uint64) string {
 }
 
 // CreateEquipment adds equipment. ID is set if empty.
-func (s *Store) CreateEquipment(e *Equipment) *Equipment {
+func (s *MemoryStore) CreateEquipment(e *Equipment) (*Equipment, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if e.ID == "" {
 		e.ID = seqID("eq", s.equipSeq.Add(1))
 	}
-	now := time.Now().UTC()
-	if e.CreatedAt.IsZero() {
-		e.CreatedAt = now
-	}
-	e.UpdatedAt = now
-	if e.Status == "" {
-		e.Status = EquipmentOperational
+	if _, ok := s.equip[e.ID]; ok {
+		return nil, fmt.Errorf("store equipment: %w", storage.ErrExists)
 	}
+	initEquipment(e)
 	cp := *e
 	s.equip[cp.ID] = &cp
-	return &cp
+	return &cp, nil
 }
 
 // GetEquipment returns equipment by ID.
-func (s *Store) GetEquipment(id string) *Equipment {
+func (s *MemoryStore) GetEquipment(id string) *Equipment {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	e, ok := s.equip[id]
@@ -61,20 +117,20 @@ func (s *Store) GetEquipment(id string) *Equipment {
 }
 
 // UpdateEquipment updates equipment.
-func (s *Store) UpdateEquipment(e *Equipment) bool {
+func (s *MemoryStore) UpdateEquipment(e *Equipment) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, ok := s.equip[e.ID]; !ok {
-		return false
+		return fmt.Errorf("equipment %s: %w", e.ID, storage.ErrNotFound)
 	}
 	e.UpdatedAt = time.Now().UTC()
 	cp := *e
 	s.equip[e.ID] = &cp
-	return true
+	return nil
 }
 
 // ListEquipment returns all equipment, optionally filtered by status or type.
-func (s *Store) ListEquipment(status EquipmentStatus, eqType EquipmentType) []Equipment {
+func (s *MemoryStore) ListEquipment(status EquipmentStatus, eqType EquipmentType) []Equipment {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var out []Equipment
@@ -91,27 +147,23 @@ func (s *Store) ListEquipment(status EquipmentStatus, eqType EquipmentType) []Eq
 }
 
 // CreateMWO adds a maintenance work order.
-func (s *Store) CreateMWO(m *MaintenanceWorkOrder) *MaintenanceWorkOrder {
+func (s *MemoryStore) CreateMWO(m *MaintenanceWorkOrder) (*MaintenanceWorkOrder, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if m.ID == "" {
 		m.ID = seqID("mwo", s.mwoSeq.Add(1))
 	}
-	now := time.Now().UTC()
-	if m.CreatedAt.IsZero() {
-		m.CreatedAt = now
-	}
-	m.UpdatedAt = now
-	if m.Status == "" {
-		m.Status = MWOStatusOpen
+	if _, ok := s.mwo[m.ID]; ok {
+		return nil, fmt.Errorf("store mwo: %w", storage.ErrExists)
 	}
+	initMWO(m)
 	cp := *m
 	s.mwo[cp.ID] = &cp
-	return &cp
+	return &cp, nil
 }
 
 // GetMWO returns an MWO by ID.
-func (s *Store) GetMWO(id string) *MaintenanceWorkOrder {
+func (s *MemoryStore) GetMWO(id string) *MaintenanceWorkOrder {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	m, ok := s.mwo[id]
@@ -123,20 +175,20 @@ func (s *Store) GetMWO(id string) *MaintenanceWorkOrder {
 }
 
 // UpdateMWO updates an MWO.
-func (s *Store) UpdateMWO(m *MaintenanceWorkOrder) bool {
+func (s *MemoryStore) UpdateMWO(m *MaintenanceWorkOrder) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, ok := s.mwo[m.ID]; !ok {
-		return false
+		return fmt.Errorf("mwo %s: %w", m.ID, storage.ErrNotFound)
 	}
 	m.UpdatedAt = time.Now().UTC()
 	cp := *m
 	s.mwo[m.ID] = &cp
-	return true
+	return nil
 }
 
 // ListMWOs returns MWOs, optionally filtered by status or equipment_id.
-func (s *Store) ListMWOs(status MWOStatus, equipmentID string) []MaintenanceWorkOrder {
+func (s *MemoryStore) ListMWOs(status MWOStatus, equipmentID string) []MaintenanceWorkOrder {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var out []MaintenanceWorkOrder
@@ -0,0 +1,118 @@
+package cmms
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
+)
+
+// testStores returns a MemoryStore and an SQLStore on a new SQLite file, to run the same checks on both.
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	db, err := storage.Open(storage.Config{Backend: storage.BackendSQLite, Path: filepath.Join(t.TempDir(), "cmms.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s, err := NewSQLStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Store{"memory": NewMemoryStore(), "sql": s}
+}
+
+// ids returns the sorted IDs of a list, which the memory store returns in no particular order.
+func ids[T any](items []T, id func(T) string) string {
+	var out []string
+	for _, it := range items {
+		out = append(out, id(it))
+	}
+	sort.Strings(out)
+	return strings.Join(out, " ")
+}
+
+func TestStore(t *testing.T) {
+	equipmentID := func(e Equipment) string { return e.ID }
+	mwoID := func(m MaintenanceWorkOrder) string { return m.ID }
+	for name, s := range testStores(t) {
+		e, err := s.CreateEquipment(&Equipment{Name: "AMR 1", Type: EquipmentTypeRobot})
+		if err != nil || e.ID != "eq-1" || e.Status != EquipmentOperational || e.CreatedAt.IsZero() {
+			t.Fatalf("%s: CreateEquipment = %+v, %v", name, e, err)
+		}
+		if _, err := s.CreateEquipment(&Equipment{ID: "press-1", Name: "Press", Type: EquipmentTypeMachine}); err != nil {
+			t.Fatalf("%s: CreateEquipment with ID: %v", name, err)
+		}
+		if _, err := s.CreateEquipment(&Equipment{ID: "eq-1", Name: "other"}); !errors.Is(err, storage.ErrExists) {
+			t.Errorf("%s: CreateEquipment with a taken ID = %v, want ErrExists", name, err)
+		}
+		if got := s.GetEquipment("eq-1"); got == nil || got.Name != "AMR 1" {
+			t.Errorf("%s: GetEquipment = %+v, want the first equipment unchanged", name, got)
+		}
+		e.Status = EquipmentUnderMaintenance
+		if err := s.UpdateEquipment(e); err != nil {
+			t.Fatalf("%s: UpdateEquipment: %v", name, err)
+		}
+		if got := s.GetEquipment("eq-1"); got.Status != EquipmentUnderMaintenance {
+			t.Errorf("%s: after UpdateEquipment GetEquipment = %+v", name, got)
+		}
+		if err := s.UpdateEquipment(&Equipment{ID: "eq-9"}); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s: UpdateEquipment of missing equipment = %v, want ErrNotFound", name, err)
+		}
+		for _, tc := range []struct {
+			status EquipmentStatus
+			eqType EquipmentType
+			want   string
+		}{
+			{"", "", "eq-1 press-1"},
+			{EquipmentUnderMaintenance, "", "eq-1"},
+			{"", EquipmentTypeMachine, "press-1"},
+			{EquipmentOperational, EquipmentTypeRobot, ""},
+		} {
+			if got := ids(s.ListEquipment(tc.status, tc.eqType), equipmentID); got != tc.want {
+				t.Errorf("%s: ListEquipment(%q, %q) = %s, want %s", name, tc.status, tc.eqType, got, tc.want)
+			}
+		}
+
+		m, err := s.CreateMWO(&MaintenanceWorkOrder{EquipmentID: "eq-1", Type: MWOCorrective, Priority: 4})
+		if err != nil || m.ID != "mwo-1" || m.Status != MWOStatusOpen {
+			t.Fatalf("%s: CreateMWO = %+v, %v", name, m, err)
+		}
+		if _, err := s.CreateMWO(&MaintenanceWorkOrder{EquipmentID: "press-1", Type: MWOPreventive}); err != nil {
+			t.Fatalf("%s: second CreateMWO: %v", name, err)
+		}
+		if _, err := s.CreateMWO(&MaintenanceWorkOrder{ID: "mwo-2", EquipmentID: "eq-1"}); !errors.Is(err, storage.ErrExists) {
+			t.Errorf("%s: CreateMWO with a taken ID = %v, want ErrExists", name, err)
+		}
+		m.Status = MWOStatusCompleted
+		if err := s.UpdateMWO(m); err != nil {
+			t.Fatalf("%s: UpdateMWO: %v", name, err)
+		}
+		if got := s.GetMWO("mwo-1"); got == nil || got.Status != MWOStatusCompleted || got.Priority != 4 {
+			t.Errorf("%s: after UpdateMWO GetMWO = %+v", name, got)
+		}
+		if got := s.GetMWO("mwo-9"); got != nil {
+			t.Errorf("%s: GetMWO of a missing MWO = %+v", name, got)
+		}
+		if err := s.UpdateMWO(&MaintenanceWorkOrder{ID: "mwo-9"}); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s: UpdateMWO of a missing MWO = %v, want ErrNotFound", name, err)
+		}
+		for _, tc := range []struct {
+			status      MWOStatus
+			equipmentID string
+			want        string
+		}{
+			{"", "", "mwo-1 mwo-2"},
+			{MWOStatusOpen, "", "mwo-2"},
+			{"", "eq-1", "mwo-1"},
+			{MWOStatusOpen, "eq-1", ""},
+		} {
+			if got := ids(s.ListMWOs(tc.status, tc.equipmentID), mwoID); got != tc.want {
+				t.Errorf("%s: ListMWOs(%q, %q) = %s, want %s", name, tc.status, tc.equipmentID, got, tc.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,333 @@
+// Package configcheck checks the config files of a deployment together, as validate-config does: each file
+// is loaded the way the process it is for loads it, then the area, zone and edge configs are checked
+// against each other.
+//
+// A deployment is a directory holding a fleet or area config (e.g. deploy/1000) with all directories
+// below it that hold none (e.g. deploy/1000/edge), so a tree such as deploy/ with one deployment per
+// directory can be checked at once.
+package configcheck
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/robotfleetos/robotfleetos/internal/area"
+	"github.com/robotfleetos/robotfleetos/internal/cmms"
+	"github.com/robotfleetos/robotfleetos/internal/edge"
+	"github.com/robotfleetos/robotfleetos/internal/erp"
+	"github.com/robotfleetos/robotfleetos/internal/fleet"
+	"github.com/robotfleetos/robotfleetos/internal/mes"
+	"github.com/robotfleetos/robotfleetos/internal/plm"
+	"github.com/robotfleetos/robotfleetos/internal/qms"
+	"github.com/robotfleetos/robotfleetos/internal/topology"
+	"github.com/robotfleetos/robotfleetos/internal/traceability"
+	"github.com/robotfleetos/robotfleetos/internal/wms"
+	"github.com/robotfleetos/robotfleetos/internal/zone"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+)
+
+// kind is one type of config file, named after its top-level section.
+type kind struct {
+	name string
+	load func(path string) (interface{}, error)
+	zero func() interface{} // an empty config, for Schema
+}
+
+// services are the service configs, in the order a file's sections are matched: erp.yaml also has a mes
+// section for its MES client, and the MES, WMS and CMMS configs a fleet section for theirs.
+var services = []kind{
+	{"erp", func(p string) (interface{}, error) { return erp.LoadConfig(p) }, func() interface{} { return &erp.Config{} }},
+	{"wms", func(p string) (interface{}, error) { return wms.LoadConfig(p) }, func() interface{} { return &wms.Config{} }},
+	{"cmms", func(p string) (interface{}, error) { return cmms.LoadConfig(p) }, func() interface{} { return &cmms.Config{} }},
+	{"mes", func(p string) (interface{}, error) { return mes.LoadConfig(p) }, func() interface{} { return &mes.Config{} }},
+	{"qms", func(p string) (interface{}, error) { return qms.LoadConfig(p) }, func() interface{} { return &qms.Config{} }},
+	{"plm", func(p string) (interface{}, error) { return plm.LoadConfig(p) }, func() interface{} { return &plm.Config{} }},
+	{"traceability", func(p string) (interface{}, error) { return traceability.LoadConfig(p) }, func() interface{} { return &traceability.Config{} }},
+}
+
+// layers are the layer configs; one file may hold several, as configs/default.yaml does.
+var layers = []kind{
+	{"fleet", func(p string) (interface{}, error) { return fleet.LoadConfig(p) }, func() interface{} { return &fleet.Config{} }},
+	{"area", func(p string) (interface{}, error) { return area.LoadConfig(p) }, func() interface{} { return &area.Config{} }},
+	{"zone", func(p string) (interface{}, error) { return zone.LoadConfig(p) }, func() interface{} { return &zone.Config{} }},
+	{"edge", func(p string) (interface{}, error) { return edge.LoadConfig(p) }, func() interface{} { return &edge.Config{} }},
+}
+
+// topologyKind is a topology file for the all-in-one binary, recognized by its "areas" section.
+var topologyKind = kind{"areas", func(p string) (interface{}, error) { return topology.Load(p) }, func() interface{} { return &topology.Topology{} }}
+
+// Kinds returns the names of the configs Schema knows.
+func Kinds() []string {
+	var names []string
+	for _, k := range append(append(append([]kind(nil), layers...), services...), topologyKind) {
+		names = append(names, k.name)
+	}
+	return names
+}
+
+// Schema returns the JSON Schema of the config named by kind (see Kinds).
+func Schema(name string) (map[string]interface{}, error) {
+	for _, k := range append(append(append([]kind(nil), layers...), services...), topologyKind) {
+		if k.name == name {
+			return config.Schema(k.zero()), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown config %q (want one of %s)", name, strings.Join(Kinds(), ", "))
+}
+
+// File is the result for one file.
+type File struct {
+	Path    string
+	Configs []string // what the file configures, e.g. ["zone"]; empty if it is not a config file
+	Err     error    // load and validation errors
+}
+
+// Report is the result of Dir.
+type Report struct {
+	Files  []File
+	Errors []error // problems between files
+}
+
+// Failed returns the number of errors in files and between them.
+func (r *Report) Failed() int {
+	n := len(r.Errors)
+	for _, f := range r.Files {
+		if f.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// loaded is a file that loaded, with its layer configs.
+type loaded struct {
+	path string
+	dep  string // deployment directory
+	area *area.Config
+	zone *zone.Config
+	edge *edge.Config
+}
+
+// Dir checks every .yaml and .yml file under root, or the single file root. Env overrides (e.g.
+// EDGE_ROBOT_ID) apply as they would in each process.
+func Dir(root string) (*Report, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ext := filepath.Ext(p); !d.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	r := &Report{}
+	var ok []*loaded
+	deps := make(map[string]bool) // directories holding a fleet or area config
+	for _, p := range paths {
+		f, l := checkFile(p)
+		r.Files = append(r.Files, f)
+		for _, c := range f.Configs {
+			if c == "fleet" || c == "area" {
+				deps[filepath.Dir(p)] = true
+			}
+		}
+		if l != nil {
+			ok = append(ok, l)
+		}
+	}
+	byDep := make(map[string][]*loaded)
+	var order []string
+	for _, l := range ok {
+		l.dep = deployment(filepath.Dir(l.path), root, deps)
+		if _, seen := byDep[l.dep]; !seen {
+			order = append(order, l.dep)
+		}
+		byDep[l.dep] = append(byDep[l.dep], l)
+	}
+	for _, dep := range order {
+		r.Errors = append(r.Errors, crossCheck(byDep[dep])...)
+	}
+	return r, nil
+}
+
+// deployment returns the nearest of dir and its parents up to root that holds a fleet or area config, or
+// root if none does.
+func deployment(dir, root string, deps map[string]bool) string {
+	root = filepath.Clean(root)
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if deps[d] {
+			return d
+		}
+		if d == root || d == filepath.Dir(d) {
+			return root
+		}
+	}
+}
+
+// checkFile loads the file at path as each config it holds. l is nil unless all of them loaded.
+func checkFile(path string) (File, *loaded) {
+	f := File{Path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		f.Err = err
+		return f, nil
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		f.Err = fmt.Errorf("%s: %w", path, err)
+		return f, nil
+	}
+	top := make(map[string]bool)
+	if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+		m := doc.Content[0]
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			top[m.Content[i].Value] = true
+		}
+	}
+	var kinds []kind
+	for _, k := range services {
+		if top[k.name] {
+			kinds = []kind{k}
+			break
+		}
+	}
+	if kinds == nil {
+		for _, k := range layers {
+			if top[k.name] {
+				kinds = append(kinds, k)
+			}
+		}
+	}
+	if kinds == nil && top[topologyKind.name] {
+		kinds = []kind{topologyKind}
+	}
+	l := &loaded{path: path}
+	var errs []error
+	for _, k := range kinds {
+		f.Configs = append(f.Configs, k.name)
+		v, err := k.load(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", k.name, err))
+			continue
+		}
+		switch c := v.(type) {
+		case *area.Config:
+			l.area = c
+		case *zone.Config:
+			l.zone = c
+		case *edge.Config:
+			l.edge = c
+		}
+	}
+	if len(errs) > 0 {
+		f.Err = errors.Join(errs...)
+		return f, nil
+	}
+	return f, l
+}
+
+// crossCheck checks the area, zone and edge configs of one deployment against each other: zones and areas
+// agree on which area owns each zone, no ID is configured twice, and every edge robot is in the zone it
+// reports to.
+func crossCheck(files []*loaded) []error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	areas := make(map[string]*loaded)
+	zones := make(map[string]*loaded)
+	robotZone := make(map[string]string)
+	for _, l := range files {
+		if a := l.area; a != nil {
+			if prev := areas[a.Area.AreaID]; prev != nil {
+				fail("%s: area %s is also configured in %s", l.path, a.Area.AreaID, prev.path)
+			} else {
+				areas[a.Area.AreaID] = l
+			}
+		}
+		if z := l.zone; z != nil {
+			if prev := zones[z.Zone.ZoneID]; prev != nil {
+				fail("%s: zone %s is also configured in %s", l.path, z.Zone.ZoneID, prev.path)
+				continue
+			}
+			zones[z.Zone.ZoneID] = l
+			for _, r := range z.Zone.Robots {
+				if other, ok := robotZone[r]; ok {
+					fail("%s: robot %s is also in zone %s (%s)", l.path, r, other, zones[other].path)
+				} else {
+					robotZone[r] = z.Zone.ZoneID
+				}
+			}
+		}
+	}
+
+	owner := make(map[string]string) // zone ID -> area listing it
+	for _, l := range files {
+		if l.area == nil || areas[l.area.Area.AreaID] != l {
+			continue
+		}
+		a := l.area.Area
+		for _, z := range a.Zones {
+			if other, ok := owner[z]; ok {
+				fail("%s: zone %s is also listed by area %s (%s)", l.path, z, other, areas[other].path)
+				continue
+			}
+			owner[z] = a.AreaID
+			if zl := zones[z]; zl != nil && zl.zone.Zone.AreaID != a.AreaID {
+				fail("%s: area %s lists zone %s, whose area_id is %s (%s)", l.path, a.AreaID, z, zl.zone.Zone.AreaID, zl.path)
+			}
+		}
+	}
+	for _, l := range files {
+		if l.zone == nil || zones[l.zone.Zone.ZoneID] != l {
+			continue
+		}
+		z := l.zone.Zone
+		if al := areas[z.AreaID]; al == nil {
+			fail("%s: zone %s: area_id %s matches no area config", l.path, z.ZoneID, z.AreaID)
+		} else if owner[z.ZoneID] != z.AreaID {
+			fail("%s: zone %s: area %s (%s) does not list it in zones", l.path, z.ZoneID, z.AreaID, al.path)
+		}
+	}
+
+	served := make(map[string]string) // robot ID -> edge config serving it
+	for _, l := range files {
+		if l.edge == nil {
+			continue
+		}
+		e := l.edge.Edge
+		robots := []edge.CellRobotConfig{{RobotID: e.RobotID, ZoneID: e.ZoneID}}
+		if e.CellMode() {
+			robots, _ = e.CellRobots() // loaded by LoadConfig already
+		}
+		for _, r := range robots {
+			if prev, ok := served[r.RobotID]; ok {
+				fail("%s: robot %s is also served by %s", l.path, r.RobotID, prev)
+				continue
+			}
+			served[r.RobotID] = l.path
+			if len(zones) == 0 {
+				continue // edge configs only; the zones are configured elsewhere
+			}
+			if zl := zones[r.ZoneID]; zl == nil {
+				fail("%s: robot %s: zone_id %s matches no zone config", l.path, r.RobotID, r.ZoneID)
+			} else if robotZone[r.RobotID] != r.ZoneID {
+				fail("%s: robot %s: zone %s (%s) does not list it in robots", l.path, r.RobotID, r.ZoneID, zl.path)
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,163 @@
+// Package conformance checks every service's HTTP handler against its OpenAPI document: each route the
+// handler registers must be in the spec, and each operation must answer with the statuses and bodies
+// the spec describes.
+package conformance
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/robotfleetos/robotfleetos/internal/cmms"
+	"github.com/robotfleetos/robotfleetos/internal/erp"
+	"github.com/robotfleetos/robotfleetos/internal/fleet"
+	"github.com/robotfleetos/robotfleetos/internal/mes"
+	"github.com/robotfleetos/robotfleetos/internal/plm"
+	"github.com/robotfleetos/robotfleetos/internal/qms"
+	"github.com/robotfleetos/robotfleetos/internal/traceability"
+	"github.com/robotfleetos/robotfleetos/internal/wms"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/client"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+	"github.com/robotfleetos/robotfleetos/pkg/openapi"
+	"github.com/robotfleetos/robotfleetos/pkg/robotmodel"
+)
+
+func TestConformance(t *testing.T) {
+	fleetSrv := httptest.NewServer(newFleet(t).Handler())
+	defer fleetSrv.Close()
+	fleetClient := client.NewFleet(fleetSrv.URL)
+	newMES := func(t *testing.T) http.Handler {
+		store, err := mes.NewStore(nil)
+		must(t, err)
+		s := &mes.Server{Service: mes.NewService(store, fleetClient), Audit: newAudit(t, "mes")}
+		return s.Handler()
+	}
+	mesSrv := httptest.NewServer(newMES(t))
+	defer mesSrv.Close()
+
+	services := []struct {
+		dir     string
+		spec    *openapi.API
+		handler func(t *testing.T) http.Handler
+	}{
+		{"fleet", fleet.OpenAPI, func(t *testing.T) http.Handler { return newFleet(t).Handler() }},
+		{"mes", mes.OpenAPI, newMES},
+		{"erp", erp.OpenAPI, func(t *testing.T) http.Handler {
+			store, err := erp.NewStore(nil)
+			must(t, err)
+			s := &erp.Server{Service: erp.NewService(store, client.NewMES(mesSrv.URL), "area-1"), Audit: newAudit(t, "erp")}
+			return s.Handler()
+		}},
+		{"wms", wms.OpenAPI, func(t *testing.T) http.Handler {
+			store, err := wms.NewStore(nil)
+			must(t, err)
+			s := &wms.Server{Service: wms.NewService(store, fleetClient, "area-1"), Audit: newAudit(t, "wms")}
+			return s.Handler()
+		}},
+		{"cmms", cmms.OpenAPI, func(t *testing.T) http.Handler {
+			store, err := cmms.NewStore(nil)
+			must(t, err)
+			s := &cmms.Server{Service: cmms.NewService(store, fleetClient), Audit: newAudit(t, "cmms")}
+			return s.Handler()
+		}},
+		{"qms", qms.OpenAPI, func(t *testing.T) http.Handler {
+			store, err := qms.NewStore(nil)
+			must(t, err)
+			s := &qms.Server{Service: qms.NewService(store), Audit: newAudit(t, "qms")}
+			return s.Handler()
+		}},
+		{"plm", plm.OpenAPI, func(t *testing.T) http.Handler {
+			store, err := plm.NewStore(nil)
+			must(t, err)
+			s := &plm.Server{Service: plm.NewService(store), Audit: newAudit(t, "plm")}
+			return s.Handler()
+		}},
+		{"traceability", traceability.OpenAPI, func(t *testing.T) http.Handler {
+			store, err := traceability.NewStore(nil)
+			must(t, err)
+			s := &traceability.Server{Service: traceability.NewService(store), Audit: newAudit(t, "traceability")}
+			return s.Handler()
+		}},
+	}
+	for _, svc := range services {
+		t.Run(svc.dir, func(t *testing.T) {
+			for _, err := range openapi.CheckRoutes(svc.spec, routes(t, svc.dir)) {
+				t.Error(err)
+			}
+			for _, err := range openapi.Check(svc.handler(t), svc.spec) {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func must(t *testing.T, err error) {
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newFleet(t *testing.T) *fleet.Server {
+	return &fleet.Server{
+		Scheduler: fleet.NewScheduler(messaging.NewWorkOrderPublisher(messaging.NewMemoryBus())),
+		State:     fleet.NewGlobalState(),
+		Safety:    fleet.NewSafetyLog(0),
+		Security:  fleet.NewSecurityLog(0),
+		Map:       fleet.NewFacilityMap(nil),
+		Models:    &robotmodel.Registry{Models: []robotmodel.Model{{ModelID: "amr-1", Role: "transport"}}},
+		Audit:     newAudit(t, "fleet"),
+	}
+}
+
+func newAudit(t *testing.T, service string) *audit.Log {
+	l, err := audit.Open(service, audit.Config{})
+	must(t, err)
+	return l
+}
+
+// routes returns the patterns the Handler method in internal/<dir> registers on its ServeMux.
+func routes(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join("..", dir, "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var patterns []string
+	fset := token.NewFileSet()
+	for _, name := range files {
+		f, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Name.Name != "Handler" {
+				continue
+			}
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok || len(call.Args) != 2 {
+					return true
+				}
+				sel, ok := call.Fun.(*ast.SelectorExpr)
+				if !ok || (sel.Sel.Name != "Handle" && sel.Sel.Name != "HandleFunc") {
+					return true
+				}
+				if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					p, _ := strconv.Unquote(lit.Value)
+					patterns = append(patterns, p)
+				}
+				return true
+			})
+		}
+	}
+	if len(patterns) == 0 {
+		t.Fatalf("no routes found in internal/%s", dir)
+	}
+	return patterns
+}
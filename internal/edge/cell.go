@@ -0,0 +1,130 @@
+package edge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/cmdsign"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+// Cell is the edge in cell gateway mode: one process runs a Gateway per robot (typically over the shared-connection
+// mqtt driver) behind a single robot-command subscription.
+type Cell struct {
+	bus      messaging.Subscriber
+	gateways map[api.RobotID]*Gateway
+	verifier *cmdsign.Verifier
+}
+
+// NewCell creates a driver and Gateway for every robot in cfg.CellRobots().
+func NewCell(cfg EdgeConfig, statusPub *messaging.RobotStatusPublisher, bus messaging.Subscriber, statusInterval time.Duration) (*Cell, error) {
+	robots, err := cfg.CellRobots()
+	if err != nil {
+		return nil, err
+	}
+	if len(robots) == 0 {
+		return nil, fmt.Errorf("cell gateway: no robots configured")
+	}
+	c := &Cell{bus: bus, gateways: make(map[api.RobotID]*Gateway, len(robots))}
+	for _, r := range robots {
+		driver, err := NewDriver(cfg.Protocol, r.DriverConfig())
+		if err != nil {
+			return nil, fmt.Errorf("cell gateway %s: %w", r.RobotID, err)
+		}
+		c.gateways[api.RobotID(r.RobotID)] = NewGateway(api.RobotID(r.RobotID), api.ZoneID(r.ZoneID), driver, statusPub, bus, statusInterval)
+	}
+	return c, nil
+}
+
+// Len returns the number of robots served.
+func (c *Cell) Len() int { return len(c.gateways) }
+
+// Run starts every gateway and routes robot commands to them. Blocks until ctx is done.
+// A robot whose driver fails to connect is logged and skipped; the others keep running.
+func (c *Cell) Run(ctx context.Context) error {
+	// Its robots' commands and zone broadcasts (no key) only, as the cell's bus permissions allow.
+	keys := []string{""}
+	for id := range c.gateways {
+		keys = append(keys, string(id))
+	}
+	if err := messaging.SubscribeKeys(ctx, c.bus, messaging.TopicRobotCommands, keys, c.handleCommand); err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	for id, g := range c.gateways {
+		wg.Add(1)
+		go func(id api.RobotID, g *Gateway) {
+			defer wg.Done()
+			if err := g.run(ctx, false); err != nil && ctx.Err() == nil {
+				log.Printf("edge cell: robot %s: %v", id, err)
+			}
+		}(id, g)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (c *Cell) handleCommand(key string, value []byte) error {
+	var cmd api.RobotCommand
+	if err := json.Unmarshal(value, &cmd); err != nil {
+		return err
+	}
+	if cmd.RobotID != "" {
+		if g := c.gateways[cmd.RobotID]; g != nil && c.verifier.Admit(cmd, g.zoneID) {
+			g.dispatch(cmd, false)
+		}
+		return nil
+	}
+	// Zone-wide safety broadcast: every robot of that zone in this cell, verified once for all of them.
+	if !c.servesZone(cmd.ZoneID) || !c.verifier.Admit(cmd, "") {
+		return nil
+	}
+	for id, g := range c.gateways {
+		if _, broadcast := commandTargets(cmd, id, g.zoneID); broadcast {
+			go g.dispatch(cmd, true)
+		}
+	}
+	return nil
+}
+
+// servesZone reports whether the cell has a robot in zone.
+func (c *Cell) servesZone(zone api.ZoneID) bool {
+	for _, g := range c.gateways {
+		if g.zoneID == zone {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCommandVerifier makes the cell check each command's zone signature, freshness and uniqueness before
+// handing it to the robot's gateway (see cmdsign.Verifier.Admit).
+func (c *Cell) SetCommandVerifier(v *cmdsign.Verifier) {
+	c.verifier = v
+}
+
+// SetSafetyEventPublisher sets the safety audit publisher on every gateway.
+func (c *Cell) SetSafetyEventPublisher(p *messaging.SafetyEventPublisher) {
+	for _, g := range c.gateways {
+		g.SetSafetyEventPublisher(p)
+	}
+}
+
+// SetStatusInterval sets the status interval of every gateway. Safe to call while running.
+func (c *Cell) SetStatusInterval(d time.Duration) {
+	for _, g := range c.gateways {
+		g.SetStatusInterval(d)
+	}
+}
+
+// SetTaskResultPublisher sets the task result publisher on every gateway.
+func (c *Cell) SetTaskResultPublisher(p *messaging.TaskResultPublisher) {
+	for _, g := range c.gateways {
+		g.SetTaskResultPublisher(p)
+	}
+}
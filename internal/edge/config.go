@@ -1,9 +1,15 @@
 package edge
 
 import (
+	"encoding/csv"
+	"fmt"
 	"os"
+	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/cmdsign"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
 )
 
 // Config holds edge layer configuration (one edge process per robot or small cell).
@@ -13,14 +19,118 @@ type Config struct {
 }
 
 type EdgeConfig struct {
-	RobotID  string `yaml:"robot_id"`
-	ZoneID   string `yaml:"zone_id"`
-	Protocol string `yaml:"robot_protocol"` // "stub", "opcua", "vendor-api", etc.
+	RobotID        string            `yaml:"robot_id"`
+	ZoneID         string            `yaml:"zone_id"`
+	Protocol       string            `yaml:"robot_protocol"`  // registered driver name: "stub", "tcp-json", "opcua", ...
+	Address        string            `yaml:"robot_address"`   // robot endpoint for network drivers (e.g. "10.0.0.12:9000")
+	DriverOptions  map[string]string `yaml:"driver_options"`  // driver-specific settings (see each driver)
+	StatusInterval string            `yaml:"status_interval"` // how often robot status is published, e.g. "2s" (default)
+	// Cell gateway mode: one process serves many robots with the protocol, address and options above.
+	// Set robots and/or robots_csv (robot_id,zone_id[,robot_address] with a header row, e.g. deploy/1000/robots.csv).
+	Robots    []CellRobotConfig `yaml:"robots"`
+	RobotsCSV string            `yaml:"robots_csv"`
+	// Zone public keys and freshness window for checking signed robot commands.
+	CommandVerification cmdsign.VerificationConfig `yaml:"command_verification"`
+}
+
+// StatusPeriod returns the status interval, 2s if unset.
+func (e EdgeConfig) StatusPeriod() (time.Duration, error) {
+	return config.Duration("edge.status_interval", e.StatusInterval, 2*time.Second)
+}
+
+// CellRobotConfig is one robot served by a cell gateway. Empty fields inherit from EdgeConfig.
+type CellRobotConfig struct {
+	RobotID       string            `yaml:"robot_id"`
+	ZoneID        string            `yaml:"zone_id"`
+	Address       string            `yaml:"robot_address"`
+	DriverOptions map[string]string `yaml:"driver_options"` // merged over EdgeConfig.DriverOptions
+}
+
+// DriverConfig returns the settings passed to NewDriver for this robot.
+func (e EdgeConfig) DriverConfig() DriverConfig {
+	return DriverConfig{
+		RobotID: api.RobotID(e.RobotID),
+		Address: e.Address,
+		Options: e.DriverOptions,
+	}
+}
+
+// CellMode reports whether this edge process is a cell gateway serving several robots.
+func (e EdgeConfig) CellMode() bool {
+	return len(e.Robots) > 0 || e.RobotsCSV != ""
+}
+
+// CellRobots returns the robots served in cell mode with inherited settings filled in.
+func (e EdgeConfig) CellRobots() ([]CellRobotConfig, error) {
+	robots := append([]CellRobotConfig(nil), e.Robots...)
+	if e.RobotsCSV != "" {
+		f, err := os.Open(e.RobotsCSV)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		cr := csv.NewReader(f)
+		cr.FieldsPerRecord = -1
+		rows, err := cr.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("robots_csv %s: %w", e.RobotsCSV, err)
+		}
+		for i, row := range rows {
+			if i == 0 && len(row) > 0 && row[0] == "robot_id" {
+				continue
+			}
+			r := CellRobotConfig{RobotID: row[0]}
+			if len(row) > 1 {
+				r.ZoneID = row[1]
+			}
+			if len(row) > 2 {
+				r.Address = row[2]
+			}
+			robots = append(robots, r)
+		}
+	}
+	seen := make(map[string]bool, len(robots))
+	for i := range robots {
+		r := &robots[i]
+		if r.RobotID == "" {
+			return nil, fmt.Errorf("cell robot %d: robot_id required", i)
+		}
+		if seen[r.RobotID] {
+			return nil, fmt.Errorf("cell robot %s listed twice", r.RobotID)
+		}
+		seen[r.RobotID] = true
+		if r.ZoneID == "" {
+			r.ZoneID = e.ZoneID
+		}
+		if r.Address == "" {
+			r.Address = e.Address
+		}
+		opts := make(map[string]string, len(e.DriverOptions)+len(r.DriverOptions))
+		for k, v := range e.DriverOptions {
+			opts[k] = v
+		}
+		for k, v := range r.DriverOptions {
+			opts[k] = v
+		}
+		r.DriverOptions = opts
+	}
+	return robots, nil
+}
+
+// DriverConfig returns the settings passed to NewDriver for this cell robot.
+func (r CellRobotConfig) DriverConfig() DriverConfig {
+	return DriverConfig{
+		RobotID: api.RobotID(r.RobotID),
+		Address: r.Address,
+		Options: r.DriverOptions,
+	}
 }
 
 type MessagingConfig struct {
 	Broker      string `yaml:"broker"`
 	TopicPrefix string `yaml:"topic_prefix"`
+
+	messaging.ConnOptions `yaml:",inline"` // NATS TLS and credentials
 }
 
 // LoadConfig reads config from path. If path is empty, uses env EDGE_CONFIG or defaults.
@@ -42,11 +152,7 @@ func LoadConfig(path string) (*Config, error) {
 		}
 	}
 	if path != "" {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return nil, err
-		}
-		if err := yaml.Unmarshal(data, cfg); err != nil {
+		if err := config.Load(path, cfg); err != nil {
 			return nil, err
 		}
 	}
@@ -63,5 +169,45 @@ func LoadConfig(path string) (*Config, error) {
 	if id := os.Getenv("EDGE_ZONE_ID"); id != "" {
 		cfg.Edge.ZoneID = id
 	}
+	if addr := os.Getenv("EDGE_ROBOT_ADDRESS"); addr != "" {
+		cfg.Edge.Address = addr
+	}
+	cfg.Messaging.ApplyEnv()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
+
+// Validate checks the robot and zone IDs, the driver, the status interval, the cell's robots and the command
+// verification keys.
+func (c *Config) Validate() error {
+	e := c.Edge
+	if e.ZoneID == "" && !e.CellMode() {
+		return fmt.Errorf("edge.zone_id is required")
+	}
+	driversMu.RLock()
+	_, ok := drivers[e.Protocol]
+	driversMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("edge.robot_protocol: unsupported robot protocol %q (available: %v)", e.Protocol, Drivers())
+	}
+	if _, err := e.StatusPeriod(); err != nil {
+		return err
+	}
+	if e.CellMode() {
+		robots, err := e.CellRobots()
+		if err != nil {
+			return err
+		}
+		for _, r := range robots {
+			if r.ZoneID == "" {
+				return fmt.Errorf("cell robot %s: zone_id required (or edge.zone_id)", r.RobotID)
+			}
+		}
+	}
+	if _, err := e.CommandVerification.Verifier(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package edge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// RobotDriver talks to one robot over a specific protocol (stub, tcp-json, opcua, ...).
+// The Gateway owns task state and the bus; the driver only moves commands down and telemetry up.
+type RobotDriver interface {
+	// Connect opens the session with the robot. Called once before any other method.
+	Connect(ctx context.Context) error
+	// StreamTelemetry delivers robot telemetry to fn until ctx is done. Blocks.
+	StreamTelemetry(ctx context.Context, fn func(Telemetry)) error
+	// Execute runs a command (TASK, MOVE, PICK, ...) and returns when the robot reports it finished.
+	Execute(ctx context.Context, cmd api.RobotCommand) error
+	// EStop stops the robot immediately.
+	EStop(ctx context.Context) error
+	// UpdateFirmware downloads, verifies and applies an image, reporting api.FirmwareStatus* values to progress.
+	UpdateFirmware(ctx context.Context, payload api.FirmwareUpdatePayload, progress func(status string)) error
+	// Close releases the session.
+	Close() error
+}
+
+// Telemetry is one sample reported by the robot. Empty strings and a nil Battery mean "not reported".
+type Telemetry struct {
+	Position        string   `json:"position,omitempty"`
+	Battery         *float64 `json:"battery,omitempty"` // percent
+	Charging        bool     `json:"charging,omitempty"`
+	Fault           string   `json:"fault,omitempty"` // non-empty = robot reports a fault (state ERROR)
+	ModelID         string   `json:"model_id,omitempty"`
+	FirmwareVersion string   `json:"firmware_version,omitempty"`
+}
+
+// DriverConfig is passed to a driver factory.
+type DriverConfig struct {
+	RobotID api.RobotID
+	Address string            // robot endpoint for network drivers (e.g. "10.0.0.12:9000")
+	Options map[string]string // driver-specific settings
+}
+
+// DriverFactory creates a driver for one robot.
+type DriverFactory func(cfg DriverConfig) (RobotDriver, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]DriverFactory)
+)
+
+// RegisterDriver makes a driver available under a protocol name (EdgeConfig.Protocol).
+// Drivers register themselves from init; registering the same name twice panics.
+func RegisterDriver(protocol string, factory DriverFactory) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if _, dup := drivers[protocol]; dup {
+		panic("edge: driver registered twice: " + protocol)
+	}
+	drivers[protocol] = factory
+}
+
+// NewDriver creates the driver registered for protocol.
+func NewDriver(protocol string, cfg DriverConfig) (RobotDriver, error) {
+	driversMu.RLock()
+	factory, ok := drivers[protocol]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported robot protocol %q (available: %v)", protocol, Drivers())
+	}
+	return factory(cfg)
+}
+
+// Drivers returns the registered protocol names, sorted.
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	out := make([]string, 0, len(drivers))
+	for name := range drivers {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
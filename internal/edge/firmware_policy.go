@@ -0,0 +1,83 @@
+package edge
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/schedule"
+)
+
+// firmwarePlan is a received FIRMWARE_UPDATE with its policy parsed once; it is held until the robot may apply it.
+type firmwarePlan struct {
+	cmd         api.RobotCommand
+	payload     api.FirmwareUpdatePayload
+	deadline    time.Time // zero = no deadline
+	windows     []*schedule.Window
+	zoneWindows []*schedule.Window
+}
+
+func newFirmwarePlan(cmd api.RobotCommand) (*firmwarePlan, error) {
+	p := &firmwarePlan{cmd: cmd}
+	if err := json.Unmarshal(cmd.Payload, &p.payload); err != nil {
+		return nil, fmt.Errorf("invalid firmware payload: %w", err)
+	}
+	if p.payload.Deadline != "" {
+		t, err := time.Parse(time.RFC3339, p.payload.Deadline)
+		if err != nil {
+			return nil, fmt.Errorf("invalid firmware deadline: %w", err)
+		}
+		p.deadline = t
+	}
+	if pol := p.payload.Policy; pol != nil {
+		var err error
+		if p.windows, err = parseWindows(pol.MaintenanceWindows); err != nil {
+			return nil, err
+		}
+		if p.zoneWindows, err = parseWindows(pol.ZoneMaintenanceWindows); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func parseWindows(in []api.FirmwareMaintenanceWindow) ([]*schedule.Window, error) {
+	out := make([]*schedule.Window, 0, len(in))
+	for _, w := range in {
+		win, err := schedule.NewWindow(w.Cron, w.Duration, w.TZ)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance window: %w", err)
+		}
+		out = append(out, win)
+	}
+	return out, nil
+}
+
+// deferReason returns why the update must wait (api.FirmwareDefer*), or "" if it may be applied now.
+// A stopped robot never updates and a busy robot always finishes its task first; past the deadline the policy constraints no longer apply.
+func (p *firmwarePlan) deferReason(now time.Time, state string, battery float64) string {
+	if state == api.RobotStateStopped {
+		return api.FirmwareDeferSafetyStop
+	}
+	if state == "BUSY" {
+		return api.FirmwareDeferBusy
+	}
+	if !p.deadline.IsZero() && !now.Before(p.deadline) {
+		return ""
+	}
+	pol := p.payload.Policy
+	if pol == nil {
+		return ""
+	}
+	if !schedule.AnyContains(p.windows, now) || !schedule.AnyContains(p.zoneWindows, now) {
+		return api.FirmwareDeferOutsideWindow
+	}
+	if pol.MinBattery > 0 && battery < pol.MinBattery {
+		return api.FirmwareDeferLowBattery
+	}
+	if pol.ChargingOnly && state != "CHARGING" {
+		return api.FirmwareDeferNotCharging
+	}
+	return ""
+}
@@ -8,77 +8,151 @@ import (
 	"time"
 
 	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/clock"
+	"github.com/robotfleetos/robotfleetos/pkg/cmdsign"
 	"github.com/robotfleetos/robotfleetos/pkg/messaging"
 )
 
-// Gateway is the edge gateway for one robot: consumes commands, executes them (stub or real protocol), publishes status.
+// Gateway is the edge gateway for one robot: consumes commands, executes them through the robot's driver, publishes status.
 type Gateway struct {
-	robotID   api.RobotID
-	zoneID    api.ZoneID
-	protocol  string
-	statusPub *messaging.RobotStatusPublisher
-	bus       messaging.Subscriber
+	robotID        api.RobotID
+	zoneID         api.ZoneID
+	driver         RobotDriver
+	statusPub      *messaging.RobotStatusPublisher
+	bus            messaging.Subscriber
 	statusInterval time.Duration
-	taskDuration   time.Duration // how long to stay BUSY for a TASK (stub)
+	safetyPub      *messaging.SafetyEventPublisher
+	resultPub      *messaging.TaskResultPublisher
+	verifier       *cmdsign.Verifier
+	clock          clock.Clock
 
-	mu    sync.RWMutex
-	state string // IDLE, BUSY, ERROR, CHARGING
-	battery float64
+	mu         sync.RWMutex
+	status     func() // publishes status; set while running, for SetStatusInterval
+	stopStatus func()
+	safety     *safetyInterlock
+	taskCancel context.CancelFunc // cancels the running task on a stop
+	state      string             // IDLE, BUSY (task or firmware); ERROR/CHARGING are derived from telemetry
+	battery    float64
+	position   string
+	charging   bool
+	fault      string
 	// Firmware (simulation)
-	modelID             string
-	firmwareVersion     string
+	modelID              string
+	firmwareVersion      string
 	firmwareUpdateStatus string
-	pendingFirmware     *api.RobotCommand // applied when robot becomes IDLE
+	firmwareDeferReason  string
+	firmwareCommandID    string        // FIRMWARE_UPDATE command the update status belongs to
+	pendingFirmware      *firmwarePlan // applied once the robot is IDLE and the policy allows it
 }
 
-// NewGateway creates an edge gateway for the given robot.
+// NewGateway creates an edge gateway for the given robot. Use NewDriver to create the driver for EdgeConfig.Protocol.
 func NewGateway(
 	robotID api.RobotID,
 	zoneID api.ZoneID,
-	protocol string,
+	driver RobotDriver,
 	statusPub *messaging.RobotStatusPublisher,
 	bus messaging.Subscriber,
 	statusInterval time.Duration,
-	taskDuration time.Duration,
 ) *Gateway {
 	if statusInterval <= 0 {
 		statusInterval = 2 * time.Second
 	}
-	if taskDuration <= 0 {
-		taskDuration = 2 * time.Second
-	}
 	return &Gateway{
-		robotID:             robotID,
-		zoneID:              zoneID,
-		protocol:            protocol,
-		statusPub:           statusPub,
-		bus:                 bus,
-		statusInterval:      statusInterval,
-		taskDuration:        taskDuration,
-		state:               "IDLE",
-		battery:             100,
-		modelID:             "stub-model",
-		firmwareVersion:     "1.0.0",
+		robotID:              robotID,
+		zoneID:               zoneID,
+		driver:               driver,
+		statusPub:            statusPub,
+		bus:                  bus,
+		statusInterval:       statusInterval,
+		state:                "IDLE",
+		battery:              100,
+		modelID:              "stub-model",
+		firmwareVersion:      "1.0.0",
 		firmwareUpdateStatus: api.FirmwareStatusIdle,
+		safety:               newSafetyInterlock(robotID, zoneID),
+		clock:                clock.Real,
 	}
 }
 
-// Run subscribes to robot commands and periodically publishes status. Blocks until ctx is done.
+// SetClock sets the time source for status publishing, timestamps and firmware policy (default clock.Real).
+// Driver I/O always runs in real time. Call before Run.
+func (g *Gateway) SetClock(clk clock.Clock) {
+	g.clock = clk
+}
+
+// SetSafetyEventPublisher sets where safety transitions and rejected commands are published for auditing.
+// Without it they are only logged.
+func (g *Gateway) SetSafetyEventPublisher(p *messaging.SafetyEventPublisher) {
+	g.safetyPub = p
+}
+
+// SetTaskResultPublisher sets where the end of each task is published for the zone.
+func (g *Gateway) SetTaskResultPublisher(p *messaging.TaskResultPublisher) {
+	g.resultPub = p
+}
+
+// SetCommandVerifier makes the gateway check each command's zone signature, freshness and uniqueness before
+// running it (see cmdsign.Verifier.Admit). Without it every command on the bus is trusted.
+func (g *Gateway) SetCommandVerifier(v *cmdsign.Verifier) {
+	g.verifier = v
+}
+
+// Run connects the driver, subscribes to robot commands and periodically publishes status. Blocks until ctx is done.
 func (g *Gateway) Run(ctx context.Context) error {
-	if err := g.bus.Subscribe(ctx, messaging.TopicRobotCommands, g.handleCommand); err != nil {
+	return g.run(ctx, true)
+}
+
+// run is Run; a Cell passes subscribe=false and delivers commands itself through dispatch.
+func (g *Gateway) run(ctx context.Context, subscribe bool) error {
+	if err := g.driver.Connect(ctx); err != nil {
 		return err
 	}
-
-	ticker := time.NewTicker(g.statusInterval)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		case <-ticker.C:
-			g.publishStatus(ctx)
+	defer g.driver.Close()
+	go func() {
+		if err := g.driver.StreamTelemetry(ctx, g.handleTelemetry); err != nil {
+			log.Printf("edge %s: telemetry: %v", g.robotID, err)
+		}
+	}()
+	if subscribe {
+		if err := messaging.SubscribeKeys(ctx, g.bus, messaging.TopicRobotCommands, []string{string(g.robotID), ""}, g.handleCommand); err != nil {
+			return err
 		}
 	}
+
+	status := func() {
+		g.tryApplyFirmware()
+		g.publishStatus(ctx)
+	}
+	g.mu.Lock()
+	g.status = status
+	g.stopStatus = clock.Every(g.clock, g.statusInterval, status)
+	g.mu.Unlock()
+	<-ctx.Done()
+	g.mu.Lock()
+	g.status = nil
+	g.stopStatus()
+	g.mu.Unlock()
+	return nil
+}
+
+// SetStatusInterval sets how often status is published. Safe to call while running.
+func (g *Gateway) SetStatusInterval(d time.Duration) {
+	g.mu.Lock()
+	g.statusInterval = d
+	status, stop := g.status, g.stopStatus
+	g.mu.Unlock()
+	if status == nil {
+		return
+	}
+	stop()
+	next := clock.Every(g.clock, d, status)
+	g.mu.Lock()
+	if g.status == nil {
+		next() // stopped meanwhile
+	} else {
+		g.stopStatus = next
+	}
+	g.mu.Unlock()
 }
 
 func (g *Gateway) handleCommand(key string, value []byte) error {
@@ -86,106 +160,289 @@ func (g *Gateway) handleCommand(key string, value []byte) error {
 	if err := json.Unmarshal(value, &cmd); err != nil {
 		return err
 	}
-	if cmd.RobotID != g.robotID {
+	ok, broadcast := commandTargets(cmd, g.robotID, g.zoneID)
+	if !ok || !g.verifier.Admit(cmd, g.zoneID) {
 		return nil
 	}
+	g.dispatch(cmd, broadcast)
+	return nil
+}
+
+// dispatch runs a command addressed to this robot. Safety commands are always handled; anything else is
+// rejected while the robot is stopped.
+func (g *Gateway) dispatch(cmd api.RobotCommand, broadcast bool) {
 	log.Printf("edge %s: received command %s type=%s", g.robotID, cmd.ID, cmd.Type)
+	if api.IsSafetyCommand(cmd.Type) {
+		source := api.SafetySourceCommand
+		if broadcast {
+			source = api.SafetySourceZoneBroadcast
+		}
+		g.handleSafety(cmd, source)
+		return
+	}
+	g.mu.Lock()
+	ev, err := g.safety.check(cmd, g.clock.Now().UTC())
+	g.mu.Unlock()
+	if err != nil {
+		log.Printf("edge %s: %v", g.robotID, err)
+		g.audit(ev)
+		if cmd.Type != api.RobotCommandTypeFirmwareUpdate {
+			g.taskEnded(cmd, "rejected: robot stopped")
+		}
+		return
+	}
 	switch cmd.Type {
 	case api.RobotCommandTypeFirmwareUpdate:
-		g.simulateFirmwareUpdate(cmd)
+		g.handleFirmwareUpdate(cmd)
 	default:
-		switch g.protocol {
-		case "stub":
-			g.executeStub(cmd)
-		default:
-			g.executeStub(cmd)
-		}
+		g.executeCommand(cmd)
 	}
-	return nil
 }
 
-func (g *Gateway) executeStub(cmd api.RobotCommand) {
+func (g *Gateway) executeCommand(cmd api.RobotCommand) {
+	ctx, cancel := context.WithCancel(context.Background())
 	g.mu.Lock()
 	g.state = "BUSY"
+	g.taskCancel = cancel
 	g.mu.Unlock()
 	go func() {
-		time.Sleep(g.taskDuration)
+		err := g.driver.Execute(ctx, cmd)
+		cancel()
 		g.mu.Lock()
 		if g.state == "BUSY" {
 			g.state = "IDLE"
 		}
-		var pending *api.RobotCommand
-		if g.pendingFirmware != nil {
-			pending = g.pendingFirmware
-			g.pendingFirmware = nil
-		}
 		g.mu.Unlock()
-		log.Printf("edge %s: task %s completed (stub)", g.robotID, cmd.ID)
-		if pending != nil {
-			log.Printf("edge %s: applying deferred firmware update", g.robotID)
-			g.simulateFirmwareUpdate(*pending)
+		if err != nil {
+			log.Printf("edge %s: task %s failed: %v", g.robotID, cmd.ID, err)
+			g.taskEnded(cmd, err.Error())
+		} else {
+			log.Printf("edge %s: task %s completed", g.robotID, cmd.ID)
+			g.taskEnded(cmd, "")
 		}
+		g.tryApplyFirmware()
 	}()
 }
 
-func (g *Gateway) simulateFirmwareUpdate(cmd api.RobotCommand) {
-	var payload api.FirmwareUpdatePayload
-	if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
-		log.Printf("edge %s: invalid firmware payload: %v", g.robotID, err)
+// taskEnded publishes how a task ended when a result publisher is set; empty failure means it succeeded.
+func (g *Gateway) taskEnded(cmd api.RobotCommand, failure string) {
+	if g.resultPub == nil {
+		return
+	}
+	res := &api.TaskResult{TaskID: cmd.ID, RobotID: g.robotID, Status: api.TaskResultSucceeded, At: g.clock.Now().UTC()}
+	if failure != "" {
+		res.Status, res.Error = api.TaskResultFailed, failure
+	}
+	if err := g.resultPub.PublishTaskResult(context.Background(), res); err != nil {
+		log.Printf("edge %s: publish task result: %v", g.robotID, err)
+	}
+}
+
+// handleSafety applies a safety command: a stop cancels the running task and stops the robot through the driver,
+// a reset re-enables commands. Every transition is audited and published immediately, not on the next tick.
+func (g *Gateway) handleSafety(cmd api.RobotCommand, source string) {
+	g.mu.Lock()
+	ev := g.safety.apply(cmd, source, g.clock.Now().UTC())
+	stopping := ev != nil && ev.To != api.SafetyStateNormal
+	var cancel context.CancelFunc
+	if stopping {
+		cancel, g.taskCancel = g.taskCancel, nil
+	}
+	g.mu.Unlock()
+	if ev == nil {
+		return
+	}
+	if stopping {
+		if cancel != nil {
+			cancel()
+		}
+		ctx, done := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := g.driver.EStop(ctx); err != nil {
+			log.Printf("edge %s: driver e-stop: %v", g.robotID, err)
+		}
+		done()
+	}
+	g.audit(ev)
+	g.publishStatus(context.Background())
+	if !stopping {
+		g.tryApplyFirmware()
+	}
+}
+
+// audit logs a safety event and publishes it when a safety publisher is set.
+func (g *Gateway) audit(ev *api.SafetyEvent) {
+	if ev == nil {
 		return
 	}
-	if payload.ModelID != "" && payload.ModelID != g.modelID {
-		log.Printf("edge %s: skip firmware (model %s != %s)", g.robotID, payload.ModelID, g.modelID)
+	log.Printf("edge %s: safety %s %s -> %s (%s %s, source %s, reason %q)", g.robotID, ev.Action, ev.From, ev.To, ev.Command, ev.CommandID, ev.Source, ev.Reason)
+	if g.safetyPub == nil {
+		return
+	}
+	if err := g.safetyPub.PublishSafetyEvent(context.Background(), ev); err != nil {
+		log.Printf("edge %s: publish safety event: %v", g.robotID, err)
+	}
+}
+
+// handleTelemetry updates battery, position and robot-reported state from the driver.
+// A robot-reported e-stop latches the interlock and is published immediately.
+func (g *Gateway) handleTelemetry(t Telemetry) {
+	var ev *api.SafetyEvent
+	defer func() {
+		if ev != nil {
+			g.audit(ev)
+			g.publishStatus(context.Background())
+		}
+	}()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if isEStopFault(t.Fault) {
+		ev = g.safety.robotStop(t.Fault, g.clock.Now().UTC())
+	}
+	if t.Battery != nil {
+		g.battery = *t.Battery
+	}
+	if t.Position != "" {
+		g.position = t.Position
+	}
+	g.charging = t.Charging
+	if t.Fault != g.fault && t.Fault != "" {
+		log.Printf("edge %s: robot fault: %s", g.robotID, t.Fault)
+	}
+	g.fault = t.Fault
+	if t.ModelID != "" {
+		g.modelID = t.ModelID
+	}
+	if t.FirmwareVersion != "" {
+		g.firmwareVersion = t.FirmwareVersion
+	}
+}
+
+// reportedStateLocked combines the gateway's task state with robot telemetry. Caller must hold g.mu.
+func (g *Gateway) reportedStateLocked() string {
+	if g.safety.stopped() {
+		return api.RobotStateStopped
+	}
+	if g.fault != "" {
+		return "ERROR"
+	}
+	if g.state == "IDLE" && g.charging {
+		return "CHARGING"
+	}
+	return g.state
+}
+
+func (g *Gateway) handleFirmwareUpdate(cmd api.RobotCommand) {
+	plan, err := newFirmwarePlan(cmd)
+	if err != nil {
+		log.Printf("edge %s: %v", g.robotID, err)
 		return
 	}
 	g.mu.Lock()
-	if g.state == "BUSY" {
-		g.pendingFirmware = &cmd
+	g.firmwareCommandID = string(cmd.ID)
+	g.firmwareDeferReason = ""
+	if plan.payload.ModelID != "" && plan.payload.ModelID != g.modelID {
+		modelID := g.modelID
+		g.firmwareUpdateStatus = api.FirmwareStatusSkipped
 		g.mu.Unlock()
-		log.Printf("edge %s: firmware update deferred until work order complete", g.robotID)
+		log.Printf("edge %s: skip firmware (model %s != %s)", g.robotID, plan.payload.ModelID, modelID)
 		return
 	}
+	g.firmwareUpdateStatus = api.FirmwareStatusIdle
+	g.pendingFirmware = plan
 	g.mu.Unlock()
-	go func() {
-		g.mu.Lock()
-		g.state = "BUSY"
-		g.firmwareUpdateStatus = api.FirmwareStatusDownloading
+	g.tryApplyFirmware()
+}
+
+// tryApplyFirmware starts the pending firmware update if the robot is IDLE and the policy allows it now;
+// otherwise it records the defer reason. Called on receipt, after each task and on every status tick.
+func (g *Gateway) tryApplyFirmware() {
+	g.mu.Lock()
+	plan := g.pendingFirmware
+	if plan == nil {
 		g.mu.Unlock()
-		log.Printf("edge %s: firmware simulating download -> %s", g.robotID, payload.Version)
-		time.Sleep(2 * time.Second)
-		g.mu.Lock()
-		g.firmwareUpdateStatus = api.FirmwareStatusApplying
+		return
+	}
+	reason := plan.deferReason(g.clock.Now(), g.reportedStateLocked(), g.battery)
+	if reason != "" {
+		changed := reason != g.firmwareDeferReason
+		g.firmwareDeferReason = reason
+		g.firmwareUpdateStatus = api.FirmwareStatusDeferred
 		g.mu.Unlock()
-		time.Sleep(2 * time.Second)
+		if changed {
+			log.Printf("edge %s: firmware %s deferred (%s)", g.robotID, plan.payload.Version, reason)
+		}
+		return
+	}
+	g.pendingFirmware = nil
+	g.firmwareDeferReason = ""
+	g.state = "BUSY"
+	g.firmwareUpdateStatus = api.FirmwareStatusDownloading
+	g.mu.Unlock()
+	go g.applyFirmware(plan.payload)
+}
+
+func (g *Gateway) applyFirmware(payload api.FirmwareUpdatePayload) {
+	log.Printf("edge %s: firmware update -> %s", g.robotID, payload.Version)
+	err := g.driver.UpdateFirmware(context.Background(), payload, func(status string) {
 		g.mu.Lock()
-		g.state = "IDLE"
+		g.firmwareUpdateStatus = status
+		g.mu.Unlock()
+	})
+	g.mu.Lock()
+	g.state = "IDLE"
+	if err != nil {
+		g.firmwareUpdateStatus = api.FirmwareStatusFailed
+	} else {
 		g.firmwareVersion = payload.Version
 		g.firmwareUpdateStatus = api.FirmwareStatusSuccess
-		g.mu.Unlock()
-		log.Printf("edge %s: firmware update complete -> %s", g.robotID, payload.Version)
-	}()
+	}
+	g.mu.Unlock()
+	if err != nil {
+		log.Printf("edge %s: firmware update %s failed: %v", g.robotID, payload.Version, err)
+		return
+	}
+	log.Printf("edge %s: firmware update complete -> %s", g.robotID, payload.Version)
 }
 
 func (g *Gateway) publishStatus(ctx context.Context) {
 	g.mu.RLock()
-	state := g.state
+	state := g.reportedStateLocked()
 	battery := g.battery
+	position := g.position
 	modelID := g.modelID
 	fwVer := g.firmwareVersion
 	fwStatus := g.firmwareUpdateStatus
+	deferReason := g.firmwareDeferReason
+	fwCommand := g.firmwareCommandID
+	safetyState, safetyReason := g.safety.state, g.safety.reason
+	fault := g.fault
 	g.mu.RUnlock()
 
 	status := &api.RobotStatus{
 		RobotID:   g.robotID,
 		State:     state,
+		Position:  position,
 		Battery:   battery,
-		UpdatedAt: time.Now().UTC(),
+		UpdatedAt: g.clock.Now().UTC(),
 		Extra: map[string]interface{}{
-			api.ExtraModelID:             modelID,
-			api.ExtraFirmwareVersion:     fwVer,
+			api.ExtraModelID:              modelID,
+			api.ExtraFirmwareVersion:      fwVer,
 			api.ExtraFirmwareUpdateStatus: fwStatus,
+			api.ExtraSafetyState:          safetyState,
 		},
 	}
+	if deferReason != "" {
+		status.Extra[api.ExtraFirmwareDeferReason] = deferReason
+	}
+	if fwCommand != "" {
+		status.Extra[api.ExtraFirmwareCommandID] = fwCommand
+	}
+	if safetyReason != "" {
+		status.Extra[api.ExtraSafetyReason] = safetyReason
+	}
+	if fault != "" {
+		status.Extra[api.ExtraFault] = fault
+	}
 	if err := g.statusPub.PublishRobotStatus(ctx, status); err != nil {
 		log.Printf("edge %s: publish status: %v", g.robotID, err)
 	}
@@ -0,0 +1,52 @@
+package edge
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+// TestGatewayTelemetry checks that fields a sample does not report keep their last value.
+func TestGatewayTelemetry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := messaging.NewMemoryBus()
+	var got api.RobotStatus
+	if err := bus.Subscribe(ctx, messaging.TopicRobotStatus, func(_ string, value []byte) error {
+		return json.Unmarshal(value, &got)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	g := NewGateway("robot-1", "zone-1", nil, messaging.NewRobotStatusPublisher(bus), bus, time.Second)
+
+	battery := 42.0
+	for _, tc := range []struct {
+		sample   Telemetry
+		battery  float64
+		position string
+	}{
+		{Telemetry{}, 100, ""},
+		{Telemetry{Battery: &battery, Position: "1.0,2.0"}, 42, "1.0,2.0"},
+		{Telemetry{Position: "3.0,4.0"}, 42, "3.0,4.0"},
+		{Telemetry{Fault: "gripper"}, 42, "3.0,4.0"},
+	} {
+		g.handleTelemetry(tc.sample)
+		g.publishStatus(ctx)
+		if got.Battery != tc.battery || got.Position != tc.position {
+			t.Errorf("after %+v: battery %v position %q, want %v %q", tc.sample, got.Battery, got.Position, tc.battery, tc.position)
+		}
+	}
+	if got.State != "ERROR" {
+		t.Errorf("state %s after a fault, want ERROR", got.State)
+	}
+
+	// On the wire an unreported battery is absent, not 0.
+	var sample Telemetry
+	if err := json.Unmarshal([]byte(`{"position":"5.0,6.0"}`), &sample); err != nil || sample.Battery != nil {
+		t.Errorf("decoded battery %v, %v; want nil", sample.Battery, err)
+	}
+}
@@ -0,0 +1,484 @@
+package edge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+func init() {
+	RegisterDriver("mqtt", newMQTTDriver)
+}
+
+// mqttDriver talks to a robot through an MQTT broker (robot_address, e.g. "tcp://broker:1883") using a per-model
+// topic/payload mapping (MQTTMapping). All mqtt drivers in a process with the same broker share one connection
+// and one wildcard subscription per topic template, so a cell gateway can serve many robots.
+// Options: mapping (path, required), client_id (default "robotfleetos-edge-<host>-<pid>"), username,
+// password (or env MQTT_PASSWORD), task_timeout (default "10m").
+type mqttDriver struct {
+	robotID     api.RobotID
+	broker      string
+	mapping     *MQTTMapping
+	clientID    string
+	username    string
+	password    string
+	taskTimeout time.Duration
+
+	conn *mqttConn
+
+	mu         sync.Mutex
+	telem      Telemetry
+	posX, posY float64
+	onTelem    func(Telemetry)
+	pending    map[string]chan error // command ID -> result
+	fwProgress func(string)
+	fwDone     chan error
+}
+
+func newMQTTDriver(cfg DriverConfig) (RobotDriver, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("mqtt driver: robot_address required (tcp://broker:1883)")
+	}
+	path := cfg.Options["mapping"]
+	if path == "" {
+		return nil, fmt.Errorf("mqtt driver: driver_options.mapping required")
+	}
+	mapping, err := LoadMQTTMapping(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &mqttDriver{
+		robotID:     cfg.RobotID,
+		broker:      cfg.Address,
+		mapping:     mapping,
+		clientID:    cfg.Options["client_id"],
+		username:    cfg.Options["username"],
+		password:    cfg.Options["password"],
+		taskTimeout: 10 * time.Minute,
+		pending:     make(map[string]chan error),
+	}
+	d.telem.ModelID = mapping.ModelID
+	if d.clientID == "" {
+		host, _ := os.Hostname()
+		d.clientID = fmt.Sprintf("robotfleetos-edge-%s-%d", host, os.Getpid())
+	}
+	if d.password == "" {
+		d.password = os.Getenv("MQTT_PASSWORD")
+	}
+	if v := cfg.Options["task_timeout"]; v != "" {
+		dur, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("mqtt driver task_timeout: %w", err)
+		}
+		d.taskTimeout = dur
+	}
+	return d, nil
+}
+
+func (d *mqttDriver) Connect(ctx context.Context) error {
+	conn, err := acquireMQTTConn(d.broker, d.clientID, d.username, d.password)
+	if err != nil {
+		return err
+	}
+	d.conn = conn
+	t := d.mapping.Topics
+	conn.route(t.State, d.mapping.QoS, d.robotID, d.handleState)
+	if t.Result != "" {
+		conn.route(t.Result, d.mapping.QoS, d.robotID, d.handleResult)
+	}
+	if t.FirmwareStatus != "" {
+		conn.route(t.FirmwareStatus, d.mapping.QoS, d.robotID, d.handleFirmwareStatus)
+	}
+	return nil
+}
+
+func (d *mqttDriver) StreamTelemetry(ctx context.Context, fn func(Telemetry)) error {
+	d.mu.Lock()
+	d.onTelem = fn
+	d.mu.Unlock()
+	<-ctx.Done()
+	d.mu.Lock()
+	d.onTelem = nil
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *mqttDriver) handleState(payload []byte) {
+	var doc interface{}
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		log.Printf("edge %s: mqtt: invalid state message: %v", d.robotID, err)
+		return
+	}
+	f := d.mapping.Telemetry
+	d.mu.Lock()
+	if v, ok := jsonPath(doc, f.Battery); ok {
+		battery := toFloat(v)
+		d.telem.Battery = &battery
+	}
+	if v, ok := jsonPath(doc, f.Position); ok {
+		if xy, isArr := v.([]interface{}); isArr && len(xy) == 2 {
+			d.telem.Position = fmt.Sprintf("%.1f,%.1f", toFloat(xy[0]), toFloat(xy[1]))
+		} else {
+			d.telem.Position = fmt.Sprint(v)
+		}
+	} else {
+		x, okX := jsonPath(doc, f.PositionX)
+		y, okY := jsonPath(doc, f.PositionY)
+		if okX || okY {
+			if okX {
+				d.posX = toFloat(x)
+			}
+			if okY {
+				d.posY = toFloat(y)
+			}
+			d.telem.Position = fmt.Sprintf("%.1f,%.1f", d.posX, d.posY)
+		}
+	}
+	if v, ok := jsonPath(doc, f.Charging); ok {
+		d.telem.Charging = toBool(v)
+	}
+	if v, ok := jsonPath(doc, f.Fault); ok {
+		switch b := v.(type) {
+		case bool:
+			d.telem.Fault = ""
+			if b {
+				d.telem.Fault = "fault"
+			}
+		case nil:
+			d.telem.Fault = ""
+		default:
+			d.telem.Fault = fmt.Sprint(v)
+		}
+	}
+	if v, ok := jsonPath(doc, f.FirmwareVersion); ok {
+		d.telem.FirmwareVersion = fmt.Sprint(v)
+	}
+	t, fn := d.telem, d.onTelem
+	d.mu.Unlock()
+	if fn != nil {
+		fn(t)
+	}
+}
+
+func (d *mqttDriver) handleResult(payload []byte) {
+	var doc interface{}
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		log.Printf("edge %s: mqtt: invalid result message: %v", d.robotID, err)
+		return
+	}
+	f := d.mapping.Result
+	idVal, _ := jsonPath(doc, f.ID)
+	id := fmt.Sprint(idVal)
+	d.mu.Lock()
+	ch, ok := d.pending[id]
+	delete(d.pending, id)
+	d.mu.Unlock()
+	if !ok {
+		return
+	}
+	var err error
+	if !d.resultOK(doc) {
+		msg := "rejected by robot"
+		if v, ok := jsonPath(doc, f.Error); ok && fmt.Sprint(v) != "" {
+			msg = fmt.Sprint(v)
+		}
+		err = errors.New(msg)
+	}
+	ch <- err
+}
+
+// resultOK evaluates result.ok; without a mapping every result is a success.
+func (d *mqttDriver) resultOK(doc interface{}) bool {
+	f := d.mapping.Result
+	if f.OK == "" {
+		return true
+	}
+	v, ok := jsonPath(doc, f.OK)
+	if !ok {
+		return false
+	}
+	if f.OKValue != "" {
+		return fmt.Sprint(v) == f.OKValue
+	}
+	return toBool(v)
+}
+
+func (d *mqttDriver) handleFirmwareStatus(payload []byte) {
+	var doc interface{}
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		log.Printf("edge %s: mqtt: invalid firmware status message: %v", d.robotID, err)
+		return
+	}
+	v, ok := jsonPath(doc, d.mapping.FirmwareStatus.Status)
+	if !ok {
+		return
+	}
+	st := fmt.Sprint(v)
+	if mapped, ok := d.mapping.FirmwareStatus.StatusMap[st]; ok {
+		st = mapped
+	}
+	st = strings.ToLower(st)
+	d.mu.Lock()
+	progress, done := d.fwProgress, d.fwDone
+	if st == api.FirmwareStatusSuccess || st == api.FirmwareStatusFailed {
+		d.fwProgress, d.fwDone = nil, nil
+	}
+	d.mu.Unlock()
+	switch st {
+	case api.FirmwareStatusSuccess:
+		if done != nil {
+			done <- nil
+		}
+	case api.FirmwareStatusFailed:
+		if done != nil {
+			done <- fmt.Errorf("robot reported firmware update failed")
+		}
+	default:
+		if progress != nil {
+			progress(st)
+		}
+	}
+}
+
+// Execute publishes the command and waits for its result (when the mapping has a result topic).
+func (d *mqttDriver) Execute(ctx context.Context, cmd api.RobotCommand) error {
+	vars := map[string]string{"id": string(cmd.ID), "type": cmd.Type, "robot_id": string(d.robotID)}
+	body, err := renderPayload(d.mapping.Payloads.Command, vars, cmd.Payload)
+	if err != nil {
+		return fmt.Errorf("mqtt driver: command: %w", err)
+	}
+	if d.mapping.Topics.Result == "" {
+		return d.publish(d.mapping.Topics.Command, body)
+	}
+	ch := make(chan error, 1)
+	d.mu.Lock()
+	d.pending[string(cmd.ID)] = ch
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		delete(d.pending, string(cmd.ID))
+		d.mu.Unlock()
+	}()
+	if err := d.publish(d.mapping.Topics.Command, body); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, d.taskTimeout)
+	defer cancel()
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("mqtt driver: %w", ctx.Err())
+	case err := <-ch:
+		return err
+	}
+}
+
+func (d *mqttDriver) EStop(ctx context.Context) error {
+	if d.mapping.Topics.EStop == "" {
+		return fmt.Errorf("mqtt driver: no estop topic in mapping for %s", d.mapping.ModelID)
+	}
+	body, err := renderPayload(d.mapping.Payloads.EStop, map[string]string{"robot_id": string(d.robotID)}, nil)
+	if err != nil {
+		return fmt.Errorf("mqtt driver: estop: %w", err)
+	}
+	return d.publish(d.mapping.Topics.EStop, body)
+}
+
+// UpdateFirmware publishes the firmware request and follows the firmware status topic until success or failed.
+func (d *mqttDriver) UpdateFirmware(ctx context.Context, payload api.FirmwareUpdatePayload, progress func(string)) error {
+	if d.mapping.Topics.Firmware == "" {
+		return fmt.Errorf("mqtt driver: no firmware topic in mapping for %s", d.mapping.ModelID)
+	}
+	vars := map[string]string{
+		"robot_id":        string(d.robotID),
+		"version":         payload.Version,
+		"download_url":    payload.DownloadURL,
+		"checksum_sha256": payload.ChecksumSHA256,
+		"campaign_id":     payload.CampaignID,
+	}
+	body, err := renderPayload(d.mapping.Payloads.Firmware, vars, nil)
+	if err != nil {
+		return fmt.Errorf("mqtt driver: firmware: %w", err)
+	}
+	if d.mapping.Topics.FirmwareStatus == "" {
+		return d.publish(d.mapping.Topics.Firmware, body)
+	}
+	done := make(chan error, 1)
+	d.mu.Lock()
+	d.fwProgress, d.fwDone = progress, done
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		if d.fwDone == done {
+			d.fwProgress, d.fwDone = nil, nil
+		}
+		d.mu.Unlock()
+	}()
+	if err := d.publish(d.mapping.Topics.Firmware, body); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}
+
+func (d *mqttDriver) publish(template string, body []byte) error {
+	if d.conn == nil {
+		return errNotConnected
+	}
+	topic := strings.Replace(template, "{robot_id}", string(d.robotID), 1)
+	tok := d.conn.client.Publish(topic, d.mapping.QoS, false, body)
+	if !tok.WaitTimeout(10 * time.Second) {
+		return fmt.Errorf("mqtt driver: publish %s: timeout", topic)
+	}
+	if err := tok.Error(); err != nil {
+		return fmt.Errorf("mqtt driver: publish %s: %w", topic, err)
+	}
+	return nil
+}
+
+func (d *mqttDriver) Close() error {
+	if d.conn == nil {
+		return nil
+	}
+	t := d.mapping.Topics
+	for _, tmpl := range []string{t.State, t.Result, t.FirmwareStatus} {
+		if tmpl != "" {
+			d.conn.unroute(tmpl, d.robotID)
+		}
+	}
+	releaseMQTTConn(d.conn)
+	d.conn = nil
+	return nil
+}
+
+// mqttConn is one broker connection shared by every mqtt driver in the process. Incoming messages on a
+// wildcard subscription are dispatched to the robot whose ID appears in the topic.
+type mqttConn struct {
+	key    string
+	client mqtt.Client
+	refs   int
+
+	mu     sync.RWMutex
+	routes map[string]*mqttRoute // keyed by topic template
+}
+
+type mqttRoute struct {
+	qos      byte
+	handlers map[api.RobotID]func([]byte)
+}
+
+var (
+	mqttConnsMu sync.Mutex
+	mqttConns   = make(map[string]*mqttConn)
+)
+
+func acquireMQTTConn(broker, clientID, username, password string) (*mqttConn, error) {
+	key := broker + "|" + clientID
+	mqttConnsMu.Lock()
+	defer mqttConnsMu.Unlock()
+	if c := mqttConns[key]; c != nil {
+		c.refs++
+		return c, nil
+	}
+	c := &mqttConn{key: key, refs: 1, routes: make(map[string]*mqttRoute)}
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(clientID).
+		SetUsername(username).
+		SetPassword(password).
+		SetCleanSession(true).
+		SetAutoReconnect(true).
+		SetOrderMatters(false).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Printf("edge: mqtt %s: connection lost: %v", broker, err)
+		}).
+		SetOnConnectHandler(func(mqtt.Client) { c.resubscribe() })
+	c.client = mqtt.NewClient(opts)
+	tok := c.client.Connect()
+	if !tok.WaitTimeout(15 * time.Second) {
+		return nil, fmt.Errorf("mqtt driver: connect %s: timeout", broker)
+	}
+	if err := tok.Error(); err != nil {
+		return nil, fmt.Errorf("mqtt driver: connect %s: %w", broker, err)
+	}
+	mqttConns[key] = c
+	return c, nil
+}
+
+func releaseMQTTConn(c *mqttConn) {
+	mqttConnsMu.Lock()
+	defer mqttConnsMu.Unlock()
+	c.refs--
+	if c.refs > 0 {
+		return
+	}
+	delete(mqttConns, c.key)
+	c.client.Disconnect(250)
+}
+
+// route registers fn for messages on template's topic for robotID, subscribing on first use.
+func (c *mqttConn) route(template string, qos byte, robotID api.RobotID, fn func([]byte)) {
+	c.mu.Lock()
+	r, ok := c.routes[template]
+	if !ok {
+		r = &mqttRoute{qos: qos, handlers: make(map[api.RobotID]func([]byte))}
+		c.routes[template] = r
+	}
+	r.handlers[robotID] = fn
+	c.mu.Unlock()
+	if !ok {
+		c.subscribe(template, qos)
+	}
+}
+
+func (c *mqttConn) unroute(template string, robotID api.RobotID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r := c.routes[template]; r != nil {
+		delete(r.handlers, robotID)
+	}
+}
+
+func (c *mqttConn) subscribe(template string, qos byte) {
+	filter := topicFilter(template)
+	tok := c.client.Subscribe(filter, qos, func(_ mqtt.Client, msg mqtt.Message) {
+		robotID := api.RobotID(topicRobot(template, msg.Topic()))
+		c.mu.RLock()
+		var fn func([]byte)
+		if r := c.routes[template]; r != nil {
+			fn = r.handlers[robotID]
+		}
+		c.mu.RUnlock()
+		if fn != nil {
+			fn(msg.Payload())
+		}
+	})
+	if tok.WaitTimeout(10*time.Second) && tok.Error() != nil {
+		log.Printf("edge: mqtt subscribe %s: %v", filter, tok.Error())
+	}
+}
+
+// resubscribe restores the wildcard subscriptions after a (re)connect; the session is clean.
+func (c *mqttConn) resubscribe() {
+	c.mu.RLock()
+	routes := make(map[string]byte, len(c.routes))
+	for tmpl, r := range c.routes {
+		routes[tmpl] = r.qos
+	}
+	c.mu.RUnlock()
+	for tmpl, qos := range routes {
+		go c.subscribe(tmpl, qos)
+	}
+}
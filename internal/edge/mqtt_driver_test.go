@@ -0,0 +1,186 @@
+package edge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+// TestMQTTCell drives three robots through one Cell over an in-process broker speaking
+// configs/mqtt/stub-model.yaml: commands go out on each robot's topic with the mapped payload, state
+// messages become robot status, and each result completes only the pending command it names.
+func TestMQTTCell(t *testing.T) {
+	broker := mqtt.New(&mqtt.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := broker.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatal(err)
+	}
+	tcp := listeners.NewTCP(listeners.Config{ID: "tcp", Address: "127.0.0.1:0"})
+	if err := broker.AddListener(tcp); err != nil {
+		t.Fatal(err)
+	}
+	go broker.Serve()
+	defer broker.Close()
+
+	// The robots answer each command after a short delay. Before that, robot-1 publishes a failed result
+	// naming robot-2's command on its own topic, which must not complete robot-2's command.
+	var mu sync.Mutex
+	commands := make(map[string]map[string]interface{}) // topic -> payload
+	publish := func(topic string, v interface{}) {
+		data, _ := json.Marshal(v)
+		if err := broker.Publish(topic, data, false, 1); err != nil {
+			t.Error(err)
+		}
+	}
+	err := broker.Subscribe("fleet/robots/+/cmd", 1, func(_ *mqtt.Client, _ packets.Subscription, pk packets.Packet) {
+		var body map[string]interface{}
+		if err := json.Unmarshal(pk.Payload, &body); err != nil {
+			t.Errorf("%s: %v", pk.TopicName, err)
+			return
+		}
+		mu.Lock()
+		commands[pk.TopicName] = body
+		mu.Unlock()
+		prefix := strings.TrimSuffix(pk.TopicName, "cmd")
+		go func() {
+			if prefix == "fleet/robots/robot-1/" {
+				publish(prefix+"result", map[string]string{"job_id": "cmd-robot-2", "status": "failed", "message": "cross-talk"})
+			}
+			time.Sleep(100 * time.Millisecond)
+			res := map[string]string{"job_id": fmt.Sprint(body["job_id"]), "status": "done"}
+			if prefix == "fleet/robots/robot-3/" {
+				res["status"], res["message"] = "failed", "gripper jam"
+			}
+			publish(prefix+"result", res)
+		}()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus := messaging.NewMemoryBus()
+	cfg := EdgeConfig{
+		ZoneID:        "zone-1",
+		Protocol:      "mqtt",
+		Address:       "tcp://" + tcp.Address(),
+		DriverOptions: map[string]string{"mapping": "../../configs/mqtt/stub-model.yaml", "client_id": "cell-test"},
+		Robots:        []CellRobotConfig{{RobotID: "robot-1"}, {RobotID: "robot-2"}, {RobotID: "robot-3"}},
+	}
+	cell, err := NewCell(cfg, messaging.NewRobotStatusPublisher(bus), bus, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cell.SetTaskResultPublisher(messaging.NewTaskResultPublisher(bus))
+
+	statuses := make(map[api.RobotID]api.RobotStatus)
+	results := make(map[api.RobotID]api.TaskResult)
+	bus.Subscribe(context.Background(), messaging.TopicRobotStatus, func(_ string, value []byte) error {
+		var s api.RobotStatus
+		if err := json.Unmarshal(value, &s); err != nil {
+			return err
+		}
+		mu.Lock()
+		statuses[s.RobotID] = s
+		mu.Unlock()
+		return nil
+	})
+	bus.Subscribe(context.Background(), messaging.TopicTaskResults, func(_ string, value []byte) error {
+		var r api.TaskResult
+		if err := json.Unmarshal(value, &r); err != nil {
+			return err
+		}
+		mu.Lock()
+		results[r.RobotID] = r
+		mu.Unlock()
+		return nil
+	})
+	waitUntil := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			mu.Lock()
+			ok := cond()
+			mu.Unlock()
+			if ok {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cell.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	waitUntil("status from every robot", func() bool { return len(statuses) == 3 })
+
+	for i := 1; i <= 3; i++ {
+		publish(fmt.Sprintf("fleet/robots/robot-%d/state", i), map[string]interface{}{
+			"pose":     map[string]float64{"x": float64(i), "y": 2},
+			"battery":  map[string]interface{}{"soc": 10 * i, "charging": false},
+			"firmware": "1.0.0",
+			"error":    nil,
+		})
+	}
+	for i := 1; i <= 3; i++ {
+		id := api.RobotID(fmt.Sprintf("robot-%d", i))
+		want := fmt.Sprintf("%d.0,2.0", i)
+		waitUntil("state of "+string(id), func() bool {
+			s := statuses[id]
+			return s.Position == want && s.Battery == float64(10*i)
+		})
+	}
+
+	cmdPub := messaging.NewRobotCommandPublisher(bus)
+	for i := 1; i <= 3; i++ {
+		id := api.RobotID(fmt.Sprintf("robot-%d", i))
+		cmd := &api.RobotCommand{ID: api.TaskID("cmd-" + id), RobotID: id, Type: "PICK", Payload: []byte(`{"sku":"A"}`)}
+		if err := cmdPub.PublishRobotCommand(ctx, cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitUntil("a result from every robot", func() bool { return len(results) == 3 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 1; i <= 3; i++ {
+		id := api.RobotID(fmt.Sprintf("robot-%d", i))
+		body := commands["fleet/robots/"+string(id)+"/cmd"]
+		params, _ := body["params"].(map[string]interface{})
+		if body["job_id"] != "cmd-"+string(id) || body["action"] != "PICK" || params["sku"] != "A" {
+			t.Errorf("%s: command payload %v", id, body)
+		}
+		r := results[id]
+		wantStatus, wantErr := api.TaskResultSucceeded, ""
+		if i == 3 {
+			wantStatus, wantErr = api.TaskResultFailed, "gripper jam"
+		}
+		if r.TaskID != api.TaskID("cmd-"+id) || r.Status != wantStatus || r.Error != wantErr {
+			t.Errorf("%s: result %+v, want %s %q", id, r, wantStatus, wantErr)
+		}
+	}
+}
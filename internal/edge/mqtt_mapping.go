@@ -0,0 +1,180 @@
+package edge
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MQTTMapping maps one robot model's MQTT topics and JSON payloads to RobotFleetOS commands and telemetry.
+// Loaded from a per-model YAML file (see configs/mqtt/). Topic templates must contain {robot_id} as a
+// whole topic level so one broker connection can serve every robot in the process.
+type MQTTMapping struct {
+	ModelID        string              `yaml:"model_id"`
+	QoS            byte                `yaml:"qos"`
+	Topics         MQTTTopics          `yaml:"topics"`
+	Payloads       MQTTPayloads        `yaml:"payloads"`
+	Telemetry      MQTTTelemetryFields `yaml:"telemetry"`
+	Result         MQTTResultFields    `yaml:"result"`
+	FirmwareStatus MQTTFirmwareFields  `yaml:"firmware_status"`
+}
+
+// MQTTTopics are topic templates. Edge → robot: command, estop, firmware. Robot → edge: state, result, firmware_status.
+type MQTTTopics struct {
+	Command        string `yaml:"command"`
+	EStop          string `yaml:"estop"`
+	Firmware       string `yaml:"firmware"`
+	State          string `yaml:"state"`
+	Result         string `yaml:"result"`          // empty = commands complete once published
+	FirmwareStatus string `yaml:"firmware_status"` // empty = firmware completes once published
+}
+
+// MQTTPayloads are JSON templates for outgoing messages. String placeholders ({id}, {type}, {robot_id}, and for
+// firmware {version}, {download_url}, {checksum_sha256}, {campaign_id}) are JSON-escaped and go inside quotes;
+// {payload} is the command payload as raw JSON and goes outside quotes.
+type MQTTPayloads struct {
+	Command  string `yaml:"command"`
+	EStop    string `yaml:"estop"`
+	Firmware string `yaml:"firmware"`
+}
+
+// MQTTTelemetryFields are dot-separated JSON paths into state messages. Empty = not reported by this model.
+type MQTTTelemetryFields struct {
+	Battery         string `yaml:"battery"`
+	Position        string `yaml:"position"`   // string or [x, y]
+	PositionX       string `yaml:"position_x"` // used with position_y when position is not mapped
+	PositionY       string `yaml:"position_y"`
+	Charging        string `yaml:"charging"`
+	Fault           string `yaml:"fault"` // string (non-empty = fault) or bool
+	FirmwareVersion string `yaml:"firmware_version"`
+}
+
+// MQTTResultFields locate the command ID and outcome in result messages.
+type MQTTResultFields struct {
+	ID      string `yaml:"id"`       // must equal the {id} sent in the command
+	OK      string `yaml:"ok"`       // bool, or compared with OKValue
+	OKValue string `yaml:"ok_value"` // e.g. "done"; empty = OK is a bool
+	Error   string `yaml:"error"`
+}
+
+// MQTTFirmwareFields locate the status in firmware status messages. StatusMap translates robot values to
+// api.FirmwareStatus* (downloading, applying, success, failed); unmapped values pass through lower-cased.
+type MQTTFirmwareFields struct {
+	Status    string            `yaml:"status"`
+	StatusMap map[string]string `yaml:"status_map"`
+}
+
+// LoadMQTTMapping reads and validates a mapping file.
+func LoadMQTTMapping(path string) (*MQTTMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := MQTTMapping{QoS: 1}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("mqtt mapping %s: %w", path, err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("mqtt mapping %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+func (m *MQTTMapping) validate() error {
+	if m.QoS > 2 {
+		return fmt.Errorf("qos must be 0, 1 or 2")
+	}
+	if m.Topics.Command == "" || m.Topics.State == "" {
+		return fmt.Errorf("topics.command and topics.state are required")
+	}
+	for name, t := range map[string]string{
+		"command": m.Topics.Command, "estop": m.Topics.EStop, "firmware": m.Topics.Firmware,
+		"state": m.Topics.State, "result": m.Topics.Result, "firmware_status": m.Topics.FirmwareStatus,
+	} {
+		if t != "" && robotLevel(t) < 0 {
+			return fmt.Errorf("topics.%s: {robot_id} must be a whole topic level", name)
+		}
+	}
+	if m.Topics.Result != "" && m.Result.ID == "" {
+		return fmt.Errorf("result.id is required when topics.result is set")
+	}
+	if m.Topics.FirmwareStatus != "" && m.FirmwareStatus.Status == "" {
+		return fmt.Errorf("firmware_status.status is required when topics.firmware_status is set")
+	}
+	return nil
+}
+
+// robotLevel returns the index of the {robot_id} level in a topic template, or -1.
+func robotLevel(template string) int {
+	for i, level := range strings.Split(template, "/") {
+		if level == "{robot_id}" {
+			return i
+		}
+	}
+	return -1
+}
+
+// topicFilter turns a template into the wildcard subscription covering every robot.
+func topicFilter(template string) string {
+	return strings.Replace(template, "{robot_id}", "+", 1)
+}
+
+// topicRobot returns the robot ID in topic according to template.
+func topicRobot(template, topic string) string {
+	i := robotLevel(template)
+	levels := strings.Split(topic, "/")
+	if i < 0 || i >= len(levels) {
+		return ""
+	}
+	return levels[i]
+}
+
+// renderPayload fills a JSON template. An empty template sends vars as a JSON object with the command
+// payload under "payload".
+func renderPayload(template string, vars map[string]string, payload []byte) ([]byte, error) {
+	raw := rawJSON(payload)
+	if template == "" {
+		obj := make(map[string]interface{}, len(vars)+1)
+		for k, v := range vars {
+			obj[k] = v
+		}
+		if raw != nil {
+			obj["payload"] = raw
+		}
+		return json.Marshal(obj)
+	}
+	out := template
+	for k, v := range vars {
+		esc, _ := json.Marshal(v)
+		out = strings.ReplaceAll(out, "{"+k+"}", string(esc[1:len(esc)-1]))
+	}
+	if raw == nil {
+		raw = json.RawMessage("null")
+	}
+	out = strings.ReplaceAll(out, "{payload}", string(raw))
+	if !json.Valid([]byte(out)) {
+		return nil, fmt.Errorf("payload template does not produce valid JSON: %s", out)
+	}
+	return []byte(out), nil
+}
+
+// jsonPath looks up a dot-separated path in a decoded JSON object.
+func jsonPath(doc interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return nil, false
+	}
+	cur := doc
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
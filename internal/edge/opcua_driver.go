@@ -0,0 +1,399 @@
+package edge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/ua"
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+func init() {
+	RegisterDriver("opcua", newOPCUADriver)
+}
+
+// opcuaDriver is an OPC UA client for robots (or PLC cells) exposing an OPC UA server.
+// Commands map to method calls and/or node writes, telemetry comes from monitored items; both are
+// described by a per-model node map (OPCUANodeMap). Security mode None with anonymous auth.
+// Options: node_map (path, required), publish_interval (default "500ms"), task_timeout (default "10m"),
+// start_timeout (how long to wait for the busy node to rise, default "2s").
+type opcuaDriver struct {
+	robotID         api.RobotID
+	endpoint        string
+	nodes           *OPCUANodeMap
+	publishInterval time.Duration
+	taskTimeout     time.Duration
+	startTimeout    time.Duration
+
+	client *opcua.Client
+
+	mu       sync.Mutex
+	telem    Telemetry
+	posX     float64
+	posY     float64
+	busy     bool
+	fwStatus string
+	changed  chan struct{} // closed and replaced on every busy/firmware_status change
+}
+
+func newOPCUADriver(cfg DriverConfig) (RobotDriver, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("opcua driver: robot_address required (opc.tcp://host:port)")
+	}
+	path := cfg.Options["node_map"]
+	if path == "" {
+		return nil, fmt.Errorf("opcua driver: driver_options.node_map required")
+	}
+	nodes, err := LoadOPCUANodeMap(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &opcuaDriver{
+		robotID:         cfg.RobotID,
+		endpoint:        cfg.Address,
+		nodes:           nodes,
+		publishInterval: 500 * time.Millisecond,
+		taskTimeout:     10 * time.Minute,
+		startTimeout:    2 * time.Second,
+		changed:         make(chan struct{}),
+	}
+	d.telem.ModelID = nodes.ModelID
+	for name, dst := range map[string]*time.Duration{
+		"publish_interval": &d.publishInterval,
+		"task_timeout":     &d.taskTimeout,
+		"start_timeout":    &d.startTimeout,
+	} {
+		if v := cfg.Options[name]; v != "" {
+			dur, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("opcua driver %s: %w", name, err)
+			}
+			*dst = dur
+		}
+	}
+	return d, nil
+}
+
+func (d *opcuaDriver) Connect(ctx context.Context) error {
+	c, err := opcua.NewClient(d.endpoint,
+		opcua.SecurityMode(ua.MessageSecurityModeNone),
+		opcua.AuthAnonymous(),
+		opcua.AutoReconnect(true),
+	)
+	if err != nil {
+		return fmt.Errorf("opcua driver: %w", err)
+	}
+	if err := c.Connect(ctx); err != nil {
+		return fmt.Errorf("opcua driver: connect %s: %w", d.endpoint, err)
+	}
+	d.client = c
+	return nil
+}
+
+// StreamTelemetry subscribes to every mapped telemetry node and reports a sample per data change.
+func (d *opcuaDriver) StreamTelemetry(ctx context.Context, fn func(Telemetry)) error {
+	fields := d.nodes.Telemetry.nodes()
+	if len(fields) == 0 {
+		<-ctx.Done()
+		return nil
+	}
+	notify := make(chan *opcua.PublishNotificationData, 16)
+	sub, err := d.client.Subscribe(ctx, &opcua.SubscriptionParameters{Interval: d.publishInterval}, notify)
+	if err != nil {
+		return fmt.Errorf("opcua driver: subscribe: %w", err)
+	}
+	defer sub.Cancel(context.Background())
+
+	handles := make(map[uint32]string, len(fields))
+	var reqs []*ua.MonitoredItemCreateRequest
+	for field, id := range fields {
+		nid, _ := ua.ParseNodeID(id) // validated by LoadOPCUANodeMap
+		h := uint32(len(reqs) + 1)
+		handles[h] = field
+		reqs = append(reqs, opcua.NewMonitoredItemCreateRequestWithDefaults(nid, ua.AttributeIDValue, h))
+	}
+	res, err := sub.Monitor(ctx, ua.TimestampsToReturnNeither, reqs...)
+	if err != nil {
+		return fmt.Errorf("opcua driver: monitor: %w", err)
+	}
+	for i, r := range res.Results {
+		if r.StatusCode != ua.StatusOK {
+			log.Printf("edge %s: opcua: monitor %s: %v", d.robotID, handles[uint32(i+1)], r.StatusCode)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-notify:
+			if n.Error != nil {
+				log.Printf("edge %s: opcua: subscription: %v", d.robotID, n.Error)
+				continue
+			}
+			dc, ok := n.Value.(*ua.DataChangeNotification)
+			if !ok {
+				continue
+			}
+			for _, item := range dc.MonitoredItems {
+				if item.Value == nil || item.Value.Value == nil {
+					continue
+				}
+				d.apply(handles[item.ClientHandle], item.Value.Value.Value())
+			}
+			d.mu.Lock()
+			t := d.telem
+			d.mu.Unlock()
+			fn(t)
+		}
+	}
+}
+
+// apply stores one monitored value in the telemetry snapshot.
+func (d *opcuaDriver) apply(field string, v interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	signal := false
+	switch field {
+	case "battery":
+		battery := toFloat(v)
+		d.telem.Battery = &battery
+	case "position":
+		d.telem.Position = fmt.Sprint(v)
+	case "position_x":
+		d.posX = toFloat(v)
+		d.telem.Position = fmt.Sprintf("%.1f,%.1f", d.posX, d.posY)
+	case "position_y":
+		d.posY = toFloat(v)
+		d.telem.Position = fmt.Sprintf("%.1f,%.1f", d.posX, d.posY)
+	case "charging":
+		d.telem.Charging = toBool(v)
+	case "fault":
+		if b, ok := v.(bool); ok {
+			d.telem.Fault = ""
+			if b {
+				d.telem.Fault = "fault"
+			}
+		} else {
+			d.telem.Fault = fmt.Sprint(v)
+		}
+	case "busy":
+		d.busy = toBool(v)
+		signal = true
+	case "firmware_version":
+		d.telem.FirmwareVersion = fmt.Sprint(v)
+	case "firmware_status":
+		d.fwStatus = strings.ToLower(fmt.Sprint(v))
+		signal = true
+	}
+	if signal {
+		close(d.changed)
+		d.changed = make(chan struct{})
+	}
+}
+
+// Execute runs the action mapped for cmd.Type (or "*"), then waits for the busy node to rise and fall.
+// Without a busy node the command is complete once the action succeeds.
+func (d *opcuaDriver) Execute(ctx context.Context, cmd api.RobotCommand) error {
+	action, ok := d.nodes.Commands[cmd.Type]
+	if !ok {
+		action, ok = d.nodes.Commands["*"]
+	}
+	if !ok {
+		return fmt.Errorf("opcua driver: no mapping for command type %q in node map for %s", cmd.Type, d.nodes.ModelID)
+	}
+	vars := map[string]string{
+		"id":       string(cmd.ID),
+		"type":     cmd.Type,
+		"payload":  string(cmd.Payload),
+		"robot_id": string(d.robotID),
+	}
+	if err := d.run(ctx, action, vars); err != nil {
+		return err
+	}
+	if d.nodes.Telemetry.Busy == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, d.taskTimeout)
+	defer cancel()
+	started, err := d.waitFor(ctx, d.startTimeout, func() bool { return d.busy })
+	if err != nil {
+		return err
+	}
+	if !started {
+		return nil // finished before the next publish, or the robot does not raise busy for this command
+	}
+	_, err = d.waitFor(ctx, 0, func() bool { return !d.busy })
+	return err
+}
+
+func (d *opcuaDriver) EStop(ctx context.Context) error {
+	if d.nodes.EStop.empty() {
+		return fmt.Errorf("opcua driver: no estop mapping in node map for %s", d.nodes.ModelID)
+	}
+	return d.run(ctx, d.nodes.EStop, map[string]string{"robot_id": string(d.robotID)})
+}
+
+// UpdateFirmware runs the firmware action and follows the firmware_status node until success or failed.
+func (d *opcuaDriver) UpdateFirmware(ctx context.Context, payload api.FirmwareUpdatePayload, progress func(string)) error {
+	if d.nodes.Firmware.empty() {
+		return fmt.Errorf("opcua driver: no firmware mapping in node map for %s", d.nodes.ModelID)
+	}
+	d.mu.Lock()
+	d.fwStatus = ""
+	d.mu.Unlock()
+	vars := map[string]string{
+		"robot_id":        string(d.robotID),
+		"version":         payload.Version,
+		"download_url":    payload.DownloadURL,
+		"checksum_sha256": payload.ChecksumSHA256,
+		"campaign_id":     payload.CampaignID,
+	}
+	if err := d.run(ctx, d.nodes.Firmware, vars); err != nil {
+		return err
+	}
+	if d.nodes.Telemetry.FirmwareStatus == "" {
+		return nil
+	}
+	reported := ""
+	for {
+		d.mu.Lock()
+		st, ch := d.fwStatus, d.changed
+		d.mu.Unlock()
+		switch st {
+		case api.FirmwareStatusSuccess:
+			return nil
+		case api.FirmwareStatusFailed:
+			return fmt.Errorf("robot reported firmware update failed")
+		case api.FirmwareStatusDownloading, api.FirmwareStatusApplying:
+			if st != reported {
+				progress(st)
+				reported = st
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+		}
+	}
+}
+
+// waitFor blocks until cond (evaluated under d.mu) holds. With timeout > 0 it returns false when the
+// timeout passes first; ctx expiry is an error.
+func (d *opcuaDriver) waitFor(ctx context.Context, timeout time.Duration, cond func() bool) (bool, error) {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		expired = t.C
+	}
+	for {
+		d.mu.Lock()
+		ok, ch := cond(), d.changed
+		d.mu.Unlock()
+		if ok {
+			return true, nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, fmt.Errorf("opcua driver: %w", ctx.Err())
+		case <-expired:
+			return false, nil
+		case <-ch:
+		}
+	}
+}
+
+// run performs an action: the method call first, then the writes in order.
+func (d *opcuaDriver) run(ctx context.Context, a OPCUAAction, vars map[string]string) error {
+	if a.Method != nil {
+		obj, _ := ua.ParseNodeID(a.Method.Object)
+		meth, _ := ua.ParseNodeID(a.Method.Method)
+		args := make([]*ua.Variant, 0, len(a.Method.Args))
+		for i, arg := range a.Method.Args {
+			v, err := opcuaVariant(expand(arg, vars), "")
+			if err != nil {
+				return fmt.Errorf("opcua driver: %s arg %d: %w", a.Method.Method, i, err)
+			}
+			args = append(args, v)
+		}
+		res, err := d.client.Call(ctx, &ua.CallMethodRequest{ObjectID: obj, MethodID: meth, InputArguments: args})
+		if err != nil {
+			return fmt.Errorf("opcua driver: call %s: %w", a.Method.Method, err)
+		}
+		if res.StatusCode != ua.StatusOK {
+			return fmt.Errorf("opcua driver: call %s: %v", a.Method.Method, res.StatusCode)
+		}
+	}
+	if len(a.Writes) == 0 {
+		return nil
+	}
+	req := &ua.WriteRequest{}
+	for _, w := range a.Writes {
+		nid, _ := ua.ParseNodeID(w.Node)
+		v, err := opcuaVariant(expand(w.Value, vars), w.Type)
+		if err != nil {
+			return fmt.Errorf("opcua driver: write %s: %w", w.Node, err)
+		}
+		req.NodesToWrite = append(req.NodesToWrite, &ua.WriteValue{
+			NodeID:      nid,
+			AttributeID: ua.AttributeIDValue,
+			Value:       &ua.DataValue{EncodingMask: ua.DataValueValue, Value: v},
+		})
+	}
+	res, err := d.client.Write(ctx, req)
+	if err != nil {
+		return fmt.Errorf("opcua driver: write: %w", err)
+	}
+	for i, st := range res.Results {
+		if st != ua.StatusOK {
+			return fmt.Errorf("opcua driver: write %s: %v", a.Writes[i].Node, st)
+		}
+	}
+	return nil
+}
+
+func (d *opcuaDriver) Close() error {
+	if d.client == nil {
+		return nil
+	}
+	return d.client.Close(context.Background())
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int16:
+		return float64(n)
+	case uint32:
+		return float64(n)
+	case uint16:
+		return float64(n)
+	case uint8:
+		return float64(n)
+	}
+	return 0
+}
+
+func toBool(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b == "true" || b == "1"
+	}
+	return toFloat(v) != 0
+}
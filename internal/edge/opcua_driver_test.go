@@ -0,0 +1,230 @@
+package edge
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gopcua/opcua/server"
+	"github.com/gopcua/opcua/ua"
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+const opcuaBusyFor = 300 * time.Millisecond
+
+// opcuaRobot is an in-process OPC UA server laid out like configs/opcua/stub-model.yaml. A write of true to
+// Command.Start raises Robot.Busy for opcuaBusyFor; Firmware.Start walks Robot.FirmwareStatus through
+// downloading and applying to success, or to failed for version "bad".
+type opcuaRobot struct {
+	srv      *server.Server
+	ns       *server.MapNamespace
+	endpoint string
+}
+
+func startOPCUARobot(t *testing.T) *opcuaRobot {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	srv := server.New(
+		server.EnableSecurity("None", ua.MessageSecurityModeNone),
+		server.EnableAuthMode(ua.UserTokenTypeAnonymous),
+		server.EndPoint("localhost", port),
+	)
+	ns := server.NewMapNamespace(srv, "RobotFleetOS.Test")
+	for k, v := range map[string]any{
+		"Robot.Battery": 87.5, "Robot.PositionX": 3.0, "Robot.PositionY": 4.0,
+		"Robot.Charging": true, "Robot.Fault": "", "Robot.Busy": false, "Robot.EStop": false,
+		"Robot.FirmwareVersion": "1.0.0", "Robot.FirmwareStatus": api.FirmwareStatusIdle,
+		"Command.ID": "", "Command.Type": "", "Command.Payload": "", "Command.Start": false,
+		"Firmware.Version": "", "Firmware.URL": "", "Firmware.Checksum": "", "Firmware.Start": false,
+	} {
+		ns.Data[k] = v
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := srv.Start(ctx); err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	r := &opcuaRobot{srv: srv, ns: ns, endpoint: "opc.tcp://localhost:" + strconv.Itoa(port)}
+	go r.run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		srv.Close()
+	})
+	return r
+}
+
+func (r *opcuaRobot) run(ctx context.Context) {
+	poll := time.NewTicker(20 * time.Millisecond)
+	defer poll.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.ns.ExternalNotification:
+		case <-poll.C:
+		}
+		if b, _ := r.ns.GetValue("Command.Start").(bool); b {
+			r.ns.SetValue("Command.Start", false)
+			r.ns.SetValue("Robot.Busy", true)
+			time.AfterFunc(opcuaBusyFor, func() { r.ns.SetValue("Robot.Busy", false) })
+		}
+		if b, _ := r.ns.GetValue("Firmware.Start").(bool); b {
+			r.ns.SetValue("Firmware.Start", false)
+			version, _ := r.ns.GetValue("Firmware.Version").(string)
+			go func() {
+				for _, st := range []string{api.FirmwareStatusDownloading, api.FirmwareStatusApplying} {
+					r.ns.SetValue("Robot.FirmwareStatus", st)
+					time.Sleep(150 * time.Millisecond)
+				}
+				if version == "bad" {
+					r.ns.SetValue("Robot.FirmwareStatus", api.FirmwareStatusFailed)
+					return
+				}
+				r.ns.SetValue("Robot.FirmwareVersion", version)
+				r.ns.SetValue("Robot.FirmwareStatus", api.FirmwareStatusSuccess)
+			}()
+		}
+	}
+}
+
+// connectOPCUA connects a driver with the stub-model node map to r and streams its telemetry into the
+// returned channel.
+func connectOPCUA(t *testing.T, r *opcuaRobot) (RobotDriver, <-chan Telemetry) {
+	t.Helper()
+	d, err := NewDriver("opcua", DriverConfig{
+		RobotID: "robot-1",
+		Address: r.endpoint,
+		Options: map[string]string{"node_map": "../../configs/opcua/stub-model.yaml", "publish_interval": "50ms"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	if err := d.Connect(ctx); err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	samples := make(chan Telemetry, 64)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := d.StreamTelemetry(ctx, func(tm Telemetry) {
+			select {
+			case samples <- tm:
+			default:
+			}
+		}); err != nil && ctx.Err() == nil {
+			t.Error(err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		d.Close()
+	})
+	return d, samples
+}
+
+// waitTelemetry returns the first sample cond accepts.
+func waitTelemetry(t *testing.T, samples <-chan Telemetry, cond func(Telemetry) bool) Telemetry {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	var last Telemetry
+	for {
+		select {
+		case tm := <-samples:
+			if cond(tm) {
+				return tm
+			}
+			last = tm
+		case <-timeout:
+			t.Fatalf("no matching telemetry; last %+v", last)
+		}
+	}
+}
+
+func TestOPCUADriverTelemetry(t *testing.T) {
+	r := startOPCUARobot(t)
+	_, samples := connectOPCUA(t, r)
+	want := Telemetry{Position: "3.0,4.0", Charging: true, ModelID: "stub-model", FirmwareVersion: "1.0.0"}
+	waitTelemetry(t, samples, func(tm Telemetry) bool {
+		battery := tm.Battery
+		tm.Battery = nil
+		return battery != nil && *battery == 87.5 && tm == want
+	})
+
+	r.ns.SetValue("Robot.Battery", float64(42))
+	r.ns.SetValue("Robot.Fault", "gripper")
+	waitTelemetry(t, samples, func(tm Telemetry) bool { return tm.Battery != nil && *tm.Battery == 42 && tm.Fault == "gripper" })
+}
+
+func TestOPCUADriverExecute(t *testing.T) {
+	r := startOPCUARobot(t)
+	d, samples := connectOPCUA(t, r)
+	waitTelemetry(t, samples, func(tm Telemetry) bool { return tm.ModelID != "" })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	cmd := api.RobotCommand{ID: "cmd-1", RobotID: "robot-1", Type: "PICK", Payload: []byte(`{"sku":"A"}`)}
+	if err := d.Execute(ctx, cmd); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took < opcuaBusyFor {
+		t.Errorf("Execute returned after %v, before busy fell (%v)", took, opcuaBusyFor)
+	}
+	if b, _ := r.ns.GetValue("Robot.Busy").(bool); b {
+		t.Error("Execute returned while the robot is still busy")
+	}
+	for node, want := range map[string]string{"Command.ID": "cmd-1", "Command.Type": "PICK", "Command.Payload": `{"sku":"A"}`} {
+		if got := r.ns.GetValue(node); got != want {
+			t.Errorf("%s = %v, want %q", node, got, want)
+		}
+	}
+}
+
+func TestOPCUADriverEStop(t *testing.T) {
+	r := startOPCUARobot(t)
+	d, _ := connectOPCUA(t, r)
+	if err := d.EStop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := r.ns.GetValue("Robot.EStop").(bool); !b {
+		t.Error("Robot.EStop not set")
+	}
+}
+
+func TestOPCUADriverUpdateFirmware(t *testing.T) {
+	r := startOPCUARobot(t)
+	d, samples := connectOPCUA(t, r)
+	waitTelemetry(t, samples, func(tm Telemetry) bool { return tm.ModelID != "" })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var progress []string
+	payload := api.FirmwareUpdatePayload{Version: "2.0.0", DownloadURL: "https://example.com/fw.bin", ChecksumSHA256: "abc"}
+	if err := d.UpdateFirmware(ctx, payload, func(st string) { progress = append(progress, st) }); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{api.FirmwareStatusDownloading, api.FirmwareStatusApplying}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+	if got := r.ns.GetValue("Firmware.URL"); got != payload.DownloadURL {
+		t.Errorf("Firmware.URL = %v", got)
+	}
+	waitTelemetry(t, samples, func(tm Telemetry) bool { return tm.FirmwareVersion == "2.0.0" })
+
+	err := d.UpdateFirmware(ctx, api.FirmwareUpdatePayload{Version: "bad"}, func(string) {})
+	if err == nil || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("UpdateFirmware(bad) = %v, want the robot's failure", err)
+	}
+}
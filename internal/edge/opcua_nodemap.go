@@ -0,0 +1,187 @@
+package edge
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gopcua/opcua/ua"
+	"gopkg.in/yaml.v3"
+)
+
+// OPCUANodeMap maps one robot model's OPC UA address space to RobotFleetOS commands and telemetry.
+// Loaded from a per-model YAML file (see configs/opcua/).
+type OPCUANodeMap struct {
+	ModelID   string                 `yaml:"model_id"`
+	Telemetry OPCUATelemetryNodes    `yaml:"telemetry"`
+	Commands  map[string]OPCUAAction `yaml:"commands"` // keyed by RobotCommand.Type ("TASK", "MOVE", ...); "*" = fallback
+	EStop     OPCUAAction            `yaml:"estop"`
+	Firmware  OPCUAAction            `yaml:"firmware"`
+}
+
+// OPCUATelemetryNodes are the monitored items feeding Telemetry. Empty = not available on this model.
+type OPCUATelemetryNodes struct {
+	Battery         string `yaml:"battery"`    // numeric 0-100
+	Position        string `yaml:"position"`   // string, reported as-is
+	PositionX       string `yaml:"position_x"` // numeric; used with position_y when position is not mapped
+	PositionY       string `yaml:"position_y"`
+	Charging        string `yaml:"charging"`         // bool
+	Fault           string `yaml:"fault"`            // string (non-empty = fault) or bool
+	Busy            string `yaml:"busy"`             // bool; commands wait for it to return to false
+	FirmwareVersion string `yaml:"firmware_version"` // string
+	FirmwareStatus  string `yaml:"firmware_status"`  // string: downloading | applying | success | failed
+}
+
+// OPCUAAction is how one command is carried out: an optional method call followed by node writes.
+// String arguments and values may use placeholders: {id}, {type}, {payload}, {robot_id}, and for firmware
+// {version}, {download_url}, {checksum_sha256}, {campaign_id}.
+type OPCUAAction struct {
+	Method *OPCUAMethod `yaml:"method"`
+	Writes []OPCUAWrite `yaml:"writes"`
+}
+
+// OPCUAMethod is a Call on an object node.
+type OPCUAMethod struct {
+	Object string        `yaml:"object"`
+	Method string        `yaml:"method"`
+	Args   []interface{} `yaml:"args"`
+}
+
+// OPCUAWrite writes Value to the node's Value attribute. Type forces the OPC UA data type
+// (bool, int16, int32, int64, uint16, uint32, float, double, string); by default it follows the YAML type.
+type OPCUAWrite struct {
+	Node  string      `yaml:"node"`
+	Value interface{} `yaml:"value"`
+	Type  string      `yaml:"type"`
+}
+
+// LoadOPCUANodeMap reads and validates a node map file.
+func LoadOPCUANodeMap(path string) (*OPCUANodeMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m OPCUANodeMap
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("opcua node map %s: %w", path, err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("opcua node map %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+func (m *OPCUANodeMap) validate() error {
+	for field, id := range m.Telemetry.nodes() {
+		if _, err := ua.ParseNodeID(id); err != nil {
+			return fmt.Errorf("telemetry.%s: %w", field, err)
+		}
+	}
+	check := func(name string, a OPCUAAction) error {
+		if a.Method != nil {
+			if _, err := ua.ParseNodeID(a.Method.Object); err != nil {
+				return fmt.Errorf("%s.method.object: %w", name, err)
+			}
+			if _, err := ua.ParseNodeID(a.Method.Method); err != nil {
+				return fmt.Errorf("%s.method.method: %w", name, err)
+			}
+		}
+		for i, w := range a.Writes {
+			if _, err := ua.ParseNodeID(w.Node); err != nil {
+				return fmt.Errorf("%s.writes[%d].node: %w", name, i, err)
+			}
+		}
+		return nil
+	}
+	for cmdType, a := range m.Commands {
+		if err := check("commands."+cmdType, a); err != nil {
+			return err
+		}
+	}
+	if err := check("estop", m.EStop); err != nil {
+		return err
+	}
+	return check("firmware", m.Firmware)
+}
+
+// nodes returns the mapped telemetry node IDs keyed by field name.
+func (t OPCUATelemetryNodes) nodes() map[string]string {
+	out := make(map[string]string)
+	for name, id := range map[string]string{
+		"battery":          t.Battery,
+		"position":         t.Position,
+		"position_x":       t.PositionX,
+		"position_y":       t.PositionY,
+		"charging":         t.Charging,
+		"fault":            t.Fault,
+		"busy":             t.Busy,
+		"firmware_version": t.FirmwareVersion,
+		"firmware_status":  t.FirmwareStatus,
+	} {
+		if id != "" {
+			out[name] = id
+		}
+	}
+	return out
+}
+
+func (a OPCUAAction) empty() bool {
+	return a.Method == nil && len(a.Writes) == 0
+}
+
+// expand replaces {placeholders} in string values.
+func expand(v interface{}, vars map[string]string) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	for k, val := range vars {
+		s = strings.ReplaceAll(s, "{"+k+"}", val)
+	}
+	return s
+}
+
+// opcuaVariant converts a YAML value (after placeholder expansion) to a Variant of the requested type.
+func opcuaVariant(v interface{}, typ string) (*ua.Variant, error) {
+	switch typ {
+	case "":
+		if n, ok := v.(int); ok {
+			return ua.NewVariant(int32(n))
+		}
+		return ua.NewVariant(v)
+	case "string":
+		return ua.NewVariant(fmt.Sprint(v))
+	case "bool":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("value %v is not a bool", v)
+		}
+		return ua.NewVariant(b)
+	}
+	var f float64
+	switch n := v.(type) {
+	case int:
+		f = float64(n)
+	case float64:
+		f = n
+	default:
+		return nil, fmt.Errorf("value %v is not a number", v)
+	}
+	switch typ {
+	case "int16":
+		return ua.NewVariant(int16(f))
+	case "int32":
+		return ua.NewVariant(int32(f))
+	case "int64":
+		return ua.NewVariant(int64(f))
+	case "uint16":
+		return ua.NewVariant(uint16(f))
+	case "uint32":
+		return ua.NewVariant(uint32(f))
+	case "float":
+		return ua.NewVariant(float32(f))
+	case "double":
+		return ua.NewVariant(f)
+	}
+	return nil, fmt.Errorf("unsupported type %q", typ)
+}
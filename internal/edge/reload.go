@@ -0,0 +1,39 @@
+package edge
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+)
+
+// reloadable are the settings Reload applies to a running gateway or cell; other changes need a restart.
+var reloadable = []string{"edge.status_interval"}
+
+// Reload applies the reloadable settings of next, a validated config read again from the same file, to the
+// running Gateway or Cell r and returns the config now in effect: cur with those settings taken from next.
+// Other changed settings, such as the cell's robots, are logged as needing a restart.
+func Reload(r interface{ SetStatusInterval(time.Duration) }, cur, next *Config) *Config {
+	eff := *cur
+	var applied, restart []string
+	for _, p := range config.Changed(cur, next) {
+		if config.Under(p, reloadable...) {
+			applied = append(applied, p)
+		} else {
+			restart = append(restart, p)
+		}
+	}
+	if cur.Edge.StatusInterval != next.Edge.StatusInterval {
+		d, _ := next.Edge.StatusPeriod()
+		r.SetStatusInterval(d)
+		eff.Edge.StatusInterval = next.Edge.StatusInterval
+	}
+	if len(applied) > 0 {
+		log.Printf("edge: config reloaded: %s", strings.Join(applied, ", "))
+	}
+	if len(restart) > 0 {
+		log.Printf("edge: config: %s changed; restart to apply", strings.Join(restart, ", "))
+	}
+	return &eff
+}
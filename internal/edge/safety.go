@@ -0,0 +1,121 @@
+package edge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// safetyInterlock is the edge-local safety state machine for one robot. It is enforced at the edge so a stop
+// holds even if the zone or the bus is unavailable:
+//
+//	NORMAL ──PROTECTIVE_STOP/STOP──▶ PROTECTIVE_STOP ──ESTOP──▶ ESTOP
+//	NORMAL ──ESTOP──▶ ESTOP
+//	PROTECTIVE_STOP / ESTOP ──RESET──▶ NORMAL
+//
+// While not NORMAL every non-safety command is rejected. Not safe for concurrent use; the owner (Gateway or
+// Simulator) guards it with its own mutex.
+type safetyInterlock struct {
+	robotID api.RobotID
+	zoneID  api.ZoneID
+	state   string
+	reason  string
+	since   time.Time
+}
+
+func newSafetyInterlock(robotID api.RobotID, zoneID api.ZoneID) *safetyInterlock {
+	return &safetyInterlock{robotID: robotID, zoneID: zoneID, state: api.SafetyStateNormal, since: time.Now().UTC()}
+}
+
+func (s *safetyInterlock) stopped() bool {
+	return s.state != api.SafetyStateNormal
+}
+
+// apply handles a safety command and returns the audit event, or nil if the state did not change
+// (repeated stop, protective stop while in ESTOP, reset while NORMAL).
+func (s *safetyInterlock) apply(cmd api.RobotCommand, source string, now time.Time) *api.SafetyEvent {
+	var p api.SafetyPayload
+	if len(cmd.Payload) > 0 {
+		_ = json.Unmarshal(cmd.Payload, &p)
+	}
+	to := s.state
+	switch cmd.Type {
+	case api.RobotCommandTypeEStop:
+		to = api.SafetyStateEStop
+	case api.RobotCommandTypeProtectiveStop, api.RobotCommandTypeStop:
+		if s.state == api.SafetyStateNormal {
+			to = api.SafetyStateProtectiveStop
+		}
+	case api.RobotCommandTypeReset:
+		to = api.SafetyStateNormal
+	}
+	if to == s.state {
+		return nil
+	}
+	ev := s.event(api.SafetyActionTransition, to, source, now)
+	ev.Command, ev.CommandID = cmd.Type, cmd.ID
+	ev.Reason, ev.Operator = p.Reason, p.Operator
+	s.state, s.since = to, now
+	s.reason = ""
+	if to != api.SafetyStateNormal {
+		s.reason = p.Reason
+		if s.reason == "" {
+			s.reason = strings.ToLower(cmd.Type)
+		}
+	}
+	return ev
+}
+
+// robotStop moves to ESTOP when the robot itself reports an e-stop (hardware button, safety PLC).
+func (s *safetyInterlock) robotStop(fault string, now time.Time) *api.SafetyEvent {
+	if s.state == api.SafetyStateEStop {
+		return nil
+	}
+	ev := s.event(api.SafetyActionTransition, api.SafetyStateEStop, api.SafetySourceRobot, now)
+	ev.Reason = "robot reported " + fault
+	s.state, s.since, s.reason = api.SafetyStateEStop, now, ev.Reason
+	return ev
+}
+
+// check returns an error and a rejection event if cmd may not run in the current state.
+func (s *safetyInterlock) check(cmd api.RobotCommand, now time.Time) (*api.SafetyEvent, error) {
+	if !s.stopped() || api.IsSafetyCommand(cmd.Type) {
+		return nil, nil
+	}
+	ev := s.event(api.SafetyActionRejected, s.state, api.SafetySourceCommand, now)
+	ev.Command, ev.CommandID, ev.Reason = cmd.Type, cmd.ID, s.reason
+	return ev, fmt.Errorf("robot %s is in %s (%s): %s %s rejected", s.robotID, s.state, s.reason, cmd.Type, cmd.ID)
+}
+
+func (s *safetyInterlock) event(action, to, source string, now time.Time) *api.SafetyEvent {
+	return &api.SafetyEvent{
+		RobotID: s.robotID,
+		ZoneID:  s.zoneID,
+		Action:  action,
+		From:    s.state,
+		To:      to,
+		Source:  source,
+		At:      now,
+	}
+}
+
+// isEStopFault reports whether a robot-reported fault is an emergency stop.
+func isEStopFault(fault string) bool {
+	f := strings.ToLower(fault)
+	return f == "estop" || f == "e-stop" || f == "emergency_stop"
+}
+
+// commandTargets reports whether cmd is addressed to robotID in zoneID, either directly or as a zone-wide
+// safety broadcast.
+func commandTargets(cmd api.RobotCommand, robotID api.RobotID, zoneID api.ZoneID) (ok bool, broadcast bool) {
+	if cmd.RobotID == robotID {
+		return true, false
+	}
+	if cmd.RobotID == "" && cmd.ZoneID == zoneID && api.IsSafetyCommand(cmd.Type) {
+		return true, true
+	}
+	return false, false
+}
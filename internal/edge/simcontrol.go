@@ -0,0 +1,178 @@
+package edge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+)
+
+// Fault kinds for Simulator.InjectFault.
+const (
+	FaultKindError        = "error"         // ERROR for the given duration, aborting the task
+	FaultKindBattery      = "battery"       // battery drops to 10% or less
+	FaultKindDropout      = "dropout"       // no status for the given duration
+	FaultKindFirmwareFail = "firmware_fail" // the next firmware apply fails
+	FaultKindClear        = "clear"         // clear ERROR, dropout and a pending firmware failure
+)
+
+// Faults returns the current fault injection settings.
+func (s *Simulator) Faults() SimFaults {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sim.Faults
+}
+
+// SetFaults replaces the fault injection settings at runtime.
+func (s *Simulator) SetFaults(f SimFaults) {
+	f.setDefaults()
+	s.mu.Lock()
+	s.sim.Faults = f
+	s.mu.Unlock()
+}
+
+// InjectFault injects one fault into one robot now. d is the ERROR or dropout length; 0 uses the configured default.
+func (s *Simulator) InjectFault(robotID api.RobotID, kind string, d time.Duration) error {
+	defer s.flushResults(context.Background())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.state[robotID]
+	if st == nil {
+		return fmt.Errorf("unknown robot %s", robotID)
+	}
+	now := s.clock.Now()
+	s.stepLocked(st, now)
+	switch kind {
+	case FaultKindError:
+		if d <= 0 {
+			d = s.sim.Faults.ErrorDuration
+		}
+		s.setErrorLocked(st, now, d)
+	case FaultKindBattery:
+		s.setBatteryFaultLocked(st)
+	case FaultKindDropout:
+		if d <= 0 {
+			d = s.sim.Faults.DropoutDuration
+		}
+		st.silentUntil = now.Add(d)
+	case FaultKindFirmwareFail:
+		st.failNextFirmware = true
+	case FaultKindClear:
+		if st.state == "ERROR" {
+			st.state = "IDLE"
+		}
+		st.fault = ""
+		st.silentUntil = time.Time{}
+		st.failNextFirmware = false
+	default:
+		return fmt.Errorf("unknown fault kind %q", kind)
+	}
+	return nil
+}
+
+// InjectFaultRequest is the JSON body for POST /sim/faults/inject.
+type InjectFaultRequest struct {
+	RobotID  string `json:"robot_id"`
+	Fault    string `json:"fault"`              // error | battery | dropout | firmware_fail | clear
+	Duration string `json:"duration,omitempty"` // for error and dropout, e.g. "30s"
+}
+
+// ControlHandler serves the runtime fault injection API:
+//
+//	GET  /sim/faults         current settings
+//	PUT  /sim/faults         update settings (fields omitted keep their value)
+//	POST /sim/faults/inject  inject one fault into one robot
+//
+// Changes are recorded in auditLog, if set.
+func (s *Simulator) ControlHandler(auditLog *audit.Log) http.Handler {
+	return ShardedControlHandler([]*Simulator{s}, auditLog)
+}
+
+// ShardedControlHandler serves the same API for several simulators, e.g. one per zone. Settings are read
+// from the first shard and written to all of them; an injected fault goes to the shard that owns the robot.
+func ShardedControlHandler(shards []*Simulator, auditLog *audit.Log) http.Handler {
+	c := &simControl{shards: shards, audit: auditLog}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sim/faults", c.handleFaults)
+	mux.HandleFunc("/sim/faults/inject", c.handleInjectFault)
+	return auditLog.Wrap(mux)
+}
+
+type simControl struct {
+	shards []*Simulator
+	audit  *audit.Log
+}
+
+func (c *simControl) owner(robotID api.RobotID) *Simulator {
+	for _, s := range c.shards {
+		s.mu.RLock()
+		_, ok := s.state[robotID]
+		s.mu.RUnlock()
+		if ok {
+			return s
+		}
+	}
+	return nil
+}
+
+func (c *simControl) handleFaults(w http.ResponseWriter, r *http.Request) {
+	if len(c.shards) == 0 {
+		http.Error(w, "no simulators", http.StatusServiceUnavailable)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		before := c.shards[0].Faults()
+		f := before
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+			http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, s := range c.shards {
+			s.SetFaults(f)
+		}
+		c.audit.Record(r, "sim.set_faults", "sim_faults", "", before, c.shards[0].Faults())
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.shards[0].Faults())
+}
+
+func (c *simControl) handleInjectFault(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req InjectFaultRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var d time.Duration
+	if req.Duration != "" {
+		var err error
+		if d, err = time.ParseDuration(req.Duration); err != nil {
+			http.Error(w, "invalid duration: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	s := c.owner(api.RobotID(req.RobotID))
+	if s == nil {
+		http.Error(w, fmt.Sprintf("unknown robot %s", req.RobotID), http.StatusBadRequest)
+		return
+	}
+	if err := s.InjectFault(api.RobotID(req.RobotID), req.Fault, d); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.audit.Record(r, "sim.inject_fault", "robot", req.RobotID, nil, req)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "robot_id": req.RobotID, "fault": req.Fault})
+}
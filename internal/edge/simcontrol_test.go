@@ -0,0 +1,46 @@
+package edge
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+func TestControlHandlerAudit(t *testing.T) {
+	bus := messaging.NewMemoryBus()
+	sim := NewSimulator("zone-1", []api.RobotID{"robot-1"}, messaging.NewRobotStatusPublisher(bus), bus, time.Second, time.Minute)
+	auditLog, err := audit.Open("fleet", audit.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sim.ControlHandler(auditLog)
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodGet, "/sim/faults", ""},
+		{http.MethodPut, "/sim/faults", `{"firmware_fail_rate":0.2}`},
+		{http.MethodPost, "/sim/faults/inject", `{"robot_id":"robot-1","fault":"dropout","duration":"45s"}`},
+		{http.MethodPost, "/sim/faults/inject", `{"robot_id":"robot-9","fault":"dropout"}`},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(req.method, req.path, strings.NewReader(req.body)))
+		if rec.Header().Get(audit.RequestIDHeader) == "" {
+			t.Errorf("%s %s: no request ID", req.method, req.path)
+		}
+	}
+
+	entries := auditLog.Query(audit.Filter{})
+	if len(entries) != 2 {
+		t.Fatalf("%d audit entries, want 2: %+v", len(entries), entries)
+	}
+	if e := entries[0]; e.Action != "sim.inject_fault" || e.EntityID != "robot-1" || !strings.Contains(string(e.After), "dropout") {
+		t.Errorf("inject entry %+v", e)
+	}
+	if e := entries[1]; e.Action != "sim.set_faults" || !strings.Contains(string(e.After), `"firmware_fail_rate":0.2`) || strings.Contains(string(e.Before), `"firmware_fail_rate":0.2`) {
+		t.Errorf("set faults entry %+v before %s after %s", e, e.Before, e.After)
+	}
+}
@@ -0,0 +1,341 @@
+package edge
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"gopkg.in/yaml.v3"
+)
+
+// SimConfig is the physical model of the Simulator: per-model speed and battery use, chargers, and the
+// seed for randomized durations. The same seed and command sequence give the same run. Loaded from YAML
+// (see configs/sim/default.yaml); zero fields take defaults.
+type SimConfig struct {
+	Seed        int64                `yaml:"seed"`
+	Jitter      float64              `yaml:"jitter"`       // ± fraction applied to handling times and task durations (default 0.2)
+	ChargeBelow float64              `yaml:"charge_below"` // idle robots below this battery % drive to a charger (default 20)
+	ChargeUntil float64              `yaml:"charge_until"` // charging ends at this battery % (default 95)
+	Chargers    []api.Point          `yaml:"chargers"`     // default: one charger at 0,0
+	Models      []SimModel           `yaml:"models"`       // default: one stub-model
+	RobotModels map[string]string    `yaml:"robot_models"` // robot_id -> model_id; other robots cycle through models
+	Locations   map[string]api.Point `yaml:"locations"`    // location_id -> coordinates for payloads without from/to
+	Faults      SimFaults            `yaml:"faults"`       // fault injection; zero = robots never fail
+}
+
+// SimModel is the physical profile of one robot model.
+type SimModel struct {
+	ModelID          string        `yaml:"model_id"`
+	SpeedMPS         float64       `yaml:"speed_mps"`           // travel speed (default 1.5)
+	DrainPerKm       float64       `yaml:"drain_per_km"`        // battery % per km driven (default 4)
+	DrainIdlePerHour float64       `yaml:"drain_idle_per_hour"` // battery % per hour while idle (default 2)
+	DrainBusyPerHour float64       `yaml:"drain_busy_per_hour"` // battery % per hour on a task, on top of driving (default 10)
+	ChargePerHour    float64       `yaml:"charge_per_hour"`     // battery % per hour on a charger (default 50)
+	HandlingTime     time.Duration `yaml:"handling_time"`       // per pick or drop stop (default 20s)
+}
+
+// DefaultSimConfig returns the model used when no config is given.
+func DefaultSimConfig() *SimConfig {
+	cfg := &SimConfig{Seed: 1}
+	cfg.setDefaults()
+	return cfg
+}
+
+// LoadSimConfig reads a simulator config file.
+func LoadSimConfig(path string) (*SimConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg SimConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("sim config %s: %w", path, err)
+	}
+	cfg.setDefaults()
+	if cfg.ChargeUntil <= cfg.ChargeBelow {
+		return nil, fmt.Errorf("sim config %s: charge_until must be above charge_below", path)
+	}
+	for robotID, modelID := range cfg.RobotModels {
+		if cfg.model(modelID) == nil {
+			return nil, fmt.Errorf("sim config %s: robot %s uses unknown model %q", path, robotID, modelID)
+		}
+	}
+	return &cfg, nil
+}
+
+// SimFaults injects failures for resilience testing. Rates per hour are per robot; the chance of a fault in
+// a status interval is 1-exp(-rate*interval). Safety commands are never delayed or duplicated.
+type SimFaults struct {
+	ErrorRatePerHour        float64       `yaml:"error_rate_per_hour"`         // robot enters ERROR and aborts its task
+	ErrorDuration           time.Duration `yaml:"error_duration"`              // ERROR clears after this (default 1m)
+	FirmwareFailRate        float64       `yaml:"firmware_fail_rate"`          // fraction of firmware applies that fail
+	DropoutRatePerHour      float64       `yaml:"dropout_rate_per_hour"`       // robot stops publishing status
+	DropoutDuration         time.Duration `yaml:"dropout_duration"`            // heartbeat dropout length (default 30s)
+	HeartbeatLossRate       float64       `yaml:"heartbeat_loss_rate"`         // fraction of single status messages dropped
+	CommandDelay            time.Duration `yaml:"command_delay"`               // commands are handled after a random delay up to this
+	CommandDuplicateRate    float64       `yaml:"command_duplicate_rate"`      // fraction of commands handled twice
+	BatteryFaultRatePerHour float64       `yaml:"battery_fault_rate_per_hour"` // battery suddenly drops to 10% or less
+}
+
+// simFaultsJSON is SimFaults with durations as strings ("30s") for the control API.
+type simFaultsJSON struct {
+	ErrorRatePerHour        float64 `json:"error_rate_per_hour"`
+	ErrorDuration           string  `json:"error_duration,omitempty"`
+	FirmwareFailRate        float64 `json:"firmware_fail_rate"`
+	DropoutRatePerHour      float64 `json:"dropout_rate_per_hour"`
+	DropoutDuration         string  `json:"dropout_duration,omitempty"`
+	HeartbeatLossRate       float64 `json:"heartbeat_loss_rate"`
+	CommandDelay            string  `json:"command_delay,omitempty"`
+	CommandDuplicateRate    float64 `json:"command_duplicate_rate"`
+	BatteryFaultRatePerHour float64 `json:"battery_fault_rate_per_hour"`
+}
+
+// MarshalJSON encodes durations as strings.
+func (f SimFaults) MarshalJSON() ([]byte, error) {
+	return json.Marshal(simFaultsJSON{
+		ErrorRatePerHour:        f.ErrorRatePerHour,
+		ErrorDuration:           f.ErrorDuration.String(),
+		FirmwareFailRate:        f.FirmwareFailRate,
+		DropoutRatePerHour:      f.DropoutRatePerHour,
+		DropoutDuration:         f.DropoutDuration.String(),
+		HeartbeatLossRate:       f.HeartbeatLossRate,
+		CommandDelay:            f.CommandDelay.String(),
+		CommandDuplicateRate:    f.CommandDuplicateRate,
+		BatteryFaultRatePerHour: f.BatteryFaultRatePerHour,
+	})
+}
+
+// UnmarshalJSON decodes onto f, so fields missing from data keep their current value.
+func (f *SimFaults) UnmarshalJSON(data []byte) error {
+	j := simFaultsJSON{
+		ErrorRatePerHour:        f.ErrorRatePerHour,
+		FirmwareFailRate:        f.FirmwareFailRate,
+		DropoutRatePerHour:      f.DropoutRatePerHour,
+		HeartbeatLossRate:       f.HeartbeatLossRate,
+		CommandDuplicateRate:    f.CommandDuplicateRate,
+		BatteryFaultRatePerHour: f.BatteryFaultRatePerHour,
+	}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	out := SimFaults{
+		ErrorRatePerHour:        j.ErrorRatePerHour,
+		ErrorDuration:           f.ErrorDuration,
+		FirmwareFailRate:        j.FirmwareFailRate,
+		DropoutRatePerHour:      j.DropoutRatePerHour,
+		DropoutDuration:         f.DropoutDuration,
+		HeartbeatLossRate:       j.HeartbeatLossRate,
+		CommandDelay:            f.CommandDelay,
+		CommandDuplicateRate:    j.CommandDuplicateRate,
+		BatteryFaultRatePerHour: j.BatteryFaultRatePerHour,
+	}
+	for _, d := range []struct {
+		s   string
+		out *time.Duration
+	}{{j.ErrorDuration, &out.ErrorDuration}, {j.DropoutDuration, &out.DropoutDuration}, {j.CommandDelay, &out.CommandDelay}} {
+		if d.s == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.s)
+		if err != nil {
+			return err
+		}
+		*d.out = v
+	}
+	*f = out
+	return nil
+}
+
+func (f *SimFaults) setDefaults() {
+	if f.ErrorDuration <= 0 {
+		f.ErrorDuration = time.Minute
+	}
+	if f.DropoutDuration <= 0 {
+		f.DropoutDuration = 30 * time.Second
+	}
+}
+
+// chance reports whether an event with the given rate per hour happens within elapsed.
+func chance(rng *rand.Rand, ratePerHour float64, elapsed time.Duration) bool {
+	if ratePerHour <= 0 || elapsed <= 0 {
+		return false
+	}
+	return rng.Float64() < 1-math.Exp(-ratePerHour*elapsed.Hours())
+}
+
+func (c *SimConfig) setDefaults() {
+	if c.Jitter <= 0 {
+		c.Jitter = 0.2
+	}
+	if c.ChargeBelow <= 0 {
+		c.ChargeBelow = 20
+	}
+	if c.ChargeUntil <= 0 {
+		c.ChargeUntil = 95
+	}
+	c.Faults.setDefaults()
+	if len(c.Chargers) == 0 {
+		c.Chargers = []api.Point{{}}
+	}
+	if len(c.Models) == 0 {
+		c.Models = []SimModel{{ModelID: "stub-model"}}
+	}
+	for i := range c.Models {
+		m := &c.Models[i]
+		if m.ModelID == "" {
+			m.ModelID = fmt.Sprintf("model-%d", i+1)
+		}
+		if m.SpeedMPS <= 0 {
+			m.SpeedMPS = 1.5
+		}
+		if m.DrainPerKm <= 0 {
+			m.DrainPerKm = 4
+		}
+		if m.DrainIdlePerHour <= 0 {
+			m.DrainIdlePerHour = 2
+		}
+		if m.DrainBusyPerHour <= 0 {
+			m.DrainBusyPerHour = 10
+		}
+		if m.ChargePerHour <= 0 {
+			m.ChargePerHour = 50
+		}
+		if m.HandlingTime <= 0 {
+			m.HandlingTime = 20 * time.Second
+		}
+	}
+}
+
+func (c *SimConfig) model(id string) *SimModel {
+	for i := range c.Models {
+		if c.Models[i].ModelID == id {
+			return &c.Models[i]
+		}
+	}
+	return nil
+}
+
+// modelFor returns robot i's model: its robot_models entry, otherwise models in turn.
+func (c *SimConfig) modelFor(robotID api.RobotID, i int) *SimModel {
+	if m := c.model(c.RobotModels[string(robotID)]); m != nil {
+		return m
+	}
+	return &c.Models[i%len(c.Models)]
+}
+
+// rng returns the robot's random source. It is seeded from the config seed and the robot ID so a robot's
+// draws do not depend on how commands to other robots interleave.
+func (c *SimConfig) rng(robotID api.RobotID) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(robotID))
+	return rand.New(rand.NewSource(c.Seed ^ int64(h.Sum64())))
+}
+
+// jitter scales d by a random factor in [1-Jitter, 1+Jitter].
+func (c *SimConfig) jitter(rng *rand.Rand, d time.Duration) time.Duration {
+	return time.Duration(float64(d) * (1 + c.Jitter*(2*rng.Float64()-1)))
+}
+
+func (c *SimConfig) nearestCharger(p api.Point) api.Point {
+	best := c.Chargers[0]
+	for _, ch := range c.Chargers[1:] {
+		if dist(p, ch) < dist(p, best) {
+			best = ch
+		}
+	}
+	return best
+}
+
+// taskStops returns the pick and drop points of a task payload: explicit from/to coordinates, otherwise
+// from_location_id/to_location_id looked up in Locations. Payloads without locations have no stops.
+func (c *SimConfig) taskStops(payload []byte) []api.Point {
+	var loc api.TaskLocations
+	if len(payload) == 0 || json.Unmarshal(payload, &loc) != nil {
+		return nil
+	}
+	var stops []api.Point
+	for _, s := range []struct {
+		p  *api.Point
+		id string
+	}{{loc.From, loc.FromLocationID}, {loc.To, loc.ToLocationID}} {
+		if s.p != nil {
+			stops = append(stops, *s.p)
+		} else if p, ok := c.Locations[s.id]; ok && s.id != "" {
+			stops = append(stops, p)
+		}
+	}
+	return stops
+}
+
+// simLeg drives from one point to another, then dwells (pick, drop).
+type simLeg struct {
+	from, to api.Point
+	travel   time.Duration
+	dwell    time.Duration
+}
+
+// simPlan is a timed route: where a robot is at any moment of a task or a trip to a charger.
+type simPlan struct {
+	start time.Time
+	legs  []simLeg
+	odo   float64 // metres of this plan already accounted for in battery drain
+}
+
+func newSimPlan(start time.Time, from api.Point, stops []api.Point, speed float64, dwell func() time.Duration) *simPlan {
+	p := &simPlan{start: start}
+	for _, to := range stops {
+		p.legs = append(p.legs, simLeg{
+			from:   from,
+			to:     to,
+			travel: time.Duration(dist(from, to) / speed * float64(time.Second)),
+			dwell:  dwell(),
+		})
+		from = to
+	}
+	return p
+}
+
+func (p *simPlan) total() time.Duration {
+	var d time.Duration
+	for _, l := range p.legs {
+		d += l.travel + l.dwell
+	}
+	return d
+}
+
+func (p *simPlan) done(now time.Time) bool {
+	return !now.Before(p.start.Add(p.total()))
+}
+
+// at returns the position at now and the metres driven since the plan started.
+func (p *simPlan) at(now time.Time) (api.Point, float64) {
+	elapsed := now.Sub(p.start)
+	var odo float64
+	pos := api.Point{}
+	if len(p.legs) > 0 {
+		pos = p.legs[0].from
+	}
+	for _, l := range p.legs {
+		d := dist(l.from, l.to)
+		if elapsed < l.travel {
+			f := float64(elapsed) / float64(l.travel)
+			return api.Point{X: l.from.X + f*(l.to.X-l.from.X), Y: l.from.Y + f*(l.to.Y-l.from.Y)}, odo + f*d
+		}
+		odo += d
+		pos = l.to
+		elapsed -= l.travel + l.dwell
+		if elapsed < 0 {
+			break
+		}
+	}
+	return pos, odo
+}
+
+func dist(a, b api.Point) float64 {
+	return math.Hypot(b.X-a.X, b.Y-a.Y)
+}
@@ -0,0 +1,194 @@
+package edge
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// simRoute is a zone-planned route a simulated robot is following. The robot holds the node it stands on
+// and acquires the next one before driving to it, so robots on routes never share a node: a robot whose
+// next node is held waits for it, which is how deadlocks show up in the simulation.
+type simRoute struct {
+	cmd          api.RobotCommand
+	waypoints    []api.RouteWaypoint
+	next         int    // waypoint to drive to next
+	reached      int    // last waypoint reached; -1 before the first
+	gen          uint64 // bumped per scheduled step, so superseded timers do nothing
+	busy         bool   // driving to a waypoint or handling a load there
+	reroute      []api.RouteWaypoint
+	waitingFor   string // node held by another robot
+	waitingSince time.Time
+}
+
+// taskRoute returns the waypoints of a TASK, MOVE or REROUTE payload, or nil if it has none.
+func taskRoute(payload []byte) []api.RouteWaypoint {
+	var p struct {
+		Route *api.TaskRoute `json:"route"`
+	}
+	if len(payload) == 0 || json.Unmarshal(payload, &p) != nil || p.Route == nil {
+		return nil
+	}
+	return p.Route.Waypoints
+}
+
+// startRouteLocked starts a task that carries a route. Caller must hold s.mu.
+func (s *Simulator) startRouteLocked(st *robotSimState, cmd api.RobotCommand, waypoints []api.RouteWaypoint, now time.Time) {
+	st.state = "BUSY"
+	st.taskSeq++
+	st.plan = &simPlan{start: now, legs: []simLeg{{from: st.pos, to: st.pos}}}
+	st.route = &simRoute{cmd: cmd, waypoints: waypoints, reached: -1}
+	st.routeTask, st.routeIndex = cmd.ID, -1
+	s.advanceRouteLocked(st, st.route, now)
+}
+
+// advanceRouteLocked moves the robot on to its next waypoint once the current one's departure time has
+// come and the next node is free, and finishes the task after the last one. It reports whether the task
+// finished. Caller must hold s.mu.
+func (s *Simulator) advanceRouteLocked(st *robotSimState, r *simRoute, now time.Time) bool {
+	r.busy = false
+	if r.reroute != nil {
+		s.applyRerouteLocked(st, r)
+	}
+	if r.next >= len(r.waypoints) {
+		s.finishTaskLocked(st, r.cmd, now)
+		return true
+	}
+	if r.reached >= 0 {
+		if depart := r.waypoints[r.reached].Depart; now.Before(depart) {
+			s.routeAfter(st, r, depart.Sub(now), s.advanceRouteLocked)
+			return false
+		}
+	}
+	wp := r.waypoints[r.next]
+	if holder, held := s.occupied[wp.NodeID]; held && holder != st.id {
+		if r.waitingFor != wp.NodeID {
+			r.waitingFor, r.waitingSince = wp.NodeID, now
+		}
+		s.routeAfter(st, r, time.Second, s.advanceRouteLocked)
+		return false
+	}
+	r.waitingFor = ""
+	s.occupied[wp.NodeID] = st.id
+	s.stepLocked(st, now)
+	speed := st.model.SpeedMPS
+	if wp.MaxSpeed > 0 && wp.MaxSpeed < speed {
+		speed = wp.MaxSpeed
+	}
+	to := wp.Point()
+	travel := time.Duration(dist(st.pos, to) / speed * float64(time.Second))
+	st.plan.extend(now, st.pos, simLeg{from: st.pos, to: to, travel: travel})
+	r.busy = true
+	s.routeAfter(st, r, travel, s.arriveLocked)
+	return false
+}
+
+// arriveLocked records the arrival at the next waypoint, releases the node the robot came from and
+// handles the load at stops. Caller must hold s.mu.
+func (s *Simulator) arriveLocked(st *robotSimState, r *simRoute, now time.Time) bool {
+	wp := r.waypoints[r.next]
+	s.stepLocked(st, now)
+	s.releaseNodesLocked(st, wp.NodeID)
+	st.node = wp.NodeID
+	r.reached, r.next = r.next, r.next+1
+	st.routeIndex = r.reached
+	if wp.Stop && r.cmd.Type != api.RobotCommandTypeMove {
+		dwell := s.sim.jitter(st.rng, st.model.HandlingTime)
+		st.plan.extend(now, st.pos, simLeg{from: st.pos, to: st.pos, dwell: dwell})
+		s.routeAfter(st, r, dwell, s.advanceRouteLocked)
+		return false
+	}
+	return s.advanceRouteLocked(st, r, now)
+}
+
+// handleReroute replaces the rest of the route of the robot's current task. A robot driving or handling a
+// load switches at its next waypoint.
+func (s *Simulator) handleReroute(cmd api.RobotCommand) {
+	waypoints := taskRoute(cmd.Payload)
+	if len(waypoints) == 0 {
+		return
+	}
+	s.mu.Lock()
+	st := s.state[cmd.RobotID]
+	if st == nil || st.route == nil || st.route.cmd.ID != cmd.ID {
+		s.mu.Unlock()
+		return
+	}
+	r := st.route
+	r.reroute = waypoints
+	finished := false
+	if !r.busy {
+		finished = s.advanceRouteLocked(st, r, s.clock.Now())
+	}
+	s.mu.Unlock()
+	if finished {
+		s.tryApplyFirmware(cmd.RobotID)
+	}
+}
+
+// applyRerouteLocked switches to the new waypoints, continuing after the node the robot holds if the new
+// route passes it. Caller must hold s.mu.
+func (s *Simulator) applyRerouteLocked(st *robotSimState, r *simRoute) {
+	r.waypoints, r.reroute = r.reroute, nil
+	r.next, r.reached = 0, -1
+	for i, wp := range r.waypoints {
+		if wp.NodeID == st.node {
+			r.next, r.reached = i+1, i
+			break
+		}
+	}
+	st.routeIndex = r.reached
+}
+
+// routeAfter runs step for the robot's route after d unless the route ended or another step was scheduled
+// in the meantime. Firmware waiting for the robot is tried once the task finishes.
+func (s *Simulator) routeAfter(st *robotSimState, r *simRoute, d time.Duration, step func(*robotSimState, *simRoute, time.Time) bool) {
+	r.gen++
+	gen, robotID := r.gen, st.id
+	s.clock.AfterFunc(d, func() {
+		s.mu.Lock()
+		st := s.state[robotID]
+		if st == nil || st.route != r || r.gen != gen {
+			s.mu.Unlock()
+			return
+		}
+		finished := step(st, r, s.clock.Now())
+		s.mu.Unlock()
+		if finished {
+			s.flushResults(context.Background())
+			s.tryApplyFirmware(robotID)
+		}
+	})
+}
+
+// releaseNodesLocked frees the nodes the robot holds except keep. Caller must hold s.mu.
+func (s *Simulator) releaseNodesLocked(st *robotSimState, keep string) {
+	for node, holder := range s.occupied {
+		if holder == st.id && node != keep {
+			delete(s.occupied, node)
+		}
+	}
+	if st.node != keep {
+		st.node = ""
+	}
+}
+
+// extend appends leg to the plan at now, standing at pos for any gap since the plan's last leg ended.
+func (p *simPlan) extend(now time.Time, pos api.Point, leg simLeg) {
+	if gap := now.Sub(p.start.Add(p.total())); gap > 0 {
+		p.legs = append(p.legs, simLeg{from: pos, to: pos, dwell: gap})
+	}
+	p.legs = append(p.legs, leg)
+}
+
+// abortRouteLocked drops the robot's route when its task is aborted. The robot stops where it is and keeps
+// only the node it last reached. Caller must hold s.mu.
+func (s *Simulator) abortRouteLocked(st *robotSimState) {
+	if st.route == nil {
+		return
+	}
+	st.route = nil
+	s.releaseNodesLocked(st, st.node)
+}
@@ -3,32 +3,71 @@ package edge
 import (
 	"context"
 	"encoding/json"
+	"math"
+	"math/rand"
 	"sync"
 	"time"
 
 	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/clock"
+	"github.com/robotfleetos/robotfleetos/pkg/cmdsign"
 	"github.com/robotfleetos/robotfleetos/pkg/messaging"
 )
 
 // Simulator simulates many robots in one process: each defers firmware when BUSY and applies when IDLE.
+// Robots drive between task locations at their model's speed, drain and recharge their battery, charge
+// where CHARGE commands send them and, as a fallback, return to the nearest charger when low (see
+// SimConfig).
 type Simulator struct {
-	zoneID        api.ZoneID
-	robots        []api.RobotID
-	statusPub     *messaging.RobotStatusPublisher
-	bus           messaging.Subscriber
+	zoneID         api.ZoneID
+	robots         []api.RobotID
+	statusPub      *messaging.RobotStatusPublisher
+	bus            messaging.Subscriber
 	statusInterval time.Duration
 	taskDuration   time.Duration
+	safetyPub      *messaging.SafetyEventPublisher
+	resultPub      *messaging.TaskResultPublisher
+	verifier       *cmdsign.Verifier
+	sim            *SimConfig
+	clock          clock.Clock
 
-	mu     sync.RWMutex
-	state  map[api.RobotID]*robotSimState
+	mu       sync.RWMutex
+	state    map[api.RobotID]*robotSimState
+	occupied map[string]api.RobotID // facility map node -> robot holding it, for robots on zone routes
+	results  []api.TaskResult       // task ends not yet published; see flushResults
 }
 
 type robotSimState struct {
-	state               string
-	modelID             string
-	firmwareVersion     string
+	id                   api.RobotID
+	state                string
+	battery              float64
+	modelID              string
+	firmwareVersion      string
 	firmwareUpdateStatus string
-	pendingFirmware     *api.RobotCommand
+	firmwareDeferReason  string
+	firmwareCommandID    string // FIRMWARE_UPDATE command the update status belongs to
+	pendingFirmware      *firmwarePlan
+	safety               *safetyInterlock
+	taskSeq              uint64 // bumped per task and on stop, so an aborted task does not finish later
+	model                *SimModel
+	rng                  *rand.Rand
+	pos                  api.Point
+	plan                 *simPlan // current task or trip to a charger; nil when standing still
+	lastStep             time.Time
+	task                 *api.RobotCommand  // task being run; nil while idle or charging
+	queue                []api.RobotCommand // tasks received while busy or charging, run in order
+	odometer             float64
+	tasksCompleted       int
+	fault                string    // injected fault reported in Extra[ExtraFault]
+	errorUntil           time.Time // ERROR clears at this time
+	silentUntil          time.Time // heartbeat dropout: no status until this time
+	failNextFirmware     bool
+	route                *simRoute  // zone-planned route of the current task
+	node                 string     // map node held: the last route waypoint reached
+	routeTask            api.TaskID // task of the current or last route
+	routeIndex           int        // last waypoint of that route reached
+	chargeUntil          float64    // battery % ending the current charge; 0 = SimConfig.ChargeUntil
+	chargeInterruptible  bool       // opportunistic charge: a new task ends it
 }
 
 // NewSimulator creates a simulator for the given robot IDs in the zone.
@@ -40,41 +79,105 @@ func NewSimulator(
 	statusInterval time.Duration,
 	taskDuration time.Duration,
 ) *Simulator {
-	state := make(map[api.RobotID]*robotSimState, len(robots))
-	for _, r := range robots {
-		state[r] = &robotSimState{
-			state:               "IDLE",
-			modelID:             "stub-model",
-			firmwareVersion:     "1.0.0",
-			firmwareUpdateStatus: api.FirmwareStatusIdle,
-		}
-	}
-	return &Simulator{
+	s := &Simulator{
 		zoneID:         zoneID,
 		robots:         robots,
 		statusPub:      statusPub,
 		bus:            bus,
 		statusInterval: statusInterval,
 		taskDuration:   taskDuration,
-		state:          state,
+		clock:          clock.Real,
+	}
+	s.SetSimConfig(DefaultSimConfig())
+	return s
+}
+
+// SetSimConfig replaces the physical model and resets every robot: model, seeded random source, a starting
+// battery between 70 and 100% and a position on a charger. Call before Run.
+func (s *Simulator) SetSimConfig(cfg *SimConfig) {
+	now := s.clock.Now()
+	state := make(map[api.RobotID]*robotSimState, len(s.robots))
+	for i, r := range s.robots {
+		model := cfg.modelFor(r, i)
+		rng := cfg.rng(r)
+		state[r] = &robotSimState{
+			id:                   r,
+			state:                "IDLE",
+			battery:              70 + 30*rng.Float64(),
+			modelID:              model.ModelID,
+			firmwareVersion:      "1.0.0",
+			firmwareUpdateStatus: api.FirmwareStatusIdle,
+			safety:               newSafetyInterlock(r, s.zoneID),
+			model:                model,
+			rng:                  rng,
+			pos:                  cfg.Chargers[i%len(cfg.Chargers)],
+			lastStep:             now,
+		}
+	}
+	s.mu.Lock()
+	s.sim = cfg
+	s.state = state
+	s.occupied = make(map[string]api.RobotID)
+	s.mu.Unlock()
+}
+
+// SetSafetyEventPublisher sets where safety transitions and rejected commands are published for auditing.
+func (s *Simulator) SetSafetyEventPublisher(p *messaging.SafetyEventPublisher) {
+	s.safetyPub = p
+}
+
+// SetTaskResultPublisher sets where the end of each task is published for the zone.
+func (s *Simulator) SetTaskResultPublisher(p *messaging.TaskResultPublisher) {
+	s.resultPub = p
+}
+
+// SetCommandVerifier makes the simulator check each command's zone signature, freshness and uniqueness
+// before running it, as a real edge does (see cmdsign.Verifier.Admit).
+func (s *Simulator) SetCommandVerifier(v *cmdsign.Verifier) {
+	s.verifier = v
+}
+
+// SetClock sets the time source (default clock.Real). With a clock.Virtual every task, charge, firmware
+// step and fault runs as a scheduled event. Call before Start or Run.
+func (s *Simulator) SetClock(clk clock.Clock) {
+	now := clk.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = clk
+	for _, st := range s.state {
+		st.lastStep = now
 	}
 }
 
 // Run subscribes to commands and publishes status for all robots. Blocks until ctx is done.
 func (s *Simulator) Run(ctx context.Context) error {
-	if err := s.bus.Subscribe(ctx, messaging.TopicRobotCommands, s.handleCommand); err != nil {
+	if err := s.Start(ctx); err != nil {
 		return err
 	}
-	ticker := time.NewTicker(s.statusInterval)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		case <-ticker.C:
-			s.publishAllStatus(ctx)
-		}
+	<-ctx.Done()
+	return nil
+}
+
+// Start subscribes to commands and schedules the status interval on the simulator's clock, then returns.
+// The interval stops when ctx is done.
+func (s *Simulator) Start(ctx context.Context) error {
+	// Commands are keyed by robot; zone-wide broadcasts have an empty key.
+	keys := make([]string, 0, len(s.robots)+1)
+	keys = append(keys, "")
+	for _, r := range s.robots {
+		keys = append(keys, string(r))
+	}
+	if err := messaging.SubscribeKeys(ctx, s.bus, messaging.TopicRobotCommands, keys, s.handleCommand); err != nil {
+		return err
 	}
+	stop := clock.Every(s.clock, s.statusInterval, func() {
+		s.stepAll()
+		s.flushResults(ctx)
+		s.tryApplyAllFirmware()
+		s.publishAllStatus(ctx)
+	})
+	context.AfterFunc(ctx, stop)
+	return nil
 }
 
 func (s *Simulator) handleCommand(key string, value []byte) error {
@@ -82,19 +185,92 @@ func (s *Simulator) handleCommand(key string, value []byte) error {
 	if err := json.Unmarshal(value, &cmd); err != nil {
 		return err
 	}
+	if cmd.RobotID == "" && cmd.ZoneID != s.zoneID {
+		return nil // another zone's broadcast
+	}
+	if !s.verifier.Admit(cmd, s.zoneID) {
+		return nil
+	}
+	if api.IsSafetyCommand(cmd.Type) {
+		s.dispatch(cmd)
+		return nil
+	}
+	delay, times := s.commandFaults(cmd.RobotID)
+	if delay == 0 && times == 1 {
+		s.dispatch(cmd)
+		return nil
+	}
+	s.clock.AfterFunc(delay, func() {
+		for i := 0; i < times; i++ {
+			s.dispatch(cmd)
+		}
+	})
+	return nil
+}
+
+// commandFaults draws the injected delay and how many times the command is handled (2 = duplicated).
+func (s *Simulator) commandFaults(robotID api.RobotID) (time.Duration, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.state[robotID]
+	f := s.sim.Faults
+	if st == nil {
+		return 0, 1
+	}
+	var delay time.Duration
+	if f.CommandDelay > 0 {
+		delay = time.Duration(st.rng.Int63n(int64(f.CommandDelay)))
+	}
+	times := 1
+	if f.CommandDuplicateRate > 0 && st.rng.Float64() < f.CommandDuplicateRate {
+		times = 2
+	}
+	return delay, times
+}
+
+func (s *Simulator) dispatch(cmd api.RobotCommand) {
+	if cmd.RobotID == "" {
+		// Zone-wide safety broadcast.
+		for _, robotID := range s.robots {
+			if _, broadcast := commandTargets(cmd, robotID, s.zoneID); broadcast {
+				s.handleSafety(robotID, cmd, api.SafetySourceZoneBroadcast)
+			}
+		}
+		return
+	}
 	s.mu.RLock()
-	_, ok := s.state[cmd.RobotID]
+	st, ok := s.state[cmd.RobotID]
 	s.mu.RUnlock()
 	if !ok {
-		return nil
+		return
+	}
+	if api.IsSafetyCommand(cmd.Type) {
+		s.handleSafety(cmd.RobotID, cmd, api.SafetySourceCommand)
+		return
+	}
+	s.mu.Lock()
+	ev, err := st.safety.check(cmd, s.clock.Now().UTC())
+	s.mu.Unlock()
+	if err != nil {
+		s.audit(ev)
+		if cmd.Type != api.RobotCommandTypeFirmwareUpdate && cmd.Type != api.RobotCommandTypeReroute {
+			s.mu.Lock()
+			s.taskEndedLocked(st, cmd, s.clock.Now(), "rejected: robot stopped")
+			s.mu.Unlock()
+			s.flushResults(context.Background())
+		}
+		return
 	}
 	switch cmd.Type {
 	case api.RobotCommandTypeFirmwareUpdate:
 		s.handleFirmwareUpdate(cmd)
+	case api.RobotCommandTypeReroute:
+		s.handleReroute(cmd)
+	case api.RobotCommandTypeCharge:
+		s.handleTask(cmd) // queued behind a running task like any other
 	default:
 		s.handleTask(cmd)
 	}
-	return nil
 }
 
 func (s *Simulator) handleTask(cmd api.RobotCommand) {
@@ -104,88 +280,485 @@ func (s *Simulator) handleTask(cmd api.RobotCommand) {
 		s.mu.Unlock()
 		return
 	}
-	st.state = "BUSY"
+	now := s.clock.Now()
+	s.stepLocked(st, now)
+	if st.state == "ERROR" {
+		s.taskEndedLocked(st, cmd, now, "robot error") // a faulted robot drops tasks
+		s.mu.Unlock()
+		s.flushResults(context.Background())
+		return
+	}
+	if st.state == api.RobotStateCharging && st.chargeInterruptible && cmd.Type != api.RobotCommandTypeCharge {
+		st.state, st.plan = "IDLE", nil // ends an opportunistic charge where the robot is
+	}
+	st.queue = append(st.queue, cmd)
+	s.startNextLocked(st, now)
 	s.mu.Unlock()
-	go func() {
-		time.Sleep(s.taskDuration)
+	s.flushResults(context.Background())
+}
+
+// startNextLocked starts the robot's next queued task if it is idle. Caller must hold s.mu.
+func (s *Simulator) startNextLocked(st *robotSimState, now time.Time) {
+	if st.state != "IDLE" || st.safety.stopped() || len(st.queue) == 0 {
+		return
+	}
+	cmd := st.queue[0]
+	st.queue = st.queue[1:]
+	s.startTaskLocked(st, cmd, now)
+}
+
+// startTaskLocked plans the route through the task's pick and drop points and finishes the task when the
+// route is done. Tasks with a zone route follow its waypoints instead (see simroute.go); tasks without
+// locations take a randomized taskDuration in place. Caller must hold s.mu.
+func (s *Simulator) startTaskLocked(st *robotSimState, cmd api.RobotCommand, now time.Time) {
+	if cmd.Type == api.RobotCommandTypeCharge {
+		s.startChargeLocked(st, cmd, now)
+		return
+	}
+	st.task = &cmd
+	if waypoints := taskRoute(cmd.Payload); len(waypoints) > 0 {
+		s.startRouteLocked(st, cmd, waypoints, now)
+		return
+	}
+	s.releaseNodesLocked(st, "")
+	stops := s.sim.taskStops(cmd.Payload)
+	dwell := func() time.Duration { return s.sim.jitter(st.rng, st.model.HandlingTime) }
+	switch {
+	case len(stops) == 0 && (cmd.Type == api.RobotCommandTypePick || cmd.Type == api.RobotCommandTypePlace):
+		stops = []api.Point{st.pos}
+	case len(stops) == 0:
+		stops = []api.Point{st.pos}
+		dwell = func() time.Duration { return s.sim.jitter(st.rng, s.taskDuration) }
+	case cmd.Type == api.RobotCommandTypeGoTo:
+		dwell = func() time.Duration { return 0 }
+	}
+	st.plan = newSimPlan(now, st.pos, stops, st.model.SpeedMPS, dwell)
+	st.state = "BUSY"
+	st.taskSeq++
+	seq := st.taskSeq
+	s.clock.AfterFunc(st.plan.total(), func() {
 		s.mu.Lock()
 		st := s.state[cmd.RobotID]
-		if st != nil && st.state == "BUSY" {
-			st.state = "IDLE"
-			pending := st.pendingFirmware
-			st.pendingFirmware = nil
+		if st != nil && st.state == "BUSY" && st.taskSeq == seq {
+			s.finishTaskLocked(st, cmd, s.clock.Now())
 			s.mu.Unlock()
-			if pending != nil {
-				s.handleFirmwareUpdate(*pending)
-			}
+			s.flushResults(context.Background())
+			s.tryApplyFirmware(cmd.RobotID)
 		} else {
 			s.mu.Unlock()
 		}
-	}()
+	})
 }
 
-func (s *Simulator) handleFirmwareUpdate(cmd api.RobotCommand) {
-	var payload api.FirmwareUpdatePayload
-	if err := json.Unmarshal(cmd.Payload, &payload); err != nil {
+// finishTaskLocked ends the robot's task, sends it to a charger if it is low and starts its next task. MOVE
+// and GOTO commands do not count as tasks. Caller must hold s.mu, and flush results and try pending firmware
+// after unlocking.
+func (s *Simulator) finishTaskLocked(st *robotSimState, cmd api.RobotCommand, now time.Time) {
+	s.stepLocked(st, now)
+	st.state = "IDLE"
+	st.plan = nil
+	st.route = nil
+	st.task = nil
+	if cmd.Type != api.RobotCommandTypeMove && cmd.Type != api.RobotCommandTypeGoTo {
+		st.tasksCompleted++
+	}
+	s.taskEndedLocked(st, cmd, now, "")
+	s.maybeChargeLocked(st, now)
+	s.startNextLocked(st, now)
+}
+
+// stepLocked advances a robot's position and battery to now. Caller must hold s.mu.
+func (s *Simulator) stepLocked(st *robotSimState, now time.Time) {
+	hours := now.Sub(st.lastStep).Hours()
+	if hours < 0 {
 		return
 	}
+	st.lastStep = now
+	m := st.model
+	if st.plan != nil {
+		pos, odo := st.plan.at(now)
+		driven := odo - st.plan.odo
+		st.plan.odo = odo
+		st.pos = pos
+		st.odometer += driven
+		st.battery -= driven / 1000 * m.DrainPerKm
+	}
+	switch {
+	case st.state == "BUSY":
+		st.battery -= hours * m.DrainBusyPerHour
+	case st.state == api.RobotStateCharging && st.plan == nil:
+		st.battery += hours * m.ChargePerHour
+	default:
+		st.battery -= hours * m.DrainIdlePerHour
+	}
+	st.battery = math.Max(0, math.Min(100, st.battery))
+	if st.state == api.RobotStateCharging && st.plan != nil && st.plan.done(now) {
+		st.plan = nil // arrived at the charger
+	}
+}
+
+// Injected faults reported in RobotStatus.Extra[api.ExtraFault].
+const (
+	faultError   = "injected_error"
+	faultBattery = "battery_fault"
+)
+
+// injectFaultsLocked draws this interval's random faults and clears expired ones. Caller must hold s.mu.
+func (s *Simulator) injectFaultsLocked(st *robotSimState, elapsed time.Duration, now time.Time) {
+	f := s.sim.Faults
+	if st.state == "ERROR" && !now.Before(st.errorUntil) {
+		st.state, st.fault = "IDLE", ""
+	}
+	if st.state != "ERROR" && !st.safety.stopped() && chance(st.rng, f.ErrorRatePerHour, elapsed) {
+		s.setErrorLocked(st, now, f.ErrorDuration)
+	}
+	if chance(st.rng, f.DropoutRatePerHour, elapsed) {
+		st.silentUntil = now.Add(f.DropoutDuration)
+	}
+	if chance(st.rng, f.BatteryFaultRatePerHour, elapsed) {
+		s.setBatteryFaultLocked(st)
+	}
+}
+
+// setErrorLocked puts the robot in ERROR for d, aborting its task (firmware in progress is left to finish).
+func (s *Simulator) setErrorLocked(st *robotSimState, now time.Time, d time.Duration) {
+	if st.firmwareUpdateStatus == api.FirmwareStatusDownloading || st.firmwareUpdateStatus == api.FirmwareStatusApplying {
+		return
+	}
+	st.state, st.fault = "ERROR", faultError
+	st.errorUntil = now.Add(d)
+	st.taskSeq++
+	st.plan = nil
+	s.abortTasksLocked(st, now, "robot error")
+	s.abortRouteLocked(st)
+}
+
+// abortTasksLocked fails the robot's running and queued tasks. Caller must hold s.mu and flush results
+// after unlocking.
+func (s *Simulator) abortTasksLocked(st *robotSimState, now time.Time, reason string) {
+	if st.task != nil {
+		s.taskEndedLocked(st, *st.task, now, reason)
+		st.task = nil
+	}
+	for _, cmd := range st.queue {
+		s.taskEndedLocked(st, cmd, now, reason)
+	}
+	st.queue = nil
+}
+
+// taskEndedLocked records how a task ended; empty failure means it succeeded. Charges and MOVEs are the
+// zone's own and not reported. Caller must hold s.mu and flush results after unlocking.
+func (s *Simulator) taskEndedLocked(st *robotSimState, cmd api.RobotCommand, now time.Time, failure string) {
+	if s.resultPub == nil || cmd.Type == api.RobotCommandTypeCharge || cmd.Type == api.RobotCommandTypeMove {
+		return
+	}
+	res := api.TaskResult{TaskID: cmd.ID, RobotID: st.id, Status: api.TaskResultSucceeded, At: now.UTC()}
+	if failure != "" {
+		res.Status, res.Error = api.TaskResultFailed, failure
+	}
+	s.results = append(s.results, res)
+}
+
+// flushResults publishes the task results recorded so far. Must not be called with s.mu held: the zone may
+// answer a result with a new command.
+func (s *Simulator) flushResults(ctx context.Context) {
 	s.mu.Lock()
-	st := s.state[cmd.RobotID]
+	results := s.results
+	s.results = nil
+	s.mu.Unlock()
+	for i := range results {
+		_ = s.resultPub.PublishTaskResult(ctx, &results[i])
+	}
+}
+
+func (s *Simulator) setBatteryFaultLocked(st *robotSimState) {
+	st.battery = math.Min(st.battery, 10*st.rng.Float64())
+	st.fault = faultBattery
+}
+
+// maybeChargeLocked sends an idle robot below ChargeBelow to the nearest charger. Caller must hold s.mu.
+func (s *Simulator) maybeChargeLocked(st *robotSimState, now time.Time) {
+	if st.state != "IDLE" || st.safety.stopped() || st.battery >= s.sim.ChargeBelow {
+		return
+	}
+	s.driveToChargerLocked(st, s.sim.nearestCharger(st.pos), 0, false, now)
+}
+
+// startChargeLocked drives the robot to the charger of a CHARGE command and charges it to the command's
+// limit. A payload without a position sends it to the nearest configured charger. Caller must hold s.mu.
+func (s *Simulator) startChargeLocked(st *robotSimState, cmd api.RobotCommand, now time.Time) {
+	var p api.ChargePayload
+	if json.Unmarshal(cmd.Payload, &p) != nil || p.ChargerID == "" {
+		p = api.ChargePayload{Position: s.sim.nearestCharger(st.pos)}
+	}
+	s.driveToChargerLocked(st, p.Position, p.Until, p.Interruptible, now)
+}
+
+func (s *Simulator) driveToChargerLocked(st *robotSimState, charger api.Point, until float64, interruptible bool, now time.Time) {
+	st.state = api.RobotStateCharging
+	st.chargeUntil, st.chargeInterruptible = until, interruptible
+	s.releaseNodesLocked(st, "")
+	st.plan = newSimPlan(now, st.pos, []api.Point{charger}, st.model.SpeedMPS, func() time.Duration { return 0 })
+}
+
+// stepAll advances every robot, starts and ends charging, and starts tasks that waited for a charge.
+func (s *Simulator) stepAll() {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, robotID := range s.robots {
+		st := s.state[robotID]
+		if st == nil {
+			continue
+		}
+		prev := st.lastStep
+		s.stepLocked(st, now)
+		s.injectFaultsLocked(st, now.Sub(prev), now)
+		until := st.chargeUntil
+		if until <= 0 {
+			until = s.sim.ChargeUntil
+		}
+		if st.state == api.RobotStateCharging && st.plan == nil && st.battery >= until {
+			st.state = "IDLE"
+			if st.fault == faultBattery {
+				st.fault = ""
+			}
+		}
+		s.maybeChargeLocked(st, now)
+		s.startNextLocked(st, now)
+	}
+}
+
+// handleSafety applies a safety command to one simulated robot; a stop aborts its running task.
+// Status is published immediately.
+func (s *Simulator) handleSafety(robotID api.RobotID, cmd api.RobotCommand, source string) {
+	s.mu.Lock()
+	st := s.state[robotID]
 	if st == nil {
 		s.mu.Unlock()
 		return
 	}
-	if payload.ModelID != "" && payload.ModelID != st.modelID {
+	s.stepLocked(st, s.clock.Now())
+	ev := st.safety.apply(cmd, source, s.clock.Now().UTC())
+	if ev != nil && ev.To != api.SafetyStateNormal && st.firmwareUpdateStatus != api.FirmwareStatusDownloading && st.firmwareUpdateStatus != api.FirmwareStatusApplying {
+		st.state = "IDLE"
+		st.taskSeq++
+		st.plan = nil
+		s.abortTasksLocked(st, s.clock.Now(), "safety stop")
+		s.abortRouteLocked(st)
+	}
+	s.mu.Unlock()
+	s.flushResults(context.Background())
+	if ev == nil {
+		return
+	}
+	s.audit(ev)
+	s.publishStatus(context.Background(), robotID)
+	if ev.To == api.SafetyStateNormal {
+		s.tryApplyFirmware(robotID)
+	}
+}
+
+func (s *Simulator) audit(ev *api.SafetyEvent) {
+	if ev == nil || s.safetyPub == nil {
+		return
+	}
+	_ = s.safetyPub.PublishSafetyEvent(context.Background(), ev)
+}
+
+func (s *Simulator) handleFirmwareUpdate(cmd api.RobotCommand) {
+	plan, err := newFirmwarePlan(cmd)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	st := s.state[cmd.RobotID]
+	if st == nil {
 		s.mu.Unlock()
 		return
 	}
-	if st.state == "BUSY" {
-		st.pendingFirmware = &cmd
+	st.firmwareCommandID = string(cmd.ID)
+	st.firmwareDeferReason = ""
+	if plan.payload.ModelID != "" && plan.payload.ModelID != st.modelID {
+		st.firmwareUpdateStatus = api.FirmwareStatusSkipped
 		s.mu.Unlock()
 		return
 	}
+	st.firmwareUpdateStatus = api.FirmwareStatusIdle
+	st.pendingFirmware = plan
+	s.mu.Unlock()
+	s.tryApplyFirmware(cmd.RobotID)
+}
+
+// tryApplyFirmware starts the robot's pending firmware update if its policy allows it now.
+func (s *Simulator) tryApplyFirmware(robotID api.RobotID) {
+	s.mu.Lock()
+	st := s.state[robotID]
+	var payload *api.FirmwareUpdatePayload
+	if st != nil {
+		payload = s.checkFirmwareLocked(st, s.clock.Now())
+	}
+	s.mu.Unlock()
+	if payload != nil {
+		s.applyFirmware(robotID, *payload)
+	}
+}
+
+// tryApplyAllFirmware re-evaluates every deferred update (maintenance windows open, battery recovers, ...).
+func (s *Simulator) tryApplyAllFirmware() {
+	now := s.clock.Now()
+	type startFirmware struct {
+		robotID api.RobotID
+		payload api.FirmwareUpdatePayload
+	}
+	var start []startFirmware
+	s.mu.Lock()
+	for _, robotID := range s.robots {
+		st := s.state[robotID]
+		if st == nil || st.pendingFirmware == nil {
+			continue
+		}
+		if payload := s.checkFirmwareLocked(st, now); payload != nil {
+			start = append(start, startFirmware{robotID, *payload})
+		}
+	}
+	s.mu.Unlock()
+	for _, f := range start {
+		s.applyFirmware(f.robotID, f.payload)
+	}
+}
+
+// checkFirmwareLocked returns the payload to apply and marks the robot BUSY, or records why it must wait.
+// Caller must hold s.mu.
+func (s *Simulator) checkFirmwareLocked(st *robotSimState, now time.Time) *api.FirmwareUpdatePayload {
+	plan := st.pendingFirmware
+	if plan == nil || st.state == "ERROR" {
+		return nil
+	}
+	if reason := plan.deferReason(now, st.reportedState(), st.battery); reason != "" {
+		st.firmwareDeferReason = reason
+		st.firmwareUpdateStatus = api.FirmwareStatusDeferred
+		return nil
+	}
+	st.pendingFirmware = nil
+	st.firmwareDeferReason = ""
+	s.stepLocked(st, now)
+	st.plan = nil
 	st.state = "BUSY"
 	st.firmwareUpdateStatus = api.FirmwareStatusDownloading
-	s.mu.Unlock()
-	go func() {
-		time.Sleep(2 * time.Second)
+	return &plan.payload
+}
+
+// applyFirmware simulates download (2s) and apply (2s) on the simulator's clock.
+func (s *Simulator) applyFirmware(robotID api.RobotID, payload api.FirmwareUpdatePayload) {
+	s.clock.AfterFunc(2*time.Second, func() {
 		s.mu.Lock()
-		st := s.state[cmd.RobotID]
-		if st != nil {
+		if st := s.state[robotID]; st != nil {
 			st.firmwareUpdateStatus = api.FirmwareStatusApplying
 		}
 		s.mu.Unlock()
-		time.Sleep(2 * time.Second)
-		s.mu.Lock()
-		st = s.state[cmd.RobotID]
-		if st != nil {
+		s.clock.AfterFunc(2*time.Second, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			st := s.state[robotID]
+			if st == nil {
+				return
+			}
 			st.state = "IDLE"
-			st.firmwareVersion = payload.Version
-			st.firmwareUpdateStatus = api.FirmwareStatusSuccess
-		}
-		s.mu.Unlock()
-	}()
+			fail := st.failNextFirmware || (s.sim.Faults.FirmwareFailRate > 0 && st.rng.Float64() < s.sim.Faults.FirmwareFailRate)
+			st.failNextFirmware = false
+			if fail {
+				st.firmwareUpdateStatus = api.FirmwareStatusFailed
+			} else {
+				st.firmwareVersion = payload.Version
+				st.firmwareUpdateStatus = api.FirmwareStatusSuccess
+			}
+			s.startNextLocked(st, s.clock.Now())
+		})
+	})
 }
 
 func (s *Simulator) publishAllStatus(ctx context.Context) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
 	for _, robotID := range s.robots {
-		st := s.state[robotID]
-		if st == nil {
-			continue
-		}
-		status := &api.RobotStatus{
-			RobotID:   robotID,
-			State:     st.state,
-			Battery:   100,
-			UpdatedAt: time.Now().UTC(),
-			Extra: map[string]interface{}{
-				api.ExtraModelID:             st.modelID,
-				api.ExtraFirmwareVersion:     st.firmwareVersion,
-				api.ExtraFirmwareUpdateStatus: st.firmwareUpdateStatus,
-			},
+		if st := s.state[robotID]; st != nil && !s.heartbeatLost(st, now) {
+			_ = s.statusPub.PublishRobotStatus(ctx, st.status(robotID, now))
 		}
+	}
+}
+
+// heartbeatLost reports whether an injected dropout or loss suppresses this status message. Caller must hold s.mu.
+func (s *Simulator) heartbeatLost(st *robotSimState, now time.Time) bool {
+	if now.Before(st.silentUntil) {
+		return true
+	}
+	return s.sim.Faults.HeartbeatLossRate > 0 && st.rng.Float64() < s.sim.Faults.HeartbeatLossRate
+}
+
+// publishStatus publishes one robot's status out of band (safety transitions).
+func (s *Simulator) publishStatus(ctx context.Context, robotID api.RobotID) {
+	s.mu.Lock()
+	st := s.state[robotID]
+	var status *api.RobotStatus
+	if now := s.clock.Now(); st != nil && !s.heartbeatLost(st, now) {
+		status = st.status(robotID, now)
+	}
+	s.mu.Unlock()
+	if status != nil {
 		_ = s.statusPub.PublishRobotStatus(ctx, status)
 	}
 }
+
+func (st *robotSimState) reportedState() string {
+	if st.safety.stopped() {
+		return api.RobotStateStopped
+	}
+	return st.state
+}
+
+// status builds the robot's RobotStatus. Caller must hold s.mu.
+func (st *robotSimState) status(robotID api.RobotID, now time.Time) *api.RobotStatus {
+	status := &api.RobotStatus{
+		RobotID:   robotID,
+		State:     st.reportedState(),
+		Position:  st.pos.String(),
+		Battery:   math.Round(st.battery*10) / 10,
+		UpdatedAt: now.UTC(),
+		Extra: map[string]interface{}{
+			api.ExtraModelID:              st.modelID,
+			api.ExtraFirmwareVersion:      st.firmwareVersion,
+			api.ExtraFirmwareUpdateStatus: st.firmwareUpdateStatus,
+			api.ExtraSafetyState:          st.safety.state,
+			api.ExtraOdometerM:            math.Round(st.odometer),
+			api.ExtraTasksCompleted:       st.tasksCompleted,
+			api.ExtraTaskQueue:            len(st.queue),
+		},
+	}
+	if st.firmwareDeferReason != "" {
+		status.Extra[api.ExtraFirmwareDeferReason] = st.firmwareDeferReason
+	}
+	if st.firmwareCommandID != "" {
+		status.Extra[api.ExtraFirmwareCommandID] = st.firmwareCommandID
+	}
+	if st.safety.reason != "" {
+		status.Extra[api.ExtraSafetyReason] = st.safety.reason
+	}
+	if st.fault != "" {
+		status.Extra[api.ExtraFault] = st.fault
+	}
+	status.Extra[api.ExtraSpeedMPS] = st.model.SpeedMPS
+	if st.node != "" {
+		status.Extra[api.ExtraRouteNode] = st.node
+	}
+	if st.routeTask != "" {
+		status.Extra[api.ExtraRouteTask] = string(st.routeTask)
+		status.Extra[api.ExtraRouteIndex] = st.routeIndex
+	}
+	if st.route != nil && st.route.waitingFor != "" {
+		status.Extra[api.ExtraWaitingFor] = st.route.waitingFor
+		status.Extra[api.ExtraWaitingSecs] = math.Round(now.Sub(st.route.waitingSince).Seconds())
+	}
+	return status
+}
@@ -0,0 +1,76 @@
+package edge
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+func init() {
+	RegisterDriver("stub", newStubDriver)
+}
+
+// stubDriver simulates a robot without hardware: tasks take a fixed duration, firmware always succeeds.
+// Options: task_duration (default "2s"), firmware_step (download and apply each, default "2s").
+type stubDriver struct {
+	taskDuration time.Duration
+	firmwareStep time.Duration
+}
+
+func newStubDriver(cfg DriverConfig) (RobotDriver, error) {
+	d := &stubDriver{taskDuration: 2 * time.Second, firmwareStep: 2 * time.Second}
+	if v := cfg.Options["task_duration"]; v != "" {
+		dur, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("stub driver task_duration: %w", err)
+		}
+		d.taskDuration = dur
+	}
+	if v := cfg.Options["firmware_step"]; v != "" {
+		dur, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("stub driver firmware_step: %w", err)
+		}
+		d.firmwareStep = dur
+	}
+	return d, nil
+}
+
+func (d *stubDriver) Connect(ctx context.Context) error { return nil }
+
+func (d *stubDriver) StreamTelemetry(ctx context.Context, fn func(Telemetry)) error {
+	battery := 100.0
+	fn(Telemetry{Battery: &battery})
+	<-ctx.Done()
+	return nil
+}
+
+func (d *stubDriver) Execute(ctx context.Context, cmd api.RobotCommand) error {
+	return sleepCtx(ctx, d.taskDuration)
+}
+
+func (d *stubDriver) EStop(ctx context.Context) error { return nil }
+
+func (d *stubDriver) UpdateFirmware(ctx context.Context, payload api.FirmwareUpdatePayload, progress func(string)) error {
+	progress(api.FirmwareStatusDownloading)
+	if err := sleepCtx(ctx, d.firmwareStep); err != nil {
+		return err
+	}
+	progress(api.FirmwareStatusApplying)
+	return sleepCtx(ctx, d.firmwareStep)
+}
+
+func (d *stubDriver) Close() error { return nil }
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
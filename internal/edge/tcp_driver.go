@@ -0,0 +1,295 @@
+package edge
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+func init() {
+	RegisterDriver("tcp-json", newTCPJSONDriver)
+}
+
+// TCPMessage is one newline-delimited JSON message of the tcp-json robot protocol.
+//
+// Edge → robot: "command" (Command, Payload), "estop", "firmware" (Payload = FirmwareUpdatePayload).
+// Robot → edge: "telemetry" (Telemetry), "firmware_status" (ID, Status), "result" (ID, OK, Error).
+// Every command, estop and firmware request is answered by exactly one "result" with the same ID.
+type TCPMessage struct {
+	Type      string          `json:"type"`
+	ID        string          `json:"id,omitempty"`
+	Command   string          `json:"command,omitempty"` // RobotCommand.Type
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Telemetry *Telemetry      `json:"telemetry,omitempty"`
+	Status    string          `json:"status,omitempty"` // firmware_status: downloading | applying
+	OK        bool            `json:"ok,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// TCP message types.
+const (
+	TCPMessageCommand        = "command"
+	TCPMessageEStop          = "estop"
+	TCPMessageFirmware       = "firmware"
+	TCPMessageTelemetry      = "telemetry"
+	TCPMessageFirmwareStatus = "firmware_status"
+	TCPMessageResult         = "result"
+)
+
+var errNotConnected = errors.New("robot not connected")
+
+// tcpJSONDriver speaks TCPMessage over a TCP connection to the robot (or its vendor bridge).
+// It reconnects with backoff if the connection drops; in-flight requests fail and are reported by the gateway.
+// Options: dial_timeout (default "5s").
+type tcpJSONDriver struct {
+	robotID     api.RobotID
+	addr        string
+	dialTimeout time.Duration
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	conn    net.Conn
+	pending map[string]chan TCPMessage
+	fwProg  map[string]func(string)
+	onTelem func(Telemetry)
+	seq     atomic.Uint64
+}
+
+func newTCPJSONDriver(cfg DriverConfig) (RobotDriver, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("tcp-json driver: robot_address required")
+	}
+	d := &tcpJSONDriver{
+		robotID:     cfg.RobotID,
+		addr:        cfg.Address,
+		dialTimeout: 5 * time.Second,
+		pending:     make(map[string]chan TCPMessage),
+		fwProg:      make(map[string]func(string)),
+	}
+	if v := cfg.Options["dial_timeout"]; v != "" {
+		dur, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("tcp-json driver dial_timeout: %w", err)
+		}
+		d.dialTimeout = dur
+	}
+	return d, nil
+}
+
+func (d *tcpJSONDriver) Connect(ctx context.Context) error {
+	if err := d.dial(ctx); err != nil {
+		return err
+	}
+	go d.readLoop(ctx)
+	return nil
+}
+
+func (d *tcpJSONDriver) dial(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: d.dialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", d.addr)
+	if err != nil {
+		return fmt.Errorf("tcp-json driver: dial %s: %w", d.addr, err)
+	}
+	d.mu.Lock()
+	d.conn = conn
+	d.mu.Unlock()
+	return nil
+}
+
+// readLoop dispatches incoming messages and reconnects when the connection drops, until ctx is done.
+func (d *tcpJSONDriver) readLoop(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		_ = d.Close()
+	}()
+	for {
+		d.mu.Lock()
+		conn := d.conn
+		d.mu.Unlock()
+		if conn != nil {
+			err := d.readConn(conn)
+			d.failPending(fmt.Errorf("connection lost: %w", err))
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		for attempt := 0; ; attempt++ {
+			if sleepCtx(ctx, backoff(attempt)) != nil {
+				return
+			}
+			if err := d.dial(ctx); err == nil {
+				log.Printf("edge %s: tcp-json reconnected to %s", d.robotID, d.addr)
+				break
+			}
+		}
+	}
+}
+
+func (d *tcpJSONDriver) readConn(conn net.Conn) error {
+	sc := bufio.NewScanner(conn)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var msg TCPMessage
+		if err := json.Unmarshal(sc.Bytes(), &msg); err != nil {
+			log.Printf("edge %s: tcp-json: invalid message: %v", d.robotID, err)
+			continue
+		}
+		d.dispatch(msg)
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+func (d *tcpJSONDriver) dispatch(msg TCPMessage) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch msg.Type {
+	case TCPMessageTelemetry:
+		if msg.Telemetry != nil && d.onTelem != nil {
+			d.onTelem(*msg.Telemetry)
+		}
+	case TCPMessageFirmwareStatus:
+		if fn := d.fwProg[msg.ID]; fn != nil {
+			fn(msg.Status)
+		}
+	case TCPMessageResult:
+		if ch, ok := d.pending[msg.ID]; ok {
+			delete(d.pending, msg.ID)
+			ch <- msg
+		}
+	}
+}
+
+func (d *tcpJSONDriver) failPending(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.conn != nil {
+		_ = d.conn.Close()
+		d.conn = nil
+	}
+	for id, ch := range d.pending {
+		delete(d.pending, id)
+		ch <- TCPMessage{Type: TCPMessageResult, ID: id, Error: err.Error()}
+	}
+}
+
+// request sends msg with a fresh ID and waits for its result.
+func (d *tcpJSONDriver) request(ctx context.Context, msg TCPMessage, progress func(string)) error {
+	msg.ID = fmt.Sprintf("%s#%d", msg.ID, d.seq.Add(1))
+	ch := make(chan TCPMessage, 1)
+	d.mu.Lock()
+	conn := d.conn
+	if conn == nil {
+		d.mu.Unlock()
+		return errNotConnected
+	}
+	d.pending[msg.ID] = ch
+	if progress != nil {
+		d.fwProg[msg.ID] = progress
+	}
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		delete(d.pending, msg.ID)
+		delete(d.fwProg, msg.ID)
+		d.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	d.writeMu.Lock()
+	_, err = conn.Write(append(data, '\n'))
+	d.writeMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("tcp-json driver: write: %w", err)
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case res := <-ch:
+		if !res.OK {
+			if res.Error == "" {
+				res.Error = "rejected by robot"
+			}
+			return errors.New(res.Error)
+		}
+		return nil
+	}
+}
+
+func (d *tcpJSONDriver) StreamTelemetry(ctx context.Context, fn func(Telemetry)) error {
+	d.mu.Lock()
+	d.onTelem = fn
+	d.mu.Unlock()
+	<-ctx.Done()
+	d.mu.Lock()
+	d.onTelem = nil
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *tcpJSONDriver) Execute(ctx context.Context, cmd api.RobotCommand) error {
+	return d.request(ctx, TCPMessage{
+		Type:    TCPMessageCommand,
+		ID:      string(cmd.ID),
+		Command: cmd.Type,
+		Payload: rawJSON(cmd.Payload),
+	}, nil)
+}
+
+func (d *tcpJSONDriver) EStop(ctx context.Context) error {
+	return d.request(ctx, TCPMessage{Type: TCPMessageEStop, ID: "estop"}, nil)
+}
+
+func (d *tcpJSONDriver) UpdateFirmware(ctx context.Context, payload api.FirmwareUpdatePayload, progress func(string)) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return d.request(ctx, TCPMessage{Type: TCPMessageFirmware, ID: "fw-" + payload.Version, Payload: data}, progress)
+}
+
+func (d *tcpJSONDriver) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.conn == nil {
+		return nil
+	}
+	err := d.conn.Close()
+	d.conn = nil
+	return err
+}
+
+// rawJSON passes valid JSON payloads through unchanged and wraps anything else as a JSON string.
+func rawJSON(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return nil
+	}
+	if json.Valid(b) {
+		return json.RawMessage(b)
+	}
+	s, _ := json.Marshal(string(b))
+	return s
+}
+
+func backoff(attempt int) time.Duration {
+	d := time.Duration(attempt+1) * 500 * time.Millisecond
+	if d > 10*time.Second {
+		d = 10 * time.Second
+	}
+	return d
+}
@@ -0,0 +1,212 @@
+package edge
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// tcpRobot is an in-process robot speaking the tcp-json protocol. It answers "TASK" commands, e-stops and
+// firmware updates, rejects "REJECT" commands, and drops the connection on "HANG".
+type tcpRobot struct {
+	t  *testing.T
+	ln net.Listener
+
+	mu       sync.Mutex
+	conn     net.Conn
+	accepted int
+	received []TCPMessage
+}
+
+func startTCPRobot(t *testing.T) *tcpRobot {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &tcpRobot{t: t, ln: ln}
+	t.Cleanup(func() {
+		ln.Close()
+		r.mu.Lock()
+		if r.conn != nil {
+			r.conn.Close()
+		}
+		r.mu.Unlock()
+	})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			r.mu.Lock()
+			r.conn = conn
+			r.accepted++
+			r.mu.Unlock()
+			go r.serve(conn)
+		}
+	}()
+	return r
+}
+
+func (r *tcpRobot) serve(conn net.Conn) {
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		var msg TCPMessage
+		if err := json.Unmarshal(sc.Bytes(), &msg); err != nil {
+			r.t.Errorf("robot: invalid message %s: %v", sc.Bytes(), err)
+			continue
+		}
+		r.mu.Lock()
+		r.received = append(r.received, msg)
+		r.mu.Unlock()
+		result := TCPMessage{Type: TCPMessageResult, ID: msg.ID, OK: true}
+		switch {
+		case msg.Command == "HANG":
+			conn.Close()
+			return
+		case msg.Command == "REJECT":
+			result = TCPMessage{Type: TCPMessageResult, ID: msg.ID, Error: "gripper jammed"}
+		case msg.Type == TCPMessageFirmware:
+			r.send(TCPMessage{Type: TCPMessageFirmwareStatus, ID: msg.ID, Status: api.FirmwareStatusDownloading})
+			r.send(TCPMessage{Type: TCPMessageFirmwareStatus, ID: msg.ID, Status: api.FirmwareStatusApplying})
+		}
+		r.send(result)
+	}
+}
+
+// send writes msg to the current connection, if any.
+func (r *tcpRobot) send(msg TCPMessage) {
+	data, _ := json.Marshal(msg)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn != nil {
+		r.conn.Write(append(data, '\n'))
+	}
+}
+
+func (r *tcpRobot) last() TCPMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.received) == 0 {
+		return TCPMessage{}
+	}
+	return r.received[len(r.received)-1]
+}
+
+// telemetry sends tm until the driver delivers a matching sample, which takes a moment after connecting.
+func (r *tcpRobot) telemetry(samples <-chan Telemetry, tm Telemetry, cond func(Telemetry) bool) {
+	r.t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		r.send(TCPMessage{Type: TCPMessageTelemetry, Telemetry: &tm})
+		select {
+		case got := <-samples:
+			if cond(got) {
+				return
+			}
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	r.t.Fatalf("no telemetry matching %+v", tm)
+}
+
+func TestTCPJSONDriver(t *testing.T) {
+	robot := startTCPRobot(t)
+	d, err := NewDriver("tcp-json", DriverConfig{RobotID: "robot-1", Address: robot.ln.Addr().String(), Options: map[string]string{"dial_timeout": "1s"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if err := d.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	samples := make(chan Telemetry, 64)
+	go d.StreamTelemetry(ctx, func(tm Telemetry) {
+		select {
+		case samples <- tm:
+		default:
+		}
+	})
+
+	battery := 87.5
+	robot.telemetry(samples, Telemetry{Position: "3.0,4.0", Battery: &battery, FirmwareVersion: "1.0.0"}, func(tm Telemetry) bool {
+		return tm.Position == "3.0,4.0" && tm.Battery != nil && *tm.Battery == 87.5 && tm.FirmwareVersion == "1.0.0"
+	})
+
+	// Commands go out with their type and payload and return once the robot answers.
+	cmd := api.RobotCommand{ID: "cmd-1", RobotID: "robot-1", Type: "TASK", Payload: json.RawMessage(`{"to":"A-01-01"}`)}
+	if err := d.Execute(ctx, cmd); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if got := robot.last(); got.Type != TCPMessageCommand || got.Command != "TASK" || !strings.HasPrefix(got.ID, "cmd-1#") || string(got.Payload) != `{"to":"A-01-01"}` {
+		t.Errorf("robot received %+v", got)
+	}
+	if err := d.Execute(ctx, api.RobotCommand{ID: "cmd-2", Type: "REJECT"}); err == nil || err.Error() != "gripper jammed" {
+		t.Errorf("rejected command: %v", err)
+	}
+	if err := d.EStop(ctx); err != nil || robot.last().Type != TCPMessageEStop {
+		t.Errorf("EStop: %v, robot received %+v", err, robot.last())
+	}
+	var progress []string
+	if err := d.UpdateFirmware(ctx, api.FirmwareUpdatePayload{Version: "1.1.0", DownloadURL: "https://example.com/fw.bin"}, func(s string) {
+		progress = append(progress, s)
+	}); err != nil {
+		t.Fatalf("UpdateFirmware: %v", err)
+	}
+	if strings.Join(progress, " ") != api.FirmwareStatusDownloading+" "+api.FirmwareStatusApplying {
+		t.Errorf("firmware progress %v", progress)
+	}
+
+	// A dropped connection fails the command in flight; the driver reconnects and carries on.
+	if err := d.Execute(ctx, api.RobotCommand{ID: "cmd-3", Type: "HANG"}); err == nil || !strings.Contains(err.Error(), "connection lost") {
+		t.Errorf("command on a dropped connection: %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		err := d.Execute(ctx, api.RobotCommand{ID: "cmd-4", Type: "TASK"})
+		if err == nil {
+			break
+		}
+		if err != errNotConnected || time.Now().After(deadline) {
+			t.Fatalf("Execute after the drop: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	robot.mu.Lock()
+	accepted := robot.accepted
+	robot.mu.Unlock()
+	if accepted != 2 {
+		t.Errorf("%d connections, want 2", accepted)
+	}
+	battery = 60
+	robot.telemetry(samples, Telemetry{Battery: &battery}, func(tm Telemetry) bool { return tm.Battery != nil && *tm.Battery == 60 })
+}
+
+func TestTCPJSONDriverNotConnected(t *testing.T) {
+	if _, err := NewDriver("tcp-json", DriverConfig{RobotID: "robot-1"}); err == nil {
+		t.Errorf("driver without an address")
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	d, err := NewDriver("tcp-json", DriverConfig{RobotID: "robot-1", Address: addr, Options: map[string]string{"dial_timeout": "1s"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Connect(context.Background()); err == nil {
+		t.Errorf("Connect to a closed port succeeded")
+	}
+	if err := d.Execute(context.Background(), api.RobotCommand{ID: "cmd-1", Type: "TASK"}); err != errNotConnected {
+		t.Errorf("Execute without a connection = %v, want %v", err, errNotConnected)
+	}
+}
@@ -3,12 +3,20 @@ package erp
 import (
 	"os"
 
-	"gopkg.in/yaml.v3"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
 type Config struct {
-	ERP ERPConfig `yaml:"erp"`
-	MES MESConfig `yaml:"mes"`
+	ERP  ERPConfig   `yaml:"erp"`
+	MES  MESConfig   `yaml:"mes"`
+	Auth auth.Config `yaml:"auth"` // API keys and JWT for this service's API; none configured leaves it open
+	Audit audit.Config `yaml:"audit"` // tamper-evident log of API actions; env AUDIT_LOG overrides the path
+	Idempotency idempotency.Config `yaml:"idempotency"` // replays retried POSTs with the same Idempotency-Key; env IDEMPOTENCY_TTL overrides the TTL
+	Storage storage.Config `yaml:"storage"` // memory (default) or sqlite; env STORE_BACKEND and STORE_PATH override
 }
 
 type ERPConfig struct {
@@ -18,6 +26,7 @@ type ERPConfig struct {
 type MESConfig struct {
 	APIURL         string `yaml:"api_url"`
 	DefaultAreaID  string `yaml:"default_area_id"`
+	Token          string `yaml:"token"` // API key or JWT sent to the API; env MES_API_TOKEN overrides
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -31,11 +40,7 @@ func LoadConfig(path string) (*Config, error) {
 		}
 	}
 	if path != "" {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return nil, err
-		}
-		if err := yaml.Unmarshal(data, cfg); err != nil {
+		if err := config.Load(path, cfg); err != nil {
 			return nil, err
 		}
 	}
@@ -57,5 +62,12 @@ func LoadConfig(path string) (*Config, error) {
 	if cfg.MES.DefaultAreaID == "" {
 		cfg.MES.DefaultAreaID = "area-1"
 	}
+	if t := os.Getenv("MES_API_TOKEN"); t != "" {
+		cfg.MES.Token = t
+	}
+	cfg.Auth.ApplyEnv()
+	cfg.Audit.ApplyEnv()
+	cfg.Idempotency.ApplyEnv()
+	cfg.Storage.ApplyEnv()
 	return cfg, nil
 }
@@ -0,0 +1,32 @@
+package erp
+
+import (
+	"net/http"
+
+	"github.com/robotfleetos/robotfleetos/pkg/openapi"
+)
+
+// OpenAPI is the ERP API's OpenAPI document, served at GET /openapi.json.
+var OpenAPI = &openapi.API{
+	Title:       "RobotFleetOS ERP API",
+	Description: "Sales orders: create, submit to the MES as production orders and cancel.",
+	Rules:       AuthRules,
+	Idempotent:  true,
+	Ops: openapi.Join(
+		openapi.Dashboard("/", "/ui"),
+		[]openapi.Op{
+			{Method: http.MethodPost, Path: "/orders", Summary: "Create a sales order",
+				Body: Order{}, Status: http.StatusCreated, Response: Order{}, Errors: []int{http.StatusBadRequest},
+				Example: Order{OrderRef: "SO-1001", SKU: "WIDGET-001", Quantity: 10}},
+			{Method: http.MethodGet, Path: "/orders", Summary: "List sales orders",
+				Query: []string{"status"}, Response: openapi.Object{"orders": []Order{}}},
+			{Method: http.MethodGet, Path: "/orders/{id}", Summary: "Get a sales order",
+				Response: Order{}, Errors: []int{http.StatusNotFound}},
+			{Method: http.MethodPost, Path: "/orders/{id}/submit_to_mes", Summary: "Submit an order to the MES as a production order",
+				Body: openapi.Object{"zone_id": "", "priority": 0}, Response: Order{}, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPost, Path: "/orders/{id}/cancel", Summary: "Cancel an order",
+				Response: Order{}, Errors: []int{http.StatusBadRequest}},
+		},
+		openapi.Common("erp"),
+	),
+}
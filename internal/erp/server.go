@@ -5,21 +5,39 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
 )
 
 //go:embed static/index.html
 var dashboardHTML []byte
 
 type Server struct {
-	Service *Service
+	Service     *Service
+	Auth        *auth.Authenticator // optional: API key/JWT authentication and role checks; nil leaves the API open
+	Audit       *audit.Log          // optional: records every change made through the API; GET /audit
+	Idempotency *idempotency.Store  // optional: replays the response to a retried POST with the same Idempotency-Key
 }
 
+// AuthRules are the ERP API's route permissions; see auth.Rule.
+var AuthRules = auth.Join(
+	audit.AuthRules,
+	auth.Public("/", "/ui", "/health", "/openapi.json"),
+	auth.Writes("/orders", auth.RolePlanner),
+	auth.Writes("/orders/", auth.RolePlanner),
+)
+
 func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", s.handleHealth)
+	mux.Handle("/openapi.json", OpenAPI)
+	mux.Handle("/audit", s.Audit)
+	mux.Handle("/audit/", s.Audit)
 	mux.HandleFunc("/orders", s.handleOrders)
 	mux.HandleFunc("/orders/", s.handleOrderByID)
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		p := r.URL.Path
 		if p == "/" || p == "/ui" || p == "/ui/" {
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -29,6 +47,7 @@ func (s *Server) Handler() http.Handler {
 		}
 		mux.ServeHTTP(w, r)
 	})
+	return s.Audit.Wrap(s.Auth.Wrap(s.Idempotency.Wrap(h)))
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -59,6 +78,7 @@ func (s *Server) handleOrders(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.Audit.Record(r, "order.create", "order", created.ID, nil, created)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(created)
@@ -100,20 +120,24 @@ func (s *Server) handleOrderByID(w http.ResponseWriter, r *http.Request) {
 			if body.Priority <= 0 {
 				body.Priority = 1
 			}
+			before := s.Service.GetOrder(r.Context(), id)
 			o, err := s.Service.SubmitToMES(r.Context(), id, body.ZoneID, body.Priority)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
+			s.Audit.Record(r, "order.submit_to_mes", "order", id, before, o)
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(o)
 			return
 		case "cancel":
+			before := s.Service.GetOrder(r.Context(), id)
 			o, err := s.Service.CancelOrder(r.Context(), id)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
+			s.Audit.Record(r, "order.cancel", "order", id, before, o)
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(o)
 			return
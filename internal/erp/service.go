@@ -4,15 +4,17 @@ import (
 	"context"
 	"fmt"
 	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/client"
 )
 
 type Service struct {
-	Store         *Store
-	MESClient     *MESClient
+	Store         Store
+	MESClient     *client.MES
 	DefaultAreaID string
 }
 
-func NewService(store *Store, mes *MESClient, defaultAreaID string) *Service {
+func NewService(store Store, mes *client.MES, defaultAreaID string) *Service {
 	if defaultAreaID == "" {
 		defaultAreaID = "area-1"
 	}
@@ -29,7 +31,7 @@ func (s *Service) CreateOrder(ctx context.Context, o *Order) (*Order, error) {
 	if o.Quantity <= 0 {
 		return nil, fmt.Errorf("quantity must be positive")
 	}
-	return s.Store.CreateOrder(o), nil
+	return s.Store.CreateOrder(o)
 }
 
 func (s *Service) GetOrder(ctx context.Context, id string) *Order {
@@ -51,15 +53,28 @@ func (s *Service) SubmitToMES(ctx context.Context, orderID, zoneID string, prior
 	if o.Status == OrderStatusCancelled {
 		return nil, fmt.Errorf("order is cancelled")
 	}
-	mesID, err := s.MESClient.CreateProductionOrder(ctx, o.OrderRef, o.SKU, o.Quantity, s.DefaultAreaID, zoneID, priority)
+	if priority <= 0 {
+		priority = 1
+	}
+	ctx = client.WithIdempotencyKey(ctx, "erp-order-"+o.ID)
+	po, err := s.MESClient.CreateOrder(ctx, client.ProductionOrderRequest{
+		ERPOrderRef: o.OrderRef,
+		SKU:         o.SKU,
+		Quantity:    o.Quantity,
+		AreaID:      s.DefaultAreaID,
+		ZoneID:      zoneID,
+		Priority:    priority,
+	})
 	if err != nil {
 		return nil, err
 	}
 	now := time.Now().UTC()
 	o.Status = OrderStatusSubmitted
-	o.MESOrderID = mesID
+	o.MESOrderID = po.ID
 	o.SubmittedAt = &now
-	s.Store.UpdateOrder(o)
+	if err := s.Store.UpdateOrder(o); err != nil {
+		return nil, err
+	}
 	return s.Store.GetOrder(orderID), nil
 }
 
@@ -72,6 +87,8 @@ func (s *Service) CancelOrder(ctx context.Context, id string) (*Order, error) {
 		return nil, fmt.Errorf("only draft orders can be cancelled")
 	}
 	o.Status = OrderStatusCancelled
-	s.Store.UpdateOrder(o)
+	if err := s.Store.UpdateOrder(o); err != nil {
+		return nil, err
+	}
 	return s.Store.GetOrder(id), nil
 }
@@ -0,0 +1,95 @@
+package erp
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
+)
+
+// sqlMigrations is the ERP schema, one entry per version; only ever append (see storage.Migrate).
+var sqlMigrations = []string{`
+CREATE TABLE erp_orders (
+	id     TEXT PRIMARY KEY,
+	status TEXT NOT NULL,
+	data   TEXT NOT NULL
+);
+CREATE INDEX erp_orders_status ON erp_orders (status);`,
+}
+
+// SQLStore keeps ERP orders in a database, each as JSON with its status in a column to filter on.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore migrates db to the current ERP schema and returns a store on it.
+func NewSQLStore(db *sql.DB) (*SQLStore, error) {
+	if err := storage.Migrate(db, "erp", sqlMigrations); err != nil {
+		return nil, err
+	}
+	return &SQLStore{db: db}, nil
+}
+
+func (s *SQLStore) CreateOrder(o *Order) (*Order, error) {
+	err := storage.InTx(s.db, func(tx *sql.Tx) error {
+		if o.ID == "" {
+			n, err := storage.NextSeq(tx, "erp_orders")
+			if err != nil {
+				return err
+			}
+			o.ID = seqID("erp", n)
+		}
+		initOrder(o)
+		data, err := json.Marshal(o)
+		if err != nil {
+			return err
+		}
+		return storage.Insert(tx, `INSERT INTO erp_orders (id, status, data) VALUES (?, ?, ?)`, o.ID, string(o.Status), string(data))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store order: %w", err)
+	}
+	cp := *o
+	return &cp, nil
+}
+
+func (s *SQLStore) GetOrder(id string) *Order {
+	var o Order
+	ok, err := storage.GetJSON(s.db, &o, `SELECT data FROM erp_orders WHERE id = ?`, id)
+	if err != nil {
+		log.Printf("erp: store: get order %s: %v", id, err)
+	}
+	if !ok || err != nil {
+		return nil
+	}
+	return &o
+}
+
+func (s *SQLStore) UpdateOrder(o *Order) error {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	if err := storage.ExecOne(s.db, `UPDATE erp_orders SET status = ?, data = ? WHERE id = ?`, string(o.Status), string(data), o.ID); err != nil {
+		return fmt.Errorf("order %s: %w", o.ID, err)
+	}
+	return nil
+}
+
+func (s *SQLStore) ListOrders(status OrderStatus) []Order {
+	var out []Order
+	err := storage.EachJSON(s.db, func(data []byte) error {
+		var o Order
+		if err := json.Unmarshal(data, &o); err != nil {
+			return err
+		}
+		out = append(out, o)
+		return nil
+	}, `SELECT data FROM erp_orders WHERE ? = '' OR status = ? ORDER BY rowid`, string(status), string(status))
+	if err != nil {
+		log.Printf("erp: store: list orders: %v", err)
+	}
+	return out
+}
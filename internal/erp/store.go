@@ -1,42 +1,74 @@
 package erp
 
 import (
+	"database/sql"
 	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
-type Store struct {
+// Store holds ERP orders: MemoryStore in memory, SQLStore in a database (see pkg/storage).
+// Reads log a database failure and report nothing found; writes return it, creates with a taken ID
+// storage.ErrExists and updates of missing orders storage.ErrNotFound.
+type Store interface {
+	CreateOrder(o *Order) (*Order, error)
+	GetOrder(id string) *Order
+	UpdateOrder(o *Order) error
+	ListOrders(status OrderStatus) []Order
+}
+
+// NewStore returns an SQLStore on db, or a MemoryStore if db is nil.
+func NewStore(db *sql.DB) (Store, error) {
+	if db == nil {
+		return NewMemoryStore(), nil
+	}
+	s, err := NewSQLStore(db)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func initOrder(o *Order) {
+	if o.CreatedAt.IsZero() {
+		o.CreatedAt = time.Now().UTC()
+	}
+	if o.Status == "" {
+		o.Status = OrderStatusDraft
+	}
+}
+
+type MemoryStore struct {
 	mu     sync.RWMutex
 	seq    atomic.Uint64
 	orders map[string]*Order
 }
 
-func NewStore() *Store {
-	return &Store{orders: make(map[string]*Order)}
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{orders: make(map[string]*Order)}
 }
 
 func seqID(prefix string, n uint64) string { return prefix + "-" + fmt.Sprintf("%d", n) }
 
-func (s *Store) CreateOrder(o *Order) *Order {
+func (s *MemoryStore) CreateOrder(o *Order) (*Order, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if o.ID == "" {
 		o.ID = seqID("erp", s.seq.Add(1))
 	}
-	if o.CreatedAt.IsZero() {
-		o.CreatedAt = time.Now().UTC()
-	}
-	if o.Status == "" {
-		o.Status = OrderStatusDraft
+	if _, ok := s.orders[o.ID]; ok {
+		return nil, fmt.Errorf("store order: %w", storage.ErrExists)
 	}
+	initOrder(o)
 	cp := *o
 	s.orders[cp.ID] = &cp
-	return &cp
+	return &cp, nil
 }
 
-func (s *Store) GetOrder(id string) *Order {
+func (s *MemoryStore) GetOrder(id string) *Order {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	o, ok := s.orders[id]
@@ -47,18 +79,18 @@ func (s *Store) GetOrder(id string) *Order {
 	return &cp
 }
 
-func (s *Store) UpdateOrder(o *Order) bool {
+func (s *MemoryStore) UpdateOrder(o *Order) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, ok := s.orders[o.ID]; !ok {
-		return false
+		return fmt.Errorf("order %s: %w", o.ID, storage.ErrNotFound)
 	}
 	cp := *o
 	s.orders[o.ID] = &cp
-	return true
+	return nil
 }
 
-func (s *Store) ListOrders(status OrderStatus) []Order {
+func (s *MemoryStore) ListOrders(status OrderStatus) []Order {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var out []Order
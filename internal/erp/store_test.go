@@ -0,0 +1,84 @@
+package erp
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
+)
+
+// testStores returns a MemoryStore and an SQLStore on a new SQLite file, to run the same checks on both.
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	db, err := storage.Open(storage.Config{Backend: storage.BackendSQLite, Path: filepath.Join(t.TempDir(), "erp.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s, err := NewSQLStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Store{"memory": NewMemoryStore(), "sql": s}
+}
+
+// ids returns the sorted IDs of a list, which the memory store returns in no particular order.
+func ids[T any](items []T, id func(T) string) string {
+	var out []string
+	for _, it := range items {
+		out = append(out, id(it))
+	}
+	sort.Strings(out)
+	return strings.Join(out, " ")
+}
+
+func TestStore(t *testing.T) {
+	orderID := func(o Order) string { return o.ID }
+	for name, s := range testStores(t) {
+		o, err := s.CreateOrder(&Order{OrderRef: "PO-1", SKU: "widget", Quantity: 10})
+		if err != nil || o.ID != "erp-1" || o.Status != OrderStatusDraft || o.CreatedAt.IsZero() {
+			t.Fatalf("%s: CreateOrder = %+v, %v", name, o, err)
+		}
+		if _, err := s.CreateOrder(&Order{ID: "erp-x", OrderRef: "PO-2", SKU: "gadget", Quantity: 1}); err != nil {
+			t.Fatalf("%s: CreateOrder with ID: %v", name, err)
+		}
+		if _, err := s.CreateOrder(&Order{ID: "erp-1", OrderRef: "PO-3"}); !errors.Is(err, storage.ErrExists) {
+			t.Errorf("%s: CreateOrder with a taken ID = %v, want ErrExists", name, err)
+		}
+		if got := s.GetOrder("erp-1"); got == nil || got.OrderRef != "PO-1" || !got.CreatedAt.Equal(o.CreatedAt) {
+			t.Errorf("%s: GetOrder = %+v, want the first order unchanged", name, got)
+		}
+		if got := s.GetOrder("erp-9"); got != nil {
+			t.Errorf("%s: GetOrder of a missing order = %+v", name, got)
+		}
+
+		o.Status = OrderStatusSubmitted
+		o.MESOrderID = "ord-1"
+		if err := s.UpdateOrder(o); err != nil {
+			t.Fatalf("%s: UpdateOrder: %v", name, err)
+		}
+		o.MESOrderID = "changed" // the store keeps its own copy
+		if got := s.GetOrder("erp-1"); got.Status != OrderStatusSubmitted || got.MESOrderID != "ord-1" {
+			t.Errorf("%s: after UpdateOrder GetOrder = %+v", name, got)
+		}
+		if err := s.UpdateOrder(&Order{ID: "erp-9"}); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s: UpdateOrder of a missing order = %v, want ErrNotFound", name, err)
+		}
+
+		for _, tc := range []struct {
+			status OrderStatus
+			want   string
+		}{
+			{"", "erp-1 erp-x"},
+			{OrderStatusSubmitted, "erp-1"},
+			{OrderStatusCancelled, ""},
+		} {
+			if got := ids(s.ListOrders(tc.status), orderID); got != tc.want {
+				t.Errorf("%s: ListOrders(%q) = %s, want %s", name, tc.status, got, tc.want)
+			}
+		}
+	}
+}
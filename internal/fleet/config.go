@@ -1,9 +1,15 @@
 package fleet
 
 import (
+	"fmt"
+	"net"
 	"os"
 
-	"gopkg.in/yaml.v3"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
 )
 
 // Config holds fleet layer configuration.
@@ -11,16 +17,23 @@ type Config struct {
 	Fleet     FleetConfig     `yaml:"fleet"`
 	Messaging MessagingConfig `yaml:"messaging"`
 	State     StateConfig     `yaml:"state"`
+	Auth      auth.Config     `yaml:"auth"` // API keys and JWT for this service's API; none configured leaves it open
+	Audit     audit.Config    `yaml:"audit"` // tamper-evident log of API actions; env AUDIT_LOG overrides the path
+	Idempotency idempotency.Config `yaml:"idempotency"` // replays retried POSTs with the same Idempotency-Key; env IDEMPOTENCY_TTL overrides the TTL
 }
 
 type FleetConfig struct {
 	SchedulerWorkers int    `yaml:"scheduler_workers"`
 	APIListen        string `yaml:"api_listen"`
+	FacilityMap      string `yaml:"facility_map"` // map file (JSON or GeoJSON); env FACILITY_MAP overrides
+	ModelRegistry    string `yaml:"model_registry"` // robot models and capabilities (YAML); env MODEL_REGISTRY overrides
 }
 
 type MessagingConfig struct {
 	Broker      string `yaml:"broker"`
 	TopicPrefix string `yaml:"topic_prefix"`
+
+	messaging.ConnOptions `yaml:",inline"` // NATS TLS and credentials
 }
 
 type StateConfig struct {
@@ -46,16 +59,33 @@ func LoadConfig(path string) (*Config, error) {
 		}
 	}
 	if path != "" {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return nil, err
-		}
-		if err := yaml.Unmarshal(data, cfg); err != nil {
+		if err := config.Load(path, cfg); err != nil {
 			return nil, err
 		}
 	}
+	if p := os.Getenv("FACILITY_MAP"); p != "" {
+		cfg.Fleet.FacilityMap = p
+	}
+	if p := os.Getenv("MODEL_REGISTRY"); p != "" {
+		cfg.Fleet.ModelRegistry = p
+	}
 	if cfg.Fleet.APIListen == "" {
 		cfg.Fleet.APIListen = ":8080"
 	}
+	cfg.Auth.ApplyEnv()
+	cfg.Audit.ApplyEnv()
+	cfg.Idempotency.ApplyEnv()
+	cfg.Messaging.ApplyEnv()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
+
+// Validate checks the API listen address.
+func (c *Config) Validate() error {
+	if _, _, err := net.SplitHostPort(c.Fleet.APIListen); err != nil {
+		return fmt.Errorf("fleet.api_listen: %w", err)
+	}
+	return nil
+}
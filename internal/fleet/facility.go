@@ -0,0 +1,142 @@
+package fleet
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/facility"
+)
+
+// maxMapBytes bounds PUT /map bodies.
+const maxMapBytes = 32 << 20
+
+// FacilityMap holds the current facility map and notifies subscribers (e.g. zone controllers in the same
+// process) when it is replaced.
+type FacilityMap struct {
+	mu       sync.RWMutex
+	m        *facility.Map
+	onUpdate []func(*facility.Map)
+}
+
+// NewFacilityMap creates a store holding m (may be nil until a map is uploaded).
+func NewFacilityMap(m *facility.Map) *FacilityMap {
+	return &FacilityMap{m: m}
+}
+
+// Get returns the current map or nil. Maps are replaced, never modified, so callers may keep it.
+func (f *FacilityMap) Get() *facility.Map {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.m
+}
+
+// Set replaces the map and calls the OnUpdate callbacks.
+func (f *FacilityMap) Set(m *facility.Map) {
+	f.mu.Lock()
+	f.m = m
+	callbacks := append([]func(*facility.Map){}, f.onUpdate...)
+	f.mu.Unlock()
+	for _, cb := range callbacks {
+		cb(m)
+	}
+}
+
+// OnUpdate registers fn to be called with each new map.
+func (f *FacilityMap) OnUpdate(fn func(*facility.Map)) {
+	f.mu.Lock()
+	f.onUpdate = append(f.onUpdate, fn)
+	f.mu.Unlock()
+}
+
+// handleMap serves GET /map (native JSON, or GeoJSON with ?format=geojson) and PUT /map (either format).
+func (s *Server) handleMap(w http.ResponseWriter, r *http.Request) {
+	if s.Map == nil {
+		http.Error(w, "facility map not configured", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		m := s.Map.Get()
+		if m == nil {
+			http.Error(w, "no facility map loaded", http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("format") == "geojson" {
+			data, err := m.GeoJSON()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/geo+json")
+			w.Write(data)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(m)
+	case http.MethodPut:
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMapBytes))
+		if err != nil {
+			http.Error(w, "read body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		m, err := facility.Parse(data)
+		if err != nil {
+			http.Error(w, "invalid map: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		before := s.Map.Get()
+		s.Map.Set(m)
+		s.Audit.Record(r, "map.replace", "map", m.ID, before, m)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok":    true,
+			"id":    m.ID,
+			"nodes": len(m.Nodes),
+			"edges": len(m.Edges),
+			"zones": len(m.Zones),
+		})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleMapRoute serves GET /map/route?from=x,y&to=x,y (or from_node / to_node): the shortest route and its
+// travel distance.
+func (s *Server) handleMapRoute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var m *facility.Map
+	if s.Map != nil {
+		m = s.Map.Get()
+	}
+	if m == nil {
+		http.Error(w, "no facility map loaded", http.StatusNotFound)
+		return
+	}
+	q := r.URL.Query()
+	endpoint := func(name string) (api.Point, bool) {
+		if id := q.Get(name + "_node"); id != "" {
+			n, ok := m.Node(id)
+			return n.Point(), ok
+		}
+		return api.ParsePoint(q.Get(name))
+	}
+	from, okFrom := endpoint("from")
+	to, okTo := endpoint("to")
+	if !okFrom || !okTo {
+		http.Error(w, "from and to required as x,y or from_node / to_node as node IDs", http.StatusBadRequest)
+		return
+	}
+	route, ok := m.Route(from, to)
+	if !ok {
+		http.Error(w, "no route", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(route)
+}
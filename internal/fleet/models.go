@@ -0,0 +1,47 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// handleModels serves GET /models: the robot models and their capabilities.
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.Models == nil {
+		http.Error(w, "model registry not configured", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Models)
+}
+
+// checkRequirements returns why an order with req cannot be done in area, or "" if it can or cannot be told
+// yet: no model in the registry meets it, or the area reported its robot models and none meets it.
+func (s *Server) checkRequirements(area api.AreaID, req *api.Requirements) string {
+	if req.IsZero() {
+		return ""
+	}
+	if s.Models != nil && len(s.Models.Capable(req)) == 0 {
+		return "no robot model in the registry meets the requirements"
+	}
+	if s.State == nil {
+		return ""
+	}
+	sum := s.State.GetArea(area)
+	if sum == nil || len(sum.Models) == 0 || api.CanServe(sum.Models, req) {
+		return ""
+	}
+	why := make([]string, len(sum.Models))
+	for i, m := range sum.Models {
+		why[i] = fmt.Sprintf("%s: %s", m.ModelID, req.Unmet(m.ModelID, m.Capabilities))
+	}
+	return fmt.Sprintf("no robot in %s meets the requirements (%s)", area, strings.Join(why, "; "))
+}
@@ -0,0 +1,71 @@
+package fleet
+
+import (
+	"net/http"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/facility"
+	"github.com/robotfleetos/robotfleetos/pkg/openapi"
+	"github.com/robotfleetos/robotfleetos/pkg/robotmodel"
+)
+
+// safetyBroadcastResponse is the body POST /safety/stop_all and /safety/reset_all answer with.
+var safetyBroadcastResponse = openapi.Object{"ok": true, "type": "", "order_id": "", "area_id": "", "zone_id": ""}
+
+// OpenAPI is the fleet API's OpenAPI document, served at GET /openapi.json.
+var OpenAPI = &openapi.API{
+	Title:       "RobotFleetOS Fleet API",
+	Description: "Work orders for areas, fleet state, safety broadcasts and events, the facility map and robot models.",
+	Rules:       AuthRules,
+	Idempotent:  true,
+	Ops: openapi.Join(
+		openapi.Dashboard("/", "/ui", "/maintenance", "/ui/maintenance"),
+		[]openapi.Op{
+			{Method: http.MethodPost, Path: "/work_orders", Summary: "Submit a work order to an area",
+				Body: CreateWorkOrderRequest{}, Status: http.StatusCreated, Response: CreateWorkOrderResponse{},
+				Errors:  []int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+				Example: CreateWorkOrderRequest{AreaID: "area-1", Priority: 1, Payload: `{"task":"pick"}`}},
+			{Method: http.MethodGet, Path: "/work_orders", Summary: "Recent work orders, newest first",
+				Response: openapi.Object{"work_orders": []RecentWorkOrderEntry{}}},
+			{Method: http.MethodPost, Path: "/firmware/simulate", Summary: "Submit a simulated firmware campaign to area-1",
+				Body:     openapi.Object{"seed_busy": 0, "policy": (*api.FirmwarePolicy)(nil), "deadline": ""},
+				Status:   http.StatusCreated,
+				Response: openapi.Object{"ok": true, "message": "", "order_id": "", "target": "", "seed_busy": 0},
+				Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+				Example:  openapi.Object{}},
+			{Method: http.MethodGet, Path: "/state", Summary: "Area summaries and the total number of robots",
+				Response: openapi.Object{"areas": []api.AreaSummary{}, "total_robots": 0}},
+			{Method: http.MethodGet, Path: "/state/areas", Summary: "Area summaries",
+				Response: openapi.Object{"areas": []api.AreaSummary{}}},
+			{Method: http.MethodPost, Path: "/safety/stop_all", Summary: "E-stop every robot of an area or zone",
+				Body: SafetyBroadcastRequest{}, Status: http.StatusAccepted, Response: safetyBroadcastResponse,
+				Errors:  []int{http.StatusBadRequest, http.StatusInternalServerError},
+				Example: SafetyBroadcastRequest{AreaID: "area-1", Reason: "drill"}},
+			{Method: http.MethodPost, Path: "/safety/reset_all", Summary: "Reset the e-stop of every robot of an area or zone",
+				Body: SafetyBroadcastRequest{}, Status: http.StatusAccepted, Response: safetyBroadcastResponse,
+				Errors:  []int{http.StatusBadRequest, http.StatusInternalServerError},
+				Example: SafetyBroadcastRequest{AreaID: "area-1"}},
+			{Method: http.MethodGet, Path: "/safety/events", Summary: "Recent edge safety events",
+				Query: []string{"robot_id", "zone_id"}, Response: openapi.Object{"events": []api.SafetyEvent{}}},
+			{Method: http.MethodGet, Path: "/security/events", Summary: "Recent robot commands edges rejected",
+				Query:    []string{"robot_id", "zone_id", "reason"},
+				Response: openapi.Object{"events": []api.SecurityEvent{}}},
+			{Method: http.MethodPut, Path: "/map", Summary: "Replace the facility map (native JSON or GeoJSON)",
+				Body:     facility.Map{},
+				Response: openapi.Object{"ok": true, "id": "", "nodes": 0, "edges": 0, "zones": 0},
+				Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+				Example: facility.Map{ID: "floor-1",
+					Nodes: []facility.Node{{ID: "a", X: 0, Y: 0}, {ID: "b", X: 10, Y: 0}},
+					Edges: []facility.Edge{{From: "a", To: "b"}}}},
+			{Method: http.MethodGet, Path: "/map", Summary: "The facility map; GeoJSON with format=geojson",
+				Query: []string{"format"}, Response: facility.Map{},
+				Errors: []int{http.StatusNotFound, http.StatusInternalServerError}},
+			{Method: http.MethodGet, Path: "/map/route", Summary: "Shortest route between two points (x,y) or nodes",
+				Query: []string{"from", "to", "from_node", "to_node"}, Response: facility.Route{},
+				Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+			{Method: http.MethodGet, Path: "/models", Summary: "Robot models and their capabilities",
+				Response: robotmodel.Registry{}, Errors: []int{http.StatusNotFound}},
+		},
+		openapi.Common("fleet"),
+	),
+}
@@ -0,0 +1,60 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+// SafetyLog keeps the most recent edge safety events (transitions and rejected commands) for the API.
+type SafetyLog struct {
+	mu     sync.RWMutex
+	events []api.SafetyEvent
+	max    int
+}
+
+// NewSafetyLog returns a log holding up to max events (default 1000).
+func NewSafetyLog(max int) *SafetyLog {
+	if max <= 0 {
+		max = 1000
+	}
+	return &SafetyLog{max: max}
+}
+
+// Run subscribes to safety events. Registers the handler and returns.
+func (l *SafetyLog) Run(ctx context.Context, bus messaging.Subscriber) error {
+	return bus.Subscribe(ctx, messaging.TopicSafetyEvents, func(key string, value []byte) error {
+		var ev api.SafetyEvent
+		if err := json.Unmarshal(value, &ev); err != nil {
+			return err
+		}
+		l.add(ev)
+		return nil
+	})
+}
+
+func (l *SafetyLog) add(ev api.SafetyEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, ev)
+	if len(l.events) > l.max {
+		l.events = l.events[len(l.events)-l.max:]
+	}
+}
+
+// List returns events newest first, optionally filtered by robot and zone.
+func (l *SafetyLog) List(robotID api.RobotID, zoneID api.ZoneID) []api.SafetyEvent {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := make([]api.SafetyEvent, 0, len(l.events))
+	for i := len(l.events) - 1; i >= 0; i-- {
+		ev := l.events[i]
+		if (robotID == "" || ev.RobotID == robotID) && (zoneID == "" || ev.ZoneID == zoneID) {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+// SecurityLog keeps the most recent edge security events (robot commands that failed signature, freshness
+// or replay checks) for the API.
+type SecurityLog struct {
+	mu     sync.RWMutex
+	events []api.SecurityEvent
+	max    int
+}
+
+// NewSecurityLog returns a log holding up to max events (default 1000).
+func NewSecurityLog(max int) *SecurityLog {
+	if max <= 0 {
+		max = 1000
+	}
+	return &SecurityLog{max: max}
+}
+
+// Run subscribes to security events. Registers the handler and returns.
+func (l *SecurityLog) Run(ctx context.Context, bus messaging.Subscriber) error {
+	return bus.Subscribe(ctx, messaging.TopicSecurityEvents, func(key string, value []byte) error {
+		var ev api.SecurityEvent
+		if err := json.Unmarshal(value, &ev); err != nil {
+			return err
+		}
+		l.add(ev)
+		return nil
+	})
+}
+
+func (l *SecurityLog) add(ev api.SecurityEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, ev)
+	if len(l.events) > l.max {
+		l.events = l.events[len(l.events)-l.max:]
+	}
+}
+
+// List returns events newest first, optionally filtered by robot, zone and reason.
+func (l *SecurityLog) List(robotID api.RobotID, zoneID api.ZoneID, reason string) []api.SecurityEvent {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := make([]api.SecurityEvent, 0, len(l.events))
+	for i := len(l.events) - 1; i >= 0; i-- {
+		ev := l.events[i]
+		if (robotID == "" || ev.RobotID == robotID) && (zoneID == "" || ev.ZoneID == zoneID) && (reason == "" || ev.Reason == reason) {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
@@ -9,6 +9,10 @@ import (
 	"time"
 
 	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
+	"github.com/robotfleetos/robotfleetos/pkg/robotmodel"
 )
 
 //go:embed static/index.html
@@ -30,17 +34,38 @@ type RecentWorkOrderEntry struct {
 type Server struct {
 	Scheduler     *Scheduler
 	State         *GlobalState
+	Safety        *SafetyLog // optional: recent edge safety events for GET /safety/events
+	Security      *SecurityLog // optional: recent rejected robot commands for GET /security/events
+	Map           *FacilityMap // optional: facility map for GET/PUT /map and GET /map/route
+	Models        *robotmodel.Registry // optional: robot models for GET /models and checking order requirements
+	Auth          *auth.Authenticator // optional: API key/JWT authentication and role checks; nil leaves the API open
+	Audit         *audit.Log          // optional: records every change made through the API; GET /audit
+	Idempotency   *idempotency.Store  // optional: replays the response to a retried POST with the same Idempotency-Key
 	recentMu      sync.RWMutex
 	recentOrders  []RecentWorkOrderEntry
 	maxRecent     int
 }
 
+// AuthRules are the fleet API's route permissions; see auth.Rule.
+var AuthRules = auth.Join(
+	audit.AuthRules,
+	auth.Public("/", "/ui", "/maintenance", "/ui/maintenance", "/health", "/openapi.json"),
+	[]auth.Rule{
+		{Method: http.MethodPost, Path: "/work_orders", Roles: []string{auth.RolePlanner, auth.RoleOperator}},
+		{Method: http.MethodPost, Path: "/firmware/simulate", Roles: []string{auth.RoleMaintenance}},
+		{Method: http.MethodPost, Path: "/safety/stop_all", Roles: []string{auth.RoleOperator, auth.RoleMaintenance, auth.RoleQuality}},
+		{Method: http.MethodPost, Path: "/safety/reset_all", Roles: []string{auth.RoleOperator, auth.RoleMaintenance}},
+	},
+)
+
 // CreateWorkOrderRequest is the JSON body for POST /work_orders.
 type CreateWorkOrderRequest struct {
 	AreaID   string  `json:"area_id"`
 	Priority int     `json:"priority"`
 	Payload  string  `json:"payload"` // raw JSON or text; stored as UTF-8 bytes
 	Deadline *string `json:"deadline,omitempty"` // RFC3339
+	Requirements *api.Requirements `json:"requirements,omitempty"` // capabilities the robot doing it needs
+	Steps        []api.TaskStep    `json:"steps,omitempty"`        // task graph run by the area; see api.TaskStep
 }
 
 // CreateWorkOrderResponse is the JSON response for POST /work_orders.
@@ -55,11 +80,21 @@ type CreateWorkOrderResponse struct {
 func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", s.handleHealth)
+	mux.Handle("/openapi.json", OpenAPI)
+	mux.Handle("/audit", s.Audit)
+	mux.Handle("/audit/", s.Audit)
 	mux.HandleFunc("/work_orders", s.handleWorkOrders)
 	mux.HandleFunc("/firmware/simulate", s.handleFirmwareSimulate)
 	mux.HandleFunc("/state", s.handleGetState)
 	mux.HandleFunc("/state/areas", s.handleGetAreas)
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/safety/stop_all", s.handleSafetyBroadcast(api.ZoneTaskTypeStopAll))
+	mux.HandleFunc("/safety/reset_all", s.handleSafetyBroadcast(api.ZoneTaskTypeResetAll))
+	mux.HandleFunc("/safety/events", s.handleSafetyEvents)
+	mux.HandleFunc("/security/events", s.handleSecurityEvents)
+	mux.HandleFunc("/map", s.handleMap)
+	mux.HandleFunc("/map/route", s.handleMapRoute)
+	mux.HandleFunc("/models", s.handleModels)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		p := r.URL.Path
 		if p == "/" || p == "/ui" || p == "/ui/" {
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -75,18 +110,32 @@ func (s *Server) Handler() http.Handler {
 		}
 		mux.ServeHTTP(w, r)
 	})
+	return s.Audit.Wrap(s.Auth.Wrap(s.Idempotency.Wrap(h)))
 }
 
 // RegisterRoutes mounts fleet API routes on mux (for backwards compatibility).
 // Prefer using Handler() so the dashboard is served correctly at "/" and "/ui".
+// Routes mounted this way are not behind s.Auth.
 func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/health", s.handleHealth)
+	mux.Handle("/openapi.json", OpenAPI)
 	mux.HandleFunc("/work_orders", s.handleWorkOrders)
 	mux.HandleFunc("/state", s.handleGetState)
 	mux.HandleFunc("/state/areas", s.handleGetAreas)
+	mux.HandleFunc("/safety/stop_all", s.handleSafetyBroadcast(api.ZoneTaskTypeStopAll))
+	mux.HandleFunc("/safety/reset_all", s.handleSafetyBroadcast(api.ZoneTaskTypeResetAll))
+	mux.HandleFunc("/safety/events", s.handleSafetyEvents)
+	mux.HandleFunc("/security/events", s.handleSecurityEvents)
+	mux.HandleFunc("/map", s.handleMap)
+	mux.HandleFunc("/map/route", s.handleMapRoute)
+	mux.HandleFunc("/models", s.handleModels)
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok", "layer": "fleet"})
 }
@@ -205,10 +254,32 @@ func (s *Server) handleCreateWorkOrder(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "area_id required", http.StatusBadRequest)
 		return
 	}
+	if err := req.Requirements.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Steps) > 0 {
+		if err := api.ValidateSteps(req.Steps); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if why := s.checkRequirements(api.AreaID(req.AreaID), req.Requirements); why != "" {
+		http.Error(w, why, http.StatusUnprocessableEntity)
+		return
+	}
+	for _, step := range req.Steps {
+		if why := s.checkRequirements(api.AreaID(req.AreaID), step.Requirements); why != "" {
+			http.Error(w, "step "+step.ID+": "+why, http.StatusUnprocessableEntity)
+			return
+		}
+	}
 	order := &api.WorkOrder{
-		AreaID:   api.AreaID(req.AreaID),
-		Priority: req.Priority,
-		Payload:  []byte(req.Payload),
+		AreaID:       api.AreaID(req.AreaID),
+		Priority:     req.Priority,
+		Payload:      []byte(req.Payload),
+		Requirements: req.Requirements,
+		Steps:        req.Steps,
 	}
 	if req.Deadline != nil {
 		t, err := time.Parse(time.RFC3339, *req.Deadline)
@@ -223,6 +294,7 @@ func (s *Server) handleCreateWorkOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	s.addRecent(order)
+	s.Audit.Record(r, "work_order.create", "work_order", string(order.ID), nil, order)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(CreateWorkOrderResponse{
@@ -238,9 +310,17 @@ func (s *Server) handleFirmwareSimulate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	var body struct {
-		SeedBusy int `json:"seed_busy"` // optional: submit this many work orders first so that many robots are BUSY and will defer firmware until done
+		SeedBusy int                 `json:"seed_busy"`          // optional: submit this many work orders first so that many robots are BUSY and will defer firmware until done
+		Policy   *api.FirmwarePolicy `json:"policy,omitempty"`   // optional: maintenance windows, min battery, charging only, holdback
+		Deadline string              `json:"deadline,omitempty"` // optional RFC3339: max deferral, after which the policy no longer holds the update back
 	}
 	_ = json.NewDecoder(r.Body).Decode(&body)
+	if body.Deadline != "" {
+		if _, err := time.Parse(time.RFC3339, body.Deadline); err != nil {
+			http.Error(w, "invalid deadline: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
 	// Optionally seed work orders so some robots are BUSY and will defer firmware until they finish.
 	if body.SeedBusy > 0 {
@@ -255,7 +335,7 @@ func (s *Server) handleFirmwareSimulate(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Firmware campaign: zone will broadcast to all robots; idle robots update immediately, busy ones defer until work complete.
-	payload := map[string]string{
+	payload := map[string]interface{}{
 		"type":             "firmware_update",
 		"campaign_id":      "sim-" + time.Now().Format("20060102150405"),
 		"version":          "2.0.0",
@@ -264,6 +344,12 @@ func (s *Server) handleFirmwareSimulate(w http.ResponseWriter, r *http.Request)
 		"checksum_sha256":  "simulated",
 		"rollback_version": "1.0.0",
 	}
+	if body.Policy != nil {
+		payload["policy"] = body.Policy
+	}
+	if body.Deadline != "" {
+		payload["deadline"] = body.Deadline
+	}
 	payloadBytes, _ := json.Marshal(payload)
 	order := &api.WorkOrder{
 		AreaID:   api.AreaID("area-1"),
@@ -275,6 +361,7 @@ func (s *Server) handleFirmwareSimulate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	s.addRecent(order)
+	s.Audit.Record(r, "firmware.simulate", "work_order", string(order.ID), nil, order)
 	resp := map[string]interface{}{
 		"ok":       true,
 		"message":  "firmware campaign submitted; zone broadcasts to all robots. Busy robots defer update until work complete.",
@@ -313,3 +400,82 @@ func (s *Server) handleGetAreas(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{"areas": areas})
 }
+
+// SafetyBroadcastRequest is the JSON body for POST /safety/stop_all and /safety/reset_all.
+type SafetyBroadcastRequest struct {
+	AreaID   string `json:"area_id"`
+	ZoneID   string `json:"zone_id,omitempty"` // empty = every zone in the area
+	Reason   string `json:"reason,omitempty"`
+	Operator string `json:"operator,omitempty"`
+}
+
+// handleSafetyBroadcast submits a stop_all / reset_all work order. The area fans it out to its zones, each zone
+// broadcasts ESTOP / RESET to its robots and every edge enforces it locally.
+func (s *Server) handleSafetyBroadcast(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req SafetyBroadcastRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.AreaID == "" {
+			http.Error(w, "area_id required", http.StatusBadRequest)
+			return
+		}
+		payload, _ := json.Marshal(map[string]string{
+			"type":     kind,
+			"zone_id":  req.ZoneID,
+			"reason":   req.Reason,
+			"operator": req.Operator,
+		})
+		order := &api.WorkOrder{AreaID: api.AreaID(req.AreaID), Priority: 0, Payload: payload}
+		if err := s.Scheduler.SubmitWorkOrder(r.Context(), order); err != nil {
+			http.Error(w, "submit failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.addRecent(order)
+		s.Audit.Record(r, "safety."+kind, "work_order", string(order.ID), nil, order)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok":       true,
+			"type":     kind,
+			"order_id": string(order.ID),
+			"area_id":  req.AreaID,
+			"zone_id":  req.ZoneID,
+		})
+	}
+}
+
+func (s *Server) handleSafetyEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	events := []api.SafetyEvent{}
+	if s.Safety != nil {
+		q := r.URL.Query()
+		events = s.Safety.List(api.RobotID(q.Get("robot_id")), api.ZoneID(q.Get("zone_id")))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"events": events})
+}
+
+// handleSecurityEvents lists robot commands edges rejected, filtered by robot_id, zone_id and reason.
+func (s *Server) handleSecurityEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	events := []api.SecurityEvent{}
+	if s.Security != nil {
+		q := r.URL.Query()
+		events = s.Security.List(api.RobotID(q.Get("robot_id")), api.ZoneID(q.Get("zone_id")), q.Get("reason"))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"events": events})
+}
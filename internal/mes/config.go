@@ -3,13 +3,21 @@ package mes
 import (
 	"os"
 
-	"gopkg.in/yaml.v3"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
 // Config holds MES configuration.
 type Config struct {
-	MES   MESConfig   `yaml:"mes"`
-	Fleet FleetConfig `yaml:"fleet"`
+	MES         MESConfig          `yaml:"mes"`
+	Fleet       FleetConfig        `yaml:"fleet"`
+	Auth        auth.Config        `yaml:"auth"`        // API keys and JWT for this service's API; none configured leaves it open
+	Audit       audit.Config       `yaml:"audit"`       // tamper-evident log of API actions; env AUDIT_LOG overrides the path
+	Idempotency idempotency.Config `yaml:"idempotency"` // replays retried POSTs with the same Idempotency-Key; env IDEMPOTENCY_TTL overrides the TTL
+	Storage     storage.Config     `yaml:"storage"`     // memory (default) or sqlite; env STORE_BACKEND and STORE_PATH override
 }
 
 // MESConfig is the MES server configuration.
@@ -20,6 +28,7 @@ type MESConfig struct {
 // FleetConfig is the Fleet API client configuration.
 type FleetConfig struct {
 	APIURL string `yaml:"api_url"`
+	Token  string `yaml:"token"` // API key or JWT sent to the API; env FLEET_API_TOKEN overrides
 }
 
 // LoadConfig reads config from path. If path is empty, uses env and defaults.
@@ -38,11 +47,7 @@ func LoadConfig(path string) (*Config, error) {
 		}
 	}
 	if path != "" {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return nil, err
-		}
-		if err := yaml.Unmarshal(data, cfg); err != nil {
+		if err := config.Load(path, cfg); err != nil {
 			return nil, err
 		}
 	}
@@ -58,5 +63,12 @@ func LoadConfig(path string) (*Config, error) {
 	if cfg.Fleet.APIURL == "" {
 		cfg.Fleet.APIURL = "http://localhost:8080"
 	}
+	if t := os.Getenv("FLEET_API_TOKEN"); t != "" {
+		cfg.Fleet.Token = t
+	}
+	cfg.Auth.ApplyEnv()
+	cfg.Audit.ApplyEnv()
+	cfg.Idempotency.ApplyEnv()
+	cfg.Storage.ApplyEnv()
 	return cfg, nil
 }
@@ -0,0 +1,43 @@
+package mes
+
+import (
+	"net/http"
+
+	"github.com/robotfleetos/robotfleetos/pkg/openapi"
+)
+
+// OpenAPI is the MES API's OpenAPI document, served at GET /openapi.json.
+var OpenAPI = &openapi.API{
+	Title:       "RobotFleetOS MES API",
+	Description: "Production orders: create, release to the fleet as work orders, pause, complete, cancel and report scrap.",
+	Rules:       AuthRules,
+	Idempotent:  true,
+	Ops: openapi.Join(
+		openapi.Dashboard("/", "/ui"),
+		[]openapi.Op{
+			{Method: http.MethodPost, Path: "/orders", Summary: "Create a production order (draft)",
+				Body: CreateOrderRequest{}, Status: http.StatusCreated, Response: ProductionOrder{},
+				Errors:  []int{http.StatusBadRequest},
+				Example: CreateOrderRequest{SKU: "WIDGET-001", Quantity: 10, AreaID: "area-1"}},
+			{Method: http.MethodGet, Path: "/orders", Summary: "List production orders",
+				Query: []string{"status"}, Response: openapi.Object{"orders": []ProductionOrder{}}},
+			{Method: http.MethodGet, Path: "/orders/{id}", Summary: "Get a production order",
+				Response: ProductionOrder{}, Errors: []int{http.StatusNotFound}},
+			{Method: http.MethodPost, Path: "/orders/{id}/release", Summary: "Release an order to the fleet as a work order",
+				Response: ProductionOrder{}, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPost, Path: "/orders/{id}/pause", Summary: "Pause a released order",
+				Response: ProductionOrder{}, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPost, Path: "/orders/{id}/scrap", Summary: "Report scrapped units of an order",
+				Body: ScrapRequest{}, Response: ProductionOrder{}, Errors: []int{http.StatusBadRequest},
+				Example: ScrapRequest{Quantity: 1, Reason: "damaged"}},
+			{Method: http.MethodPost, Path: "/orders/{id}/complete", Summary: "Complete an order",
+				Response: ProductionOrder{}, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPost, Path: "/orders/{id}/cancel", Summary: "Cancel an order",
+				Response: ProductionOrder{}, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPost, Path: "/firmware/trigger", Summary: "Start a firmware update campaign on the fleet",
+				Body: openapi.Object{"seed_busy": 0}, Response: openapi.Object{"ok": true, "message": ""},
+				Errors: []int{http.StatusBadGateway}},
+		},
+		openapi.Common("mes"),
+	),
+}
@@ -5,6 +5,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
 )
 
 //go:embed static/index.html
@@ -13,16 +17,37 @@ var dashboardHTML []byte
 // Server is the MES HTTP API server.
 type Server struct {
 	Service *Service
+	Auth    *auth.Authenticator // optional: API key/JWT authentication and role checks; nil leaves the API open
+	Audit   *audit.Log          // optional: records every change made through the API; GET /audit
+	Idempotency *idempotency.Store // optional: replays the response to a retried POST with the same Idempotency-Key
 }
 
+// AuthRules are the MES API's route permissions; see auth.Rule.
+var AuthRules = auth.Join(
+	audit.AuthRules,
+	auth.Public("/", "/ui", "/health", "/openapi.json"),
+	[]auth.Rule{
+		{Method: http.MethodPost, Path: "/orders", Roles: []string{auth.RolePlanner}},
+		{Method: http.MethodPost, Path: "/orders/*/release", Roles: []string{auth.RolePlanner, auth.RoleOperator}},
+		{Method: http.MethodPost, Path: "/orders/*/pause", Roles: []string{auth.RolePlanner, auth.RoleOperator}},
+		{Method: http.MethodPost, Path: "/orders/*/complete", Roles: []string{auth.RoleOperator}},
+		{Method: http.MethodPost, Path: "/orders/*/cancel", Roles: []string{auth.RolePlanner}},
+		{Method: http.MethodPost, Path: "/orders/*/scrap", Roles: []string{auth.RoleOperator, auth.RoleQuality}},
+		{Method: http.MethodPost, Path: "/firmware/trigger", Roles: []string{auth.RoleMaintenance}},
+	},
+)
+
 // Handler returns the HTTP handler: dashboard at / and /ui, API on /health, /orders, etc.
 func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", s.handleHealth)
+	mux.Handle("/openapi.json", OpenAPI)
+	mux.Handle("/audit", s.Audit)
+	mux.Handle("/audit/", s.Audit)
 	mux.HandleFunc("/firmware/trigger", s.handleFirmwareTrigger)
 	mux.HandleFunc("/orders", s.handleOrders)
 	mux.HandleFunc("/orders/", s.handleOrderByID)
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		p := r.URL.Path
 		if p == "/" || p == "/ui" || p == "/ui/" {
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -32,6 +57,7 @@ func (s *Server) Handler() http.Handler {
 		}
 		mux.ServeHTTP(w, r)
 	})
+	return s.Audit.Wrap(s.Auth.Wrap(s.Idempotency.Wrap(h)))
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -57,6 +83,7 @@ func (s *Server) handleFirmwareTrigger(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadGateway)
 		return
 	}
+	s.Audit.Record(r, "firmware.trigger", "firmware_campaign", "", nil, map[string]interface{}{"seed_busy": body.SeedBusy, "message": msg})
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "message": msg})
 }
@@ -111,6 +138,7 @@ func (s *Server) handleCreateOrder(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	s.Audit.Record(r, "order.create", "order", created.ID, nil, created)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(created)
@@ -173,11 +201,13 @@ func (s *Server) handleReleaseOrder(w http.ResponseWriter, r *http.Request, id s
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	before := s.Service.GetOrder(r.Context(), id)
 	order, err := s.Service.ReleaseOrder(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	s.Audit.Record(r, "order.release", "order", id, before, order)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(order)
 }
@@ -187,11 +217,13 @@ func (s *Server) handlePauseOrder(w http.ResponseWriter, r *http.Request, id str
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	before := s.Service.GetOrder(r.Context(), id)
 	order, err := s.Service.PauseOrder(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	s.Audit.Record(r, "order.pause", "order", id, before, order)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(order)
 }
@@ -201,11 +233,13 @@ func (s *Server) handleCompleteOrder(w http.ResponseWriter, r *http.Request, id
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	before := s.Service.GetOrder(r.Context(), id)
 	order, err := s.Service.CompleteOrder(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	s.Audit.Record(r, "order.complete", "order", id, before, order)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(order)
 }
@@ -215,11 +249,13 @@ func (s *Server) handleCancelOrder(w http.ResponseWriter, r *http.Request, id st
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	before := s.Service.GetOrder(r.Context(), id)
 	order, err := s.Service.CancelOrder(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	s.Audit.Record(r, "order.cancel", "order", id, before, order)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(order)
 }
@@ -240,11 +276,13 @@ func (s *Server) handleReportScrap(w http.ResponseWriter, r *http.Request, id st
 		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	before := s.Service.GetOrder(r.Context(), id)
 	order, err := s.Service.ReportScrap(r.Context(), id, req.Quantity, req.Reason)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	s.Audit.Record(r, "order.scrap", "order", id, before, order)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(order)
 }
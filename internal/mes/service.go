@@ -5,16 +5,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/client"
 )
 
 // Service implements MES business logic: create, release (to Fleet), pause, complete, scrap.
 type Service struct {
-	Store       *Store
-	FleetClient *FleetClient
+	Store       Store
+	FleetClient *client.Fleet
 }
 
 // NewService returns an MES service with the given store and Fleet client.
-func NewService(store *Store, fleetClient *FleetClient) *Service {
+func NewService(store Store, fleetClient *client.Fleet) *Service {
 	return &Service{Store: store, FleetClient: fleetClient}
 }
 
@@ -70,17 +72,23 @@ func (s *Service) ReleaseOrder(ctx context.Context, id string) (*ProductionOrder
 	if err != nil {
 		return nil, err
 	}
-	fleetWOID, err := s.FleetClient.SubmitWorkOrder(ctx, order.AreaID, order.Priority, payloadBytes, nil)
+	// A retried release reuses the key, so Fleet creates one work order per release.
+	ctx = client.WithIdempotencyKey(ctx, fmt.Sprintf("mes-order-%s-%d", order.ID, order.Releases+1))
+	wo, err := s.FleetClient.SubmitWorkOrder(ctx, client.WorkOrderRequest{
+		AreaID:   order.AreaID,
+		Priority: order.Priority,
+		Payload:  string(payloadBytes),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("submit to fleet: %w", err)
 	}
 	now := time.Now().UTC()
-	order.FleetWorkOrderID = fleetWOID
+	order.FleetWorkOrderID = wo.ID
+	order.Releases++
 	order.Status = OrderStatusInProgress
 	order.ReleasedAt = &now
-	ok := s.Store.Update(order)
-	if !ok {
-		return nil, fmt.Errorf("store update failed")
+	if err := s.Store.Update(order); err != nil {
+		return nil, fmt.Errorf("store update failed: %w", err)
 	}
 	return s.Store.Get(id), nil
 }
@@ -95,9 +103,8 @@ func (s *Service) PauseOrder(ctx context.Context, id string) (*ProductionOrder,
 		return nil, fmt.Errorf("order cannot be paused from status %s", order.Status)
 	}
 	order.Status = OrderStatusPaused
-	ok := s.Store.Update(order)
-	if !ok {
-		return nil, fmt.Errorf("store update failed")
+	if err := s.Store.Update(order); err != nil {
+		return nil, fmt.Errorf("store update failed: %w", err)
 	}
 	return s.Store.Get(id), nil
 }
@@ -117,9 +124,8 @@ func (s *Service) CompleteOrder(ctx context.Context, id string) (*ProductionOrde
 	if order.QuantityCompleted == 0 {
 		order.QuantityCompleted = order.Quantity - order.QuantityScrapped
 	}
-	ok := s.Store.Update(order)
-	if !ok {
-		return nil, fmt.Errorf("store update failed")
+	if err := s.Store.Update(order); err != nil {
+		return nil, fmt.Errorf("store update failed: %w", err)
 	}
 	return s.Store.Get(id), nil
 }
@@ -134,16 +140,19 @@ func (s *Service) CancelOrder(ctx context.Context, id string) (*ProductionOrder,
 		return nil, fmt.Errorf("order already terminal: %s", order.Status)
 	}
 	order.Status = OrderStatusCancelled
-	ok := s.Store.Update(order)
-	if !ok {
-		return nil, fmt.Errorf("store update failed")
+	if err := s.Store.Update(order); err != nil {
+		return nil, fmt.Errorf("store update failed: %w", err)
 	}
 	return s.Store.Get(id), nil
 }
 
 // TriggerFirmwareUpdate calls Fleet's firmware simulate endpoint (for use during production).
 func (s *Service) TriggerFirmwareUpdate(ctx context.Context, seedBusy int) (message string, err error) {
-	return s.FleetClient.FirmwareSimulate(ctx, seedBusy)
+	campaign, err := s.FleetClient.FirmwareSimulate(ctx, client.FirmwareRequest{SeedBusy: seedBusy})
+	if err != nil {
+		return "", err
+	}
+	return campaign.Message, nil
 }
 
 // ReportScrap adds a scrap record and updates quantity scrapped.
@@ -167,9 +176,8 @@ func (s *Service) ReportScrap(ctx context.Context, id string, quantity int, reas
 		Reason:     reason,
 		RecordedAt: time.Now().UTC(),
 	})
-	ok := s.Store.Update(order)
-	if !ok {
-		return nil, fmt.Errorf("store update failed")
+	if err := s.Store.Update(order); err != nil {
+		return nil, fmt.Errorf("store update failed: %w", err)
 	}
 	return s.Store.Get(id), nil
 }
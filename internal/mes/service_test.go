@@ -0,0 +1,61 @@
+package mes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/client"
+)
+
+// TestReleaseOrderIdempotencyKey checks that a release sends the same Idempotency-Key on every retry, and a
+// release after a pause sends a new one.
+func TestReleaseOrderIdempotencyKey(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	fleet := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get(client.IdempotencyHeader))
+		first := len(keys) == 1
+		mu.Unlock()
+		if first {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"id": "wo-1", "area_id": "area-1"})
+	}))
+	defer fleet.Close()
+
+	fc := client.NewFleet(fleet.URL)
+	fc.Retry = client.Retry{Attempts: 2, MinDelay: time.Millisecond}
+	svc := NewService(NewMemoryStore(), fc)
+	ctx := context.Background()
+	order, err := svc.CreateOrder(ctx, &ProductionOrder{SKU: "SKU-1", AreaID: "area-1", Quantity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ReleaseOrder(ctx, order.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.PauseOrder(ctx, order.ID); err != nil {
+		t.Fatal(err)
+	}
+	released, err := svc.ReleaseOrder(ctx, order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"mes-order-" + order.ID + "-1", "mes-order-" + order.ID + "-1", "mes-order-" + order.ID + "-2"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("Idempotency-Keys %q, want %q", keys, want)
+	}
+	if released.Releases != 2 || released.FleetWorkOrderID != "wo-1" {
+		t.Errorf("released order %+v", released)
+	}
+}
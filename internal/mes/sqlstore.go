@@ -0,0 +1,99 @@
+package mes
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
+)
+
+// sqlMigrations is the MES schema, one entry per version; only ever append (see storage.Migrate).
+var sqlMigrations = []string{`
+CREATE TABLE mes_orders (
+	id     TEXT PRIMARY KEY,
+	status TEXT NOT NULL,
+	data   TEXT NOT NULL
+);
+CREATE INDEX mes_orders_status ON mes_orders (status);`,
+}
+
+// SQLStore keeps production orders in a database, each as JSON with its status in a column to filter on.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore migrates db to the current MES schema and returns a store on it.
+func NewSQLStore(db *sql.DB) (*SQLStore, error) {
+	if err := storage.Migrate(db, "mes", sqlMigrations); err != nil {
+		return nil, err
+	}
+	return &SQLStore{db: db}, nil
+}
+
+// Create persists a new order (ID generated if empty). Returns the order with ID set.
+func (s *SQLStore) Create(order *ProductionOrder) (*ProductionOrder, error) {
+	err := storage.InTx(s.db, func(tx *sql.Tx) error {
+		if order.ID == "" {
+			n, err := storage.NextSeq(tx, "mes_orders")
+			if err != nil {
+				return err
+			}
+			order.ID = "ord-" + formatSeq(n)
+		}
+		initOrder(order)
+		data, err := json.Marshal(order)
+		if err != nil {
+			return err
+		}
+		return storage.Insert(tx, `INSERT INTO mes_orders (id, status, data) VALUES (?, ?, ?)`, order.ID, string(order.Status), string(data))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store order: %w", err)
+	}
+	cp := *order
+	return &cp, nil
+}
+
+// Get returns an order by ID, or nil if not found.
+func (s *SQLStore) Get(id string) *ProductionOrder {
+	var o ProductionOrder
+	ok, err := storage.GetJSON(s.db, &o, `SELECT data FROM mes_orders WHERE id = ?`, id)
+	if err != nil {
+		log.Printf("mes: store: get order %s: %v", id, err)
+	}
+	if !ok || err != nil {
+		return nil
+	}
+	return &o
+}
+
+// List returns all orders in creation order, optionally filtered by status.
+func (s *SQLStore) List(statusFilter OrderStatus) []ProductionOrder {
+	var out []ProductionOrder
+	err := storage.EachJSON(s.db, func(data []byte) error {
+		var o ProductionOrder
+		if err := json.Unmarshal(data, &o); err != nil {
+			return err
+		}
+		out = append(out, o)
+		return nil
+	}, `SELECT data FROM mes_orders WHERE ? = '' OR status = ? ORDER BY rowid`, string(statusFilter), string(statusFilter))
+	if err != nil {
+		log.Printf("mes: store: list orders: %v", err)
+	}
+	return out
+}
+
+// Update updates an existing order.
+func (s *SQLStore) Update(order *ProductionOrder) error {
+	data, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+	if err := storage.ExecOne(s.db, `UPDATE mes_orders SET status = ?, data = ? WHERE id = ?`, string(order.Status), string(data), order.ID); err != nil {
+		return fmt.Errorf("order %s: %w", order.ID, err)
+	}
+	return nil
+}
@@ -1,38 +1,76 @@
 package mes
 
 import (
+	"database/sql"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
-// Store is an in-memory store for production orders.
-type Store struct {
+// Store holds production orders: MemoryStore in memory, SQLStore in a database (see pkg/storage).
+// Reads log a database failure and report nothing found; writes return it.
+type Store interface {
+	// Create persists a new order (ID generated if empty). Returns the order with ID set; storage.ErrExists
+	// if the ID is taken.
+	Create(order *ProductionOrder) (*ProductionOrder, error)
+	// Get returns an order by ID, or nil if not found.
+	Get(id string) *ProductionOrder
+	// List returns all orders, optionally filtered by status.
+	List(statusFilter OrderStatus) []ProductionOrder
+	// Update updates an existing order; storage.ErrNotFound if there is none.
+	Update(order *ProductionOrder) error
+}
+
+// NewStore returns an SQLStore on db, or a MemoryStore if db is nil (the memory backend).
+func NewStore(db *sql.DB) (Store, error) {
+	if db == nil {
+		return NewMemoryStore(), nil
+	}
+	s, err := NewSQLStore(db)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// initOrder sets the defaults of a new order.
+func initOrder(order *ProductionOrder) {
+	if order.CreatedAt.IsZero() {
+		order.CreatedAt = time.Now().UTC()
+	}
+	if order.Status == "" {
+		order.Status = OrderStatusDraft
+	}
+	order.QuantityCompleted = 0
+	order.QuantityScrapped = 0
+}
+
+// MemoryStore is an in-memory store for production orders.
+type MemoryStore struct {
 	mu     sync.RWMutex
 	orders map[string]*ProductionOrder
 	seq    atomic.Uint64
 }
 
-// NewStore returns a new in-memory order store.
-func NewStore() *Store {
-	return &Store{orders: make(map[string]*ProductionOrder)}
+// NewMemoryStore returns a new in-memory order store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{orders: make(map[string]*ProductionOrder)}
 }
 
 // Create persists a new order (ID generated if empty). Returns the order with ID set.
-func (s *Store) Create(order *ProductionOrder) (*ProductionOrder, error) {
+func (s *MemoryStore) Create(order *ProductionOrder) (*ProductionOrder, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if order.ID == "" {
 		order.ID = "ord-" + formatSeq(s.seq.Add(1))
 	}
-	if order.CreatedAt.IsZero() {
-		order.CreatedAt = time.Now().UTC()
-	}
-	if order.Status == "" {
-		order.Status = OrderStatusDraft
+	if _, ok := s.orders[order.ID]; ok {
+		return nil, fmt.Errorf("store order: %w", storage.ErrExists)
 	}
-	order.QuantityCompleted = 0
-	order.QuantityScrapped = 0
+	initOrder(order)
 	cp := *order
 	s.orders[order.ID] = &cp
 	return &cp, nil
@@ -51,7 +89,7 @@ func formatSeq(n uint64) string {
 }
 
 // Get returns an order by ID, or nil if not found.
-func (s *Store) Get(id string) *ProductionOrder {
+func (s *MemoryStore) Get(id string) *ProductionOrder {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	o, ok := s.orders[id]
@@ -63,7 +101,7 @@ func (s *Store) Get(id string) *ProductionOrder {
 }
 
 // List returns all orders, optionally filtered by status.
-func (s *Store) List(statusFilter OrderStatus) []ProductionOrder {
+func (s *MemoryStore) List(statusFilter OrderStatus) []ProductionOrder {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var out []ProductionOrder
@@ -76,14 +114,14 @@ func (s *Store) List(statusFilter OrderStatus) []ProductionOrder {
 	return out
 }
 
-// Update updates an existing order. Returns false if not found.
-func (s *Store) Update(order *ProductionOrder) bool {
+// Update updates an existing order.
+func (s *MemoryStore) Update(order *ProductionOrder) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, ok := s.orders[order.ID]; !ok {
-		return false
+		return fmt.Errorf("order %s: %w", order.ID, storage.ErrNotFound)
 	}
 	cp := *order
 	s.orders[order.ID] = &cp
-	return true
+	return nil
 }
@@ -0,0 +1,90 @@
+package mes
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
+)
+
+// testStores returns a MemoryStore and an SQLStore on a new SQLite file, to run the same checks on both.
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	db, err := storage.Open(storage.Config{Backend: storage.BackendSQLite, Path: filepath.Join(t.TempDir(), "mes.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s, err := NewSQLStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Store{"memory": NewMemoryStore(), "sql": s}
+}
+
+// ids returns the sorted IDs of a list, which the memory store returns in no particular order.
+func ids[T any](items []T, id func(T) string) string {
+	var out []string
+	for _, it := range items {
+		out = append(out, id(it))
+	}
+	sort.Strings(out)
+	return strings.Join(out, " ")
+}
+
+func TestStore(t *testing.T) {
+	orderID := func(o ProductionOrder) string { return o.ID }
+	for name, s := range testStores(t) {
+		o, err := s.Create(&ProductionOrder{SKU: "widget", Quantity: 10, QuantityCompleted: 3})
+		if err != nil || o.ID != "ord-1" || o.Status != OrderStatusDraft || o.QuantityCompleted != 0 || o.CreatedAt.IsZero() {
+			t.Fatalf("%s: Create = %+v, %v", name, o, err)
+		}
+		if _, err := s.Create(&ProductionOrder{ID: "wo-7", SKU: "gadget", Quantity: 1, Status: OrderStatusReleased}); err != nil {
+			t.Fatalf("%s: Create with ID: %v", name, err)
+		}
+		if _, err := s.Create(&ProductionOrder{ID: "ord-1", SKU: "other"}); !errors.Is(err, storage.ErrExists) {
+			t.Errorf("%s: Create with a taken ID = %v, want ErrExists", name, err)
+		}
+		if got := s.Get("ord-1"); got == nil || got.SKU != "widget" || got.Quantity != 10 || !got.CreatedAt.Equal(o.CreatedAt) {
+			t.Errorf("%s: Get = %+v, want the first order unchanged", name, got)
+		}
+		if got := s.Get("ord-9"); got != nil {
+			t.Errorf("%s: Get of a missing order = %+v", name, got)
+		}
+
+		o.Status = OrderStatusReleased
+		o.QuantityCompleted = 4
+		if err := s.Update(o); err != nil {
+			t.Fatalf("%s: Update: %v", name, err)
+		}
+		o.QuantityCompleted = 99 // the store keeps its own copy
+		if got := s.Get("ord-1"); got.Status != OrderStatusReleased || got.QuantityCompleted != 4 {
+			t.Errorf("%s: after Update Get = %+v", name, got)
+		}
+		if err := s.Update(&ProductionOrder{ID: "ord-9"}); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s: Update of a missing order = %v, want ErrNotFound", name, err)
+		}
+		if s.Get("ord-9") != nil {
+			t.Errorf("%s: Update created a missing order", name)
+		}
+
+		for _, tc := range []struct {
+			status OrderStatus
+			want   string
+		}{
+			{"", "ord-1 wo-7"},
+			{OrderStatusReleased, "ord-1 wo-7"},
+			{OrderStatusDraft, ""},
+		} {
+			if got := ids(s.List(tc.status), orderID); got != tc.want {
+				t.Errorf("%s: List(%q) = %s, want %s", name, tc.status, got, tc.want)
+			}
+		}
+		if o, err := s.Create(&ProductionOrder{SKU: "widget"}); err != nil || o.ID != "ord-2" {
+			t.Errorf("%s: next generated ID = %+v, %v", name, o, err)
+		}
+	}
+}
@@ -30,6 +30,7 @@ type ProductionOrder struct {
 	BOMRevision        string       `json:"bom_revision,omitempty"`
 	RoutingRevision    string       `json:"routing_revision,omitempty"`
 	FleetWorkOrderID   string       `json:"fleet_work_order_id,omitempty"` // set when released to Fleet
+	Releases           int          `json:"releases,omitempty"`            // times released to Fleet; keys each release's work order
 	CreatedAt          time.Time    `json:"created_at"`
 	ReleasedAt         *time.Time   `json:"released_at,omitempty"`
 	CompletedAt        *time.Time   `json:"completed_at,omitempty"`
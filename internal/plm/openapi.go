@@ -0,0 +1,47 @@
+package plm
+
+import (
+	"net/http"
+
+	"github.com/robotfleetos/robotfleetos/pkg/openapi"
+)
+
+// OpenAPI is the PLM API's OpenAPI document, served at GET /openapi.json.
+var OpenAPI = &openapi.API{
+	Title:       "RobotFleetOS PLM API",
+	Description: "Product revisions, their bills of materials and engineering change orders.",
+	Rules:       AuthRules,
+	Ops: openapi.Join(
+		openapi.Dashboard("/", "/ui"),
+		[]openapi.Op{
+			{Method: http.MethodPost, Path: "/products", Summary: "Create a product",
+				Body: Product{}, Status: http.StatusCreated, Response: Product{}, Errors: []int{http.StatusBadRequest},
+				Example: Product{SKU: "WIDGET-001", Name: "Widget", Revision: "A", Status: ProductStatusDraft}},
+			{Method: http.MethodGet, Path: "/products", Summary: "List products",
+				Query: []string{"status", "sku"}, Response: openapi.Object{"products": []Product{}}},
+			{Method: http.MethodGet, Path: "/products/{id}", Summary: "Get a product",
+				Response: Product{}, Errors: []int{http.StatusNotFound}},
+			{Method: http.MethodPut, Path: "/products/{id}", Summary: "Update a product",
+				Body: Product{}, Response: Product{}, Errors: []int{http.StatusBadRequest},
+				Example: Product{SKU: "WIDGET-001", Name: "Widget", Revision: "A", Status: ProductStatusReleased}},
+			{Method: http.MethodPost, Path: "/products/{id}/bom", Summary: "Add a line to a product's bill of materials",
+				Body: BOMLine{}, Status: http.StatusCreated, Response: BOMLine{}, Errors: []int{http.StatusBadRequest},
+				Example: BOMLine{ChildSKU: "BOLT-M4", Quantity: 4}},
+			{Method: http.MethodGet, Path: "/products/{id}/bom", Summary: "A product's bill of materials",
+				Response: openapi.Object{"bom": []BOMLine{}}},
+			{Method: http.MethodDelete, Path: "/products/{id}/bom/{line_id}", Summary: "Remove a line from a product's bill of materials",
+				Status: http.StatusNoContent, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPost, Path: "/ecos", Summary: "Create an engineering change order",
+				Body: ECO{}, Status: http.StatusCreated, Response: ECO{}, Errors: []int{http.StatusBadRequest},
+				Example: ECO{Title: "Switch to M4 bolts", Status: ECOStatusDraft}},
+			{Method: http.MethodGet, Path: "/ecos", Summary: "List engineering change orders",
+				Query: []string{"status", "product_id"}, Response: openapi.Object{"ecos": []ECO{}}},
+			{Method: http.MethodGet, Path: "/ecos/{id}", Summary: "Get an engineering change order",
+				Response: ECO{}, Errors: []int{http.StatusNotFound}},
+			{Method: http.MethodPut, Path: "/ecos/{id}", Summary: "Update an engineering change order, e.g. to approve it",
+				Body: ECO{}, Response: ECO{}, Errors: []int{http.StatusBadRequest},
+				Example: ECO{Title: "Switch to M4 bolts", Status: ECOStatusApproved}},
+		},
+		openapi.Common("plm"),
+	),
+}
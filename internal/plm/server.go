@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
 )
 
 //go:embed static/index.html
@@ -12,16 +15,31 @@ var dashboardHTML []byte
 
 type Server struct {
 	Service *Service
+	Auth    *auth.Authenticator // optional: API key/JWT authentication and role checks; nil leaves the API open
+	Audit   *audit.Log          // optional: records every change made through the API; GET /audit
 }
 
+// AuthRules are the PLM API's route permissions; see auth.Rule.
+var AuthRules = auth.Join(
+	audit.AuthRules,
+	auth.Public("/", "/ui", "/health", "/openapi.json"),
+	auth.Writes("/products", auth.RolePlanner),
+	auth.Writes("/products/", auth.RolePlanner),
+	auth.Writes("/ecos", auth.RolePlanner, auth.RoleQuality),
+	auth.Writes("/ecos/", auth.RolePlanner, auth.RoleQuality),
+)
+
 func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", s.handleHealth)
+	mux.Handle("/openapi.json", OpenAPI)
+	mux.Handle("/audit", s.Audit)
+	mux.Handle("/audit/", s.Audit)
 	mux.HandleFunc("/products", s.handleProducts)
 	mux.HandleFunc("/products/", s.handleProductByID)
 	mux.HandleFunc("/ecos", s.handleECOs)
 	mux.HandleFunc("/ecos/", s.handleECOByID)
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		p := r.URL.Path
 		if p == "/" || p == "/ui" || p == "/ui/" {
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -31,6 +49,7 @@ func (s *Server) Handler() http.Handler {
 		}
 		mux.ServeHTTP(w, r)
 	})
+	return s.Audit.Wrap(s.Auth.Wrap(h))
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -62,6 +81,7 @@ func (s *Server) handleProducts(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.Audit.Record(r, "product.create", "product", created.ID, nil, created)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(created)
@@ -103,11 +123,13 @@ func (s *Server) handleProductByID(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		p.ID = id
+		before := s.Service.GetProduct(r.Context(), id)
 		updated, err := s.Service.UpdateProduct(r.Context(), &p)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.Audit.Record(r, "product.update", "product", id, before, updated)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(updated)
 		return
@@ -116,6 +138,27 @@ func (s *Server) handleProductByID(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleBOM(w http.ResponseWriter, r *http.Request, productID string, parts []string) {
+	if len(parts) > 2 && parts[2] != "" {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		lineID := parts[2]
+		var before *BOMLine
+		for _, l := range s.Service.GetBOM(r.Context(), productID) {
+			if l.ID == lineID {
+				before = &l
+				break
+			}
+		}
+		if err := s.Service.DeleteBOMLine(r.Context(), lineID); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.Audit.Record(r, "bom_line.delete", "bom_line", lineID, before, nil)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
 	switch r.Method {
 	case http.MethodGet:
 		lines := s.Service.GetBOM(r.Context(), productID)
@@ -133,22 +176,11 @@ func (s *Server) handleBOM(w http.ResponseWriter, r *http.Request, productID str
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.Audit.Record(r, "bom_line.create", "bom_line", created.ID, nil, created)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(created)
 		return
-	case http.MethodDelete:
-		if len(parts) < 3 {
-			http.Error(w, "bom line id required", http.StatusBadRequest)
-			return
-		}
-		lineID := parts[2]
-		if err := s.Service.DeleteBOMLine(r.Context(), lineID); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-		w.WriteHeader(http.StatusNoContent)
-		return
 	}
 	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 }
@@ -173,6 +205,7 @@ func (s *Server) handleECOs(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.Audit.Record(r, "eco.create", "eco", created.ID, nil, created)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(created)
@@ -204,11 +237,13 @@ func (s *Server) handleECOByID(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		e.ID = id
+		before := s.Service.GetECO(r.Context(), id)
 		updated, err := s.Service.UpdateECO(r.Context(), &e)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.Audit.Record(r, "eco.update", "eco", id, before, updated)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(updated)
 		return
@@ -2,16 +2,19 @@ package plm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
 // Service implements PLM business logic.
 type Service struct {
-	Store *Store
+	Store Store
 }
 
 // NewService returns a PLM service.
-func NewService(store *Store) *Service {
+func NewService(store Store) *Service {
 	return &Service{Store: store}
 }
 
@@ -26,7 +29,7 @@ func (s *Service) CreateProduct(ctx context.Context, p *Product) (*Product, erro
 	if p.Revision == "" {
 		p.Revision = "A"
 	}
-	return s.Store.CreateProduct(p), nil
+	return s.Store.CreateProduct(p)
 }
 
 // GetProduct returns a product by ID.
@@ -39,7 +42,9 @@ func (s *Service) UpdateProduct(ctx context.Context, p *Product) (*Product, erro
 	if s.Store.GetProduct(p.ID) == nil {
 		return nil, fmt.Errorf("product not found: %s", p.ID)
 	}
-	s.Store.UpdateProduct(p)
+	if err := s.Store.UpdateProduct(p); err != nil {
+		return nil, err
+	}
 	return s.Store.GetProduct(p.ID), nil
 }
 
@@ -60,13 +65,15 @@ func (s *Service) AddBOMLine(ctx context.Context, parentProductID string, b *BOM
 		return nil, fmt.Errorf("quantity must be positive")
 	}
 	b.ParentProductID = parentProductID
-	return s.Store.AddBOMLine(b), nil
+	return s.Store.AddBOMLine(b)
 }
 
 // DeleteBOMLine removes a BOM line.
 func (s *Service) DeleteBOMLine(ctx context.Context, lineID string) error {
-	if !s.Store.DeleteBOMLine(lineID) {
+	if err := s.Store.DeleteBOMLine(lineID); errors.Is(err, storage.ErrNotFound) {
 		return fmt.Errorf("bom line not found: %s", lineID)
+	} else if err != nil {
+		return err
 	}
 	return nil
 }
@@ -81,7 +88,7 @@ func (s *Service) CreateECO(ctx context.Context, e *ECO) (*ECO, error) {
 	if e.Title == "" {
 		return nil, fmt.Errorf("title required")
 	}
-	return s.Store.CreateECO(e), nil
+	return s.Store.CreateECO(e)
 }
 
 // GetECO returns an ECO by ID.
@@ -94,7 +101,9 @@ func (s *Service) UpdateECO(ctx context.Context, e *ECO) (*ECO, error) {
 	if s.Store.GetECO(e.ID) == nil {
 		return nil, fmt.Errorf("eco not found: %s", e.ID)
 	}
-	s.Store.UpdateECO(e)
+	if err := s.Store.UpdateECO(e); err != nil {
+		return nil, err
+	}
 	return s.Store.GetECO(e.ID), nil
 }
 
@@ -0,0 +1,242 @@
+package plm
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
+)
+
+// sqlMigrations is the PLM schema, one entry per version; only ever append (see storage.Migrate).
+var sqlMigrations = []string{`
+CREATE TABLE plm_products (
+	id     TEXT PRIMARY KEY,
+	status TEXT NOT NULL,
+	sku    TEXT NOT NULL,
+	data   TEXT NOT NULL
+);
+CREATE INDEX plm_products_sku ON plm_products (sku);
+CREATE TABLE plm_bom_lines (
+	id                TEXT PRIMARY KEY,
+	parent_product_id TEXT NOT NULL,
+	data              TEXT NOT NULL
+);
+CREATE INDEX plm_bom_lines_parent ON plm_bom_lines (parent_product_id);
+CREATE TABLE plm_ecos (
+	id         TEXT PRIMARY KEY,
+	status     TEXT NOT NULL,
+	product_id TEXT NOT NULL,
+	data       TEXT NOT NULL
+);
+CREATE INDEX plm_ecos_product ON plm_ecos (product_id);`,
+}
+
+// SQLStore keeps products, BOM lines and ECOs in a database, each as JSON with the fields it is filtered
+// on in columns.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore migrates db to the current PLM schema and returns a store on it.
+func NewSQLStore(db *sql.DB) (*SQLStore, error) {
+	if err := storage.Migrate(db, "plm", sqlMigrations); err != nil {
+		return nil, err
+	}
+	return &SQLStore{db: db}, nil
+}
+
+func (s *SQLStore) CreateProduct(p *Product) (*Product, error) {
+	err := storage.InTx(s.db, func(tx *sql.Tx) error {
+		if p.ID == "" {
+			n, err := storage.NextSeq(tx, "plm_products")
+			if err != nil {
+				return err
+			}
+			p.ID = seqID("prod", n)
+		}
+		initProduct(p)
+		data, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		return storage.Insert(tx, `INSERT INTO plm_products (id, status, sku, data) VALUES (?, ?, ?, ?)`,
+			p.ID, string(p.Status), p.SKU, string(data))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store product: %w", err)
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (s *SQLStore) GetProduct(id string) *Product {
+	var p Product
+	ok, err := storage.GetJSON(s.db, &p, `SELECT data FROM plm_products WHERE id = ?`, id)
+	if err != nil {
+		log.Printf("plm: store: get product %s: %v", id, err)
+	}
+	if !ok || err != nil {
+		return nil
+	}
+	return &p
+}
+
+func (s *SQLStore) UpdateProduct(p *Product) error {
+	p.UpdatedAt = time.Now().UTC()
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	err = storage.ExecOne(s.db, `UPDATE plm_products SET status = ?, sku = ?, data = ? WHERE id = ?`,
+		string(p.Status), p.SKU, string(data), p.ID)
+	if err != nil {
+		return fmt.Errorf("product %s: %w", p.ID, err)
+	}
+	return nil
+}
+
+func (s *SQLStore) ListProducts(status ProductStatus, sku string) []Product {
+	var out []Product
+	err := storage.EachJSON(s.db, func(data []byte) error {
+		var p Product
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		out = append(out, p)
+		return nil
+	}, `SELECT data FROM plm_products WHERE (? = '' OR status = ?) AND (? = '' OR sku = ?) ORDER BY rowid`,
+		string(status), string(status), sku, sku)
+	if err != nil {
+		log.Printf("plm: store: list products: %v", err)
+	}
+	return out
+}
+
+func (s *SQLStore) AddBOMLine(b *BOMLine) (*BOMLine, error) {
+	err := storage.InTx(s.db, func(tx *sql.Tx) error {
+		if b.ID == "" {
+			n, err := storage.NextSeq(tx, "plm_bom_lines")
+			if err != nil {
+				return err
+			}
+			b.ID = seqID("bom", n)
+		}
+		initBOMLine(b)
+		data, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+		return storage.Insert(tx, `INSERT INTO plm_bom_lines (id, parent_product_id, data) VALUES (?, ?, ?)`,
+			b.ID, b.ParentProductID, string(data))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store bom line: %w", err)
+	}
+	cp := *b
+	return &cp, nil
+}
+
+func (s *SQLStore) GetBOMLine(id string) *BOMLine {
+	var b BOMLine
+	ok, err := storage.GetJSON(s.db, &b, `SELECT data FROM plm_bom_lines WHERE id = ?`, id)
+	if err != nil {
+		log.Printf("plm: store: get bom line %s: %v", id, err)
+	}
+	if !ok || err != nil {
+		return nil
+	}
+	return &b
+}
+
+func (s *SQLStore) DeleteBOMLine(id string) error {
+	if err := storage.ExecOne(s.db, `DELETE FROM plm_bom_lines WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("bom line %s: %w", id, err)
+	}
+	return nil
+}
+
+func (s *SQLStore) ListBOMLinesByParent(parentProductID string) []BOMLine {
+	var out []BOMLine
+	err := storage.EachJSON(s.db, func(data []byte) error {
+		var b BOMLine
+		if err := json.Unmarshal(data, &b); err != nil {
+			return err
+		}
+		out = append(out, b)
+		return nil
+	}, `SELECT data FROM plm_bom_lines WHERE parent_product_id = ? ORDER BY rowid`, parentProductID)
+	if err != nil {
+		log.Printf("plm: store: list bom lines: %v", err)
+	}
+	return out
+}
+
+func (s *SQLStore) CreateECO(e *ECO) (*ECO, error) {
+	err := storage.InTx(s.db, func(tx *sql.Tx) error {
+		if e.ID == "" {
+			n, err := storage.NextSeq(tx, "plm_ecos")
+			if err != nil {
+				return err
+			}
+			e.ID = seqID("eco", n)
+		}
+		initECO(e)
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		return storage.Insert(tx, `INSERT INTO plm_ecos (id, status, product_id, data) VALUES (?, ?, ?, ?)`,
+			e.ID, string(e.Status), e.ProductID, string(data))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store eco: %w", err)
+	}
+	cp := *e
+	return &cp, nil
+}
+
+func (s *SQLStore) GetECO(id string) *ECO {
+	var e ECO
+	ok, err := storage.GetJSON(s.db, &e, `SELECT data FROM plm_ecos WHERE id = ?`, id)
+	if err != nil {
+		log.Printf("plm: store: get eco %s: %v", id, err)
+	}
+	if !ok || err != nil {
+		return nil
+	}
+	return &e
+}
+
+func (s *SQLStore) UpdateECO(e *ECO) error {
+	e.UpdatedAt = time.Now().UTC()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	err = storage.ExecOne(s.db, `UPDATE plm_ecos SET status = ?, product_id = ?, data = ? WHERE id = ?`,
+		string(e.Status), e.ProductID, string(data), e.ID)
+	if err != nil {
+		return fmt.Errorf("eco %s: %w", e.ID, err)
+	}
+	return nil
+}
+
+func (s *SQLStore) ListECOs(status ECOStatus, productID string) []ECO {
+	var out []ECO
+	err := storage.EachJSON(s.db, func(data []byte) error {
+		var e ECO
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		out = append(out, e)
+		return nil
+	}, `SELECT data FROM plm_ecos WHERE (? = '' OR status = ?) AND (? = '' OR product_id = ?) ORDER BY rowid`,
+		string(status), string(status), productID, productID)
+	if err != nil {
+		log.Printf("plm: store: list ecos: %v", err)
+	}
+	return out
+}
@@ -1,13 +1,74 @@
 package plm
 
 import (
+	"database/sql"
 	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
-type Store struct {
+// Store holds products, BOM lines and ECOs: MemoryStore in memory, SQLStore in a database (see
+// pkg/storage). Reads log a database failure and report nothing found; writes return it, creates with a
+// taken ID storage.ErrExists, and updates and deletes of missing entities storage.ErrNotFound.
+type Store interface {
+	CreateProduct(p *Product) (*Product, error)
+	GetProduct(id string) *Product
+	UpdateProduct(p *Product) error
+	ListProducts(status ProductStatus, sku string) []Product
+	AddBOMLine(b *BOMLine) (*BOMLine, error)
+	GetBOMLine(id string) *BOMLine
+	DeleteBOMLine(id string) error
+	ListBOMLinesByParent(parentProductID string) []BOMLine
+	CreateECO(e *ECO) (*ECO, error)
+	GetECO(id string) *ECO
+	UpdateECO(e *ECO) error
+	ListECOs(status ECOStatus, productID string) []ECO
+}
+
+// NewStore returns an SQLStore on db, or a MemoryStore if db is nil.
+func NewStore(db *sql.DB) (Store, error) {
+	if db == nil {
+		return NewMemoryStore(), nil
+	}
+	s, err := NewSQLStore(db)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func initProduct(p *Product) {
+	now := time.Now().UTC()
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = now
+	}
+	p.UpdatedAt = now
+	if p.Status == "" {
+		p.Status = ProductStatusDraft
+	}
+}
+
+func initBOMLine(b *BOMLine) {
+	if b.CreatedAt.IsZero() {
+		b.CreatedAt = time.Now().UTC()
+	}
+}
+
+func initECO(e *ECO) {
+	now := time.Now().UTC()
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = now
+	}
+	e.UpdatedAt = now
+	if e.Status == "" {
+		e.Status = ECOStatusDraft
+	}
+}
+
+type MemoryStore struct {
 	mu         sync.RWMutex
 	productSeq atomic.Uint64
 	products   map[string]*Product
@@ -17,8 +78,8 @@ type Store struct {
 	ecos       map[string]*ECO
 }
 
-func NewStore() *Store {
-	return &Store{
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
 		products: make(map[string]*Product),
 		bomLines: make(map[string]*BOMLine),
 		ecos:     make(map[string]*ECO),
@@ -27,26 +88,22 @@ func NewStore() *Store {
 
 func seqID(prefix string, n uint64) string { return prefix + "-" + fmt.Sprintf("%d", n) }
 
-func (s *Store) CreateProduct(p *Product) *Product {
+func (s *MemoryStore) CreateProduct(p *Product) (*Product, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if p.ID == "" {
 		p.ID = seqID("prod", s.productSeq.Add(1))
 	}
-	now := time.Now().UTC()
-	if p.CreatedAt.IsZero() {
-		p.CreatedAt = now
-	}
-	p.UpdatedAt = now
-	if p.Status == "" {
-		p.Status = ProductStatusDraft
+	if _, ok := s.products[p.ID]; ok {
+		return nil, fmt.Errorf("store product: %w", storage.ErrExists)
 	}
+	initProduct(p)
 	cp := *p
 	s.products[cp.ID] = &cp
-	return &cp
+	return &cp, nil
 }
 
-func (s *Store) GetProduct(id string) *Product {
+func (s *MemoryStore) GetProduct(id string) *Product {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	p, ok := s.products[id]
@@ -57,19 +114,19 @@ func (s *Store) GetProduct(id string) *Product {
 	return &cp
 }
 
-func (s *Store) UpdateProduct(p *Product) bool {
+func (s *MemoryStore) UpdateProduct(p *Product) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, ok := s.products[p.ID]; !ok {
-		return false
+		return fmt.Errorf("product %s: %w", p.ID, storage.ErrNotFound)
 	}
 	p.UpdatedAt = time.Now().UTC()
 	cp := *p
 	s.products[p.ID] = &cp
-	return true
+	return nil
 }
 
-func (s *Store) ListProducts(status ProductStatus, sku string) []Product {
+func (s *MemoryStore) ListProducts(status ProductStatus, sku string) []Product {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var out []Product
@@ -85,21 +142,22 @@ func (s *Store) ListProducts(status ProductStatus, sku string) []Product {
 	return out
 }
 
-func (s *Store) AddBOMLine(b *BOMLine) *BOMLine {
+func (s *MemoryStore) AddBOMLine(b *BOMLine) (*BOMLine, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if b.ID == "" {
 		b.ID = seqID("bom", s.bomSeq.Add(1))
 	}
-	if b.CreatedAt.IsZero() {
-		b.CreatedAt = time.Now().UTC()
+	if _, ok := s.bomLines[b.ID]; ok {
+		return nil, fmt.Errorf("store bom line: %w", storage.ErrExists)
 	}
+	initBOMLine(b)
 	cp := *b
 	s.bomLines[cp.ID] = &cp
-	return &cp
+	return &cp, nil
 }
 
-func (s *Store) GetBOMLine(id string) *BOMLine {
+func (s *MemoryStore) GetBOMLine(id string) *BOMLine {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	b, ok := s.bomLines[id]
@@ -110,17 +168,17 @@ func (s *Store) GetBOMLine(id string) *BOMLine {
 	return &cp
 }
 
-func (s *Store) DeleteBOMLine(id string) bool {
+func (s *MemoryStore) DeleteBOMLine(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, ok := s.bomLines[id]; !ok {
-		return false
+		return fmt.Errorf("bom line %s: %w", id, storage.ErrNotFound)
 	}
 	delete(s.bomLines, id)
-	return true
+	return nil
 }
 
-func (s *Store) ListBOMLinesByParent(parentProductID string) []BOMLine {
+func (s *MemoryStore) ListBOMLinesByParent(parentProductID string) []BOMLine {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var out []BOMLine
@@ -133,26 +191,22 @@ func (s *Store) ListBOMLinesByParent(parentProductID string) []BOMLine {
 	return out
 }
 
-func (s *Store) CreateECO(e *ECO) *ECO {
+func (s *MemoryStore) CreateECO(e *ECO) (*ECO, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if e.ID == "" {
 		e.ID = seqID("eco", s.ecoSeq.Add(1))
 	}
-	now := time.Now().UTC()
-	if e.CreatedAt.IsZero() {
-		e.CreatedAt = now
-	}
-	e.UpdatedAt = now
-	if e.Status == "" {
-		e.Status = ECOStatusDraft
+	if _, ok := s.ecos[e.ID]; ok {
+		return nil, fmt.Errorf("store eco: %w", storage.ErrExists)
 	}
+	initECO(e)
 	cp := *e
 	s.ecos[cp.ID] = &cp
-	return &cp
+	return &cp, nil
 }
 
-func (s *Store) GetECO(id string) *ECO {
+func (s *MemoryStore) GetECO(id string) *ECO {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	e, ok := s.ecos[id]
@@ -163,19 +217,19 @@ func (s *Store) GetECO(id string) *ECO {
 	return &cp
 }
 
-func (s *Store) UpdateECO(e *ECO) bool {
+func (s *MemoryStore) UpdateECO(e *ECO) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, ok := s.ecos[e.ID]; !ok {
-		return false
+		return fmt.Errorf("eco %s: %w", e.ID, storage.ErrNotFound)
 	}
 	e.UpdatedAt = time.Now().UTC()
 	cp := *e
 	s.ecos[e.ID] = &cp
-	return true
+	return nil
 }
 
-func (s *Store) ListECOs(status ECOStatus, productID string) []ECO {
+func (s *MemoryStore) ListECOs(status ECOStatus, productID string) []ECO {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var out []ECO
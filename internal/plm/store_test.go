@@ -0,0 +1,141 @@
+package plm
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
+)
+
+// testStores returns a MemoryStore and an SQLStore on a new SQLite file, to run the same checks on both.
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	db, err := storage.Open(storage.Config{Backend: storage.BackendSQLite, Path: filepath.Join(t.TempDir(), "plm.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s, err := NewSQLStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Store{"memory": NewMemoryStore(), "sql": s}
+}
+
+// ids returns the sorted IDs of a list, which the memory store returns in no particular order.
+func ids[T any](items []T, id func(T) string) string {
+	var out []string
+	for _, it := range items {
+		out = append(out, id(it))
+	}
+	sort.Strings(out)
+	return strings.Join(out, " ")
+}
+
+func TestStore(t *testing.T) {
+	productID := func(p Product) string { return p.ID }
+	bomID := func(b BOMLine) string { return b.ID }
+	ecoID := func(e ECO) string { return e.ID }
+	for name, s := range testStores(t) {
+		p, err := s.CreateProduct(&Product{SKU: "widget", Name: "Widget", Revision: "A"})
+		if err != nil || p.ID != "prod-1" || p.Status != ProductStatusDraft || p.CreatedAt.IsZero() {
+			t.Fatalf("%s: CreateProduct = %+v, %v", name, p, err)
+		}
+		if _, err := s.CreateProduct(&Product{ID: "gear", SKU: "gear", Name: "Gear", Revision: "A"}); err != nil {
+			t.Fatalf("%s: CreateProduct with ID: %v", name, err)
+		}
+		if _, err := s.CreateProduct(&Product{ID: "prod-1", SKU: "other"}); !errors.Is(err, storage.ErrExists) {
+			t.Errorf("%s: CreateProduct with a taken ID = %v, want ErrExists", name, err)
+		}
+		p.Status = ProductStatusReleased
+		if err := s.UpdateProduct(p); err != nil {
+			t.Fatalf("%s: UpdateProduct: %v", name, err)
+		}
+		if got := s.GetProduct("prod-1"); got == nil || got.Status != ProductStatusReleased || got.Name != "Widget" {
+			t.Errorf("%s: after UpdateProduct GetProduct = %+v", name, got)
+		}
+		if got := s.GetProduct("prod-9"); got != nil {
+			t.Errorf("%s: GetProduct of a missing product = %+v", name, got)
+		}
+		if err := s.UpdateProduct(&Product{ID: "prod-9"}); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s: UpdateProduct of a missing product = %v, want ErrNotFound", name, err)
+		}
+		for _, tc := range []struct {
+			status ProductStatus
+			sku    string
+			want   string
+		}{
+			{"", "", "gear prod-1"},
+			{ProductStatusReleased, "", "prod-1"},
+			{"", "gear", "gear"},
+			{ProductStatusReleased, "gear", ""},
+		} {
+			if got := ids(s.ListProducts(tc.status, tc.sku), productID); got != tc.want {
+				t.Errorf("%s: ListProducts(%q, %q) = %s, want %s", name, tc.status, tc.sku, got, tc.want)
+			}
+		}
+
+		for _, b := range []*BOMLine{
+			{ParentProductID: "prod-1", ChildSKU: "gear", Quantity: 2, LineNumber: 10},
+			{ParentProductID: "prod-1", ChildSKU: "screw", Quantity: 4, LineNumber: 20},
+			{ParentProductID: "gear", ChildSKU: "blank", Quantity: 1},
+		} {
+			if _, err := s.AddBOMLine(b); err != nil {
+				t.Fatalf("%s: AddBOMLine: %v", name, err)
+			}
+		}
+		if _, err := s.AddBOMLine(&BOMLine{ID: "bom-1", ParentProductID: "gear", ChildSKU: "x"}); !errors.Is(err, storage.ErrExists) {
+			t.Errorf("%s: AddBOMLine with a taken ID = %v, want ErrExists", name, err)
+		}
+		if got := s.GetBOMLine("bom-2"); got == nil || got.ChildSKU != "screw" || got.Quantity != 4 {
+			t.Errorf("%s: GetBOMLine = %+v", name, got)
+		}
+		if err := s.DeleteBOMLine("bom-2"); err != nil {
+			t.Fatalf("%s: DeleteBOMLine: %v", name, err)
+		}
+		if err := s.DeleteBOMLine("bom-2"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s: DeleteBOMLine of a missing line = %v, want ErrNotFound", name, err)
+		}
+		if got := ids(s.ListBOMLinesByParent("prod-1"), bomID); got != "bom-1" {
+			t.Errorf("%s: BOM of prod-1 = %s, want bom-1", name, got)
+		}
+
+		e, err := s.CreateECO(&ECO{Title: "Thicker gear", ProductID: "gear"})
+		if err != nil || e.ID != "eco-1" || e.Status != ECOStatusDraft {
+			t.Fatalf("%s: CreateECO = %+v, %v", name, e, err)
+		}
+		if _, err := s.CreateECO(&ECO{Title: "New screw", ProductID: "prod-1"}); err != nil {
+			t.Fatalf("%s: second CreateECO: %v", name, err)
+		}
+		if _, err := s.CreateECO(&ECO{ID: "eco-2", Title: "other"}); !errors.Is(err, storage.ErrExists) {
+			t.Errorf("%s: CreateECO with a taken ID = %v, want ErrExists", name, err)
+		}
+		e.Status = ECOStatusApproved
+		if err := s.UpdateECO(e); err != nil {
+			t.Fatalf("%s: UpdateECO: %v", name, err)
+		}
+		if got := s.GetECO("eco-1"); got == nil || got.Status != ECOStatusApproved || got.Title != "Thicker gear" {
+			t.Errorf("%s: after UpdateECO GetECO = %+v", name, got)
+		}
+		if err := s.UpdateECO(&ECO{ID: "eco-9"}); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s: UpdateECO of a missing ECO = %v, want ErrNotFound", name, err)
+		}
+		for _, tc := range []struct {
+			status    ECOStatus
+			productID string
+			want      string
+		}{
+			{"", "", "eco-1 eco-2"},
+			{ECOStatusDraft, "", "eco-2"},
+			{"", "gear", "eco-1"},
+			{ECOStatusDraft, "gear", ""},
+		} {
+			if got := ids(s.ListECOs(tc.status, tc.productID), ecoID); got != tc.want {
+				t.Errorf("%s: ListECOs(%q, %q) = %s, want %s", name, tc.status, tc.productID, got, tc.want)
+			}
+		}
+	}
+}
@@ -3,12 +3,18 @@ package qms
 import (
 	"os"
 
-	"gopkg.in/yaml.v3"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
 // Config holds QMS server configuration.
 type Config struct {
-	QMS QMSConfig `yaml:"qms"`
+	QMS     QMSConfig      `yaml:"qms"`
+	Auth    auth.Config    `yaml:"auth"`    // API keys and JWT for this service's API; none configured leaves it open
+	Audit   audit.Config   `yaml:"audit"`   // tamper-evident log of API actions; env AUDIT_LOG overrides the path
+	Storage storage.Config `yaml:"storage"` // memory (default) or sqlite; env STORE_BACKEND and STORE_PATH override
 }
 
 // QMSConfig is the server config.
@@ -27,11 +33,7 @@ func LoadConfig(path string) (*Config, error) {
 		}
 	}
 	if path != "" {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return nil, err
-		}
-		if err := yaml.Unmarshal(data, cfg); err != nil {
+		if err := config.Load(path, cfg); err != nil {
 			return nil, err
 		}
 	}
@@ -41,5 +43,8 @@ func LoadConfig(path string) (*Config, error) {
 	if cfg.QMS.Listen == "" {
 		cfg.QMS.Listen = ":8084"
 	}
+	cfg.Auth.ApplyEnv()
+	cfg.Audit.ApplyEnv()
+	cfg.Storage.ApplyEnv()
 	return cfg, nil
 }
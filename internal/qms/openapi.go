@@ -0,0 +1,43 @@
+package qms
+
+import (
+	"net/http"
+
+	"github.com/robotfleetos/robotfleetos/pkg/openapi"
+)
+
+// OpenAPI is the QMS API's OpenAPI document, served at GET /openapi.json.
+var OpenAPI = &openapi.API{
+	Title:       "RobotFleetOS QMS API",
+	Description: "Inspections, non-conformance reports and quality holds on serials and lots.",
+	Rules:       AuthRules,
+	Ops: openapi.Join(
+		openapi.Dashboard("/", "/ui"),
+		[]openapi.Op{
+			{Method: http.MethodPost, Path: "/inspections", Summary: "Record an inspection",
+				Body: Inspection{}, Status: http.StatusCreated, Response: Inspection{}, Errors: []int{http.StatusBadRequest},
+				Example: Inspection{Serial: "SN-1", SKU: "WIDGET-001", Result: "pass"}},
+			{Method: http.MethodGet, Path: "/inspections", Summary: "List inspections",
+				Query: []string{"serial", "lot"}, Response: openapi.Object{"inspections": []Inspection{}}},
+			{Method: http.MethodPost, Path: "/ncr", Summary: "Open a non-conformance report",
+				Body: NCR{}, Status: http.StatusCreated, Response: NCR{}, Errors: []int{http.StatusBadRequest},
+				Example: NCR{Serial: "SN-1", SKU: "WIDGET-001", Description: "scratched housing"}},
+			{Method: http.MethodGet, Path: "/ncr", Summary: "List non-conformance reports",
+				Query: []string{"status"}, Response: openapi.Object{"ncrs": []NCR{}}},
+			{Method: http.MethodGet, Path: "/ncr/{id}", Summary: "Get a non-conformance report",
+				Response: NCR{}, Errors: []int{http.StatusNotFound}},
+			{Method: http.MethodPost, Path: "/ncr/{id}/close", Summary: "Close a non-conformance report",
+				Response: NCR{}, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPost, Path: "/holds", Summary: "Put a serial or lot on hold",
+				Body: Hold{}, Status: http.StatusCreated, Response: Hold{}, Errors: []int{http.StatusBadRequest},
+				Example: Hold{Lot: "LOT-1", Reason: "supplier recall"}},
+			{Method: http.MethodGet, Path: "/holds", Summary: "List holds; active=true leaves out released ones",
+				Query: []string{"active"}, Response: openapi.Object{"holds": []Hold{}}},
+			{Method: http.MethodGet, Path: "/holds/{id}", Summary: "Get a hold",
+				Response: Hold{}, Errors: []int{http.StatusNotFound}},
+			{Method: http.MethodPost, Path: "/holds/{id}/release", Summary: "Release a hold",
+				Response: Hold{}, Errors: []int{http.StatusBadRequest}},
+		},
+		openapi.Common("qms"),
+	),
+}
@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
 )
 
 //go:embed static/index.html
@@ -13,18 +16,34 @@ var dashboardHTML []byte
 // Server is the QMS HTTP API server.
 type Server struct {
 	Service *Service
+	Auth    *auth.Authenticator // optional: API key/JWT authentication and role checks; nil leaves the API open
+	Audit   *audit.Log          // optional: records every change made through the API; GET /audit
 }
 
+// AuthRules are the QMS API's route permissions; see auth.Rule.
+var AuthRules = auth.Join(
+	audit.AuthRules,
+	auth.Public("/", "/ui", "/health", "/openapi.json"),
+	auth.Writes("/inspections", auth.RoleQuality, auth.RoleOperator),
+	auth.Writes("/ncr", auth.RoleQuality),
+	auth.Writes("/ncr/", auth.RoleQuality),
+	auth.Writes("/holds", auth.RoleQuality),
+	auth.Writes("/holds/", auth.RoleQuality),
+)
+
 // Handler returns the HTTP handler.
 func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", s.handleHealth)
+	mux.Handle("/openapi.json", OpenAPI)
+	mux.Handle("/audit", s.Audit)
+	mux.Handle("/audit/", s.Audit)
 	mux.HandleFunc("/inspections", s.handleInspections)
 	mux.HandleFunc("/ncr", s.handleNCR)
 	mux.HandleFunc("/ncr/", s.handleNCRByID)
 	mux.HandleFunc("/holds", s.handleHolds)
 	mux.HandleFunc("/holds/", s.handleHoldByID)
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		p := r.URL.Path
 		if p == "/" || p == "/ui" || p == "/ui/" {
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -34,6 +53,7 @@ func (s *Server) Handler() http.Handler {
 		}
 		mux.ServeHTTP(w, r)
 	})
+	return s.Audit.Wrap(s.Auth.Wrap(h))
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -57,6 +77,7 @@ func (s *Server) handleInspections(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.Audit.Record(r, "inspection.create", "inspection", created.ID, nil, created)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(created)
@@ -85,6 +106,7 @@ func (s *Server) handleNCR(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.Audit.Record(r, "ncr.create", "ncr", created.ID, nil, created)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(created)
@@ -106,15 +128,19 @@ func (s *Server) handleNCRByID(w http.ResponseWriter, r *http.Request) {
 		action := id[idx+1:]
 		id = id[:idx]
 		if action == "close" && r.Method == http.MethodPost {
+			before := s.Service.GetNCR(r.Context(), id)
 			n, err := s.Service.CloseNCR(r.Context(), id)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
+			s.Audit.Record(r, "ncr.close", "ncr", id, before, n)
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(n)
 			return
 		}
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 	if r.Method == http.MethodGet {
 		n := s.Service.GetNCR(r.Context(), id)
@@ -141,6 +167,7 @@ func (s *Server) handleHolds(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.Audit.Record(r, "hold.create", "hold", created.ID, nil, created)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(created)
@@ -162,15 +189,19 @@ func (s *Server) handleHoldByID(w http.ResponseWriter, r *http.Request) {
 		action := id[idx+1:]
 		id = id[:idx]
 		if action == "release" && r.Method == http.MethodPost {
+			before := s.Service.GetHold(r.Context(), id)
 			h, err := s.Service.ReleaseHold(r.Context(), id)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
+			s.Audit.Record(r, "hold.release", "hold", id, before, h)
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(h)
 			return
 		}
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 	if r.Method == http.MethodGet {
 		h := s.Service.GetHold(r.Context(), id)
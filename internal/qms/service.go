@@ -8,11 +8,11 @@ import (
 
 // Service implements QMS business logic.
 type Service struct {
-	Store *Store
+	Store Store
 }
 
 // NewService returns a QMS service.
-func NewService(store *Store) *Service {
+func NewService(store Store) *Service {
 	return &Service{Store: store}
 }
 
@@ -27,7 +27,7 @@ func (s *Service) RecordInspection(ctx context.Context, r *Inspection) (*Inspect
 	if r.Serial == "" && r.Lot == "" {
 		return nil, fmt.Errorf("serial or lot required")
 	}
-	return s.Store.AddInspection(r), nil
+	return s.Store.AddInspection(r)
 }
 
 // ListInspections returns inspections with optional filters.
@@ -43,7 +43,7 @@ func (s *Service) CreateNCR(ctx context.Context, n *NCR) (*NCR, error) {
 	if n.Serial == "" && n.Lot == "" {
 		return nil, fmt.Errorf("serial or lot required")
 	}
-	return s.Store.CreateNCR(n), nil
+	return s.Store.CreateNCR(n)
 }
 
 // GetNCR returns an NCR by ID.
@@ -63,7 +63,9 @@ func (s *Service) CloseNCR(ctx context.Context, id string) (*NCR, error) {
 	now := time.Now().UTC()
 	n.Status = NCRStatusClosed
 	n.ClosedAt = &now
-	s.Store.UpdateNCR(n)
+	if err := s.Store.UpdateNCR(n); err != nil {
+		return nil, err
+	}
 	return s.Store.GetNCR(id), nil
 }
 
@@ -80,7 +82,7 @@ func (s *Service) CreateHold(ctx context.Context, h *Hold) (*Hold, error) {
 	if h.Serial == "" && h.Lot == "" {
 		return nil, fmt.Errorf("serial or lot required")
 	}
-	return s.Store.CreateHold(h), nil
+	return s.Store.CreateHold(h)
 }
 
 // ReleaseHold releases a hold.
@@ -94,7 +96,9 @@ func (s *Service) ReleaseHold(ctx context.Context, id string) (*Hold, error) {
 	}
 	now := time.Now().UTC()
 	h.ReleasedAt = &now
-	s.Store.UpdateHold(h)
+	if err := s.Store.UpdateHold(h); err != nil {
+		return nil, err
+	}
 	return s.Store.GetHold(id), nil
 }
 
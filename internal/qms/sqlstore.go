@@ -0,0 +1,228 @@
+package qms
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
+)
+
+// sqlMigrations is the QMS schema, one entry per version; only ever append (see storage.Migrate).
+// Inspections are a log like in memory: IDs are not unique keys there.
+var sqlMigrations = []string{`
+CREATE TABLE qms_inspections (
+	id     TEXT NOT NULL,
+	serial TEXT NOT NULL,
+	lot    TEXT NOT NULL,
+	data   TEXT NOT NULL
+);
+CREATE INDEX qms_inspections_serial ON qms_inspections (serial);
+CREATE INDEX qms_inspections_lot ON qms_inspections (lot);
+CREATE TABLE qms_ncrs (
+	id     TEXT PRIMARY KEY,
+	status TEXT NOT NULL,
+	data   TEXT NOT NULL
+);
+CREATE TABLE qms_holds (
+	id       TEXT PRIMARY KEY,
+	released INTEGER NOT NULL,
+	data     TEXT NOT NULL
+);`,
+}
+
+// SQLStore keeps inspections, NCRs and holds in a database, each as JSON with the fields it is filtered
+// on in columns.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore migrates db to the current QMS schema and returns a store on it.
+func NewSQLStore(db *sql.DB) (*SQLStore, error) {
+	if err := storage.Migrate(db, "qms", sqlMigrations); err != nil {
+		return nil, err
+	}
+	return &SQLStore{db: db}, nil
+}
+
+// AddInspection appends an inspection.
+func (s *SQLStore) AddInspection(r *Inspection) (*Inspection, error) {
+	err := storage.InTx(s.db, func(tx *sql.Tx) error {
+		if r.ID == "" {
+			n, err := storage.NextSeq(tx, "qms_inspections")
+			if err != nil {
+				return err
+			}
+			r.ID = "ins-" + formatSeq(n)
+		}
+		if r.CreatedAt.IsZero() {
+			r.CreatedAt = time.Now().UTC()
+		}
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO qms_inspections (id, serial, lot, data) VALUES (?, ?, ?, ?)`,
+			r.ID, r.Serial, r.Lot, string(data))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store inspection: %w", err)
+	}
+	cp := *r
+	return &cp, nil
+}
+
+// ListInspections returns inspections in the order added, optionally filtered by serial or lot.
+func (s *SQLStore) ListInspections(serial, lot string) []Inspection {
+	var out []Inspection
+	err := storage.EachJSON(s.db, func(data []byte) error {
+		var r Inspection
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		out = append(out, r)
+		return nil
+	}, `SELECT data FROM qms_inspections WHERE (? = '' OR serial = ?) AND (? = '' OR lot = ?) ORDER BY rowid`,
+		serial, serial, lot, lot)
+	if err != nil {
+		log.Printf("qms: store: list inspections: %v", err)
+	}
+	return out
+}
+
+// CreateNCR adds an NCR.
+func (s *SQLStore) CreateNCR(n *NCR) (*NCR, error) {
+	err := storage.InTx(s.db, func(tx *sql.Tx) error {
+		if n.ID == "" {
+			seq, err := storage.NextSeq(tx, "qms_ncrs")
+			if err != nil {
+				return err
+			}
+			n.ID = "ncr-" + formatSeq(seq)
+		}
+		initNCR(n)
+		data, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		return storage.Insert(tx, `INSERT INTO qms_ncrs (id, status, data) VALUES (?, ?, ?)`, n.ID, string(n.Status), string(data))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store ncr: %w", err)
+	}
+	cp := *n
+	return &cp, nil
+}
+
+// GetNCR returns an NCR by ID.
+func (s *SQLStore) GetNCR(id string) *NCR {
+	var n NCR
+	ok, err := storage.GetJSON(s.db, &n, `SELECT data FROM qms_ncrs WHERE id = ?`, id)
+	if err != nil {
+		log.Printf("qms: store: get ncr %s: %v", id, err)
+	}
+	if !ok || err != nil {
+		return nil
+	}
+	return &n
+}
+
+// UpdateNCR updates an NCR.
+func (s *SQLStore) UpdateNCR(n *NCR) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	if err := storage.ExecOne(s.db, `UPDATE qms_ncrs SET status = ?, data = ? WHERE id = ?`, string(n.Status), string(data), n.ID); err != nil {
+		return fmt.Errorf("ncr %s: %w", n.ID, err)
+	}
+	return nil
+}
+
+// ListNCRs returns NCRs in creation order, optionally filtered by status.
+func (s *SQLStore) ListNCRs(status NCRStatus) []NCR {
+	var out []NCR
+	err := storage.EachJSON(s.db, func(data []byte) error {
+		var n NCR
+		if err := json.Unmarshal(data, &n); err != nil {
+			return err
+		}
+		out = append(out, n)
+		return nil
+	}, `SELECT data FROM qms_ncrs WHERE ? = '' OR status = ? ORDER BY rowid`, string(status), string(status))
+	if err != nil {
+		log.Printf("qms: store: list ncrs: %v", err)
+	}
+	return out
+}
+
+// CreateHold adds a hold.
+func (s *SQLStore) CreateHold(h *Hold) (*Hold, error) {
+	err := storage.InTx(s.db, func(tx *sql.Tx) error {
+		if h.ID == "" {
+			n, err := storage.NextSeq(tx, "qms_holds")
+			if err != nil {
+				return err
+			}
+			h.ID = "hold-" + formatSeq(n)
+		}
+		if h.HeldAt.IsZero() {
+			h.HeldAt = time.Now().UTC()
+		}
+		data, err := json.Marshal(h)
+		if err != nil {
+			return err
+		}
+		return storage.Insert(tx, `INSERT INTO qms_holds (id, released, data) VALUES (?, ?, ?)`, h.ID, h.ReleasedAt != nil, string(data))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store hold: %w", err)
+	}
+	cp := *h
+	return &cp, nil
+}
+
+// GetHold returns a hold by ID.
+func (s *SQLStore) GetHold(id string) *Hold {
+	var h Hold
+	ok, err := storage.GetJSON(s.db, &h, `SELECT data FROM qms_holds WHERE id = ?`, id)
+	if err != nil {
+		log.Printf("qms: store: get hold %s: %v", id, err)
+	}
+	if !ok || err != nil {
+		return nil
+	}
+	return &h
+}
+
+// UpdateHold updates a hold.
+func (s *SQLStore) UpdateHold(h *Hold) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	if err := storage.ExecOne(s.db, `UPDATE qms_holds SET released = ?, data = ? WHERE id = ?`, h.ReleasedAt != nil, string(data), h.ID); err != nil {
+		return fmt.Errorf("hold %s: %w", h.ID, err)
+	}
+	return nil
+}
+
+// ListHolds returns holds in creation order (active only if activeOnly).
+func (s *SQLStore) ListHolds(activeOnly bool) []Hold {
+	var out []Hold
+	err := storage.EachJSON(s.db, func(data []byte) error {
+		var h Hold
+		if err := json.Unmarshal(data, &h); err != nil {
+			return err
+		}
+		out = append(out, h)
+		return nil
+	}, `SELECT data FROM qms_holds WHERE NOT ? OR NOT released ORDER BY rowid`, activeOnly)
+	if err != nil {
+		log.Printf("qms: store: list holds: %v", err)
+	}
+	return out
+}
@@ -1,13 +1,64 @@
 package qms
 
 import (
+	"database/sql"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
-// Store is an in-memory store for inspections, NCRs, and holds.
-type Store struct {
+// Store holds inspections, NCRs, and holds: MemoryStore in memory, SQLStore in a database (see
+// pkg/storage). Reads log a database failure and report nothing found; writes return it.
+type Store interface {
+	// AddInspection appends an inspection.
+	AddInspection(r *Inspection) (*Inspection, error)
+	// ListInspections returns inspections in the order added, optionally filtered by serial or lot.
+	ListInspections(serial, lot string) []Inspection
+	// CreateNCR adds an NCR; storage.ErrExists if its ID is taken.
+	CreateNCR(n *NCR) (*NCR, error)
+	// GetNCR returns an NCR by ID.
+	GetNCR(id string) *NCR
+	// UpdateNCR updates an NCR; storage.ErrNotFound if there is none.
+	UpdateNCR(n *NCR) error
+	// ListNCRs returns NCRs, optionally filtered by status.
+	ListNCRs(status NCRStatus) []NCR
+	// CreateHold adds a hold; storage.ErrExists if its ID is taken.
+	CreateHold(h *Hold) (*Hold, error)
+	// GetHold returns a hold by ID.
+	GetHold(id string) *Hold
+	// UpdateHold updates a hold; storage.ErrNotFound if there is none.
+	UpdateHold(h *Hold) error
+	// ListHolds returns holds (active only if activeOnly).
+	ListHolds(activeOnly bool) []Hold
+}
+
+// NewStore returns an SQLStore on db, or a MemoryStore if db is nil (the memory backend).
+func NewStore(db *sql.DB) (Store, error) {
+	if db == nil {
+		return NewMemoryStore(), nil
+	}
+	s, err := NewSQLStore(db)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// initNCR sets the defaults of a new NCR.
+func initNCR(n *NCR) {
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now().UTC()
+	}
+	if n.Status == "" {
+		n.Status = NCRStatusOpen
+	}
+}
+
+// MemoryStore is an in-memory store for inspections, NCRs, and holds.
+type MemoryStore struct {
 	mu          sync.RWMutex
 	inspections []Inspection
 	insSeq      atomic.Uint64
@@ -17,9 +68,9 @@ type Store struct {
 	holdMap     map[string]*Hold
 }
 
-// NewStore returns a new QMS store.
-func NewStore() *Store {
-	return &Store{
+// NewMemoryStore returns a new in-memory QMS store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
 		inspections: make([]Inspection, 0),
 		ncrMap:      make(map[string]*NCR),
 		holdMap:     make(map[string]*Hold),
@@ -39,7 +90,7 @@ func formatSeq(n uint64) string {
 }
 
 // AddInspection appends an inspection.
-func (s *Store) AddInspection(r *Inspection) *Inspection {
+func (s *MemoryStore) AddInspection(r *Inspection) (*Inspection, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if r.ID == "" {
@@ -50,11 +101,11 @@ func (s *Store) AddInspection(r *Inspection) *Inspection {
 	}
 	cp := *r
 	s.inspections = append(s.inspections, cp)
-	return &s.inspections[len(s.inspections)-1]
+	return &cp, nil
 }
 
 // ListInspections returns inspections, optionally filtered by serial or lot.
-func (s *Store) ListInspections(serial, lot string) []Inspection {
+func (s *MemoryStore) ListInspections(serial, lot string) []Inspection {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var out []Inspection
@@ -71,25 +122,23 @@ func (s *Store) ListInspections(serial, lot string) []Inspection {
 }
 
 // CreateNCR adds an NCR.
-func (s *Store) CreateNCR(n *NCR) *NCR {
+func (s *MemoryStore) CreateNCR(n *NCR) (*NCR, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if n.ID == "" {
 		n.ID = "ncr-" + formatSeq(s.ncrSeq.Add(1))
 	}
-	if n.CreatedAt.IsZero() {
-		n.CreatedAt = time.Now().UTC()
-	}
-	if n.Status == "" {
-		n.Status = NCRStatusOpen
+	if _, ok := s.ncrMap[n.ID]; ok {
+		return nil, fmt.Errorf("store ncr: %w", storage.ErrExists)
 	}
+	initNCR(n)
 	cp := *n
 	s.ncrMap[cp.ID] = &cp
-	return &cp
+	return &cp, nil
 }
 
 // GetNCR returns an NCR by ID.
-func (s *Store) GetNCR(id string) *NCR {
+func (s *MemoryStore) GetNCR(id string) *NCR {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	n, ok := s.ncrMap[id]
@@ -101,19 +150,19 @@ func (s *Store) GetNCR(id string) *NCR {
 }
 
 // UpdateNCR updates an NCR.
-func (s *Store) UpdateNCR(n *NCR) bool {
+func (s *MemoryStore) UpdateNCR(n *NCR) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, ok := s.ncrMap[n.ID]; !ok {
-		return false
+		return fmt.Errorf("ncr %s: %w", n.ID, storage.ErrNotFound)
 	}
 	cp := *n
 	s.ncrMap[n.ID] = &cp
-	return true
+	return nil
 }
 
 // ListNCRs returns NCRs, optionally filtered by status.
-func (s *Store) ListNCRs(status NCRStatus) []NCR {
+func (s *MemoryStore) ListNCRs(status NCRStatus) []NCR {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var out []NCR
@@ -127,22 +176,25 @@ func (s *Store) ListNCRs(status NCRStatus) []NCR {
 }
 
 // CreateHold adds a hold.
-func (s *Store) CreateHold(h *Hold) *Hold {
+func (s *MemoryStore) CreateHold(h *Hold) (*Hold, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if h.ID == "" {
 		h.ID = "hold-" + formatSeq(s.holdSeq.Add(1))
 	}
+	if _, ok := s.holdMap[h.ID]; ok {
+		return nil, fmt.Errorf("store hold: %w", storage.ErrExists)
+	}
 	if h.HeldAt.IsZero() {
 		h.HeldAt = time.Now().UTC()
 	}
 	cp := *h
 	s.holdMap[cp.ID] = &cp
-	return &cp
+	return &cp, nil
 }
 
 // GetHold returns a hold by ID.
-func (s *Store) GetHold(id string) *Hold {
+func (s *MemoryStore) GetHold(id string) *Hold {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	h, ok := s.holdMap[id]
@@ -154,19 +206,19 @@ func (s *Store) GetHold(id string) *Hold {
 }
 
 // UpdateHold updates a hold.
-func (s *Store) UpdateHold(h *Hold) bool {
+func (s *MemoryStore) UpdateHold(h *Hold) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, ok := s.holdMap[h.ID]; !ok {
-		return false
+		return fmt.Errorf("hold %s: %w", h.ID, storage.ErrNotFound)
 	}
 	cp := *h
 	s.holdMap[h.ID] = &cp
-	return true
+	return nil
 }
 
 // ListHolds returns holds (active only if activeOnly).
-func (s *Store) ListHolds(activeOnly bool) []Hold {
+func (s *MemoryStore) ListHolds(activeOnly bool) []Hold {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var out []Hold
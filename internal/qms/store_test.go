@@ -0,0 +1,125 @@
+package qms
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
+)
+
+// testStores returns a MemoryStore and an SQLStore on a new SQLite file, to run the same checks on both.
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	db, err := storage.Open(storage.Config{Backend: storage.BackendSQLite, Path: filepath.Join(t.TempDir(), "qms.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s, err := NewSQLStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Store{"memory": NewMemoryStore(), "sql": s}
+}
+
+// ids returns the sorted IDs of a list, which the memory store returns in no particular order.
+func ids[T any](items []T, id func(T) string) string {
+	var out []string
+	for _, it := range items {
+		out = append(out, id(it))
+	}
+	sort.Strings(out)
+	return strings.Join(out, " ")
+}
+
+func TestStore(t *testing.T) {
+	inspectionID := func(r Inspection) string { return r.ID }
+	ncrID := func(n NCR) string { return n.ID }
+	holdID := func(h Hold) string { return h.ID }
+	for name, s := range testStores(t) {
+		for _, r := range []*Inspection{
+			{Serial: "sn-1", Lot: "lot-1", SKU: "widget", Result: InspectionPass},
+			{Serial: "sn-2", Lot: "lot-1", SKU: "widget", Result: InspectionFail},
+			{ID: "ins-x", Serial: "sn-1", SKU: "widget", Result: InspectionPass},
+		} {
+			if _, err := s.AddInspection(r); err != nil {
+				t.Fatalf("%s: AddInspection: %v", name, err)
+			}
+		}
+		// Inspections are a log: a reused ID is another entry.
+		if _, err := s.AddInspection(&Inspection{ID: "ins-1", SKU: "widget", Result: InspectionPass}); err != nil {
+			t.Errorf("%s: AddInspection with a used ID: %v", name, err)
+		}
+		for _, tc := range []struct{ serial, lot, want string }{
+			{"", "", "ins-1 ins-1 ins-2 ins-x"},
+			{"sn-1", "", "ins-1 ins-x"},
+			{"", "lot-1", "ins-1 ins-2"},
+			{"sn-2", "lot-2", ""},
+		} {
+			if got := ids(s.ListInspections(tc.serial, tc.lot), inspectionID); got != tc.want {
+				t.Errorf("%s: ListInspections(%q, %q) = %s, want %s", name, tc.serial, tc.lot, got, tc.want)
+			}
+		}
+
+		n, err := s.CreateNCR(&NCR{Serial: "sn-2", SKU: "widget", Description: "scratch"})
+		if err != nil || n.ID != "ncr-1" || n.Status != NCRStatusOpen || n.CreatedAt.IsZero() {
+			t.Fatalf("%s: CreateNCR = %+v, %v", name, n, err)
+		}
+		if _, err := s.CreateNCR(&NCR{SKU: "widget", Description: "dent"}); err != nil {
+			t.Fatalf("%s: second CreateNCR: %v", name, err)
+		}
+		if _, err := s.CreateNCR(&NCR{ID: "ncr-1", SKU: "other"}); !errors.Is(err, storage.ErrExists) {
+			t.Errorf("%s: CreateNCR with a taken ID = %v, want ErrExists", name, err)
+		}
+		n.Status = NCRStatusClosed
+		if err := s.UpdateNCR(n); err != nil {
+			t.Fatalf("%s: UpdateNCR: %v", name, err)
+		}
+		if got := s.GetNCR("ncr-1"); got == nil || got.Status != NCRStatusClosed || got.Description != "scratch" {
+			t.Errorf("%s: after UpdateNCR GetNCR = %+v", name, got)
+		}
+		if got := s.GetNCR("ncr-9"); got != nil {
+			t.Errorf("%s: GetNCR of a missing NCR = %+v", name, got)
+		}
+		if err := s.UpdateNCR(&NCR{ID: "ncr-9"}); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s: UpdateNCR of a missing NCR = %v, want ErrNotFound", name, err)
+		}
+		if got := ids(s.ListNCRs(NCRStatusOpen), ncrID); got != "ncr-2" {
+			t.Errorf("%s: open NCRs %s, want ncr-2", name, got)
+		}
+		if got := ids(s.ListNCRs(""), ncrID); got != "ncr-1 ncr-2" {
+			t.Errorf("%s: all NCRs %s", name, got)
+		}
+
+		h, err := s.CreateHold(&Hold{Lot: "lot-1", Reason: "failed inspection"})
+		if err != nil || h.ID != "hold-1" {
+			t.Fatalf("%s: CreateHold = %+v, %v", name, h, err)
+		}
+		if _, err := s.CreateHold(&Hold{Serial: "sn-2", Reason: "scratch"}); err != nil {
+			t.Fatalf("%s: second CreateHold: %v", name, err)
+		}
+		if _, err := s.CreateHold(&Hold{ID: "hold-2", Reason: "other"}); !errors.Is(err, storage.ErrExists) {
+			t.Errorf("%s: CreateHold with a taken ID = %v, want ErrExists", name, err)
+		}
+		released := h.HeldAt.Add(1)
+		h.ReleasedAt = &released
+		if err := s.UpdateHold(h); err != nil {
+			t.Fatalf("%s: UpdateHold: %v", name, err)
+		}
+		if got := s.GetHold("hold-1"); got == nil || got.ReleasedAt == nil || got.Reason != "failed inspection" {
+			t.Errorf("%s: after UpdateHold GetHold = %+v", name, got)
+		}
+		if err := s.UpdateHold(&Hold{ID: "hold-9"}); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s: UpdateHold of a missing hold = %v, want ErrNotFound", name, err)
+		}
+		if got := ids(s.ListHolds(true), holdID); got != "hold-2" {
+			t.Errorf("%s: active holds %s, want hold-2", name, got)
+		}
+		if got := ids(s.ListHolds(false), holdID); got != "hold-1 hold-2" {
+			t.Errorf("%s: all holds %s", name, got)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+// Package sim runs the area, zone and edge layers as a deterministic discrete-event simulation: every
+// controller and the robot simulator share a clock.Virtual and an in-memory bus, so a shift runs as fast as
+// its events can be processed and the same options always produce the same report.
+package sim
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/internal/area"
+	"github.com/robotfleetos/robotfleetos/internal/edge"
+	"github.com/robotfleetos/robotfleetos/internal/zone"
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/clock"
+	"github.com/robotfleetos/robotfleetos/pkg/facility"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+// Options describe one simulated shift. Zero fields take defaults.
+type Options struct {
+	Start            time.Time            // virtual start (default 2025-01-06T06:00:00Z)
+	Duration         time.Duration        // virtual length (default 8h)
+	AreaID           api.AreaID           // default area-1
+	ZoneID           api.ZoneID           // default zone-1
+	Robots           int                  // robot-1..robot-N (default 100)
+	OrderRatePerHour float64              // work orders per hour, Poisson arrivals (default 4 per robot)
+	Seed             int64                // workload seed; also the simulator seed unless Sim is set (default 1)
+	Sim              *edge.SimConfig      // physical model (default edge.DefaultSimConfig with Seed)
+	StatusInterval   time.Duration        // robot status interval (default 15s; status JSON dominates run time)
+	ReportInterval   time.Duration        // zone and area summary interval (default 30s)
+	TaskDuration     time.Duration        // duration of tasks without locations (default 2m)
+	Map              *facility.Map        // facility map: orders go between its location nodes and the zone routes robots
+	Charging         *zone.ChargingPolicy // zone charging (default zone.DefaultChargingPolicy); chargers default to the map's, else Sim's
+}
+
+func (o *Options) setDefaults() {
+	if o.Start.IsZero() {
+		o.Start = time.Date(2025, 1, 6, 6, 0, 0, 0, time.UTC)
+	}
+	if o.Duration <= 0 {
+		o.Duration = 8 * time.Hour
+	}
+	if o.AreaID == "" {
+		o.AreaID = "area-1"
+	}
+	if o.ZoneID == "" {
+		o.ZoneID = "zone-1"
+	}
+	if o.Robots <= 0 {
+		o.Robots = 100
+	}
+	if o.OrderRatePerHour <= 0 {
+		o.OrderRatePerHour = 4 * float64(o.Robots)
+	}
+	if o.Seed == 0 {
+		o.Seed = 1
+	}
+	if o.Sim == nil {
+		o.Sim = edge.DefaultSimConfig()
+		o.Sim.Seed = o.Seed
+	}
+	if o.StatusInterval <= 0 {
+		o.StatusInterval = 15 * time.Second
+	}
+	if o.ReportInterval <= 0 {
+		o.ReportInterval = 30 * time.Second
+	}
+	if o.TaskDuration <= 0 {
+		o.TaskDuration = 2 * time.Minute
+	}
+}
+
+// chargingPolicy returns the zone's charging policy. Without a map or listed chargers the zone manages the
+// simulator's chargers.
+func (o *Options) chargingPolicy() zone.ChargingPolicy {
+	p := zone.DefaultChargingPolicy()
+	if o.Charging != nil {
+		p = *o.Charging
+	}
+	if o.Map == nil && len(p.Chargers) == 0 {
+		for i, pt := range o.Sim.Chargers {
+			p.Chargers = append(p.Chargers, zone.Charger{ID: fmt.Sprintf("charger-%d", i+1), Position: pt})
+		}
+	}
+	return p
+}
+
+// Report is the outcome of a shift. Everything except WallTime is a function of the options only; Digest
+// hashes the final state of every robot so two runs can be compared with one string.
+type Report struct {
+	Seed               int64   `json:"seed"`
+	Start              string  `json:"start"`
+	End                string  `json:"end"`
+	VirtualDuration    string  `json:"virtual_duration"`
+	Robots             int     `json:"robots"`
+	Events             int     `json:"events"`
+	OrdersSubmitted    int     `json:"orders_submitted"`
+	TasksDispatched    int     `json:"tasks_dispatched"`
+	TasksCompleted     int     `json:"tasks_completed"`
+	TasksQueuedAtEnd   int     `json:"tasks_queued_at_end"` // on robots and held by the zone
+	Reroutes           int     `json:"reroutes"`            // REROUTE commands the zone sent to break deadlocks and stalls
+	MovesAside         int     `json:"moves_aside"`         // MOVE commands sending idle robots out of the way
+	Charges            int     `json:"charges"`             // CHARGE commands the zone sent
+	ChargerUtilization float64 `json:"charger_utilization"` // mean share of charger time in use over the zone's summaries
+	TasksPerRobotHour  float64 `json:"tasks_per_robot_hour"`
+	OdometerKm         float64 `json:"odometer_km"`
+	BusyRobotHours     float64 `json:"busy_robot_hours"`    // BUSY and not waiting
+	WaitingRobotHours  float64 `json:"waiting_robot_hours"` // BUSY but waiting for a node another robot holds
+	IdleRobotHours     float64 `json:"idle_robot_hours"`
+	ChargingRobotHours float64 `json:"charging_robot_hours"`
+	ErrorRobotHours    float64 `json:"error_robot_hours"`
+	Utilization        float64 `json:"utilization"` // busy (not waiting) / total robot hours
+	BatteryAvgEnd      float64 `json:"battery_avg_end"`
+	BatteryMin         float64 `json:"battery_min"`
+	Digest             string  `json:"digest"`
+	WallTime           string  `json:"wall_time"`
+}
+
+// Run simulates one shift on a virtual clock and returns its report.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	opts.setDefaults()
+	wallStart := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	clk := clock.NewVirtual(opts.Start)
+	bus := messaging.NewMemoryBus()
+	robots := make([]api.RobotID, opts.Robots)
+	for i := range robots {
+		robots[i] = api.RobotID(fmt.Sprintf("robot-%d", i+1))
+	}
+
+	obs := newObserver(opts.StatusInterval)
+	if err := bus.Subscribe(ctx, messaging.TopicRobotStatus, obs.handleStatus); err != nil {
+		return nil, err
+	}
+	if err := bus.Subscribe(ctx, messaging.TopicRobotCommands, obs.handleCommand); err != nil {
+		return nil, err
+	}
+	if err := bus.Subscribe(ctx, messaging.TopicZoneSummary, obs.handleZoneSummary); err != nil {
+		return nil, err
+	}
+
+	areaCtrl := area.NewController(opts.AreaID, []api.ZoneID{opts.ZoneID},
+		messaging.NewZoneTaskPublisher(bus), messaging.NewAreaSummaryPublisher(bus), bus, opts.ReportInterval)
+	areaCtrl.SetClock(clk)
+	zoneCtrl := zone.NewController(opts.ZoneID, robots,
+		messaging.NewRobotCommandPublisher(bus), messaging.NewZoneSummaryPublisher(bus), bus, opts.ReportInterval)
+	zoneCtrl.SetClock(clk)
+	if opts.Map != nil {
+		zoneCtrl.SetFacilityMap(opts.Map)
+	}
+	zoneCtrl.SetChargingPolicy(opts.chargingPolicy())
+	simulator := edge.NewSimulator(opts.ZoneID, robots, messaging.NewRobotStatusPublisher(bus), bus, opts.StatusInterval, opts.TaskDuration)
+	simulator.SetSimConfig(opts.Sim)
+	simulator.SetClock(clk)
+	for _, start := range []func(context.Context) error{areaCtrl.Start, zoneCtrl.Start, simulator.Start} {
+		if err := start(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	gen := newWorkload(opts, clk, messaging.NewWorkOrderPublisher(bus))
+	gen.schedule(ctx)
+	events := clk.RunUntil(opts.Start.Add(opts.Duration))
+	if gen.err != nil {
+		return nil, gen.err
+	}
+
+	r := obs.report(robots)
+	r.Seed = opts.Seed
+	r.Start = opts.Start.Format(time.RFC3339)
+	r.End = clk.Now().Format(time.RFC3339)
+	r.VirtualDuration = opts.Duration.String()
+	r.Robots = opts.Robots
+	r.Events = events
+	r.OrdersSubmitted = gen.orders
+	if hours := opts.Duration.Hours() * float64(opts.Robots); hours > 0 {
+		r.TasksPerRobotHour = round2(float64(r.TasksCompleted) / hours)
+	}
+	r.WallTime = time.Since(wallStart).Round(time.Millisecond).String()
+	return r, nil
+}
+
+// workload submits work orders with exponential inter-arrival times, the way the fleet scheduler would.
+type workload struct {
+	opts   Options
+	clk    clock.Clock
+	pub    messaging.WorkOrderPublisher
+	rng    *rand.Rand
+	points []api.Point
+	nodes  []facility.Node // map location nodes; used instead of points when set
+	orders int
+	err    error
+}
+
+func newWorkload(opts Options, clk clock.Clock, pub messaging.WorkOrderPublisher) *workload {
+	w := &workload{opts: opts, clk: clk, pub: pub, rng: rand.New(rand.NewSource(opts.Seed))}
+	if opts.Map != nil {
+		for _, n := range opts.Map.Nodes {
+			if n.LocationID != "" {
+				w.nodes = append(w.nodes, n)
+			}
+		}
+	}
+	ids := make([]string, 0, len(opts.Sim.Locations))
+	for id := range opts.Sim.Locations {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		w.points = append(w.points, opts.Sim.Locations[id])
+	}
+	return w
+}
+
+func (w *workload) schedule(ctx context.Context) {
+	gap := time.Duration(w.rng.ExpFloat64() / w.opts.OrderRatePerHour * float64(time.Hour))
+	w.clk.AfterFunc(gap, func() {
+		if ctx.Err() != nil || w.err != nil {
+			return
+		}
+		if err := w.submit(ctx); err != nil {
+			w.err = err
+			return
+		}
+		w.schedule(ctx)
+	})
+}
+
+func (w *workload) submit(ctx context.Context) error {
+	w.orders++
+	payload, err := json.Marshal(w.locations())
+	if err != nil {
+		return err
+	}
+	return w.pub.PublishWorkOrder(ctx, &api.WorkOrder{
+		ID:        api.WorkOrderID(fmt.Sprintf("order-%06d", w.orders)),
+		AreaID:    w.opts.AreaID,
+		Priority:  1,
+		Payload:   payload,
+		CreatedAt: w.clk.Now().UTC(),
+	})
+}
+
+// locations picks the order's pick and drop: two location nodes of the map, or two points.
+func (w *workload) locations() api.TaskLocations {
+	if len(w.nodes) == 0 {
+		return api.TaskLocations{From: w.point(), To: w.point()}
+	}
+	from, to := w.nodes[w.rng.Intn(len(w.nodes))], w.nodes[w.rng.Intn(len(w.nodes))]
+	fromPt, toPt := from.Point(), to.Point()
+	return api.TaskLocations{
+		FromLocationID: from.LocationID,
+		ToLocationID:   to.LocationID,
+		FromNodeID:     from.ID,
+		ToNodeID:       to.ID,
+		From:           &fromPt,
+		To:             &toPt,
+	}
+}
+
+// point picks one of the configured locations, or a random spot on a 50×50 m floor if there are none.
+func (w *workload) point() *api.Point {
+	if len(w.points) > 0 {
+		p := w.points[w.rng.Intn(len(w.points))]
+		return &p
+	}
+	return &api.Point{X: math.Round(w.rng.Float64()*500) / 10, Y: math.Round(w.rng.Float64()*500) / 10}
+}
+
+// stateWaiting is the observer's state for a BUSY robot waiting for a node another robot holds.
+const stateWaiting = "BUSY_WAITING"
+
+// observer accumulates robot time per state from status samples and keeps each robot's last status.
+type observer struct {
+	interval   time.Duration
+	last       map[api.RobotID]api.RobotStatus
+	hours      map[string]float64
+	minBatt    float64
+	tasks      int
+	reroutes   int
+	moves      int
+	charges    int
+	chargerUse []float64 // charger utilization per zone summary
+	held       int       // tasks the zone held at its last summary
+}
+
+func newObserver(interval time.Duration) *observer {
+	return &observer{interval: interval, last: make(map[api.RobotID]api.RobotStatus), hours: make(map[string]float64), minBatt: 100}
+}
+
+func (o *observer) handleStatus(key string, value []byte) error {
+	var st api.RobotStatus
+	if err := json.Unmarshal(value, &st); err != nil {
+		return err
+	}
+	o.last[st.RobotID] = st
+	state := st.State
+	if n, _ := st.Extra[api.ExtraWaitingFor].(string); n != "" && state == "BUSY" {
+		state = stateWaiting
+	}
+	o.hours[state] += o.interval.Hours()
+	o.minBatt = math.Min(o.minBatt, st.Battery)
+	return nil
+}
+
+func (o *observer) handleCommand(key string, value []byte) error {
+	var cmd api.RobotCommand
+	if err := json.Unmarshal(value, &cmd); err != nil {
+		return err
+	}
+	switch cmd.Type {
+	case "TASK":
+		o.tasks++
+	case api.RobotCommandTypeReroute:
+		o.reroutes++
+	case api.RobotCommandTypeMove:
+		o.moves++
+	case api.RobotCommandTypeCharge:
+		o.charges++
+	}
+	return nil
+}
+
+func (o *observer) handleZoneSummary(key string, value []byte) error {
+	var sum api.ZoneSummary
+	if err := json.Unmarshal(value, &sum); err != nil {
+		return err
+	}
+	o.held = sum.TaskQueue
+	if sum.Chargers > 0 {
+		o.chargerUse = append(o.chargerUse, sum.ChargerUtilization)
+	}
+	return nil
+}
+
+func (o *observer) report(robots []api.RobotID) *Report {
+	r := &Report{
+		TasksDispatched:    o.tasks,
+		Reroutes:           o.reroutes,
+		MovesAside:         o.moves,
+		Charges:            o.charges,
+		TasksQueuedAtEnd:   o.held,
+		BusyRobotHours:     round2(o.hours["BUSY"]),
+		WaitingRobotHours:  round2(o.hours[stateWaiting]),
+		IdleRobotHours:     round2(o.hours["IDLE"]),
+		ChargingRobotHours: round2(o.hours[api.RobotStateCharging]),
+		ErrorRobotHours:    round2(o.hours["ERROR"]),
+		BatteryMin:         o.minBatt,
+	}
+	var total, battery, odo float64
+	for _, h := range o.hours {
+		total += h
+	}
+	if total > 0 {
+		r.Utilization = round2(o.hours["BUSY"] / total)
+	}
+	digest := sha256.New()
+	for _, id := range robots {
+		st := o.last[id]
+		battery += st.Battery
+		odo += number(st.Extra[api.ExtraOdometerM])
+		r.TasksCompleted += int(number(st.Extra[api.ExtraTasksCompleted]))
+		r.TasksQueuedAtEnd += int(number(st.Extra[api.ExtraTaskQueue]))
+		fmt.Fprintf(digest, "%s|%s|%s|%.1f|%v|%v\n", id, st.State, st.Position, st.Battery,
+			st.Extra[api.ExtraOdometerM], st.Extra[api.ExtraTasksCompleted])
+	}
+	if len(robots) > 0 {
+		r.BatteryAvgEnd = round2(battery / float64(len(robots)))
+	}
+	r.OdometerKm = round2(odo / 1000)
+	if len(o.chargerUse) > 0 {
+		var use float64
+		for _, u := range o.chargerUse {
+			use += u
+		}
+		r.ChargerUtilization = round2(use / float64(len(o.chargerUse)))
+	}
+	r.Digest = hex.EncodeToString(digest.Sum(nil))[:16]
+	return r
+}
+
+func number(v interface{}) float64 {
+	f, _ := v.(float64)
+	return f
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
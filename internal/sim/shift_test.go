@@ -0,0 +1,32 @@
+package sim
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunDeterministic(t *testing.T) {
+	run := func(seed int64) *Report {
+		t.Helper()
+		r, err := Run(context.Background(), Options{Robots: 20, Duration: 2 * time.Hour, Seed: seed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	a, b := run(7), run(7)
+	if a.Digest != b.Digest {
+		t.Errorf("same seed, digests %s and %s", a.Digest, b.Digest)
+	}
+	a.WallTime, b.WallTime = "", ""
+	if *a != *b {
+		t.Errorf("same seed, reports differ:\n%+v\n%+v", *a, *b)
+	}
+	if a.TasksCompleted == 0 {
+		t.Errorf("no tasks completed: %+v", *a)
+	}
+	if c := run(8); c.Digest == a.Digest {
+		t.Errorf("seeds 7 and 8 gave the same digest %s", a.Digest)
+	}
+}
@@ -0,0 +1,155 @@
+// Package topology describes which areas, zones and robots one process runs, either from a YAML file or
+// from a generator spec such as "10x50x20" (areas × zones per area × robots per zone).
+package topology
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+)
+
+// Topology is the area → zone → robot tree.
+type Topology struct {
+	Areas []Area `yaml:"areas" json:"areas"`
+}
+
+// Area is one area controller and the zones it dispatches to.
+type Area struct {
+	AreaID string `yaml:"area_id" json:"area_id"`
+	Zones  []Zone `yaml:"zones" json:"zones"`
+}
+
+// Zone is one zone controller. Robots lists its robot IDs; RobotCount generates robot-N IDs instead,
+// numbered across the whole topology in file order.
+type Zone struct {
+	ZoneID     string   `yaml:"zone_id" json:"zone_id"`
+	Robots     []string `yaml:"robots,omitempty" json:"robots,omitempty"`
+	RobotCount int      `yaml:"robot_count,omitempty" json:"robot_count,omitempty"`
+}
+
+// Load reads a topology file and resolves robot IDs.
+func Load(path string) (*Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t Topology
+	if err := config.Decode(data, &t); err != nil {
+		return nil, fmt.Errorf("topology %s: %w", path, err)
+	}
+	if err := t.resolve(); err != nil {
+		return nil, fmt.Errorf("topology %s: %w", path, err)
+	}
+	return &t, nil
+}
+
+// Generate builds areas area-1..N, each with zonesPerArea zones (zone-1..M numbered across areas), each
+// with robotsPerZone robots (robot-1..K numbered across zones).
+func Generate(areas, zonesPerArea, robotsPerZone int) *Topology {
+	t := &Topology{Areas: make([]Area, areas)}
+	zoneN := 0
+	for a := range t.Areas {
+		t.Areas[a] = Area{AreaID: fmt.Sprintf("area-%d", a+1), Zones: make([]Zone, zonesPerArea)}
+		for z := range t.Areas[a].Zones {
+			zoneN++
+			t.Areas[a].Zones[z] = Zone{ZoneID: fmt.Sprintf("zone-%d", zoneN), RobotCount: robotsPerZone}
+		}
+	}
+	_ = t.resolve()
+	return t
+}
+
+// Parse accepts a generator spec ("AxZxR", "×" also accepted) or a path to a topology file.
+func Parse(s string) (*Topology, error) {
+	s = strings.TrimSpace(s)
+	parts := strings.Split(strings.ReplaceAll(s, "×", "x"), "x")
+	if len(parts) == 3 {
+		n := make([]int, 3)
+		var err error
+		for i, p := range parts {
+			if n[i], err = strconv.Atoi(strings.TrimSpace(p)); err != nil || n[i] <= 0 {
+				return nil, fmt.Errorf("topology spec %q: want areas x zones-per-area x robots-per-zone", s)
+			}
+		}
+		return Generate(n[0], n[1], n[2]), nil
+	}
+	return Load(s)
+}
+
+// resolve generates robot IDs for RobotCount zones and checks that every ID is set and unique.
+func (t *Topology) resolve() error {
+	if len(t.Areas) == 0 {
+		return fmt.Errorf("no areas")
+	}
+	next := 1
+	areas := make(map[string]bool)
+	zones := make(map[string]bool)
+	robots := make(map[string]string) // robot → zone
+	for a := range t.Areas {
+		area := &t.Areas[a]
+		if area.AreaID == "" {
+			return fmt.Errorf("areas[%d]: area_id is required", a)
+		}
+		if areas[area.AreaID] {
+			return fmt.Errorf("duplicate area %s", area.AreaID)
+		}
+		areas[area.AreaID] = true
+		for z := range area.Zones {
+			zone := &area.Zones[z]
+			if zone.ZoneID == "" {
+				return fmt.Errorf("area %s: zones[%d]: zone_id is required", area.AreaID, z)
+			}
+			if zones[zone.ZoneID] {
+				return fmt.Errorf("duplicate zone %s", zone.ZoneID)
+			}
+			zones[zone.ZoneID] = true
+			if len(zone.Robots) == 0 {
+				for i := 0; i < zone.RobotCount; i++ {
+					zone.Robots = append(zone.Robots, fmt.Sprintf("robot-%d", next))
+					next++
+				}
+			}
+			zone.RobotCount = len(zone.Robots)
+			for _, r := range zone.Robots {
+				if other, dup := robots[r]; dup {
+					return fmt.Errorf("robot %s is in zones %s and %s", r, other, zone.ZoneID)
+				}
+				robots[r] = zone.ZoneID
+			}
+		}
+	}
+	return nil
+}
+
+// Counts returns the number of areas, zones and robots.
+func (t *Topology) Counts() (areas, zones, robots int) {
+	for _, a := range t.Areas {
+		zones += len(a.Zones)
+		for _, z := range a.Zones {
+			robots += len(z.Robots)
+		}
+	}
+	return len(t.Areas), zones, robots
+}
+
+// ZoneIDs returns the area's zone IDs.
+func (a Area) ZoneIDs() []api.ZoneID {
+	ids := make([]api.ZoneID, len(a.Zones))
+	for i, z := range a.Zones {
+		ids[i] = api.ZoneID(z.ZoneID)
+	}
+	return ids
+}
+
+// RobotIDs returns the zone's robot IDs.
+func (z Zone) RobotIDs() []api.RobotID {
+	ids := make([]api.RobotID, len(z.Robots))
+	for i, r := range z.Robots {
+		ids[i] = api.RobotID(r)
+	}
+	return ids
+}
@@ -0,0 +1,31 @@
+package traceability
+
+import (
+	"net/http"
+
+	"github.com/robotfleetos/robotfleetos/pkg/openapi"
+)
+
+// OpenAPI is the traceability API's OpenAPI document, served at GET /openapi.json.
+var OpenAPI = &openapi.API{
+	Title:       "RobotFleetOS Traceability API",
+	Description: "Events of serials and lots, their genealogy and recall queries.",
+	Rules:       AuthRules,
+	Ops: openapi.Join(
+		openapi.Dashboard("/", "/ui"),
+		[]openapi.Op{
+			{Method: http.MethodPost, Path: "/records", Summary: "Record an event of a serial or lot",
+				Body: TraceRecord{}, Status: http.StatusCreated, Response: TraceRecord{}, Errors: []int{http.StatusBadRequest},
+				Example: TraceRecord{Serial: "SN-1", Lot: "LOT-1", EventType: "produced", SKU: "WIDGET-001", Quantity: 1}},
+			{Method: http.MethodGet, Path: "/genealogy", Summary: "Events of a serial (and the serials assembled into it) or of a lot",
+				Query:    []string{"serial", "lot"},
+				Response: openapi.Object{"serial": "", "lot": "", "records": []TraceRecord{}},
+				Errors:   []int{http.StatusBadRequest}},
+			{Method: http.MethodGet, Path: "/recall", Summary: "Events of a lot or SKU, optionally between RFC3339 from and to",
+				Query: []string{"lot", "sku", "from", "to"}, Response: openapi.Object{"records": []TraceRecord{}}},
+			{Method: http.MethodGet, Path: "/stats", Summary: "Number of records",
+				Response: openapi.Object{"total_records": 0}},
+		},
+		openapi.Common("traceability"),
+	),
+}
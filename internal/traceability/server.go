@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
 )
 
 //go:embed static/index.html
@@ -13,17 +16,30 @@ var dashboardHTML []byte
 // Server is the Traceability HTTP API server.
 type Server struct {
 	Service *Service
+	Auth    *auth.Authenticator // optional: API key/JWT authentication and role checks; nil leaves the API open
+	Audit   *audit.Log          // optional: records every change made through the API; GET /audit
 }
 
+// AuthRules are the traceability API's route permissions; see auth.Rule.
+var AuthRules = auth.Join(
+	audit.AuthRules,
+	auth.Public("/", "/ui", "/health", "/openapi.json"),
+	auth.Writes("/records", auth.RoleOperator, auth.RoleQuality),
+	auth.Writes("/recall", auth.RoleQuality),
+)
+
 // Handler returns the HTTP handler: dashboard at / and /ui, API elsewhere.
 func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", s.handleHealth)
+	mux.Handle("/openapi.json", OpenAPI)
+	mux.Handle("/audit", s.Audit)
+	mux.Handle("/audit/", s.Audit)
 	mux.HandleFunc("/records", s.handleRecords)
 	mux.HandleFunc("/genealogy", s.handleGenealogy)
 	mux.HandleFunc("/recall", s.handleRecall)
 	mux.HandleFunc("/stats", s.handleStats)
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		p := r.URL.Path
 		if p == "/" || p == "/ui" || p == "/ui/" {
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -33,6 +49,7 @@ func (s *Server) Handler() http.Handler {
 		}
 		mux.ServeHTTP(w, r)
 	})
+	return s.Audit.Wrap(s.Auth.Wrap(h))
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -59,6 +76,7 @@ func (s *Server) handleRecords(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	s.Audit.Record(r, "trace_record.create", "trace_record", created.ID, nil, created)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(created)
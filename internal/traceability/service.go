@@ -8,11 +8,11 @@ import (
 
 // Service implements traceability business logic.
 type Service struct {
-	Store *Store
+	Store Store
 }
 
 // NewService returns a traceability service.
-func NewService(store *Store) *Service {
+func NewService(store Store) *Service {
 	return &Service{Store: store}
 }
 
@@ -30,7 +30,7 @@ func (s *Service) Record(ctx context.Context, r *TraceRecord) (*TraceRecord, err
 	if r.Quantity <= 0 && r.EventType != EventScrap {
 		r.Quantity = 1
 	}
-	return s.Store.Add(r), nil
+	return s.Store.Add(r)
 }
 
 // GetGenealogyBySerial returns all records for the given serial.
@@ -0,0 +1,119 @@
+package traceability
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
+)
+
+// sqlMigrations is the traceability schema, one entry per version; only ever append (see
+// storage.Migrate). Records are a log like in memory: IDs are not unique keys there. created is
+// created_at in Unix nanoseconds, for recall date ranges.
+var sqlMigrations = []string{`
+CREATE TABLE trace_records (
+	id      TEXT    NOT NULL,
+	serial  TEXT    NOT NULL,
+	lot     TEXT    NOT NULL,
+	sku     TEXT    NOT NULL,
+	created INTEGER NOT NULL,
+	data    TEXT    NOT NULL
+);
+CREATE INDEX trace_records_serial ON trace_records (serial);
+CREATE INDEX trace_records_lot ON trace_records (lot);`,
+}
+
+// SQLStore keeps trace records in a database, each as JSON with the fields it is looked up by in columns.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore migrates db to the current traceability schema and returns a store on it.
+func NewSQLStore(db *sql.DB) (*SQLStore, error) {
+	if err := storage.Migrate(db, "traceability", sqlMigrations); err != nil {
+		return nil, err
+	}
+	return &SQLStore{db: db}, nil
+}
+
+// Add appends a record.
+func (s *SQLStore) Add(r *TraceRecord) (*TraceRecord, error) {
+	err := storage.InTx(s.db, func(tx *sql.Tx) error {
+		if r.ID == "" {
+			n, err := storage.NextSeq(tx, "trace_records")
+			if err != nil {
+				return err
+			}
+			r.ID = "tr-" + formatSeq(n)
+		}
+		if r.CreatedAt.IsZero() {
+			r.CreatedAt = time.Now().UTC()
+		}
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO trace_records (id, serial, lot, sku, created, data) VALUES (?, ?, ?, ?, ?, ?)`,
+			r.ID, r.Serial, r.Lot, r.SKU, r.CreatedAt.UnixNano(), string(data))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store trace record: %w", err)
+	}
+	cp := *r
+	return &cp, nil
+}
+
+// records returns the records query selects, in the order added.
+func (s *SQLStore) records(what, query string, args ...any) []TraceRecord {
+	var out []TraceRecord
+	err := storage.EachJSON(s.db, func(data []byte) error {
+		var r TraceRecord
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		out = append(out, r)
+		return nil
+	}, query, args...)
+	if err != nil {
+		log.Printf("traceability: store: %s: %v", what, err)
+	}
+	return out
+}
+
+// BySerial returns all records for the given serial (chronological order).
+func (s *SQLStore) BySerial(serial string) []TraceRecord {
+	return s.records("records by serial", `SELECT data FROM trace_records WHERE serial = ? ORDER BY rowid`, serial)
+}
+
+// ByLot returns all records for the given lot (chronological order).
+func (s *SQLStore) ByLot(lot string) []TraceRecord {
+	return s.records("records by lot", `SELECT data FROM trace_records WHERE lot = ? ORDER BY rowid`, lot)
+}
+
+// Recall returns records matching filters (lot, sku, from_date, to_date) for recall reporting.
+func (s *SQLStore) Recall(lot, sku string, from, to *time.Time) []TraceRecord {
+	query := `SELECT data FROM trace_records WHERE (? = '' OR lot = ?) AND (? = '' OR sku = ?)`
+	args := []any{lot, lot, sku, sku}
+	if from != nil {
+		query += ` AND created >= ?`
+		args = append(args, from.UnixNano())
+	}
+	if to != nil {
+		query += ` AND created <= ?`
+		args = append(args, to.UnixNano())
+	}
+	return s.records("recall", query+` ORDER BY rowid`, args...)
+}
+
+// Count returns total record count (for dashboard).
+func (s *SQLStore) Count() int {
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM trace_records`).Scan(&n); err != nil {
+		log.Printf("traceability: store: count: %v", err)
+	}
+	return n
+}
@@ -1,13 +1,41 @@
 package traceability
 
 import (
+	"database/sql"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
-// Store is an in-memory store for trace records, indexed by serial and lot.
-type Store struct {
+// Store holds trace records: MemoryStore in memory, SQLStore in a database (see pkg/storage).
+// Reads log a database failure and report nothing found; writes return it.
+type Store interface {
+	// Add appends a record and indexes it by serial and lot.
+	Add(r *TraceRecord) (*TraceRecord, error)
+	// BySerial returns all records for the given serial (chronological order).
+	BySerial(serial string) []TraceRecord
+	// ByLot returns all records for the given lot (chronological order).
+	ByLot(lot string) []TraceRecord
+	// Recall returns records matching filters (lot, sku, from_date, to_date) for recall reporting.
+	Recall(lot, sku string, from, to *time.Time) []TraceRecord
+	// Count returns total record count (for dashboard).
+	Count() int
+}
+
+// NewStore returns an SQLStore on db, or a MemoryStore if db is nil (the memory backend).
+func NewStore(db *sql.DB) (Store, error) {
+	if db == nil {
+		return NewMemoryStore(), nil
+	}
+	s, err := NewSQLStore(db)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// MemoryStore is an in-memory store for trace records, indexed by serial and lot.
+type MemoryStore struct {
 	mu       sync.RWMutex
 	records  []TraceRecord
 	bySerial map[string][]int // serial -> indices into records
@@ -15,9 +43,9 @@ type Store struct {
 	seq      atomic.Uint64
 }
 
-// NewStore returns a new traceability store.
-func NewStore() *Store {
-	return &Store{
+// NewMemoryStore returns a new in-memory traceability store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
 		records:  make([]TraceRecord, 0),
 		bySerial: make(map[string][]int),
 		byLot:    make(map[string][]int),
@@ -37,7 +65,7 @@ func formatSeq(n uint64) string {
 }
 
 // Add appends a record and indexes it by serial and lot.
-func (s *Store) Add(r *TraceRecord) *TraceRecord {
+func (s *MemoryStore) Add(r *TraceRecord) (*TraceRecord, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if r.ID == "" {
@@ -62,11 +90,11 @@ func (s *Store) Add(r *TraceRecord) *TraceRecord {
 	if cp.Lot != "" {
 		s.byLot[cp.Lot] = append(s.byLot[cp.Lot], idx)
 	}
-	return &s.records[idx]
+	return &cp, nil
 }
 
 // BySerial returns all records for the given serial (chronological order).
-func (s *Store) BySerial(serial string) []TraceRecord {
+func (s *MemoryStore) BySerial(serial string) []TraceRecord {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	idxs, ok := s.bySerial[serial]
@@ -84,7 +112,7 @@ func (s *Store) BySerial(serial string) []TraceRecord {
 }
 
 // ByLot returns all records for the given lot (chronological order).
-func (s *Store) ByLot(lot string) []TraceRecord {
+func (s *MemoryStore) ByLot(lot string) []TraceRecord {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	idxs, ok := s.byLot[lot]
@@ -102,7 +130,7 @@ func (s *Store) ByLot(lot string) []TraceRecord {
 }
 
 // Recall returns records matching filters (lot, sku, from_date, to_date) for recall reporting.
-func (s *Store) Recall(lot, sku string, from, to *time.Time) []TraceRecord {
+func (s *MemoryStore) Recall(lot, sku string, from, to *time.Time) []TraceRecord {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var out []TraceRecord
@@ -126,7 +154,7 @@ func (s *Store) Recall(lot, sku string, from, to *time.Time) []TraceRecord {
 }
 
 // Count returns total record count (for dashboard).
-func (s *Store) Count() int {
+func (s *MemoryStore) Count() int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return len(s.records)
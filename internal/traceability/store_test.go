@@ -0,0 +1,77 @@
+package traceability
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
+)
+
+// testStores returns a MemoryStore and an SQLStore on a new SQLite file, to run the same checks on both.
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	db, err := storage.Open(storage.Config{Backend: storage.BackendSQLite, Path: filepath.Join(t.TempDir(), "traceability.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s, err := NewSQLStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Store{"memory": NewMemoryStore(), "sql": s}
+}
+
+// ids returns the IDs of records in the order returned; both stores keep the order added.
+func ids(records []TraceRecord) string {
+	var out []string
+	for _, r := range records {
+		out = append(out, r.ID)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestStore(t *testing.T) {
+	t0 := time.Date(2025, 1, 6, 6, 0, 0, 0, time.UTC)
+	for name, s := range testStores(t) {
+		for i, r := range []*TraceRecord{
+			{Serial: "sn-1", Lot: "lot-1", SKU: "widget", EventType: EventProduced},
+			{Serial: "sn-2", Lot: "lot-1", SKU: "widget", EventType: EventProduced},
+			{Lot: "lot-2", SKU: "gear", EventType: EventReceived, Extra: map[string]string{"supplier": "acme"}},
+			{Serial: "sn-1", Lot: "lot-1", SKU: "widget", EventType: EventShipped},
+			// Records are a log: a reused ID is another entry.
+			{ID: "tr-2", Serial: "sn-2", SKU: "widget", EventType: EventInspection},
+		} {
+			r.CreatedAt = t0.Add(time.Duration(i) * time.Hour)
+			if _, err := s.Add(r); err != nil {
+				t.Fatalf("%s: Add: %v", name, err)
+			}
+		}
+		from, to := t0.Add(time.Hour), t0.Add(3*time.Hour)
+		for _, tc := range []struct {
+			what string
+			got  []TraceRecord
+			want string
+		}{
+			{"BySerial(sn-1)", s.BySerial("sn-1"), "tr-1 tr-4"},
+			{"BySerial(sn-2)", s.BySerial("sn-2"), "tr-2 tr-2"},
+			{"BySerial(sn-9)", s.BySerial("sn-9"), ""},
+			{"ByLot(lot-1)", s.ByLot("lot-1"), "tr-1 tr-2 tr-4"},
+			{"Recall(lot-1)", s.Recall("lot-1", "", nil, nil), "tr-1 tr-2 tr-4"},
+			{"Recall(widget, from)", s.Recall("", "widget", &from, nil), "tr-2 tr-4 tr-2"},
+			{"Recall(from, to)", s.Recall("", "", &from, &to), "tr-2 tr-3 tr-4"},
+		} {
+			if got := ids(tc.got); got != tc.want {
+				t.Errorf("%s: %s = %s, want %s", name, tc.what, got, tc.want)
+			}
+		}
+		if got := s.ByLot("lot-2"); len(got) != 1 || got[0].Extra["supplier"] != "acme" || !got[0].CreatedAt.Equal(t0.Add(2*time.Hour)) {
+			t.Errorf("%s: ByLot(lot-2) = %+v", name, got)
+		}
+		if n := s.Count(); n != 5 {
+			t.Errorf("%s: Count = %d, want 5", name, n)
+		}
+	}
+}
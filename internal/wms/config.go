@@ -3,24 +3,34 @@ package wms
 import (
 	"os"
 
-	"gopkg.in/yaml.v3"
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
 // Config holds WMS configuration.
 type Config struct {
 	WMS WMSConfig `yaml:"wms"`
 	Fleet FleetConfig `yaml:"fleet"`
+	Auth auth.Config `yaml:"auth"` // API keys and JWT for this service's API; none configured leaves it open
+	Audit audit.Config `yaml:"audit"` // tamper-evident log of API actions; env AUDIT_LOG overrides the path
+	Idempotency idempotency.Config `yaml:"idempotency"` // replays retried POSTs with the same Idempotency-Key; env IDEMPOTENCY_TTL overrides the TTL
+	Storage storage.Config `yaml:"storage"` // memory (default) or sqlite; env STORE_BACKEND and STORE_PATH override
 }
 
 // WMSConfig is the WMS server configuration.
 type WMSConfig struct {
 	Listen           string `yaml:"listen"`
 	WarehouseAreaID  string `yaml:"warehouse_area_id"`
+	FacilityMap      string `yaml:"facility_map"` // map file binding locations to nodes; env FACILITY_MAP overrides
 }
 
 // FleetConfig is the Fleet API client configuration.
 type FleetConfig struct {
 	APIURL string `yaml:"api_url"`
+	Token  string `yaml:"token"` // API key or JWT sent to the API; env FLEET_API_TOKEN overrides
 }
 
 // LoadConfig reads config from path. Uses env and defaults if path empty.
@@ -40,17 +50,16 @@ func LoadConfig(path string) (*Config, error) {
 		}
 	}
 	if path != "" {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return nil, err
-		}
-		if err := yaml.Unmarshal(data, cfg); err != nil {
+		if err := config.Load(path, cfg); err != nil {
 			return nil, err
 		}
 	}
 	if url := os.Getenv("FLEET_API_URL"); url != "" {
 		cfg.Fleet.APIURL = url
 	}
+	if p := os.Getenv("FACILITY_MAP"); p != "" {
+		cfg.WMS.FacilityMap = p
+	}
 	if listen := os.Getenv("WMS_LISTEN"); listen != "" {
 		cfg.WMS.Listen = listen
 	}
@@ -63,5 +72,12 @@ func LoadConfig(path string) (*Config, error) {
 	if cfg.Fleet.APIURL == "" {
 		cfg.Fleet.APIURL = "http://localhost:8080"
 	}
+	if t := os.Getenv("FLEET_API_TOKEN"); t != "" {
+		cfg.Fleet.Token = t
+	}
+	cfg.Auth.ApplyEnv()
+	cfg.Audit.ApplyEnv()
+	cfg.Idempotency.ApplyEnv()
+	cfg.Storage.ApplyEnv()
 	return cfg, nil
 }
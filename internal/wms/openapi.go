@@ -0,0 +1,45 @@
+package wms
+
+import (
+	"net/http"
+
+	"github.com/robotfleetos/robotfleetos/pkg/openapi"
+)
+
+// OpenAPI is the WMS API's OpenAPI document, served at GET /openapi.json.
+var OpenAPI = &openapi.API{
+	Title:       "RobotFleetOS WMS API",
+	Description: "Warehouse locations, inventory and tasks; released tasks become fleet work orders.",
+	Rules:       AuthRules,
+	Idempotent:  true,
+	Ops: openapi.Join(
+		openapi.Dashboard("/", "/ui"),
+		[]openapi.Op{
+			{Method: http.MethodPost, Path: "/locations", Summary: "Create a location",
+				Body: Location{}, Response: Location{}, Errors: []int{http.StatusBadRequest},
+				Example: Location{ID: "loc-a", ZoneID: "zone-1", Type: LocationTypeStorage}},
+			{Method: http.MethodGet, Path: "/locations", Summary: "List locations",
+				Response: openapi.Object{"locations": []Location{}}},
+			{Method: http.MethodPost, Path: "/inventory", Summary: "Receive a quantity of a SKU at a location",
+				Body:     openapi.Object{"location_id": "", "sku": "", "quantity": 0, "lot": ""},
+				Response: openapi.Object{"ok": ""}, Errors: []int{http.StatusBadRequest},
+				Example: openapi.Object{"location_id": "loc-a", "sku": "WIDGET-001", "quantity": 5}},
+			{Method: http.MethodGet, Path: "/inventory", Summary: "List inventory",
+				Query: []string{"location_id", "sku"}, Response: openapi.Object{"inventory": []Inventory{}}},
+			{Method: http.MethodPost, Path: "/tasks", Summary: "Create a task (pending)",
+				Body: Task{}, Status: http.StatusCreated, Response: Task{}, Errors: []int{http.StatusBadRequest},
+				Example: Task{Type: TaskTypePick, FromLocationID: "loc-a", SKU: "WIDGET-001", Quantity: 1}},
+			{Method: http.MethodGet, Path: "/tasks", Summary: "List tasks",
+				Query: []string{"status", "type"}, Response: openapi.Object{"tasks": []Task{}}},
+			{Method: http.MethodGet, Path: "/tasks/{id}", Summary: "Get a task",
+				Response: Task{}, Errors: []int{http.StatusNotFound}},
+			{Method: http.MethodPost, Path: "/tasks/{id}/release", Summary: "Release a task to the fleet as a work order",
+				Response: Task{}, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPost, Path: "/tasks/{id}/complete", Summary: "Complete a task",
+				Response: Task{}, Errors: []int{http.StatusBadRequest}},
+			{Method: http.MethodPost, Path: "/tasks/{id}/cancel", Summary: "Cancel a task",
+				Response: Task{}, Errors: []int{http.StatusBadRequest}},
+		},
+		openapi.Common("wms"),
+	),
+}
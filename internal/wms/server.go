@@ -5,6 +5,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
 )
 
 //go:embed static/index.html
@@ -12,18 +16,34 @@ var dashboardHTML []byte
 
 // Server is the WMS HTTP API server.
 type Server struct {
-	Service *Service
+	Service     *Service
+	Auth        *auth.Authenticator // optional: API key/JWT authentication and role checks; nil leaves the API open
+	Audit       *audit.Log          // optional: records every change made through the API; GET /audit
+	Idempotency *idempotency.Store  // optional: replays the response to a retried POST with the same Idempotency-Key
 }
 
+// AuthRules are the WMS API's route permissions; see auth.Rule.
+var AuthRules = auth.Join(
+	audit.AuthRules,
+	auth.Public("/", "/ui", "/health", "/openapi.json"),
+	auth.Writes("/locations", auth.RolePlanner),
+	auth.Writes("/inventory", auth.RoleOperator, auth.RolePlanner),
+	auth.Writes("/tasks", auth.RoleOperator, auth.RolePlanner),
+	auth.Writes("/tasks/", auth.RoleOperator, auth.RolePlanner),
+)
+
 // Handler returns the HTTP handler: dashboard at / and /ui, API elsewhere.
 func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", s.handleHealth)
+	mux.Handle("/openapi.json", OpenAPI)
+	mux.Handle("/audit", s.Audit)
+	mux.Handle("/audit/", s.Audit)
 	mux.HandleFunc("/locations", s.handleLocations)
 	mux.HandleFunc("/inventory", s.handleInventory)
 	mux.HandleFunc("/tasks", s.handleTasks)
 	mux.HandleFunc("/tasks/", s.handleTaskByID)
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		p := r.URL.Path
 		if p == "/" || p == "/ui" || p == "/ui/" {
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -33,6 +53,7 @@ func (s *Server) Handler() http.Handler {
 		}
 		mux.ServeHTTP(w, r)
 	})
+	return s.Audit.Wrap(s.Auth.Wrap(s.Idempotency.Wrap(h)))
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -55,6 +76,7 @@ func (s *Server) handleLocations(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.Audit.Record(r, "location.create", "location", loc.ID, nil, loc)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(loc)
 		return
@@ -80,10 +102,13 @@ func (s *Server) handleInventory(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
 			return
 		}
+		before := s.Service.ListInventory(r.Context(), req.LocationID, req.SKU)
 		if err := s.Service.ReceiveInventory(r.Context(), req.LocationID, req.SKU, req.Quantity, req.Lot); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		after := s.Service.ListInventory(r.Context(), req.LocationID, req.SKU)
+		s.Audit.Record(r, "inventory.receive", "inventory", req.LocationID+"/"+req.SKU, before, after)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"ok": "received"})
 		return
@@ -111,6 +136,7 @@ func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.Audit.Record(r, "task.create", "task", created.ID, nil, created)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(created)
@@ -162,11 +188,13 @@ func (s *Server) handleTaskRelease(w http.ResponseWriter, r *http.Request, id st
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	before := s.Service.GetTask(r.Context(), id)
 	t, err := s.Service.ReleaseTask(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	s.Audit.Record(r, "task.release", "task", id, before, t)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(t)
 }
@@ -176,11 +204,13 @@ func (s *Server) handleTaskComplete(w http.ResponseWriter, r *http.Request, id s
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	before := s.Service.GetTask(r.Context(), id)
 	t, err := s.Service.CompleteTask(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	s.Audit.Record(r, "task.complete", "task", id, before, t)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(t)
 }
@@ -190,11 +220,13 @@ func (s *Server) handleTaskCancel(w http.ResponseWriter, r *http.Request, id str
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	before := s.Service.GetTask(r.Context(), id)
 	t, err := s.Service.CancelTask(r.Context(), id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	s.Audit.Record(r, "task.cancel", "task", id, before, t)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(t)
 }
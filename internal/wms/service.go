@@ -4,31 +4,84 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"sync"
 	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/client"
+	"github.com/robotfleetos/robotfleetos/pkg/facility"
 )
 
 // Service implements WMS business logic.
 type Service struct {
-	Store            *Store
-	FleetClient      *FleetClient
+	Store            Store
+	FleetClient      *client.Fleet
 	WarehouseAreaID  string // area_id sent to Fleet for warehouse tasks (e.g. area-1)
+
+	mapMu       sync.RWMutex
+	facilityMap *facility.Map
 }
 
 // NewService returns a WMS service.
-func NewService(store *Store, fleet *FleetClient, warehouseAreaID string) *Service {
+func NewService(store Store, fleet *client.Fleet, warehouseAreaID string) *Service {
 	if warehouseAreaID == "" {
 		warehouseAreaID = "area-1"
 	}
 	return &Service{Store: store, FleetClient: fleet, WarehouseAreaID: warehouseAreaID}
 }
 
+// SetFacilityMap binds locations to the map's nodes, now and as they are created. Existing locations that
+// name a node missing from the map keep their coordinates and are logged.
+func (s *Service) SetFacilityMap(m *facility.Map) {
+	s.mapMu.Lock()
+	s.facilityMap = m
+	s.mapMu.Unlock()
+	for _, loc := range s.Store.ListLocations() {
+		if err := s.bindLocation(&loc); err != nil {
+			log.Printf("wms: location %s: %v", loc.ID, err)
+			continue
+		}
+		if err := s.Store.CreateLocation(&loc); err != nil {
+			log.Printf("wms: location %s: %v", loc.ID, err)
+		}
+	}
+}
+
+// bindLocation resolves loc's facility map node (explicit node_id, else the node bound to its ID) and fills
+// in coordinates from it.
+func (s *Service) bindLocation(loc *Location) error {
+	s.mapMu.RLock()
+	m := s.facilityMap
+	s.mapMu.RUnlock()
+	if m == nil {
+		return nil
+	}
+	var (
+		n  facility.Node
+		ok bool
+	)
+	if loc.NodeID != "" {
+		if n, ok = m.Node(loc.NodeID); !ok {
+			return fmt.Errorf("unknown map node %s", loc.NodeID)
+		}
+	} else if n, ok = m.NodeForLocation(loc.ID); !ok {
+		return nil
+	}
+	loc.NodeID = n.ID
+	p := n.Point()
+	loc.Coordinates = &p
+	return nil
+}
+
 // CreateLocation adds a location.
 func (s *Service) CreateLocation(ctx context.Context, loc *Location) error {
 	if loc.ID == "" {
 		return fmt.Errorf("location id required")
 	}
-	s.Store.CreateLocation(loc)
-	return nil
+	if err := s.bindLocation(loc); err != nil {
+		return err
+	}
+	return s.Store.CreateLocation(loc)
 }
 
 // ListLocations returns all locations.
@@ -49,8 +102,7 @@ func (s *Service) ReceiveInventory(ctx context.Context, locationID, sku string,
 	if quantity <= 0 {
 		return fmt.Errorf("quantity must be positive")
 	}
-	s.Store.AddInventory(locationID, sku, quantity, lot)
-	return nil
+	return s.Store.AddInventory(locationID, sku, quantity, lot)
 }
 
 // ListInventory returns inventory, optionally filtered.
@@ -79,7 +131,7 @@ func (s *Service) CreateTask(ctx context.Context, t *Task) (*Task, error) {
 	default:
 		return nil, fmt.Errorf("invalid task type: %s", t.Type)
 	}
-	return s.Store.CreateTask(t), nil
+	return s.Store.CreateTask(t)
 }
 
 // GetTask returns a task by ID.
@@ -110,19 +162,43 @@ func (s *Service) ReleaseTask(ctx context.Context, id string) (*Task, error) {
 		"quantity":          t.Quantity,
 		"lot":               t.Lot,
 	}
+	if loc := s.Store.GetLocation(t.FromLocationID); loc != nil {
+		if loc.Coordinates != nil {
+			payload["from"] = loc.Coordinates
+		}
+		if loc.NodeID != "" {
+			payload["from_node_id"] = loc.NodeID
+		}
+	}
+	if loc := s.Store.GetLocation(t.ToLocationID); loc != nil {
+		if loc.Coordinates != nil {
+			payload["to"] = loc.Coordinates
+		}
+		if loc.NodeID != "" {
+			payload["to_node_id"] = loc.NodeID
+		}
+	}
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
 	}
-	fleetID, err := s.FleetClient.SubmitWorkOrder(ctx, s.WarehouseAreaID, 1, payloadBytes)
+	// Keyed by task so a release retried after a lost response does not move the stock twice.
+	ctx = client.WithIdempotencyKey(ctx, "wms-task-"+t.ID)
+	wo, err := s.FleetClient.SubmitWorkOrder(ctx, client.WorkOrderRequest{
+		AreaID:   s.WarehouseAreaID,
+		Priority: 1,
+		Payload:  string(payloadBytes),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("submit to fleet: %w", err)
 	}
 	now := time.Now().UTC()
-	t.FleetWorkOrderID = fleetID
+	t.FleetWorkOrderID = wo.ID
 	t.Status = TaskStatusInProgress
 	t.ReleasedAt = &now
-	s.Store.UpdateTask(t)
+	if err := s.Store.UpdateTask(t); err != nil {
+		return nil, err
+	}
 	return s.Store.GetTask(id), nil
 }
 
@@ -138,17 +214,21 @@ func (s *Service) CompleteTask(ctx context.Context, id string) (*Task, error) {
 	now := time.Now().UTC()
 	t.Status = TaskStatusCompleted
 	t.CompletedAt = &now
+	var err error
 	switch t.Type {
 	case TaskTypePick:
-		s.Store.AddInventory(t.FromLocationID, t.SKU, -t.Quantity, t.Lot)
-	case TaskTypePutaway:
-		s.Store.AddInventory(t.FromLocationID, t.SKU, -t.Quantity, t.Lot)
-		s.Store.AddInventory(t.ToLocationID, t.SKU, t.Quantity, t.Lot)
-	case TaskTypeMove:
-		s.Store.AddInventory(t.FromLocationID, t.SKU, -t.Quantity, t.Lot)
-		s.Store.AddInventory(t.ToLocationID, t.SKU, t.Quantity, t.Lot)
+		err = s.Store.AddInventory(t.FromLocationID, t.SKU, -t.Quantity, t.Lot)
+	case TaskTypePutaway, TaskTypeMove:
+		if err = s.Store.AddInventory(t.FromLocationID, t.SKU, -t.Quantity, t.Lot); err == nil {
+			err = s.Store.AddInventory(t.ToLocationID, t.SKU, t.Quantity, t.Lot)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Store.UpdateTask(t); err != nil {
+		return nil, err
 	}
-	s.Store.UpdateTask(t)
 	return s.Store.GetTask(id), nil
 }
 
@@ -162,6 +242,8 @@ func (s *Service) CancelTask(ctx context.Context, id string) (*Task, error) {
 		return nil, fmt.Errorf("task already terminal")
 	}
 	t.Status = TaskStatusCancelled
-	s.Store.UpdateTask(t)
+	if err := s.Store.UpdateTask(t); err != nil {
+		return nil, err
+	}
 	return s.Store.GetTask(id), nil
 }
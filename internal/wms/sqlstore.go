@@ -0,0 +1,228 @@
+package wms
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
+)
+
+// sqlMigrations is the WMS schema, one entry per version; only ever append (see storage.Migrate).
+// Version 1 seeds the demo locations a MemoryStore starts with (demoLocations). Inventory is kept in
+// plain columns so quantities can be adjusted in place.
+var sqlMigrations = []string{`
+CREATE TABLE wms_locations (
+	id   TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+CREATE TABLE wms_inventory (
+	location_id TEXT    NOT NULL,
+	sku         TEXT    NOT NULL,
+	lot         TEXT    NOT NULL,
+	quantity    INTEGER NOT NULL,
+	updated_at  TEXT    NOT NULL,
+	PRIMARY KEY (location_id, sku, lot)
+);
+CREATE TABLE wms_tasks (
+	id     TEXT PRIMARY KEY,
+	status TEXT NOT NULL,
+	type   TEXT NOT NULL,
+	data   TEXT NOT NULL
+);
+INSERT INTO wms_locations (id, data) VALUES
+	('RECV-01', '{"id":"RECV-01","zone_id":"area-1","type":"receiving","name":"Receiving dock 1","coordinates":{"x":0,"y":40}}'),
+	('STAGE-01', '{"id":"STAGE-01","zone_id":"area-1","type":"staging","name":"Staging 1","coordinates":{"x":10,"y":40}}'),
+	('A-01-01', '{"id":"A-01-01","zone_id":"area-1","type":"storage","name":"Aisle 1, bin 1","coordinates":{"x":30,"y":10}}'),
+	('A-01-02', '{"id":"A-01-02","zone_id":"area-1","type":"storage","name":"Aisle 1, bin 2","coordinates":{"x":30,"y":12}}');`,
+}
+
+// SQLStore keeps locations, inventory and tasks in a database. Locations and tasks are stored as JSON
+// with the fields they are filtered on in columns.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore migrates db to the current WMS schema and returns a store on it.
+func NewSQLStore(db *sql.DB) (*SQLStore, error) {
+	if err := storage.Migrate(db, "wms", sqlMigrations); err != nil {
+		return nil, err
+	}
+	return &SQLStore{db: db}, nil
+}
+
+// CreateLocation adds or replaces a location.
+func (s *SQLStore) CreateLocation(loc *Location) error {
+	if loc.ID == "" {
+		return nil
+	}
+	data, err := json.Marshal(loc)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO wms_locations (id, data) VALUES (?, ?)
+		ON CONFLICT (id) DO UPDATE SET data = excluded.data`, loc.ID, string(data))
+	if err != nil {
+		return fmt.Errorf("store location: %w", err)
+	}
+	return nil
+}
+
+// ListLocations returns all locations in the order first added.
+func (s *SQLStore) ListLocations() []Location {
+	var out []Location
+	err := storage.EachJSON(s.db, func(data []byte) error {
+		var loc Location
+		if err := json.Unmarshal(data, &loc); err != nil {
+			return err
+		}
+		out = append(out, loc)
+		return nil
+	}, `SELECT data FROM wms_locations ORDER BY rowid`)
+	if err != nil {
+		log.Printf("wms: store: list locations: %v", err)
+	}
+	return out
+}
+
+// GetLocation returns a location by ID.
+func (s *SQLStore) GetLocation(id string) *Location {
+	var loc Location
+	ok, err := storage.GetJSON(s.db, &loc, `SELECT data FROM wms_locations WHERE id = ?`, id)
+	if err != nil {
+		log.Printf("wms: store: get location %s: %v", id, err)
+	}
+	if !ok || err != nil {
+		return nil
+	}
+	return &loc
+}
+
+// SetInventory sets inventory at a location (by location+sku+lot).
+func (s *SQLStore) SetInventory(locationID, sku string, quantity int, lot string) error {
+	_, err := s.db.Exec(`INSERT INTO wms_inventory (location_id, sku, lot, quantity, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (location_id, sku, lot) DO UPDATE SET quantity = excluded.quantity, updated_at = excluded.updated_at`,
+		locationID, sku, lot, quantity, time.Now().UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("store inventory: %w", err)
+	}
+	return nil
+}
+
+// AddInventory adds quantity to existing inventory (or creates). Use negative to subtract; the quantity
+// does not go below zero and nothing is created for a subtraction.
+func (s *SQLStore) AddInventory(locationID, sku string, delta int, lot string) error {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	res, err := s.db.Exec(`UPDATE wms_inventory SET quantity = MAX(quantity + ?, 0), updated_at = ?
+		WHERE location_id = ? AND sku = ? AND lot = ?`, delta, now, locationID, sku, lot)
+	if err == nil && delta > 0 {
+		var n int64
+		if n, err = res.RowsAffected(); err == nil && n == 0 {
+			_, err = s.db.Exec(`INSERT INTO wms_inventory (location_id, sku, lot, quantity, updated_at) VALUES (?, ?, ?, ?, ?)`,
+				locationID, sku, lot, delta, now)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("store inventory: %w", err)
+	}
+	return nil
+}
+
+// ListInventory returns inventory with a positive quantity, optionally filtered by location and/or sku.
+func (s *SQLStore) ListInventory(locationID, sku string) []Inventory {
+	rows, err := s.db.Query(`SELECT location_id, sku, lot, quantity, updated_at FROM wms_inventory
+		WHERE quantity > 0 AND (? = '' OR location_id = ?) AND (? = '' OR sku = ?) ORDER BY rowid`,
+		locationID, locationID, sku, sku)
+	if err != nil {
+		log.Printf("wms: store: list inventory: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	var out []Inventory
+	for rows.Next() {
+		var inv Inventory
+		var updated string
+		if err := rows.Scan(&inv.LocationID, &inv.SKU, &inv.Lot, &inv.Quantity, &updated); err != nil {
+			log.Printf("wms: store: list inventory: %v", err)
+			return out
+		}
+		inv.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updated)
+		out = append(out, inv)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("wms: store: list inventory: %v", err)
+	}
+	return out
+}
+
+// CreateTask adds a task and returns it with ID set.
+func (s *SQLStore) CreateTask(t *Task) (*Task, error) {
+	err := storage.InTx(s.db, func(tx *sql.Tx) error {
+		if t.ID == "" {
+			n, err := storage.NextSeq(tx, "wms_tasks")
+			if err != nil {
+				return err
+			}
+			t.ID = "wms-" + formatSeq(n)
+		}
+		initTask(t)
+		data, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		return storage.Insert(tx, `INSERT INTO wms_tasks (id, status, type, data) VALUES (?, ?, ?, ?)`,
+			t.ID, string(t.Status), string(t.Type), string(data))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store task: %w", err)
+	}
+	cp := *t
+	return &cp, nil
+}
+
+// GetTask returns a task by ID.
+func (s *SQLStore) GetTask(id string) *Task {
+	var t Task
+	ok, err := storage.GetJSON(s.db, &t, `SELECT data FROM wms_tasks WHERE id = ?`, id)
+	if err != nil {
+		log.Printf("wms: store: get task %s: %v", id, err)
+	}
+	if !ok || err != nil {
+		return nil
+	}
+	return &t
+}
+
+// UpdateTask updates a task.
+func (s *SQLStore) UpdateTask(t *Task) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	err = storage.ExecOne(s.db, `UPDATE wms_tasks SET status = ?, type = ?, data = ? WHERE id = ?`,
+		string(t.Status), string(t.Type), string(data), t.ID)
+	if err != nil {
+		return fmt.Errorf("task %s: %w", t.ID, err)
+	}
+	return nil
+}
+
+// ListTasks returns tasks in creation order, optionally filtered by status and type.
+func (s *SQLStore) ListTasks(status TaskStatus, taskType TaskType) []Task {
+	var out []Task
+	err := storage.EachJSON(s.db, func(data []byte) error {
+		var t Task
+		if err := json.Unmarshal(data, &t); err != nil {
+			return err
+		}
+		out = append(out, t)
+		return nil
+	}, `SELECT data FROM wms_tasks WHERE (? = '' OR status = ?) AND (? = '' OR type = ?) ORDER BY rowid`,
+		string(status), string(status), string(taskType), string(taskType))
+	if err != nil {
+		log.Printf("wms: store: list tasks: %v", err)
+	}
+	return out
+}
@@ -1,13 +1,74 @@
 package wms
 
 import (
+	"database/sql"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
 )
 
-// Store is an in-memory store for locations, inventory, and tasks.
-type Store struct {
+// Store holds locations, inventory, and tasks: MemoryStore in memory, SQLStore in a database (see
+// pkg/storage). Reads log a database failure and report nothing found; writes return it.
+type Store interface {
+	// CreateLocation adds or replaces a location. Locations without an ID are ignored.
+	CreateLocation(loc *Location) error
+	// ListLocations returns all locations.
+	ListLocations() []Location
+	// GetLocation returns a location by ID.
+	GetLocation(id string) *Location
+	// SetInventory sets the quantity at a location (by location+sku+lot).
+	SetInventory(locationID, sku string, quantity int, lot string) error
+	// AddInventory adds quantity to existing inventory (or creates). Use negative to subtract; the
+	// quantity does not go below zero.
+	AddInventory(locationID, sku string, delta int, lot string) error
+	// ListInventory returns inventory with a positive quantity, optionally filtered by location and/or sku.
+	ListInventory(locationID, sku string) []Inventory
+	// CreateTask adds a task and returns it with ID set; storage.ErrExists if the ID is taken.
+	CreateTask(t *Task) (*Task, error)
+	// GetTask returns a task by ID.
+	GetTask(id string) *Task
+	// UpdateTask updates a task; storage.ErrNotFound if there is none.
+	UpdateTask(t *Task) error
+	// ListTasks returns tasks, optionally filtered by status and type.
+	ListTasks(status TaskStatus, taskType TaskType) []Task
+}
+
+// NewStore returns an SQLStore on db, or a MemoryStore if db is nil (the memory backend).
+func NewStore(db *sql.DB) (Store, error) {
+	if db == nil {
+		return NewMemoryStore(), nil
+	}
+	s, err := NewSQLStore(db)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// demoLocations are the receiving, staging and storage locations a new store starts with.
+var demoLocations = []Location{
+	{ID: "RECV-01", ZoneID: "area-1", Type: LocationTypeReceiving, Name: "Receiving dock 1", Coordinates: &api.Point{X: 0, Y: 40}},
+	{ID: "STAGE-01", ZoneID: "area-1", Type: LocationTypeStaging, Name: "Staging 1", Coordinates: &api.Point{X: 10, Y: 40}},
+	{ID: "A-01-01", ZoneID: "area-1", Type: LocationTypeStorage, Name: "Aisle 1, bin 1", Coordinates: &api.Point{X: 30, Y: 10}},
+	{ID: "A-01-02", ZoneID: "area-1", Type: LocationTypeStorage, Name: "Aisle 1, bin 2", Coordinates: &api.Point{X: 30, Y: 12}},
+}
+
+// initTask sets the defaults of a new task.
+func initTask(t *Task) {
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now().UTC()
+	}
+	if t.Status == "" {
+		t.Status = TaskStatusPending
+	}
+}
+
+// MemoryStore is an in-memory store for locations, inventory, and tasks.
+type MemoryStore struct {
 	mu         sync.RWMutex
 	locations  map[string]*Location
 	inventory  []Inventory // multiple rows per location+sku+lot
@@ -15,18 +76,18 @@ type Store struct {
 	taskSeq    atomic.Uint64
 }
 
-// NewStore returns a new in-memory WMS store.
-func NewStore() *Store {
-	s := &Store{
+// NewMemoryStore returns a new in-memory WMS store.
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
 		locations: make(map[string]*Location),
 		inventory: make([]Inventory, 0),
 		tasks:     make(map[string]*Task),
 	}
 	// Seed default receiving and staging for demo
-	s.locations["RECV-01"] = &Location{ID: "RECV-01", ZoneID: "area-1", Type: LocationTypeReceiving, Name: "Receiving dock 1"}
-	s.locations["STAGE-01"] = &Location{ID: "STAGE-01", ZoneID: "area-1", Type: LocationTypeStaging, Name: "Staging 1"}
-	s.locations["A-01-01"] = &Location{ID: "A-01-01", ZoneID: "area-1", Type: LocationTypeStorage, Name: "Aisle 1, bin 1"}
-	s.locations["A-01-02"] = &Location{ID: "A-01-02", ZoneID: "area-1", Type: LocationTypeStorage, Name: "Aisle 1, bin 2"}
+	for _, loc := range demoLocations {
+		cp := loc
+		s.locations[loc.ID] = &cp
+	}
 	return s
 }
 
@@ -43,18 +104,19 @@ func formatSeq(n uint64) string {
 }
 
 // CreateLocation adds a location.
-func (s *Store) CreateLocation(loc *Location) {
+func (s *MemoryStore) CreateLocation(loc *Location) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if loc.ID == "" {
-		return
+		return nil
 	}
 	cp := *loc
 	s.locations[loc.ID] = &cp
+	return nil
 }
 
 // ListLocations returns all locations.
-func (s *Store) ListLocations() []Location {
+func (s *MemoryStore) ListLocations() []Location {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	out := make([]Location, 0, len(s.locations))
@@ -65,7 +127,7 @@ func (s *Store) ListLocations() []Location {
 }
 
 // GetLocation returns a location by ID.
-func (s *Store) GetLocation(id string) *Location {
+func (s *MemoryStore) GetLocation(id string) *Location {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	loc, ok := s.locations[id]
@@ -77,7 +139,7 @@ func (s *Store) GetLocation(id string) *Location {
 }
 
 // SetInventory sets or adjusts inventory at a location (by location+sku+lot).
-func (s *Store) SetInventory(locationID, sku string, quantity int, lot string) {
+func (s *MemoryStore) SetInventory(locationID, sku string, quantity int, lot string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	now := time.Now().UTC()
@@ -99,10 +161,11 @@ func (s *Store) SetInventory(locationID, sku string, quantity int, lot string) {
 			UpdatedAt:  now,
 		})
 	}
+	return nil
 }
 
 // AddInventory adds quantity to existing inventory (or creates). Use negative to subtract.
-func (s *Store) AddInventory(locationID, sku string, delta int, lot string) {
+func (s *MemoryStore) AddInventory(locationID, sku string, delta int, lot string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	now := time.Now().UTC()
@@ -113,7 +176,7 @@ func (s *Store) AddInventory(locationID, sku string, delta int, lot string) {
 				s.inventory[i].Quantity = 0
 			}
 			s.inventory[i].UpdatedAt = now
-			return
+			return nil
 		}
 	}
 	if delta > 0 {
@@ -125,10 +188,11 @@ func (s *Store) AddInventory(locationID, sku string, delta int, lot string) {
 			UpdatedAt:  now,
 		})
 	}
+	return nil
 }
 
 // ListInventory returns inventory, optionally filtered by location and/or sku.
-func (s *Store) ListInventory(locationID, sku string) []Inventory {
+func (s *MemoryStore) ListInventory(locationID, sku string) []Inventory {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var out []Inventory
@@ -148,25 +212,23 @@ func (s *Store) ListInventory(locationID, sku string) []Inventory {
 }
 
 // CreateTask adds a task and returns it with ID set.
-func (s *Store) CreateTask(t *Task) *Task {
+func (s *MemoryStore) CreateTask(t *Task) (*Task, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if t.ID == "" {
 		t.ID = "wms-" + formatSeq(s.taskSeq.Add(1))
 	}
-	if t.CreatedAt.IsZero() {
-		t.CreatedAt = time.Now().UTC()
-	}
-	if t.Status == "" {
-		t.Status = TaskStatusPending
+	if _, ok := s.tasks[t.ID]; ok {
+		return nil, fmt.Errorf("store task: %w", storage.ErrExists)
 	}
+	initTask(t)
 	cp := *t
 	s.tasks[t.ID] = &cp
-	return &cp
+	return &cp, nil
 }
 
 // GetTask returns a task by ID.
-func (s *Store) GetTask(id string) *Task {
+func (s *MemoryStore) GetTask(id string) *Task {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	t, ok := s.tasks[id]
@@ -178,19 +240,19 @@ func (s *Store) GetTask(id string) *Task {
 }
 
 // UpdateTask updates a task.
-func (s *Store) UpdateTask(t *Task) bool {
+func (s *MemoryStore) UpdateTask(t *Task) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, ok := s.tasks[t.ID]; !ok {
-		return false
+		return fmt.Errorf("task %s: %w", t.ID, storage.ErrNotFound)
 	}
 	cp := *t
 	s.tasks[t.ID] = &cp
-	return true
+	return nil
 }
 
 // ListTasks returns tasks, optionally filtered by status and type.
-func (s *Store) ListTasks(status TaskStatus, taskType TaskType) []Task {
+func (s *MemoryStore) ListTasks(status TaskStatus, taskType TaskType) []Task {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var out []Task
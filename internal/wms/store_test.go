@@ -0,0 +1,130 @@
+package wms
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/robotfleetos/robotfleetos/pkg/storage"
+)
+
+// testStores returns a MemoryStore and an SQLStore on a new SQLite file, to run the same checks on both.
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	db, err := storage.Open(storage.Config{Backend: storage.BackendSQLite, Path: filepath.Join(t.TempDir(), "wms.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s, err := NewSQLStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Store{"memory": NewMemoryStore(), "sql": s}
+}
+
+// ids returns the sorted IDs of a list, which the memory store returns in no particular order.
+func ids[T any](items []T, id func(T) string) string {
+	var out []string
+	for _, it := range items {
+		out = append(out, id(it))
+	}
+	sort.Strings(out)
+	return strings.Join(out, " ")
+}
+
+func TestStore(t *testing.T) {
+	locationID := func(l Location) string { return l.ID }
+	stock := func(inv Inventory) string {
+		return fmt.Sprintf("%s/%s/%s=%d", inv.LocationID, inv.SKU, inv.Lot, inv.Quantity)
+	}
+	taskID := func(t Task) string { return t.ID }
+	for name, s := range testStores(t) {
+		// Locations are added or replaced: the facility map rebinds them on every start.
+		for _, loc := range []*Location{
+			{ID: "dock-1", ZoneID: "zone-1", Type: LocationTypeReceiving},
+			{ID: "rack-1", ZoneID: "zone-1", Type: LocationTypeStorage},
+			{ID: "rack-1", ZoneID: "zone-2", Type: LocationTypeStorage, NodeID: "n-7"},
+			{ZoneID: "zone-1"},
+		} {
+			if err := s.CreateLocation(loc); err != nil {
+				t.Fatalf("%s: CreateLocation(%s): %v", name, loc.ID, err)
+			}
+		}
+		// Both start with the default locations.
+		if got := ids(s.ListLocations(), locationID); got != "A-01-01 A-01-02 RECV-01 STAGE-01 dock-1 rack-1" {
+			t.Errorf("%s: locations %s", name, got)
+		}
+		if got := s.GetLocation("rack-1"); got == nil || got.ZoneID != "zone-2" || got.NodeID != "n-7" {
+			t.Errorf("%s: replaced location %+v", name, got)
+		}
+		if got := s.GetLocation("rack-9"); got != nil {
+			t.Errorf("%s: GetLocation of a missing location = %+v", name, got)
+		}
+
+		for _, err := range []error{
+			s.SetInventory("rack-1", "widget", 10, "lot-1"),
+			s.SetInventory("rack-1", "widget", 5, "lot-1"),
+			s.AddInventory("rack-1", "widget", 3, "lot-2"),
+			s.AddInventory("dock-1", "gear", 4, ""),
+			s.AddInventory("dock-1", "gear", -9, ""),
+			s.AddInventory("dock-1", "screw", -1, ""),
+		} {
+			if err != nil {
+				t.Fatalf("%s: inventory: %v", name, err)
+			}
+		}
+		for _, tc := range []struct{ location, sku, want string }{
+			{"", "", "rack-1/widget/lot-1=5 rack-1/widget/lot-2=3"},
+			{"dock-1", "", ""},
+			{"rack-1", "widget", "rack-1/widget/lot-1=5 rack-1/widget/lot-2=3"},
+			{"", "gear", ""},
+		} {
+			if got := ids(s.ListInventory(tc.location, tc.sku), stock); got != tc.want {
+				t.Errorf("%s: ListInventory(%q, %q) = %s, want %s", name, tc.location, tc.sku, got, tc.want)
+			}
+		}
+
+		task, err := s.CreateTask(&Task{Type: TaskTypePick, FromLocationID: "rack-1", ToLocationID: "dock-1", SKU: "widget", Quantity: 2})
+		if err != nil || task.ID != "wms-1" || task.Status != TaskStatusPending || task.CreatedAt.IsZero() {
+			t.Fatalf("%s: CreateTask = %+v, %v", name, task, err)
+		}
+		if _, err := s.CreateTask(&Task{ID: "move-1", Type: TaskTypeMove, SKU: "gear", Quantity: 1}); err != nil {
+			t.Fatalf("%s: CreateTask with ID: %v", name, err)
+		}
+		if _, err := s.CreateTask(&Task{ID: "wms-1", Type: TaskTypeMove}); !errors.Is(err, storage.ErrExists) {
+			t.Errorf("%s: CreateTask with a taken ID = %v, want ErrExists", name, err)
+		}
+		task.Status = TaskStatusReleased
+		task.FleetWorkOrderID = "wo-1"
+		if err := s.UpdateTask(task); err != nil {
+			t.Fatalf("%s: UpdateTask: %v", name, err)
+		}
+		if got := s.GetTask("wms-1"); got == nil || got.Status != TaskStatusReleased || got.FleetWorkOrderID != "wo-1" || got.SKU != "widget" {
+			t.Errorf("%s: after UpdateTask GetTask = %+v", name, got)
+		}
+		if got := s.GetTask("wms-9"); got != nil {
+			t.Errorf("%s: GetTask of a missing task = %+v", name, got)
+		}
+		if err := s.UpdateTask(&Task{ID: "wms-9"}); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s: UpdateTask of a missing task = %v, want ErrNotFound", name, err)
+		}
+		for _, tc := range []struct {
+			status   TaskStatus
+			taskType TaskType
+			want     string
+		}{
+			{"", "", "move-1 wms-1"},
+			{TaskStatusPending, "", "move-1"},
+			{"", TaskTypePick, "wms-1"},
+			{TaskStatusPending, TaskTypePick, ""},
+		} {
+			if got := ids(s.ListTasks(tc.status, tc.taskType), taskID); got != tc.want {
+				t.Errorf("%s: ListTasks(%q, %q) = %s, want %s", name, tc.status, tc.taskType, got, tc.want)
+			}
+		}
+	}
+}
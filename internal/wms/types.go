@@ -1,6 +1,10 @@
 package wms
 
-import "time"
+import (
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
 
 // TaskType is the type of warehouse task.
 type TaskType string
@@ -38,6 +42,12 @@ type Location struct {
 	ZoneID string       `json:"zone_id"`
 	Type   LocationType `json:"type"`
 	Name   string       `json:"name,omitempty"`
+	// Coordinates on the facility floor in metres; sent to the fleet with released tasks so robots
+	// (and the simulator) can compute travel.
+	Coordinates *api.Point `json:"coordinates,omitempty"`
+	// NodeID binds the location to a facility map node. With a map configured, locations are bound to the
+	// node whose location_id matches and take their coordinates from it.
+	NodeID string `json:"node_id,omitempty"`
 }
 
 // Inventory is quantity of a SKU at a location.
@@ -0,0 +1,68 @@
+package zone
+
+import (
+	"sort"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/robotmodel"
+)
+
+// SetModelRegistry sets the robot models task requirements are matched against, using the model each robot
+// reports in Extra["model_id"]. Without a registry requirements are not checked. Safe to call while running.
+func (c *Controller) SetModelRegistry(r *robotmodel.Registry) {
+	c.mu.Lock()
+	c.models = r
+	c.mu.Unlock()
+}
+
+// robotModelLocked returns the model a robot last reported, or "" if it has not. Caller holds c.mu.
+func (c *Controller) robotModelLocked(id api.RobotID) string {
+	st := c.robotStatus[id]
+	if st == nil {
+		return ""
+	}
+	m, _ := st.Extra[api.ExtraModelID].(string)
+	return m
+}
+
+// capableLocked returns which robots may take a task with req: those whose model meets it or, while none
+// does, those that have not reported a model yet. ok is false if no robot can take it. Caller holds c.mu.
+func (c *Controller) capableLocked(req *api.Requirements) (func(api.RobotID) bool, bool) {
+	if c.models == nil || req.IsZero() {
+		return func(api.RobotID) bool { return true }, true
+	}
+	capable := make(map[api.RobotID]bool)
+	unknown := make(map[api.RobotID]bool)
+	for _, id := range c.robots {
+		model := c.robotModelLocked(id)
+		switch {
+		case model == "":
+			unknown[id] = true
+		case req.Unmet(model, c.models.Capabilities(model)) == "":
+			capable[id] = true
+		}
+	}
+	if len(capable) == 0 {
+		capable = unknown
+	}
+	return func(id api.RobotID) bool { return capable[id] }, len(capable) > 0
+}
+
+// modelCountsLocked counts robots per reported model for the zone summary. Caller holds c.mu.
+func (c *Controller) modelCountsLocked() []api.ModelCount {
+	if c.models == nil {
+		return nil
+	}
+	counts := make(map[string]int)
+	for _, id := range c.robots {
+		if model := c.robotModelLocked(id); model != "" {
+			counts[model]++
+		}
+	}
+	out := make([]api.ModelCount, 0, len(counts))
+	for model, n := range counts {
+		out = append(out, api.ModelCount{ModelID: model, Robots: n, Capabilities: c.models.Capabilities(model)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ModelID < out[j].ModelID })
+	return out
+}
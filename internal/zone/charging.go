@@ -0,0 +1,297 @@
+package zone
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/facility"
+)
+
+// ChargingPolicy controls when a zone sends its robots to charge.
+type ChargingPolicy struct {
+	Disabled           bool      // robots charge on their own
+	ChargeBelow        float64   // idle robots below this battery % must charge and queue for a charger
+	OpportunisticBelow float64   // idle robots below this % charge while a charger is free; a task ends it early
+	ChargeUntil        float64   // battery % at which a charge ends
+	Chargers           []Charger // in addition to the facility map's chargers in the zone
+}
+
+// Charger is a charging station the zone hands out, one robot at a time.
+type Charger struct {
+	ID       string
+	NodeID   string // facility map node; its coordinates win over Position when the map has it
+	Position api.Point
+}
+
+// DefaultChargingPolicy is the policy of a new controller.
+func DefaultChargingPolicy() ChargingPolicy {
+	return ChargingPolicy{ChargeBelow: 25, OpportunisticBelow: 60, ChargeUntil: 95}
+}
+
+const (
+	// chargingInterval is how often the zone hands out free chargers.
+	chargingInterval = 5 * time.Second
+	// chargeStartTimeout frees a charger whose robot never reported CHARGING.
+	chargeStartTimeout = 2 * time.Minute
+)
+
+// chargerSlot is a charger and the robot it is given to, if any.
+type chargerSlot struct {
+	Charger
+	robot         api.RobotID
+	interruptible bool
+	assigned      time.Time
+	started       bool          // the robot reported CHARGING since it was sent
+	busy          time.Duration // time in use in the current utilization window, for finished charges
+}
+
+// SetChargingPolicy sets when robots are sent to charge. Safe to call while running; robots on a charger
+// that is still listed keep it.
+func (c *Controller) SetChargingPolicy(p ChargingPolicy) {
+	c.mu.Lock()
+	c.charging = p
+	c.resetChargersLocked()
+	c.mu.Unlock()
+}
+
+// resetChargersLocked rebuilds the charger list from the policy and the facility map. Caller holds c.mu.
+func (c *Controller) resetChargersLocked() {
+	old := make(map[string]*chargerSlot, len(c.chargers))
+	for _, s := range c.chargers {
+		old[s.ID] = s
+	}
+	var list []Charger
+	listed := make(map[string]bool)
+	for _, ch := range c.charging.Chargers {
+		if c.facility != nil && ch.NodeID != "" {
+			if n, ok := c.facility.Node(ch.NodeID); ok {
+				ch.Position = n.Point()
+			}
+		}
+		list = append(list, ch)
+		listed[ch.ID], listed[ch.NodeID] = true, true
+	}
+	if m := c.facility; m != nil {
+		for _, n := range m.Nodes {
+			if n.Type != facility.NodeCharger || listed[n.ID] {
+				continue
+			}
+			if len(m.Zones) > 0 && m.ZoneAt(n.Point()) != string(c.zoneID) {
+				continue
+			}
+			list = append(list, Charger{ID: n.ID, NodeID: n.ID, Position: n.Point()})
+		}
+	}
+	c.chargers = make([]*chargerSlot, len(list))
+	for i, ch := range list {
+		c.chargers[i] = &chargerSlot{Charger: ch}
+		if prev := old[ch.ID]; prev != nil {
+			prev.Charger = ch
+			c.chargers[i] = prev
+		}
+	}
+}
+
+// chargerOfLocked returns the charger given to robot, or nil. Caller holds c.mu.
+func (c *Controller) chargerOfLocked(robot api.RobotID) *chargerSlot {
+	for _, s := range c.chargers {
+		if s.robot == robot {
+			return s
+		}
+	}
+	return nil
+}
+
+// queuedForChargeLocked reports whether robot is waiting for a charger. Caller holds c.mu.
+func (c *Controller) queuedForChargeLocked(robot api.RobotID) bool {
+	for _, id := range c.chargeQueue {
+		if id == robot {
+			return true
+		}
+	}
+	return false
+}
+
+// needsChargeLocked reports whether robot should get no tasks until it has charged: it is queued for a
+// charger, or idle below ChargeBelow and about to be. Caller holds c.mu.
+func (c *Controller) needsChargeLocked(robot api.RobotID, st *api.RobotStatus) bool {
+	if c.charging.Disabled || len(c.chargers) == 0 {
+		return false
+	}
+	return c.queuedForChargeLocked(robot) || (st != nil && st.State == "IDLE" && st.Battery < c.charging.ChargeBelow)
+}
+
+// interruptibleLocked reports whether robot is on an opportunistic charge with enough battery to take a
+// task. Caller holds c.mu.
+func (c *Controller) interruptibleLocked(robot api.RobotID, st *api.RobotStatus) bool {
+	s := c.chargerOfLocked(robot)
+	return s != nil && s.interruptible && st != nil && st.State == api.RobotStateCharging && st.Battery >= c.charging.ChargeBelow
+}
+
+// manageCharging frees chargers whose robots are done, queues idle robots below ChargeBelow and hands
+// free chargers to the queue in order. With no one queued, idle robots below OpportunisticBelow take free
+// chargers, lowest battery first, until a task needs them.
+func (c *Controller) manageCharging(ctx context.Context) {
+	var cmds []*api.RobotCommand
+	c.mu.Lock()
+	if c.charging.Disabled || len(c.chargers) == 0 {
+		c.mu.Unlock()
+		return
+	}
+	now := c.clock.Now()
+	for _, s := range c.chargers {
+		if s.robot == "" {
+			continue
+		}
+		st := c.robotStatus[s.robot]
+		switch {
+		case st != nil && st.State == api.RobotStateCharging:
+			s.started = true
+		case s.started, st != nil && (st.State == "ERROR" || st.State == api.RobotStateStopped),
+			now.Sub(s.assigned) >= chargeStartTimeout:
+			c.releaseChargerLocked(s, now)
+		}
+	}
+
+	// Robots that are no longer idle or have been charged elsewhere leave the queue.
+	queue := c.chargeQueue[:0]
+	for _, id := range c.chargeQueue {
+		if c.idleForChargeLocked(id) && c.robotStatus[id].Battery < c.charging.ChargeBelow {
+			queue = append(queue, id)
+		}
+	}
+	c.chargeQueue = queue
+	for _, id := range c.robots {
+		if c.idleForChargeLocked(id) && c.robotStatus[id].Battery < c.charging.ChargeBelow && !c.queuedForChargeLocked(id) {
+			c.chargeQueue = append(c.chargeQueue, id)
+			log.Printf("zone %s: robot %s at %.0f%% queued for a charger", c.zoneID, id, c.robotStatus[id].Battery)
+		}
+	}
+	for len(c.chargeQueue) > 0 {
+		cmd := c.assignChargerLocked(c.chargeQueue[0], false, now)
+		if cmd == nil {
+			break
+		}
+		c.chargeQueue = c.chargeQueue[1:]
+		cmds = append(cmds, cmd)
+	}
+
+	if len(c.chargeQueue) == 0 {
+		var low []api.RobotID
+		for _, id := range c.robots {
+			if c.idleForChargeLocked(id) && c.robotStatus[id].Battery < c.charging.OpportunisticBelow {
+				low = append(low, id)
+			}
+		}
+		sort.SliceStable(low, func(i, j int) bool { return c.robotStatus[low[i]].Battery < c.robotStatus[low[j]].Battery })
+		for _, id := range low {
+			cmd := c.assignChargerLocked(id, true, now)
+			if cmd == nil {
+				break
+			}
+			cmds = append(cmds, cmd)
+		}
+	}
+	c.mu.Unlock()
+	for _, cmd := range cmds {
+		if err := c.cmdPub.PublishRobotCommand(ctx, cmd); err != nil {
+			log.Printf("zone %s: publish %s to %s: %v", c.zoneID, cmd.Type, cmd.RobotID, err)
+		}
+	}
+}
+
+// idleForChargeLocked reports whether robot could be sent to charge: idle with a known status, not given
+// a task or command since, not on a zone route and not already on a charger. Caller holds c.mu.
+func (c *Controller) idleForChargeLocked(robot api.RobotID) bool {
+	st := c.robotStatus[robot]
+	return st != nil && st.State == "IDLE" && !c.dispatched[robot] && c.routes[robot] == nil && c.chargerOfLocked(robot) == nil
+}
+
+// assignChargerLocked gives robot the free charger nearest to it and returns the CHARGE command, or nil if
+// every charger is taken. Caller holds c.mu.
+func (c *Controller) assignChargerLocked(robot api.RobotID, interruptible bool, now time.Time) *api.RobotCommand {
+	pos, known := api.ParsePoint(c.robotStatus[robot].Position)
+	var best *chargerSlot
+	bestD := math.Inf(1)
+	for _, s := range c.chargers {
+		if s.robot != "" {
+			continue
+		}
+		d := 0.0
+		if known {
+			d = math.Hypot(s.Position.X-pos.X, s.Position.Y-pos.Y)
+			if c.facility != nil {
+				d = c.facility.DistancesTo(s.Position)(pos)
+			}
+		}
+		if best == nil || d < bestD {
+			best, bestD = s, d
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	payload, _ := json.Marshal(api.ChargePayload{
+		ChargerID:     best.ID,
+		NodeID:        best.NodeID,
+		Position:      best.Position,
+		Until:         c.charging.ChargeUntil,
+		Interruptible: interruptible,
+	})
+	best.robot, best.interruptible, best.assigned, best.started = robot, interruptible, now, false
+	c.dispatched[robot] = true
+	kind := "charge"
+	if interruptible {
+		kind = "opportunistic charge"
+	}
+	log.Printf("zone %s: robot %s at %.0f%% -> charger %s (%s)", c.zoneID, robot, c.robotStatus[robot].Battery, best.ID, kind)
+	return &api.RobotCommand{
+		ID:        api.TaskID(fmt.Sprintf("charge-%s-%d", robot, now.UnixNano())),
+		RobotID:   robot,
+		Type:      api.RobotCommandTypeCharge,
+		Payload:   payload,
+		CreatedAt: now.UTC(),
+	}
+}
+
+// releaseChargerLocked frees a charger and books the time it was in use. Caller holds c.mu.
+func (c *Controller) releaseChargerLocked(s *chargerSlot, now time.Time) {
+	s.busy += now.Sub(laterOf(s.assigned, c.chargeWindow))
+	s.robot, s.interruptible, s.started = "", false, false
+}
+
+// chargerUsageLocked fills in the summary's charger fields and starts a new utilization window. Caller
+// holds c.mu.
+func (c *Controller) chargerUsageLocked(sum *api.ZoneSummary, now time.Time) {
+	if len(c.chargers) == 0 {
+		return
+	}
+	var busy time.Duration
+	for _, s := range c.chargers {
+		busy += s.busy
+		s.busy = 0
+		if s.robot != "" {
+			sum.ChargersInUse++
+			busy += now.Sub(laterOf(s.assigned, c.chargeWindow))
+		}
+	}
+	sum.Chargers = len(c.chargers)
+	sum.ChargeQueue = len(c.chargeQueue)
+	if window := now.Sub(c.chargeWindow); !c.chargeWindow.IsZero() && window > 0 {
+		sum.ChargerUtilization = math.Round(busy.Seconds()/window.Seconds()/float64(len(c.chargers))*1000) / 1000
+	}
+	c.chargeWindow = now
+}
+
+func laterOf(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -1,9 +1,15 @@
 package zone
 
 import (
+	"fmt"
 	"os"
+	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/cmdsign"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+	"github.com/robotfleetos/robotfleetos/pkg/schedule"
 )
 
 // Config holds zone layer configuration.
@@ -16,11 +22,175 @@ type ZoneConfig struct {
 	ZoneID  string   `yaml:"zone_id"`
 	AreaID  string   `yaml:"area_id"`
 	Robots  []string `yaml:"robots"` // robot IDs in this zone (for dispatching commands)
+	ReportInterval string `yaml:"report_interval"` // how often the zone summary goes to the area, e.g. "5s" (default)
+	Firmware FirmwareConfig `yaml:"firmware"`
+	FacilityMap string `yaml:"facility_map"` // map file for distance-based assignment; env FACILITY_MAP overrides
+	Traffic     TrafficConfig `yaml:"traffic"`
+	Charging    ChargingConfig `yaml:"charging"`
+	ModelRegistry string `yaml:"model_registry"` // robot models and capabilities for task requirements; env MODEL_REGISTRY overrides
+	CommandSigning cmdsign.SigningConfig `yaml:"command_signing"` // Ed25519 key signing robot commands for verifying edges
+}
+
+// ReportPeriod returns the report interval, 5s if unset.
+func (z ZoneConfig) ReportPeriod() (time.Duration, error) {
+	return config.Duration("zone.report_interval", z.ReportInterval, 5*time.Second)
+}
+
+// TrafficConfig tunes route planning on the facility map. Durations are Go durations ("20s"); zero fields
+// take the defaults of DefaultTrafficPolicy.
+type TrafficConfig struct {
+	Disabled     bool    `yaml:"disabled"`      // send bare tasks even with a map
+	RobotSpeed   float64 `yaml:"robot_speed"`   // m/s for robots that do not report speed_mps
+	StopDwell    string  `yaml:"stop_dwell"`    // handling time planned at each pick and drop
+	Clearance    string  `yaml:"clearance"`     // gap kept between two robots using the same node
+	ParkHold     string  `yaml:"park_hold"`     // how long the last stop stays booked after the robot is done
+	StallTimeout string  `yaml:"stall_timeout"` // a robot blocked this long is rerouted
+	MaxRobots    int     `yaml:"max_robots"`    // robots routed on the map at once; 0 = a quarter of its nodes
+}
+
+// Policy converts the traffic settings, filling in defaults.
+func (t TrafficConfig) Policy() (TrafficPolicy, error) {
+	p := DefaultTrafficPolicy()
+	p.Disabled = t.Disabled
+	if t.RobotSpeed < 0 {
+		return p, fmt.Errorf("traffic.robot_speed must not be negative: %v", t.RobotSpeed)
+	}
+	if t.RobotSpeed > 0 {
+		p.Speed = t.RobotSpeed
+	}
+	if t.MaxRobots < 0 {
+		return p, fmt.Errorf("traffic.max_robots must not be negative: %d", t.MaxRobots)
+	}
+	p.MaxRobots = t.MaxRobots
+	for _, f := range []struct {
+		name string
+		s    string
+		d    *time.Duration
+	}{
+		{"stop_dwell", t.StopDwell, &p.Dwell},
+		{"clearance", t.Clearance, &p.Clearance},
+		{"park_hold", t.ParkHold, &p.Park},
+		{"stall_timeout", t.StallTimeout, &p.StallTimeout},
+	} {
+		if f.s == "" {
+			continue
+		}
+		d, err := time.ParseDuration(f.s)
+		if err != nil || d < 0 {
+			return p, fmt.Errorf("traffic.%s: invalid duration %q", f.name, f.s)
+		}
+		*f.d = d
+	}
+	return p, nil
+}
+
+// ChargingConfig sets when the zone sends robots to charge and where. Chargers of the facility map inside
+// the zone are added to the listed ones; zero thresholds take the defaults of DefaultChargingPolicy.
+type ChargingConfig struct {
+	Disabled           bool            `yaml:"disabled"`            // robots charge on their own
+	ChargeBelow        float64         `yaml:"charge_below"`        // idle robots below this % must charge, queueing for a charger
+	OpportunisticBelow float64         `yaml:"opportunistic_below"` // idle robots below this % charge while a charger is free
+	ChargeUntil        float64         `yaml:"charge_until"`
+	Chargers           []ChargerConfig `yaml:"chargers"`
+}
+
+// ChargerConfig is a charger by facility map node or by position.
+type ChargerConfig struct {
+	ID     string  `yaml:"id"`
+	NodeID string  `yaml:"node_id"`
+	X      float64 `yaml:"x"`
+	Y      float64 `yaml:"y"`
+}
+
+// Policy converts the charging settings, filling in defaults.
+func (c ChargingConfig) Policy() (ChargingPolicy, error) {
+	p := DefaultChargingPolicy()
+	p.Disabled = c.Disabled
+	for _, f := range []struct {
+		name string
+		v    float64
+		dst  *float64
+	}{
+		{"charge_below", c.ChargeBelow, &p.ChargeBelow},
+		{"opportunistic_below", c.OpportunisticBelow, &p.OpportunisticBelow},
+		{"charge_until", c.ChargeUntil, &p.ChargeUntil},
+	} {
+		if f.v < 0 || f.v > 100 {
+			return p, fmt.Errorf("charging.%s must be in [0, 100]: %v", f.name, f.v)
+		}
+		if f.v > 0 {
+			*f.dst = f.v
+		}
+	}
+	if p.ChargeUntil <= p.ChargeBelow || p.ChargeUntil <= p.OpportunisticBelow {
+		return p, fmt.Errorf("charging.charge_until (%v) must be above charge_below and opportunistic_below", p.ChargeUntil)
+	}
+	seen := make(map[string]bool)
+	for i, ch := range c.Chargers {
+		id := ch.ID
+		if id == "" {
+			id = ch.NodeID
+		}
+		if id == "" {
+			return p, fmt.Errorf("charging.chargers[%d]: id or node_id required", i)
+		}
+		if seen[id] {
+			return p, fmt.Errorf("charging.chargers[%d]: duplicate charger %q", i, id)
+		}
+		seen[id] = true
+		p.Chargers = append(p.Chargers, Charger{ID: id, NodeID: ch.NodeID, Position: api.Point{X: ch.X, Y: ch.Y}})
+	}
+	return p, nil
+}
+
+// FirmwareConfig holds zone-level firmware rollout constraints, merged with each campaign's policy.
+type FirmwareConfig struct {
+	MaintenanceWindows []MaintenanceWindowConfig `yaml:"maintenance_windows"` // empty = any time
+	MinBattery         float64                   `yaml:"min_battery"`
+	ChargingOnly       bool                      `yaml:"charging_only"`
+	HoldbackPercent    int                       `yaml:"holdback_percent"` // % of robots never updating at the same time
+}
+
+// MaintenanceWindowConfig is a recurring window: opens at each cron match and stays open for duration.
+type MaintenanceWindowConfig struct {
+	Cron     string `yaml:"cron"`     // e.g. "0 22 * * *" or shift boundaries "45 5,13,21 * * *"
+	Duration string `yaml:"duration"` // e.g. "6h", "30m"
+	TZ       string `yaml:"tz"`
+}
+
+// Policy converts the zone firmware settings to the policy merged into FIRMWARE_UPDATE payloads.
+func (f FirmwareConfig) Policy() api.FirmwarePolicy {
+	p := api.FirmwarePolicy{
+		MinBattery:      f.MinBattery,
+		ChargingOnly:    f.ChargingOnly,
+		HoldbackPercent: f.HoldbackPercent,
+	}
+	for _, w := range f.MaintenanceWindows {
+		p.ZoneMaintenanceWindows = append(p.ZoneMaintenanceWindows, api.FirmwareMaintenanceWindow{Cron: w.Cron, Duration: w.Duration, TZ: w.TZ})
+	}
+	return p
+}
+
+func (f FirmwareConfig) validate() error {
+	if f.HoldbackPercent < 0 || f.HoldbackPercent >= 100 {
+		return fmt.Errorf("firmware.holdback_percent must be in [0, 100): %d", f.HoldbackPercent)
+	}
+	if f.MinBattery < 0 || f.MinBattery > 100 {
+		return fmt.Errorf("firmware.min_battery must be in [0, 100]: %v", f.MinBattery)
+	}
+	for i, w := range f.MaintenanceWindows {
+		if _, err := schedule.NewWindow(w.Cron, w.Duration, w.TZ); err != nil {
+			return fmt.Errorf("firmware.maintenance_windows[%d]: %w", i, err)
+		}
+	}
+	return nil
 }
 
 type MessagingConfig struct {
 	Broker      string `yaml:"broker"`
 	TopicPrefix string `yaml:"topic_prefix"`
+
+	messaging.ConnOptions `yaml:",inline"` // NATS TLS and credentials
 }
 
 // LoadConfig reads config from path. If path is empty, uses env ZONE_CONFIG or defaults.
@@ -42,19 +212,61 @@ func LoadConfig(path string) (*Config, error) {
 		}
 	}
 	if path != "" {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return nil, err
-		}
-		if err := yaml.Unmarshal(data, cfg); err != nil {
+		if err := config.Load(path, cfg); err != nil {
 			return nil, err
 		}
 	}
+	if p := os.Getenv("FACILITY_MAP"); p != "" {
+		cfg.Zone.FacilityMap = p
+	}
+	if p := os.Getenv("MODEL_REGISTRY"); p != "" {
+		cfg.Zone.ModelRegistry = p
+	}
 	if cfg.Zone.ZoneID == "" {
 		cfg.Zone.ZoneID = "zone-1"
 	}
 	if len(cfg.Zone.Robots) == 0 {
 		cfg.Zone.Robots = []string{"robot-1"}
 	}
+	cfg.Zone.CommandSigning.ApplyEnv()
+	cfg.Messaging.ApplyEnv()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
+
+// Validate checks the settings against each other: the zone's IDs and robots, the report interval, and the
+// firmware, traffic, charging and command signing settings.
+func (c *Config) Validate() error {
+	z := c.Zone
+	if z.ZoneID == "" || z.AreaID == "" {
+		return fmt.Errorf("zone.zone_id and zone.area_id are required")
+	}
+	seen := make(map[string]bool, len(z.Robots))
+	for i, r := range z.Robots {
+		if r == "" {
+			return fmt.Errorf("zone.robots[%d]: empty robot ID", i)
+		}
+		if seen[r] {
+			return fmt.Errorf("zone.robots: %s listed twice", r)
+		}
+		seen[r] = true
+	}
+	if _, err := z.ReportPeriod(); err != nil {
+		return err
+	}
+	if err := z.Firmware.validate(); err != nil {
+		return err
+	}
+	if _, err := z.Traffic.Policy(); err != nil {
+		return err
+	}
+	if _, err := z.Charging.Policy(); err != nil {
+		return err
+	}
+	if _, err := z.CommandSigning.Signer(api.ZoneID(z.ZoneID)); err != nil {
+		return err
+	}
+	return nil
+}
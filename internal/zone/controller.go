@@ -3,15 +3,24 @@ package zone
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/clock"
+	"github.com/robotfleetos/robotfleetos/pkg/facility"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
 	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+	"github.com/robotfleetos/robotfleetos/pkg/robotmodel"
 )
 
+// dedupeTTL is how long a zone task ID is remembered to drop redeliveries of it.
+const dedupeTTL = 10 * time.Minute
+
 // Controller runs the zone layer: consumes zone tasks, publishes robot commands, aggregates robot status, reports zone summary to area.
 type Controller struct {
 	zoneID   api.ZoneID
@@ -20,10 +29,36 @@ type Controller struct {
 	summaryPub *messaging.ZoneSummaryPublisher
 	bus      messaging.Subscriber
 	reportInterval time.Duration
+	clock    clock.Clock
 
 	mu          sync.RWMutex
+	report      func()      // publishes the summary; set while running, for SetReportInterval
+	stopReport  func()
+	subscribed  map[api.RobotID]bool // robots whose status and task results are subscribed to
 	robotStatus map[api.RobotID]*api.RobotStatus
+	dispatched  map[api.RobotID]bool // given a task since its last status
 	cmdSeq      atomic.Uint64
+	seen        *idempotency.Dedupe // tasks handled lately, so a redelivered one is dropped
+	facility    *facility.Map // optional; nearest idle robot by travel distance when set
+
+	traffic      TrafficPolicy // route planning on the facility map
+	reservations *facility.Reservations
+	routes       map[api.RobotID]*activeRoute
+	trafficActed map[api.RobotID]time.Time // last reroute or move per robot, to let it take effect
+	held         []api.ZoneTask            // routed tasks waiting for a robot the map has room for, in order
+
+	charging    ChargingPolicy
+	chargers    []*chargerSlot
+	chargeQueue []api.RobotID // robots below ChargeBelow waiting for a free charger, in order
+	chargeWindow time.Time    // start of the charger utilization window, reset by each summary
+
+	models *robotmodel.Registry // optional; task requirements are matched against robot models when set
+
+	resultPub *messaging.TaskResultPublisher // optional; reports task graph step results to the area
+	steps     map[api.TaskID]api.RobotID     // task graph steps dispatched and not yet ended
+
+	firmwarePolicy   api.FirmwarePolicy // zone-level constraints merged into each firmware task
+	firmwareRollouts []*firmwareRollout
 }
 
 // NewController creates a zone controller.
@@ -39,7 +74,7 @@ func NewController(
 	for _, r := range robots {
 		statusMap[r] = nil
 	}
-	return &Controller{
+	c := &Controller{
 		zoneID:        zoneID,
 		robots:        robots,
 		cmdPub:        cmdPub,
@@ -47,28 +82,80 @@ func NewController(
 		bus:           bus,
 		reportInterval: reportInterval,
 		robotStatus:   statusMap,
+		subscribed:    make(map[api.RobotID]bool),
+		dispatched:    make(map[api.RobotID]bool),
+		steps:         make(map[api.TaskID]api.RobotID),
+		seen:          idempotency.NewDedupe(dedupeTTL),
+		clock:         clock.Real,
+		traffic:       DefaultTrafficPolicy(),
+		charging:      DefaultChargingPolicy(),
 	}
+	c.resetTrafficLocked()
+	return c
+}
+
+// SetClock sets the time source (default clock.Real). Call before Start or Run.
+func (c *Controller) SetClock(clk clock.Clock) {
+	c.clock = clk
+}
+
+// SetFacilityMap makes task assignment prefer the idle robot with the shortest travel distance to the
+// task's pickup, and routes transport tasks over the map (see SetTrafficPolicy). Nil restores round-robin
+// and bare tasks. The map's chargers inside the zone are handed out to robots (see SetChargingPolicy). Safe
+// to call while running, e.g. when the map is replaced; routes issued on the old map are forgotten.
+func (c *Controller) SetFacilityMap(m *facility.Map) {
+	c.mu.Lock()
+	c.facility = m
+	c.resetTrafficLocked()
+	c.resetChargersLocked()
+	c.mu.Unlock()
 }
 
 // Run subscribes to zone tasks and robot status, and periodically publishes zone summary. Blocks until ctx is done.
 func (c *Controller) Run(ctx context.Context) error {
-	if err := c.bus.Subscribe(ctx, messaging.TopicZoneTasks, c.handleZoneTask); err != nil {
+	if err := c.Start(ctx); err != nil {
 		return err
 	}
-	if err := c.bus.Subscribe(ctx, messaging.TopicRobotStatus, c.handleRobotStatus); err != nil {
+	<-ctx.Done()
+	return nil
+}
+
+// Start subscribes and schedules firmware rollout progress, the zone summary, traffic checks and charger
+// assignment on the controller's clock, then returns. The periodic work stops when ctx is done.
+func (c *Controller) Start(ctx context.Context) error {
+	if err := messaging.SubscribeKeys(ctx, c.bus, messaging.TopicZoneTasks, []string{string(c.zoneID)}, c.handleZoneTask); err != nil {
+		return err
+	}
+	c.mu.RLock()
+	robots := c.robots
+	c.mu.RUnlock()
+	if err := c.subscribeRobots(ctx, robots); err != nil {
 		return err
 	}
 
-	ticker := time.NewTicker(c.reportInterval)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		case <-ticker.C:
-			c.publishZoneSummary(ctx)
-		}
+	report := func() {
+		c.advanceFirmwareRollouts(ctx)
+		c.publishZoneSummary(ctx)
 	}
+	c.mu.Lock()
+	c.chargeWindow = c.clock.Now()
+	c.report = report
+	c.stopReport = clock.Every(c.clock, c.reportInterval, report)
+	c.mu.Unlock()
+	context.AfterFunc(ctx, func() {
+		c.mu.Lock()
+		c.report = nil
+		c.stopReport()
+		c.mu.Unlock()
+	})
+	stopTraffic := clock.Every(c.clock, trafficInterval, func() {
+		c.resolveTraffic(ctx)
+		c.dispatchHeld(ctx)
+	})
+	context.AfterFunc(ctx, stopTraffic)
+	stopCharging := clock.Every(c.clock, chargingInterval, func() { c.manageCharging(ctx) })
+	context.AfterFunc(ctx, stopCharging)
+	return nil
 }
 
 func (c *Controller) handleZoneTask(key string, value []byte) error {
@@ -79,12 +166,42 @@ func (c *Controller) handleZoneTask(key string, value []byte) error {
 	if task.ZoneID != c.zoneID {
 		return nil
 	}
-	if len(c.robots) == 0 {
+	if !c.seen.First(string(task.ID), c.clock.Now()) {
+		log.Printf("zone %s: dropping redelivered task %s", c.zoneID, task.ID)
+		return nil
+	}
+	if err := c.dispatchTask(task); err != nil {
+		c.seen.Forget(string(task.ID)) // let a redelivery try again
+		return err
+	}
+	return nil
+}
+
+// dispatchTask carries out a zone task: a safety broadcast or firmware rollout to every robot, anything
+// else as a command to one robot.
+func (c *Controller) dispatchTask(task api.ZoneTask) error {
+	c.mu.RLock()
+	robotCount := len(c.robots)
+	c.mu.RUnlock()
+	if robotCount == 0 {
 		log.Printf("zone %s: no robots, dropping task %s", c.zoneID, task.ID)
+		c.stepFailed(&task, "no robots")
 		return nil
 	}
-	cmdType := "TASK"
-	if len(task.Payload) > 0 {
+	cmdType := taskCommandType(&task)
+	if len(task.Payload) > 0 && task.StepID == "" {
+		var safety struct {
+			Type string `json:"type"`
+			api.SafetyPayload
+		}
+		if json.Unmarshal(task.Payload, &safety) == nil {
+			switch safety.Type {
+			case api.ZoneTaskTypeStopAll:
+				return c.broadcastSafety(context.Background(), task.ID, api.RobotCommandTypeEStop, safety.SafetyPayload)
+			case api.ZoneTaskTypeResetAll:
+				return c.broadcastSafety(context.Background(), task.ID, api.RobotCommandTypeReset, safety.SafetyPayload)
+			}
+		}
 		var maybeFw struct {
 			Type       string `json:"type"`
 			CampaignID string `json:"campaign_id"`
@@ -96,40 +213,167 @@ func (c *Controller) handleZoneTask(key string, value []byte) error {
 	}
 
 	if cmdType == api.RobotCommandTypeFirmwareUpdate {
-		// Broadcast firmware to all robots in zone; each may apply when IDLE (busy robots defer).
-		for _, robotID := range c.robots {
-			cmd := &api.RobotCommand{
-				ID:        api.TaskID(string(task.ID) + "-" + string(robotID)),
-				RobotID:   robotID,
-				Type:      api.RobotCommandTypeFirmwareUpdate,
-				Payload:   task.Payload,
-				CreatedAt: time.Now().UTC(),
-			}
-			if err := c.cmdPub.PublishRobotCommand(context.Background(), cmd); err != nil {
-				log.Printf("zone %s: publish robot command %s: %v", c.zoneID, robotID, err)
-				return err
-			}
-		}
-		log.Printf("zone %s: firmware task %s -> %d robots (broadcast)", c.zoneID, task.ID, len(c.robots))
-		return nil
+		// Roll out to all robots in zone; each may apply when IDLE and its policy allows (busy robots defer).
+		// With a holdback, robots beyond the concurrency limit are queued and released as others finish.
+		return c.startFirmwareRollout(context.Background(), task)
 	}
 
-	robotID := c.robots[int(c.cmdSeq.Add(1))%len(c.robots)]
+	robotID, reason := c.taskRobot(&task)
+	switch {
+	case robotID != "":
+		return c.sendTask(context.Background(), &task, robotID, cmdType)
+	case reason != "":
+		log.Printf("zone %s: rejecting task %s: %s", c.zoneID, task.ID, reason)
+		c.stepFailed(&task, reason)
+	default:
+		c.holdTask(task)
+	}
+	return nil
+}
+
+// sendTask publishes a task to robotID, routed over the facility map if it has one.
+func (c *Controller) sendTask(ctx context.Context, task *api.ZoneTask, robotID api.RobotID, cmdType string) error {
+	c.trackStep(task, robotID)
 	cmd := &api.RobotCommand{
 		ID:        task.ID,
 		RobotID:   robotID,
 		Type:      cmdType,
-		Payload:   task.Payload,
-		CreatedAt: time.Now().UTC(),
+		Payload:   c.routeTask(robotID, task.ID, task.Payload),
+		CreatedAt: c.clock.Now().UTC(),
 	}
-	if err := c.cmdPub.PublishRobotCommand(context.Background(), cmd); err != nil {
+	if err := c.cmdPub.PublishRobotCommand(ctx, cmd); err != nil {
 		log.Printf("zone %s: publish robot command: %v", c.zoneID, err)
+		c.stepFailed(task, "publish robot command: "+err.Error())
 		return err
 	}
 	log.Printf("zone %s: task %s -> robot %s", c.zoneID, task.ID, robotID)
 	return nil
 }
 
+// taskCommandType is the command a task goes out as: its type for a task graph step, else TASK.
+func taskCommandType(task *api.ZoneTask) string {
+	if task.StepID != "" && task.Type != "" {
+		return task.Type
+	}
+	return "TASK"
+}
+
+// nextRobot picks the next robot round-robin, preferring robots that last reported IDLE and have not been
+// given a task since; with a facility map, the nearest such robot to the task's pickup. Next come robots on
+// an opportunistic charge, which end it for the task. Otherwise it skips robots that are STOPPED (their
+// edge would reject the command), CHARGING, in ERROR or waiting for a charger; the edge queues the task
+// behind the current one. Falls back to plain round-robin. Only robots that meet req are considered; ok is
+// false if there are none. A task routed over the facility map only goes to an idle robot the map has room
+// for (see routableLocked); without one the robot is "" and ok is true, and the task waits in the zone.
+func (c *Controller) nextRobot(payload []byte, req *api.Requirements) (api.RobotID, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	capable, ok := c.capableLocked(req)
+	if !ok {
+		return "", false
+	}
+	start := int(c.cmdSeq.Add(1))
+	idle := func(id api.RobotID, st *api.RobotStatus) bool {
+		return capable(id) && (st == nil || st.State == "IDLE") && !c.dispatched[id] && !c.needsChargeLocked(id, st)
+	}
+	interruptible := func(id api.RobotID, st *api.RobotStatus) bool {
+		return capable(id) && !c.dispatched[id] && c.interruptibleLocked(id, st)
+	}
+	available := func(id api.RobotID, st *api.RobotStatus) bool {
+		return capable(id) && (st == nil || (st.State != api.RobotStateStopped && st.State != api.RobotStateCharging && st.State != "ERROR")) && !c.needsChargeLocked(id, st)
+	}
+	anyCapable := func(id api.RobotID, _ *api.RobotStatus) bool { return capable(id) }
+	candidates := []func(api.RobotID, *api.RobotStatus) bool{idle, interruptible, available, anyCapable}
+	_, _, routed := c.taskStopsLocked(payload)
+	if routed {
+		free, routable := idle, c.routableLocked()
+		idle = func(id api.RobotID, st *api.RobotStatus) bool { return free(id, st) && routable(id, st) }
+		candidates = []func(api.RobotID, *api.RobotStatus) bool{idle}
+	}
+	if id, ok := c.nearestIdle(payload, start, idle); ok {
+		c.dispatched[id] = true
+		return id, true
+	}
+	for _, ok := range candidates {
+		for i := 0; i < len(c.robots); i++ {
+			id := c.robots[(start+i)%len(c.robots)]
+			if ok(id, c.robotStatus[id]) {
+				if i > 0 {
+					c.cmdSeq.Add(uint64(i))
+				}
+				c.dispatched[id] = true
+				return id, true
+			}
+		}
+	}
+	return "", routed
+}
+
+// nearestIdle returns the idle robot with the shortest travel distance to the task's pickup on the facility
+// map; ties go round-robin from start. ok is false without a map, a resolvable pickup or an idle robot with
+// a known, connected position. Caller holds c.mu.
+func (c *Controller) nearestIdle(payload []byte, start int, idle func(api.RobotID, *api.RobotStatus) bool) (api.RobotID, bool) {
+	if c.facility == nil || len(payload) == 0 {
+		return "", false
+	}
+	var loc api.TaskLocations
+	if json.Unmarshal(payload, &loc) != nil {
+		return "", false
+	}
+	pickup, ok := c.facility.Resolve(loc.From, loc.FromNodeID, loc.FromLocationID)
+	if !ok {
+		return "", false
+	}
+	distance := c.facility.DistancesTo(pickup)
+	var best api.RobotID
+	bestD := math.Inf(1)
+	for i := 0; i < len(c.robots); i++ {
+		id := c.robots[(start+i)%len(c.robots)]
+		st := c.robotStatus[id]
+		if st == nil || !idle(id, st) {
+			continue
+		}
+		pos, ok := api.ParsePoint(st.Position)
+		if !ok {
+			continue
+		}
+		if d := distance(pos); d < bestD {
+			best, bestD = id, d
+		}
+	}
+	return best, best != ""
+}
+
+// StopAll e-stops every robot in the zone with one broadcast command; each edge enforces the stop locally.
+func (c *Controller) StopAll(ctx context.Context, reason, operator string) error {
+	return c.broadcastSafety(ctx, api.TaskID(fmt.Sprintf("stop-all-%s-%d", c.zoneID, c.clock.Now().UnixNano())), api.RobotCommandTypeEStop, api.SafetyPayload{Reason: reason, Operator: operator})
+}
+
+// ResetAll clears ESTOP / PROTECTIVE_STOP on every robot in the zone.
+func (c *Controller) ResetAll(ctx context.Context, reason, operator string) error {
+	return c.broadcastSafety(ctx, api.TaskID(fmt.Sprintf("reset-all-%s-%d", c.zoneID, c.clock.Now().UnixNano())), api.RobotCommandTypeReset, api.SafetyPayload{Reason: reason, Operator: operator})
+}
+
+func (c *Controller) broadcastSafety(ctx context.Context, id api.TaskID, cmdType string, p api.SafetyPayload) error {
+	payload, _ := json.Marshal(p)
+	cmd := &api.RobotCommand{
+		ID:        id,
+		ZoneID:    c.zoneID,
+		Type:      cmdType,
+		Payload:   payload,
+		CreatedAt: c.clock.Now().UTC(),
+	}
+	if err := c.cmdPub.PublishRobotCommand(ctx, cmd); err != nil {
+		log.Printf("zone %s: publish %s broadcast: %v", c.zoneID, cmdType, err)
+		return err
+	}
+	c.mu.RLock()
+	robotCount := len(c.robots)
+	c.mu.RUnlock()
+	log.Printf("zone %s: %s broadcast %s to %d robots (reason %q, operator %q)", c.zoneID, cmdType, id, robotCount, p.Reason, p.Operator)
+	return nil
+}
+
 func (c *Controller) handleRobotStatus(key string, value []byte) error {
 	var status api.RobotStatus
 	if err := json.Unmarshal(value, &status); err != nil {
@@ -140,21 +384,22 @@ func (c *Controller) handleRobotStatus(key string, value []byte) error {
 	}
 	c.mu.Lock()
 	c.robotStatus[status.RobotID] = &status
+	delete(c.dispatched, status.RobotID)
+	c.routeStatusLocked(&status)
 	c.mu.Unlock()
 	return nil
 }
 
 func (c *Controller) ownsRobot(r api.RobotID) bool {
-	for _, id := range c.robots {
-		if id == r {
-			return true
-		}
-	}
-	return false
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.robotStatus[r]
+	return ok
 }
 
 func (c *Controller) publishZoneSummary(ctx context.Context) {
-	c.mu.RLock()
+	now := c.clock.Now()
+	c.mu.Lock()
 	robotCount := len(c.robots)
 	healthy := 0
 	busy := 0
@@ -168,15 +413,18 @@ func (c *Controller) publishZoneSummary(ctx context.Context) {
 			}
 		}
 	}
-	c.mu.RUnlock()
-
 	sum := &api.ZoneSummary{
 		ZoneID:     c.zoneID,
 		RobotCount: robotCount,
 		Healthy:    healthy,
 		Busy:       busy,
-		UpdatedAt:  time.Now().UTC(),
+		UpdatedAt:  now.UTC(),
 	}
+	c.chargerUsageLocked(sum, now)
+	sum.TaskQueue = len(c.held)
+	sum.Models = c.modelCountsLocked()
+	c.mu.Unlock()
+
 	if err := c.summaryPub.PublishZoneSummary(ctx, sum); err != nil {
 		log.Printf("zone %s: publish zone summary: %v", c.zoneID, err)
 	}
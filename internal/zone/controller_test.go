@@ -0,0 +1,52 @@
+package zone
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/clock"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+// TestRedeliveredTaskDropped publishes a zone task twice, as an at-least-once bus may, and expects one
+// broadcast until the task ID has been forgotten.
+func TestRedeliveredTaskDropped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clk := clock.NewVirtual(time.Date(2025, 1, 6, 6, 0, 0, 0, time.UTC))
+	bus := messaging.NewMemoryBus()
+	estops := 0
+	if err := bus.Subscribe(ctx, messaging.TopicRobotCommands, func(_ string, value []byte) error {
+		var cmd api.RobotCommand
+		if err := json.Unmarshal(value, &cmd); err != nil {
+			return err
+		}
+		if cmd.Type == api.RobotCommandTypeEStop {
+			estops++
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	ctrl := NewController("zone-1", []api.RobotID{"robot-1"}, messaging.NewRobotCommandPublisher(bus), messaging.NewZoneSummaryPublisher(bus), bus, 5*time.Second)
+	ctrl.SetClock(clk)
+	if err := ctrl.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	payload, _ := json.Marshal(map[string]string{"type": api.ZoneTaskTypeStopAll, "reason": "test"})
+	task := &api.ZoneTask{ID: "stop-1", ZoneID: "zone-1", Payload: payload}
+	pub := messaging.NewZoneTaskPublisher(bus)
+	for _, wait := range []time.Duration{0, time.Minute, dedupeTTL} {
+		clk.RunFor(wait)
+		if err := pub.PublishZoneTask(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if estops != 2 {
+		t.Errorf("%d ESTOP broadcasts, want 2: one for the task, one after its ID was forgotten", estops)
+	}
+}
@@ -0,0 +1,214 @@
+package zone
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// firmwareReportTimeout is how long a robot updating firmware may go without a status naming its command
+// before the zone counts the update as failed and frees its holdback slot.
+const firmwareReportTimeout = 10 * time.Minute
+
+// firmwareRollout tracks one firmware task across the zone's robots.
+type firmwareRollout struct {
+	taskID   api.TaskID
+	version  string
+	payload  []byte                    // FirmwareUpdatePayload with the merged policy
+	holdback int                       // percent of the zone kept out of the update at any time
+	queue    []api.RobotID             // held back, not yet dispatched
+	active   map[api.RobotID]time.Time // dispatched and not done; when the robot last reported on its command
+}
+
+// commandID is the ID of the FIRMWARE_UPDATE command the rollout sends robotID.
+func (r *firmwareRollout) commandID(robotID api.RobotID) string {
+	return string(r.taskID) + "-" + string(robotID)
+}
+
+// SetFirmwarePolicy sets zone-level firmware constraints (maintenance windows, battery, holdback).
+// They are merged with each campaign's policy; the stricter setting wins.
+func (c *Controller) SetFirmwarePolicy(p api.FirmwarePolicy) {
+	c.mu.Lock()
+	c.firmwarePolicy = p
+	c.mu.Unlock()
+}
+
+func (c *Controller) startFirmwareRollout(ctx context.Context, task api.ZoneTask) error {
+	var payload api.FirmwareUpdatePayload
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return err
+	}
+	c.mu.RLock()
+	zonePolicy := c.firmwarePolicy
+	c.mu.RUnlock()
+	payload.Policy = mergeFirmwarePolicy(payload.Policy, zonePolicy)
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	r := &firmwareRollout{
+		taskID:   task.ID,
+		version:  payload.Version,
+		payload:  data,
+		holdback: holdbackPercent(payload.Policy),
+		active:   make(map[api.RobotID]time.Time),
+	}
+	// Robots known to be another model are left out; the edge skips the update for them anyway.
+	otherModel := 0
+	for _, robotID := range c.robots {
+		if st := c.robotStatus[robotID]; payload.ModelID != "" && st != nil {
+			if model, _ := st.Extra[api.ExtraModelID].(string); model != "" && model != payload.ModelID {
+				otherModel++
+				continue
+			}
+		}
+		r.queue = append(r.queue, robotID)
+	}
+	c.firmwareRollouts = append(c.firmwareRollouts, r)
+	robotCount := len(r.queue)
+	c.mu.Unlock()
+	if otherModel > 0 {
+		log.Printf("zone %s: firmware task %s skips %d robots of other models than %s", c.zoneID, task.ID, otherModel, payload.ModelID)
+	}
+	sent := c.advanceFirmwareRollouts(ctx)
+	held := robotCount - sent
+	if held > 0 {
+		log.Printf("zone %s: firmware task %s -> %d robots, %d held back (holdback %d%%)", c.zoneID, task.ID, sent, held, r.holdback)
+	} else {
+		log.Printf("zone %s: firmware task %s -> %d robots (broadcast)", c.zoneID, task.ID, sent)
+	}
+	return nil
+}
+
+// advanceFirmwareRollouts retires robots that finished updating or stopped reporting on their command, and
+// dispatches queued robots up to the holdback limit. Returns the number of commands published.
+func (c *Controller) advanceFirmwareRollouts(ctx context.Context) int {
+	var cmds []*api.RobotCommand
+	var owners []*firmwareRollout // owners[i] is the rollout cmds[i] belongs to
+	c.mu.Lock()
+	if len(c.firmwareRollouts) == 0 {
+		c.mu.Unlock()
+		return 0
+	}
+	now := c.clock.Now().UTC()
+	inFlight := 0
+	for _, r := range c.firmwareRollouts {
+		for robotID, heard := range r.active {
+			st := c.robotStatus[robotID]
+			switch {
+			case firmwareDone(st, r.version, r.commandID(robotID)):
+				delete(r.active, robotID)
+			case st != nil && st.Extra[api.ExtraFirmwareCommandID] == r.commandID(robotID):
+				r.active[robotID] = now
+			case now.Sub(heard) >= firmwareReportTimeout:
+				log.Printf("zone %s: firmware task %s: no report from robot %s for %v, counted as failed", c.zoneID, r.taskID, robotID, firmwareReportTimeout)
+				delete(r.active, robotID)
+			}
+		}
+		inFlight += len(r.active)
+	}
+	remaining := c.firmwareRollouts[:0]
+	for _, r := range c.firmwareRollouts {
+		limit := firmwareConcurrencyLimit(len(c.robots), r.holdback)
+		for len(r.queue) > 0 && inFlight < limit {
+			robotID := r.queue[0]
+			r.queue = r.queue[1:]
+			r.active[robotID] = now
+			inFlight++
+			cmds = append(cmds, &api.RobotCommand{
+				ID:        api.TaskID(r.commandID(robotID)),
+				RobotID:   robotID,
+				Type:      api.RobotCommandTypeFirmwareUpdate,
+				Payload:   r.payload,
+				CreatedAt: now,
+			})
+			owners = append(owners, r)
+		}
+		if len(r.queue) > 0 || len(r.active) > 0 {
+			remaining = append(remaining, r)
+		} else {
+			log.Printf("zone %s: firmware task %s finished", c.zoneID, r.taskID)
+		}
+	}
+	c.firmwareRollouts = remaining
+	c.mu.Unlock()
+
+	sent := 0
+	for i, cmd := range cmds {
+		if err := c.cmdPub.PublishRobotCommand(ctx, cmd); err != nil {
+			log.Printf("zone %s: publish robot command %s: %v", c.zoneID, cmd.RobotID, err)
+			// Free the holdback slot and retry the robot on the next tick.
+			r := owners[i]
+			c.mu.Lock()
+			delete(r.active, cmd.RobotID)
+			r.queue = append([]api.RobotID{cmd.RobotID}, r.queue...)
+			c.mu.Unlock()
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
+// firmwareConcurrencyLimit is how many robots may be updating at once so holdbackPercent of the zone stays available.
+func firmwareConcurrencyLimit(robots, holdbackPercent int) int {
+	if holdbackPercent <= 0 {
+		return robots
+	}
+	held := (robots*holdbackPercent + 99) / 100
+	if limit := robots - held; limit > 0 {
+		return limit
+	}
+	return 1
+}
+
+// firmwareDone reports whether a robot's latest status shows the update finished: the target version, or a
+// failure or skip reported for commandID. A failure left over from an earlier update does not count.
+func firmwareDone(status *api.RobotStatus, version, commandID string) bool {
+	if status == nil || status.Extra == nil {
+		return false
+	}
+	if v, _ := status.Extra[api.ExtraFirmwareVersion].(string); v == version {
+		return true
+	}
+	if id, _ := status.Extra[api.ExtraFirmwareCommandID].(string); id != commandID {
+		return false
+	}
+	switch status.Extra[api.ExtraFirmwareUpdateStatus] {
+	case api.FirmwareStatusFailed, api.FirmwareStatusRollback, api.FirmwareStatusSkipped:
+		return true
+	}
+	return false
+}
+
+func holdbackPercent(p *api.FirmwarePolicy) int {
+	if p == nil {
+		return 0
+	}
+	return p.HoldbackPercent
+}
+
+// mergeFirmwarePolicy combines campaign and zone constraints: both window sets must be open, the higher
+// battery floor and holdback apply, and charging-only applies if either requires it. Returns nil if unconstrained.
+func mergeFirmwarePolicy(campaign *api.FirmwarePolicy, zone api.FirmwarePolicy) *api.FirmwarePolicy {
+	merged := zone
+	if campaign != nil {
+		merged.MaintenanceWindows = campaign.MaintenanceWindows
+		if campaign.MinBattery > merged.MinBattery {
+			merged.MinBattery = campaign.MinBattery
+		}
+		merged.ChargingOnly = merged.ChargingOnly || campaign.ChargingOnly
+		if campaign.HoldbackPercent > merged.HoldbackPercent {
+			merged.HoldbackPercent = campaign.HoldbackPercent
+		}
+	}
+	if len(merged.MaintenanceWindows) == 0 && len(merged.ZoneMaintenanceWindows) == 0 &&
+		merged.MinBattery == 0 && !merged.ChargingOnly && merged.HoldbackPercent == 0 {
+		return nil
+	}
+	return &merged
+}
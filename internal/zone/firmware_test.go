@@ -0,0 +1,113 @@
+package zone
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/internal/edge"
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/clock"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+func TestFirmwareDone(t *testing.T) {
+	status := func(version, fwStatus, commandID string) *api.RobotStatus {
+		return &api.RobotStatus{Extra: map[string]interface{}{
+			api.ExtraFirmwareVersion:      version,
+			api.ExtraFirmwareUpdateStatus: fwStatus,
+			api.ExtraFirmwareCommandID:    commandID,
+		}}
+	}
+	for _, tc := range []struct {
+		name   string
+		status *api.RobotStatus
+		want   bool
+	}{
+		{"no status", nil, false},
+		{"target version", status("2.0.0", api.FirmwareStatusSuccess, "fw-1-robot-1"), true},
+		{"deferred", status("1.0.0", api.FirmwareStatusDeferred, "fw-2-robot-1"), false},
+		{"failed", status("1.0.0", api.FirmwareStatusFailed, "fw-2-robot-1"), true},
+		{"rolled back", status("1.0.0", api.FirmwareStatusRollback, "fw-2-robot-1"), true},
+		{"skipped", status("1.0.0", api.FirmwareStatusSkipped, "fw-2-robot-1"), true},
+		{"failed earlier rollout", status("1.0.0", api.FirmwareStatusFailed, "fw-1-robot-1"), false},
+		{"failed without command", status("1.0.0", api.FirmwareStatusFailed, ""), false},
+	} {
+		if got := firmwareDone(tc.status, "2.0.0", "fw-2-robot-1"); got != tc.want {
+			t.Errorf("%s: firmwareDone = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// TestFirmwareRolloutFinishes rolls out firmware for stub-model with a holdback that lets one robot update at
+// a time. robot-2 is another model and robot-3 never reports; the rollout must still update robot-1 and
+// finish, whether or not the zone knows the models when the task arrives.
+func TestFirmwareRolloutFinishes(t *testing.T) {
+	for _, statusFirst := range []bool{true, false} {
+		ctx, cancel := context.WithCancel(context.Background())
+		clk := clock.NewVirtual(time.Date(2025, 1, 6, 6, 0, 0, 0, time.UTC))
+		bus := messaging.NewMemoryBus()
+		robots := []api.RobotID{"robot-1", "robot-2", "robot-3"}
+		commands := make(map[api.RobotID]int)
+		if err := bus.Subscribe(ctx, messaging.TopicRobotCommands, func(_ string, value []byte) error {
+			var cmd api.RobotCommand
+			if err := json.Unmarshal(value, &cmd); err != nil {
+				return err
+			}
+			if cmd.Type == api.RobotCommandTypeFirmwareUpdate {
+				commands[cmd.RobotID]++
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		ctrl := NewController("zone-1", robots, messaging.NewRobotCommandPublisher(bus), messaging.NewZoneSummaryPublisher(bus), bus, 5*time.Second)
+		ctrl.SetClock(clk)
+		sim := edge.NewSimulator("zone-1", robots[:2], messaging.NewRobotStatusPublisher(bus), bus, time.Second, time.Minute)
+		cfg := edge.DefaultSimConfig()
+		cfg.Models = []edge.SimModel{{ModelID: "stub-model"}, {ModelID: "other-model"}}
+		cfg.RobotModels = map[string]string{"robot-1": "stub-model", "robot-2": "other-model"}
+		sim.SetSimConfig(cfg)
+		sim.SetClock(clk)
+		for _, start := range []func(context.Context) error{ctrl.Start, sim.Start} {
+			if err := start(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if statusFirst {
+			clk.RunFor(5 * time.Second)
+		}
+		payload, _ := json.Marshal(map[string]interface{}{
+			"type": "firmware_update", "campaign_id": "c1", "version": "2.0.0", "model_id": "stub-model",
+			"download_url": "https://example.com/fw.bin", "checksum_sha256": "abc",
+			"policy": api.FirmwarePolicy{HoldbackPercent: 50},
+		})
+		if err := messaging.NewZoneTaskPublisher(bus).PublishZoneTask(ctx, &api.ZoneTask{ID: "fw-1", ZoneID: "zone-1", Payload: payload}); err != nil {
+			t.Fatal(err)
+		}
+		clk.RunFor(firmwareReportTimeout + 5*time.Minute)
+
+		ctrl.mu.RLock()
+		rollouts := len(ctrl.firmwareRollouts)
+		version, _ := ctrl.robotStatus["robot-1"].Extra[api.ExtraFirmwareVersion].(string)
+		ctrl.mu.RUnlock()
+		if rollouts != 0 {
+			t.Errorf("status first %v: %d rollouts still running", statusFirst, rollouts)
+		}
+		if version != "2.0.0" {
+			t.Errorf("status first %v: robot-1 on firmware %q, want 2.0.0", statusFirst, version)
+		}
+		// Known to be another model, robot-2 is left out; otherwise its edge skips the update.
+		want := map[api.RobotID]int{"robot-1": 1, "robot-2": 1, "robot-3": 1}
+		if statusFirst {
+			delete(want, "robot-2")
+		}
+		if !reflect.DeepEqual(commands, want) {
+			t.Errorf("status first %v: FIRMWARE_UPDATE commands %v, want %v", statusFirst, commands, want)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,174 @@
+package zone
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/clock"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+// reloadable are the settings Reload applies to a running controller; other changes need a restart.
+var reloadable = []string{"zone.robots", "zone.report_interval", "zone.firmware", "zone.traffic", "zone.charging"}
+
+// Reload applies the reloadable settings of next, a validated config read again from the same file, and
+// returns the config now in effect: cur with those settings taken from next. Other changed settings are
+// logged as needing a restart.
+func (c *Controller) Reload(ctx context.Context, cur, next *Config) *Config {
+	eff := *cur
+	var applied, restart []string
+	for _, p := range config.Changed(cur, next) {
+		if config.Under(p, reloadable...) {
+			applied = append(applied, p)
+		} else {
+			restart = append(restart, p)
+		}
+	}
+	z := next.Zone
+	if !reflect.DeepEqual(cur.Zone.Robots, z.Robots) {
+		robots := make([]api.RobotID, len(z.Robots))
+		for i, r := range z.Robots {
+			robots[i] = api.RobotID(r)
+		}
+		if err := c.SetRobots(ctx, robots); err != nil {
+			log.Printf("zone %s: reload robots: %v", c.zoneID, err)
+		} else {
+			eff.Zone.Robots = z.Robots
+		}
+	}
+	if cur.Zone.ReportInterval != z.ReportInterval {
+		d, _ := z.ReportPeriod()
+		c.SetReportInterval(d)
+		eff.Zone.ReportInterval = z.ReportInterval
+	}
+	if !reflect.DeepEqual(cur.Zone.Firmware, z.Firmware) {
+		c.SetFirmwarePolicy(z.Firmware.Policy())
+		eff.Zone.Firmware = z.Firmware
+	}
+	if !reflect.DeepEqual(cur.Zone.Traffic, z.Traffic) {
+		p, _ := z.Traffic.Policy()
+		c.SetTrafficPolicy(p)
+		eff.Zone.Traffic = z.Traffic
+	}
+	if !reflect.DeepEqual(cur.Zone.Charging, z.Charging) {
+		p, _ := z.Charging.Policy()
+		c.SetChargingPolicy(p)
+		eff.Zone.Charging = z.Charging
+	}
+	if len(applied) > 0 {
+		log.Printf("zone %s: config reloaded: %s", c.zoneID, strings.Join(applied, ", "))
+	}
+	if len(restart) > 0 {
+		log.Printf("zone %s: config: %s changed; restart to apply", c.zoneID, strings.Join(restart, ", "))
+	}
+	return &eff
+}
+
+// SetRobots replaces the robots the zone dispatches to. Added robots are subscribed to on ctx, the context
+// the controller runs with; removed robots lose their status, route, charger and pending firmware updates
+// and get no further tasks. Safe to call while running.
+func (c *Controller) SetRobots(ctx context.Context, robots []api.RobotID) error {
+	keep := make(map[api.RobotID]bool, len(robots))
+	var added []api.RobotID
+	now := c.clock.Now()
+	c.mu.Lock()
+	for _, r := range robots {
+		keep[r] = true
+		if _, ok := c.robotStatus[r]; !ok {
+			c.robotStatus[r] = nil
+		}
+		if !c.subscribed[r] {
+			added = append(added, r)
+		}
+	}
+	for _, r := range c.robots {
+		if !keep[r] {
+			c.dropRobotLocked(r, now)
+		}
+	}
+	c.robots = append([]api.RobotID(nil), robots...)
+	running := c.report != nil
+	c.mu.Unlock()
+	if !running {
+		return nil
+	}
+	return c.subscribeRobots(ctx, added)
+}
+
+// subscribeRobots subscribes to the status and task results of robots.
+func (c *Controller) subscribeRobots(ctx context.Context, robots []api.RobotID) error {
+	if len(robots) == 0 {
+		return nil
+	}
+	keys := make([]string, len(robots))
+	for i, r := range robots {
+		keys[i] = string(r)
+	}
+	if err := messaging.SubscribeKeys(ctx, c.bus, messaging.TopicRobotStatus, keys, c.handleRobotStatus); err != nil {
+		return err
+	}
+	if err := messaging.SubscribeKeys(ctx, c.bus, messaging.TopicTaskResults, keys, c.handleTaskResult); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	for _, r := range robots {
+		c.subscribed[r] = true
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// dropRobotLocked forgets robot r after it left the zone. Its subscriptions stay; handleRobotStatus ignores
+// robots the zone does not own. Caller holds c.mu.
+func (c *Controller) dropRobotLocked(r api.RobotID, now time.Time) {
+	delete(c.robotStatus, r)
+	delete(c.dispatched, r)
+	delete(c.routes, r)
+	delete(c.trafficActed, r)
+	c.reservations.Release(string(r))
+	if s := c.chargerOfLocked(r); s != nil {
+		c.releaseChargerLocked(s, now)
+	}
+	queue := c.chargeQueue[:0]
+	for _, id := range c.chargeQueue {
+		if id != r {
+			queue = append(queue, id)
+		}
+	}
+	c.chargeQueue = queue
+	for _, ro := range c.firmwareRollouts {
+		q := ro.queue[:0]
+		for _, id := range ro.queue {
+			if id != r {
+				q = append(q, id)
+			}
+		}
+		ro.queue = q
+		delete(ro.active, r)
+	}
+}
+
+// SetReportInterval sets how often the zone summary is published. Safe to call while running.
+func (c *Controller) SetReportInterval(d time.Duration) {
+	c.mu.Lock()
+	c.reportInterval = d
+	report, stop := c.report, c.stopReport
+	c.mu.Unlock()
+	if report == nil {
+		return
+	}
+	stop()
+	next := clock.Every(c.clock, d, report)
+	c.mu.Lock()
+	if c.report == nil {
+		next() // stopped meanwhile
+	} else {
+		c.stopReport = next
+	}
+	c.mu.Unlock()
+}
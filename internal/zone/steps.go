@@ -0,0 +1,86 @@
+package zone
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+// SetStepResultPublisher sets where the results of task graph steps are reported to the area. Without it
+// the area only learns of a step's end by its timeout. Call before Start or Run.
+func (c *Controller) SetStepResultPublisher(p *messaging.TaskResultPublisher) {
+	c.resultPub = p
+}
+
+// taskRobot returns the robot a task goes to: the robot a task graph step is pinned to, or the next robot
+// (see nextRobot). Without one it returns "" and the reason, or no reason if the task waits for a robot the
+// facility map has room for.
+func (c *Controller) taskRobot(task *api.ZoneTask) (api.RobotID, string) {
+	if task.RobotID == "" {
+		if id, ok := c.nextRobot(task.Payload, task.Requirements); ok {
+			return id, ""
+		}
+		return "", "no robot meets its requirements"
+	}
+	if !c.ownsRobot(task.RobotID) {
+		return "", "robot " + string(task.RobotID) + " is not in zone " + string(c.zoneID)
+	}
+	c.mu.Lock()
+	c.dispatched[task.RobotID] = true
+	c.mu.Unlock()
+	return task.RobotID, ""
+}
+
+// trackStep remembers that robotID was given a task graph step, so its result is forwarded to the area.
+func (c *Controller) trackStep(task *api.ZoneTask, robotID api.RobotID) {
+	if task.StepID == "" {
+		return
+	}
+	c.mu.Lock()
+	c.steps[task.ID] = robotID
+	c.mu.Unlock()
+}
+
+// handleTaskResult forwards the result of a task graph step to the area.
+func (c *Controller) handleTaskResult(key string, value []byte) error {
+	var res api.TaskResult
+	if err := json.Unmarshal(value, &res); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	_, ok := c.steps[res.TaskID]
+	delete(c.steps, res.TaskID)
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	res.ZoneID = c.zoneID
+	c.publishStepResult(&res)
+	return nil
+}
+
+// stepFailed reports a task graph step the zone could not dispatch.
+func (c *Controller) stepFailed(task *api.ZoneTask, reason string) {
+	if task.StepID == "" {
+		return
+	}
+	c.publishStepResult(&api.TaskResult{
+		TaskID: task.ID,
+		ZoneID: c.zoneID,
+		Status: api.TaskResultFailed,
+		Error:  reason,
+		At:     c.clock.Now().UTC(),
+	})
+}
+
+func (c *Controller) publishStepResult(res *api.TaskResult) {
+	if c.resultPub == nil {
+		return
+	}
+	if err := c.resultPub.PublishStepResult(context.Background(), res); err != nil {
+		log.Printf("zone %s: publish step result: %v", c.zoneID, err)
+	}
+}
@@ -0,0 +1,725 @@
+package zone
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/facility"
+)
+
+// TrafficPolicy controls how a zone with a facility map routes its robots.
+type TrafficPolicy struct {
+	Disabled     bool          // send bare tasks; robots find their own way
+	Speed        float64       // m/s for robots that do not report speed_mps
+	Dwell        time.Duration // handling time planned at each pick and drop
+	Clearance    time.Duration // gap between two robots using the same node
+	Park         time.Duration // how long the last stop stays booked after the robot is done there
+	StallTimeout time.Duration // a robot blocked this long behind another is rerouted
+	MaxRobots    int           // robots on the map at once; 0 = a quarter of its nodes, at least one
+}
+
+// DefaultTrafficPolicy is the policy of a new controller.
+func DefaultTrafficPolicy() TrafficPolicy {
+	return TrafficPolicy{
+		Speed:        1.5,
+		Dwell:        20 * time.Second,
+		Clearance:    2 * time.Second,
+		Park:         30 * time.Second,
+		StallTimeout: 30 * time.Second,
+	}
+}
+
+// trafficInterval is how often the zone looks for blocked robots.
+const trafficInterval = 2 * time.Second
+
+// activeRoute is a route the zone issued and booked, kept until the robot reports it done.
+type activeRoute struct {
+	taskID  api.TaskID
+	steps   []facility.Step
+	planned time.Time // first planned or last rerouted
+}
+
+// SetTrafficPolicy sets how tasks are routed when a facility map is set.
+func (c *Controller) SetTrafficPolicy(p TrafficPolicy) {
+	c.mu.Lock()
+	c.traffic = p
+	c.mu.Unlock()
+}
+
+// resetTrafficLocked forgets every route and booking, e.g. when the map changes. Caller holds c.mu.
+func (c *Controller) resetTrafficLocked() {
+	c.reservations = facility.NewReservations()
+	c.routes = make(map[api.RobotID]*activeRoute)
+	c.trafficActed = make(map[api.RobotID]time.Time)
+}
+
+// routeTask plans and books a route through a transport task's pick and drop nodes for an idle robot and
+// returns the payload with the route added under "route". Without a map, a known robot position or
+// resolvable stops the payload is returned unchanged and the robot finds its own way.
+func (c *Controller) routeTask(robotID api.RobotID, taskID api.TaskID, payload []byte) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m, st := c.facility, c.robotStatus[robotID]
+	if st == nil || st.State != "IDLE" || c.routes[robotID] != nil {
+		return payload
+	}
+	pick, drop, ok := c.taskStopsLocked(payload)
+	if !ok {
+		return payload
+	}
+	start, ok := robotNode(m, st, avoidNodes(c.holdersLocked(), robotID, nil, nil))
+	if !ok {
+		return payload
+	}
+	now := c.clock.Now()
+	c.reservations.Prune(now)
+	stops := []string{pick, drop}
+	// A parked robot may stand on a stop; the route goes there anyway and the robot is moved aside once
+	// this one is waiting for it.
+	blocked := c.parkedLocked(robotID)
+	delete(blocked, pick)
+	delete(blocked, drop)
+	steps, err := c.planLocked(m, robotID, start, now, stops, blocked, false, c.traffic.Dwell)
+	if err != nil {
+		log.Printf("zone %s: task %s: no route for robot %s: %v", c.zoneID, taskID, robotID, err)
+		return payload
+	}
+	data, err := withRoute(m, payload, steps)
+	if err != nil {
+		return payload
+	}
+	c.reservations.Reserve(string(robotID), steps, c.traffic.Clearance, c.traffic.Park)
+	c.routes[robotID] = &activeRoute{taskID: taskID, steps: steps, planned: now}
+	return data
+}
+
+// taskStopsLocked returns the pick and drop nodes of a transport task payload. ok is false without a map,
+// with traffic disabled or if either stop does not resolve to a node. Caller holds c.mu.
+func (c *Controller) taskStopsLocked(payload []byte) (pick, drop string, ok bool) {
+	m := c.facility
+	if m == nil || c.traffic.Disabled || len(payload) == 0 {
+		return "", "", false
+	}
+	var loc api.TaskLocations
+	if json.Unmarshal(payload, &loc) != nil {
+		return "", "", false
+	}
+	pick, okPick := stopNode(m, loc.From, loc.FromNodeID, loc.FromLocationID)
+	drop, okDrop := stopNode(m, loc.To, loc.ToNodeID, loc.ToLocationID)
+	return pick, drop, okPick && okDrop
+}
+
+// mapCapacityLocked returns how many robots may be on the map at once. Robots waiting in a corridor without
+// passing places block everyone behind them, so the default leaves most of the map free. Caller holds c.mu.
+func (c *Controller) mapCapacityLocked() int {
+	if c.traffic.MaxRobots > 0 {
+		return c.traffic.MaxRobots
+	}
+	return max(1, len(c.facility.Nodes)/4)
+}
+
+// onMapLocked reports whether a robot holds a node or has a zone route. Caller holds c.mu.
+func (c *Controller) onMapLocked(id api.RobotID) bool {
+	if c.routes[id] != nil {
+		return true
+	}
+	if st := c.robotStatus[id]; st != nil {
+		n, _ := st.Extra[api.ExtraRouteNode].(string)
+		return n != ""
+	}
+	return false
+}
+
+// routableLocked returns which idle robots a routed task may go to: those already on the map and, while
+// fewer than mapCapacityLocked robots are, those whose entry node is free. Caller holds c.mu.
+func (c *Controller) routableLocked() func(api.RobotID, *api.RobotStatus) bool {
+	holder := c.holdersLocked()
+	onMap := 0
+	for _, id := range c.robots {
+		if c.onMapLocked(id) {
+			onMap++
+		}
+	}
+	room := onMap < c.mapCapacityLocked()
+	return func(id api.RobotID, st *api.RobotStatus) bool {
+		if st == nil || st.State != "IDLE" || c.routes[id] != nil {
+			return false
+		}
+		if c.onMapLocked(id) {
+			return true
+		}
+		_, ok := robotNode(c.facility, st, avoidNodes(holder, id, nil, nil))
+		return room && ok
+	}
+}
+
+// holdTask keeps a task no robot can be routed to yet, to be dispatched by dispatchHeld.
+func (c *Controller) holdTask(task api.ZoneTask) {
+	c.mu.Lock()
+	c.held = append(c.held, task)
+	n := len(c.held)
+	c.mu.Unlock()
+	log.Printf("zone %s: holding task %s until a robot can be routed to it (%d held)", c.zoneID, task.ID, n)
+}
+
+// dispatchHeld sends held tasks, in order, to robots that can now be routed to them.
+func (c *Controller) dispatchHeld(ctx context.Context) {
+	c.mu.Lock()
+	held := c.held
+	c.held = nil
+	c.mu.Unlock()
+	var keep []api.ZoneTask
+	for i := range held {
+		task := &held[i]
+		robotID, reason := c.taskRobot(task)
+		switch {
+		case robotID != "":
+			_ = c.sendTask(ctx, task, robotID, taskCommandType(task))
+		case reason != "":
+			log.Printf("zone %s: rejecting task %s: %s", c.zoneID, task.ID, reason)
+			c.stepFailed(task, reason)
+		default:
+			keep = append(keep, *task)
+		}
+	}
+	c.mu.Lock()
+	c.held = append(keep, c.held...)
+	c.mu.Unlock()
+}
+
+// planLocked plans a timed route for robot from start through stops, avoiding blocked nodes; with evacuate
+// the robot must leave start before others' bookings of it. The route is not booked. Caller holds c.mu.
+func (c *Controller) planLocked(m *facility.Map, robotID api.RobotID, start string, at time.Time, stops []string, blocked map[string]bool, evacuate bool, dwell time.Duration) ([]facility.Step, error) {
+	speed := c.traffic.Speed
+	if st := c.robotStatus[robotID]; st != nil {
+		if v, _ := st.Extra[api.ExtraSpeedMPS].(float64); v > 0 {
+			speed = v
+		}
+	}
+	return m.PlanTimed(c.reservations, facility.PlanRequest{
+		Holder:    string(robotID),
+		From:      start,
+		Start:     at,
+		Stops:     stops,
+		Speed:     speed,
+		Dwell:     dwell,
+		Clearance: c.traffic.Clearance,
+		Park:      c.traffic.Park,
+		Blocked:   blocked,
+		Evacuate:  evacuate,
+	})
+}
+
+// parkedLocked returns the nodes held by robots without a zone route, other than except: they stand there
+// until they are given something to do. Caller holds c.mu.
+func (c *Controller) parkedLocked(except api.RobotID) map[string]bool {
+	parked := make(map[string]bool)
+	for id, st := range c.robotStatus {
+		if id == except || st == nil || c.routes[id] != nil {
+			continue
+		}
+		if n, _ := st.Extra[api.ExtraRouteNode].(string); n != "" {
+			parked[n] = true
+		}
+	}
+	return parked
+}
+
+// holdersLocked maps each node a robot reports holding to that robot. Caller holds c.mu.
+func (c *Controller) holdersLocked() map[string]api.RobotID {
+	holder := make(map[string]api.RobotID)
+	for id, st := range c.robotStatus {
+		if st == nil {
+			continue
+		}
+		if n, _ := st.Extra[api.ExtraRouteNode].(string); n != "" {
+			holder[n] = id
+		}
+	}
+	return holder
+}
+
+// routeStatusLocked drops the route and bookings of a robot that finished, aborted or faulted. Caller holds c.mu.
+func (c *Controller) routeStatusLocked(status *api.RobotStatus) {
+	r := c.routes[status.RobotID]
+	if r == nil {
+		return
+	}
+	task, _ := status.Extra[api.ExtraRouteTask].(string)
+	switch {
+	case status.State == "ERROR" || status.State == api.RobotStateStopped:
+	case status.State != "BUSY" && task == string(r.taskID):
+	default:
+		return
+	}
+	c.reservations.Release(string(status.RobotID))
+	delete(c.routes, status.RobotID)
+}
+
+// resolveTraffic looks for robots waiting on each other. A cycle of waits is a deadlock: the robot whose
+// route was planned last is rerouted around the others. A robot waiting longer than StallTimeout is
+// rerouted, or the idle robot in its way is moved to a free neighbouring node.
+func (c *Controller) resolveTraffic(ctx context.Context) {
+	var cmds []*api.RobotCommand
+	c.mu.Lock()
+	m := c.facility
+	if m == nil || c.traffic.Disabled {
+		c.mu.Unlock()
+		return
+	}
+	now := c.clock.Now()
+	c.reservations.Prune(now)
+	holder := c.holdersLocked()
+	blockedBy := make(map[api.RobotID]api.RobotID)
+	for id, st := range c.robotStatus {
+		if st == nil {
+			continue
+		}
+		if n, _ := st.Extra[api.ExtraWaitingFor].(string); n != "" {
+			if h, ok := holder[n]; ok && h != id {
+				blockedBy[id] = h
+			}
+		}
+	}
+	handled := make(map[api.RobotID]bool)
+	for _, id := range c.robots {
+		h, waiting := blockedBy[id]
+		if !waiting || handled[id] {
+			continue
+		}
+		if cycle := waitCycle(blockedBy, id); cycle != nil {
+			// Robots with the newest routes yield first: older routes have been waited for longest.
+			var victims []api.RobotID
+			for _, r := range cycle {
+				handled[r] = true
+				if c.routes[r] != nil && now.Sub(c.trafficActed[r]) >= c.traffic.StallTimeout {
+					victims = append(victims, r)
+				}
+			}
+			sort.SliceStable(victims, func(i, j int) bool {
+				return c.routes[victims[i]].planned.After(c.routes[victims[j]].planned)
+			})
+			resolved := len(victims) == 0
+			for _, victim := range victims {
+				var deadlockCmds []*api.RobotCommand
+				if deadlockCmds, resolved = c.resolveDeadlockLocked(m, cycle, victim, now, holder); resolved {
+					cmds = append(cmds, deadlockCmds...)
+					break
+				}
+			}
+			if !resolved {
+				// Nobody in the cycle can get out of the way: the robots queued behind it make room, and the
+				// cycle is tried again once they have moved.
+				log.Printf("zone %s: robots %v deadlocked, none can make way yet", c.zoneID, cycle)
+				for _, r := range victims {
+					c.trafficActed[r] = now
+				}
+				inCycle := make(map[api.RobotID]bool, len(cycle))
+				for _, r := range cycle {
+					inCycle[r] = true
+				}
+				ahead := c.aheadLocked(cycle...)
+				for _, r := range c.robots {
+					if b, ok := blockedBy[r]; ok && inCycle[b] && !inCycle[r] && now.Sub(c.trafficActed[r]) >= c.traffic.StallTimeout {
+						handled[r] = true
+						c.reservations.Release(string(r))
+						cmds = appendCmd(cmds, c.rerouteLocked(m, r, now, holder, nil, ahead, "making way for deadlocked "+string(b)))
+					}
+				}
+			}
+			continue
+		}
+		if secs, _ := c.robotStatus[id].Extra[api.ExtraWaitingSecs].(float64); secs < c.traffic.StallTimeout.Seconds() {
+			continue
+		}
+		handled[id] = true
+		if hs := c.robotStatus[h]; hs != nil && hs.State == "IDLE" && c.routes[h] == nil && !c.dispatched[h] {
+			cmds = appendCmd(cmds, c.moveAsideLocked(m, h, id, now, holder))
+		} else if now.Sub(c.trafficActed[id]) >= c.traffic.StallTimeout {
+			// Whether h is busy or waiting behind others in turn, the robot goes around it or out of the
+			// way of where h is going.
+			c.reservations.Release(string(id))
+			cmds = appendCmd(cmds, c.rerouteLocked(m, id, now, holder, nil, c.aheadLocked(h), "stalled behind "+string(h)))
+		}
+	}
+	c.mu.Unlock()
+	for _, cmd := range cmds {
+		if err := c.cmdPub.PublishRobotCommand(ctx, cmd); err != nil {
+			log.Printf("zone %s: publish %s to %s: %v", c.zoneID, cmd.Type, cmd.RobotID, err)
+		}
+	}
+}
+
+// resolveDeadlockLocked breaks a cycle of robots waiting on each other. Their bookings no longer match
+// where they are, so the cycle is replanned from the nodes its robots hold. The victim takes a way around
+// the others if there is one. Otherwise it steps aside to a free neighbouring node and waits there while
+// the others are replanned, then continues around their new routes. ok is false if the victim can do
+// neither; the bookings are then left as they were. Caller holds c.mu.
+func (c *Controller) resolveDeadlockLocked(m *facility.Map, cycle []api.RobotID, victim api.RobotID, now time.Time, holder map[string]api.RobotID) ([]*api.RobotCommand, bool) {
+	r, start, stops, ok := c.remainingLocked(m, victim)
+	if !ok || start == "" {
+		return nil, false
+	}
+	inCycle := make(map[api.RobotID]bool, len(cycle))
+	for _, id := range cycle {
+		inCycle[id] = true
+		c.reservations.Release(string(id))
+	}
+	replanOthers := func(cmds []*api.RobotCommand) []*api.RobotCommand {
+		for _, id := range cycle {
+			if id != victim {
+				cmds = appendCmd(cmds, c.rerouteLocked(m, id, now, holder, map[api.RobotID]bool{victim: true}, nil, "in deadlock with "+string(victim)))
+			}
+		}
+		return cmds
+	}
+
+	// The others stay where they are until replanned, so the way around must not need their nodes, even
+	// as stops.
+	if steps, err := c.planLocked(m, victim, start, now, stops, avoidNodes(holder, victim, nil, nil), false, c.traffic.Dwell); err == nil {
+		c.trafficActed[victim] = now
+		return replanOthers(appendCmd(nil, c.bookRerouteLocked(m, victim, r, steps, now, "deadlock"))), true
+	}
+	var others []api.RobotID
+	for _, id := range cycle {
+		if id != victim {
+			others = append(others, id)
+		}
+	}
+	side, err := c.sideStepLocked(m, victim, start, now, avoidNodes(holder, victim, nil, nil), c.aheadLocked(others...))
+	if err != nil {
+		for _, id := range cycle {
+			if route := c.routes[id]; route != nil {
+				c.reservations.Reserve(string(id), route.steps, c.traffic.Clearance, c.traffic.Park)
+			}
+		}
+		return nil, false
+	}
+	c.trafficActed[victim] = now
+	c.reservations.Reserve(string(victim), side, c.traffic.Clearance, c.traffic.StallTimeout)
+	cmds := replanOthers(nil)
+	steps, err := c.continueLocked(m, victim, side, stops, avoidNodes(holder, victim, inCycle, stops))
+	c.reservations.Release(string(victim))
+	if err != nil {
+		c.reservations.Reserve(string(victim), r.steps, c.traffic.Clearance, c.traffic.Park)
+		log.Printf("zone %s: robot %s deadlock, no way around: %v", c.zoneID, victim, err)
+		return cmds, true
+	}
+	return appendCmd(cmds, c.bookRerouteLocked(m, victim, r, steps, now, "deadlock, stepping aside")), true
+}
+
+// rerouteLocked replans the rest of a robot's route from the node it holds, avoiding nodes held by robots
+// other than the movable ones, and returns the REROUTE command. A robot that has not entered the map yet
+// enters at the nearest free node instead. When there is no such route the robot first steps aside to a
+// free node, off keepClear if it can. The caller releases the robot's bookings; they are restored if no
+// route is found. Returns nil if the robot is not following a zone route or cannot be rerouted. Caller
+// holds c.mu.
+func (c *Controller) rerouteLocked(m *facility.Map, robotID api.RobotID, now time.Time, holder map[string]api.RobotID, movable map[api.RobotID]bool, keepClear map[string]bool, reason string) *api.RobotCommand {
+	r, start, stops, ok := c.remainingLocked(m, robotID)
+	if ok && start == "" {
+		start, ok = robotNode(m, c.robotStatus[robotID], avoidNodes(holder, robotID, nil, nil))
+	}
+	if !ok {
+		if r != nil {
+			c.reservations.Reserve(string(robotID), r.steps, c.traffic.Clearance, c.traffic.Park)
+		}
+		return nil
+	}
+	c.trafficActed[robotID] = now
+	avoid := avoidNodes(holder, robotID, movable, stops)
+	steps, err := c.planLocked(m, robotID, start, now, stops, avoid, false, c.traffic.Dwell)
+	if err != nil {
+		var side []facility.Step
+		if side, err = c.sideStepLocked(m, robotID, start, now, avoid, keepClear); err == nil {
+			steps, err = c.continueLocked(m, robotID, side, stops, avoid)
+		}
+	}
+	if err != nil {
+		c.reservations.Reserve(string(robotID), r.steps, c.traffic.Clearance, c.traffic.Park)
+		log.Printf("zone %s: robot %s %s, no way around: %v", c.zoneID, robotID, reason, err)
+		return nil
+	}
+	return c.bookRerouteLocked(m, robotID, r, steps, now, reason)
+}
+
+// bookRerouteLocked books a robot's new route and returns the REROUTE command for it, or nil if the robot
+// would drive the same nodes as before. Caller holds c.mu.
+func (c *Controller) bookRerouteLocked(m *facility.Map, robotID api.RobotID, r *activeRoute, steps []facility.Step, now time.Time, reason string) *api.RobotCommand {
+	c.reservations.Reserve(string(robotID), steps, c.traffic.Clearance, c.traffic.Park)
+	r.planned = now
+	if reached := len(r.steps) - len(steps); reached >= 0 && sameNodes(r.steps[reached:], steps) {
+		// The robot keeps driving the route it has; keep the steps it reached so its route_index still
+		// points into r.steps.
+		r.steps = append(r.steps[:reached:reached], steps...)
+		return nil
+	}
+	r.steps = steps
+	log.Printf("zone %s: robot %s %s, rerouted via %d nodes", c.zoneID, robotID, reason, len(steps))
+	return c.routeCommand(m, robotID, r.taskID, api.RobotCommandTypeReroute, steps)
+}
+
+// remainingLocked returns a robot's zone route, the node it holds and the stops it has not reached yet.
+// ok is false unless the robot reports following the route from a map node, or waiting to enter the map
+// at its first node; start is then "". Caller holds c.mu.
+func (c *Controller) remainingLocked(m *facility.Map, robotID api.RobotID) (r *activeRoute, start string, stops []string, ok bool) {
+	r, st := c.routes[robotID], c.robotStatus[robotID]
+	if r == nil || st == nil {
+		return r, "", nil, false
+	}
+	if task, _ := st.Extra[api.ExtraRouteTask].(string); task != string(r.taskID) {
+		return r, "", nil, false
+	}
+	start, _ = st.Extra[api.ExtraRouteNode].(string)
+	index, _ := st.Extra[api.ExtraRouteIndex].(float64)
+	for i := max(int(index)+1, 0); i < len(r.steps); i++ {
+		if r.steps[i].Stop {
+			stops = append(stops, r.steps[i].NodeID)
+		}
+	}
+	_, known := m.Node(start)
+	entering := start == "" && index < 0
+	return r, start, stops, (known || entering) && len(stops) > 0
+}
+
+// aheadLocked returns the nodes the robots' zone routes still take them through. Caller holds c.mu.
+func (c *Controller) aheadLocked(robots ...api.RobotID) map[string]bool {
+	ahead := make(map[string]bool)
+	for _, id := range robots {
+		r, st := c.routes[id], c.robotStatus[id]
+		if r == nil || st == nil {
+			continue
+		}
+		from := 0
+		if task, _ := st.Extra[api.ExtraRouteTask].(string); task == string(r.taskID) {
+			index, _ := st.Extra[api.ExtraRouteIndex].(float64)
+			from = min(max(int(index)+1, 0), len(r.steps))
+		}
+		for _, s := range r.steps[from:] {
+			ahead[s.NodeID] = true
+		}
+	}
+	return ahead
+}
+
+// sideStepLocked plans a move from start to the nearest free node, clearing start in time for others'
+// bookings of it. It prefers nodes off keepClear, e.g. the routes of the robots it makes way for. Caller
+// holds c.mu.
+func (c *Controller) sideStepLocked(m *facility.Map, robotID api.RobotID, start string, now time.Time, avoid, keepClear map[string]bool) ([]facility.Step, error) {
+	err := fmt.Errorf("no free node next to %s", start)
+	for _, to := range asideNodes(m, start, avoid, keepClear) {
+		var side []facility.Step
+		if side, err = c.planLocked(m, robotID, start, now, []string{to}, avoid, true, 0); err == nil {
+			return side, nil
+		}
+	}
+	return nil, err
+}
+
+// asideNodes returns where a robot at start can get out of the way: the free nodes it reaches through free
+// nodes that are off keepClear, nearest first, then its free neighbours on keepClear.
+func asideNodes(m *facility.Map, start string, avoid, keepClear map[string]bool) []string {
+	var out []string
+	seen := map[string]bool{start: true}
+	for queue := []string{start}; len(queue) > 0; queue = queue[1:] {
+		for _, l := range m.Links(queue[0]) {
+			if seen[l.To] || avoid[l.To] {
+				continue
+			}
+			seen[l.To] = true
+			queue = append(queue, l.To)
+			if !keepClear[l.To] {
+				out = append(out, l.To)
+			}
+		}
+	}
+	for _, l := range m.Links(start) {
+		if !avoid[l.To] && keepClear[l.To] {
+			out = append(out, l.To)
+		}
+	}
+	return out
+}
+
+// continueLocked extends a side step with a route from the side node through stops. Caller holds c.mu.
+func (c *Controller) continueLocked(m *facility.Map, robotID api.RobotID, side []facility.Step, stops []string, avoid map[string]bool) ([]facility.Step, error) {
+	last := side[len(side)-1]
+	rest, err := c.planLocked(m, robotID, last.NodeID, last.Arrive, stops, avoid, true, c.traffic.Dwell)
+	if err != nil {
+		rest, err = c.planLocked(m, robotID, last.NodeID, last.Arrive, stops, avoid, false, c.traffic.Dwell)
+	}
+	if err != nil {
+		return nil, err
+	}
+	steps := append([]facility.Step(nil), side...)
+	steps[len(steps)-1].Stop, steps[len(steps)-1].Depart = false, rest[0].Depart
+	return append(steps, rest[1:]...), nil
+}
+
+func sameNodes(a, b []facility.Step) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].NodeID != b[i].NodeID || a[i].Stop != b[i].Stop {
+			return false
+		}
+	}
+	return true
+}
+
+// avoidNodes returns the nodes held by robots other than robotID and the movable ones, except stops.
+func avoidNodes(holder map[string]api.RobotID, robotID api.RobotID, movable map[api.RobotID]bool, stops []string) map[string]bool {
+	avoid := make(map[string]bool)
+	for n, h := range holder {
+		if h != robotID && !movable[h] {
+			avoid[n] = true
+		}
+	}
+	for _, s := range stops {
+		delete(avoid, s)
+	}
+	return avoid
+}
+
+// moveAsideLocked sends an idle robot standing in another robot's way to the nearest free node, preferring
+// one off the waiting robot's route (see asideNodes). Caller holds c.mu.
+func (c *Controller) moveAsideLocked(m *facility.Map, robotID, waiting api.RobotID, now time.Time, holder map[string]api.RobotID) *api.RobotCommand {
+	st := c.robotStatus[robotID]
+	if now.Sub(c.trafficActed[robotID]) < c.traffic.StallTimeout {
+		return nil
+	}
+	start, _ := st.Extra[api.ExtraRouteNode].(string)
+	onRoute := make(map[string]bool)
+	if r := c.routes[waiting]; r != nil {
+		for _, s := range r.steps {
+			onRoute[s.NodeID] = true
+		}
+	}
+	avoid := make(map[string]bool)
+	for n, h := range holder {
+		if h != robotID {
+			avoid[n] = true
+		}
+	}
+	for _, to := range asideNodes(m, start, avoid, onRoute) {
+		steps, err := c.planLocked(m, robotID, start, now, []string{to}, avoid, false, 0)
+		if err != nil {
+			continue
+		}
+		id := api.TaskID(fmt.Sprintf("move-%s-%d", robotID, now.UnixNano()))
+		c.trafficActed[robotID] = now
+		c.reservations.Reserve(string(robotID), steps, c.traffic.Clearance, c.traffic.Park)
+		c.routes[robotID] = &activeRoute{taskID: id, steps: steps, planned: now}
+		log.Printf("zone %s: robot %s moves from %s to %s for robot %s", c.zoneID, robotID, start, to, waiting)
+		return c.routeCommand(m, robotID, id, api.RobotCommandTypeMove, steps)
+	}
+	return nil
+}
+
+func (c *Controller) routeCommand(m *facility.Map, robotID api.RobotID, id api.TaskID, cmdType string, steps []facility.Step) *api.RobotCommand {
+	payload, _ := withRoute(m, nil, steps)
+	return &api.RobotCommand{
+		ID:        id,
+		RobotID:   robotID,
+		Type:      cmdType,
+		Payload:   payload,
+		CreatedAt: c.clock.Now().UTC(),
+	}
+}
+
+// waitCycle returns the robots on a cycle of waits through start, or nil if start is not on one.
+func waitCycle(blockedBy map[api.RobotID]api.RobotID, start api.RobotID) []api.RobotID {
+	cycle := []api.RobotID{start}
+	seen := map[api.RobotID]bool{start: true}
+	for r := blockedBy[start]; r != ""; r = blockedBy[r] {
+		if r == start {
+			return cycle
+		}
+		if seen[r] {
+			return nil
+		}
+		seen[r] = true
+		cycle = append(cycle, r)
+	}
+	return nil
+}
+
+func appendCmd(cmds []*api.RobotCommand, cmd *api.RobotCommand) []*api.RobotCommand {
+	if cmd == nil {
+		return cmds
+	}
+	return append(cmds, cmd)
+}
+
+// withRoute adds steps to a JSON object payload as an api.TaskRoute under "route".
+func withRoute(m *facility.Map, payload []byte, steps []facility.Step) ([]byte, error) {
+	fields := make(map[string]json.RawMessage)
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &fields); err != nil {
+			return nil, err
+		}
+	}
+	route := api.TaskRoute{Waypoints: make([]api.RouteWaypoint, len(steps))}
+	for i, s := range steps {
+		n, _ := m.Node(s.NodeID)
+		route.Waypoints[i] = api.RouteWaypoint{
+			NodeID:   s.NodeID,
+			X:        n.X,
+			Y:        n.Y,
+			Arrive:   s.Arrive.UTC(),
+			Depart:   s.Depart.UTC(),
+			MaxSpeed: s.MaxSpeed,
+			Stop:     s.Stop,
+		}
+	}
+	data, err := json.Marshal(route)
+	if err != nil {
+		return nil, err
+	}
+	fields["route"] = data
+	return json.Marshal(fields)
+}
+
+// stopNode returns the node for a task endpoint: the node itself, else the node bound to the WMS location,
+// else the node nearest to the point.
+func stopNode(m *facility.Map, p *api.Point, nodeID, locationID string) (string, bool) {
+	if _, ok := m.Node(nodeID); ok && nodeID != "" {
+		return nodeID, true
+	}
+	if n, ok := m.NodeForLocation(locationID); ok && locationID != "" {
+		return n.ID, true
+	}
+	if p != nil {
+		if n, _, ok := m.Nearest(*p); ok {
+			return n.ID, true
+		}
+	}
+	return "", false
+}
+
+// robotNode returns the node a robot holds, else the node nearest to its position that is not in held:
+// where a robot off the map enters it.
+func robotNode(m *facility.Map, st *api.RobotStatus, held map[string]bool) (string, bool) {
+	if n, _ := st.Extra[api.ExtraRouteNode].(string); n != "" {
+		if _, ok := m.Node(n); ok {
+			return n, true
+		}
+	}
+	pos, ok := api.ParsePoint(st.Position)
+	if !ok {
+		return "", false
+	}
+	best, bestD := "", math.Inf(1)
+	for _, n := range m.Nodes {
+		if d := math.Hypot(n.X-pos.X, n.Y-pos.Y); !held[n.ID] && d < bestD {
+			best, bestD = n.ID, d
+		}
+	}
+	return best, best != ""
+}
@@ -0,0 +1,164 @@
+package zone
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/internal/edge"
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/clock"
+	"github.com/robotfleetos/robotfleetos/pkg/facility"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+// TestTrafficNarrowMaps runs simulated robots against the zone on a virtual clock. Every robot's task
+// crosses the others' on maps with no room for robots to pass except where noted, and every task must end
+// with its robot on its drop node.
+func TestTrafficNarrowMaps(t *testing.T) {
+	corridor := []facility.Node{node("W", 0, 0), node("A", 10, 0), node("B", 20, 0), node("E", 30, 0)}
+	corridorEdges := []facility.Edge{{From: "W", To: "A"}, {From: "A", To: "B"}, {From: "B", To: "E"}}
+	var ring []facility.Node
+	var ringEdges []facility.Edge
+	for i := 0; i < 6; i++ {
+		ring = append(ring, node(fmt.Sprintf("N%d", i), float64(20*(i%3)), float64(20*(i/3))))
+	}
+	for _, e := range [][2]int{{0, 1}, {1, 2}, {2, 5}, {5, 4}, {4, 3}, {3, 0}} {
+		ringEdges = append(ringEdges, facility.Edge{From: ring[e[0]].ID, To: ring[e[1]].ID})
+	}
+
+	for _, tc := range []struct {
+		name      string
+		nodes     []facility.Node
+		edges     []facility.Edge
+		maxRobots int
+		starts    []api.Point
+		tasks     [][2]string // pick, drop
+	}{
+		{
+			name:      "head-on with a passing bay",
+			nodes:     append(corridor[:len(corridor):len(corridor)], node("P", 20, 6)),
+			edges:     append(corridorEdges[:len(corridorEdges):len(corridorEdges)], facility.Edge{From: "B", To: "P"}),
+			maxRobots: 2,
+			starts:    []api.Point{{X: -5, Y: 0}, {X: 35, Y: 0}},
+			tasks:     [][2]string{{"W", "E"}, {"E", "W"}},
+		},
+		{
+			name:      "ring both ways",
+			nodes:     ring,
+			edges:     ringEdges,
+			maxRobots: 4,
+			starts:    []api.Point{{X: -5, Y: 0}, {X: 45, Y: 0}, {X: 20, Y: 25}, {X: -5, Y: 20}},
+			tasks:     [][2]string{{"N0", "N5"}, {"N2", "N3"}, {"N4", "N1"}, {"N3", "N2"}},
+		},
+		{
+			name:   "corridor held to one robot",
+			nodes:  corridor,
+			edges:  corridorEdges,
+			starts: []api.Point{{X: -5, Y: 0}, {X: 35, Y: 0}, {X: 10, Y: -5}},
+			tasks:  [][2]string{{"W", "E"}, {"E", "W"}, {"A", "W"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &facility.Map{ID: "test", Nodes: tc.nodes, Edges: tc.edges}
+			if err := m.Build(); err != nil {
+				t.Fatal(err)
+			}
+			policy := DefaultTrafficPolicy()
+			policy.MaxRobots = tc.maxRobots
+			last, results := runTraffic(t, m, policy, tc.starts, tc.tasks)
+			for i, task := range tc.tasks {
+				id := api.TaskID(fmt.Sprintf("task-%d", i+1))
+				res, ok := results[id]
+				if !ok || res.Status != api.TaskResultSucceeded {
+					t.Errorf("%s %s->%s: result %+v", id, task[0], task[1], res)
+					continue
+				}
+				if st := last[res.RobotID]; st.Extra[api.ExtraRouteTask] == string(id) && st.Extra[api.ExtraRouteNode] != task[1] {
+					t.Errorf("%s: robot %s ended on %v, want %s", id, res.RobotID, st.Extra[api.ExtraRouteNode], task[1])
+				}
+			}
+		})
+	}
+}
+
+func node(id string, x, y float64) facility.Node {
+	return facility.Node{ID: id, X: x, Y: y, Type: "location", LocationID: id}
+}
+
+// runTraffic starts one robot per start point, submits the tasks once the zone has heard from every robot
+// and runs the virtual clock until every task has a result or an hour has passed. It returns each robot's
+// last status and the results by task.
+func runTraffic(t *testing.T, m *facility.Map, policy TrafficPolicy, starts []api.Point, tasks [][2]string) (map[api.RobotID]api.RobotStatus, map[api.TaskID]api.TaskResult) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clk := clock.NewVirtual(time.Date(2025, 1, 6, 6, 0, 0, 0, time.UTC))
+	bus := messaging.NewMemoryBus()
+	robots := make([]api.RobotID, len(starts))
+	for i := range robots {
+		robots[i] = api.RobotID(fmt.Sprintf("robot-%d", i+1))
+	}
+
+	last := make(map[api.RobotID]api.RobotStatus)
+	results := make(map[api.TaskID]api.TaskResult)
+	if err := bus.Subscribe(ctx, messaging.TopicRobotStatus, func(_ string, value []byte) error {
+		var st api.RobotStatus
+		if err := json.Unmarshal(value, &st); err != nil {
+			return err
+		}
+		last[st.RobotID] = st
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Subscribe(ctx, messaging.TopicTaskResults, func(_ string, value []byte) error {
+		var res api.TaskResult
+		if err := json.Unmarshal(value, &res); err != nil {
+			return err
+		}
+		results[res.TaskID] = res
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := NewController("zone-1", robots, messaging.NewRobotCommandPublisher(bus), messaging.NewZoneSummaryPublisher(bus), bus, 5*time.Second)
+	ctrl.SetClock(clk)
+	ctrl.SetFacilityMap(m)
+	ctrl.SetTrafficPolicy(policy)
+	sim := edge.NewSimulator("zone-1", robots, messaging.NewRobotStatusPublisher(bus), bus, time.Second, time.Minute)
+	cfg := edge.DefaultSimConfig()
+	cfg.Jitter = 0
+	cfg.Chargers = starts
+	sim.SetSimConfig(cfg)
+	sim.SetTaskResultPublisher(messaging.NewTaskResultPublisher(bus))
+	sim.SetClock(clk)
+	for _, start := range []func(context.Context) error{ctrl.Start, sim.Start} {
+		if err := start(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clk.RunFor(5 * time.Second)
+	if len(last) != len(robots) {
+		t.Fatalf("status from %d of %d robots", len(last), len(robots))
+	}
+	pub := messaging.NewZoneTaskPublisher(bus)
+	for i, task := range tasks {
+		from, _ := m.Node(task[0])
+		to, _ := m.Node(task[1])
+		fromPt, toPt := from.Point(), to.Point()
+		payload, _ := json.Marshal(api.TaskLocations{FromNodeID: from.ID, ToNodeID: to.ID, From: &fromPt, To: &toPt})
+		zt := &api.ZoneTask{ID: api.TaskID(fmt.Sprintf("task-%d", i+1)), ZoneID: "zone-1", Payload: payload, CreatedAt: clk.Now()}
+		if err := pub.PublishZoneTask(ctx, zt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for end := clk.Now().Add(time.Hour); len(results) < len(tasks) && clk.Now().Before(end); {
+		clk.RunFor(10 * time.Second)
+	}
+	return last, results
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Capabilities describe what robots of a model can do. Zero values mean the model cannot do it.
+type Capabilities struct {
+	PayloadKg   float64  `json:"payload_kg,omitempty" yaml:"payload_kg"`
+	LiftHeightM float64  `json:"lift_height_m,omitempty" yaml:"lift_height_m"`
+	Gripper     string   `json:"gripper,omitempty" yaml:"gripper"` // e.g. "vacuum", "parallel", "fork"
+	Tags        []string `json:"tags,omitempty" yaml:"tags"`       // anything else tasks may ask for, e.g. "cold_store"
+}
+
+// Requirements are what a work order or task needs from the robot that does it. A nil or empty value is
+// met by every robot.
+type Requirements struct {
+	PayloadKg   float64  `json:"payload_kg,omitempty"`    // at least
+	LiftHeightM float64  `json:"lift_height_m,omitempty"` // at least
+	Gripper     string   `json:"gripper,omitempty"`       // exactly
+	Tags        []string `json:"tags,omitempty"`          // all of
+	ModelIDs    []string `json:"model_ids,omitempty"`     // any of; empty = any model
+}
+
+// IsZero reports whether r asks for nothing.
+func (r *Requirements) IsZero() bool {
+	return r == nil || (r.PayloadKg == 0 && r.LiftHeightM == 0 && r.Gripper == "" && len(r.Tags) == 0 && len(r.ModelIDs) == 0)
+}
+
+// Validate rejects negative amounts.
+func (r *Requirements) Validate() error {
+	if r == nil {
+		return nil
+	}
+	if r.PayloadKg < 0 || r.LiftHeightM < 0 {
+		return fmt.Errorf("requirements: payload_kg and lift_height_m must not be negative")
+	}
+	return nil
+}
+
+// Unmet returns why a robot of modelID with capabilities c cannot meet r, or "" if it can.
+func (r *Requirements) Unmet(modelID string, c Capabilities) string {
+	if r.IsZero() {
+		return ""
+	}
+	var missing []string
+	if len(r.ModelIDs) > 0 && !contains(r.ModelIDs, modelID) {
+		missing = append(missing, "model "+strings.Join(r.ModelIDs, "|"))
+	}
+	if r.PayloadKg > c.PayloadKg {
+		missing = append(missing, fmt.Sprintf("payload %gkg > %gkg", r.PayloadKg, c.PayloadKg))
+	}
+	if r.LiftHeightM > c.LiftHeightM {
+		missing = append(missing, fmt.Sprintf("lift %gm > %gm", r.LiftHeightM, c.LiftHeightM))
+	}
+	if r.Gripper != "" && r.Gripper != c.Gripper {
+		missing = append(missing, "gripper "+r.Gripper)
+	}
+	for _, t := range r.Tags {
+		if !contains(c.Tags, t) {
+			missing = append(missing, "tag "+t)
+		}
+	}
+	return strings.Join(missing, ", ")
+}
+
+// ModelCount is how many robots of a model a zone or area has, with the model's capabilities.
+type ModelCount struct {
+	ModelID      string       `json:"model_id"`
+	Robots       int          `json:"robots"`
+	Capabilities Capabilities `json:"capabilities"`
+}
+
+// CanServe reports whether any of models meets r.
+func CanServe(models []ModelCount, r *Requirements) bool {
+	for _, m := range models {
+		if m.Robots > 0 && r.Unmet(m.ModelID, m.Capabilities) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeModelCounts adds the robots of b to a by model, keeping the result sorted by model ID.
+func MergeModelCounts(a, b []ModelCount) []ModelCount {
+	for _, m := range b {
+		found := false
+		for i := range a {
+			if a[i].ModelID == m.ModelID {
+				a[i].Robots += m.Robots
+				found = true
+				break
+			}
+		}
+		if !found {
+			a = append(a, m)
+		}
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i].ModelID < a[j].ModelID })
+	return a
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+package api
+
+// RobotCommandTypeCharge sends a robot to a charger. The payload is a ChargePayload. The robot reports
+// CHARGING on the way and on the charger, and IDLE once it reaches Until.
+const RobotCommandTypeCharge = "CHARGE"
+
+// ChargePayload is the payload of a CHARGE command.
+type ChargePayload struct {
+	ChargerID     string  `json:"charger_id"`
+	NodeID        string  `json:"node_id,omitempty"` // facility map node of the charger, if it has one
+	Position      Point   `json:"position"`
+	Until         float64 `json:"until"`                   // battery % at which charging ends
+	Interruptible bool    `json:"interruptible,omitempty"` // opportunistic charge: a new task ends it early
+}
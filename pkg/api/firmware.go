@@ -14,17 +14,30 @@ const (
 const (
 	ExtraModelID             = "model_id"               // e.g. "picker-v2", "agv-x1"
 	ExtraFirmwareVersion     = "firmware_version"      // e.g. "2.1.0"
-	ExtraFirmwareUpdateStatus = "firmware_update_status" // idle | downloading | applying | success | failed | rollback
+	ExtraFirmwareUpdateStatus = "firmware_update_status" // idle | deferred | downloading | applying | success | failed | rollback | skipped
+	ExtraFirmwareDeferReason  = "firmware_defer_reason"  // set while deferred: busy | outside_window | low_battery | not_charging | safety_stop
+	ExtraFirmwareCommandID    = "firmware_command_id"    // FIRMWARE_UPDATE command the update status belongs to
 )
 
 // FirmwareUpdateStatus values for ExtraFirmwareUpdateStatus.
 const (
 	FirmwareStatusIdle        = "idle"
+	FirmwareStatusDeferred    = "deferred"
 	FirmwareStatusDownloading = "downloading"
 	FirmwareStatusApplying     = "applying"
 	FirmwareStatusSuccess      = "success"
 	FirmwareStatusFailed       = "failed"
 	FirmwareStatusRollback     = "rollback"
+	FirmwareStatusSkipped      = "skipped" // the update is for another model
+)
+
+// Firmware defer reasons for ExtraFirmwareDeferReason.
+const (
+	FirmwareDeferBusy          = "busy"
+	FirmwareDeferOutsideWindow = "outside_window"
+	FirmwareDeferLowBattery    = "low_battery"
+	FirmwareDeferNotCharging   = "not_charging"
+	FirmwareDeferSafetyStop    = "safety_stop" // robot is in ESTOP or PROTECTIVE_STOP
 )
 
 // FirmwareUpdatePayload is the JSON payload for RobotCommand type FIRMWARE_UPDATE.
@@ -37,7 +50,26 @@ type FirmwareUpdatePayload struct {
 	ChecksumSHA256  string `json:"checksum_sha256"`
 	RollbackVersion string `json:"rollback_version,omitempty"`
 	RollbackURL     string `json:"rollback_url,omitempty"`
-	Deadline        string `json:"deadline,omitempty"` // RFC3339
+	Deadline        string          `json:"deadline,omitempty"` // RFC3339; after this, policy constraints no longer defer the update
+	Policy          *FirmwarePolicy `json:"policy,omitempty"`
+}
+
+// FirmwareMaintenanceWindow is a recurring period during which firmware may be applied.
+// Cron gives the opening times (5-field, e.g. "0 22 * * 1-5"); Duration how long each stays open (e.g. "6h").
+type FirmwareMaintenanceWindow struct {
+	Cron     string `json:"cron"`
+	Duration string `json:"duration"`
+	TZ       string `json:"tz,omitempty"` // IANA name; empty = edge local time
+}
+
+// FirmwarePolicy constrains when a robot may apply firmware. Set per campaign (FirmwareUpdatePayload.Policy)
+// and merged with zone-level settings before the zone dispatches FIRMWARE_UPDATE commands.
+type FirmwarePolicy struct {
+	MaintenanceWindows     []FirmwareMaintenanceWindow `json:"maintenance_windows,omitempty"`      // campaign windows; empty = any time
+	ZoneMaintenanceWindows []FirmwareMaintenanceWindow `json:"zone_maintenance_windows,omitempty"` // set by the zone; must also be open
+	MinBattery             float64                     `json:"min_battery,omitempty"`              // 0-100; defer below this
+	ChargingOnly           bool                        `json:"charging_only,omitempty"`            // only apply while CHARGING
+	HoldbackPercent        int                         `json:"holdback_percent,omitempty"`         // zone keeps this % of robots out of the update at any time
 }
 
 // FirmwareRollbackPayload is the JSON payload for RobotCommand type FIRMWARE_ROLLBACK.
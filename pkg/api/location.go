@@ -0,0 +1,54 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Point is a floor coordinate in metres. RobotStatus.Position carries it as "x,y".
+type Point struct {
+	X float64 `json:"x" yaml:"x"`
+	Y float64 `json:"y" yaml:"y"`
+}
+
+// String formats p the way RobotStatus.Position is reported.
+func (p Point) String() string {
+	return fmt.Sprintf("%.1f,%.1f", p.X, p.Y)
+}
+
+// ParsePoint parses an "x,y" position.
+func ParsePoint(s string) (Point, bool) {
+	xs, ys, ok := strings.Cut(s, ",")
+	if !ok {
+		return Point{}, false
+	}
+	x, errX := strconv.ParseFloat(strings.TrimSpace(xs), 64)
+	y, errY := strconv.ParseFloat(strings.TrimSpace(ys), 64)
+	if errX != nil || errY != nil {
+		return Point{}, false
+	}
+	return Point{X: x, Y: y}, true
+}
+
+// TaskLocations are the location fields of a transport payload (WMS pick/putaway/move) as they travel
+// WorkOrder → ZoneTask → RobotCommand. Coordinates and facility map nodes are filled in by the WMS when its
+// locations have them.
+type TaskLocations struct {
+	FromLocationID string `json:"from_location_id,omitempty"`
+	ToLocationID   string `json:"to_location_id,omitempty"`
+	FromNodeID     string `json:"from_node_id,omitempty"`
+	ToNodeID       string `json:"to_node_id,omitempty"`
+	From           *Point `json:"from,omitempty"`
+	To             *Point `json:"to,omitempty"`
+}
+
+// RobotStateCharging is RobotStatus.State while a robot drives to or sits on a charger.
+const RobotStateCharging = "CHARGING"
+
+// RobotStatus Extra keys reported by the simulator for capacity planning.
+const (
+	ExtraOdometerM      = "odometer_m"      // metres driven since start
+	ExtraTasksCompleted = "tasks_completed" // tasks finished since start
+	ExtraTaskQueue      = "task_queue"      // tasks waiting behind the current one
+)
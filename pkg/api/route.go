@@ -0,0 +1,44 @@
+package api
+
+import "time"
+
+// Route commands. The payload carries a TaskRoute under "route".
+const (
+	// RobotCommandTypeReroute replaces the remaining route of the robot's current task (command ID = task ID).
+	// The zone sends it to break deadlocks.
+	RobotCommandTypeReroute = "REROUTE"
+	// RobotCommandTypeMove drives an idle robot along a route without a task, e.g. off a node another robot needs.
+	RobotCommandTypeMove = "MOVE"
+)
+
+// TaskRoute is a zone-planned route through the facility graph, carried in TASK, MOVE and REROUTE payloads under
+// "route" next to the task's own fields. Robots drive waypoint to waypoint and hold each node until they
+// reach the next, so two robots never share a node or pass each other on a segment.
+type TaskRoute struct {
+	Waypoints []RouteWaypoint `json:"waypoints"`
+}
+
+// RouteWaypoint is one node of a route with the time window the zone reserved for it. A robot may arrive
+// late but does not leave before Depart.
+type RouteWaypoint struct {
+	NodeID   string    `json:"node_id"`
+	X        float64   `json:"x"`
+	Y        float64   `json:"y"`
+	Arrive   time.Time `json:"arrive"`
+	Depart   time.Time `json:"depart"`
+	MaxSpeed float64   `json:"max_speed,omitempty"` // m/s on the segment into this waypoint; 0 = no limit
+	Stop     bool      `json:"stop,omitempty"`      // pick or drop: the robot handles the load here
+}
+
+// Point returns the waypoint's coordinates.
+func (w RouteWaypoint) Point() Point { return Point{X: w.X, Y: w.Y} }
+
+// RobotStatus Extra keys reported by robots that follow zone routes.
+const (
+	ExtraSpeedMPS    = "speed_mps"    // nominal travel speed, used by the zone for planning
+	ExtraRouteTask   = "route_task"   // task of the route being (or last) followed
+	ExtraRouteNode   = "route_node"   // node the robot holds: where it stands, or the last one it reached
+	ExtraRouteIndex  = "route_index"  // index of the last waypoint reached
+	ExtraWaitingFor  = "waiting_for"  // node held by another robot that blocks the next move
+	ExtraWaitingSecs = "waiting_secs" // how long the robot has been blocked
+)
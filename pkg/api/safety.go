@@ -0,0 +1,79 @@
+package api
+
+import "time"
+
+// RobotCommandType values for edge-local safety. These are always accepted by the edge, also while stopped.
+// A command with empty RobotID and ZoneID set is a zone-wide broadcast; only safety commands may be broadcast.
+const (
+	RobotCommandTypeEStop          = "ESTOP"           // emergency stop; requires RESET
+	RobotCommandTypeProtectiveStop = "PROTECTIVE_STOP" // controlled stop (e.g. person in zone); requires RESET
+	RobotCommandTypeStop           = "STOP"            // alias for PROTECTIVE_STOP
+	RobotCommandTypeReset          = "RESET"           // clear ESTOP / PROTECTIVE_STOP back to NORMAL
+)
+
+// IsSafetyCommand reports whether t is a safety command type.
+func IsSafetyCommand(t string) bool {
+	switch t {
+	case RobotCommandTypeEStop, RobotCommandTypeProtectiveStop, RobotCommandTypeStop, RobotCommandTypeReset:
+		return true
+	}
+	return false
+}
+
+// Safety states reported in RobotStatus.Extra[ExtraSafetyState]. While not NORMAL, RobotStatus.State is RobotStateStopped.
+const (
+	SafetyStateNormal         = "NORMAL"
+	SafetyStateProtectiveStop = "PROTECTIVE_STOP"
+	SafetyStateEStop          = "ESTOP"
+)
+
+// RobotStateStopped is RobotStatus.State while the robot is in ESTOP or PROTECTIVE_STOP.
+const RobotStateStopped = "STOPPED"
+
+// RobotStatus Extra keys for safety.
+const (
+	ExtraSafetyState  = "safety_state"  // NORMAL | PROTECTIVE_STOP | ESTOP
+	ExtraSafetyReason = "safety_reason" // set while stopped
+)
+
+// SafetyPayload is the optional JSON payload of safety commands.
+type SafetyPayload struct {
+	Reason   string `json:"reason,omitempty"`
+	Operator string `json:"operator,omitempty"`
+}
+
+// Zone task payload types for zone-wide safety broadcasts ({"type":"stop_all","reason":"..."}).
+// The zone turns them into a broadcast ESTOP / RESET for every robot; each edge enforces it locally.
+const (
+	ZoneTaskTypeStopAll  = "stop_all"
+	ZoneTaskTypeResetAll = "reset_all"
+)
+
+// SafetyEvent records one safety state transition, or a command rejected because the robot is stopped.
+// Published by the edge on the safety events topic for auditing.
+type SafetyEvent struct {
+	RobotID   RobotID   `json:"robot_id"`
+	ZoneID    ZoneID    `json:"zone_id"`
+	Action    string    `json:"action"` // transition | rejected
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Command   string    `json:"command,omitempty"` // command type that caused it
+	CommandID TaskID    `json:"command_id,omitempty"`
+	Source    string    `json:"source"` // command | zone_broadcast | robot
+	Reason    string    `json:"reason,omitempty"`
+	Operator  string    `json:"operator,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// SafetyEvent actions.
+const (
+	SafetyActionTransition = "transition"
+	SafetyActionRejected   = "rejected"
+)
+
+// SafetyEvent sources.
+const (
+	SafetySourceCommand       = "command"
+	SafetySourceZoneBroadcast = "zone_broadcast"
+	SafetySourceRobot         = "robot"
+)
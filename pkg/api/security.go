@@ -0,0 +1,28 @@
+package api
+
+import "time"
+
+// SecurityEvent records a robot command the edge did not trust: unsigned, signed by an unknown key or a key
+// not valid for the zone, tampered with, too old or replayed. Published by the edge on the security events
+// topic. Stop commands still run when they fail verification (Executed); everything else is dropped.
+type SecurityEvent struct {
+	RobotID   RobotID   `json:"robot_id,omitempty"` // empty for a zone-wide broadcast
+	ZoneID    ZoneID    `json:"zone_id"`
+	CommandID TaskID    `json:"command_id"`
+	Command   string    `json:"command"` // command type
+	KeyID     string    `json:"key_id,omitempty"`
+	Reason    string    `json:"reason"` // see SecurityReason*
+	Detail    string    `json:"detail,omitempty"`
+	Executed  bool      `json:"executed"` // a stop run despite failing verification
+	At        time.Time `json:"at"`
+}
+
+// SecurityEvent reasons.
+const (
+	SecurityReasonUnsigned     = "unsigned"
+	SecurityReasonUnknownKey   = "unknown_key"
+	SecurityReasonBadSignature = "bad_signature"
+	SecurityReasonWrongZone    = "wrong_zone" // key not valid for the command's zone, or command for another zone
+	SecurityReasonStale        = "stale"      // created_at outside the freshness window
+	SecurityReasonReplay       = "replay"     // same command seen before
+)
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Robot command types of task graph steps. A step may also use TASK or any type its robots understand.
+const (
+	RobotCommandTypeGoTo  = "GOTO"  // drive to the payload's "to" location without handling a load
+	RobotCommandTypePick  = "PICK"  // pick up the load where the robot stands; the payload names it and its "location_id"
+	RobotCommandTypePlace = "PLACE" // put down the load where the robot stands
+)
+
+// TaskStep is one step of a work order's task graph. A step starts once every step in After has succeeded;
+// steps without After start at once, so independent steps run in parallel. The area sends each step to a
+// zone as a ZoneTask and the zone to a robot as a RobotCommand of the step's Type.
+type TaskStep struct {
+	ID           string          `json:"id"`
+	Type         string          `json:"type"` // robot command type, e.g. GOTO, PICK, PLACE, TASK
+	Payload      json.RawMessage `json:"payload,omitempty"`
+	After        []string        `json:"after,omitempty"`
+	SameRobot    string          `json:"same_robot,omitempty"`   // run on the robot that did this earlier step, e.g. place after pick
+	ZoneID       ZoneID          `json:"zone_id,omitempty"`      // default: the SameRobot step's zone, else any capable zone
+	Requirements *Requirements   `json:"requirements,omitempty"` // default: the work order's
+	Retries      int             `json:"retries,omitempty"`      // extra attempts after a failure
+	Compensate   *TaskStep       `json:"compensate,omitempty"`   // undoes this step if the graph fails after it succeeded
+}
+
+// ValidateSteps checks a task graph: unique step IDs, a type per step, After and SameRobot naming steps of
+// the graph, SameRobot among the step's (transitive) predecessors, no cycles and no negative retries.
+func ValidateSteps(steps []TaskStep) error {
+	byID := make(map[string]*TaskStep, len(steps))
+	for i := range steps {
+		s := &steps[i]
+		if s.ID == "" {
+			return fmt.Errorf("steps[%d]: id required", i)
+		}
+		if _, dup := byID[s.ID]; dup {
+			return fmt.Errorf("step %s: duplicate id", s.ID)
+		}
+		if s.Type == "" {
+			return fmt.Errorf("step %s: type required", s.ID)
+		}
+		if !stepType(s.Type) {
+			return fmt.Errorf("step %s: %s cannot be a step", s.ID, s.Type)
+		}
+		if s.Retries < 0 {
+			return fmt.Errorf("step %s: retries must not be negative", s.ID)
+		}
+		if err := s.Requirements.Validate(); err != nil {
+			return fmt.Errorf("step %s: %w", s.ID, err)
+		}
+		if s.Compensate != nil && !stepType(s.Compensate.Type) {
+			return fmt.Errorf("step %s: compensate: type %q cannot be a step", s.ID, s.Compensate.Type)
+		}
+		byID[s.ID] = s
+	}
+	for _, s := range steps {
+		for _, dep := range s.After {
+			if byID[dep] == nil {
+				return fmt.Errorf("step %s: after unknown step %q", s.ID, dep)
+			}
+		}
+	}
+	// Depth-first search for cycles, collecting each step's predecessors on the way.
+	const (
+		visiting = 1
+		done     = 2
+	)
+	mark := make(map[string]int, len(steps))
+	preds := make(map[string]map[string]bool, len(steps))
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch mark[id] {
+		case visiting:
+			return fmt.Errorf("step %s: cycle in after", id)
+		case done:
+			return nil
+		}
+		mark[id] = visiting
+		p := make(map[string]bool)
+		for _, dep := range byID[id].After {
+			if err := visit(dep); err != nil {
+				return err
+			}
+			p[dep] = true
+			for q := range preds[dep] {
+				p[q] = true
+			}
+		}
+		preds[id] = p
+		mark[id] = done
+		return nil
+	}
+	for _, s := range steps {
+		if err := visit(s.ID); err != nil {
+			return err
+		}
+	}
+	for _, s := range steps {
+		if s.SameRobot != "" && !preds[s.ID][s.SameRobot] {
+			return fmt.Errorf("step %s: same_robot %q must be a step it runs after", s.ID, s.SameRobot)
+		}
+	}
+	return nil
+}
+
+// stepType reports whether robots may be given t as a task graph step: not empty, not a safety or firmware
+// command and not one the zone sends on its own (CHARGE, MOVE, REROUTE).
+func stepType(t string) bool {
+	switch t {
+	case "", RobotCommandTypeFirmwareUpdate, RobotCommandTypeFirmwareRollback, RobotCommandTypeCharge, RobotCommandTypeMove, RobotCommandTypeReroute:
+		return false
+	}
+	return !IsSafetyCommand(t)
+}
+
+// Task result statuses.
+const (
+	TaskResultSucceeded = "succeeded"
+	TaskResultFailed    = "failed"
+)
+
+// TaskResult is how a robot command ended. Edges publish one per task command (keyed by robot); zones
+// forward the results of task graph steps to their area (keyed by zone).
+type TaskResult struct {
+	TaskID  TaskID    `json:"task_id"`
+	RobotID RobotID   `json:"robot_id,omitempty"`
+	ZoneID  ZoneID    `json:"zone_id,omitempty"` // set by the zone when forwarding
+	Status  string    `json:"status"`            // succeeded | failed
+	Error   string    `json:"error,omitempty"`   // why it failed, e.g. "robot error", "safety stop"
+	At      time.Time `json:"at"`
+}
+
+// TaskGraphStats counts an area's multi-step work orders; omitted while the area has run none.
+type TaskGraphStats struct {
+	Running       int `json:"running"`
+	Succeeded     int `json:"succeeded"`     // since start
+	Failed        int `json:"failed"`        // since start, after retries and compensation
+	Retries       int `json:"retries"`       // step attempts repeated after a failure
+	Compensations int `json:"compensations"` // compensating steps run
+}
@@ -26,6 +26,8 @@ type WorkOrder struct {
 	Payload   []byte       `json:"payload"` // JSON or structured; schema defined by fleet
 	CreatedAt time.Time    `json:"created_at"`
 	Deadline  *time.Time   `json:"deadline,omitempty"`
+	Requirements *Requirements `json:"requirements,omitempty"` // only robots meeting these may do the work
+	Steps        []TaskStep    `json:"steps,omitempty"`        // task graph; without steps the order is one zone task
 }
 
 // ZoneTask is a decomposed task assigned to a zone.
@@ -35,6 +37,12 @@ type ZoneTask struct {
 	OrderID   WorkOrderID `json:"order_id"`
 	Payload   []byte      `json:"payload"`
 	CreatedAt time.Time   `json:"created_at"`
+	Requirements *Requirements `json:"requirements,omitempty"` // copied from the work order
+
+	// Set for task graph steps: the zone reports the step's TaskResult to the area.
+	StepID  string  `json:"step_id,omitempty"`
+	Type    string  `json:"type,omitempty"`     // robot command type; default TASK
+	RobotID RobotID `json:"robot_id,omitempty"` // the robot that must do it (SameRobot); default: the zone picks
 }
 
 // RobotCommand is a command sent from zone/edge to a robot (or edge gateway).
@@ -44,6 +52,9 @@ type RobotCommand struct {
 	Type      string    `json:"type"` // e.g. "MOVE", "PICK", "STOP"
 	Payload   []byte    `json:"payload"`
 	CreatedAt time.Time `json:"created_at"`
+	ZoneID    ZoneID    `json:"zone_id,omitempty"` // with empty RobotID: broadcast to every robot in the zone (safety commands only)
+	KeyID     string    `json:"key_id,omitempty"`    // zone key that signed the command (see pkg/cmdsign)
+	Signature []byte    `json:"signature,omitempty"` // Ed25519 signature of cmdsign.SigningInput
 }
 
 // RobotStatus is telemetry/heartbeat from edge to zone.
@@ -56,6 +67,9 @@ type RobotStatus struct {
 	Extra     map[string]interface{} `json:"extra,omitempty"`
 }
 
+// ExtraFault is the RobotStatus.Extra key for a fault the robot reports (e.g. "estop", "battery_fault").
+const ExtraFault = "fault"
+
 // ZoneSummary is aggregated zone state reported to area.
 type ZoneSummary struct {
 	ZoneID     ZoneID    `json:"zone_id"`
@@ -63,6 +77,19 @@ type ZoneSummary struct {
 	Healthy    int       `json:"healthy"`
 	Busy       int       `json:"busy"`
 	UpdatedAt  time.Time `json:"updated_at"`
+
+	// Chargers the zone manages; omitted for zones without chargers.
+	Chargers           int     `json:"chargers,omitempty"`
+	ChargersInUse      int     `json:"chargers_in_use,omitempty"`
+	ChargeQueue        int     `json:"charge_queue,omitempty"`        // robots waiting for a free charger
+	ChargerUtilization float64 `json:"charger_utilization,omitempty"` // share of charger time in use since the previous summary
+
+	// TaskQueue counts tasks held until a robot can be routed to them on the zone's facility map.
+	TaskQueue int `json:"task_queue,omitempty"`
+
+	// Models counts the zone's robots per reported model, with capabilities from the zone's model registry;
+	// omitted for zones without a registry.
+	Models []ModelCount `json:"models,omitempty"`
 }
 
 // AreaSummary is aggregated area state reported to fleet.
@@ -71,4 +98,14 @@ type AreaSummary struct {
 	ZoneCount  int       `json:"zone_count"`
 	RobotCount int       `json:"robot_count"`
 	UpdatedAt  time.Time `json:"updated_at"`
+
+	// Chargers summed over the area's zones; utilization is weighted by each zone's charger count.
+	Chargers           int     `json:"chargers,omitempty"`
+	ChargersInUse      int     `json:"chargers_in_use,omitempty"`
+	ChargeQueue        int     `json:"charge_queue,omitempty"`
+	ChargerUtilization float64 `json:"charger_utilization,omitempty"`
+
+	Models []ModelCount `json:"models,omitempty"` // summed over the area's zones
+
+	TaskGraphs *TaskGraphStats `json:"task_graphs,omitempty"`
 }
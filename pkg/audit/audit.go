@@ -0,0 +1,280 @@
+// Package audit is the append-only, tamper-evident record of what was done through each service's API:
+// who released an order, raised a hold, submitted a maintenance work order or started a firmware campaign,
+// with the entity before and after.
+//
+// Entries are hash-chained: each carries the SHA-256 of its own content and of the entry before it, so
+// editing, inserting or deleting an entry breaks every hash after it. With a path the log is a JSON-lines
+// file that is checked when opened and by cmd/auditverify; without one only the latest entries are kept,
+// in memory.
+package audit
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+)
+
+// Config is a service's "audit" config section.
+type Config struct {
+	Path string `yaml:"path"` // JSON-lines file the log is appended to; empty = in memory; env AUDIT_LOG overrides
+}
+
+// ApplyEnv overrides the path from AUDIT_LOG.
+func (c *Config) ApplyEnv() {
+	if p := os.Getenv("AUDIT_LOG"); p != "" {
+		c.Path = p
+	}
+}
+
+// Entry is one recorded action.
+type Entry struct {
+	Seq       uint64          `json:"seq"`
+	Time      time.Time       `json:"time"`
+	Service   string          `json:"service"`
+	Actor     string          `json:"actor"`           // authenticated subject; ActorAnonymous or ActorSystem otherwise
+	Roles     []string        `json:"roles,omitempty"` // the actor's roles
+	Action    string          `json:"action"`          // e.g. "order.release"
+	Entity    string          `json:"entity"`          // e.g. "order"
+	EntityID  string          `json:"entity_id,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"` // entity before the action; empty on create
+	After     json.RawMessage `json:"after,omitempty"`  // entity after it; empty on delete
+	RequestID string          `json:"request_id,omitempty"`
+	Remote    string          `json:"remote,omitempty"` // client address
+	Prev      string          `json:"prev"`             // hash of the previous entry; empty for the first
+	Hash      string          `json:"hash"`             // SHA-256 (hex) of this entry with Hash empty
+}
+
+// Actors that are not authenticated callers.
+const (
+	ActorAnonymous = "anonymous" // the API has no auth configured
+	ActorSystem    = "system"    // done by the service itself
+)
+
+// RequestIDHeader carries the request ID recorded with an entry; Wrap sets it when the caller did not.
+const RequestIDHeader = "X-Request-ID"
+
+// hash returns the hash of e as stored, i.e. with e.Hash empty.
+func hash(e Entry) (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// tailEntries is how many of the latest entries a Log keeps in memory (up to twice as many between trims).
+// Queries they answer skip the file; a log without a file has only them.
+const tailEntries = 10000
+
+// Log is a service's audit log. Safe for concurrent use; a nil Log records nothing.
+type Log struct {
+	service string
+	path    string
+
+	mu   sync.RWMutex
+	head Head
+	tail []Entry // latest entries, oldest first
+	file *os.File
+}
+
+// Open returns the audit log of service. With cfg.Path set, existing entries are read and their chain
+// verified (a broken chain is an error, so a tampered log is noticed at startup) and new ones are appended.
+func Open(service string, cfg Config) (*Log, error) {
+	l := &Log{service: service, path: cfg.Path}
+	if cfg.Path == "" {
+		return l, nil
+	}
+	if f, err := os.Open(cfg.Path); err == nil {
+		var c chain
+		err := each(f, func(e Entry) error {
+			if err := c.add(e); err != nil {
+				return err
+			}
+			l.keep(e)
+			return nil
+		})
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("audit: %s: %w", cfg.Path, err)
+		}
+		l.head = c.head
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	f, err := os.OpenFile(cfg.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	l.file = f
+	return l, nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// Append chains e to the log and writes it: Seq, Prev and Hash are set here, Time and Service if empty.
+func (l *Log) Append(e Entry) (Entry, error) {
+	if l == nil {
+		return e, nil
+	}
+	if e.Service == "" {
+		e.Service = l.service
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	e.Seq = l.head.Seq + 1
+	e.Prev = l.head.Hash
+	h, err := hash(e)
+	if err != nil {
+		return e, err
+	}
+	e.Hash = h
+	if l.file != nil {
+		data, _ := json.Marshal(e)
+		if _, err := l.file.Write(append(data, '\n')); err != nil {
+			return e, fmt.Errorf("audit: %w", err)
+		}
+		if err := l.file.Sync(); err != nil {
+			return e, fmt.Errorf("audit: %w", err)
+		}
+	}
+	l.head = Head{Seq: e.Seq, Hash: e.Hash}
+	l.keep(e)
+	return e, nil
+}
+
+// keep adds e to the tail, dropping the oldest entries once it holds twice tailEntries.
+func (l *Log) keep(e Entry) {
+	l.tail = append(l.tail, e)
+	if len(l.tail) >= 2*tailEntries {
+		l.tail = append([]Entry(nil), l.tail[len(l.tail)-tailEntries:]...)
+	}
+}
+
+// Record appends an action taken through r, by the caller authenticated on it: action on entity id, with
+// the entity before and after (nil when there is none, e.g. before a create). Failures are logged; the
+// action has already happened.
+func (l *Log) Record(r *http.Request, action, entity, id string, before, after any) {
+	if l == nil {
+		return
+	}
+	e := Entry{
+		Actor:     ActorAnonymous,
+		Action:    action,
+		Entity:    entity,
+		EntityID:  id,
+		Before:    snapshot(before),
+		After:     snapshot(after),
+		RequestID: r.Header.Get(RequestIDHeader),
+		Remote:    r.RemoteAddr,
+	}
+	if p := auth.FromContext(r.Context()); p != nil {
+		e.Actor, e.Roles = p.Subject, p.Roles
+	}
+	if _, err := l.Append(e); err != nil {
+		log.Printf("audit: %s %s %s by %s not recorded: %v", action, entity, id, e.Actor, err)
+	}
+}
+
+// RecordSystem appends an action the service took on its own, e.g. from a background job.
+func (l *Log) RecordSystem(action, entity, id string, before, after any) {
+	if l == nil {
+		return
+	}
+	e := Entry{Actor: ActorSystem, Action: action, Entity: entity, EntityID: id, Before: snapshot(before), After: snapshot(after)}
+	if _, err := l.Append(e); err != nil {
+		log.Printf("audit: %s %s %s not recorded: %v", action, entity, id, err)
+	}
+}
+
+// snapshot serializes v now, so later changes to it are not recorded. Nil pointers are no value.
+func snapshot(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+	return data
+}
+
+// Wrap returns next with a request ID on every request: the caller's X-Request-ID, or a new one, echoed
+// in the response. A nil Log returns next unchanged.
+func (l *Log) Wrap(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			var b [8]byte
+			_, _ = rand.Read(b[:])
+			id = hex.EncodeToString(b[:])
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Read decodes a JSON-lines log.
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	err := each(r, func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// errStop ends each early without an error.
+var errStop = errors.New("stop")
+
+// each decodes a JSON-lines log one entry at a time, calling fn with each until it returns an error.
+func each(r io.Reader, fn func(Entry) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(e); err != nil {
+			if err == errStop {
+				return nil
+			}
+			return err
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,185 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testTime = time.Date(2025, 1, 6, 6, 0, 0, 0, time.UTC)
+
+// writeLog appends n entries to a log file in a new directory and returns its path and head.
+func writeLog(t *testing.T, n int) (string, Head) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open("mes", Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	var last Entry
+	for i := 1; i <= n; i++ {
+		if last, err = l.Append(Entry{Time: testTime.Add(time.Duration(i) * time.Minute), Actor: "alice", Action: "order.release", Entity: "order", EntityID: fmt.Sprintf("o-%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path, Head{Seq: last.Seq, Hash: last.Hash}
+}
+
+func TestVerifyTampering(t *testing.T) {
+	path, head := writeLog(t, 5)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	lines = lines[:len(lines)-1] // after the last newline
+	join := func(idx ...int) string {
+		var b strings.Builder
+		for _, i := range idx {
+			b.WriteString(lines[i])
+		}
+		return b.String()
+	}
+	if _, err := Check(strings.NewReader(join(0, 1, 2, 3, 4)), head); err != nil {
+		t.Fatalf("untouched log: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		log       string
+		want      string
+		truncated bool // a valid but shorter chain, caught only by the head
+	}{
+		{"edited", join(0, 1) + strings.Replace(lines[2], `"alice"`, `"mallory"`, 1) + join(3, 4), "entry 3: hash mismatch", false},
+		{"deleted", join(0, 1, 3, 4), "entry 4: follows entry 2", false},
+		{"reordered", join(0, 2, 1, 3, 4), "entry 3: follows entry 1", false},
+		{"first deleted", join(1, 2, 3, 4), "entry 2: log does not start at entry 1", false},
+		{"cut off the end", join(0, 1, 2, 3), "entry 5 with hash", true},
+		{"not JSON", join(0, 1) + "{\n" + join(3, 4), "line 3", false},
+	} {
+		if _, err := Check(strings.NewReader(tc.log), head); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: Check error %v, want %q", tc.name, err, tc.want)
+		}
+		if entries, err := Read(strings.NewReader(tc.log)); err == nil && Verify(entries) == nil && !tc.truncated {
+			t.Errorf("%s: Verify passed", tc.name)
+		}
+
+		// A service refuses to open the tampered file, and one already running notices on /audit/verify.
+		tampered := filepath.Join(t.TempDir(), "audit.jsonl")
+		if err := os.WriteFile(tampered, []byte(tc.log), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Open("mes", Config{Path: tampered}); err == nil && !tc.truncated {
+			t.Errorf("%s: Open succeeded", tc.name)
+		}
+		running, err := Open("mes", Config{Path: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(tc.log), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := running.Verify(); err == nil {
+			t.Errorf("%s: Log.Verify passed", tc.name)
+		}
+		running.Close()
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReopen(t *testing.T) {
+	path, head := writeLog(t, 3)
+	l, err := Open("mes", Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	e, err := l.Append(Entry{Actor: "bob", Action: "order.pause", Entity: "order", EntityID: "o-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Seq != 4 || e.Prev != head.Hash {
+		t.Errorf("entry after reopening: seq %d prev %s, want 4 and %s", e.Seq, e.Prev, head.Hash)
+	}
+	if got, err := l.Verify(); err != nil || got != (Head{Seq: 4, Hash: e.Hash}) {
+		t.Errorf("Verify = %+v, %v", got, err)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	path, _ := writeLog(t, 6)
+	l, err := Open("mes", Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	mem, _ := Open("mes", Config{})
+	for i := 1; i <= 6; i++ {
+		mem.Append(Entry{Time: testTime.Add(time.Duration(i) * time.Minute), Actor: "alice", Action: "order.release", Entity: "order", EntityID: fmt.Sprintf("o-%d", i)})
+	}
+	ids := func(entries []Entry) string {
+		var b bytes.Buffer
+		for _, e := range entries {
+			fmt.Fprintf(&b, "%s ", e.EntityID)
+		}
+		return strings.TrimSpace(b.String())
+	}
+
+	for _, tc := range []struct {
+		filter Filter
+		want   string
+	}{
+		{Filter{}, "o-6 o-5 o-4 o-3 o-2 o-1"},
+		{Filter{Limit: 2}, "o-6 o-5"},
+		{Filter{EntityID: "o-2"}, "o-2"},
+		{Filter{Actor: "bob"}, ""},
+		{Filter{Since: testTime.Add(2 * time.Minute), Until: testTime.Add(5 * time.Minute)}, "o-4 o-3 o-2"},
+	} {
+		if got := ids(l.Query(tc.filter)); got != tc.want {
+			t.Errorf("Query(%+v) = %s, want %s", tc.filter, got, tc.want)
+		}
+		if got := ids(mem.Query(tc.filter)); got != tc.want {
+			t.Errorf("in memory: Query(%+v) = %s, want %s", tc.filter, got, tc.want)
+		}
+	}
+
+	// Entries dropped from memory are read back from the file.
+	l.mu.Lock()
+	l.tail = l.tail[len(l.tail)-2:]
+	l.mu.Unlock()
+	if got := ids(l.Query(Filter{Limit: 4})); got != "o-6 o-5 o-4 o-3" {
+		t.Errorf("Query past the tail = %s", got)
+	}
+	if got := ids(l.Query(Filter{EntityID: "o-1"})); got != "o-1" {
+		t.Errorf("Query for an old entry = %s", got)
+	}
+	if _, err := l.Verify(); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+}
+
+func TestTail(t *testing.T) {
+	l, _ := Open("mes", Config{})
+	for i := 0; i < 2*tailEntries+5; i++ {
+		if _, err := l.Append(Entry{Actor: ActorSystem, Action: "test"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(l.tail); n < tailEntries || n >= 2*tailEntries {
+		t.Errorf("%d entries in memory, want %d to %d", n, tailEntries, 2*tailEntries)
+	}
+	head, err := l.Verify()
+	if err != nil || head.Seq != 2*tailEntries+5 {
+		t.Errorf("Verify = %+v, %v", head, err)
+	}
+	if got := l.Query(Filter{Limit: 3}); len(got) != 3 || got[0].Seq != head.Seq {
+		t.Errorf("latest entries %+v", got)
+	}
+}
@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+)
+
+// Filter selects entries; empty fields match everything.
+type Filter struct {
+	Actor    string
+	Action   string
+	Entity   string
+	EntityID string
+	Since    time.Time // inclusive
+	Until    time.Time // exclusive
+	Limit    int       // newest first; 0 = 100
+}
+
+func (f Filter) match(e *Entry) bool {
+	return (f.Actor == "" || e.Actor == f.Actor) &&
+		(f.Action == "" || e.Action == f.Action) &&
+		(f.Entity == "" || e.Entity == f.Entity) &&
+		(f.EntityID == "" || e.EntityID == f.EntityID) &&
+		(f.Since.IsZero() || !e.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || e.Time.Before(f.Until))
+}
+
+// Query returns the entries matching f, newest first. Entries older than the ones kept in memory are read
+// from the file, if there is one.
+func (l *Log) Query(f Filter) []Entry {
+	if l == nil {
+		return nil
+	}
+	if f.Limit <= 0 {
+		f.Limit = 100
+	}
+	l.mu.RLock()
+	var out []Entry
+	for i := len(l.tail) - 1; i >= 0 && len(out) < f.Limit; i-- {
+		if f.match(&l.tail[i]) {
+			out = append(out, l.tail[i])
+		}
+	}
+	older := l.head.Seq - uint64(len(l.tail)) // entries before the tail, the first lines of the file
+	l.mu.RUnlock()
+	if len(out) == f.Limit || older == 0 || l.path == "" {
+		return out
+	}
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		log.Printf("audit: query: %v", err)
+		return out
+	}
+	defer file.Close()
+	want := f.Limit - len(out)
+	var matches []Entry // the latest want matches, or up to twice as many between trims
+	var n uint64
+	err = each(file, func(e Entry) error {
+		if n == older {
+			return errStop
+		}
+		n++
+		if f.match(&e) {
+			if matches = append(matches, e); len(matches) >= 2*want {
+				matches = append([]Entry(nil), matches[len(matches)-want:]...)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("audit: query: %s: %v", l.path, err)
+	}
+	for i := len(matches) - 1; i >= 0 && len(out) < f.Limit; i-- {
+		out = append(out, matches[i])
+	}
+	return out
+}
+
+// Verify checks the chain of the log (see Verify) and returns its head. A log with a file checks the whole
+// file up to the head, without holding up appends; one without checks the entries still in memory.
+func (l *Log) Verify() (Head, error) {
+	if l == nil {
+		return Head{}, nil
+	}
+	l.mu.RLock()
+	head, tail := l.head, l.tail
+	l.mu.RUnlock()
+	if l.path == "" {
+		var c chain
+		if len(tail) > 0 && tail[0].Seq > 1 {
+			// The entries before the tail are gone; take its first link as given.
+			c = chain{head: Head{Seq: tail[0].Seq - 1, Hash: tail[0].Prev}, n: 1}
+		}
+		for _, e := range tail {
+			if err := c.add(e); err != nil {
+				return head, err
+			}
+		}
+		return head, nil
+	}
+	f, err := os.Open(l.path)
+	if err != nil {
+		return head, err
+	}
+	defer f.Close()
+	_, err = check(f, head, head.Seq)
+	return head, err
+}
+
+// ServeHTTP serves GET /audit?actor=&action=&entity=&entity_id=&since=&until=&limit= (RFC3339 times),
+// newest first, and GET /audit/verify, which checks the chain and returns its head.
+func (l *Log) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path == "/audit/verify" {
+		head, err := l.Verify()
+		resp := map[string]interface{}{"ok": err == nil, "head": head}
+		if err != nil {
+			resp["error"] = err.Error()
+		}
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+	q := r.URL.Query()
+	f := Filter{Actor: q.Get("actor"), Action: q.Get("action"), Entity: q.Get("entity"), EntityID: q.Get("entity_id")}
+	for _, t := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		if s := q.Get(t.name); s != "" {
+			v, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				http.Error(w, t.name+": want an RFC3339 time", http.StatusBadRequest)
+				return
+			}
+			*t.dst = v
+		}
+	}
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			http.Error(w, "limit: want a non-negative number", http.StatusBadRequest)
+			return
+		}
+		f.Limit = n
+	}
+	entries := l.Query(f)
+	if entries == nil {
+		entries = []Entry{}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"entries": entries})
+}
+
+// AuthRules limit the audit log to admins and quality; services join them into their own rules.
+var AuthRules = []auth.Rule{
+	{Path: "/audit", Roles: []string{auth.RoleQuality}},
+	{Path: "/audit/", Roles: []string{auth.RoleQuality}},
+}
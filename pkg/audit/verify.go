@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"fmt"
+	"io"
+)
+
+// Verify checks the chain of entries: sequence numbers count up from 1, each entry's hash matches its
+// content and each links to the one before. The first failure is returned with the entry's seq.
+func Verify(entries []Entry) error {
+	var c chain
+	for _, e := range entries {
+		if err := c.add(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check verifies the JSON-lines log read from r as Verify does, one entry at a time, and that it still
+// holds the entry at want unchanged (see Contains). It returns the head of the log.
+func Check(r io.Reader, want Head) (Head, error) {
+	return check(r, want, 0)
+}
+
+// check is Check reading at most n entries (0 = all), for a log that may grow while it is read.
+func check(r io.Reader, want Head, n uint64) (Head, error) {
+	var c chain
+	found := want.Seq == 0
+	err := each(r, func(e Entry) error {
+		if n > 0 && c.n == n {
+			return errStop
+		}
+		if err := c.add(e); err != nil {
+			return err
+		}
+		found = found || (e.Seq == want.Seq && e.Hash == want.Hash)
+		return nil
+	})
+	if err != nil {
+		return c.head, err
+	}
+	if !found {
+		return c.head, fmt.Errorf("entry %d with hash %s not found (log truncated or rewritten)", want.Seq, want.Hash)
+	}
+	return c.head, nil
+}
+
+// chain checks entries one at a time, in order.
+type chain struct {
+	head Head   // last entry checked
+	n    uint64 // entries checked
+}
+
+func (c *chain) add(e Entry) error {
+	if c.n > 0 {
+		if e.Seq != c.head.Seq+1 {
+			return fmt.Errorf("entry %d: follows entry %d (entries missing or reordered)", e.Seq, c.head.Seq)
+		}
+		if e.Prev != c.head.Hash {
+			return fmt.Errorf("entry %d: does not link to entry %d (entry removed or replaced)", e.Seq, c.head.Seq)
+		}
+	} else if e.Seq != 1 || e.Prev != "" {
+		return fmt.Errorf("entry %d: log does not start at entry 1 (entries removed from the start)", e.Seq)
+	}
+	h, err := hash(e)
+	if err != nil {
+		return fmt.Errorf("entry %d: %w", e.Seq, err)
+	}
+	if h != e.Hash {
+		return fmt.Errorf("entry %d: hash mismatch (entry modified)", e.Seq)
+	}
+	c.head = Head{Seq: e.Seq, Hash: e.Hash}
+	c.n++
+	return nil
+}
+
+// Head is the position of the last entry of a log. Keeping it somewhere else (a ticket, another system)
+// also exposes entries cut off the end, which the chain alone cannot show.
+type Head struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+// HeadOf returns the head of entries; zero for an empty log.
+func HeadOf(entries []Entry) Head {
+	if len(entries) == 0 {
+		return Head{}
+	}
+	last := entries[len(entries)-1]
+	return Head{Seq: last.Seq, Hash: last.Hash}
+}
+
+// Contains reports whether entries still hold the entry at head unchanged.
+func Contains(entries []Entry, head Head) bool {
+	if head.Seq == 0 {
+		return true
+	}
+	if len(entries) == 0 {
+		return false
+	}
+	i := head.Seq - entries[0].Seq
+	return head.Seq >= entries[0].Seq && i < uint64(len(entries)) && entries[i].Seq == head.Seq && entries[i].Hash == head.Hash
+}
@@ -0,0 +1,205 @@
+// Package auth authenticates HTTP API callers by API key or JWT and authorizes them by role per route.
+// Every service's Server has an optional Authenticator; without one (no keys configured) the API stays open.
+//
+// Callers send their credential as "Authorization: Bearer <api key or JWT>" or "X-API-Key: <api key>".
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config is a service's "auth" config section. Auth is on when it has an API key or a JWT key.
+type Config struct {
+	APIKeys     []APIKey  `yaml:"api_keys"`
+	JWT         JWTConfig `yaml:"jwt"`
+	Permissions []Rule    `yaml:"permissions"` // checked before the service's own rules
+}
+
+// APIKey is a static credential and the roles it grants. Give the key itself or its SHA-256 (hex).
+type APIKey struct {
+	Name   string   `yaml:"name"` // who uses it, e.g. "mes"; the principal's subject
+	Key    string   `yaml:"key"`
+	SHA256 string   `yaml:"sha256"`
+	Roles  []string `yaml:"roles"`
+}
+
+// JWTConfig accepts JWTs signed with HS256/384/512 by Secret and/or RS256/384/512 by the key in PublicKey.
+type JWTConfig struct {
+	Secret     string `yaml:"secret"`       // HMAC secret; env AUTH_JWT_SECRET overrides
+	PublicKey  string `yaml:"public_key"`   // RSA public key or certificate, PEM file or inline; env AUTH_JWT_PUBLIC_KEY overrides
+	Issuer     string `yaml:"issuer"`       // required iss, if set
+	Audience   string `yaml:"audience"`     // required in aud, if set
+	RolesClaim string `yaml:"roles_claim"`  // claim holding the roles, dotted for nested ones (e.g. "realm_access.roles"); default "roles"
+	Leeway     string `yaml:"leeway"`       // clock skew allowed on exp and nbf; default 30s
+	AllowNoExp bool   `yaml:"allow_no_exp"` // accept tokens without exp, which never expire; default false
+}
+
+// ApplyEnv overrides the JWT keys from AUTH_JWT_SECRET and AUTH_JWT_PUBLIC_KEY.
+func (c *Config) ApplyEnv() {
+	if s := os.Getenv("AUTH_JWT_SECRET"); s != "" {
+		c.JWT.Secret = s
+	}
+	if k := os.Getenv("AUTH_JWT_PUBLIC_KEY"); k != "" {
+		c.JWT.PublicKey = k
+	}
+}
+
+// Enabled reports whether c configures any credential.
+func (c *Config) Enabled() bool {
+	return len(c.APIKeys) > 0 || c.JWT.Secret != "" || c.JWT.PublicKey != ""
+}
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Subject string // API key name or JWT sub
+	Roles   []string
+	Method  string // "api_key" or "jwt"
+}
+
+// HasRole reports whether p has role; admins have every role.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role || r == RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// FromContext returns the caller of an authenticated request, or nil.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Authenticator checks the credentials and roles of requests to one service.
+type Authenticator struct {
+	keys  map[[sha256.Size]byte]APIKey
+	jwt   *jwtVerifier
+	rules []Rule
+}
+
+// New returns an Authenticator for cfg and the service's rules, or nil if cfg enables no auth.
+// cfg.Permissions go before rules; see Rule for how they match.
+func New(cfg Config, rules []Rule) (*Authenticator, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	a := &Authenticator{keys: make(map[[sha256.Size]byte]APIKey, len(cfg.APIKeys))}
+	for i, k := range cfg.APIKeys {
+		if k.Name == "" {
+			return nil, fmt.Errorf("auth: api_keys[%d]: name required", i)
+		}
+		if err := checkRoles(k.Roles); err != nil {
+			return nil, fmt.Errorf("auth: api key %s: %w", k.Name, err)
+		}
+		var sum [sha256.Size]byte
+		switch {
+		case k.Key != "" && k.SHA256 == "":
+			sum = sha256.Sum256([]byte(k.Key))
+		case k.SHA256 != "" && k.Key == "":
+			b, err := hex.DecodeString(k.SHA256)
+			if err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("auth: api key %s: sha256 must be 64 hex digits", k.Name)
+			}
+			copy(sum[:], b)
+		default:
+			return nil, fmt.Errorf("auth: api key %s: set one of key and sha256", k.Name)
+		}
+		if _, dup := a.keys[sum]; dup {
+			return nil, fmt.Errorf("auth: api key %s: same key as another", k.Name)
+		}
+		k.Key = ""
+		a.keys[sum] = k
+	}
+	if cfg.JWT.Secret != "" || cfg.JWT.PublicKey != "" {
+		v, err := newJWTVerifier(cfg.JWT)
+		if err != nil {
+			return nil, fmt.Errorf("auth: jwt: %w", err)
+		}
+		a.jwt = v
+	}
+	for i, r := range cfg.Permissions {
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("auth: permissions[%d]: %w", i, err)
+		}
+	}
+	a.rules = append(append([]Rule(nil), cfg.Permissions...), rules...)
+	return a, nil
+}
+
+// Wrap returns next behind authentication and the route rules. A nil Authenticator returns next unchanged.
+// Failed authentication gets 401, a missing role 403; the caller of a passed request is in its context.
+func (a *Authenticator) Wrap(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rule := match(a.rules, r.Method, r.URL.Path)
+		if rule.Public {
+			next.ServeHTTP(w, r)
+			return
+		}
+		p, err := a.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="robotfleetos"`)
+			http.Error(w, "unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !rule.allows(p) {
+			log.Printf("auth: %s %s denied to %s (roles %v)", r.Method, r.URL.Path, p.Subject, p.Roles)
+			http.Error(w, fmt.Sprintf("forbidden: needs role %s", strings.Join(rule.Roles, " or ")), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+	})
+}
+
+// Authenticate returns the caller of r from its API key or JWT.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	cred := r.Header.Get("X-API-Key")
+	if cred == "" {
+		h := r.Header.Get("Authorization")
+		if h == "" {
+			return nil, fmt.Errorf("no credentials")
+		}
+		scheme, token, ok := strings.Cut(h, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return nil, fmt.Errorf("authorization must be a bearer token")
+		}
+		cred = strings.TrimSpace(token)
+	}
+	if strings.Count(cred, ".") == 2 && a.jwt != nil {
+		return a.jwt.verify(cred, time.Now())
+	}
+	return a.apiKey(cred)
+}
+
+// apiKey looks up key, comparing digests in constant time.
+func (a *Authenticator) apiKey(key string) (*Principal, error) {
+	sum := sha256.Sum256([]byte(key))
+	for s, k := range a.keys {
+		if subtle.ConstantTimeCompare(s[:], sum[:]) == 1 {
+			return &Principal{Subject: k.Name, Roles: k.Roles, Method: "api_key"}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid credentials")
+}
+
+// SetBearer sets the Authorization header of an outgoing request to token (an API key or JWT), if any.
+func SetBearer(req *http.Request, token string) {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
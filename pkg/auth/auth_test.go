@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	if a, err := New(Config{}, nil); a != nil || err != nil {
+		t.Errorf("New without credentials = %v, %v; want nil, nil", a, err)
+	}
+	for name, cfg := range map[string]Config{
+		"no name":      {APIKeys: []APIKey{{Key: "k"}}},
+		"unknown role": {APIKeys: []APIKey{{Name: "a", Key: "k", Roles: []string{"boss"}}}},
+		"key and hash": {APIKeys: []APIKey{{Name: "a", Key: "k", SHA256: hex.EncodeToString(make([]byte, 32))}}},
+		"bad hash":     {APIKeys: []APIKey{{Name: "a", SHA256: "abc"}}},
+		"duplicate":    {APIKeys: []APIKey{{Name: "a", Key: "k"}, {Name: "b", Key: "k"}}},
+		"bad rule":     {APIKeys: []APIKey{{Name: "a", Key: "k"}}, Permissions: []Rule{{Path: "/x"}}},
+	} {
+		if _, err := New(cfg, nil); err == nil {
+			t.Errorf("%s: New = nil error", name)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	sum := sha256.Sum256([]byte("mes-key"))
+	a, err := New(Config{
+		APIKeys: []APIKey{
+			{Name: "ops", Key: "ops-key", Roles: []string{RoleOperator}},
+			{Name: "mes", SHA256: hex.EncodeToString(sum[:]), Roles: []string{RolePlanner}},
+			{Name: "root", Key: "root-key", Roles: []string{RoleAdmin}},
+		},
+		JWT:         JWTConfig{Secret: testSecret},
+		Permissions: []Rule{{Method: http.MethodPost, Path: "/robots/*/estop", Roles: []string{RoleOperator}}},
+	}, Join(
+		Public("/health"),
+		Writes("/orders/", RolePlanner),
+		[]Rule{{Method: http.MethodPost, Path: "/robots/", Roles: []string{RoleMaintenance}}},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var caller *Principal
+	h := a.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { caller = FromContext(r.Context()) }))
+
+	jwt := token(t, "HS256", []byte(testSecret), map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix(), "roles": "quality"})
+	for _, tc := range []struct {
+		method, path, header, cred string
+		want                       int
+		subject                    string
+	}{
+		{"GET", "/health", "", "", http.StatusOK, ""},
+		{"GET", "/orders", "", "", http.StatusUnauthorized, ""},
+		{"GET", "/orders", "X-API-Key", "wrong", http.StatusUnauthorized, ""},
+		{"GET", "/orders", "Authorization", "Basic b3BzLWtleQ==", http.StatusUnauthorized, ""},
+		{"GET", "/orders", "X-API-Key", "ops-key", http.StatusOK, "ops"},
+		{"GET", "/orders", "Authorization", "Bearer mes-key", http.StatusOK, "mes"},
+		{"GET", "/orders", "Authorization", "Bearer " + jwt, http.StatusOK, "alice"},
+		{"POST", "/orders/o-1/release", "X-API-Key", "mes-key", http.StatusOK, "mes"},
+		{"POST", "/orders/o-1/release", "X-API-Key", "ops-key", http.StatusForbidden, ""},
+		{"DELETE", "/orders/o-1", "X-API-Key", "mes-key", http.StatusOK, "mes"},
+		// Permissions from the config come before the service's rules.
+		{"POST", "/robots/r-1/estop", "X-API-Key", "ops-key", http.StatusOK, "ops"},
+		{"POST", "/robots/r-1/firmware", "X-API-Key", "ops-key", http.StatusForbidden, ""},
+		// Without a rule, writes need admin.
+		{"POST", "/areas", "X-API-Key", "mes-key", http.StatusForbidden, ""},
+		{"POST", "/areas", "X-API-Key", "root-key", http.StatusOK, "root"},
+		{"POST", "/areas", "Authorization", "Bearer " + jwt, http.StatusForbidden, ""},
+	} {
+		caller = nil
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.cred)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s %s with %s %q: %d, want %d", tc.method, tc.path, tc.header, tc.cred, rec.Code, tc.want)
+			continue
+		}
+		got := ""
+		if caller != nil {
+			got = caller.Subject
+		}
+		if got != tc.subject {
+			t.Errorf("%s %s: caller %q, want %q", tc.method, tc.path, got, tc.subject)
+		}
+	}
+
+	var none *Authenticator
+	if got := none.Wrap(h); got == nil {
+		t.Errorf("nil Authenticator wrapped to nil")
+	}
+}
+
+func TestRuleMatch(t *testing.T) {
+	rules := []Rule{
+		{Method: http.MethodPost, Path: "/orders/*/scrap", Roles: []string{RoleQuality}},
+		{Method: "post", Path: "/orders/", Roles: []string{RolePlanner}},
+		{Path: "/orders", Roles: []string{RoleOperator}},
+		{Path: "/", Public: true},
+	}
+	for _, tc := range []struct {
+		method, path string
+		want         Rule
+	}{
+		{"POST", "/orders/o-1/scrap", rules[0]},
+		{"POST", "/orders/o-1/release", rules[1]},
+		{"POST", "/orders/o-1", rules[1]},
+		{"POST", "/orders/", rules[1]},
+		{"POST", "/orders", rules[2]},
+		{"GET", "/orders", rules[2]},
+		{"GET", "/orders/o-1/scrap", Rule{Roles: []string{AnyRole}}},
+		{"GET", "/", rules[3]},
+		{"HEAD", "/areas", Rule{Roles: []string{AnyRole}}},
+		{"PUT", "/areas/a-1", Rule{Roles: []string{RoleAdmin}}},
+	} {
+		got := Lookup(rules, tc.method, tc.path)
+		if got.Method != tc.want.Method || got.Path != tc.want.Path || got.Public != tc.want.Public || len(got.Roles) != len(tc.want.Roles) || (len(got.Roles) > 0 && got.Roles[0] != tc.want.Roles[0]) {
+			t.Errorf("Lookup(%s %s) = %+v, want %+v", tc.method, tc.path, got, tc.want)
+		}
+	}
+
+	p := &Principal{Roles: []string{RolePlanner}}
+	if !(Rule{Roles: []string{RoleQuality, RolePlanner}}).allows(p) || (Rule{Roles: []string{RoleQuality}}).allows(p) {
+		t.Errorf("planner allowed by the wrong rules")
+	}
+	if !(Rule{Roles: []string{RoleQuality}}).allows(&Principal{Roles: []string{RoleAdmin}}) {
+		t.Errorf("admin denied")
+	}
+}
@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha512" // SHA-384 and SHA-512 for HS/RS384 and 512
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// jwtVerifier checks compact JWS tokens (RFC 7515/7519) with the configured keys.
+type jwtVerifier struct {
+	secret     []byte
+	public     *rsa.PublicKey
+	issuer     string
+	audience   string
+	rolesClaim []string
+	leeway     time.Duration
+	allowNoExp bool
+}
+
+func newJWTVerifier(c JWTConfig) (*jwtVerifier, error) {
+	v := &jwtVerifier{secret: []byte(c.Secret), issuer: c.Issuer, audience: c.Audience, leeway: 30 * time.Second, allowNoExp: c.AllowNoExp}
+	if c.PublicKey != "" {
+		pemData := []byte(c.PublicKey)
+		if !strings.HasPrefix(strings.TrimSpace(c.PublicKey), "-----BEGIN") {
+			data, err := os.ReadFile(c.PublicKey)
+			if err != nil {
+				return nil, err
+			}
+			pemData = data
+		}
+		key, err := parseRSAPublicKey(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("public_key: %w", err)
+		}
+		v.public = key
+	}
+	claim := c.RolesClaim
+	if claim == "" {
+		claim = "roles"
+	}
+	v.rolesClaim = strings.Split(claim, ".")
+	if c.Leeway != "" {
+		d, err := time.ParseDuration(c.Leeway)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("leeway: invalid duration %q", c.Leeway)
+		}
+		v.leeway = d
+	}
+	return v, nil
+}
+
+// parseRSAPublicKey reads the first PEM block: a PKIX or PKCS#1 public key or a certificate.
+func parseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block")
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// verify checks token's signature, exp, nbf, iss and aud and returns its subject and roles. Tokens need a
+// subject, and an exp unless allowNoExp is set.
+func (v *jwtVerifier) verify(token string, now time.Time) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature")
+	}
+	if err := v.checkSignature(header.Alg, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims")
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok && !v.allowNoExp {
+		return nil, fmt.Errorf("token has no expiry")
+	}
+	if ok && now.After(time.Unix(int64(exp), 0).Add(v.leeway)) {
+		return nil, fmt.Errorf("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.leeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("token not valid yet")
+	}
+	if v.issuer != "" && claims["iss"] != v.issuer {
+		return nil, fmt.Errorf("wrong token issuer")
+	}
+	if v.audience != "" && !hasAudience(claims["aud"], v.audience) {
+		return nil, fmt.Errorf("wrong token audience")
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+	return &Principal{Subject: sub, Roles: v.roles(claims), Method: "jwt"}, nil
+}
+
+func (v *jwtVerifier) checkSignature(alg, signed string, sig []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "HS256", "RS256":
+		hash = crypto.SHA256
+	case "HS384", "RS384":
+		hash = crypto.SHA384
+	case "HS512", "RS512":
+		hash = crypto.SHA512
+	}
+	switch {
+	case hash == 0:
+	case strings.HasPrefix(alg, "HS") && len(v.secret) > 0:
+		mac := hmac.New(hash.New, v.secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return fmt.Errorf("invalid token signature")
+		}
+		return nil
+	case strings.HasPrefix(alg, "RS") && v.public != nil:
+		h := hash.New()
+		h.Write([]byte(signed))
+		if rsa.VerifyPKCS1v15(v.public, hash, h.Sum(nil), sig) != nil {
+			return fmt.Errorf("invalid token signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("token algorithm %q not accepted", alg)
+}
+
+// roles reads the roles claim: a list of strings or a space-separated string.
+func (v *jwtVerifier) roles(claims map[string]any) []string {
+	var val any = claims
+	for _, k := range v.rolesClaim {
+		m, ok := val.(map[string]any)
+		if !ok {
+			return nil
+		}
+		val = m[k]
+	}
+	switch r := val.(type) {
+	case string:
+		return strings.Fields(r)
+	case []any:
+		roles := make([]string, 0, len(r))
+		for _, x := range r {
+			if s, ok := x.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+		return roles
+	}
+	return nil
+}
+
+func hasAudience(aud any, want string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == want
+	case []any:
+		for _, x := range a {
+			if x == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
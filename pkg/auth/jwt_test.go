@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "test-secret"
+
+var testNow = time.Date(2025, 1, 6, 6, 0, 0, 0, time.UTC)
+
+// token returns a compact JWT with alg in its header, signed with key: a []byte HMAC secret, an
+// *rsa.PrivateKey or nil for no signature.
+func token(t *testing.T, alg string, key any, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	body, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		sum := sha256.Sum256([]byte(signed))
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, sum[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// claims returns valid claims for alice with the overrides applied in order; a nil value drops the claim.
+func claims(overrides ...map[string]any) map[string]any {
+	c := map[string]any{"sub": "alice", "exp": testNow.Add(time.Hour).Unix(), "roles": []string{"operator"}}
+	for _, o := range overrides {
+		for k, v := range o {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+	}
+	return c
+}
+
+func rsaKey(t *testing.T) (*rsa.PrivateKey, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestJWTVerify(t *testing.T) {
+	key, pub := rsaKey(t)
+	other, _ := rsaKey(t)
+	v, err := newJWTVerifier(JWTConfig{Secret: testSecret, PublicKey: pub, Issuer: "idp", Audience: "fleet"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := map[string]any{"iss": "idp", "aud": []string{"mes", "fleet"}}
+	with := func(extra map[string]any) map[string]any { return claims(ok, extra) }
+	secret := []byte(testSecret)
+	for _, tc := range []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"HS256", token(t, "HS256", secret, with(nil)), ""},
+		{"RS256", token(t, "RS256", key, with(nil)), ""},
+		{"HS256 wrong secret", token(t, "HS256", []byte("other"), with(nil)), "invalid token signature"},
+		{"RS256 other key", token(t, "RS256", other, with(nil)), "invalid token signature"},
+		{"alg none", token(t, "none", nil, with(nil)), `algorithm "none" not accepted`},
+		{"alg none with HMAC signature", token(t, "none", secret, with(nil)), `algorithm "none" not accepted`},
+		// An RS256 verifier must not take the public key as an HMAC secret.
+		{"HS256 signed with the public key", token(t, "HS256", []byte(pub), with(nil)), "invalid token signature"},
+		{"tampered claims", tamper(token(t, "HS256", secret, with(nil))), "invalid token signature"},
+		{"no sub", token(t, "HS256", secret, with(map[string]any{"sub": nil})), "no subject"},
+		{"empty sub", token(t, "HS256", secret, with(map[string]any{"sub": ""})), "no subject"},
+		{"no exp", token(t, "HS256", secret, with(map[string]any{"exp": nil})), "no expiry"},
+		{"expired", token(t, "HS256", secret, with(map[string]any{"exp": testNow.Add(-time.Minute).Unix()})), "expired"},
+		{"expired within leeway", token(t, "HS256", secret, with(map[string]any{"exp": testNow.Add(-10 * time.Second).Unix()})), ""},
+		{"not valid yet", token(t, "HS256", secret, with(map[string]any{"nbf": testNow.Add(time.Minute).Unix()})), "not valid yet"},
+		{"nbf within leeway", token(t, "HS256", secret, with(map[string]any{"nbf": testNow.Add(10 * time.Second).Unix()})), ""},
+		{"wrong issuer", token(t, "HS256", secret, with(map[string]any{"iss": "other"})), "issuer"},
+		{"wrong audience", token(t, "HS256", secret, with(map[string]any{"aud": "mes"})), "audience"},
+		{"single audience", token(t, "HS256", secret, with(map[string]any{"aud": "fleet"})), ""},
+		{"malformed", "a.b", "malformed"},
+	} {
+		p, err := v.verify(tc.token, testNow)
+		switch {
+		case tc.wantErr == "" && err != nil:
+			t.Errorf("%s: %v", tc.name, err)
+		case tc.wantErr == "" && (p.Subject != "alice" || p.Method != "jwt" || !reflect.DeepEqual(p.Roles, []string{"operator"})):
+			t.Errorf("%s: principal %+v", tc.name, p)
+		case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+			t.Errorf("%s: error %v, want %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+// tamper swaps the subject in a token's claims, keeping its signature.
+func tamper(tok string) string {
+	parts := strings.Split(tok, ".")
+	data, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var c map[string]any
+	json.Unmarshal(data, &c)
+	c["sub"] = "mallory"
+	data, _ = json.Marshal(c)
+	parts[1] = base64.RawURLEncoding.EncodeToString(data)
+	return strings.Join(parts, ".")
+}
+
+func TestJWTKeyAlgorithms(t *testing.T) {
+	key, pub := rsaKey(t)
+	// A verifier with only an RSA key rejects HMAC tokens, and one with only a secret rejects RSA ones.
+	rsaOnly, err := newJWTVerifier(JWTConfig{PublicKey: pub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rsaOnly.verify(token(t, "HS256", []byte(testSecret), claims(nil)), testNow); err == nil {
+		t.Errorf("RSA-only verifier accepted HS256")
+	}
+	hmacOnly, err := newJWTVerifier(JWTConfig{Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := hmacOnly.verify(token(t, "RS256", key, claims(nil)), testNow); err == nil {
+		t.Errorf("HMAC-only verifier accepted RS256")
+	}
+
+	// The public key may be a file, and a token without exp passes only with allow_no_exp.
+	file := filepath.Join(t.TempDir(), "jwt.pem")
+	if err := os.WriteFile(file, []byte(pub), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fromFile, err := newJWTVerifier(JWTConfig{PublicKey: file, AllowNoExp: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fromFile.verify(token(t, "RS256", key, claims(map[string]any{"exp": nil})), testNow); err != nil {
+		t.Errorf("key from file, no exp allowed: %v", err)
+	}
+	for _, bad := range []JWTConfig{
+		{PublicKey: filepath.Join(t.TempDir(), "missing.pem")},
+		{PublicKey: "-----BEGIN PUBLIC KEY-----\nnot base64\n-----END PUBLIC KEY-----"},
+		{Secret: testSecret, Leeway: "soon"},
+	} {
+		if _, err := newJWTVerifier(bad); err == nil {
+			t.Errorf("newJWTVerifier(%+v) = nil error", bad)
+		}
+	}
+}
+
+func TestJWTRolesClaim(t *testing.T) {
+	v, err := newJWTVerifier(JWTConfig{Secret: testSecret, RolesClaim: "realm_access.roles"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, roles := range []any{[]string{"planner", "quality"}, "planner quality"} {
+		c := claims(map[string]any{"roles": nil, "realm_access": map[string]any{"roles": roles}})
+		p, err := v.verify(token(t, "HS256", []byte(testSecret), c), testNow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(p.Roles, []string{"planner", "quality"}) {
+			t.Errorf("roles from %v: %v", roles, p.Roles)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Roles. Admins may do everything; the others are granted per route.
+const (
+	RoleOperator    = "operator"    // runs the floor: releases and completes work, stops robots
+	RolePlanner     = "planner"     // plans production: orders, work orders, tasks, products
+	RoleMaintenance = "maintenance" // equipment, maintenance work orders, firmware
+	RoleQuality     = "quality"     // inspections, NCRs, holds, recalls
+	RoleAdmin       = "admin"
+)
+
+// AnyRole in Rule.Roles lets every authenticated caller through.
+const AnyRole = "*"
+
+// Rule grants a route to roles. The first rule matching a request decides; without one, reads (GET, HEAD)
+// are open to every authenticated caller and everything else needs admin.
+//
+// Path matches like an http.ServeMux pattern: "/orders" only itself, "/orders/" the paths below it and "/"
+// only the root. A "*" segment matches any one segment, e.g. "/orders/*/scrap".
+type Rule struct {
+	Method string   `yaml:"method"` // e.g. POST; empty matches every method
+	Path   string   `yaml:"path"`
+	Roles  []string `yaml:"roles"`  // any of; admin always passes
+	Public bool     `yaml:"public"` // no credentials needed, e.g. /health
+}
+
+func (r Rule) validate() error {
+	if !strings.HasPrefix(r.Path, "/") {
+		return fmt.Errorf("path %q must start with /", r.Path)
+	}
+	if r.Public {
+		return nil
+	}
+	if len(r.Roles) == 0 {
+		return fmt.Errorf("path %s: roles or public required", r.Path)
+	}
+	return checkRoles(r.Roles)
+}
+
+func checkRoles(roles []string) error {
+	for _, role := range roles {
+		switch role {
+		case RoleOperator, RolePlanner, RoleMaintenance, RoleQuality, RoleAdmin, AnyRole:
+		default:
+			return fmt.Errorf("unknown role %q", role)
+		}
+	}
+	return nil
+}
+
+func (r Rule) allows(p *Principal) bool {
+	for _, role := range r.Roles {
+		if role == AnyRole || p.HasRole(role) {
+			return true
+		}
+	}
+	return p.HasRole(RoleAdmin)
+}
+
+func (r Rule) matches(method, p string) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, method) {
+		return false
+	}
+	want, got := segments(r.Path), segments(p)
+	if r.Path != "/" && strings.HasSuffix(r.Path, "/") {
+		// A subtree, without its root unless asked with the slash.
+		if len(got) < len(want) || (len(got) == len(want) && !strings.HasSuffix(p, "/")) {
+			return false
+		}
+	} else if len(got) != len(want) {
+		return false
+	}
+	for i, w := range want {
+		if ok, _ := path.Match(w, got[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func segments(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+// Lookup returns the rule deciding method on path p: the first of rules matching, else the default.
+func Lookup(rules []Rule, method, p string) Rule {
+	return match(rules, method, p)
+}
+
+// match returns the rule deciding method and p, or the default one.
+func match(rules []Rule, method, p string) Rule {
+	for _, r := range rules {
+		if r.matches(method, p) {
+			return r
+		}
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return Rule{Roles: []string{AnyRole}}
+	}
+	return Rule{Roles: []string{RoleAdmin}}
+}
+
+// Public returns rules opening paths to everyone, e.g. health checks and the dashboard pages.
+func Public(paths ...string) []Rule {
+	rules := make([]Rule, len(paths))
+	for i, p := range paths {
+		rules[i] = Rule{Method: http.MethodGet, Path: p, Public: true}
+	}
+	return rules
+}
+
+// Writes returns rules granting POST, PUT, PATCH and DELETE on path to roles; reads keep the default.
+func Writes(path string, roles ...string) []Rule {
+	methods := []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	rules := make([]Rule, len(methods))
+	for i, m := range methods {
+		rules[i] = Rule{Method: m, Path: path, Roles: roles}
+	}
+	return rules
+}
+
+// Join concatenates rule lists.
+func Join(lists ...[]Rule) []Rule {
+	var rules []Rule
+	for _, l := range lists {
+		rules = append(rules, l...)
+	}
+	return rules
+}
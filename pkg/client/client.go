@@ -0,0 +1,273 @@
+// Package client is the Go SDK for the RobotFleetOS HTTP APIs: the fleet layer and the MES, ERP, WMS, CMMS,
+// QMS, PLM and traceability services. Each API has a typed client embedding Client, which sends the bearer
+// token, retries with backoff and turns error responses into *Error:
+//
+//	fleet := client.NewFleet("http://localhost:8080")
+//	fleet.Token = os.Getenv("FLEET_TOKEN")
+//	wo, err := fleet.SubmitWorkOrder(ctx, client.WorkOrderRequest{AreaID: "area-1", Priority: 1, Payload: `{"sku":"A"}`})
+//
+// Every POST, PUT and DELETE carries an Idempotency-Key header so a server that honours it applies a retried
+// request once. The key is random per call unless the caller sets one with WithIdempotencyKey, e.g. derived
+// from its own record so that submitting the record again is also applied once.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	mrand "math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+)
+
+// IdempotencyHeader is the request header carrying the idempotency key.
+const IdempotencyHeader = "Idempotency-Key"
+
+// Retry is the retry policy: network errors and 429, 502, 503 and 504 responses are retried up to Attempts
+// calls in total, waiting MinDelay, then twice as long each time up to MaxDelay, with jitter. A 429 or 503
+// with Retry-After waits that long instead (still at most MaxDelay).
+type Retry struct {
+	Attempts int
+	MinDelay time.Duration
+	MaxDelay time.Duration
+}
+
+// DefaultRetry is the policy of new clients.
+var DefaultRetry = Retry{Attempts: 3, MinDelay: 200 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+// NoRetry calls once.
+var NoRetry = Retry{Attempts: 1}
+
+// Client calls one API. The typed clients (Fleet, MES, ...) embed it; use Do for endpoints they don't cover.
+type Client struct {
+	Name       string // API name used in errors, e.g. "fleet"
+	BaseURL    string // e.g. "http://localhost:8080"
+	HTTPClient *http.Client
+	Token      string // API key or JWT sent as bearer token; empty sends none
+	Retry      Retry
+}
+
+// New returns a client for the API called name at baseURL with DefaultRetry and a 15s timeout per call.
+func New(name, baseURL string) *Client {
+	return &Client{
+		Name:       name,
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 15 * time.Second},
+		Retry:      DefaultRetry,
+	}
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns a context whose POST, PUT and DELETE calls send key as their idempotency key.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+func newKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Do sends body (JSON-encoded unless nil) to method path and decodes a JSON response into out (unless nil).
+// path may carry a query. Error responses are returned as *Error.
+func (c *Client) Do(ctx context.Context, method, path string, body, out interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	key := ""
+	if method != http.MethodGet && method != http.MethodHead {
+		key, _ = ctx.Value(idempotencyKey{}).(string)
+		if key == "" {
+			key = newKey()
+		}
+	}
+	attempts := c.Retry.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(ctx, method, path, data, key)
+		if err == nil && resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			if out == nil || resp.StatusCode == http.StatusNoContent {
+				return nil
+			}
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return fmt.Errorf("%s: %s %s: decode response: %w", c.Name, method, path, err)
+			}
+			return nil
+		}
+		var wait time.Duration
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			err = fmt.Errorf("%s: %s %s: %w", c.Name, method, path, err)
+		} else {
+			err = c.responseError(method, path, resp)
+			wait = retryAfter(resp)
+			if !retryable(resp.StatusCode) {
+				return err
+			}
+		}
+		if attempt >= attempts {
+			return err
+		}
+		if wait <= 0 {
+			wait = c.Retry.delay(attempt)
+		}
+		if c.Retry.MaxDelay > 0 && wait > c.Retry.MaxDelay {
+			wait = c.Retry.MaxDelay
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, data []byte, key string) (*http.Response, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyHeader, key)
+	}
+	auth.SetBearer(req, c.Token)
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return hc.Do(req)
+}
+
+// delay is the backoff before retry n (1 = the first retry): MinDelay doubled n-1 times, ±25% jitter.
+func (r Retry) delay(n int) time.Duration {
+	d := float64(r.MinDelay) * math.Pow(2, float64(n-1))
+	if r.MaxDelay > 0 && d > float64(r.MaxDelay) {
+		d = float64(r.MaxDelay)
+	}
+	return time.Duration(d * (0.75 + 0.5*mrand.Float64()))
+}
+
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the wait a Retry-After header in seconds asks for, or 0.
+func retryAfter(resp *http.Response) time.Duration {
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return 0
+}
+
+// query returns "?"+the non-empty params, or "" if all are empty. params are name, value pairs.
+func query(params ...string) string {
+	v := url.Values{}
+	for i := 0; i+1 < len(params); i += 2 {
+		if params[i+1] != "" {
+			v.Set(params[i], params[i+1])
+		}
+	}
+	if len(v) == 0 {
+		return ""
+	}
+	return "?" + v.Encode()
+}
+
+// Health is the response of GET /health, which every API serves.
+type Health struct {
+	Status string `json:"status"`
+	Layer  string `json:"layer"`
+}
+
+// Health checks the API is up.
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	var out Health
+	if err := c.Do(ctx, http.MethodGet, "/health", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Audit returns the API's audit log entries matching f, newest first.
+func (c *Client) Audit(ctx context.Context, f audit.Filter) ([]audit.Entry, error) {
+	params := []string{"actor", f.Actor, "action", f.Action, "entity", f.Entity, "entity_id", f.EntityID}
+	if !f.Since.IsZero() {
+		params = append(params, "since", f.Since.Format(time.RFC3339))
+	}
+	if !f.Until.IsZero() {
+		params = append(params, "until", f.Until.Format(time.RFC3339))
+	}
+	if f.Limit > 0 {
+		params = append(params, "limit", strconv.Itoa(f.Limit))
+	}
+	var out struct {
+		Entries []audit.Entry `json:"entries"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/audit"+query(params...), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Entries, nil
+}
+
+// VerifyAudit checks the API's audit chain and returns its head; a broken chain is an error.
+func (c *Client) VerifyAudit(ctx context.Context) (audit.Head, error) {
+	var out struct {
+		OK    bool       `json:"ok"`
+		Head  audit.Head `json:"head"`
+		Error string     `json:"error"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/audit/verify", nil, &out); err != nil {
+		return audit.Head{}, err
+	}
+	if !out.OK {
+		return out.Head, errors.New(c.Name + ": audit log: " + out.Error)
+	}
+	return out.Head, nil
+}
+
+// action POSTs to path/id/verb and decodes the updated object into out.
+func (c *Client) action(ctx context.Context, path, id, verb string, body, out interface{}) error {
+	return c.Do(ctx, http.MethodPost, path+"/"+url.PathEscape(id)+"/"+verb, body, out)
+}
+
+// get GETs path/id into out.
+func (c *Client) get(ctx context.Context, path, id string, out interface{}) error {
+	return c.Do(ctx, http.MethodGet, path+"/"+url.PathEscape(id), nil, out)
+}
@@ -0,0 +1,221 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fastRetry retries without making the tests wait.
+var fastRetry = Retry{Attempts: 3, MinDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+type request struct {
+	method, path, key, auth, body string
+}
+
+// server answers with the given statuses in turn, then 200, and records the requests. A status of -1
+// drops the connection instead of answering.
+type server struct {
+	*httptest.Server
+	mu         sync.Mutex
+	statuses   []int
+	retryAfter string
+	requests   []request
+}
+
+func newServer(t *testing.T, statuses ...int) *server {
+	s := &server{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.requests = append(s.requests, request{r.Method, r.URL.RequestURI(), r.Header.Get(IdempotencyHeader), r.Header.Get("Authorization"), string(body)})
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		s.mu.Unlock()
+		switch {
+		case status == -1:
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			conn.Close()
+		case status >= 300:
+			if s.retryAfter != "" {
+				w.Header().Set("Retry-After", s.retryAfter)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			io.WriteString(w, `{"error":"status `+strconv.Itoa(status)+`"}`)
+		default:
+			io.WriteString(w, `{"id":"wo-1"}`)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *server) calls() []request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]request(nil), s.requests...)
+}
+
+func TestRetry(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		retry    Retry
+		statuses []int
+		calls    int
+		status   int // of the returned *Error; 0 = success
+	}{
+		{"unavailable then up", fastRetry, []int{503, 502, 200}, 3, 0},
+		{"connection dropped", fastRetry, []int{-1, 200}, 2, 0},
+		{"rate limited then up", fastRetry, []int{429, 200}, 2, 0},
+		{"still down", fastRetry, []int{503, 504, 503, 200}, 3, 503},
+		{"bad request", fastRetry, []int{400, 200}, 1, 400},
+		{"not found", fastRetry, []int{404, 200}, 1, 404},
+		{"conflict", fastRetry, []int{409, 200}, 1, 409},
+		{"unprocessable", fastRetry, []int{422, 200}, 1, 422},
+		{"server error", fastRetry, []int{500, 200}, 1, 500},
+		{"no retry", NoRetry, []int{503, 200}, 1, 503},
+		{"zero attempts", Retry{}, []int{503, 200}, 1, 503},
+	} {
+		srv := newServer(t, tc.statuses...)
+		c := New("fleet", srv.URL)
+		c.Retry = tc.retry
+		var out struct{ ID string }
+		err := c.Do(context.Background(), http.MethodPost, "/work_orders", map[string]string{"area_id": "area-1"}, &out)
+		if n := len(srv.calls()); n != tc.calls {
+			t.Errorf("%s: %d calls, want %d", tc.name, n, tc.calls)
+		}
+		if got := StatusCode(err); got != tc.status || (tc.status == 0 && (err != nil || out.ID != "wo-1")) {
+			t.Errorf("%s: Do = %v (status %d, id %q), want status %d", tc.name, err, got, out.ID, tc.status)
+		}
+		var e *Error
+		if tc.status != 0 && (!errors.As(err, &e) || e.Message != "status "+strconv.Itoa(tc.status) || e.Service != "fleet" || e.Path != "/work_orders") {
+			t.Errorf("%s: error %#v", tc.name, err)
+		}
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	srv := newServer(t, 503, 502, 200, 503, 200, 503, 200)
+	c := New("fleet", srv.URL)
+	c.Retry = fastRetry
+	c.Token = "secret"
+	ctx := context.Background()
+	body := map[string]string{"area_id": "area-1"}
+
+	// Every attempt of a call sends the same key and body; the next call gets a new key.
+	if err := c.Do(ctx, http.MethodPost, "/work_orders", body, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Do(ctx, http.MethodDelete, "/work_orders/wo-1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	// A key from the caller is used as is.
+	if err := c.Do(WithIdempotencyKey(ctx, "mes-order-7-1"), http.MethodPost, "/work_orders", body, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Do(WithIdempotencyKey(ctx, "ignored"), http.MethodGet, "/work_orders", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := srv.calls()
+	if len(calls) != 8 {
+		t.Fatalf("%d calls, want 8: %+v", len(calls), calls)
+	}
+	first, second := calls[0].key, calls[3].key
+	if len(first) != 32 || first == second {
+		t.Errorf("keys %q and %q, want two different random keys", first, second)
+	}
+	for i, want := range []request{
+		{"POST", "/work_orders", first, "Bearer secret", `{"area_id":"area-1"}`},
+		{"POST", "/work_orders", first, "Bearer secret", `{"area_id":"area-1"}`},
+		{"POST", "/work_orders", first, "Bearer secret", `{"area_id":"area-1"}`},
+		{"DELETE", "/work_orders/wo-1", second, "Bearer secret", ""},
+		{"DELETE", "/work_orders/wo-1", second, "Bearer secret", ""},
+		{"POST", "/work_orders", "mes-order-7-1", "Bearer secret", `{"area_id":"area-1"}`},
+		{"POST", "/work_orders", "mes-order-7-1", "Bearer secret", `{"area_id":"area-1"}`},
+		{"GET", "/work_orders", "", "Bearer secret", ""},
+	} {
+		if calls[i] != want {
+			t.Errorf("call %d = %+v, want %+v", i+1, calls[i], want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r := Retry{Attempts: 6, MinDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for n, base := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		base *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			if d := r.delay(n + 1); d < base*3/4 || d > base*5/4 {
+				t.Errorf("delay(%d) = %v, want %v ± 25%%", n+1, d, base)
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		header string
+		want   time.Duration
+	}{
+		{"2", 2 * time.Second},
+		{"", 0},
+		{"0", 0},
+		{"-1", 0},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0},
+	} {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Retry-After", tc.header)
+		if got := retryAfter(resp); got != tc.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", tc.header, got, tc.want)
+		}
+	}
+
+	// Retry-After is honoured, but not past MaxDelay.
+	for _, tc := range []struct {
+		retry    Retry
+		min, max time.Duration
+	}{
+		{Retry{Attempts: 2, MinDelay: time.Millisecond, MaxDelay: 5 * time.Second}, time.Second, 3 * time.Second},
+		{Retry{Attempts: 2, MinDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond}, 50 * time.Millisecond, 900 * time.Millisecond},
+	} {
+		srv := newServer(t, 503, 200)
+		srv.retryAfter = "1"
+		c := New("fleet", srv.URL)
+		c.Retry = tc.retry
+		start := time.Now()
+		if err := c.Do(context.Background(), http.MethodGet, "/health", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(start); d < tc.min || d > tc.max {
+			t.Errorf("MaxDelay %v: retried after %v, want %v to %v", tc.retry.MaxDelay, d, tc.min, tc.max)
+		}
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	srv := newServer(t, 503, 200)
+	c := New("fleet", srv.URL)
+	c.Retry = Retry{Attempts: 3, MinDelay: time.Minute, MaxDelay: time.Minute}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := c.Do(ctx, http.MethodGet, "/health", nil, nil)
+	if StatusCode(err) != 503 || time.Since(start) > 5*time.Second {
+		t.Errorf("Do cancelled while backing off = %v after %v, want the 503 at once", err, time.Since(start))
+	}
+	if n := len(srv.calls()); n != 1 {
+		t.Errorf("%d calls, want 1", n)
+	}
+}
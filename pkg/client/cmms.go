@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// CMMS is a client for the CMMS API.
+type CMMS struct{ *Client }
+
+// NewCMMS returns a client for the CMMS API at baseURL (e.g. "http://localhost:8085").
+func NewCMMS(baseURL string) *CMMS { return &CMMS{New("cmms", baseURL)} }
+
+// Equipment is a maintainable asset.
+type Equipment struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"` // robot, zone, machine, other
+	AreaID    string    `json:"area_id,omitempty"`
+	ZoneID    string    `json:"zone_id,omitempty"`
+	Status    string    `json:"status"` // operational, under_maintenance, out_of_service
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MaintenanceWorkOrder is a maintenance work order for a piece of equipment.
+type MaintenanceWorkOrder struct {
+	ID                    string     `json:"id"`
+	EquipmentID           string     `json:"equipment_id"`
+	Type                  string     `json:"type"`   // preventive, corrective, inspection, firmware_upgrade
+	Status                string     `json:"status"` // open, in_progress, completed, cancelled
+	Priority              int        `json:"priority"`
+	DueDate               *time.Time `json:"due_date,omitempty"`
+	Description           string     `json:"description"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+	CompletedAt           *time.Time `json:"completed_at,omitempty"`
+	FleetWorkOrderID      string     `json:"fleet_work_order_id,omitempty"`
+	TargetFirmwareVersion string     `json:"target_firmware_version,omitempty"`
+}
+
+// CreateEquipment registers an asset.
+func (c *CMMS) CreateEquipment(ctx context.Context, e Equipment) (*Equipment, error) {
+	var out Equipment
+	if err := c.Do(ctx, http.MethodPost, "/equipment", e, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateEquipment replaces an asset.
+func (c *CMMS) UpdateEquipment(ctx context.Context, e Equipment) (*Equipment, error) {
+	var out Equipment
+	if err := c.Do(ctx, http.MethodPut, "/equipment/"+url.PathEscape(e.ID), e, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Equipment lists the assets, optionally of one status and type.
+func (c *CMMS) Equipment(ctx context.Context, status, eqType string) ([]Equipment, error) {
+	var out struct {
+		Equipment []Equipment `json:"equipment"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/equipment"+query("status", status, "type", eqType), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Equipment, nil
+}
+
+// GetEquipment returns one asset.
+func (c *CMMS) GetEquipment(ctx context.Context, id string) (*Equipment, error) {
+	var out Equipment
+	if err := c.get(ctx, "/equipment", id, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateMWO creates an open maintenance work order.
+func (c *CMMS) CreateMWO(ctx context.Context, m MaintenanceWorkOrder) (*MaintenanceWorkOrder, error) {
+	var out MaintenanceWorkOrder
+	if err := c.Do(ctx, http.MethodPost, "/mwo", m, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MWOs lists the maintenance work orders, optionally of one status and piece of equipment.
+func (c *CMMS) MWOs(ctx context.Context, status, equipmentID string) ([]MaintenanceWorkOrder, error) {
+	var out struct {
+		WorkOrders []MaintenanceWorkOrder `json:"work_orders"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/mwo"+query("status", status, "equipment_id", equipmentID), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.WorkOrders, nil
+}
+
+// MWO returns one maintenance work order.
+func (c *CMMS) MWO(ctx context.Context, id string) (*MaintenanceWorkOrder, error) {
+	var out MaintenanceWorkOrder
+	if err := c.get(ctx, "/mwo", id, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *CMMS) mwoAction(ctx context.Context, id, verb string) (*MaintenanceWorkOrder, error) {
+	var out MaintenanceWorkOrder
+	if err := c.action(ctx, "/mwo", id, verb, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StartMWO starts a maintenance work order.
+func (c *CMMS) StartMWO(ctx context.Context, id string) (*MaintenanceWorkOrder, error) {
+	return c.mwoAction(ctx, id, "start")
+}
+
+// CompleteMWO completes a maintenance work order.
+func (c *CMMS) CompleteMWO(ctx context.Context, id string) (*MaintenanceWorkOrder, error) {
+	return c.mwoAction(ctx, id, "complete")
+}
+
+// CancelMWO cancels a maintenance work order.
+func (c *CMMS) CancelMWO(ctx context.Context, id string) (*MaintenanceWorkOrder, error) {
+	return c.mwoAction(ctx, id, "cancel")
+}
+
+// SubmitMWOToFleet submits a maintenance work order to the fleet and returns the fleet work order ID.
+func (c *CMMS) SubmitMWOToFleet(ctx context.Context, id string, priority int) (fleetWorkOrderID string, err error) {
+	var out struct {
+		FleetWorkOrderID string `json:"fleet_work_order_id"`
+	}
+	path := "/mwo/" + url.PathEscape(id) + "/submit_to_fleet"
+	if priority > 0 {
+		path += query("priority", strconv.Itoa(priority))
+	}
+	if err := c.Do(ctx, http.MethodPost, path, nil, &out); err != nil {
+		return "", err
+	}
+	return out.FleetWorkOrderID, nil
+}
+
+// TriggerFirmware starts a firmware campaign on the fleet through CMMS.
+func (c *CMMS) TriggerFirmware(ctx context.Context, seedBusy int) (message string, err error) {
+	var out struct {
+		Message string `json:"message"`
+	}
+	if err := c.Do(ctx, http.MethodPost, "/firmware/trigger", map[string]int{"seed_busy": seedBusy}, &out); err != nil {
+		return "", err
+	}
+	return out.Message, nil
+}
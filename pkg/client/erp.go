@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ERP is a client for the ERP API.
+type ERP struct{ *Client }
+
+// NewERP returns a client for the ERP API at baseURL (e.g. "http://localhost:8087").
+func NewERP(baseURL string) *ERP { return &ERP{New("erp", baseURL)} }
+
+// SalesOrder is an ERP order, submitted to MES as a production order.
+type SalesOrder struct {
+	ID          string     `json:"id"`
+	OrderRef    string     `json:"order_ref"`
+	CustomerRef string     `json:"customer_ref,omitempty"`
+	SKU         string     `json:"sku"`
+	Quantity    int        `json:"quantity"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	Status      string     `json:"status"` // draft, submitted, cancelled
+	MESOrderID  string     `json:"mes_order_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	SubmittedAt *time.Time `json:"submitted_at,omitempty"`
+}
+
+// CreateOrder creates a draft order; ID, status and times are set by ERP.
+func (c *ERP) CreateOrder(ctx context.Context, o SalesOrder) (*SalesOrder, error) {
+	var out SalesOrder
+	if err := c.Do(ctx, http.MethodPost, "/orders", o, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Orders lists the orders, optionally of one status.
+func (c *ERP) Orders(ctx context.Context, status string) ([]SalesOrder, error) {
+	var out struct {
+		Orders []SalesOrder `json:"orders"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/orders"+query("status", status), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Orders, nil
+}
+
+// Order returns one order.
+func (c *ERP) Order(ctx context.Context, id string) (*SalesOrder, error) {
+	var out SalesOrder
+	if err := c.get(ctx, "/orders", id, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SubmitToMES creates the MES production order of a draft order, in zoneID if set.
+func (c *ERP) SubmitToMES(ctx context.Context, id, zoneID string, priority int) (*SalesOrder, error) {
+	body := map[string]interface{}{"zone_id": zoneID, "priority": priority}
+	var out SalesOrder
+	if err := c.action(ctx, "/orders", id, "submit_to_mes", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CancelOrder cancels a draft order.
+func (c *ERP) CancelOrder(ctx context.Context, id string) (*SalesOrder, error) {
+	var out SalesOrder
+	if err := c.action(ctx, "/orders", id, "cancel", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error is an error response of an API.
+type Error struct {
+	Service    string // Client.Name, e.g. "fleet"
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string // the response body: the "error" field of a JSON body, else the text
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("%s: %s %s: %d %s", e.Service, e.Method, e.Path, e.StatusCode, msg)
+}
+
+// StatusCode returns the HTTP status of the *Error in err's chain, or 0 if there is none.
+func StatusCode(err error) int {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound reports whether err is a 404 response. Some APIs answer an unknown ID in an action with 400
+// and "... not found"; those count too.
+func IsNotFound(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.StatusCode == http.StatusNotFound ||
+		e.StatusCode == http.StatusBadRequest && strings.Contains(e.Message, "not found")
+}
+
+// IsConflict reports whether err is a 409 response.
+func IsConflict(err error) bool {
+	return StatusCode(err) == http.StatusConflict
+}
+
+func (c *Client) responseError(method, path string, resp *http.Response) *Error {
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	msg := strings.TrimSpace(string(data))
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		msg = body.Error
+	}
+	return &Error{Service: c.Name, Method: method, Path: path, StatusCode: resp.StatusCode, Message: msg}
+}
@@ -0,0 +1,237 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/facility"
+	"github.com/robotfleetos/robotfleetos/pkg/robotmodel"
+)
+
+// Fleet is a client for the fleet layer API.
+type Fleet struct{ *Client }
+
+// NewFleet returns a client for the fleet API at baseURL (e.g. "http://localhost:8080").
+func NewFleet(baseURL string) *Fleet { return &Fleet{New("fleet", baseURL)} }
+
+// WorkOrderRequest is the body of POST /work_orders.
+type WorkOrderRequest struct {
+	AreaID       string            `json:"area_id"`
+	Priority     int               `json:"priority"`
+	Payload      string            `json:"payload"`            // raw JSON or text; "{}" if empty
+	Deadline     *time.Time        `json:"deadline,omitempty"` // sent as RFC3339
+	Requirements *api.Requirements `json:"requirements,omitempty"`
+	Steps        []api.TaskStep    `json:"steps,omitempty"`
+}
+
+// SubmittedWorkOrder is the response of POST /work_orders.
+type SubmittedWorkOrder struct {
+	ID        string    `json:"id"`
+	AreaID    string    `json:"area_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WorkOrder is one of the recent work orders GET /work_orders lists.
+type WorkOrder struct {
+	ID             string    `json:"id"`
+	AreaID         string    `json:"area_id"`
+	Priority       int       `json:"priority"`
+	PayloadSummary string    `json:"payload_summary"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// SubmitWorkOrder submits a work order to an area.
+func (c *Fleet) SubmitWorkOrder(ctx context.Context, req WorkOrderRequest) (*SubmittedWorkOrder, error) {
+	if req.Payload == "" {
+		req.Payload = "{}"
+	}
+	body := struct {
+		WorkOrderRequest
+		Deadline string `json:"deadline,omitempty"`
+	}{WorkOrderRequest: req}
+	if req.Deadline != nil {
+		body.Deadline = req.Deadline.Format(time.RFC3339)
+	}
+	var out SubmittedWorkOrder
+	if err := c.Do(ctx, http.MethodPost, "/work_orders", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SubmitPayload submits a work order whose payload is v encoded as JSON.
+func (c *Fleet) SubmitPayload(ctx context.Context, areaID string, priority int, v interface{}) (*SubmittedWorkOrder, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return c.SubmitWorkOrder(ctx, WorkOrderRequest{AreaID: areaID, Priority: priority, Payload: string(payload)})
+}
+
+// WorkOrders lists the recent work orders, newest first.
+func (c *Fleet) WorkOrders(ctx context.Context) ([]WorkOrder, error) {
+	var out struct {
+		WorkOrders []WorkOrder `json:"work_orders"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/work_orders", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.WorkOrders, nil
+}
+
+// FleetState is the response of GET /state.
+type FleetState struct {
+	Areas       []api.AreaSummary `json:"areas"`
+	TotalRobots int               `json:"total_robots"`
+}
+
+// State returns the area summaries and the robot count.
+func (c *Fleet) State(ctx context.Context) (*FleetState, error) {
+	var out FleetState
+	if err := c.Do(ctx, http.MethodGet, "/state", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Areas returns the area summaries.
+func (c *Fleet) Areas(ctx context.Context) ([]api.AreaSummary, error) {
+	var out struct {
+		Areas []api.AreaSummary `json:"areas"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/state/areas", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Areas, nil
+}
+
+// FirmwareRequest is the body of POST /firmware/simulate.
+type FirmwareRequest struct {
+	SeedBusy int                 `json:"seed_busy,omitempty"` // submit this many work orders first so robots are busy
+	Policy   *api.FirmwarePolicy `json:"policy,omitempty"`
+	Deadline *time.Time          `json:"deadline,omitempty"` // max deferral; sent as RFC3339
+}
+
+// FirmwareCampaign is the response of POST /firmware/simulate.
+type FirmwareCampaign struct {
+	OK       bool   `json:"ok"`
+	Message  string `json:"message"`
+	OrderID  string `json:"order_id"`
+	Target   string `json:"target"`
+	SeedBusy int    `json:"seed_busy,omitempty"`
+}
+
+// FirmwareSimulate starts a firmware campaign; robots that are busy defer the update until their work is done.
+func (c *Fleet) FirmwareSimulate(ctx context.Context, req FirmwareRequest) (*FirmwareCampaign, error) {
+	body := struct {
+		FirmwareRequest
+		Deadline string `json:"deadline,omitempty"`
+	}{FirmwareRequest: req}
+	if req.Deadline != nil {
+		body.Deadline = req.Deadline.Format(time.RFC3339)
+	}
+	var out FirmwareCampaign
+	if err := c.Do(ctx, http.MethodPost, "/firmware/simulate", body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SafetyRequest is the body of POST /safety/stop_all and /safety/reset_all.
+type SafetyRequest struct {
+	AreaID   string `json:"area_id"`
+	ZoneID   string `json:"zone_id,omitempty"` // empty = every zone in the area
+	Reason   string `json:"reason,omitempty"`
+	Operator string `json:"operator,omitempty"`
+}
+
+// SafetyBroadcast is the response of POST /safety/stop_all and /safety/reset_all.
+type SafetyBroadcast struct {
+	OK      bool   `json:"ok"`
+	Type    string `json:"type"`
+	OrderID string `json:"order_id"`
+	AreaID  string `json:"area_id"`
+	ZoneID  string `json:"zone_id"`
+}
+
+// StopAll e-stops every robot of the area, or of one zone.
+func (c *Fleet) StopAll(ctx context.Context, req SafetyRequest) (*SafetyBroadcast, error) {
+	var out SafetyBroadcast
+	if err := c.Do(ctx, http.MethodPost, "/safety/stop_all", req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ResetAll resets the e-stop of every robot of the area, or of one zone.
+func (c *Fleet) ResetAll(ctx context.Context, req SafetyRequest) (*SafetyBroadcast, error) {
+	var out SafetyBroadcast
+	if err := c.Do(ctx, http.MethodPost, "/safety/reset_all", req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SafetyEvents lists safety events, optionally of one robot or zone.
+func (c *Fleet) SafetyEvents(ctx context.Context, robotID, zoneID string) ([]api.SafetyEvent, error) {
+	var out struct {
+		Events []api.SafetyEvent `json:"events"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/safety/events"+query("robot_id", robotID, "zone_id", zoneID), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Events, nil
+}
+
+// SecurityEvents lists the robot commands edges rejected, optionally of one robot, zone or reason.
+func (c *Fleet) SecurityEvents(ctx context.Context, robotID, zoneID, reason string) ([]api.SecurityEvent, error) {
+	var out struct {
+		Events []api.SecurityEvent `json:"events"`
+	}
+	path := "/security/events" + query("robot_id", robotID, "zone_id", zoneID, "reason", reason)
+	if err := c.Do(ctx, http.MethodGet, path, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Events, nil
+}
+
+// Map returns the facility map.
+func (c *Fleet) Map(ctx context.Context) (*facility.Map, error) {
+	var data json.RawMessage
+	if err := c.Do(ctx, http.MethodGet, "/map", nil, &data); err != nil {
+		return nil, err
+	}
+	return facility.Parse(data)
+}
+
+// PutMap replaces the facility map.
+func (c *Fleet) PutMap(ctx context.Context, m *facility.Map) error {
+	return c.Do(ctx, http.MethodPut, "/map", m, nil)
+}
+
+// Route returns the shortest route on the facility map between two points.
+func (c *Fleet) Route(ctx context.Context, from, to api.Point) (*facility.Route, error) {
+	var out facility.Route
+	path := "/map/route" + query("from", point(from), "to", point(to))
+	if err := c.Do(ctx, http.MethodGet, path, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Models returns the robot model registry.
+func (c *Fleet) Models(ctx context.Context) (*robotmodel.Registry, error) {
+	var out robotmodel.Registry
+	if err := c.Do(ctx, http.MethodGet, "/models", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func point(p api.Point) string {
+	return strconv.FormatFloat(p.X, 'g', -1, 64) + "," + strconv.FormatFloat(p.Y, 'g', -1, 64)
+}
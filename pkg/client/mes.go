@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// MES is a client for the MES API.
+type MES struct{ *Client }
+
+// NewMES returns a client for the MES API at baseURL (e.g. "http://localhost:8081").
+func NewMES(baseURL string) *MES { return &MES{New("mes", baseURL)} }
+
+// ProductionOrder is a manufacturing order tracked by MES.
+type ProductionOrder struct {
+	ID                string        `json:"id"`
+	ERPOrderRef       string        `json:"erp_order_ref,omitempty"`
+	SKU               string        `json:"sku"`
+	ProductID         string        `json:"product_id,omitempty"`
+	Quantity          int           `json:"quantity"`
+	QuantityCompleted int           `json:"quantity_completed"`
+	QuantityScrapped  int           `json:"quantity_scrapped"`
+	AreaID            string        `json:"area_id"`
+	ZoneID            string        `json:"zone_id,omitempty"`
+	Status            string        `json:"status"` // draft, released, in_progress, paused, completed, cancelled
+	Priority          int           `json:"priority"`
+	BOMRevision       string        `json:"bom_revision,omitempty"`
+	RoutingRevision   string        `json:"routing_revision,omitempty"`
+	FleetWorkOrderID  string        `json:"fleet_work_order_id,omitempty"`
+	CreatedAt         time.Time     `json:"created_at"`
+	ReleasedAt        *time.Time    `json:"released_at,omitempty"`
+	CompletedAt       *time.Time    `json:"completed_at,omitempty"`
+	ScrapRecords      []ScrapRecord `json:"scrap_records,omitempty"`
+}
+
+// ScrapRecord is one scrap report of a production order.
+type ScrapRecord struct {
+	Quantity   int       `json:"quantity"`
+	Reason     string    `json:"reason"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// ProductionOrderRequest is the body of POST /orders.
+type ProductionOrderRequest struct {
+	ERPOrderRef     string `json:"erp_order_ref,omitempty"`
+	SKU             string `json:"sku"`
+	ProductID       string `json:"product_id,omitempty"`
+	Quantity        int    `json:"quantity"`
+	AreaID          string `json:"area_id"`
+	ZoneID          string `json:"zone_id,omitempty"`
+	Priority        int    `json:"priority,omitempty"`
+	BOMRevision     string `json:"bom_revision,omitempty"`
+	RoutingRevision string `json:"routing_revision,omitempty"`
+}
+
+// CreateOrder creates a draft production order.
+func (c *MES) CreateOrder(ctx context.Context, req ProductionOrderRequest) (*ProductionOrder, error) {
+	var out ProductionOrder
+	if err := c.Do(ctx, http.MethodPost, "/orders", req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Orders lists the production orders, optionally of one status.
+func (c *MES) Orders(ctx context.Context, status string) ([]ProductionOrder, error) {
+	var out struct {
+		Orders []ProductionOrder `json:"orders"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/orders"+query("status", status), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Orders, nil
+}
+
+// Order returns one production order.
+func (c *MES) Order(ctx context.Context, id string) (*ProductionOrder, error) {
+	var out ProductionOrder
+	if err := c.get(ctx, "/orders", id, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *MES) orderAction(ctx context.Context, id, verb string, body interface{}) (*ProductionOrder, error) {
+	var out ProductionOrder
+	if err := c.action(ctx, "/orders", id, verb, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReleaseOrder releases a draft or paused order to the fleet as a work order.
+func (c *MES) ReleaseOrder(ctx context.Context, id string) (*ProductionOrder, error) {
+	return c.orderAction(ctx, id, "release", nil)
+}
+
+// PauseOrder pauses an order.
+func (c *MES) PauseOrder(ctx context.Context, id string) (*ProductionOrder, error) {
+	return c.orderAction(ctx, id, "pause", nil)
+}
+
+// CompleteOrder completes an order.
+func (c *MES) CompleteOrder(ctx context.Context, id string) (*ProductionOrder, error) {
+	return c.orderAction(ctx, id, "complete", nil)
+}
+
+// CancelOrder cancels an order.
+func (c *MES) CancelOrder(ctx context.Context, id string) (*ProductionOrder, error) {
+	return c.orderAction(ctx, id, "cancel", nil)
+}
+
+// ScrapOrder reports quantity of an order scrapped.
+func (c *MES) ScrapOrder(ctx context.Context, id string, quantity int, reason string) (*ProductionOrder, error) {
+	body := map[string]interface{}{"quantity": quantity, "reason": reason}
+	return c.orderAction(ctx, id, "scrap", body)
+}
+
+// TriggerFirmware starts a firmware campaign on the fleet through MES.
+func (c *MES) TriggerFirmware(ctx context.Context, seedBusy int) (message string, err error) {
+	var out struct {
+		Message string `json:"message"`
+	}
+	if err := c.Do(ctx, http.MethodPost, "/firmware/trigger", map[string]int{"seed_busy": seedBusy}, &out); err != nil {
+		return "", err
+	}
+	return out.Message, nil
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// PLM is a client for the PLM API.
+type PLM struct{ *Client }
+
+// NewPLM returns a client for the PLM API at baseURL (e.g. "http://localhost:8086").
+func NewPLM(baseURL string) *PLM { return &PLM{New("plm", baseURL)} }
+
+// Product is a product revision.
+type Product struct {
+	ID        string    `json:"id"`
+	SKU       string    `json:"sku"`
+	Name      string    `json:"name"`
+	Revision  string    `json:"revision"`
+	Status    string    `json:"status"` // draft, released, obsolete
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BOMLine is one line of a product's bill of materials.
+type BOMLine struct {
+	ID              string    `json:"id"`
+	ParentProductID string    `json:"parent_product_id"`
+	ChildSKU        string    `json:"child_sku"`
+	ChildRevision   string    `json:"child_revision,omitempty"`
+	Quantity        float64   `json:"quantity"`
+	LineNumber      int       `json:"line_number,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// ECO is an engineering change order.
+type ECO struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	ProductID   string    `json:"product_id,omitempty"`
+	Status      string    `json:"status"` // draft, approved, implemented
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreateProduct creates a product.
+func (c *PLM) CreateProduct(ctx context.Context, p Product) (*Product, error) {
+	var out Product
+	if err := c.Do(ctx, http.MethodPost, "/products", p, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateProduct replaces a product.
+func (c *PLM) UpdateProduct(ctx context.Context, p Product) (*Product, error) {
+	var out Product
+	if err := c.Do(ctx, http.MethodPut, "/products/"+url.PathEscape(p.ID), p, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Products lists the products, optionally of one status or SKU.
+func (c *PLM) Products(ctx context.Context, status, sku string) ([]Product, error) {
+	var out struct {
+		Products []Product `json:"products"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/products"+query("status", status, "sku", sku), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Products, nil
+}
+
+// Product returns one product.
+func (c *PLM) Product(ctx context.Context, id string) (*Product, error) {
+	var out Product
+	if err := c.get(ctx, "/products", id, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// BOM returns the bill of materials of a product.
+func (c *PLM) BOM(ctx context.Context, productID string) ([]BOMLine, error) {
+	var out struct {
+		BOM []BOMLine `json:"bom"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/products/"+url.PathEscape(productID)+"/bom", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.BOM, nil
+}
+
+// AddBOMLine adds a line to the bill of materials of a product.
+func (c *PLM) AddBOMLine(ctx context.Context, productID string, l BOMLine) (*BOMLine, error) {
+	var out BOMLine
+	if err := c.Do(ctx, http.MethodPost, "/products/"+url.PathEscape(productID)+"/bom", l, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteBOMLine removes a line from the bill of materials of a product.
+func (c *PLM) DeleteBOMLine(ctx context.Context, productID, lineID string) error {
+	path := "/products/" + url.PathEscape(productID) + "/bom/" + url.PathEscape(lineID)
+	return c.Do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+// CreateECO creates an engineering change order.
+func (c *PLM) CreateECO(ctx context.Context, e ECO) (*ECO, error) {
+	var out ECO
+	if err := c.Do(ctx, http.MethodPost, "/ecos", e, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateECO replaces an engineering change order, e.g. to approve it.
+func (c *PLM) UpdateECO(ctx context.Context, e ECO) (*ECO, error) {
+	var out ECO
+	if err := c.Do(ctx, http.MethodPut, "/ecos/"+url.PathEscape(e.ID), e, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ECOs lists the engineering change orders, optionally of one status or product.
+func (c *PLM) ECOs(ctx context.Context, status, productID string) ([]ECO, error) {
+	var out struct {
+		ECOs []ECO `json:"ecos"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/ecos"+query("status", status, "product_id", productID), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.ECOs, nil
+}
+
+// ECO returns one engineering change order.
+func (c *PLM) ECO(ctx context.Context, id string) (*ECO, error) {
+	var out ECO
+	if err := c.get(ctx, "/ecos", id, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// QMS is a client for the QMS API.
+type QMS struct{ *Client }
+
+// NewQMS returns a client for the QMS API at baseURL (e.g. "http://localhost:8084").
+func NewQMS(baseURL string) *QMS { return &QMS{New("qms", baseURL)} }
+
+// Inspection is an inspection of a serial or lot at a station.
+type Inspection struct {
+	ID         string    `json:"id"`
+	Serial     string    `json:"serial,omitempty"`
+	Lot        string    `json:"lot,omitempty"`
+	SKU        string    `json:"sku"`
+	StationID  string    `json:"station_id,omitempty"`
+	Result     string    `json:"result"` // pass or fail
+	Notes      string    `json:"notes,omitempty"`
+	MESOrderID string    `json:"mes_order_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// NCR is a non-conformance report.
+type NCR struct {
+	ID          string     `json:"id"`
+	Serial      string     `json:"serial,omitempty"`
+	Lot         string     `json:"lot,omitempty"`
+	SKU         string     `json:"sku"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"` // open or closed
+	CreatedAt   time.Time  `json:"created_at"`
+	ClosedAt    *time.Time `json:"closed_at,omitempty"`
+}
+
+// Hold is a quality hold on a serial or lot.
+type Hold struct {
+	ID         string     `json:"id"`
+	Serial     string     `json:"serial,omitempty"`
+	Lot        string     `json:"lot,omitempty"`
+	Reason     string     `json:"reason"`
+	HeldAt     time.Time  `json:"held_at"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+}
+
+// RecordInspection records an inspection.
+func (c *QMS) RecordInspection(ctx context.Context, in Inspection) (*Inspection, error) {
+	var out Inspection
+	if err := c.Do(ctx, http.MethodPost, "/inspections", in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Inspections lists the inspections, optionally of one serial or lot.
+func (c *QMS) Inspections(ctx context.Context, serial, lot string) ([]Inspection, error) {
+	var out struct {
+		Inspections []Inspection `json:"inspections"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/inspections"+query("serial", serial, "lot", lot), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Inspections, nil
+}
+
+// CreateNCR opens a non-conformance report.
+func (c *QMS) CreateNCR(ctx context.Context, n NCR) (*NCR, error) {
+	var out NCR
+	if err := c.Do(ctx, http.MethodPost, "/ncr", n, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// NCRs lists the non-conformance reports, optionally of one status.
+func (c *QMS) NCRs(ctx context.Context, status string) ([]NCR, error) {
+	var out struct {
+		NCRs []NCR `json:"ncrs"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/ncr"+query("status", status), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.NCRs, nil
+}
+
+// NCR returns one non-conformance report.
+func (c *QMS) NCR(ctx context.Context, id string) (*NCR, error) {
+	var out NCR
+	if err := c.get(ctx, "/ncr", id, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CloseNCR closes a non-conformance report.
+func (c *QMS) CloseNCR(ctx context.Context, id string) (*NCR, error) {
+	var out NCR
+	if err := c.action(ctx, "/ncr", id, "close", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateHold puts a serial or lot on hold.
+func (c *QMS) CreateHold(ctx context.Context, h Hold) (*Hold, error) {
+	var out Hold
+	if err := c.Do(ctx, http.MethodPost, "/holds", h, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Holds lists the holds; activeOnly leaves out released ones.
+func (c *QMS) Holds(ctx context.Context, activeOnly bool) ([]Hold, error) {
+	path := "/holds"
+	if activeOnly {
+		path += "?active=true"
+	}
+	var out struct {
+		Holds []Hold `json:"holds"`
+	}
+	if err := c.Do(ctx, http.MethodGet, path, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Holds, nil
+}
+
+// Hold returns one hold.
+func (c *QMS) Hold(ctx context.Context, id string) (*Hold, error) {
+	var out Hold
+	if err := c.get(ctx, "/holds", id, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReleaseHold releases a hold.
+func (c *QMS) ReleaseHold(ctx context.Context, id string) (*Hold, error) {
+	var out Hold
+	if err := c.action(ctx, "/holds", id, "release", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
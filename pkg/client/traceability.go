@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Traceability is a client for the traceability API.
+type Traceability struct{ *Client }
+
+// NewTraceability returns a client for the traceability API at baseURL (e.g. "http://localhost:8083").
+func NewTraceability(baseURL string) *Traceability {
+	return &Traceability{New("traceability", baseURL)}
+}
+
+// TraceRecord is one traceability event of a serial or lot.
+type TraceRecord struct {
+	ID               string            `json:"id"`
+	Serial           string            `json:"serial,omitempty"`
+	Lot              string            `json:"lot,omitempty"`
+	EventType        string            `json:"event_type"` // produced, received, shipped, component_linked, inspection, rework, scrap
+	SKU              string            `json:"sku"`
+	Quantity         int               `json:"quantity,omitempty"`
+	MESOrderID       string            `json:"mes_order_id,omitempty"`
+	WMSTaskID        string            `json:"wms_task_id,omitempty"`
+	FleetWorkOrderID string            `json:"fleet_work_order_id,omitempty"`
+	StationID        string            `json:"station_id,omitempty"`
+	ZoneID           string            `json:"zone_id,omitempty"`
+	ParentSerial     string            `json:"parent_serial,omitempty"` // assembly: this serial is part of parent
+	CreatedAt        time.Time         `json:"created_at"`
+	Extra            map[string]string `json:"extra,omitempty"`
+}
+
+// Record records an event.
+func (c *Traceability) Record(ctx context.Context, r TraceRecord) (*TraceRecord, error) {
+	var out TraceRecord
+	if err := c.Do(ctx, http.MethodPost, "/records", r, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GenealogyBySerial returns the events of a serial and of the serials assembled into it.
+func (c *Traceability) GenealogyBySerial(ctx context.Context, serial string) ([]TraceRecord, error) {
+	return c.records(ctx, "/genealogy"+query("serial", serial))
+}
+
+// GenealogyByLot returns the events of a lot.
+func (c *Traceability) GenealogyByLot(ctx context.Context, lot string) ([]TraceRecord, error) {
+	return c.records(ctx, "/genealogy"+query("lot", lot))
+}
+
+// Recall returns the events of a lot or SKU, optionally between from and to (zero for open ends).
+func (c *Traceability) Recall(ctx context.Context, lot, sku string, from, to time.Time) ([]TraceRecord, error) {
+	params := []string{"lot", lot, "sku", sku}
+	if !from.IsZero() {
+		params = append(params, "from", from.Format(time.RFC3339))
+	}
+	if !to.IsZero() {
+		params = append(params, "to", to.Format(time.RFC3339))
+	}
+	return c.records(ctx, "/recall"+query(params...))
+}
+
+// Stats returns the number of records.
+func (c *Traceability) Stats(ctx context.Context) (totalRecords int, err error) {
+	var out struct {
+		TotalRecords int `json:"total_records"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/stats", nil, &out); err != nil {
+		return 0, err
+	}
+	return out.TotalRecords, nil
+}
+
+func (c *Traceability) records(ctx context.Context, path string) ([]TraceRecord, error) {
+	var out struct {
+		Records []TraceRecord `json:"records"`
+	}
+	if err := c.Do(ctx, http.MethodGet, path, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Records, nil
+}
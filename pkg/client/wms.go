@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// WMS is a client for the WMS API.
+type WMS struct{ *Client }
+
+// NewWMS returns a client for the WMS API at baseURL (e.g. "http://localhost:8082").
+func NewWMS(baseURL string) *WMS { return &WMS{New("wms", baseURL)} }
+
+// Location is a warehouse location.
+type Location struct {
+	ID          string     `json:"id"`
+	ZoneID      string     `json:"zone_id"`
+	Type        string     `json:"type"` // receiving, storage, staging, shipping
+	Name        string     `json:"name,omitempty"`
+	Coordinates *api.Point `json:"coordinates,omitempty"`
+	NodeID      string     `json:"node_id,omitempty"` // facility map node
+}
+
+// Inventory is the quantity of a SKU (and lot) at a location.
+type Inventory struct {
+	LocationID string    `json:"location_id"`
+	SKU        string    `json:"sku"`
+	Quantity   int       `json:"quantity"`
+	Lot        string    `json:"lot,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Task is a warehouse task: pick, putaway or move.
+type Task struct {
+	ID               string     `json:"id"`
+	Type             string     `json:"type"`
+	Status           string     `json:"status"` // pending, released, in_progress, completed, cancelled
+	FromLocationID   string     `json:"from_location_id,omitempty"`
+	ToLocationID     string     `json:"to_location_id,omitempty"`
+	SKU              string     `json:"sku"`
+	Quantity         int        `json:"quantity"`
+	Lot              string     `json:"lot,omitempty"`
+	FleetWorkOrderID string     `json:"fleet_work_order_id,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ReleasedAt       *time.Time `json:"released_at,omitempty"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+}
+
+// CreateLocation creates a location.
+func (c *WMS) CreateLocation(ctx context.Context, l Location) (*Location, error) {
+	var out Location
+	if err := c.Do(ctx, http.MethodPost, "/locations", l, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Locations lists the locations.
+func (c *WMS) Locations(ctx context.Context) ([]Location, error) {
+	var out struct {
+		Locations []Location `json:"locations"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/locations", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Locations, nil
+}
+
+// Receive adds quantity of sku (and lot) to a location.
+func (c *WMS) Receive(ctx context.Context, locationID, sku string, quantity int, lot string) error {
+	body := map[string]interface{}{"location_id": locationID, "sku": sku, "quantity": quantity, "lot": lot}
+	return c.Do(ctx, http.MethodPost, "/inventory", body, nil)
+}
+
+// Inventory lists stock, optionally at one location or of one SKU.
+func (c *WMS) Inventory(ctx context.Context, locationID, sku string) ([]Inventory, error) {
+	var out struct {
+		Inventory []Inventory `json:"inventory"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/inventory"+query("location_id", locationID, "sku", sku), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Inventory, nil
+}
+
+// CreateTask creates a pending task.
+func (c *WMS) CreateTask(ctx context.Context, t Task) (*Task, error) {
+	var out Task
+	if err := c.Do(ctx, http.MethodPost, "/tasks", t, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Tasks lists the tasks, optionally of one status and type.
+func (c *WMS) Tasks(ctx context.Context, status, taskType string) ([]Task, error) {
+	var out struct {
+		Tasks []Task `json:"tasks"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/tasks"+query("status", status, "type", taskType), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Tasks, nil
+}
+
+// Task returns one task.
+func (c *WMS) Task(ctx context.Context, id string) (*Task, error) {
+	var out Task
+	if err := c.get(ctx, "/tasks", id, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *WMS) taskAction(ctx context.Context, id, verb string) (*Task, error) {
+	var out Task
+	if err := c.action(ctx, "/tasks", id, verb, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReleaseTask releases a pending task to the fleet as a work order.
+func (c *WMS) ReleaseTask(ctx context.Context, id string) (*Task, error) {
+	return c.taskAction(ctx, id, "release")
+}
+
+// CompleteTask completes a task and moves its stock.
+func (c *WMS) CompleteTask(ctx context.Context, id string) (*Task, error) {
+	return c.taskAction(ctx, id, "complete")
+}
+
+// CancelTask cancels a task.
+func (c *WMS) CancelTask(ctx context.Context, id string) (*Task, error) {
+	return c.taskAction(ctx, id, "cancel")
+}
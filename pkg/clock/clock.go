@@ -0,0 +1,60 @@
+// Package clock abstracts time for controllers and the simulator so they can run on wall-clock time or on a
+// virtual clock driven by a discrete-event scheduler.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and runs functions later. Periodic work is expressed with Every rather than tickers
+// so a virtual clock can run it without goroutines.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f once d has elapsed. On the real clock f runs in its own goroutine; on a virtual
+	// clock it runs on the goroutine that advances time.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a pending AfterFunc call.
+type Timer interface {
+	// Stop prevents the call if it has not run yet and reports whether it did so.
+	Stop() bool
+}
+
+// Real is the wall clock.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) }
+
+// Every calls f every interval until the returned stop function is called. The next call is scheduled
+// after f returns, so calls never overlap.
+func Every(c Clock, interval time.Duration, f func()) (stop func()) {
+	var (
+		mu      sync.Mutex
+		t       Timer
+		stopped bool
+	)
+	var tick func()
+	tick = func() {
+		f()
+		mu.Lock()
+		defer mu.Unlock()
+		if !stopped {
+			t = c.AfterFunc(interval, tick)
+		}
+	}
+	mu.Lock()
+	t = c.AfterFunc(interval, tick)
+	mu.Unlock()
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		stopped = true
+		t.Stop()
+	}
+}
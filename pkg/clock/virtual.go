@@ -0,0 +1,123 @@
+package clock
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Virtual is a discrete-event scheduler: time only moves when Run* is called, jumping from one scheduled
+// call to the next. Calls run one at a time in time order, ties in the order they were scheduled, so the
+// same inputs always give the same run. An 8-hour shift takes as long as its events take to process.
+type Virtual struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    uint64
+	events eventHeap
+}
+
+// NewVirtual returns a virtual clock starting at start.
+func NewVirtual(start time.Time) *Virtual {
+	return &Virtual{now: start}
+}
+
+// Now returns the virtual time.
+func (v *Virtual) Now() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.now
+}
+
+// AfterFunc schedules f at Now()+d. f runs on the goroutine calling Run*.
+func (v *Virtual) AfterFunc(d time.Duration, f func()) Timer {
+	if d < 0 {
+		d = 0
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.seq++
+	e := &event{at: v.now.Add(d), seq: v.seq, f: f, v: v}
+	heap.Push(&v.events, e)
+	return e
+}
+
+// RunUntil processes every call scheduled up to end, then sets the time to end. Returns the number of calls run.
+func (v *Virtual) RunUntil(end time.Time) int {
+	n := 0
+	for {
+		v.mu.Lock()
+		if len(v.events) == 0 || v.events[0].at.After(end) {
+			if end.After(v.now) {
+				v.now = end
+			}
+			v.mu.Unlock()
+			return n
+		}
+		e := heap.Pop(&v.events).(*event)
+		e.index = -1
+		if e.at.After(v.now) {
+			v.now = e.at
+		}
+		v.mu.Unlock()
+		e.f()
+		n++
+	}
+}
+
+// RunFor processes calls for d of virtual time.
+func (v *Virtual) RunFor(d time.Duration) int {
+	return v.RunUntil(v.Now().Add(d))
+}
+
+// Pending returns the number of scheduled calls.
+func (v *Virtual) Pending() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return len(v.events)
+}
+
+type event struct {
+	at    time.Time
+	seq   uint64
+	f     func()
+	v     *Virtual
+	index int // position in the heap; -1 once run or stopped
+}
+
+func (e *event) Stop() bool {
+	e.v.mu.Lock()
+	defer e.v.mu.Unlock()
+	if e.index < 0 {
+		return false
+	}
+	heap.Remove(&e.v.events, e.index)
+	e.index = -1
+	return true
+}
+
+type eventHeap []*event
+
+func (h eventHeap) Len() int { return len(h) }
+func (h eventHeap) Less(i, j int) bool {
+	if h[i].at.Equal(h[j].at) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].at.Before(h[j].at)
+}
+func (h eventHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *eventHeap) Push(x interface{}) {
+	e := x.(*event)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *eventHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
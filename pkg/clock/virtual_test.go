@@ -0,0 +1,79 @@
+package clock
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2025, 1, 6, 6, 0, 0, 0, time.UTC)
+
+func TestVirtualOrder(t *testing.T) {
+	v := NewVirtual(start)
+	var got []string
+	at := func(name string) func() {
+		return func() { got = append(got, name+"@"+v.Now().Sub(start).String()) }
+	}
+	v.AfterFunc(3*time.Second, at("c"))
+	v.AfterFunc(time.Second, at("a"))
+	v.AfterFunc(2*time.Second, at("b1"))
+	v.AfterFunc(2*time.Second, at("b2")) // same time: scheduling order
+	v.AfterFunc(-time.Second, at("now")) // negative delays run at Now
+	v.AfterFunc(time.Second, func() {
+		// Scheduled while running: at 1s+1s, after b1 and b2 that were scheduled first.
+		v.AfterFunc(time.Second, at("b3"))
+	})
+	if n := v.RunUntil(start.Add(10 * time.Second)); n != 7 {
+		t.Errorf("RunUntil ran %d calls, want 7", n)
+	}
+	want := []string{"now@0s", "a@1s", "b1@2s", "b2@2s", "b3@2s", "c@3s"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+func TestVirtualStop(t *testing.T) {
+	v := NewVirtual(start)
+	ran := false
+	timer := v.AfterFunc(time.Second, func() { ran = true })
+	kept := v.AfterFunc(2*time.Second, func() {})
+	if !timer.Stop() {
+		t.Error("Stop of a pending call = false")
+	}
+	if timer.Stop() {
+		t.Error("second Stop = true")
+	}
+	if v.Pending() != 1 {
+		t.Errorf("Pending = %d, want 1", v.Pending())
+	}
+	v.RunFor(time.Minute)
+	if ran {
+		t.Error("stopped call ran")
+	}
+	if kept.Stop() {
+		t.Error("Stop after the call ran = true")
+	}
+}
+
+func TestVirtualRunFor(t *testing.T) {
+	v := NewVirtual(start)
+	var ticks []time.Duration
+	stop := Every(v, 10*time.Second, func() { ticks = append(ticks, v.Now().Sub(start)) })
+	if n := v.RunFor(35 * time.Second); n != 3 {
+		t.Errorf("RunFor ran %d calls, want 3", n)
+	}
+	if got := v.Now(); !got.Equal(start.Add(35 * time.Second)) {
+		t.Errorf("Now = %v, want start+35s", got)
+	}
+	want := []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second}
+	if !reflect.DeepEqual(ticks, want) {
+		t.Errorf("ticks = %v, want %v", ticks, want)
+	}
+	stop()
+	if n := v.RunFor(time.Hour); n != 0 {
+		t.Errorf("RunFor after stop ran %d calls", n)
+	}
+	if v.RunUntil(start) != 0 || !v.Now().Equal(start.Add(35*time.Second+time.Hour)) {
+		t.Error("RunUntil a past time moved the clock back")
+	}
+}
@@ -0,0 +1,180 @@
+package cmdsign
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/clock"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+var testNow = time.Date(2025, 1, 6, 6, 0, 0, 0, time.UTC)
+
+func newKey(t *testing.T, seed byte) ed25519.PrivateKey {
+	t.Helper()
+	s := make([]byte, ed25519.SeedSize)
+	s[0] = seed
+	return ed25519.NewKeyFromSeed(s)
+}
+
+func command(created time.Time) *api.RobotCommand {
+	return &api.RobotCommand{
+		ID:        "cmd-1",
+		RobotID:   "robot-1",
+		Type:      api.RobotCommandTypeFirmwareUpdate,
+		Payload:   json.RawMessage(`{"download_url":"https://example.com/fw.bin"}`),
+		CreatedAt: created,
+	}
+}
+
+func reason(err error) string {
+	var rej *Rejection
+	if errors.As(err, &rej) {
+		return rej.Reason
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func TestVerify(t *testing.T) {
+	signer := NewSigner("zone-1-key", "zone-1", newKey(t, 1))
+	forger := NewSigner("zone-1-key", "zone-1", newKey(t, 2))
+	other := NewSigner("zone-2-key", "zone-2", newKey(t, 3))
+	for _, tc := range []struct {
+		name   string
+		cmd    func() *api.RobotCommand
+		zone   api.ZoneID
+		policy Policy
+		want   string
+	}{
+		{"signed", func() *api.RobotCommand { c := command(testNow); signer.Sign(c); return c }, "zone-1", Policy{}, ""},
+		{"broadcast", func() *api.RobotCommand { c := command(testNow); c.RobotID = ""; signer.Sign(c); return c }, "", Policy{}, ""},
+		{"unsigned", func() *api.RobotCommand { return command(testNow) }, "zone-1", Policy{}, ""},
+		{"unsigned required", func() *api.RobotCommand { return command(testNow) }, "zone-1", Policy{Require: true}, api.SecurityReasonUnsigned},
+		{"tampered payload", func() *api.RobotCommand {
+			c := command(testNow)
+			signer.Sign(c)
+			c.Payload = json.RawMessage(`{"download_url":"https://evil.example.com/fw.bin"}`)
+			return c
+		}, "zone-1", Policy{}, api.SecurityReasonBadSignature},
+		{"tampered robot", func() *api.RobotCommand { c := command(testNow); signer.Sign(c); c.RobotID = "robot-2"; return c }, "zone-1", Policy{}, api.SecurityReasonBadSignature},
+		{"other key under the same ID", func() *api.RobotCommand { c := command(testNow); forger.Sign(c); return c }, "zone-1", Policy{}, api.SecurityReasonBadSignature},
+		{"unknown key", func() *api.RobotCommand { c := command(testNow); other.Sign(c); c.KeyID = "nobody"; return c }, "zone-1", Policy{}, api.SecurityReasonUnknownKey},
+		{"key for another zone", func() *api.RobotCommand { c := command(testNow); c.ZoneID = "zone-1"; other.Sign(c); return c }, "zone-1", Policy{}, api.SecurityReasonWrongZone},
+		{"robot in another zone", func() *api.RobotCommand { c := command(testNow); signer.Sign(c); return c }, "zone-2", Policy{}, api.SecurityReasonWrongZone},
+		{"stale", func() *api.RobotCommand { c := command(testNow.Add(-31 * time.Second)); signer.Sign(c); return c }, "zone-1", Policy{}, api.SecurityReasonStale},
+		{"just fresh", func() *api.RobotCommand { c := command(testNow.Add(-30 * time.Second)); signer.Sign(c); return c }, "zone-1", Policy{}, ""},
+		{"from the future", func() *api.RobotCommand { c := command(testNow.Add(31 * time.Second)); signer.Sign(c); return c }, "zone-1", Policy{}, api.SecurityReasonStale},
+		{"older within max age", func() *api.RobotCommand { c := command(testNow.Add(-time.Minute)); signer.Sign(c); return c }, "zone-1", Policy{MaxAge: 2 * time.Minute}, ""},
+	} {
+		v := NewVerifier(NewKeyring(signer.Key(), other.Key()), tc.policy)
+		v.SetClock(clock.NewVirtual(testNow))
+		if got := reason(v.Verify(context.Background(), tc.cmd(), tc.zone)); got != tc.want {
+			t.Errorf("%s: rejected %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	signer := NewSigner("zone-1-key", "zone-1", newKey(t, 1))
+	clk := clock.NewVirtual(testNow)
+	v := NewVerifier(NewKeyring(signer.Key()), Policy{})
+	v.SetClock(clk)
+	ctx := context.Background()
+
+	cmd := command(testNow)
+	signer.Sign(cmd)
+	if err := v.Verify(ctx, cmd, "zone-1"); err != nil {
+		t.Fatal(err)
+	}
+	clk.RunFor(10 * time.Second)
+	if got := reason(v.Verify(ctx, cmd, "zone-1")); got != api.SecurityReasonReplay {
+		t.Errorf("same command again: rejected %q, want replay", got)
+	}
+	// The same ID for another robot, or reissued later, is another command.
+	again := command(testNow.Add(5 * time.Second))
+	signer.Sign(again)
+	if err := v.Verify(ctx, again, "zone-1"); err != nil {
+		t.Errorf("reissued command: %v", err)
+	}
+	// Once out of the window a replay is stale, however long ago its ID was forgotten.
+	clk.RunFor(time.Minute)
+	if got := reason(v.Verify(ctx, cmd, "zone-1")); got != api.SecurityReasonStale {
+		t.Errorf("old command again: rejected %q, want stale", got)
+	}
+}
+
+func TestSigningPublisher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signer := NewSigner("zone-1-key", "zone-1", newKey(t, 1))
+	bus := messaging.NewMemoryBus()
+	var got api.RobotCommand
+	if err := bus.Subscribe(ctx, messaging.TopicRobotCommands, func(_ string, value []byte) error {
+		return json.Unmarshal(value, &got)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	cmd := command(testNow)
+	if err := signer.Publisher(messaging.NewRobotCommandPublisher(bus)).PublishRobotCommand(ctx, cmd); err != nil {
+		t.Fatal(err)
+	}
+	if len(cmd.Signature) != 0 || cmd.KeyID != "" {
+		t.Errorf("caller's command was signed")
+	}
+	v := NewVerifier(NewKeyring(signer.Key()), Policy{Require: true})
+	v.SetClock(clock.NewVirtual(testNow))
+	if err := v.Verify(ctx, &got, "zone-1"); err != nil {
+		t.Errorf("published command: %v", err)
+	}
+}
+
+func TestAdmit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := messaging.NewMemoryBus()
+	var events []api.SecurityEvent
+	if err := bus.Subscribe(ctx, messaging.TopicSecurityEvents, func(_ string, value []byte) error {
+		var ev api.SecurityEvent
+		if err := json.Unmarshal(value, &ev); err != nil {
+			return err
+		}
+		events = append(events, ev)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	v := NewVerifier(NewKeyring(), Policy{Require: true})
+	v.SetClock(clock.NewVirtual(testNow))
+	v.SetSecurityEventPublisher(messaging.NewSecurityEventPublisher(bus))
+
+	for _, tc := range []struct {
+		cmdType string
+		want    bool
+	}{
+		{api.RobotCommandTypeFirmwareUpdate, false},
+		{api.RobotCommandTypeReset, false},
+		{api.RobotCommandTypeEStop, true},
+	} {
+		cmd := *command(testNow)
+		cmd.Type = tc.cmdType
+		if got := v.Admit(cmd, "zone-1"); got != tc.want {
+			t.Errorf("Admit unsigned %s = %v, want %v", tc.cmdType, got, tc.want)
+		}
+	}
+	if len(events) != 3 || events[0].Reason != api.SecurityReasonUnsigned || events[0].ZoneID != "zone-1" || !events[2].Executed || events[1].Executed {
+		t.Errorf("security events %+v", events)
+	}
+
+	var none *Verifier
+	if !none.Admit(*command(testNow), "zone-1") {
+		t.Errorf("nil Verifier dropped a command")
+	}
+}
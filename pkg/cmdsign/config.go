@@ -0,0 +1,94 @@
+package cmdsign
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// SigningConfig is a zone's "command_signing" section. Signing is on when a private key is set.
+type SigningConfig struct {
+	KeyID      string `yaml:"key_id"`      // name edges know the key by; default the zone ID
+	PrivateKey string `yaml:"private_key"` // Ed25519 PEM or base64 seed, file or inline; env COMMAND_SIGNING_KEY overrides
+}
+
+// ApplyEnv overrides the private key from COMMAND_SIGNING_KEY.
+func (c *SigningConfig) ApplyEnv() {
+	if k := os.Getenv("COMMAND_SIGNING_KEY"); k != "" {
+		c.PrivateKey = k
+	}
+}
+
+// Signer returns the signer for zone, or nil if c sets no private key.
+func (c SigningConfig) Signer(zone api.ZoneID) (*Signer, error) {
+	if c.PrivateKey == "" {
+		return nil, nil
+	}
+	key, err := LoadPrivateKey(c.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("command_signing.private_key: %w", err)
+	}
+	id := c.KeyID
+	if id == "" {
+		id = string(zone)
+	}
+	return NewSigner(id, zone, key), nil
+}
+
+// VerificationConfig is an edge's "command_verification" section. Verification is on when keys are
+// listed or signatures are required (keys then come from the state store, where available).
+type VerificationConfig struct {
+	Require bool        `yaml:"require"` // reject unsigned commands; off while zones are being switched to signing
+	MaxAge  string      `yaml:"max_age"` // freshness window for created_at; default 30s
+	Keys    []KeyConfig `yaml:"keys"`
+}
+
+// KeyConfig is a zone's public key as an edge trusts it.
+type KeyConfig struct {
+	KeyID     string   `yaml:"key_id"`
+	PublicKey string   `yaml:"public_key"` // Ed25519 PEM or base64, file or inline
+	Zones     []string `yaml:"zones"`      // zones the key may sign for; empty = any
+}
+
+// Enabled reports whether c turns verification on.
+func (c VerificationConfig) Enabled() bool {
+	return c.Require || len(c.Keys) > 0
+}
+
+// Verifier returns the verifier for c, or nil if c does not enable verification.
+func (c VerificationConfig) Verifier() (*Verifier, error) {
+	if !c.Enabled() {
+		return nil, nil
+	}
+	p := Policy{Require: c.Require}
+	if c.MaxAge != "" {
+		d, err := time.ParseDuration(c.MaxAge)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("command_verification.max_age: invalid duration %q", c.MaxAge)
+		}
+		p.MaxAge = d
+	}
+	keys := make([]Key, 0, len(c.Keys))
+	seen := make(map[string]bool, len(c.Keys))
+	for i, kc := range c.Keys {
+		if kc.KeyID == "" {
+			return nil, fmt.Errorf("command_verification.keys[%d]: key_id required", i)
+		}
+		if seen[kc.KeyID] {
+			return nil, fmt.Errorf("command_verification.keys[%d]: duplicate key_id %q", i, kc.KeyID)
+		}
+		seen[kc.KeyID] = true
+		pub, err := ParsePublicKey(kc.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("command_verification.keys[%d] (%s): %w", i, kc.KeyID, err)
+		}
+		k := Key{ID: kc.KeyID, Public: pub}
+		for _, z := range kc.Zones {
+			k.Zones = append(k.Zones, api.ZoneID(z))
+		}
+		keys = append(keys, k)
+	}
+	return NewVerifier(NewKeyring(keys...), p), nil
+}
@@ -0,0 +1,225 @@
+package cmdsign
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/state"
+)
+
+// StorePrefix is where zones publish their public keys in a state.Store, one entry per key ID.
+const StorePrefix = "command_keys/"
+
+// Key is a zone's public key. A key with no zones is valid for every zone.
+type Key struct {
+	ID     string
+	Zones  []api.ZoneID
+	Public ed25519.PublicKey
+}
+
+func (k Key) validFor(zone api.ZoneID) bool {
+	if len(k.Zones) == 0 {
+		return true
+	}
+	for _, z := range k.Zones {
+		if z == zone {
+			return true
+		}
+	}
+	return false
+}
+
+// storedKey is a Key as JSON in the state store.
+type storedKey struct {
+	KeyID     string       `json:"key_id"`
+	Zones     []api.ZoneID `json:"zones,omitempty"`
+	PublicKey string       `json:"public_key"` // base64
+}
+
+// PublishKey puts k in store for edges using Keyring.UseStore.
+func PublishKey(ctx context.Context, store state.Store, k Key) error {
+	data, err := json.Marshal(storedKey{KeyID: k.ID, Zones: k.Zones, PublicKey: base64.StdEncoding.EncodeToString(k.Public)})
+	if err != nil {
+		return err
+	}
+	return store.Put(ctx, StorePrefix+k.ID, data)
+}
+
+func decodeStoredKey(data []byte) (Key, error) {
+	var sk storedKey
+	if err := json.Unmarshal(data, &sk); err != nil {
+		return Key{}, err
+	}
+	pub, err := ParsePublicKey(sk.PublicKey)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{ID: sk.KeyID, Zones: sk.Zones, Public: pub}, nil
+}
+
+// Keyring holds the public keys an edge accepts, by key ID. Safe for concurrent use.
+type Keyring struct {
+	mu     sync.RWMutex
+	keys   map[string]Key
+	static map[string]bool // keys from config; the store does not replace or remove them
+	store  state.Store
+}
+
+// NewKeyring returns a keyring with the given (configured) keys.
+func NewKeyring(keys ...Key) *Keyring {
+	r := &Keyring{keys: make(map[string]Key, len(keys)), static: make(map[string]bool, len(keys))}
+	for _, k := range keys {
+		r.keys[k.ID] = k
+		r.static[k.ID] = true
+	}
+	return r
+}
+
+// UseStore also takes keys from store: ones under StorePrefix are looked up when first used and kept up to
+// date (replaced or revoked) by a watch until ctx is done.
+func (r *Keyring) UseStore(ctx context.Context, store state.Store) error {
+	events, err := store.Watch(ctx, StorePrefix)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.store = store
+	r.mu.Unlock()
+	go func() {
+		for ev := range events {
+			id := strings.TrimPrefix(ev.Key, StorePrefix)
+			if ev.Delete {
+				r.remove(id)
+				continue
+			}
+			k, err := decodeStoredKey(ev.Value)
+			if err != nil || k.ID != id {
+				log.Printf("cmdsign: ignoring key %s from store: %v", id, err)
+				continue
+			}
+			r.set(k)
+		}
+	}()
+	return nil
+}
+
+func (r *Keyring) set(k Key) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.static[k.ID] {
+		r.keys[k.ID] = k
+	}
+}
+
+func (r *Keyring) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.static[id] {
+		delete(r.keys, id)
+	}
+}
+
+// Lookup returns the key with id, reading it from the store if it is not known yet.
+func (r *Keyring) Lookup(ctx context.Context, id string) (Key, bool) {
+	r.mu.RLock()
+	k, ok := r.keys[id]
+	store := r.store
+	r.mu.RUnlock()
+	if ok || store == nil || id == "" {
+		return k, ok
+	}
+	data, err := store.Get(ctx, StorePrefix+id)
+	if err != nil || data == nil {
+		return Key{}, false
+	}
+	k, err = decodeStoredKey(data)
+	if err != nil || k.ID != id {
+		return Key{}, false
+	}
+	r.set(k)
+	return k, true
+}
+
+// LoadPrivateKey reads an Ed25519 private key: a PKCS#8 PEM block ("openssl genpkey -algorithm ed25519"),
+// or the base64 32-byte seed, given inline or as a file.
+func LoadPrivateKey(s string) (ed25519.PrivateKey, error) {
+	data, err := keyMaterial(s)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "PRIVATE KEY" {
+			return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+		}
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		ed, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("not an Ed25519 key")
+		}
+		return ed, nil
+	}
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("want a PEM private key or a base64 %d-byte seed", ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// ParsePublicKey reads an Ed25519 public key: a PKIX PEM block ("openssl pkey -pubout") or the base64
+// 32-byte key, given inline or as a file.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	data, err := keyMaterial(s)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "PUBLIC KEY" {
+			return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		ed, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("not an Ed25519 key")
+		}
+		return ed, nil
+	}
+	pub, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("want a PEM public key or a base64 %d-byte key", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(pub), nil
+}
+
+// keyMaterial returns the contents of the file s if there is one, else s itself (a PEM block or base64).
+func keyMaterial(s string) ([]byte, error) {
+	t := strings.TrimSpace(s)
+	if t == "" {
+		return nil, fmt.Errorf("empty key")
+	}
+	if strings.HasPrefix(t, "-----BEGIN") {
+		return []byte(t), nil
+	}
+	if _, err := os.Stat(t); err == nil {
+		return os.ReadFile(t)
+	}
+	if _, err := base64.StdEncoding.DecodeString(t); err != nil {
+		return nil, fmt.Errorf("not a file, PEM block or base64 key")
+	}
+	return []byte(t), nil
+}
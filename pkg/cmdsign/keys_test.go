@@ -0,0 +1,146 @@
+package cmdsign
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/state"
+)
+
+func TestLoadKeys(t *testing.T) {
+	key := newKey(t, 1)
+	pub := key.Public().(ed25519.PublicKey)
+	privDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}))
+	pubPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+	dir := t.TempDir()
+	privFile, pubFile := filepath.Join(dir, "zone.key"), filepath.Join(dir, "zone.pub")
+	if err := os.WriteFile(privFile, []byte(privPEM), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pubFile, []byte(pubPEM), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{privPEM, privFile, base64.StdEncoding.EncodeToString(key.Seed())} {
+		got, err := LoadPrivateKey(s)
+		if err != nil || !got.Equal(key) {
+			t.Errorf("LoadPrivateKey(%.20q) = %v", s, err)
+		}
+	}
+	for _, s := range []string{pubPEM, pubFile, base64.StdEncoding.EncodeToString(pub)} {
+		got, err := ParsePublicKey(s)
+		if err != nil || !got.Equal(pub) {
+			t.Errorf("ParsePublicKey(%.20q) = %v", s, err)
+		}
+	}
+	for _, s := range []string{"", "not a key!", base64.StdEncoding.EncodeToString([]byte("short")), pubPEM, filepath.Join(dir, "missing")} {
+		if _, err := LoadPrivateKey(s); err == nil {
+			t.Errorf("LoadPrivateKey(%.20q) = nil error", s)
+		}
+	}
+	if _, err := ParsePublicKey(privPEM); err == nil {
+		t.Errorf("ParsePublicKey(private PEM) = nil error")
+	}
+}
+
+func TestConfig(t *testing.T) {
+	key := newKey(t, 1)
+	seed := base64.StdEncoding.EncodeToString(key.Seed())
+	signer, err := SigningConfig{PrivateKey: seed}.Signer("zone-1")
+	if err != nil || signer.Key().ID != "zone-1" {
+		t.Fatalf("Signer without key_id = %+v, %v", signer, err)
+	}
+	if s, err := (SigningConfig{}).Signer("zone-1"); s != nil || err != nil {
+		t.Errorf("Signer without a key = %v, %v; want nil, nil", s, err)
+	}
+
+	pub := base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	v, err := VerificationConfig{MaxAge: "1m", Keys: []KeyConfig{{KeyID: "zone-1", PublicKey: pub, Zones: []string{"zone-1"}}}}.Verifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.policy.MaxAge != time.Minute {
+		t.Errorf("max age %v", v.policy.MaxAge)
+	}
+	if k, ok := v.Keys().Lookup(context.Background(), "zone-1"); !ok || !k.validFor("zone-1") || k.validFor("zone-2") {
+		t.Errorf("configured key %+v, %v", k, ok)
+	}
+	if v, err := (VerificationConfig{}).Verifier(); v != nil || err != nil {
+		t.Errorf("Verifier when disabled = %v, %v; want nil, nil", v, err)
+	}
+	for name, c := range map[string]VerificationConfig{
+		"bad max age":  {MaxAge: "-1s", Require: true},
+		"no key ID":    {Keys: []KeyConfig{{PublicKey: pub}}},
+		"duplicate ID": {Keys: []KeyConfig{{KeyID: "a", PublicKey: pub}, {KeyID: "a", PublicKey: pub}}},
+		"bad key":      {Keys: []KeyConfig{{KeyID: "a", PublicKey: "AAAA"}}},
+	} {
+		if _, err := c.Verifier(); err == nil {
+			t.Errorf("%s: Verifier = nil error", name)
+		}
+	}
+}
+
+func TestKeyringStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := state.NewMemoryStore()
+	zone1 := NewSigner("zone-1", "zone-1", newKey(t, 1)).Key()
+	static := NewSigner("static", "zone-9", newKey(t, 9)).Key()
+	if err := PublishKey(ctx, store, zone1); err != nil {
+		t.Fatal(err)
+	}
+	r := NewKeyring(static)
+	if err := r.UseStore(ctx, store); err != nil {
+		t.Fatal(err)
+	}
+
+	if k, ok := r.Lookup(ctx, "zone-1"); !ok || !k.Public.Equal(zone1.Public) {
+		t.Fatalf("key from store %+v, %v", k, ok)
+	}
+	if _, ok := r.Lookup(ctx, "zone-2"); ok {
+		t.Errorf("found a key never published")
+	}
+
+	// A configured key stays whatever the store says; a revoked one goes. The watch delivers in order, so
+	// once the revocation is in, so is the replacement.
+	replaced := NewSigner("static", "zone-9", newKey(t, 10)).Key()
+	if err := PublishKey(ctx, store, replaced); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, StorePrefix+"zone-1"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		_, ok := r.keys["zone-1"]
+		return !ok
+	})
+	if k, ok := r.Lookup(ctx, "static"); !ok || !k.Public.Equal(static.Public) {
+		t.Errorf("configured key replaced from store")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+	}
+}
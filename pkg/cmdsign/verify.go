@@ -0,0 +1,165 @@
+package cmdsign
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+	"github.com/robotfleetos/robotfleetos/pkg/clock"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+// DefaultMaxAge is how old (or how far ahead, for clock skew) a command may be by default.
+const DefaultMaxAge = 30 * time.Second
+
+// Policy configures a Verifier.
+type Policy struct {
+	// Require rejects unsigned commands. Without it they run, so edges can be rolled out before zones
+	// sign; signed commands are verified either way.
+	Require bool
+	// MaxAge bounds |now - CreatedAt|; commands seen within it are remembered to catch replays.
+	MaxAge time.Duration
+}
+
+// Rejection is why a command failed verification; Reason is one of api.SecurityReason*.
+type Rejection struct {
+	Reason string
+	Detail string
+}
+
+func (r *Rejection) Error() string {
+	return "command " + r.Reason + ": " + r.Detail
+}
+
+// Verifier checks robot commands at the edge. Safe for concurrent use; a nil Verifier admits everything.
+type Verifier struct {
+	keys   *Keyring
+	policy Policy
+	clock  clock.Clock
+	pub    *messaging.SecurityEventPublisher
+
+	mu     sync.Mutex
+	seen   map[seenKey]time.Time // verified commands -> CreatedAt, kept until older than MaxAge
+	pruned time.Time
+}
+
+type seenKey struct {
+	id      api.TaskID
+	robotID api.RobotID
+	created int64
+}
+
+// NewVerifier returns a verifier of commands signed by keys.
+func NewVerifier(keys *Keyring, p Policy) *Verifier {
+	if p.MaxAge <= 0 {
+		p.MaxAge = DefaultMaxAge
+	}
+	return &Verifier{keys: keys, policy: p, clock: clock.Real, seen: make(map[seenKey]time.Time)}
+}
+
+// SetClock sets the time source for the freshness check (default clock.Real). Call before use.
+func (v *Verifier) SetClock(clk clock.Clock) {
+	v.clock = clk
+}
+
+// Keys returns the keyring, e.g. to add keys from a state store with UseStore.
+func (v *Verifier) Keys() *Keyring {
+	return v.keys
+}
+
+// SetSecurityEventPublisher sets where rejected commands are reported. Without it they are only logged.
+func (v *Verifier) SetSecurityEventPublisher(p *messaging.SecurityEventPublisher) {
+	v.pub = p
+}
+
+// Verify checks cmd for a robot in zone (empty for a zone-wide broadcast, whose own zone is checked
+// against the key): signature, key valid for the command's zone, freshness and replay. A verified command
+// is remembered, so verifying the same message twice reports a replay.
+func (v *Verifier) Verify(ctx context.Context, cmd *api.RobotCommand, zone api.ZoneID) error {
+	if len(cmd.Signature) == 0 {
+		if v.policy.Require {
+			return &Rejection{api.SecurityReasonUnsigned, "signature required"}
+		}
+		return nil
+	}
+	k, ok := v.keys.Lookup(ctx, cmd.KeyID)
+	if !ok {
+		return &Rejection{api.SecurityReasonUnknownKey, fmt.Sprintf("key %q", cmd.KeyID)}
+	}
+	if !ed25519.Verify(k.Public, SigningInput(cmd), cmd.Signature) {
+		return &Rejection{api.SecurityReasonBadSignature, fmt.Sprintf("key %q", cmd.KeyID)}
+	}
+	if !k.validFor(cmd.ZoneID) {
+		return &Rejection{api.SecurityReasonWrongZone, fmt.Sprintf("key %q not valid for zone %q", cmd.KeyID, cmd.ZoneID)}
+	}
+	if zone != "" && cmd.ZoneID != zone {
+		return &Rejection{api.SecurityReasonWrongZone, fmt.Sprintf("signed for zone %q, robot is in %q", cmd.ZoneID, zone)}
+	}
+	now := v.clock.Now()
+	if age := now.Sub(cmd.CreatedAt); age > v.policy.MaxAge {
+		return &Rejection{api.SecurityReasonStale, fmt.Sprintf("created %s ago", age.Round(time.Millisecond))}
+	} else if age < -v.policy.MaxAge {
+		return &Rejection{api.SecurityReasonStale, fmt.Sprintf("created %s in the future", (-age).Round(time.Millisecond))}
+	}
+	key := seenKey{cmd.ID, cmd.RobotID, cmd.CreatedAt.UnixNano()}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if now.Sub(v.pruned) > v.policy.MaxAge {
+		for k, created := range v.seen {
+			if now.Sub(created) > v.policy.MaxAge {
+				delete(v.seen, k)
+			}
+		}
+		v.pruned = now
+	}
+	if _, dup := v.seen[key]; dup {
+		return &Rejection{api.SecurityReasonReplay, fmt.Sprintf("command %s already received", cmd.ID)}
+	}
+	v.seen[key] = cmd.CreatedAt
+	return nil
+}
+
+// Admit verifies cmd as Verify does, reports a failure as a security event and returns whether the edge
+// should run cmd. Stop commands (ESTOP, PROTECTIVE_STOP, STOP) run even if they fail: a forged stop only
+// stops a robot, while dropping a genuine one is unsafe. RESET and everything else are dropped.
+func (v *Verifier) Admit(cmd api.RobotCommand, zone api.ZoneID) bool {
+	if v == nil {
+		return true
+	}
+	ctx := context.Background()
+	err := v.Verify(ctx, &cmd, zone)
+	if err == nil {
+		return true
+	}
+	rej, _ := err.(*Rejection)
+	stop := api.IsSafetyCommand(cmd.Type) && cmd.Type != api.RobotCommandTypeReset
+	ev := &api.SecurityEvent{
+		RobotID:   cmd.RobotID,
+		ZoneID:    cmd.ZoneID,
+		CommandID: cmd.ID,
+		Command:   cmd.Type,
+		KeyID:     cmd.KeyID,
+		Reason:    rej.Reason,
+		Detail:    rej.Detail,
+		Executed:  stop,
+		At:        v.clock.Now().UTC(),
+	}
+	if ev.ZoneID == "" {
+		ev.ZoneID = zone
+	}
+	action := "dropped"
+	if stop {
+		action = "run anyway"
+	}
+	log.Printf("cmdsign: %s %s for robot %q: %v (%s)", cmd.Type, cmd.ID, cmd.RobotID, err, action)
+	if v.pub != nil {
+		if err := v.pub.PublishSecurityEvent(ctx, ev); err != nil {
+			log.Printf("cmdsign: publish security event: %v", err)
+		}
+	}
+	return stop
+}
@@ -0,0 +1,210 @@
+// Package config reads the YAML config files of every layer and service strictly and reloads them while a
+// process runs.
+//
+// Decode rejects keys the config struct does not have, with their line and the closest known key, so a typo
+// such as "robot:" for "robots:" fails at start instead of leaving the default in place. Top-level sections
+// of other layers (see Sections) are skipped, so one file can configure several, as configs/default.yaml does.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Sections are the top-level sections of all layer and service configs.
+var Sections = []string{
+	"fleet", "area", "zone", "edge", "messaging", "state", "auth", "audit", "idempotency", "storage",
+	"mes", "erp", "wms", "cmms", "qms", "plm", "traceability",
+}
+
+// Load reads the YAML file at path into v (see Decode).
+func Load(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := Decode(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Decode unmarshals YAML into v, a pointer to a config struct, keeping the values v already holds for keys
+// the YAML does not set. Unknown keys are errors, all of them reported at once; at the top level, the
+// Sections of other configs are skipped.
+func Decode(data []byte, v interface{}) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		return nil // empty file
+	}
+	if errs := checkFields(doc.Content[0], reflect.TypeOf(v), "", true); len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return doc.Decode(v)
+}
+
+// Duration parses the duration setting name, e.g. "5s". Empty takes def; zero and negative are errors.
+func Duration(name, s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s: invalid duration %q", name, s)
+	}
+	return d, nil
+}
+
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// checkFields reports the keys of mapping nodes in n that have no field in t, recursing into the values of
+// known keys, sequences and maps. Values of the wrong type are left to Decode.
+func checkFields(n *yaml.Node, t reflect.Type, path string, top bool) []error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+	var errs []error
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		fields, open := yamlFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, val := n.Content[i], n.Content[i+1]
+			ft, ok := fields[k.Value]
+			switch {
+			case ok:
+				errs = append(errs, checkFields(val, ft, join(path, k.Value), false)...)
+			case open || k.Value == "<<" || top && isSection(k.Value):
+			default:
+				msg := fmt.Sprintf("line %d: unknown field %s", k.Line, join(path, k.Value))
+				if s := closest(k.Value, fields); s != "" {
+					msg += fmt.Sprintf(" (did you mean %s?)", s)
+				}
+				errs = append(errs, errors.New(msg))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if n.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for i, c := range n.Content {
+			errs = append(errs, checkFields(c, t.Elem(), fmt.Sprintf("%s[%d]", path, i), false)...)
+		}
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			errs = append(errs, checkFields(n.Content[i+1], t.Elem(), join(path, n.Content[i].Value), false)...)
+		}
+	}
+	return errs
+}
+
+// yamlFields returns the keys of struct t with their types, including those of inlined structs. open is
+// true if t inlines a map, which takes any key.
+func yamlFields(t reflect.Type) (fields map[string]reflect.Type, open bool) {
+	fields = make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(","+opts+",", ",inline,") {
+			ft := f.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Map {
+				open = true
+				continue
+			}
+			inner, innerOpen := yamlFields(ft)
+			for k, v := range inner {
+				fields[k] = v
+			}
+			open = open || innerOpen
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields, open
+}
+
+func isSection(key string) bool {
+	for _, s := range Sections {
+		if key == s {
+			return true
+		}
+	}
+	return false
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// closest returns the known key nearest to key by edit distance, or "" if none is close enough to be a typo.
+func closest(key string, fields map[string]reflect.Type) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	best, bestDist := "", min(len(key)/2, 3)+1
+	for _, name := range names {
+		if d := distance(key, name); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
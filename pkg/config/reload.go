@@ -0,0 +1,112 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Watch calls reload when the process gets SIGHUP and, if poll is positive, when the file at path changes
+// (its size or modification time, checked every poll). It blocks until ctx is done.
+func Watch(ctx context.Context, path string, poll time.Duration, reload func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	var tick <-chan time.Time
+	if poll > 0 {
+		t := time.NewTicker(poll)
+		defer t.Stop()
+		tick = t.C
+	}
+	last, _ := os.Stat(path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			last, _ = os.Stat(path)
+			reload()
+		case <-tick:
+			fi, err := os.Stat(path)
+			if err != nil || last != nil && fi.Size() == last.Size() && fi.ModTime().Equal(last.ModTime()) {
+				continue
+			}
+			last = fi
+			reload()
+		}
+	}
+}
+
+// PollInterval returns how often Watch checks config files for changes, from env CONFIG_WATCH (e.g. "5s").
+// Unset, files are only reloaded on SIGHUP.
+func PollInterval() time.Duration {
+	s := os.Getenv("CONFIG_WATCH")
+	if s == "" {
+		return 0
+	}
+	d, err := Duration("CONFIG_WATCH", s, 0)
+	if err != nil {
+		log.Printf("config: %v; reloading on SIGHUP only", err)
+	}
+	return d
+}
+
+// Changed returns the settings that differ between a and b, two values of the same config type, as YAML
+// paths such as "zone.robots". Structs are compared field by field; anything else as a whole.
+func Changed(a, b interface{}) []string {
+	return changed(reflect.ValueOf(a), reflect.ValueOf(b), "")
+}
+
+func changed(a, b reflect.Value, path string) []string {
+	for a.Kind() == reflect.Ptr {
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				return []string{path}
+			}
+			return nil
+		}
+		a, b = a.Elem(), b.Elem()
+	}
+	if a.Kind() != reflect.Struct {
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return nil
+		}
+		return []string{path}
+	}
+	var out []string
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		p := path
+		if !strings.Contains(","+opts+",", ",inline,") {
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			p = join(path, name)
+		}
+		out = append(out, changed(a.Field(i), b.Field(i), p)...)
+	}
+	return out
+}
+
+// Under reports whether the setting at path is one of settings or inside one of them.
+func Under(path string, settings ...string) bool {
+	for _, s := range settings {
+		if path == s || strings.HasPrefix(path, s+".") || strings.HasPrefix(path, s+"[") {
+			return true
+		}
+	}
+	return false
+}
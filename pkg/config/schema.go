@@ -0,0 +1,59 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Schema returns a JSON Schema for the YAML config v decodes, for editors and CI linters (e.g. the
+// yaml-language-server "$schema" comment). Like Decode it rejects unknown keys and allows the top-level
+// Sections of other configs.
+func Schema(v interface{}) map[string]interface{} {
+	s := schemaOf(reflect.TypeOf(v))
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	if props, ok := s["properties"].(map[string]interface{}); ok {
+		for _, name := range Sections {
+			if _, ok := props[name]; !ok {
+				props[name] = map[string]interface{}{}
+			}
+		}
+	}
+	return s
+}
+
+func schemaOf(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+	case reflect.Struct:
+		fields, open := yamlFields(t)
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		props := make(map[string]interface{}, len(fields))
+		for _, name := range names {
+			props[name] = schemaOf(fields[name])
+		}
+		return map[string]interface{}{"type": "object", "properties": props, "additionalProperties": open}
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,139 @@
+package facility
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// GeoJSON form of a map: a FeatureCollection in facility coordinates (metres, not WGS84) where Point
+// features are nodes, LineString features are edges and Polygon features are zones. Feature properties
+// carry the remaining fields under the same names as the native JSON; "kind" may be set explicitly to
+// "node", "edge" or "zone" and otherwise follows the geometry type.
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	ID       string    `json:"id,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string     `json:"type"`
+	Geometry   geometry   `json:"geometry"`
+	Properties properties `json:"properties"`
+}
+
+type geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+type properties struct {
+	Kind       string  `json:"kind,omitempty"`
+	ID         string  `json:"id,omitempty"`
+	Type       string  `json:"type,omitempty"`
+	LocationID string  `json:"location_id,omitempty"`
+	From       string  `json:"from,omitempty"`
+	To         string  `json:"to,omitempty"`
+	Length     float64 `json:"length,omitempty"`
+	SpeedLimit float64 `json:"speed_limit,omitempty"`
+	OneWay     bool    `json:"one_way,omitempty"`
+	Aisle      string  `json:"aisle,omitempty"`
+	ZoneID     string  `json:"zone_id,omitempty"`
+}
+
+// GeoJSON encodes the map as a GeoJSON FeatureCollection.
+func (m *Map) GeoJSON() ([]byte, error) {
+	fc := featureCollection{Type: "FeatureCollection", ID: m.ID, Name: m.Name}
+	add := func(geomType string, coords any, props properties) error {
+		raw, err := json.Marshal(coords)
+		if err != nil {
+			return err
+		}
+		fc.Features = append(fc.Features, feature{
+			Type:       "Feature",
+			Geometry:   geometry{Type: geomType, Coordinates: raw},
+			Properties: props,
+		})
+		return nil
+	}
+	for _, n := range m.Nodes {
+		props := properties{Kind: "node", ID: n.ID, Type: n.Type, LocationID: n.LocationID}
+		if err := add("Point", [2]float64{n.X, n.Y}, props); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range m.Edges {
+		a, _ := m.Node(e.From)
+		b, _ := m.Node(e.To)
+		props := properties{Kind: "edge", From: e.From, To: e.To, Length: e.Length, SpeedLimit: e.SpeedLimit, OneWay: e.OneWay, Aisle: e.Aisle}
+		if err := add("LineString", [][2]float64{{a.X, a.Y}, {b.X, b.Y}}, props); err != nil {
+			return nil, err
+		}
+	}
+	for _, z := range m.Zones {
+		ring := make([][2]float64, 0, len(z.Polygon)+1)
+		for _, p := range z.Polygon {
+			ring = append(ring, [2]float64{p.X, p.Y})
+		}
+		ring = append(ring, ring[0]) // GeoJSON rings are closed
+		if err := add("Polygon", [][][2]float64{ring}, properties{Kind: "zone", ZoneID: z.ZoneID}); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(fc)
+}
+
+func parseGeoJSON(data []byte) (*Map, error) {
+	var fc featureCollection
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return nil, err
+	}
+	m := &Map{ID: fc.ID, Name: fc.Name}
+	for i, f := range fc.Features {
+		kind := f.Properties.Kind
+		if kind == "" {
+			kind = map[string]string{"Point": "node", "LineString": "edge", "Polygon": "zone"}[f.Geometry.Type]
+		}
+		switch kind {
+		case "node":
+			var c []float64
+			if err := json.Unmarshal(f.Geometry.Coordinates, &c); err != nil || len(c) < 2 || f.Geometry.Type != "Point" {
+				return nil, fmt.Errorf("features[%d]: node needs Point coordinates", i)
+			}
+			p := f.Properties
+			m.Nodes = append(m.Nodes, Node{ID: p.ID, X: c[0], Y: c[1], Type: p.Type, LocationID: p.LocationID})
+		case "edge":
+			p := f.Properties
+			if p.From == "" || p.To == "" {
+				return nil, fmt.Errorf("features[%d]: edge needs from and to properties", i)
+			}
+			m.Edges = append(m.Edges, Edge{From: p.From, To: p.To, Length: p.Length, SpeedLimit: p.SpeedLimit, OneWay: p.OneWay, Aisle: p.Aisle})
+		case "zone":
+			var rings [][][]float64
+			if err := json.Unmarshal(f.Geometry.Coordinates, &rings); err != nil || len(rings) == 0 || f.Geometry.Type != "Polygon" {
+				return nil, fmt.Errorf("features[%d]: zone needs Polygon coordinates", i)
+			}
+			z := ZoneArea{ZoneID: f.Properties.ZoneID}
+			if z.ZoneID == "" {
+				z.ZoneID = f.Properties.ID
+			}
+			ring := rings[0] // holes are ignored
+			if n := len(ring); n > 1 && ring[0][0] == ring[n-1][0] && ring[0][1] == ring[n-1][1] {
+				ring = ring[:n-1]
+			}
+			for _, c := range ring {
+				if len(c) < 2 {
+					return nil, fmt.Errorf("features[%d]: bad polygon coordinate", i)
+				}
+				z.Polygon = append(z.Polygon, api.Point{X: c[0], Y: c[1]})
+			}
+			m.Zones = append(m.Zones, z)
+		default:
+			return nil, fmt.Errorf("features[%d]: unsupported %s feature", i, f.Geometry.Type)
+		}
+	}
+	return m, nil
+}
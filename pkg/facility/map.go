@@ -0,0 +1,295 @@
+// Package facility models the floor robots drive on: a graph of nodes (waypoints, WMS locations,
+// chargers) with coordinates in metres, edges with lengths and speed limits, and zones as polygons.
+// Maps are exchanged as JSON, either in the native form of Map or as a GeoJSON FeatureCollection.
+package facility
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// Node types. Nodes without a type are waypoints.
+const (
+	NodeWaypoint = "waypoint"
+	NodeLocation = "location" // a WMS location; set LocationID
+	NodeCharger  = "charger"
+)
+
+// Map is a facility map. Call Build after constructing or changing one in code; Parse and Load build it.
+type Map struct {
+	ID    string     `json:"id,omitempty"`
+	Name  string     `json:"name,omitempty"`
+	Nodes []Node     `json:"nodes"`
+	Edges []Edge     `json:"edges"`
+	Zones []ZoneArea `json:"zones,omitempty"`
+
+	idx *index
+}
+
+// Node is a point on the graph.
+type Node struct {
+	ID         string  `json:"id"`
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+	Type       string  `json:"type,omitempty"`
+	LocationID string  `json:"location_id,omitempty"` // WMS location bound to this node
+}
+
+// Point returns the node's coordinates.
+func (n Node) Point() api.Point { return api.Point{X: n.X, Y: n.Y} }
+
+// Edge connects two nodes. Edges are traversable both ways unless OneWay is set.
+type Edge struct {
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	Length     float64 `json:"length,omitempty"`      // metres; 0 = straight-line distance
+	SpeedLimit float64 `json:"speed_limit,omitempty"` // m/s; 0 = no limit
+	OneWay     bool    `json:"one_way,omitempty"`
+	Aisle      string  `json:"aisle,omitempty"` // label, e.g. "A-01"
+}
+
+// ZoneArea is a zone's footprint on the floor.
+type ZoneArea struct {
+	ZoneID  string      `json:"zone_id"`
+	Polygon []api.Point `json:"polygon"`
+}
+
+// Load reads a map file (native JSON or GeoJSON).
+func Load(path string) (*Map, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("facility map %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse decodes and builds a map from native JSON or a GeoJSON FeatureCollection.
+func Parse(data []byte) (*Map, error) {
+	var probe struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+	var m *Map
+	if probe.Type == "FeatureCollection" {
+		var err error
+		if m, err = parseGeoJSON(data); err != nil {
+			return nil, err
+		}
+	} else {
+		m = &Map{}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(m); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.Build(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Build validates the map, fills in edge lengths and indexes it for lookups and routing.
+func (m *Map) Build() error {
+	idx := &index{
+		nodes:     make(map[string]int, len(m.Nodes)),
+		locations: make(map[string]int),
+		adj:       make([][]arc, len(m.Nodes)),
+		radj:      make([][]arc, len(m.Nodes)),
+		stretch:   1,
+	}
+	for i, n := range m.Nodes {
+		if n.ID == "" {
+			return fmt.Errorf("nodes[%d]: id is required", i)
+		}
+		if _, dup := idx.nodes[n.ID]; dup {
+			return fmt.Errorf("duplicate node %s", n.ID)
+		}
+		if math.IsNaN(n.X) || math.IsNaN(n.Y) || math.IsInf(n.X, 0) || math.IsInf(n.Y, 0) {
+			return fmt.Errorf("node %s: invalid coordinates", n.ID)
+		}
+		switch n.Type {
+		case "", NodeWaypoint, NodeLocation, NodeCharger:
+		default:
+			return fmt.Errorf("node %s: unknown type %q", n.ID, n.Type)
+		}
+		idx.nodes[n.ID] = i
+		if n.LocationID != "" {
+			if other, dup := idx.locations[n.LocationID]; dup {
+				return fmt.Errorf("location %s is bound to nodes %s and %s", n.LocationID, m.Nodes[other].ID, n.ID)
+			}
+			idx.locations[n.LocationID] = i
+		}
+	}
+	for i := range m.Edges {
+		e := &m.Edges[i]
+		from, okFrom := idx.nodes[e.From]
+		to, okTo := idx.nodes[e.To]
+		if !okFrom || !okTo {
+			return fmt.Errorf("edges[%d]: unknown node %s", i, map[bool]string{true: e.To, false: e.From}[okFrom])
+		}
+		if from == to {
+			return fmt.Errorf("edges[%d]: %s connects to itself", i, e.From)
+		}
+		if e.Length < 0 || e.SpeedLimit < 0 {
+			return fmt.Errorf("edges[%d] %s-%s: length and speed_limit must not be negative", i, e.From, e.To)
+		}
+		straight := dist(m.Nodes[from].Point(), m.Nodes[to].Point())
+		if e.Length == 0 {
+			e.Length = straight
+		}
+		if straight > 0 {
+			idx.stretch = math.Min(idx.stretch, e.Length/straight)
+		}
+		idx.addArc(from, to, i)
+		if !e.OneWay {
+			idx.addArc(to, from, i)
+		}
+	}
+	zones := make(map[string]bool)
+	for i, z := range m.Zones {
+		if z.ZoneID == "" {
+			return fmt.Errorf("zones[%d]: zone_id is required", i)
+		}
+		if zones[z.ZoneID] {
+			return fmt.Errorf("duplicate zone %s", z.ZoneID)
+		}
+		zones[z.ZoneID] = true
+		if len(z.Polygon) < 3 {
+			return fmt.Errorf("zone %s: polygon needs at least 3 points", z.ZoneID)
+		}
+	}
+	idx.grid = newGrid(m.Nodes)
+	m.idx = idx
+	return nil
+}
+
+// Node returns a node by ID.
+func (m *Map) Node(id string) (Node, bool) {
+	i, ok := m.index().nodes[id]
+	if !ok {
+		return Node{}, false
+	}
+	return m.Nodes[i], true
+}
+
+// NodeForLocation returns the node a WMS location is bound to.
+func (m *Map) NodeForLocation(locationID string) (Node, bool) {
+	i, ok := m.index().locations[locationID]
+	if !ok {
+		return Node{}, false
+	}
+	return m.Nodes[i], true
+}
+
+// Nearest returns the node closest to p in a straight line and its distance.
+func (m *Map) Nearest(p api.Point) (Node, float64, bool) {
+	i, d := m.index().grid.nearest(m.Nodes, p)
+	if i < 0 {
+		return Node{}, 0, false
+	}
+	return m.Nodes[i], d, true
+}
+
+// Link is an edge as seen from the node it leaves.
+type Link struct {
+	To         string
+	Length     float64
+	SpeedLimit float64
+}
+
+// Links returns the edges that can be driven from node id.
+func (m *Map) Links(id string) []Link {
+	x := m.index()
+	i, ok := x.nodes[id]
+	if !ok {
+		return nil
+	}
+	out := make([]Link, 0, len(x.adj[i]))
+	for _, a := range x.adj[i] {
+		e := m.Edges[a.edge]
+		out = append(out, Link{To: m.Nodes[a.to].ID, Length: e.Length, SpeedLimit: e.SpeedLimit})
+	}
+	return out
+}
+
+// ZoneAt returns the zone whose polygon contains p, or "" if none does.
+func (m *Map) ZoneAt(p api.Point) string {
+	for _, z := range m.Zones {
+		if contains(z.Polygon, p) {
+			return z.ZoneID
+		}
+	}
+	return ""
+}
+
+// Resolve returns the coordinates for a task endpoint: the point itself, else the node, else the node
+// bound to the WMS location.
+func (m *Map) Resolve(p *api.Point, nodeID, locationID string) (api.Point, bool) {
+	if p != nil {
+		return *p, true
+	}
+	if n, ok := m.Node(nodeID); ok && nodeID != "" {
+		return n.Point(), true
+	}
+	if n, ok := m.NodeForLocation(locationID); ok && locationID != "" {
+		return n.Point(), true
+	}
+	return api.Point{}, false
+}
+
+func (m *Map) index() *index {
+	if m.idx == nil {
+		// An unbuilt map behaves as empty rather than panicking; Build reports what is wrong with it.
+		if err := m.Build(); err != nil {
+			return &index{grid: newGrid(nil)}
+		}
+	}
+	return m.idx
+}
+
+type arc struct {
+	to   int
+	edge int
+}
+
+type index struct {
+	nodes     map[string]int
+	locations map[string]int
+	adj       [][]arc // outgoing
+	radj      [][]arc // incoming, for distances to a target
+	grid      *grid
+	stretch   float64 // min edge length / straight-line distance, capped at 1: keeps A* admissible
+}
+
+func (x *index) addArc(from, to, edge int) {
+	x.adj[from] = append(x.adj[from], arc{to: to, edge: edge})
+	x.radj[to] = append(x.radj[to], arc{to: from, edge: edge})
+}
+
+func dist(a, b api.Point) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
+
+// contains reports whether p is inside the polygon (even-odd rule).
+func contains(poly []api.Point, p api.Point) bool {
+	in := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a, b := poly[i], poly[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			in = !in
+		}
+	}
+	return in
+}
@@ -0,0 +1,240 @@
+package facility
+
+import (
+	"container/heap"
+	"math"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// ShortestPath returns the node IDs from one node to another along the graph (A*) and the path length in
+// metres. ok is false when either node is unknown or to is unreachable.
+func (m *Map) ShortestPath(from, to string) (path []string, length float64, ok bool) {
+	x := m.index()
+	src, okFrom := x.nodes[from]
+	dst, okTo := x.nodes[to]
+	if !okFrom || !okTo {
+		return nil, 0, false
+	}
+	goal := m.Nodes[dst].Point()
+	h := func(i int) float64 { return dist(m.Nodes[i].Point(), goal) * x.stretch }
+	d, prev := m.search(x.adj, src, dst, h)
+	if math.IsInf(d[dst], 1) {
+		return nil, 0, false
+	}
+	for i := dst; i != -1; i = prev[i] {
+		path = append(path, m.Nodes[i].ID)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, d[dst], true
+}
+
+// Route is the shortest way between two points: straight to the nearest node, along the graph, and
+// straight from the last node to the destination.
+type Route struct {
+	Nodes  []string `json:"nodes"`
+	Length float64  `json:"length_m"`
+}
+
+// Route returns the shortest route between two points. ok is false when the map has no nodes or the
+// destination's node is unreachable.
+func (m *Map) Route(from, to api.Point) (Route, bool) {
+	a, da, ok := m.Nearest(from)
+	if !ok {
+		return Route{}, false
+	}
+	b, db, _ := m.Nearest(to)
+	path, length, ok := m.ShortestPath(a.ID, b.ID)
+	if !ok {
+		return Route{}, false
+	}
+	return Route{Nodes: path, Length: da + length + db}, true
+}
+
+// Distance is the travel distance between two points, or +Inf when there is no route.
+func (m *Map) Distance(from, to api.Point) float64 {
+	r, ok := m.Route(from, to)
+	if !ok {
+		return math.Inf(1)
+	}
+	return r.Length
+}
+
+// DistancesTo runs one search back from target and returns a function giving the travel distance from
+// any point to it (+Inf when unreachable). Use it to rank many robots against one pickup.
+func (m *Map) DistancesTo(target api.Point) func(api.Point) float64 {
+	x := m.index()
+	t, dt, ok := m.Nearest(target)
+	if !ok {
+		return func(api.Point) float64 { return math.Inf(1) }
+	}
+	d, _ := m.search(x.radj, x.nodes[t.ID], -1, nil)
+	return func(p api.Point) float64 {
+		i, dp := x.grid.nearest(m.Nodes, p)
+		if i < 0 {
+			return math.Inf(1)
+		}
+		return dp + d[i] + dt
+	}
+}
+
+// TravelTime is how long path takes at speed m/s, slowing down on edges with a lower speed limit.
+// Consecutive nodes that are not connected are crossed in a straight line.
+func (m *Map) TravelTime(path []string, speed float64) time.Duration {
+	if speed <= 0 {
+		return 0
+	}
+	x := m.index()
+	var sec float64
+	for k := 1; k < len(path); k++ {
+		a, okA := x.nodes[path[k-1]]
+		b, okB := x.nodes[path[k]]
+		if !okA || !okB {
+			continue
+		}
+		length, v := dist(m.Nodes[a].Point(), m.Nodes[b].Point()), speed
+		for _, arc := range x.adj[a] {
+			if arc.to == b {
+				e := m.Edges[arc.edge]
+				length = e.Length
+				if e.SpeedLimit > 0 && e.SpeedLimit < v {
+					v = e.SpeedLimit
+				}
+				break
+			}
+		}
+		sec += length / v
+	}
+	return time.Duration(sec * float64(time.Second))
+}
+
+// search computes distances from src over adj, stopping early once dst (if >= 0) is settled. With a
+// heuristic h (a lower bound on the distance to dst) it is A*, otherwise Dijkstra.
+func (m *Map) search(adj [][]arc, src, dst int, h func(int) float64) ([]float64, []int) {
+	if h == nil {
+		h = func(int) float64 { return 0 }
+	}
+	d := make([]float64, len(m.Nodes))
+	prev := make([]int, len(m.Nodes))
+	for i := range d {
+		d[i] = math.Inf(1)
+		prev[i] = -1
+	}
+	d[src] = 0
+	q := &queue{{node: src, rank: h(src)}}
+	for q.Len() > 0 {
+		it := heap.Pop(q).(item)
+		if it.dist > d[it.node] {
+			continue
+		}
+		if it.node == dst {
+			break
+		}
+		for _, a := range adj[it.node] {
+			if nd := it.dist + m.Edges[a.edge].Length; nd < d[a.to] {
+				d[a.to] = nd
+				prev[a.to] = it.node
+				heap.Push(q, item{node: a.to, dist: nd, rank: nd + h(a.to)})
+			}
+		}
+	}
+	return d, prev
+}
+
+type item struct {
+	node int
+	dist float64
+	rank float64 // dist plus the heuristic
+}
+
+type queue []item
+
+func (q queue) Len() int           { return len(q) }
+func (q queue) Less(i, j int) bool { return q[i].rank < q[j].rank }
+func (q queue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *queue) Push(x any)        { *q = append(*q, x.(item)) }
+func (q *queue) Pop() any {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return it
+}
+
+// grid buckets nodes into square cells so the nearest node is found without scanning the whole map.
+type grid struct {
+	minX, minY float64
+	cell       float64
+	cols, rows int
+	cells      [][]int
+}
+
+func newGrid(nodes []Node) *grid {
+	g := &grid{cell: 1, cols: 1, rows: 1}
+	if len(nodes) == 0 {
+		return g
+	}
+	minX, minY, maxX, maxY := nodes[0].X, nodes[0].Y, nodes[0].X, nodes[0].Y
+	for _, n := range nodes {
+		minX, minY = math.Min(minX, n.X), math.Min(minY, n.Y)
+		maxX, maxY = math.Max(maxX, n.X), math.Max(maxY, n.Y)
+	}
+	// About one node per cell on average.
+	side := math.Max(maxX-minX, maxY-minY)
+	if side > 0 {
+		g.cell = side / math.Ceil(math.Sqrt(float64(len(nodes))))
+	}
+	g.minX, g.minY = minX, minY
+	g.cols = int((maxX-minX)/g.cell) + 1
+	g.rows = int((maxY-minY)/g.cell) + 1
+	g.cells = make([][]int, g.cols*g.rows)
+	for i, n := range nodes {
+		c, r := g.locate(n.X, n.Y)
+		g.cells[r*g.cols+c] = append(g.cells[r*g.cols+c], i)
+	}
+	return g
+}
+
+func (g *grid) locate(x, y float64) (int, int) {
+	c := int(math.Floor((x - g.minX) / g.cell))
+	r := int(math.Floor((y - g.minY) / g.cell))
+	return min(max(c, 0), g.cols-1), min(max(r, 0), g.rows-1)
+}
+
+// nearest searches rings of cells around p until no unvisited cell can hold a closer node.
+func (g *grid) nearest(nodes []Node, p api.Point) (int, float64) {
+	if len(g.cells) == 0 {
+		return -1, 0
+	}
+	best, bestD := -1, math.Inf(1)
+	c0, r0 := g.locate(p.X, p.Y)
+	// Cells in ring k are at least (k-1) cells from p's cell, and no closer than the grid's bounding box
+	// when p lies outside it.
+	outside := math.Hypot(
+		math.Max(0, math.Max(g.minX-p.X, p.X-(g.minX+float64(g.cols)*g.cell))),
+		math.Max(0, math.Max(g.minY-p.Y, p.Y-(g.minY+float64(g.rows)*g.cell))),
+	)
+	for ring := 0; ring <= max(g.cols, g.rows); ring++ {
+		if best >= 0 && math.Max(outside, float64(ring-1)*g.cell) > bestD {
+			break
+		}
+		for r := r0 - ring; r <= r0+ring; r++ {
+			for c := c0 - ring; c <= c0+ring; c++ {
+				if r < 0 || c < 0 || r >= g.rows || c >= g.cols {
+					continue
+				}
+				if ring > 0 && r != r0-ring && r != r0+ring && c != c0-ring && c != c0+ring {
+					continue // interior, visited in an earlier ring
+				}
+				for _, i := range g.cells[r*g.cols+c] {
+					if d := dist(nodes[i].Point(), p); d < bestD {
+						best, bestD = i, d
+					}
+				}
+			}
+		}
+	}
+	return best, bestD
+}
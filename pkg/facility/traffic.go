@@ -0,0 +1,385 @@
+package facility
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Reservations books nodes (intersections) and edges (segments) for time windows, so routes planned one after
+// another do not put two robots on the same node or segment at once. Not safe for concurrent use.
+type Reservations struct {
+	nodes map[string][]Booking
+	edges map[string][]Booking // keyed by both end nodes in sorted order: a segment is booked in both directions
+}
+
+// Booking is one holder's claim on a node or edge.
+type Booking struct {
+	Holder   string
+	From, To time.Time
+}
+
+// NewReservations returns an empty table.
+func NewReservations() *Reservations {
+	return &Reservations{nodes: make(map[string][]Booking), edges: make(map[string][]Booking)}
+}
+
+// Step is one node of a timed route: the robot reaches it at Arrive and leaves at Depart.
+type Step struct {
+	NodeID   string
+	Arrive   time.Time
+	Depart   time.Time
+	MaxSpeed float64 // speed limit of the edge into this node; 0 = none
+	Stop     bool    // a pick or drop point
+}
+
+// Reserve books a planned route for holder. A robot holds each node from its arrival until it reaches the
+// next one plus clearance, and the last node until park after it departs. Each segment is booked while the
+// robot drives it.
+func (r *Reservations) Reserve(holder string, steps []Step, clearance, park time.Duration) {
+	for i, s := range steps {
+		end := s.Depart.Add(park)
+		if i+1 < len(steps) {
+			next := steps[i+1]
+			end = next.Arrive.Add(clearance)
+			r.edges[edgeKey(s.NodeID, next.NodeID)] = append(r.edges[edgeKey(s.NodeID, next.NodeID)], Booking{holder, s.Depart, next.Arrive})
+		}
+		r.nodes[s.NodeID] = append(r.nodes[s.NodeID], Booking{holder, s.Arrive, end})
+	}
+}
+
+// Release drops every booking of holder.
+func (r *Reservations) Release(holder string) {
+	for _, t := range []map[string][]Booking{r.nodes, r.edges} {
+		for k, bs := range t {
+			if kept := filterBookings(bs, func(b Booking) bool { return b.Holder != holder }); len(kept) == 0 {
+				delete(t, k)
+			} else {
+				t[k] = kept
+			}
+		}
+	}
+}
+
+// Prune drops bookings that ended before t.
+func (r *Reservations) Prune(t time.Time) {
+	for _, tab := range []map[string][]Booking{r.nodes, r.edges} {
+		for k, bs := range tab {
+			if kept := filterBookings(bs, func(b Booking) bool { return !b.To.Before(t) }); len(kept) == 0 {
+				delete(tab, k)
+			} else {
+				tab[k] = kept
+			}
+		}
+	}
+}
+
+// NodeBookings returns the bookings of one node, for inspection.
+func (r *Reservations) NodeBookings(id string) []Booking {
+	return append([]Booking(nil), r.nodes[id]...)
+}
+
+func filterBookings(bs []Booking, keep func(Booking) bool) []Booking {
+	out := bs[:0]
+	for _, b := range bs {
+		if keep(b) {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+func edgeKey(a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return a + "|" + b
+}
+
+// conflict returns the latest end among bookings by others that overlap [from, to).
+func conflict(bs []Booking, holder string, from, to time.Time) (time.Time, bool) {
+	var end time.Time
+	found := false
+	for _, b := range bs {
+		if b.Holder != holder && b.From.Before(to) && from.Before(b.To) {
+			if !found || b.To.After(end) {
+				end = b.To
+			}
+			found = true
+		}
+	}
+	return end, found
+}
+
+// PlanRequest asks for a timed route from a node through a sequence of stops.
+type PlanRequest struct {
+	Holder    string
+	From      string    // node the robot starts at
+	Start     time.Time // when it is there
+	Stops     []string  // pick and drop nodes, in order
+	Speed     float64   // m/s; edges with a lower speed limit are driven at the limit
+	Dwell     time.Duration
+	Clearance time.Duration
+	Park      time.Duration   // how long the last stop stays booked after the robot is done there
+	Blocked   map[string]bool // nodes that must not be entered (e.g. where a robot is parked or broken down)
+	// Evacuate makes the robot leave From before anyone else's booking of it starts. By default the robot
+	// is taken to be standing there: it may wait at From regardless of bookings.
+	Evacuate bool
+}
+
+// PlanTimed finds a route through req.Stops that avoids the bookings in res: a space-time A* over the graph
+// in which the robot may wait at a node (if nobody else needs it) until the next node and segment are free.
+// Earlier bookings win; the result is not booked, call Reserve for that.
+func (m *Map) PlanTimed(res *Reservations, req PlanRequest) ([]Step, error) {
+	x := m.index()
+	cur, ok := x.nodes[req.From]
+	if !ok {
+		return nil, fmt.Errorf("unknown node %s", req.From)
+	}
+	if req.Speed <= 0 {
+		return nil, fmt.Errorf("speed must be positive")
+	}
+	stops := make([]int, len(req.Stops))
+	for k, id := range req.Stops {
+		if stops[k], ok = x.nodes[id]; !ok {
+			return nil, fmt.Errorf("unknown node %s", id)
+		}
+		if req.Blocked[id] {
+			return nil, fmt.Errorf("stop %s is blocked", id)
+		}
+	}
+	p := &timedPlanner{m: m, x: x, res: res, req: req}
+	// Legs are planned one after another. When a leg finds no way through, the robot arrives at the stop
+	// before it later (5s, 10s, 20s, ...) so whatever blocked the leg has time to pass. When the first leg
+	// finds none, a robot standing at From leaves it later.
+	notBefore := make([]float64, len(stops))
+	var steps []tstep
+	leave := 0.0
+	for attempt := 0; ; attempt++ {
+		var arrivals []float64
+		var k int
+		var err error
+		steps, arrivals, k, err = p.legs(cur, leave, stops, notBefore)
+		if err == nil {
+			break
+		}
+		if (k == 0 && req.Evacuate) || attempt >= 8 {
+			return nil, err
+		}
+		if k == 0 {
+			leave += 5 * math.Pow(2, float64(attempt))
+			continue
+		}
+		notBefore[k-1] = arrivals[k-1] + 5*math.Pow(2, float64(attempt))
+	}
+	out := make([]Step, len(steps))
+	for i, s := range steps {
+		out[i] = Step{
+			NodeID:   m.Nodes[s.node].ID,
+			Arrive:   p.at(s.arrive),
+			Depart:   p.at(s.depart),
+			MaxSpeed: s.maxSpeed,
+			Stop:     s.stop,
+		}
+	}
+	return out, nil
+}
+
+// legs plans each leg to the next stop, leaving cur no earlier than leave and arriving at stop k no earlier
+// than notBefore[k]. On failure it returns the index of the leg that failed and the arrivals planned before
+// it.
+func (p *timedPlanner) legs(cur int, leave float64, stops []int, notBefore []float64) ([]tstep, []float64, int, error) {
+	steps := []tstep{{node: cur}}
+	arrivals := make([]float64, 0, len(stops))
+	t := leave
+	for k, goal := range stops {
+		hold := p.req.Dwell.Seconds() + p.req.Clearance.Seconds()
+		if k == len(stops)-1 {
+			hold = p.req.Dwell.Seconds() + p.req.Park.Seconds()
+		}
+		standing := k == 0 && !p.req.Evacuate
+		if goal == cur {
+			arrive := math.Max(t, notBefore[k])
+			if !standing && p.busy(goal, t, arrive+hold) {
+				return nil, arrivals, k, fmt.Errorf("stop %s is booked", p.m.Nodes[goal].ID)
+			}
+			steps[len(steps)-1].arrive = math.Min(steps[len(steps)-1].arrive, arrive)
+		} else {
+			seg, err := p.search(cur, t, goal, hold, notBefore[k], standing)
+			if err != nil {
+				return nil, arrivals, k, err
+			}
+			steps[len(steps)-1].depart = seg[0].depart
+			steps = append(steps, seg[1:]...)
+		}
+		last := &steps[len(steps)-1]
+		last.stop = true
+		last.depart = math.Max(last.arrive, notBefore[k]) + p.req.Dwell.Seconds()
+		arrivals = append(arrivals, last.arrive)
+		cur, t = goal, last.depart
+	}
+	return steps, arrivals, -1, nil
+}
+
+// tstep is a Step with times in seconds after the request's start.
+type tstep struct {
+	node           int
+	arrive, depart float64
+	maxSpeed       float64
+	stop           bool
+}
+
+type timedPlanner struct {
+	m   *Map
+	x   *index
+	res *Reservations
+	req PlanRequest
+}
+
+func (p *timedPlanner) at(sec float64) time.Time {
+	return p.req.Start.Add(time.Duration(sec * float64(time.Second)))
+}
+
+func (p *timedPlanner) busy(node int, from, to float64) bool {
+	_, ok := conflict(p.res.nodes[p.m.Nodes[node].ID], p.req.Holder, p.at(from), p.at(to))
+	return ok
+}
+
+// label is a search state: the robot reached node at t, having left parent at depart over edge.
+type label struct {
+	node   int
+	t      float64
+	depart float64
+	edge   int
+	parent *label
+	rank   float64
+	// standing is set on the start label when the robot is already at the node and may wait there.
+	standing bool
+}
+
+type labelQueue []*label
+
+func (q labelQueue) Len() int           { return len(q) }
+func (q labelQueue) Less(i, j int) bool { return q[i].rank < q[j].rank }
+func (q labelQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *labelQueue) Push(x any)        { *q = append(*q, x.(*label)) }
+func (q *labelQueue) Pop() any {
+	old := *q
+	l := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return l
+}
+
+// search finds the earliest arrival at goal, no earlier than minArrive, leaving from at t0, such that goal is
+// then free for hold seconds. With standing, bookings of from do not keep the robot from waiting there.
+// The returned steps start with from (its depart set) and end with goal (its arrive set).
+func (p *timedPlanner) search(from int, t0 float64, goal int, hold, minArrive float64, standing bool) ([]tstep, error) {
+	const eps = 1e-6
+	goalPt := p.m.Nodes[goal].Point()
+	h := func(n int) float64 { return dist(p.m.Nodes[n].Point(), goalPt) * p.x.stretch / p.req.Speed }
+	best := make([]float64, len(p.m.Nodes))
+	for i := range best {
+		best[i] = math.Inf(1)
+	}
+	best[from] = t0
+	q := &labelQueue{{node: from, t: t0, edge: -1, rank: t0 + h(from), standing: standing}}
+	limit := 50*len(p.m.Nodes) + 1000
+	for pops := 0; q.Len() > 0 && pops < limit; pops++ {
+		l := heap.Pop(q).(*label)
+		if l.node != goal && l.t > best[l.node]+eps {
+			continue
+		}
+		if l.node == goal {
+			if l.t < minArrive-eps {
+				if next, ok := p.expand(l.parent, l.edge, minArrive-p.travel(l.edge)); ok {
+					next.rank = next.t
+					heap.Push(q, next)
+				}
+				continue
+			}
+			end, busy := conflict(p.res.nodes[p.m.Nodes[goal].ID], p.req.Holder, p.at(l.t), p.at(l.t+hold))
+			if !busy {
+				return p.unwind(l), nil
+			}
+			// Arrive once the goal is free again, waiting at the node before it.
+			if next, ok := p.expand(l.parent, l.edge, end.Sub(p.req.Start).Seconds()-p.travel(l.edge)+eps); ok {
+				next.rank = next.t
+				heap.Push(q, next)
+			}
+			continue
+		}
+		for _, a := range p.x.adj[l.node] {
+			if p.req.Blocked[p.m.Nodes[a.to].ID] && a.to != goal {
+				continue
+			}
+			next, ok := p.expand(l, a.edge, l.t)
+			if !ok || (a.to != goal && next.t >= best[a.to]-eps) {
+				continue
+			}
+			if a.to != goal {
+				best[a.to] = next.t
+			}
+			next.rank = next.t + h(a.to)
+			heap.Push(q, next)
+		}
+	}
+	return nil, fmt.Errorf("no conflict-free route from %s to %s", p.m.Nodes[from].ID, p.m.Nodes[goal].ID)
+}
+
+func (p *timedPlanner) travel(edge int) float64 {
+	e := p.m.Edges[edge]
+	v := p.req.Speed
+	if e.SpeedLimit > 0 && e.SpeedLimit < v {
+		v = e.SpeedLimit
+	}
+	return e.Length / v
+}
+
+// expand moves from l over edge, leaving no earlier than minDepart: it pushes the departure past bookings
+// of the segment and the next node, and gives up if waiting would overlap someone else's booking of l's node.
+func (p *timedPlanner) expand(l *label, edge int, minDepart float64) (*label, bool) {
+	if l == nil {
+		return nil, false
+	}
+	e := p.m.Edges[edge]
+	to := p.x.nodes[e.To]
+	if to == l.node {
+		to = p.x.nodes[e.From]
+	}
+	here, there := p.m.Nodes[l.node].ID, p.m.Nodes[to].ID
+	tau, clear := p.travel(edge), p.req.Clearance.Seconds()
+	d := math.Max(l.t, minDepart)
+	for i := 0; i < 32; i++ {
+		if _, busy := conflict(p.res.nodes[here], p.req.Holder, p.at(l.t), p.at(d+tau+clear)); busy && !l.standing {
+			return nil, false
+		}
+		if end, busy := conflict(p.res.edges[edgeKey(here, there)], p.req.Holder, p.at(d), p.at(d+tau)); busy {
+			d = end.Sub(p.req.Start).Seconds()
+			continue
+		}
+		if end, busy := conflict(p.res.nodes[there], p.req.Holder, p.at(d+tau), p.at(d+tau+clear)); busy {
+			d = math.Max(d+1e-3, end.Sub(p.req.Start).Seconds()-tau)
+			continue
+		}
+		return &label{node: to, t: d + tau, depart: d, edge: edge, parent: l}, true
+	}
+	return nil, false
+}
+
+func (p *timedPlanner) unwind(l *label) []tstep {
+	var out []tstep
+	depart := 0.0 // when the robot leaves the step being added: the departure recorded on its successor
+	for ; l != nil; l = l.parent {
+		s := tstep{node: l.node, arrive: l.t, depart: depart}
+		if l.edge >= 0 {
+			s.maxSpeed = p.m.Edges[l.edge].SpeedLimit
+		}
+		out = append(out, s)
+		depart = l.depart
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package idempotency
+
+import (
+	"sync"
+	"time"
+)
+
+// Dedupe remembers IDs for a TTL so a bus consumer can drop a message redelivered within it. Times are
+// passed in, so controllers on a virtual clock dedupe on virtual time. Safe for concurrent use.
+type Dedupe struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	seen      map[string]time.Time // ID -> when it is forgotten
+	nextSweep time.Time
+}
+
+// NewDedupe returns a Dedupe that remembers IDs for ttl.
+func NewDedupe(ttl time.Duration) *Dedupe {
+	return &Dedupe{ttl: ttl, seen: make(map[string]time.Time)}
+}
+
+// First records id at now and reports whether it is new, i.e. not recorded in the TTL before now.
+func (d *Dedupe) First(id string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !now.Before(d.nextSweep) {
+		d.nextSweep = now.Add(d.ttl)
+		for k, until := range d.seen {
+			if !now.Before(until) {
+				delete(d.seen, k)
+			}
+		}
+	}
+	if until, ok := d.seen[id]; ok && now.Before(until) {
+		return false
+	}
+	d.seen[id] = now.Add(d.ttl)
+	return true
+}
+
+// Forget drops id, e.g. when handling it failed and a redelivery should be handled again.
+func (d *Dedupe) Forget(id string) {
+	d.mu.Lock()
+	delete(d.seen, id)
+	d.mu.Unlock()
+}
@@ -0,0 +1,32 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDedupe(t *testing.T) {
+	d := NewDedupe(10 * time.Minute)
+	t0 := time.Date(2025, 1, 6, 6, 0, 0, 0, time.UTC)
+	for _, step := range []struct {
+		id    string
+		after time.Duration
+		want  bool
+	}{
+		{"a", 0, true},
+		{"a", time.Second, false},
+		{"b", time.Second, true},
+		{"a", 10*time.Minute - time.Second, false},
+		{"a", 10 * time.Minute, true},
+		{"b", 10 * time.Minute, false},
+		{"b", 30 * time.Minute, true},
+	} {
+		if got := d.First(step.id, t0.Add(step.after)); got != step.want {
+			t.Errorf("First(%s) at +%v = %v, want %v", step.id, step.after, got, step.want)
+		}
+	}
+	d.Forget("b")
+	if !d.First("b", t0.Add(30*time.Minute)) {
+		t.Errorf("First after Forget = false, want true")
+	}
+}
@@ -0,0 +1,224 @@
+// Package idempotency makes retried writes safe. Store replays the response to a POST whose Idempotency-Key
+// was seen before, so a client retrying a timed-out create gets the original result instead of a second
+// object; Dedupe lets bus consumers drop redelivered messages by ID.
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/config"
+)
+
+// Header is the request header carrying the key; pkg/client sends it on every write.
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set to "true" on a response replayed from an earlier request.
+const ReplayedHeader = "Idempotent-Replayed"
+
+const (
+	maxKeyLen  = 255
+	maxBody    = 1 << 20 // bodies of POSTs with a key are read up front to fingerprint them
+	sweepEvery = time.Minute
+)
+
+// Config configures a service's Store.
+type Config struct {
+	TTL string `yaml:"ttl"` // how long a key's response is replayed, e.g. "24h" (default); env IDEMPOTENCY_TTL overrides
+}
+
+// ApplyEnv overrides the TTL from IDEMPOTENCY_TTL.
+func (c *Config) ApplyEnv() {
+	if t := os.Getenv("IDEMPOTENCY_TTL"); t != "" {
+		c.TTL = t
+	}
+}
+
+// Period returns the TTL, 24h if unset.
+func (c Config) Period() (time.Duration, error) {
+	return config.Duration("idempotency.ttl", c.TTL, 24*time.Hour)
+}
+
+// Store remembers the responses to POSTs with an Idempotency-Key for the TTL. Keys are scoped to the caller
+// (the authenticated subject, if any) and the path. Safe for concurrent use; a nil Store does nothing.
+//
+// Only 2xx responses are kept: a failed request changed nothing, so its retry runs again. A retry while the
+// first request is still running waits for it. Reusing a key with a different body or query is a 422.
+type Store struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	nextSweep time.Time
+}
+
+type entry struct {
+	fingerprint [sha256.Size]byte
+	done        chan struct{} // closed when the first request has finished
+	status      int
+	contentType string
+	body        []byte
+	expires     time.Time
+}
+
+// New returns a Store for cfg.
+func New(cfg Config) (*Store, error) {
+	ttl, err := cfg.Period()
+	if err != nil {
+		return nil, err
+	}
+	return &Store{ttl: ttl, now: time.Now, entries: make(map[string]*entry)}, nil
+}
+
+// Wrap returns next with POSTs carrying an Idempotency-Key deduplicated. Put it inside auth.Wrap so keys are
+// scoped to the caller and unauthenticated requests never reach the store.
+func (s *Store) Wrap(next http.Handler) http.Handler {
+	if s == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxKeyLen {
+			http.Error(w, "Idempotency-Key longer than 255 characters", http.StatusBadRequest)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "read body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		s.serve(w, r, scope(r, key), fingerprint(r, body), next)
+	})
+}
+
+func (s *Store) serve(w http.ResponseWriter, r *http.Request, id string, fp [sha256.Size]byte, next http.Handler) {
+	for {
+		s.mu.Lock()
+		now := s.now()
+		s.sweepLocked(now)
+		e := s.entries[id]
+		if e != nil && e.status != 0 && now.After(e.expires) {
+			delete(s.entries, id)
+			e = nil
+		}
+		if e == nil {
+			e = &entry{fingerprint: fp, done: make(chan struct{})}
+			s.entries[id] = e
+			s.mu.Unlock()
+			s.run(w, r, id, e, next)
+			return
+		}
+		s.mu.Unlock()
+		if e.fingerprint != fp {
+			http.Error(w, "Idempotency-Key was used with a different request", http.StatusUnprocessableEntity)
+			return
+		}
+		select {
+		case <-e.done:
+		case <-r.Context().Done():
+			http.Error(w, "request with this Idempotency-Key still in progress", http.StatusConflict)
+			return
+		}
+		if e.status != 0 {
+			if e.contentType != "" {
+				w.Header().Set("Content-Type", e.contentType)
+			}
+			w.Header().Set(ReplayedHeader, "true")
+			w.WriteHeader(e.status)
+			w.Write(e.body)
+			return
+		}
+		// The first request failed and its entry is gone; run this one.
+	}
+}
+
+// run serves the first request with a key and keeps its response if it succeeded.
+func (s *Store) run(w http.ResponseWriter, r *http.Request, id string, e *entry, next http.Handler) {
+	rec := &recorder{ResponseWriter: w}
+	defer func() {
+		s.mu.Lock()
+		if rec.status >= 200 && rec.status < 300 {
+			e.status = rec.status
+			e.contentType = w.Header().Get("Content-Type")
+			e.body = rec.body.Bytes()
+			e.expires = s.now().Add(s.ttl)
+		} else {
+			delete(s.entries, id)
+		}
+		s.mu.Unlock()
+		close(e.done)
+	}()
+	next.ServeHTTP(rec, r)
+}
+
+// sweepLocked drops expired entries, at most once a minute.
+func (s *Store) sweepLocked(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	s.nextSweep = now.Add(sweepEvery)
+	for id, e := range s.entries {
+		if e.status != 0 && now.After(e.expires) {
+			delete(s.entries, id)
+		}
+	}
+}
+
+// scope identifies a key: the same key from another caller or on another path is another request.
+func scope(r *http.Request, key string) string {
+	caller := ""
+	if p := auth.FromContext(r.Context()); p != nil {
+		caller = p.Subject
+	}
+	return caller + "\x00" + r.URL.Path + "\x00" + key
+}
+
+func fingerprint(r *http.Request, body []byte) [sha256.Size]byte {
+	h := sha256.New()
+	io.WriteString(h, r.URL.RawQuery)
+	h.Write([]byte{0})
+	h.Write(body)
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// recorder passes a response through and keeps a copy of its status and body.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
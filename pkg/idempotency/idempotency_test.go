@@ -0,0 +1,210 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// counter is a handler that creates an object per call and answers with its number, or with status if set.
+type counter struct {
+	mu     sync.Mutex
+	calls  int
+	status int
+}
+
+func (c *counter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	c.calls++
+	n, status := c.calls, c.status
+	c.mu.Unlock()
+	if status == 0 {
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"id":%d}`, n)
+}
+
+func (c *counter) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+func newStore(t *testing.T) (*Store, *time.Time) {
+	t.Helper()
+	s, err := New(Config{TTL: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 1, 6, 6, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func post(h http.Handler, target, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReplay(t *testing.T) {
+	s, _ := newStore(t)
+	next := &counter{}
+	h := s.Wrap(next)
+
+	first := post(h, "/orders", "k1", `{"sku":"a"}`)
+	again := post(h, "/orders", "k1", `{"sku":"a"}`)
+	if first.Code != http.StatusCreated || first.Body.String() != `{"id":1}` {
+		t.Fatalf("first response %d %s", first.Code, first.Body)
+	}
+	if again.Code != first.Code || again.Body.String() != first.Body.String() {
+		t.Errorf("replayed %d %s, want %d %s", again.Code, again.Body, first.Code, first.Body)
+	}
+	if again.Header().Get(ReplayedHeader) != "true" || again.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("replayed headers %v", again.Header())
+	}
+	if first.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("first response marked replayed")
+	}
+	if n := next.count(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+
+	// Another key, another path or no key at all is another request.
+	post(h, "/orders", "k2", `{"sku":"a"}`)
+	post(h, "/returns", "k1", `{"sku":"a"}`)
+	post(h, "/orders", "", `{"sku":"a"}`)
+	if n := next.count(); n != 4 {
+		t.Errorf("handler ran %d times, want 4", n)
+	}
+}
+
+func TestKeyReuse(t *testing.T) {
+	s, _ := newStore(t)
+	next := &counter{}
+	h := s.Wrap(next)
+
+	post(h, "/orders", "k1", `{"sku":"a"}`)
+	for _, tc := range []struct{ target, body string }{
+		{"/orders", `{"sku":"b"}`},
+		{"/orders?dry_run=true", `{"sku":"a"}`},
+	} {
+		if rec := post(h, tc.target, "k1", tc.body); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("POST %s %s with a used key: %d, want 422", tc.target, tc.body, rec.Code)
+		}
+	}
+	if n := next.count(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+	if rec := post(h, "/orders", strings.Repeat("k", maxKeyLen+1), `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("long key: %d, want 400", rec.Code)
+	}
+}
+
+func TestFailureNotKept(t *testing.T) {
+	s, _ := newStore(t)
+	next := &counter{status: http.StatusServiceUnavailable}
+	h := s.Wrap(next)
+
+	if rec := post(h, "/orders", "k1", `{}`); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("first response %d", rec.Code)
+	}
+	next.mu.Lock()
+	next.status = 0
+	next.mu.Unlock()
+	rec := post(h, "/orders", "k1", `{}`)
+	if rec.Code != http.StatusCreated || rec.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("retry after a failure: %d replayed=%q, want a fresh 201", rec.Code, rec.Header().Get(ReplayedHeader))
+	}
+	if n := next.count(); n != 2 {
+		t.Errorf("handler ran %d times, want 2", n)
+	}
+}
+
+func TestInFlight(t *testing.T) {
+	s, _ := newStore(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	next := &counter{}
+	h := s.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		next.ServeHTTP(w, r)
+	}))
+
+	results := make(chan *httptest.ResponseRecorder, 2)
+	go func() { results <- post(h, "/orders", "k1", `{}`) }()
+	<-started
+
+	// A retry that gives up while the first is running gets a 409.
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`)).WithContext(ctx)
+	req.Header.Set(Header, "k1")
+	cancel()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("cancelled retry while in flight: %d, want 409", rec.Code)
+	}
+
+	// One that waits gets the first request's response.
+	go func() { results <- post(h, "/orders", "k1", `{}`) }()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	a, b := <-results, <-results
+	if a.Code != http.StatusCreated || b.Code != http.StatusCreated || a.Body.String() != b.Body.String() {
+		t.Errorf("concurrent responses %d %s and %d %s, want the same 201", a.Code, a.Body, b.Code, b.Body)
+	}
+	if a.Header().Get(ReplayedHeader) == b.Header().Get(ReplayedHeader) {
+		t.Errorf("want exactly one response replayed")
+	}
+	if n := next.count(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	s, now := newStore(t)
+	next := &counter{}
+	h := s.Wrap(next)
+
+	post(h, "/orders", "k1", `{}`)
+	*now = now.Add(time.Hour)
+	if rec := post(h, "/orders", "k1", `{}`); rec.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("replay at the TTL: replayed=%q", rec.Header().Get(ReplayedHeader))
+	}
+	*now = now.Add(time.Second)
+	if rec := post(h, "/orders", "k1", `{"sku":"b"}`); rec.Code != http.StatusCreated || rec.Body.String() != `{"id":2}` {
+		t.Errorf("after the TTL: %d %s, want a fresh 201", rec.Code, rec.Body)
+	}
+	*now = now.Add(2 * time.Hour)
+	post(h, "/orders", "k2", `{}`) // sweeps k1
+	s.mu.Lock()
+	_, kept := s.entries[scope(httptest.NewRequest(http.MethodPost, "/orders", nil), "k1")]
+	s.mu.Unlock()
+	if kept {
+		t.Errorf("expired key still stored")
+	}
+}
+
+func TestNilStore(t *testing.T) {
+	var s *Store
+	next := &counter{}
+	h := s.Wrap(next)
+	post(h, "/orders", "k1", `{}`)
+	post(h, "/orders", "k1", `{}`)
+	if n := next.count(); n != 2 {
+		t.Errorf("handler ran %d times, want 2", n)
+	}
+}
@@ -15,6 +15,10 @@ const (
 	TopicRobotStatus  = "edge.robot_status"
 	TopicZoneSummary  = "zone.summary"
 	TopicAreaSummary  = "area.summary"
+	TopicSafetyEvents = "edge.safety_events"
+	TopicTaskResults  = "edge.task_results"
+	TopicStepResults  = "zone.step_results"
+	TopicSecurityEvents = "edge.security_events"
 )
 
 // Publisher publishes messages to a topic (or partition).
@@ -27,6 +31,35 @@ type Subscriber interface {
 	Subscribe(ctx context.Context, topic string, handler func(key string, value []byte) error) error
 }
 
+// KeySubscriber is implemented by buses that route by message key, so a subscriber that owns a few
+// partitions (an area, a zone's robots) is not called for every message on the topic.
+type KeySubscriber interface {
+	SubscribeKey(ctx context.Context, topic, key string, handler func(key string, value []byte) error) error
+}
+
+// SubscribeKeys delivers the messages on topic whose key is one of keys to handler. Buses that implement
+// KeySubscriber route them directly; on others handler is wrapped in a key filter.
+func SubscribeKeys(ctx context.Context, sub Subscriber, topic string, keys []string, handler func(key string, value []byte) error) error {
+	if ks, ok := sub.(KeySubscriber); ok {
+		for _, k := range keys {
+			if err := ks.SubscribeKey(ctx, topic, k, handler); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		set[k] = true
+	}
+	return sub.Subscribe(ctx, topic, func(key string, value []byte) error {
+		if !set[key] {
+			return nil
+		}
+		return handler(key, value)
+	})
+}
+
 // Bus combines publish and subscribe; implementations can be Kafka, NATS JetStream, etc.
 type Bus interface {
 	Publisher
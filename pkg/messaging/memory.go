@@ -7,28 +7,37 @@ import (
 
 // MemoryBus is an in-memory implementation of Bus for development and testing.
 // Topics are keyed by topic name; each topic has a list of subscribers that receive copies of messages.
+// Keyed subscribers (SubscribeKey) only receive messages published with their key.
 type MemoryBus struct {
 	mu          sync.RWMutex
 	subscribers map[string][]func(key string, value []byte) error
+	keyed       map[string]map[string][]func(key string, value []byte) error
 }
 
 // NewMemoryBus returns a new in-memory message bus.
 func NewMemoryBus() *MemoryBus {
 	return &MemoryBus{
 		subscribers: make(map[string][]func(key string, value []byte) error),
+		keyed:       make(map[string]map[string][]func(key string, value []byte) error),
 	}
 }
 
-// Publish sends a message to all subscribers of the topic.
+// Publish sends a message to all subscribers of the topic, then to the subscribers of its key.
 func (b *MemoryBus) Publish(ctx context.Context, topic string, key string, value []byte) error {
 	b.mu.RLock()
 	handlers := b.subscribers[topic]
+	keyed := b.keyed[topic][key]
 	b.mu.RUnlock()
 	for _, h := range handlers {
 		if err := h(key, value); err != nil {
 			return err
 		}
 	}
+	for _, h := range keyed {
+		if err := h(key, value); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -39,3 +48,16 @@ func (b *MemoryBus) Subscribe(ctx context.Context, topic string, handler func(ke
 	b.mu.Unlock()
 	return nil
 }
+
+// SubscribeKey registers a handler for the messages on topic published with key.
+func (b *MemoryBus) SubscribeKey(ctx context.Context, topic, key string, handler func(key string, value []byte) error) error {
+	b.mu.Lock()
+	byKey := b.keyed[topic]
+	if byKey == nil {
+		byKey = make(map[string][]func(key string, value []byte) error)
+		b.keyed[topic] = byKey
+	}
+	byKey[key] = append(byKey[key], handler)
+	b.mu.Unlock()
+	return nil
+}
@@ -2,6 +2,7 @@ package messaging
 
 import (
 	"context"
+	"log"
 	"strings"
 	"sync"
 	"time"
@@ -10,18 +11,22 @@ import (
 )
 
 // NewBusFromURL returns a Bus. If url is empty or "memory", returns an in-memory bus.
-// Otherwise treats url as NATS server (e.g. "nats://localhost:4222") and returns a NATS bus.
-func NewBusFromURL(url string) (Bus, error) {
+// Otherwise treats url as NATS server (e.g. "nats://localhost:4222") and returns a NATS bus connected with opts.
+func NewBusFromURL(url string, opts ConnOptions) (Bus, error) {
 	url = strings.TrimSpace(url)
 	if url == "" || url == "memory" {
 		return NewMemoryBus(), nil
 	}
-	return NewNATSBus(url)
+	return NewNATSBus(url, opts)
 }
 
 const keyHeader = "X-Key"
 
 // NATSBus implements Bus using NATS for multi-process deployment.
+//
+// A message with a key goes to the subject "<topic>.<key>" (see Subject), one without to "<topic>", so the
+// server can route keyed subscriptions and NATS permissions can restrict a connection to its own robots,
+// zones or areas (see Permissions).
 type NATSBus struct {
 	nc     *nats.Conn
 	subs   []*nats.Subscription
@@ -30,13 +35,20 @@ type NATSBus struct {
 
 // NewNATSBus connects to NATS at url (e.g. "nats://localhost:4222") and returns a Bus.
 // Retries with backoff so containers can start before NATS is ready; reconnects automatically if NATS restarts.
-func NewNATSBus(url string) (*NATSBus, error) {
-	opts := []nats.Option{
+func NewNATSBus(url string, conn ConnOptions) (*NATSBus, error) {
+	secure, err := conn.natsOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts := append([]nats.Option{
 		nats.MaxReconnects(-1),
 		nats.ReconnectWait(2 * time.Second),
-	}
+		// Permission violations arrive asynchronously; without a handler they would go unnoticed.
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+			log.Printf("messaging: %v", err)
+		}),
+	}, secure...)
 	var nc *nats.Conn
-	var err error
 	for attempt := 0; attempt < 30; attempt++ {
 		nc, err = nats.Connect(url, opts...)
 		if err == nil {
@@ -55,9 +67,24 @@ func (b *NATSBus) Close() {
 	b.nc.Drain()
 }
 
-// Publish publishes a message to the topic with optional key in header.
+// Subject is the NATS subject of a message on topic with key: "<topic>.<key>", with the characters NATS
+// reserves in subjects ('.', '*', '>' and white space) in key replaced by '_', or topic if key is empty.
+func Subject(topic, key string) string {
+	if key == "" {
+		return topic
+	}
+	return topic + "." + strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// Publish publishes a message to the topic's subject for key, with the key itself in a header.
 func (b *NATSBus) Publish(ctx context.Context, topic string, key string, value []byte) error {
-	msg := nats.NewMsg(topic)
+	msg := nats.NewMsg(Subject(topic, key))
 	msg.Data = value
 	if key != "" {
 		msg.Header.Set(keyHeader, key)
@@ -65,11 +92,29 @@ func (b *NATSBus) Publish(ctx context.Context, topic string, key string, value [
 	return b.nc.PublishMsg(msg)
 }
 
-// Subscribe registers a handler for the topic. Messages are delivered asynchronously.
-// When ctx is cancelled, the subscription is unsubscribed.
+// Subscribe registers a handler for every message on the topic, keyed or not. Messages are delivered
+// asynchronously. When ctx is cancelled, the subscription is unsubscribed.
 func (b *NATSBus) Subscribe(ctx context.Context, topic string, handler func(key string, value []byte) error) error {
-	sub, err := b.nc.Subscribe(topic, func(m *nats.Msg) {
+	if err := b.subscribe(ctx, topic, topic, handler); err != nil {
+		return err
+	}
+	return b.subscribe(ctx, topic, topic+".*", handler)
+}
+
+// SubscribeKey registers a handler for the messages on topic published with key; the server routes only
+// those to this connection.
+func (b *NATSBus) SubscribeKey(ctx context.Context, topic, key string, handler func(key string, value []byte) error) error {
+	return b.subscribe(ctx, topic, Subject(topic, key), handler)
+}
+
+// subscribe subscribes to subject. Messages whose key header does not match their subject are dropped, so
+// a publisher allowed only its own subjects cannot pass as another key.
+func (b *NATSBus) subscribe(ctx context.Context, topic, subject string, handler func(key string, value []byte) error) error {
+	sub, err := b.nc.Subscribe(subject, func(m *nats.Msg) {
 		key := m.Header.Get(keyHeader)
+		if Subject(topic, key) != m.Subject {
+			return
+		}
 		_ = handler(key, m.Data)
 	})
 	if err != nil {
@@ -0,0 +1,85 @@
+package messaging
+
+import "fmt"
+
+// Layers that connect to the bus, for Permissions.
+const (
+	LayerFleet = "fleet"
+	LayerArea  = "area"
+	LayerZone  = "zone"
+	LayerEdge  = "edge"
+)
+
+// Scope is what one connection owns: the areas of an area process, the zone and robots of a zone, the
+// robots of an edge or cell gateway. Entries may be NATS JWT templates such as "{{tag(robot)}}", which the
+// server expands per user from the user's tags (robot:robot-1, zone:zone-1, ...).
+type Scope struct {
+	Areas  []string
+	Zones  []string
+	Robots []string
+}
+
+// TemplateScope is the scope of a layer taken from its NATS user's tags area, zone and robot.
+var TemplateScope = Scope{
+	Areas:  []string{"{{tag(area)}}"},
+	Zones:  []string{"{{tag(zone)}}"},
+	Robots: []string{"{{tag(robot)}}"},
+}
+
+// LayerPermissions are the subjects a connection may publish and subscribe to, as in a NATS server
+// authorization block or user JWT.
+type LayerPermissions struct {
+	Publish   []string `json:"publish"`
+	Subscribe []string `json:"subscribe"`
+}
+
+// Permissions returns the least a layer needs on the bus for scope:
+//
+//   - edge: publishes status, safety events, security events and task results of its robots, and
+//     unkeyed security events for rejected broadcasts; receives their commands and zone broadcasts
+//     (safety stops)
+//   - zone: commands its robots and broadcasts; publishes its summary and step results; receives its zone
+//     tasks and its robots' status and results
+//   - area: sends zone tasks to its zones; publishes its summary; receives its work orders and its zones'
+//     summaries and step results
+//   - fleet: publishes work orders to every area; receives every area summary, safety and security event
+//
+// So a compromised edge can neither publish work orders nor report for another robot.
+func Permissions(layer string, scope Scope) (LayerPermissions, error) {
+	var p LayerPermissions
+	switch layer {
+	case LayerEdge:
+		p.Publish = keyed(scope.Robots, TopicRobotStatus, TopicSafetyEvents, TopicSecurityEvents, TopicTaskResults)
+		p.Publish = append(p.Publish, TopicSecurityEvents)
+		p.Subscribe = append(keyed(scope.Robots, TopicRobotCommands), TopicRobotCommands)
+	case LayerZone:
+		p.Publish = append(keyed(scope.Robots, TopicRobotCommands), TopicRobotCommands)
+		p.Publish = append(p.Publish, keyed(scope.Zones, TopicZoneSummary, TopicStepResults)...)
+		p.Subscribe = append(keyed(scope.Zones, TopicZoneTasks), keyed(scope.Robots, TopicRobotStatus, TopicTaskResults)...)
+	case LayerArea:
+		p.Publish = append(keyed(scope.Zones, TopicZoneTasks), keyed(scope.Areas, TopicAreaSummary)...)
+		p.Subscribe = append(keyed(scope.Areas, TopicWorkOrders), keyed(scope.Zones, TopicZoneSummary, TopicStepResults)...)
+	case LayerFleet:
+		p.Publish = keyed([]string{"*"}, TopicWorkOrders)
+		p.Subscribe = []string{TopicAreaSummary, TopicAreaSummary + ".*", TopicSafetyEvents, TopicSafetyEvents + ".*",
+			TopicSecurityEvents, TopicSecurityEvents + ".*"}
+	default:
+		return p, fmt.Errorf("messaging: unknown layer %q", layer)
+	}
+	return p, nil
+}
+
+// keyed returns the subjects of topics for each key, in topic order. A "*" key is kept as the wildcard.
+func keyed(keys []string, topics ...string) []string {
+	subjects := make([]string, 0, len(keys)*len(topics))
+	for _, t := range topics {
+		for _, k := range keys {
+			if k == "*" {
+				subjects = append(subjects, t+".*")
+			} else {
+				subjects = append(subjects, Subject(t, k))
+			}
+		}
+	}
+	return subjects
+}
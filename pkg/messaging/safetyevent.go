@@ -0,0 +1,27 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// SafetyEventPublisher publishes edge safety transitions for auditing (TopicSafetyEvents).
+type SafetyEventPublisher struct {
+	bus Publisher
+}
+
+// NewSafetyEventPublisher returns a publisher for safety events.
+func NewSafetyEventPublisher(bus Publisher) *SafetyEventPublisher {
+	return &SafetyEventPublisher{bus: bus}
+}
+
+// PublishSafetyEvent serializes the event and publishes to TopicSafetyEvents with key = robot_id.
+func (p *SafetyEventPublisher) PublishSafetyEvent(ctx context.Context, ev *api.SafetyEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return p.bus.Publish(ctx, TopicSafetyEvents, string(ev.RobotID), data)
+}
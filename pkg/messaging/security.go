@@ -0,0 +1,105 @@
+package messaging
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/nats-io/nats.go"
+)
+
+// ConnOptions secure a layer's NATS connection: TLS (with a client certificate for mTLS) and NATS
+// credentials. Layer configs embed it in their messaging section; the zero value connects in plain text
+// without credentials, and the in-memory bus ignores it.
+type ConnOptions struct {
+	Name  string     `yaml:"name"` // connection name the server shows, e.g. "zone-1"
+	TLS   TLSOptions `yaml:"tls"`
+	Creds string     `yaml:"creds"` // .creds file with user JWT and nkey seed; env MESSAGING_CREDS
+	NKey  string     `yaml:"nkey"`  // nkey seed file; env MESSAGING_NKEY
+}
+
+// TLSOptions configure TLS to the NATS server. TLS is on when any field is set or the URL is tls://.
+type TLSOptions struct {
+	CA         string `yaml:"ca"`          // PEM bundle to verify the server; default the system roots; env MESSAGING_TLS_CA
+	Cert       string `yaml:"cert"`        // client certificate (PEM) for mTLS; env MESSAGING_TLS_CERT
+	Key        string `yaml:"key"`         // its private key; env MESSAGING_TLS_KEY
+	ServerName string `yaml:"server_name"` // expected server name if not the URL's host
+}
+
+func (t TLSOptions) enabled() bool {
+	return t.CA != "" || t.Cert != "" || t.Key != "" || t.ServerName != ""
+}
+
+// ApplyEnv overrides o from MESSAGING_CREDS, MESSAGING_NKEY and MESSAGING_TLS_CA, _CERT and _KEY.
+func (o *ConnOptions) ApplyEnv() {
+	for env, field := range map[string]*string{
+		"MESSAGING_CREDS":    &o.Creds,
+		"MESSAGING_NKEY":     &o.NKey,
+		"MESSAGING_TLS_CA":   &o.TLS.CA,
+		"MESSAGING_TLS_CERT": &o.TLS.Cert,
+		"MESSAGING_TLS_KEY":  &o.TLS.Key,
+	} {
+		if v := os.Getenv(env); v != "" {
+			*field = v
+		}
+	}
+}
+
+// natsOptions turns o into NATS connect options.
+func (o ConnOptions) natsOptions() ([]nats.Option, error) {
+	var opts []nats.Option
+	if o.Name != "" {
+		opts = append(opts, nats.Name(o.Name))
+	}
+	if o.Creds != "" && o.NKey != "" {
+		return nil, fmt.Errorf("messaging: set one of creds and nkey")
+	}
+	if o.Creds != "" {
+		if _, err := os.Stat(o.Creds); err != nil {
+			return nil, fmt.Errorf("messaging: creds: %w", err)
+		}
+		opts = append(opts, nats.UserCredentials(o.Creds))
+	}
+	if o.NKey != "" {
+		opt, err := nats.NkeyOptionFromSeed(o.NKey)
+		if err != nil {
+			return nil, fmt.Errorf("messaging: nkey: %w", err)
+		}
+		opts = append(opts, opt)
+	}
+	if o.TLS.enabled() {
+		cfg, err := o.TLS.config()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, nats.Secure(cfg))
+	}
+	return opts, nil
+}
+
+func (t TLSOptions) config() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: t.ServerName}
+	if t.CA != "" {
+		pem, err := os.ReadFile(t.CA)
+		if err != nil {
+			return nil, fmt.Errorf("messaging: tls ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("messaging: tls ca %s: no certificates", t.CA)
+		}
+		cfg.RootCAs = pool
+	}
+	if (t.Cert == "") != (t.Key == "") {
+		return nil, fmt.Errorf("messaging: tls cert and key go together")
+	}
+	if t.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(t.Cert, t.Key)
+		if err != nil {
+			return nil, fmt.Errorf("messaging: tls client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,28 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// SecurityEventPublisher publishes robot commands the edge rejected as untrusted (TopicSecurityEvents).
+type SecurityEventPublisher struct {
+	bus Publisher
+}
+
+// NewSecurityEventPublisher returns a publisher for security events.
+func NewSecurityEventPublisher(bus Publisher) *SecurityEventPublisher {
+	return &SecurityEventPublisher{bus: bus}
+}
+
+// PublishSecurityEvent serializes the event and publishes to TopicSecurityEvents with key = robot_id (empty
+// for a rejected zone-wide broadcast).
+func (p *SecurityEventPublisher) PublishSecurityEvent(ctx context.Context, ev *api.SecurityEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return p.bus.Publish(ctx, TopicSecurityEvents, string(ev.RobotID), data)
+}
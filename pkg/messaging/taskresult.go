@@ -0,0 +1,37 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// TaskResultPublisher publishes how robot commands ended: edges to zones (TopicTaskResults, key = robot_id)
+// and zones to areas for task graph steps (TopicStepResults, key = zone_id).
+type TaskResultPublisher struct {
+	bus Publisher
+}
+
+// NewTaskResultPublisher returns a publisher for task results.
+func NewTaskResultPublisher(bus Publisher) *TaskResultPublisher {
+	return &TaskResultPublisher{bus: bus}
+}
+
+// PublishTaskResult serializes the result and publishes to TopicTaskResults with key = robot_id.
+func (p *TaskResultPublisher) PublishTaskResult(ctx context.Context, res *api.TaskResult) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return p.bus.Publish(ctx, TopicTaskResults, string(res.RobotID), data)
+}
+
+// PublishStepResult serializes the result and publishes to TopicStepResults with key = zone_id.
+func (p *TaskResultPublisher) PublishStepResult(ctx context.Context, res *api.TaskResult) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return p.bus.Publish(ctx, TopicStepResults, string(res.ZoneID), data)
+}
@@ -0,0 +1,255 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Check runs every operation of a against h, in order, and returns where they disagree:
+//   - a status that is neither the operation's success status nor one of its errors;
+//   - a success body that does not match the response schema, e.g. a field the spec doesn't have;
+//   - a method the spec doesn't list for a path that h answers with anything but 404 or 405 (HTML pages aside).
+//
+// Path parameters are filled with the "id" of the last object a POST to the path before them created, so
+// declaring a collection's create before its actions exercises the actions on a real object. h should be
+// a fresh server without authentication; Check changes its state.
+func Check(h http.Handler, a *API) []error {
+	a.Document()
+	var errs []error
+	ids := map[string]string{}
+	methods := map[string]map[string]bool{}
+	for _, op := range a.Ops {
+		if methods[op.Path] == nil && !isHTML(op.Response) {
+			methods[op.Path] = map[string]bool{}
+		}
+		if methods[op.Path] != nil {
+			methods[op.Path][op.Method] = true
+		}
+
+		body := op.Example
+		if body == nil {
+			body = op.Body
+		}
+		rec := serve(h, op.Method, fill(op.Path, ids), body)
+		name := op.Method + " " + op.Path
+		if rec.Code != op.status() && !hasStatus(op.Errors, rec.Code) {
+			errs = append(errs, fmt.Errorf("%s: status %d not in spec: %s", name, rec.Code, firstLine(rec.Body.String())))
+			continue
+		}
+		if rec.Code != op.status() || op.Response == nil || isHTML(op.Response) {
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: response is not JSON: %v", name, err))
+			continue
+		}
+		if err := a.validate(a.gen.value(op.Response), v, "response"); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", name, err))
+			continue
+		}
+		if obj, ok := v.(map[string]interface{}); ok && op.Method == http.MethodPost {
+			if id, ok := obj["id"].(string); ok && id != "" {
+				ids[op.Path] = id
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(methods))
+	for p := range methods {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		for _, m := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete} {
+			if methods[p][m] {
+				continue
+			}
+			rec := serve(h, m, fill(p, ids), nil)
+			if rec.Code != http.StatusNotFound && rec.Code != http.StatusMethodNotAllowed {
+				errs = append(errs, fmt.Errorf("%s %s: not in spec but answered %d", m, p, rec.Code))
+			}
+		}
+	}
+	return errs
+}
+
+// CheckRoutes returns the ServeMux patterns (e.g. "/orders", "/orders/") that no path of a falls under, and
+// the paths of a that no pattern serves. HTML pages are left out since handlers serve those before the mux.
+func CheckRoutes(a *API, patterns []string) []error {
+	var errs []error
+	served := func(p, pattern string) bool {
+		if strings.HasSuffix(pattern, "/") {
+			return strings.HasPrefix(p, pattern)
+		}
+		return p == pattern
+	}
+	for _, pattern := range patterns {
+		found := false
+		for _, op := range a.Ops {
+			if served(op.Path, pattern) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("route %s is not in the spec", pattern))
+		}
+	}
+	for _, op := range a.Ops {
+		if isHTML(op.Response) {
+			continue
+		}
+		found := false
+		for _, pattern := range patterns {
+			if served(op.Path, pattern) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("%s %s is in the spec but no route serves it", op.Method, op.Path))
+		}
+	}
+	return errs
+}
+
+func serve(h http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// fill replaces each path parameter with the ID created at the path before it, or "missing".
+func fill(p string, ids map[string]string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range pathParam.FindAllStringIndex(p, -1) {
+		id, ok := ids[strings.TrimSuffix(p[:loc[0]], "/")]
+		if !ok {
+			id = "missing"
+		}
+		b.WriteString(p[last:loc[0]])
+		b.WriteString(id)
+		last = loc[1]
+	}
+	b.WriteString(p[last:])
+	return b.String()
+}
+
+func hasStatus(codes []int, code int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func firstLine(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	return s
+}
+
+// validate checks the decoded JSON v against schema s, resolving component references.
+func (a *API) validate(s map[string]interface{}, v interface{}, at string) error {
+	if ref, ok := s["$ref"].(string); ok {
+		def, _ := a.gen.defs[strings.TrimPrefix(ref, "#/components/schemas/")].(map[string]interface{})
+		return a.validate(def, v, at)
+	}
+	if v == nil {
+		if s["nullable"] == true || len(s) == 0 {
+			return nil
+		}
+		return fmt.Errorf("%s: null, want %v", at, s["type"])
+	}
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if err := a.validate(sub.(map[string]interface{}), v, at); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	switch s["type"] {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: %T, want object", at, v)
+		}
+		props, _ := s["properties"].(map[string]interface{})
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			sub, ok := props[name].(map[string]interface{})
+			if !ok {
+				if extra, ok := s["additionalProperties"].(map[string]interface{}); ok {
+					sub = extra
+				} else if s["additionalProperties"] == false {
+					return fmt.Errorf("%s: field %q is not in the spec", at, name)
+				} else {
+					continue
+				}
+			}
+			if err := a.validate(sub, obj[name], at+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		list, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: %T, want array", at, v)
+		}
+		items, _ := s["items"].(map[string]interface{})
+		for i, e := range list {
+			if err := a.validate(items, e, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: %T, want string", at, v)
+		}
+		if s["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				return fmt.Errorf("%s: %q is not an RFC3339 time", at, str)
+			}
+		}
+	case "integer":
+		if n, ok := v.(float64); !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%s: %v, want integer", at, v)
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s: %T, want number", at, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: %T, want boolean", at, v)
+		}
+	}
+	return nil
+}
+
+func isHTML(v interface{}) bool {
+	_, ok := v.(html)
+	return ok
+}
@@ -0,0 +1,381 @@
+// Package openapi describes a service's HTTP API as OpenAPI 3 operations, with JSON schemas derived from the
+// Go types the handlers decode and encode. Each service declares its API next to its server, serves the
+// document at GET /openapi.json and checks its handler against it with Check.
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/audit"
+	"github.com/robotfleetos/robotfleetos/pkg/auth"
+	"github.com/robotfleetos/robotfleetos/pkg/idempotency"
+)
+
+// Op is one operation: a method on a path.
+type Op struct {
+	Method   string
+	Path     string // parameters in braces, e.g. "/orders/{id}/release"
+	Summary  string
+	Query    []string    // optional query parameters, all strings
+	Body     interface{} // request body: a value of its Go type, e.g. Order{}; nil for none
+	Status   int         // success status; 0 means 200
+	Response interface{} // success body: a value of its Go type, an Object or HTML; nil for none
+	Errors   []int       // error statuses the handler answers with a plain-text message
+
+	// Example is the request body Check sends; without one it sends Body's zero value.
+	Example interface{}
+}
+
+// Object is a JSON object response with fixed fields, each given by a value of its Go type, e.g.
+// Object{"orders": []Order{}} for {"orders": [...]}.
+type Object map[string]interface{}
+
+type html struct{}
+
+// HTML as an Op.Response is an HTML page, e.g. a dashboard.
+var HTML = html{}
+
+// API is the OpenAPI document of one service.
+type API struct {
+	Title       string
+	Description string
+	Rules       []auth.Rule // route permissions; each operation documents the roles it needs
+	Ops         []Op
+	Idempotent  bool // POSTs take an Idempotency-Key header; see pkg/idempotency
+
+	once sync.Once
+	doc  map[string]interface{}
+	gen  *schemas
+}
+
+// Common returns the operations every service serves: /health, /openapi.json and the audit log.
+func Common(layer string) []Op {
+	return []Op{
+		{Method: http.MethodGet, Path: "/health", Summary: "Health check (" + layer + ")",
+			Response: Object{"status": "", "layer": ""}},
+		{Method: http.MethodGet, Path: "/openapi.json", Summary: "This OpenAPI document",
+			Response: Object{}},
+		{Method: http.MethodGet, Path: "/audit", Summary: "Audit log entries, newest first (RFC3339 since and until)",
+			Query:    []string{"actor", "action", "entity", "entity_id", "since", "until", "limit"},
+			Response: Object{"entries": []audit.Entry{}}, Errors: []int{http.StatusBadRequest}},
+		{Method: http.MethodGet, Path: "/audit/verify", Summary: "Check the audit log's hash chain",
+			Response: Object{"ok": false, "head": audit.Head{}, "error": ""}},
+	}
+}
+
+// Dashboard returns GET operations serving the HTML dashboard at paths.
+func Dashboard(paths ...string) []Op {
+	ops := make([]Op, len(paths))
+	for i, p := range paths {
+		ops[i] = Op{Method: http.MethodGet, Path: p, Summary: "Dashboard", Response: HTML}
+	}
+	return ops
+}
+
+// Join concatenates lists of operations.
+func Join(lists ...[]Op) []Op {
+	var out []Op
+	for _, l := range lists {
+		out = append(out, l...)
+	}
+	return out
+}
+
+// ServeHTTP serves the document as JSON.
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(a.Document())
+}
+
+// Document returns the OpenAPI 3.0 document.
+func (a *API) Document() map[string]interface{} {
+	a.once.Do(a.build)
+	return a.doc
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+func (a *API) build() {
+	a.gen = &schemas{defs: map[string]interface{}{}, types: map[string]reflect.Type{}}
+	paths := map[string]interface{}{}
+	for _, op := range a.Ops {
+		item, _ := paths[op.Path].(map[string]interface{})
+		if item == nil {
+			item = map[string]interface{}{}
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = a.operation(op)
+	}
+	a.doc = map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]interface{}{"title": a.Title, "description": a.Description, "version": "1"},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": a.gen.defs,
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]interface{}{"type": "http", "scheme": "bearer", "description": "API key or JWT"},
+				"apiKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"bearer": []string{}}, map[string]interface{}{"apiKey": []string{}}},
+	}
+}
+
+func (a *API) operation(op Op) map[string]interface{} {
+	o := map[string]interface{}{"operationId": operationID(op), "summary": op.Summary}
+	var params []interface{}
+	for _, m := range pathParam.FindAllStringSubmatch(op.Path, -1) {
+		params = append(params, map[string]interface{}{
+			"name": m[1], "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, q := range op.Query {
+		params = append(params, map[string]interface{}{
+			"name": q, "in": "query", "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	errs := append([]int(nil), op.Errors...)
+	if a.Idempotent && op.Method == http.MethodPost {
+		params = append(params, map[string]interface{}{
+			"name": idempotency.Header, "in": "header", "schema": map[string]interface{}{"type": "string", "maxLength": 255},
+			"description": "Retries with the same key get the first successful response again",
+		})
+		errs = append(errs, http.StatusUnprocessableEntity)
+	}
+	if params != nil {
+		o["parameters"] = params
+	}
+	if op.Body != nil {
+		o["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": a.gen.value(op.Body)}},
+		}
+	}
+	responses := map[string]interface{}{}
+	ok := map[string]interface{}{"description": http.StatusText(op.status())}
+	switch op.Response.(type) {
+	case nil:
+	case html:
+		ok["content"] = map[string]interface{}{"text/html": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}}
+	default:
+		ok["content"] = map[string]interface{}{"application/json": map[string]interface{}{"schema": a.gen.value(op.Response)}}
+	}
+	responses[strconv.Itoa(op.status())] = ok
+	rule := auth.Lookup(a.Rules, op.Method, pathParam.ReplaceAllString(op.Path, "x"))
+	if rule.Public {
+		o["security"] = []interface{}{}
+	} else {
+		if len(rule.Roles) > 0 {
+			o["x-roles"] = rule.Roles
+		}
+		errs = append(errs, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	for _, code := range errs {
+		responses[strconv.Itoa(code)] = map[string]interface{}{
+			"description": http.StatusText(code),
+			"content":     map[string]interface{}{"text/plain": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}},
+		}
+	}
+	o["responses"] = responses
+	return o
+}
+
+func (op Op) status() int {
+	if op.Status == 0 {
+		return http.StatusOK
+	}
+	return op.Status
+}
+
+// operationID is the method and the path's words in camel case, e.g. postOrdersIdRelease.
+func operationID(op Op) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(op.Method))
+	for _, w := range strings.FieldsFunc(op.Path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return b.String()
+}
+
+// schemas derives JSON schemas from Go types the way encoding/json encodes them. Named structs become
+// components referenced by name.
+type schemas struct {
+	defs  map[string]interface{}
+	types map[string]reflect.Type
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawType           = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func (g *schemas) value(v interface{}) map[string]interface{} {
+	if obj, ok := v.(Object); ok {
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		props := make(map[string]interface{}, len(obj))
+		for _, name := range names {
+			props[name] = g.value(obj[name])
+		}
+		s := map[string]interface{}{"type": "object", "properties": props}
+		if len(obj) > 0 {
+			s["additionalProperties"] = false
+		}
+		return s
+	}
+	if v == nil {
+		return map[string]interface{}{}
+	}
+	return g.of(reflect.TypeOf(v))
+}
+
+func nullable(s map[string]interface{}) map[string]interface{} {
+	if _, ok := s["$ref"]; ok {
+		return map[string]interface{}{"allOf": []interface{}{s}, "nullable": true}
+	}
+	if len(s) == 0 {
+		return s
+	}
+	s["nullable"] = true
+	return s
+}
+
+func (g *schemas) of(t reflect.Type) map[string]interface{} {
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawType || t.Kind() == reflect.Interface:
+		return map[string]interface{}{}
+	case t.Kind() != reflect.Ptr && t.Implements(jsonMarshalerType):
+		return map[string]interface{}{}
+	case t.Kind() != reflect.Ptr && t.Implements(textMarshalerType):
+		return map[string]interface{}{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return nullable(g.of(t.Elem()))
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte", "nullable": true}
+		}
+		return nullable(map[string]interface{}{"type": "array", "items": g.of(t.Elem())})
+	case reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.of(t.Elem())}
+	case reflect.Map:
+		return nullable(map[string]interface{}{"type": "object", "additionalProperties": g.of(t.Elem())})
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := g.name(t)
+		if _, ok := g.defs[name]; !ok {
+			g.defs[name] = map[string]interface{}{} // placeholder for recursive types
+			g.defs[name] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+// name returns the component name of t: its type name, qualified by its package if another type has it.
+func (g *schemas) name(t reflect.Type) string {
+	name := t.Name()
+	if other, ok := g.types[name]; ok && other != t {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	g.types[name] = t
+	return name
+}
+
+func (g *schemas) object(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	for _, f := range jsonFields(t) {
+		s := g.of(f.typ)
+		if f.quoted {
+			s = map[string]interface{}{"type": "string"}
+		}
+		props[f.name] = s
+	}
+	return map[string]interface{}{"type": "object", "properties": props, "additionalProperties": false}
+}
+
+type field struct {
+	name   string
+	typ    reflect.Type
+	quoted bool // ",string"
+}
+
+// jsonFields returns the fields encoding/json encodes for struct t, with embedded structs flattened; a
+// field of the outer struct wins over one of the same name in an embedded struct.
+func jsonFields(t reflect.Type) []field {
+	var out []field
+	seen := map[string]bool{}
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		seen[name] = true
+		out = append(out, field{name: name, typ: f.Type, quoted: strings.Contains(opts, "string")})
+	}
+	for _, et := range embedded {
+		for _, f := range jsonFields(et) {
+			if !seen[f.name] {
+				seen[f.name] = true
+				out = append(out, f)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+// Package robotmodel is the registry of robot models and their capabilities. Robots report their model in
+// RobotStatus.Extra["model_id"]; the registry says what that model can do, so tasks with api.Requirements
+// only go to robots that can perform them.
+package robotmodel
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/robotfleetos/robotfleetos/pkg/api"
+)
+
+// Model is one robot model.
+type Model struct {
+	ModelID      string           `json:"model_id" yaml:"model_id"`
+	Role         string           `json:"role,omitempty" yaml:"role"` // e.g. "picking", "transport"
+	Capabilities api.Capabilities `json:"capabilities" yaml:"capabilities"`
+}
+
+// Registry holds the known models. The zero value is an empty registry.
+type Registry struct {
+	Models []Model `json:"models" yaml:"models"`
+
+	byID map[string]int
+}
+
+// Load reads a registry from a YAML (or JSON) file.
+func Load(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("model registry %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Parse decodes and validates a registry: every model needs a unique model_id and non-negative amounts.
+func Parse(data []byte) (*Registry, error) {
+	r := &Registry{}
+	if err := yaml.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	r.byID = make(map[string]int, len(r.Models))
+	for i, m := range r.Models {
+		if m.ModelID == "" {
+			return nil, fmt.Errorf("models[%d]: model_id required", i)
+		}
+		if _, dup := r.byID[m.ModelID]; dup {
+			return nil, fmt.Errorf("models[%d]: duplicate model_id %q", i, m.ModelID)
+		}
+		if m.Capabilities.PayloadKg < 0 || m.Capabilities.LiftHeightM < 0 {
+			return nil, fmt.Errorf("model %s: payload_kg and lift_height_m must not be negative", m.ModelID)
+		}
+		r.byID[m.ModelID] = i
+	}
+	return r, nil
+}
+
+// Model returns a model by ID.
+func (r *Registry) Model(id string) (Model, bool) {
+	if r == nil {
+		return Model{}, false
+	}
+	if r.byID == nil {
+		// Built in code rather than parsed.
+		for _, m := range r.Models {
+			if m.ModelID == id {
+				return m, true
+			}
+		}
+		return Model{}, false
+	}
+	i, ok := r.byID[id]
+	if !ok {
+		return Model{}, false
+	}
+	return r.Models[i], true
+}
+
+// Capabilities returns what robots of model id can do; unknown models can do nothing.
+func (r *Registry) Capabilities(id string) api.Capabilities {
+	m, _ := r.Model(id)
+	return m.Capabilities
+}
+
+// Capable returns the IDs of the models that meet req, sorted.
+func (r *Registry) Capable(req *api.Requirements) []string {
+	if r == nil {
+		return nil
+	}
+	var ids []string
+	for _, m := range r.Models {
+		if req.Unmet(m.ModelID, m.Capabilities) == "" {
+			ids = append(ids, m.ModelID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
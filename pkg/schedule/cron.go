@@ -0,0 +1,138 @@
+// Package schedule provides cron-style recurring windows (maintenance windows, shift boundaries).
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed 5-field cron expression: minute hour day-of-month month day-of-week.
+// Supports "*", single values, ranges ("1-5"), lists ("6,14,22") and steps ("*/15", "0-30/10").
+type Cron struct {
+	minute [60]bool
+	hour   [24]bool
+	dom    [32]bool
+	month  [13]bool
+	dow    [7]bool
+	// domStar/dowStar follow cron semantics: if both fields are restricted, a day matches either.
+	domStar bool
+	dowStar bool
+}
+
+// ParseCron parses a 5-field cron expression (e.g. "0 22 * * 1-5").
+func ParseCron(expr string) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: expected 5 fields, got %d", expr, len(fields))
+	}
+	c := &Cron{}
+	if err := parseField(fields[0], 0, 59, c.minute[:]); err != nil {
+		return nil, fmt.Errorf("cron %q minute: %w", expr, err)
+	}
+	if err := parseField(fields[1], 0, 23, c.hour[:]); err != nil {
+		return nil, fmt.Errorf("cron %q hour: %w", expr, err)
+	}
+	if err := parseField(fields[2], 1, 31, c.dom[:]); err != nil {
+		return nil, fmt.Errorf("cron %q day-of-month: %w", expr, err)
+	}
+	if err := parseField(fields[3], 1, 12, c.month[:]); err != nil {
+		return nil, fmt.Errorf("cron %q month: %w", expr, err)
+	}
+	// Day-of-week accepts 0-7 (both 0 and 7 are Sunday).
+	var dow [8]bool
+	if err := parseField(fields[4], 0, 7, dow[:]); err != nil {
+		return nil, fmt.Errorf("cron %q day-of-week: %w", expr, err)
+	}
+	copy(c.dow[:], dow[:7])
+	if dow[7] {
+		c.dow[0] = true
+	}
+	c.domStar = fields[2] == "*"
+	c.dowStar = fields[4] == "*"
+	return c, nil
+}
+
+func parseField(field string, min, max int, out []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			n, err := strconv.Atoi(part[idx+1:])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid step %q", part)
+			}
+			step = n
+			part = part[:idx]
+		}
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			a, errA := strconv.Atoi(bounds[0])
+			b, errB := strconv.Atoi(bounds[1])
+			if errA != nil || errB != nil {
+				return fmt.Errorf("invalid range %q", part)
+			}
+			lo, hi = a, b
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return fmt.Errorf("invalid value %q", part)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return fmt.Errorf("value out of range %d-%d: %q", min, max, part)
+		}
+		for v := lo; v <= hi; v += step {
+			out[v] = true
+		}
+	}
+	return nil
+}
+
+// Matches reports whether t (truncated to the minute) matches the expression.
+func (c *Cron) Matches(t time.Time) bool {
+	return c.minute[t.Minute()] && c.hour[t.Hour()] && c.month[int(t.Month())] && c.dayMatches(t)
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	domOK := c.dom[t.Day()]
+	dowOK := c.dow[int(t.Weekday())]
+	if c.domStar || c.dowStar {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
+
+// Next returns the first matching minute strictly after from, or the zero time if none is found within 5 years.
+func (c *Cron) Next(from time.Time) time.Time {
+	loc := from.Location()
+	t := from.Truncate(time.Minute).Add(time.Minute)
+	limit := from.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !c.month[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.hour[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !c.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,59 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// Window is a recurring period: it opens at each time matching Cron and stays open for Duration.
+// Shift boundaries are expressed the same way, e.g. "45 5,13,21 * * *" for 30m.
+type Window struct {
+	cron     *Cron
+	duration time.Duration
+	loc      *time.Location
+}
+
+// NewWindow parses a window from a cron expression, a duration (e.g. "2h") and an optional IANA time zone.
+// An empty tz uses the process local time zone.
+func NewWindow(cronExpr, duration, tz string) (*Window, error) {
+	c, err := ParseCron(cronExpr)
+	if err != nil {
+		return nil, err
+	}
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return nil, fmt.Errorf("window duration %q: %w", duration, err)
+	}
+	if d <= 0 {
+		return nil, fmt.Errorf("window duration must be positive: %q", duration)
+	}
+	loc := time.Local
+	if tz != "" {
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("window time zone %q: %w", tz, err)
+		}
+	}
+	return &Window{cron: c, duration: d, loc: loc}, nil
+}
+
+// Contains reports whether t falls inside an occurrence of the window.
+func (w *Window) Contains(t time.Time) bool {
+	t = t.In(w.loc)
+	// Any opening after t - duration is still open at t, so t is inside iff such an opening is not after t.
+	start := w.cron.Next(t.Add(-w.duration))
+	return !start.IsZero() && !start.After(t)
+}
+
+// AnyContains reports whether t is inside any of the windows. An empty list means "always open".
+func AnyContains(windows []*Window, t time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for _, w := range windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// bootstrap creates the tables Migrate and NextSeq use themselves.
+const bootstrap = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	scope      TEXT    NOT NULL,
+	version    INTEGER NOT NULL,
+	applied_at TEXT    NOT NULL,
+	PRIMARY KEY (scope, version)
+);
+CREATE TABLE IF NOT EXISTS sequences (
+	name  TEXT    PRIMARY KEY,
+	value INTEGER NOT NULL
+);`
+
+// Migrate brings the schema of scope (a store, e.g. "mes") up to date. migrations[i] is version i+1; each
+// runs once, in a transaction with its version record, so a failed migration leaves the previous version.
+// Migrations are only ever appended. A database at a version newer than len(migrations) is an error, so an
+// older binary does not write to a schema it does not know.
+func Migrate(db *sql.DB, scope string, migrations []string) error {
+	if _, err := db.Exec(bootstrap); err != nil {
+		return fmt.Errorf("storage: %w", err)
+	}
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations WHERE scope = ?`, scope).Scan(&current); err != nil {
+		return fmt.Errorf("storage: %s: %w", scope, err)
+	}
+	if current > len(migrations) {
+		return fmt.Errorf("storage: %s: database schema is at version %d, this build knows %d", scope, current, len(migrations))
+	}
+	for v := current + 1; v <= len(migrations); v++ {
+		err := InTx(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(migrations[v-1]); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (scope, version, applied_at) VALUES (?, ?, ?)`,
+				scope, v, time.Now().UTC().Format(time.RFC3339))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("storage: %s: migration %d: %w", scope, v, err)
+		}
+	}
+	return nil
+}
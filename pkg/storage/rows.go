@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Queryer is a *sql.DB or *sql.Tx.
+type Queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// GetJSON unmarshals the JSON document query selects (one row, one column) into v. It returns false, and
+// no error, when there is no row.
+func GetJSON(q Queryer, v any, query string, args ...any) (bool, error) {
+	var data []byte
+	if err := q.QueryRow(query, args...).Scan(&data); err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+// EachJSON calls fn with the JSON document of every row query selects (one column), in order.
+func EachJSON(q Queryer, fn func(data []byte) error, query string, args ...any) error {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ExecOne runs a statement that must change a row, e.g. an update by ID, and returns ErrNotFound if it
+// changed none.
+func ExecOne(q Queryer, query string, args ...any) error {
+	res, err := q.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Insert runs an INSERT and returns ErrExists if it would duplicate a primary key or unique column.
+func Insert(q Queryer, query string, args ...any) error {
+	_, err := q.Exec(query, args...)
+	var se *sqlite.Error
+	if errors.As(err, &se) && (se.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY || se.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE) {
+		return ErrExists
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+// Package storage opens the database behind the enterprise services' stores (MES, ERP, WMS, CMMS, QMS,
+// PLM, traceability) and keeps their schemas up to date.
+//
+// The default backend keeps everything in memory, as before, so a restart starts empty. The "sqlite"
+// backend keeps it in a file through a pure-Go SQLite driver (no cgo). Each service defines a Store
+// interface with a memory and an SQL implementation; the SQL one passes its schema to Migrate.
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// Backends.
+const (
+	BackendMemory = "memory"
+	BackendSQLite = "sqlite"
+)
+
+// ErrNotFound is returned by store updates and deletes of entities that do not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrExists is returned by store creates of entities whose ID is taken.
+var ErrExists = errors.New("already exists")
+
+// Config is a service's "storage" config section.
+type Config struct {
+	Backend string `yaml:"backend"` // "memory" (default) or "sqlite"; env STORE_BACKEND
+	Path    string `yaml:"path"`    // SQLite database file, created if missing; env STORE_PATH
+}
+
+// ApplyEnv overrides the config from STORE_BACKEND and STORE_PATH.
+func (c *Config) ApplyEnv() {
+	if v := os.Getenv("STORE_BACKEND"); v != "" {
+		c.Backend = v
+	}
+	if v := os.Getenv("STORE_PATH"); v != "" {
+		c.Path = v
+	}
+}
+
+// Open opens the database cfg selects, or returns nil for the memory backend.
+func Open(cfg Config) (*sql.DB, error) {
+	switch cfg.Backend {
+	case "", BackendMemory:
+		return nil, nil
+	case BackendSQLite:
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("storage: sqlite backend needs a path")
+		}
+		// WAL lets readers run alongside the writer; busy_timeout waits out other processes' locks.
+		db, err := sql.Open("sqlite", "file:"+cfg.Path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)")
+		if err != nil {
+			return nil, fmt.Errorf("storage: %w", err)
+		}
+		// SQLite has one writer at a time; a single connection queues writes instead of failing them
+		// with SQLITE_BUSY, and the services' load is far below what one connection serves.
+		db.SetMaxOpenConns(1)
+		if err := db.Ping(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("storage: %s: %w", cfg.Path, err)
+		}
+		return db, nil
+	default:
+		return nil, fmt.Errorf("storage: unknown backend %q (want %q or %q)", cfg.Backend, BackendMemory, BackendSQLite)
+	}
+}
+
+// NextSeq returns the next value of the named sequence, starting at 1. Stores number generated IDs with it
+// (e.g. "ord-42") so IDs are not reused after a restart.
+func NextSeq(q Queryer, name string) (uint64, error) {
+	var n uint64
+	err := q.QueryRow(`INSERT INTO sequences (name, value) VALUES (?, 1)
+		ON CONFLICT (name) DO UPDATE SET value = value + 1 RETURNING value`, name).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("sequence %s: %w", name, err)
+	}
+	return n, nil
+}
+
+// InTx runs fn in a transaction, committed if fn returns nil and rolled back otherwise.
+func InTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openTest(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := Open(Config{Backend: BackendSQLite, Path: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func version(t *testing.T, db *sql.DB, scope string) int {
+	t.Helper()
+	var v int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations WHERE scope = ?`, scope).Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestMigrate(t *testing.T) {
+	db := openTest(t)
+	migrations := []string{
+		`CREATE TABLE items (id TEXT PRIMARY KEY)`,
+		`ALTER TABLE items ADD COLUMN name TEXT`,
+	}
+	if err := Migrate(db, "test", migrations[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrate(db, "test", migrations); err != nil {
+		t.Fatal(err)
+	}
+	// Running again applies nothing: version 2 would fail on the existing column.
+	if err := Migrate(db, "test", migrations); err != nil {
+		t.Errorf("second run: %v", err)
+	}
+	if v := version(t, db, "test"); v != 2 {
+		t.Errorf("version %d, want 2", v)
+	}
+	if _, err := db.Exec(`INSERT INTO items (id, name) VALUES ('a', 'b')`); err != nil {
+		t.Errorf("migrated table: %v", err)
+	}
+	// Scopes are versioned apart.
+	if err := Migrate(db, "other", []string{`CREATE TABLE other_items (id TEXT)`}); err != nil || version(t, db, "other") != 1 {
+		t.Errorf("other scope: %v", err)
+	}
+
+	// An older build refuses a newer schema.
+	if err := Migrate(db, "test", migrations[:1]); err == nil || !strings.Contains(err.Error(), "version 2") {
+		t.Errorf("older build: %v", err)
+	}
+}
+
+func TestMigrateRollback(t *testing.T) {
+	db := openTest(t)
+	migrations := []string{
+		`CREATE TABLE items (id TEXT PRIMARY KEY)`,
+		`CREATE TABLE parts (id TEXT PRIMARY KEY); INSERT INTO missing VALUES (1)`,
+	}
+	if err := Migrate(db, "test", migrations); err == nil || !strings.Contains(err.Error(), "migration 2") {
+		t.Fatalf("failing migration: %v", err)
+	}
+	if v := version(t, db, "test"); v != 1 {
+		t.Errorf("version %d after a failed migration, want 1", v)
+	}
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'parts'`).Scan(&n); err != nil || n != 0 {
+		t.Errorf("table from the failed migration left behind (%d, %v)", n, err)
+	}
+
+	// Fixed, it applies on the next start.
+	migrations[1] = `CREATE TABLE parts (id TEXT PRIMARY KEY)`
+	if err := Migrate(db, "test", migrations); err != nil || version(t, db, "test") != 2 {
+		t.Errorf("fixed migration: %v", err)
+	}
+}
+
+func TestNextSeq(t *testing.T) {
+	db := openTest(t)
+	if err := Migrate(db, "test", nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		want uint64
+	}{
+		{"orders", 1}, {"orders", 2}, {"tasks", 1}, {"orders", 3}, {"tasks", 2},
+	} {
+		if got, err := NextSeq(db, tc.name); err != nil || got != tc.want {
+			t.Errorf("NextSeq(%s) = %d, %v; want %d", tc.name, got, err, tc.want)
+		}
+	}
+	// A rolled-back transaction gives its number back.
+	errRollback := errors.New("rollback")
+	if err := InTx(db, func(tx *sql.Tx) error {
+		if _, err := NextSeq(tx, "orders"); err != nil {
+			return err
+		}
+		return errRollback
+	}); !errors.Is(err, errRollback) {
+		t.Fatal(err)
+	}
+	if got, _ := NextSeq(db, "orders"); got != 4 {
+		t.Errorf("after rollback NextSeq = %d, want 4", got)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	db := openTest(t)
+	if err := Migrate(db, "test", []string{`CREATE TABLE items (id TEXT PRIMARY KEY, code TEXT UNIQUE, qty INTEGER NOT NULL)`}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Insert(db, `INSERT INTO items (id, code, qty) VALUES (?, ?, ?)`, "a", "A", 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		id, code string
+		qty      any
+		want     error
+	}{
+		{"a", "B", 1, ErrExists},
+		{"b", "A", 1, ErrExists},
+		{"c", "C", 2, nil},
+	} {
+		if err := Insert(db, `INSERT INTO items (id, code, qty) VALUES (?, ?, ?)`, tc.id, tc.code, tc.qty); !errors.Is(err, tc.want) {
+			t.Errorf("Insert(%s, %s) = %v, want %v", tc.id, tc.code, err, tc.want)
+		}
+	}
+	// Other constraint failures are not duplicates.
+	if err := Insert(db, `INSERT INTO items (id, code, qty) VALUES (?, ?, ?)`, "d", "D", nil); err == nil || errors.Is(err, ErrExists) {
+		t.Errorf("NOT NULL violation = %v", err)
+	}
+	var qty int
+	if err := db.QueryRow(`SELECT qty FROM items WHERE id = 'a'`).Scan(&qty); err != nil || qty != 1 {
+		t.Errorf("existing row changed: %d, %v", qty, err)
+	}
+}
@@ -0,0 +1,167 @@
+package telemetry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"runtime/pprof"
+	"time"
+)
+
+// StartCPUProfile starts the CPU profiler. The returned stop function stops it and returns the CPU time
+// sampled per layer label; samples without a label (runtime, GC, unlabelled goroutines) are under "".
+// Only one CPU profile can run in a process at a time.
+func StartCPUProfile() (stop func() (map[string]time.Duration, error), err error) {
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return nil, err
+	}
+	return func() (map[string]time.Duration, error) {
+		pprof.StopCPUProfile()
+		return cpuByLabel(&buf, LabelKey)
+	}, nil
+}
+
+// cpuByLabel sums the cpu/nanoseconds value of each sample in a gzipped profile.proto by the value of one
+// string label. It decodes only the fields it needs: sample (2) and string_table (6).
+func cpuByLabel(r io.Reader, key string) (map[string]time.Duration, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	type sample struct {
+		values []int64
+		labels [][2]int64 // key, str as string table indexes
+	}
+	var (
+		samples []sample
+		strs    []string
+	)
+	err = protoFields(data, func(field int, wire int, v uint64, b []byte) error {
+		switch {
+		case field == 2 && wire == 2:
+			var s sample
+			err := protoFields(b, func(field int, wire int, v uint64, b []byte) error {
+				switch {
+				case field == 2 && wire == 0:
+					s.values = append(s.values, int64(v))
+				case field == 2 && wire == 2:
+					return protoPacked(b, func(v uint64) { s.values = append(s.values, int64(v)) })
+				case field == 3 && wire == 2:
+					var l [2]int64
+					err := protoFields(b, func(field int, wire int, v uint64, _ []byte) error {
+						if wire == 0 && (field == 1 || field == 2) {
+							l[field-1] = int64(v)
+						}
+						return nil
+					})
+					s.labels = append(s.labels, l)
+					return err
+				}
+				return nil
+			})
+			samples = append(samples, s)
+			return err
+		case field == 6 && wire == 2:
+			strs = append(strs, string(b))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	str := func(i int64) string {
+		if i < 0 || int(i) >= len(strs) {
+			return ""
+		}
+		return strs[i]
+	}
+	out := make(map[string]time.Duration)
+	for _, s := range samples {
+		if len(s.values) == 0 {
+			continue
+		}
+		layer := ""
+		for _, l := range s.labels {
+			if str(l[0]) == key {
+				layer = str(l[1])
+			}
+		}
+		out[layer] += time.Duration(s.values[len(s.values)-1]) // [samples/count, cpu/nanoseconds]
+	}
+	return out, nil
+}
+
+var errProto = errors.New("telemetry: malformed profile")
+
+// protoFields calls f for each field of a protobuf message: v is set for varints, b for length-delimited
+// fields. Fixed-size fields are skipped.
+func protoFields(data []byte, f func(field, wire int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		tag, n := protoVarint(data)
+		if n == 0 {
+			return errProto
+		}
+		data = data[n:]
+		field, wire := int(tag>>3), int(tag&7)
+		var v uint64
+		var b []byte
+		switch wire {
+		case 0:
+			if v, n = protoVarint(data); n == 0 {
+				return errProto
+			}
+			data = data[n:]
+		case 1, 5:
+			size := 8
+			if wire == 5 {
+				size = 4
+			}
+			if len(data) < size {
+				return errProto
+			}
+			data = data[size:]
+			continue
+		case 2:
+			l, n := protoVarint(data)
+			if n == 0 || uint64(len(data)-n) < l {
+				return errProto
+			}
+			b, data = data[n:n+int(l)], data[n+int(l):]
+		default:
+			return errProto
+		}
+		if err := f(field, wire, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func protoPacked(b []byte, f func(uint64)) error {
+	for len(b) > 0 {
+		v, n := protoVarint(b)
+		if n == 0 {
+			return errProto
+		}
+		f(v)
+		b = b[n:]
+	}
+	return nil
+}
+
+func protoVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		v |= uint64(b[i]&0x7f) << (7 * i)
+		if b[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
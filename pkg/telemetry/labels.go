@@ -0,0 +1,61 @@
+package telemetry
+
+import (
+	"context"
+	"runtime/pprof"
+	"time"
+
+	"github.com/robotfleetos/robotfleetos/pkg/clock"
+	"github.com/robotfleetos/robotfleetos/pkg/messaging"
+)
+
+// LabelBus returns a bus for one layer: its subscription handlers run with the layer's pprof label, and
+// after each Publish the label is set back, because on MemoryBus Publish runs the subscribers' handlers
+// on the caller's goroutine. Give every layer in the process its own LabelBus over the shared bus.
+func LabelBus(bus messaging.Bus, layer string) messaging.Bus {
+	return &labelBus{bus: bus, ctx: pprof.WithLabels(context.Background(), pprof.Labels(LabelKey, layer))}
+}
+
+type labelBus struct {
+	bus messaging.Bus
+	ctx context.Context
+}
+
+func (b *labelBus) Publish(ctx context.Context, topic, key string, value []byte) error {
+	err := b.bus.Publish(ctx, topic, key, value)
+	pprof.SetGoroutineLabels(b.ctx)
+	return err
+}
+
+func (b *labelBus) Subscribe(ctx context.Context, topic string, handler func(key string, value []byte) error) error {
+	return b.bus.Subscribe(ctx, topic, b.wrap(handler))
+}
+
+func (b *labelBus) SubscribeKey(ctx context.Context, topic, key string, handler func(key string, value []byte) error) error {
+	return messaging.SubscribeKeys(ctx, b.bus, topic, []string{key}, b.wrap(handler))
+}
+
+func (b *labelBus) wrap(handler func(key string, value []byte) error) func(key string, value []byte) error {
+	return func(key string, value []byte) error {
+		pprof.SetGoroutineLabels(b.ctx)
+		return handler(key, value)
+	}
+}
+
+// LabelClock returns a clock whose AfterFunc callbacks (periodic summaries, simulated task completions)
+// run with the layer's pprof label.
+func LabelClock(clk clock.Clock, layer string) clock.Clock {
+	return &labelClock{Clock: clk, ctx: pprof.WithLabels(context.Background(), pprof.Labels(LabelKey, layer))}
+}
+
+type labelClock struct {
+	clock.Clock
+	ctx context.Context
+}
+
+func (c *labelClock) AfterFunc(d time.Duration, f func()) clock.Timer {
+	return c.Clock.AfterFunc(d, func() {
+		pprof.SetGoroutineLabels(c.ctx)
+		f()
+	})
+}
@@ -0,0 +1,148 @@
+// Package telemetry measures what each layer of the hierarchy (fleet, area, zone, edge) costs when several
+// run in one process: goroutines and CPU through pprof labels, heap by measuring each layer's setup.
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LabelKey is the pprof label that names the layer a goroutine or CPU sample belongs to.
+const LabelKey = "layer"
+
+// Layers records what each layer costs in this process: instances started, heap retained by its setup and,
+// through pprof goroutine labels, the goroutines it is running now.
+type Layers struct {
+	mu     sync.Mutex
+	layers []*LayerUsage
+}
+
+// LayerUsage is one layer's line in a Report.
+type LayerUsage struct {
+	Layer      string `json:"layer"`
+	Instances  int    `json:"instances"`
+	Goroutines int    `json:"goroutines"`
+	HeapBytes  uint64 `json:"heap_bytes"` // live heap added while the layer was set up
+}
+
+// Report is the per-layer and process usage served at /debug/layers.
+type Report struct {
+	Layers          []LayerUsage `json:"layers"`
+	Goroutines      int          `json:"goroutines"`       // whole process
+	OtherGoroutines int          `json:"goroutines_other"` // runtime, HTTP server, timer callbacks in flight
+	HeapAllocBytes  uint64       `json:"heap_alloc_bytes"`
+	StackInuseBytes uint64       `json:"stack_inuse_bytes"`
+	SysBytes        uint64       `json:"sys_bytes"`
+	NumGC           uint32       `json:"num_gc"`
+}
+
+// Measure runs setup, which builds and launches one layer and returns its instance count, and records the
+// live heap it added. Layers are measured one after another, so the deltas add up to the startup heap.
+func (l *Layers) Measure(layer string, setup func() int) {
+	before := LiveHeap()
+	n := setup()
+	after := LiveHeap()
+	u := &LayerUsage{Layer: layer, Instances: n}
+	if after > before {
+		u.HeapBytes = after - before
+	}
+	l.mu.Lock()
+	l.layers = append(l.layers, u)
+	l.mu.Unlock()
+}
+
+// LiveHeap runs a garbage collection and returns the heap still in use.
+func LiveHeap() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+// Go runs f in a new goroutine labelled with its layer. Goroutines f starts inherit the label.
+func Go(ctx context.Context, layer string, f func(ctx context.Context)) {
+	go pprof.Do(ctx, pprof.Labels(LabelKey, layer), f)
+}
+
+// Report counts goroutines per layer label and reads process memory totals.
+func (l *Layers) Report() Report {
+	counts, total := GoroutinesByLayer()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	r := Report{
+		Goroutines:      total,
+		OtherGoroutines: total,
+		HeapAllocBytes:  m.HeapAlloc,
+		StackInuseBytes: m.StackInuse,
+		SysBytes:        m.Sys,
+		NumGC:           m.NumGC,
+	}
+	l.mu.Lock()
+	for _, u := range l.layers {
+		lu := *u
+		lu.Goroutines = counts[u.Layer]
+		r.OtherGoroutines -= lu.Goroutines
+		r.Layers = append(r.Layers, lu)
+	}
+	l.mu.Unlock()
+	return r
+}
+
+// GoroutinesByLayer parses the goroutine profile, where each stack group starts with "<count> @ <pcs>"
+// and may be followed by a "# labels: {...}" line.
+func GoroutinesByLayer() (map[string]int, int) {
+	var buf bytes.Buffer
+	_ = pprof.Lookup("goroutine").WriteTo(&buf, 1)
+	counts := make(map[string]int)
+	total, pending := 0, 0
+	sc := bufio.NewScanner(&buf)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if n, _, ok := strings.Cut(line, " @ "); ok {
+			if c, err := strconv.Atoi(n); err == nil {
+				pending = c
+				total += c
+			}
+			continue
+		}
+		if labels, ok := strings.CutPrefix(line, "# labels: "); ok && pending > 0 {
+			var m map[string]string
+			if json.Unmarshal([]byte(labels), &m) == nil && m[LabelKey] != "" {
+				counts[m[LabelKey]] += pending
+			}
+			pending = 0
+		}
+	}
+	return counts, total
+}
+
+// LogSummary logs one line with goroutines and startup heap per layer.
+func (l *Layers) LogSummary() {
+	r := l.Report()
+	var parts []string
+	for _, u := range r.Layers {
+		parts = append(parts, u.Layer+": "+strconv.Itoa(u.Instances)+" instances, "+strconv.Itoa(u.Goroutines)+" goroutines, "+strconv.FormatUint(u.HeapBytes>>10, 10)+" KiB")
+	}
+	log.Printf("layers: %s; process: %d goroutines, heap %d MiB, stacks %d MiB", strings.Join(parts, "; "),
+		r.Goroutines, r.HeapAllocBytes>>20, r.StackInuseBytes>>20)
+}
+
+// ServeHTTP serves the Report as JSON.
+func (l *Layers) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(l.Report())
+}